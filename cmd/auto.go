@@ -0,0 +1,57 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"github.com/google/test-server/internal/config"
+	"github.com/google/test-server/internal/replay"
+	"github.com/spf13/cobra"
+)
+
+var autoRecordingDir string
+
+var autoCmd = &cobra.Command{
+	Use:   "auto",
+	Short: "Replay recorded responses and record the missing ones",
+	Long: `Runs test-server in auto mode: requests with a recording are replayed as in
+replay mode, and requests without one are proxied to the target server and
+added to the recordings as in record mode, so the next run replays them.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		config, err := config.ReadConfig(cfgFile)
+		if err != nil {
+			panic(err)
+		}
+		seedRandom(cmd)
+		configureListen(cmd, config)
+		configureTLS()
+		configureJournal()
+		redactor := newRedactor()
+		exitOnError(replay.ValidateAuto(config, autoRecordingDir))
+
+		ready := bindEndpoints(config)
+		enableDashboard(config)
+		err = replay.Auto(config, autoRecordingDir, redactor, ready)
+		if err != nil {
+			panic(err)
+		}
+		failOnViolations()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(autoCmd)
+	autoCmd.Flags().StringVar(&autoRecordingDir, "recording-dir", "recordings", "Directory containing and receiving recorded requests and responses")
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/google/test-server/internal/certs"
+	"github.com/google/test-server/internal/listen"
+	"github.com/spf13/cobra"
+)
+
+var (
+	clientCertOutDir string
+	clientCertEmails []string
+)
+
+var clientCertCmd = &cobra.Command{
+	Use:   "client-cert <name>",
+	Short: "Issue a TLS client certificate for the endpoints with client_auth",
+	Long: `Issue a TLS client certificate with the common name <name>, signed by
+the CA test-server generates in <data-dir>/tls, which the endpoints with
+client_auth accept unless they name another CA. The certificate and its
+private key are written to <name>.pem and <name>-key.pem in --out-dir.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+		dir := filepath.Join(dataDir, "tls")
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		ca, err := certs.LoadCA(filepath.Join(dir, listen.CAFile), filepath.Join(dir, listen.CAKey))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		cert, err := ca.ClientCertificate(name, clientCertEmails...)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		outDir := clientCertOutDir
+		if outDir == "" {
+			outDir = filepath.Join(dir, "clients")
+		}
+		if err := os.MkdirAll(outDir, 0755); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		certFile, keyFile := filepath.Join(outDir, name+".pem"), filepath.Join(outDir, name+"-key.pem")
+		if err := certs.WriteFiles(cert, certFile, keyFile); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "Wrote the client certificate %s and its key %s.\n", certFile, keyFile)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(clientCertCmd)
+	clientCertCmd.Flags().StringVar(&clientCertOutDir, "out-dir", "", "Directory to write the certificate and key to (default is <data-dir>/tls/clients)")
+	clientCertCmd.Flags().StringSliceVar(&clientCertEmails, "email", nil, "Email addresses of the certificate, for client_cert conditions on email")
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/google/test-server/internal/har"
+	"github.com/spf13/cobra"
+)
+
+var exportOut string
+
+// exportCmd groups the commands that convert recordings into the formats of
+// other tools.
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Convert recordings into the formats of other tools",
+}
+
+var exportHARCmd = &cobra.Command{
+	Use:   "har <recordings>...",
+	Short: "Convert recordings into a HAR file",
+	Long: `Convert the interactions of recordings into an HTTP Archive (HAR) file,
+to inspect them in browser developer tools or HAR viewers. Each argument is
+a recording file or a recording directory. The responses have the bodies
+replay sends, with the streamed responses as Server-Sent Events. Recordings
+do not keep when their interactions happened: every entry of a file starts
+at the time the file was last modified.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		h, warnings, err := har.Export(har.Creator{Name: "test-server", Version: rootCmd.Version}, args...)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		for _, w := range warnings {
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", w)
+		}
+		data, err := json.MarshalIndent(h, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if exportOut == "" {
+			os.Stdout.Write(data)
+			return
+		}
+		if err := os.WriteFile(exportOut, data, 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "Wrote %d entries to %s.\n", len(h.Log.Entries), exportOut)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+	exportCmd.PersistentFlags().StringVar(&exportOut, "out", "", "File to write (default is stdout)")
+	exportCmd.AddCommand(exportHARCmd)
+}
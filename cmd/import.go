@@ -0,0 +1,314 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/google/test-server/internal/config"
+	"github.com/google/test-server/internal/har"
+	"github.com/google/test-server/internal/openapi"
+	"github.com/google/test-server/internal/polly"
+	"github.com/google/test-server/internal/redact"
+	"github.com/google/test-server/internal/route"
+	"github.com/google/test-server/internal/vcr"
+	"github.com/google/test-server/internal/wiremock"
+	"github.com/spf13/cobra"
+)
+
+var (
+	importOut        string
+	importServerPath bool
+	importHost       string
+	importTestName   string
+	vcrMatchOn       []string
+	pollyMatchOn     []string
+)
+
+// importCmd groups the commands that convert other formats into stub files.
+var importCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Convert API descriptions and recordings of other tools into stub files and recordings",
+}
+
+var importOpenAPICmd = &cobra.Command{
+	Use:   "openapi <spec>",
+	Short: "Generate stubs from an OpenAPI 3 specification",
+	Long: `Generate a stub file with a route per operation of an OpenAPI 3
+specification, in YAML or JSON. Each route answers with the lowest 2xx
+response of its operation, or its default response, with the first example
+the specification declares for it or else a body made up from the response
+schema. Load the file with stubs_dir, or copy its routes into the config.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		doc, err := openapi.Load(args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		prefix := ""
+		if importServerPath {
+			prefix = doc.BasePath()
+		}
+		routes, err := doc.Stubs(prefix)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		writeStubs(routes)
+	},
+}
+
+var importWireMockCmd = &cobra.Command{
+	Use:   "wiremock <mappings>...",
+	Short: "Convert WireMock stub mappings into stubs",
+	Long: `Convert WireMock stub mappings into a stub file. Each argument is a
+mapping file, a directory of mapping files or the root directory of
+WireMock, with its mappings and __files directories; the body files are
+inlined. The request matchers, responses, delays, faults, priorities and
+scenarios are kept where routes can express them, and a warning tells what
+is dropped, such as Handlebars response templates.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		routes, warnings, err := wiremock.Load(args...)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		for _, w := range warnings {
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", w)
+		}
+		writeStubs(validRoutes(routes))
+	},
+}
+
+var importHARCmd = &cobra.Command{
+	Use:   "har <file.har>",
+	Short: "Convert a HAR file into a recording",
+	Long: `Convert the entries of an HTTP Archive (HAR) file, as saved by browsers
+and debugging proxies, into a recording file, with the entries sent to one
+host. The endpoint of that host in --config, if given, sets the port,
+target type and redacted headers of the recording, and TEST_SERVER_SECRETS
+are redacted as when recording.
+
+Replay the recording with the match_on of the endpoint set, or, with
+--test-name and the file saved as <test name>.json in the recording
+directory, from a test that sends the same requests with that Test-Name
+header.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		h, err := har.Load(args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		writeRecording(cmd, h)
+	},
+}
+
+var importVCRCmd = &cobra.Command{
+	Use:   "vcr <cassette.yaml>",
+	Short: "Convert a VCR.py cassette into a recording",
+	Long: `Convert the interactions of a VCR.py cassette into a recording file,
+with the interactions sent to one host, as import har does. Compressed
+response bodies are decoded.
+
+VCR.py matches requests with the match_on of the test, which the cassette
+does not keep: pass it with --match-on to get the match_on of the endpoint
+that replays the recording the same way.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		cassette, err := vcr.Load(args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		h, warnings, err := cassette.HAR()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		for _, w := range warnings {
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", w)
+		}
+		matchOn, warnings := vcr.MatchOn(vcrMatchOn)
+		writeRecording(cmd, h)
+		printMatchOn(matchOn, warnings, "VCR.py")
+	},
+}
+
+var importPollyCmd = &cobra.Command{
+	Use:   "polly <recording.har>",
+	Short: "Convert a Polly.JS recording into a recording",
+	Long: `Convert the entries of a Polly.JS recording into a recording file, with
+the entries sent to one host, as import har does. Binary bodies saved in
+chunks are joined.
+
+Polly.JS matches requests with the matchRequestsBy options of the test,
+which the recording does not keep: pass the enabled ones with --match-on,
+e.g. method,body,url.pathname, to get the match_on of the endpoint that
+replays the recording the same way.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		recording, err := polly.Load(args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		matchOn, warnings := polly.MatchOn(pollyMatchOn)
+		writeRecording(cmd, recording.HAR)
+		printMatchOn(matchOn, warnings, "Polly.JS")
+	},
+}
+
+// writeRecording writes the entries of h sent to one host as a recording to
+// --out, or to stdout.
+func writeRecording(cmd *cobra.Command, h *har.HAR) {
+	endpoint, err := harEndpoint(cmd, h)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	redactor, err := redact.NewRedact(strings.Split(os.Getenv("TEST_SERVER_SECRETS"), ","))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	recording, warnings, err := h.Recording(har.Options{Endpoint: endpoint, TestName: importTestName, Redactor: redactor})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	for _, w := range warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", w)
+	}
+	data, err := json.MarshalIndent(recording, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	writeOut(data, fmt.Sprintf("%d interactions", len(recording.Interactions)))
+}
+
+// printMatchOn tells the match_on that replays a recording as tool matched
+// it.
+func printMatchOn(matchOn, warnings []string, tool string) {
+	for _, w := range warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", w)
+	}
+	fmt.Fprintf(os.Stderr, "Set match_on: [%s] on the endpoint to match the requests as %s did.\n", strings.Join(matchOn, ", "), tool)
+}
+
+// harEndpoint returns the endpoint of the host of --host, or of the only host
+// of the entries of h: the one of --config if it has one, or else one made
+// from the URL of the first entry for the host.
+func harEndpoint(cmd *cobra.Command, h *har.HAR) (config.EndpointConfig, error) {
+	host := strings.ToLower(importHost)
+	if host == "" {
+		hosts := h.Hosts()
+		if len(hosts) != 1 {
+			return config.EndpointConfig{}, fmt.Errorf("the entries were sent to %d hosts, pick one with --host: %s", len(hosts), strings.Join(hosts, ", "))
+		}
+		host = hosts[0]
+	}
+	if cmd.Flag("config").Changed {
+		cfg, err := config.ReadConfig(cfgFile)
+		if err != nil {
+			return config.EndpointConfig{}, err
+		}
+		for _, ep := range cfg.Endpoints {
+			if strings.EqualFold(ep.TargetHost, host) {
+				return ep, nil
+			}
+		}
+		return config.EndpointConfig{}, fmt.Errorf("%s has no endpoint with target_host %s", cfgFile, host)
+	}
+	endpoint := config.EndpointConfig{TargetHost: host, TargetType: "https", TargetPort: 443}
+	for _, e := range h.Log.Entries {
+		u, err := url.Parse(e.Request.URL)
+		if err != nil || !strings.EqualFold(u.Hostname(), host) {
+			continue
+		}
+		if u.Scheme == "http" {
+			endpoint.TargetType, endpoint.TargetPort = "http", 80
+		}
+		if port, err := strconv.ParseInt(u.Port(), 10, 64); err == nil {
+			endpoint.TargetPort = port
+		}
+		break
+	}
+	return endpoint, nil
+}
+
+// validRoutes returns the routes test-server accepts, warning about the
+// others, e.g. with a regular expression Go does not support.
+func validRoutes(routes []config.Route) []config.Route {
+	var valid []config.Route
+	for _, r := range routes {
+		if _, err := route.New("", []config.Route{r}); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: skipped the route %q: %v\n", r.Name, err)
+			continue
+		}
+		valid = append(valid, r)
+	}
+	return valid
+}
+
+// writeStubs writes routes as a stub file to --out, or to stdout.
+func writeStubs(routes []config.Route) {
+	data, err := route.MarshalStubs(routes)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	writeOut(data, fmt.Sprintf("%d stubs", len(routes)))
+}
+
+// writeOut writes data, described by what, to --out, or to stdout.
+func writeOut(data []byte, what string) {
+	if importOut == "" {
+		os.Stdout.Write(data)
+		return
+	}
+	if err := os.WriteFile(importOut, data, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "Wrote %s to %s.\n", what, importOut)
+}
+
+func init() {
+	rootCmd.AddCommand(importCmd)
+	importCmd.PersistentFlags().StringVar(&importOut, "out", "", "Stub or recording file to write (default is stdout)")
+	importCmd.AddCommand(importOpenAPICmd)
+	importCmd.AddCommand(importWireMockCmd)
+	importCmd.AddCommand(importHARCmd)
+	importCmd.AddCommand(importVCRCmd)
+	importCmd.AddCommand(importPollyCmd)
+	importOpenAPICmd.Flags().BoolVar(&importServerPath, "server-path", true, "Prefix the paths with the path of the first server URL of the specification, e.g. /v1")
+	for _, c := range []*cobra.Command{importHARCmd, importVCRCmd, importPollyCmd} {
+		c.Flags().StringVar(&importHost, "host", "", "Host whose requests are imported (default is the only host of the requests)")
+		c.Flags().StringVar(&importTestName, "test-name", "", "Test-Name header added to every request, to replay the recording as the one of that test")
+	}
+	importVCRCmd.Flags().StringSliceVar(&vcrMatchOn, "match-on", vcr.DefaultMatchOn, "match_on of the VCR.py test")
+	importPollyCmd.Flags().StringSliceVar(&pollyMatchOn, "match-on", polly.DefaultMatchRequestsBy, "matchRequestsBy options the Polly.JS test enabled")
+}
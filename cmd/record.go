@@ -16,12 +16,8 @@ limitations under the License.
 package cmd
 
 import (
-	"os"
-	"strings"
-
 	"github.com/google/test-server/internal/config"
 	"github.com/google/test-server/internal/record"
-	"github.com/google/test-server/internal/redact"
 	"github.com/spf13/cobra"
 )
 
@@ -37,17 +33,20 @@ target server, and all requests and responses will be recorded.`,
 		if err != nil {
 			panic(err)
 		}
-
-		secrets := os.Getenv("TEST_SERVER_SECRETS")
-		redactor, err := redact.NewRedact(strings.Split(secrets, ","))
-		if err != nil {
-			panic(err)
-		}
-
-		err = record.Record(config, recordingDir, redactor)
+		seedRandom(cmd)
+		configureListen(cmd, config)
+		configureTLS()
+		configureJournal()
+		redactor := newRedactor()
+		exitOnError(record.Validate(config, recordingDir))
+
+		ready := bindEndpoints(config)
+		enableDashboard(config)
+		err = record.Record(config, recordingDir, redactor, ready)
 		if err != nil {
 			panic(err)
 		}
+		failOnViolations()
 	},
 }
 
@@ -17,16 +17,21 @@ limitations under the License.
 package cmd
 
 import (
+	"errors"
+	"fmt"
 	"os"
-	"strings"
 
 	"github.com/google/test-server/internal/config"
-	"github.com/google/test-server/internal/redact"
 	"github.com/google/test-server/internal/replay"
 	"github.com/spf13/cobra"
 )
 
-var replayRecordingDir string
+var (
+	replayRecordingDir string
+	replayStrict       bool
+	replayPassthrough  bool
+	replayRecordPass   bool
+)
 
 // replayCmd represents the replay command
 var replayCmd = &cobra.Command{
@@ -34,28 +39,51 @@ var replayCmd = &cobra.Command{
 	Short: "Replay recorded HTTP responses",
 	Long: `Replay mode serves recorded HTTP responses for matching requests.
 It listens on the configured source ports and returns recorded responses
-when it finds a matching request. Returns a 404 error if no matching
-recording is found.`,
+when it finds a matching request. Returns an error if no matching
+recording is found: 501 Not Implemented with the difference from the
+closest recorded request in strict mode, which also makes test-server exit
+with status 1 when stopped if any request had no recording. With
+--passthrough, requests without a recording are proxied to the target
+instead, and with --record-passthrough also recorded.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		config, err := config.ReadConfig(cfgFile)
 		if err != nil {
 			panic(err)
 		}
-
-		secrets := os.Getenv("TEST_SERVER_SECRETS")
-		redactor, err := redact.NewRedact(strings.Split(secrets, ","))
-		if err != nil {
-			panic(err)
+		seedRandom(cmd)
+		configureListen(cmd, config)
+		configureTLS()
+		configureJournal()
+		redactor := newRedactor()
+		if replayRecordPass && !replayPassthrough {
+			fmt.Fprintln(os.Stderr, "Error: --record-passthrough needs --passthrough")
+			os.Exit(1)
+		}
+		opts := replay.Options{
+			Strict:            replayStrict,
+			Passthrough:       replayPassthrough,
+			RecordPassthrough: replayRecordPass,
 		}
+		exitOnError(replay.Validate(config, replayRecordingDir, opts))
 
-		err = replay.Replay(config, replayRecordingDir, redactor)
+		ready := bindEndpoints(config)
+		enableDashboard(config)
+		err = replay.Replay(config, replayRecordingDir, redactor, opts, ready)
+		if errors.Is(err, replay.ErrUnmatched) {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
 		if err != nil {
 			panic(err)
 		}
+		failOnViolations()
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(replayCmd)
 	replayCmd.Flags().StringVar(&replayRecordingDir, "recording-dir", "recordings", "Directory containing recorded requests and responses")
+	replayCmd.Flags().BoolVar(&replayPassthrough, "passthrough", false, "Proxy requests without a recording to the target instead of failing them")
+	replayCmd.Flags().BoolVar(&replayRecordPass, "record-passthrough", false, "Also record the requests proxied by --passthrough")
+	replayCmd.Flags().BoolVar(&replayStrict, "strict", false, "Answer requests without a recording with 501 and a diff, and exit with status 1 when stopped if there were any")
 }
@@ -17,12 +17,39 @@ limitations under the License.
 package cmd
 
 import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
+	"github.com/google/test-server/internal/config"
+	"github.com/google/test-server/internal/listen"
+	"github.com/google/test-server/internal/proxy"
+	"github.com/google/test-server/internal/record"
+	"github.com/google/test-server/internal/redact"
+	"github.com/google/test-server/internal/route"
 	"github.com/spf13/cobra"
 )
 
-var cfgFile string
+var (
+	cfgFile     string
+	seed        int64
+	dashboard   bool
+	dataDir     string
+	useTLS      bool
+	useHTTP3    bool
+	tlsCert     string
+	tlsKey      string
+	listenOn    string
+	port        int64
+	portFile    string
+	bindAddr    string
+	drainFor    time.Duration
+	journalSize int
+)
 
 var rootCmd = &cobra.Command{
 	Use:   "test-server",
@@ -31,13 +58,245 @@ var rootCmd = &cobra.Command{
 a server and then replay the recorded sequenced as part of text fixtures.`,
 }
 
-func Execute() {
+// Root returns the root command with its subcommands, for generating shell
+// completions and man pages.
+func Root() *cobra.Command {
+	return rootCmd
+}
+
+func Execute(version string) {
+	rootCmd.Version = version
 	err := rootCmd.Execute()
 	if err != nil {
 		os.Exit(1)
 	}
 }
 
+// seedRandom seeds the random behavior of the server with --seed, or a
+// random seed, and logs it so that the run can be reproduced.
+func seedRandom(cmd *cobra.Command) {
+	if !cmd.Flag("seed").Changed {
+		seed = rand.Int63()
+	}
+	route.SetSeed(seed)
+	fmt.Printf("Random seed: %d (pass --seed %d to reproduce this run)\n", seed, seed)
+}
+
+// enableDashboard serves the dashboard of every endpoint with --dashboard
+// and logs where.
+func enableDashboard(cfg *config.TestServerConfig) {
+	if !dashboard {
+		return
+	}
+	route.EnableDashboard()
+	for _, ep := range cfg.Endpoints {
+		l := listen.Listeners(&ep)[0]
+		at := listen.BaseURL(l) + route.DashboardPath
+		if socket := listen.Socket(l); socket != "" {
+			at += " over the Unix socket " + socket
+		}
+		fmt.Printf("Dashboard of %s: %s\n", ep.TargetHost, at)
+	}
+}
+
+// configureListen makes the endpoint of cfg listen on --listen or --port,
+// which need a config with a single endpoint, binds the ports without a
+// bind_address to --bind-address, and drains the requests in flight for up
+// to --shutdown-timeout when stopped.
+func configureListen(cmd *cobra.Command, cfg *config.TestServerConfig) {
+	record.SetShutdownTimeout(drainFor)
+	for _, flag := range []string{"listen", "port"} {
+		if cmd.Flag(flag).Changed && len(cfg.Endpoints) != 1 {
+			fmt.Fprintf(os.Stderr, "Error: --%s needs a config with a single endpoint, not %d; set it on each endpoint instead\n", flag, len(cfg.Endpoints))
+			os.Exit(1)
+		}
+	}
+	if cmd.Flag("listen").Changed {
+		cfg.Endpoints[0].Listen = listenOn
+	}
+	if cmd.Flag("port").Changed {
+		cfg.Endpoints[0].SourcePort = port
+	}
+	if bindAddr == "" {
+		return
+	}
+	for i := range cfg.Endpoints {
+		if cfg.Endpoints[i].BindAddress == "" {
+			cfg.Endpoints[i].BindAddress = bindAddr
+		}
+	}
+	if cfg.Proxy != nil && cfg.Proxy.BindAddress == "" {
+		cfg.Proxy.BindAddress = bindAddr
+	}
+}
+
+// listening is where an endpoint listens, in the startup announcement.
+type listening struct {
+	TargetHost string `json:"target_host"`
+	listener
+	// Listeners are the additional listeners of the endpoint.
+	Listeners []listener `json:"listeners,omitempty"`
+}
+
+// listener is a port or socket of an endpoint in the startup announcement.
+type listener struct {
+	Port     int64  `json:"port,omitempty"`
+	Socket   string `json:"socket,omitempty"`
+	BaseURL  string `json:"base_url"`
+	AdminURL string `json:"admin_url"`
+}
+
+// proxyListening is where the forward proxy listens, in the startup
+// announcement.
+type proxyListening struct {
+	Port   int64  `json:"port"`
+	URL    string `json:"url"`
+	CACert string `json:"ca_cert"`
+}
+
+// announcement is the JSON line announcing that the endpoints listen. Its
+// top-level fields are those of the first endpoint.
+type announcement struct {
+	PID int `json:"pid"`
+	listening
+	Endpoints []listening     `json:"endpoints"`
+	Proxy     *proxyListening `json:"proxy,omitempty"`
+}
+
+// bindEndpoints opens the listeners of the endpoints of cfg and the port of
+// its forward proxy, picking free ports for those that are 0, and returns the
+// function that writes their ports to --port-file and announces them on
+// stdout as a JSON line, for test harnesses to read, which the modes call
+// once their servers are about to accept connections.
+func bindEndpoints(cfg *config.TestServerConfig) func() {
+	a := announcement{PID: os.Getpid()}
+	var ports strings.Builder
+	for i := range cfg.Endpoints {
+		ep := &cfg.Endpoints[i]
+		if err := listen.Bind(ep); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: endpoint %s: %v\n", ep.TargetHost, err)
+			os.Exit(1)
+		}
+		var ls []listener
+		for _, l := range listen.Listeners(ep) {
+			lis := listener{
+				Socket:   listen.Socket(l),
+				BaseURL:  listen.BaseURL(l),
+				AdminURL: listen.BaseURL(l) + route.AdminPath,
+			}
+			if lis.Socket == "" {
+				lis.Port = l.Port
+			}
+			ls = append(ls, lis)
+		}
+		if ls[0].Socket == "" {
+			fmt.Fprintf(&ports, "%d\n", ep.SourcePort)
+		}
+		a.Endpoints = append(a.Endpoints, listening{TargetHost: ep.TargetHost, listener: ls[0], Listeners: ls[1:]})
+	}
+	if len(a.Endpoints) > 0 {
+		a.listening = a.Endpoints[0]
+	}
+	if cfg.Proxy != nil {
+		if err := proxy.Bind(cfg); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: proxy: %v\n", err)
+			os.Exit(1)
+		}
+		a.Proxy = &proxyListening{
+			Port:   cfg.Proxy.Port,
+			URL:    listen.BaseURL(config.Listener{Port: cfg.Proxy.Port, BindAddress: cfg.Proxy.BindAddress}),
+			CACert: cfg.Proxy.CACert,
+		}
+		if a.Proxy.CACert == "" {
+			a.Proxy.CACert = proxy.DefaultCACert
+		}
+		fmt.Fprintf(&ports, "%d\n", cfg.Proxy.Port)
+	}
+	return func() { announce(a, ports.String()) }
+}
+
+// announce writes ports to --port-file and a to stdout.
+func announce(a announcement, ports string) {
+	if portFile != "" {
+		// The file appears whole, for the harnesses polling for it.
+		tmp := portFile + ".tmp"
+		if err := os.WriteFile(tmp, []byte(ports), 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if err := os.Rename(tmp, portFile); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	line, _ := json.Marshal(a)
+	fmt.Println(string(line))
+}
+
+// newRedactor returns the redactor of the secrets of TEST_SERVER_SECRETS.
+func newRedactor() *redact.Redact {
+	redactor, err := redact.NewRedact(strings.Split(os.Getenv("TEST_SERVER_SECRETS"), ","))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: TEST_SERVER_SECRETS: %v\n", err)
+		os.Exit(1)
+	}
+	return redactor
+}
+
+// exitOnError exits with status 1 if err, an invalid configuration or flag,
+// is not nil.
+func exitOnError(err error) {
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// configureTLS serves the endpoints over TLS with --tls, with the
+// certificate of --tls-cert and --tls-key or one generated under --data-dir,
+// and over HTTP/3 as well with --http3.
+func configureTLS() {
+	listen.SetTLS(listen.TLSOptions{
+		All:      useTLS,
+		CertFile: tlsCert,
+		KeyFile:  tlsKey,
+		Dir:      filepath.Join(dataDir, "tls"),
+		HTTP3:    useHTTP3,
+	})
+}
+
+// configureJournal makes the journal of every endpoint keep its last
+// --journal-size requests.
+func configureJournal() {
+	if journalSize < 0 {
+		fmt.Fprintf(os.Stderr, "Error: --journal-size must not be negative, got %d\n", journalSize)
+		os.Exit(1)
+	}
+	route.SetJournalSize(journalSize)
+}
+
+// failOnViolations exits with status 1 if a request or response violated
+// the OpenAPI document of an endpoint with openapi.strict.
+func failOnViolations() {
+	if n := route.StrictViolations(); n > 0 {
+		fmt.Fprintf(os.Stderr, "Error: %d requests or their responses violated the OpenAPI document of a strict endpoint\n", n)
+		os.Exit(1)
+	}
+}
+
 func init() {
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is ./test-server.yaml)")
+	rootCmd.PersistentFlags().BoolVar(&dashboard, "dashboard", false, "Serve a web dashboard of the requests, stubs and scenarios of every endpoint at "+route.DashboardPath)
+	rootCmd.PersistentFlags().StringVar(&dataDir, "data-dir", ".test-server", "Directory of the files test-server generates, such as the TLS certificates")
+	rootCmd.PersistentFlags().BoolVar(&useTLS, "tls", false, "Serve every endpoint over TLS, not only those with source_type https")
+	rootCmd.PersistentFlags().BoolVar(&useHTTP3, "http3", false, "Serve every endpoint over HTTP/3 as well, on the UDP ports of its TLS ports, not only those with http3")
+	rootCmd.PersistentFlags().StringVar(&tlsCert, "tls-cert", "", "PEM certificate of the endpoints served over TLS (default is one for localhost and the bind addresses generated under --data-dir, with the CA to trust in <data-dir>/tls/ca.pem)")
+	rootCmd.PersistentFlags().StringVar(&tlsKey, "tls-key", "", "PEM private key of --tls-cert")
+	rootCmd.PersistentFlags().StringVar(&listenOn, "listen", "", "Where the endpoint listens instead of its source_port: unix:<path> for a Unix domain socket (needs a config with a single endpoint)")
+	rootCmd.PersistentFlags().Int64Var(&port, "port", 0, "Port the endpoint listens on instead of its source_port, any free one if 0 (needs a config with a single endpoint)")
+	rootCmd.PersistentFlags().StringVar(&bindAddr, "bind-address", "", "Address the ports of the endpoints and the proxy without a bind_address are bound to: an IP address or host name, 0.0.0.0 for IPv4 only, :: for IPv6 only (default is every address of both)")
+	rootCmd.PersistentFlags().DurationVar(&drainFor, "shutdown-timeout", 10*time.Second, "How long the requests in flight get to complete, and their recordings to be written, once test-server is interrupted or terminated")
+	rootCmd.PersistentFlags().StringVar(&portFile, "port-file", "", "File to write the ports of the endpoints to, one per line, once they listen")
+	rootCmd.PersistentFlags().IntVar(&journalSize, "journal-size", route.DefaultJournalSize, "Number of requests the journal of each endpoint keeps for "+route.JournalPath+", dropping the oldest ones; 0 keeps none")
+	rootCmd.PersistentFlags().Int64Var(&seed, "seed", 0, "Seed of the random behavior of the routes, such as weights, jitter and fake data (default is a random seed, which is logged)")
 }
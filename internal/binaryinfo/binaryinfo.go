@@ -0,0 +1,174 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package binaryinfo inspects a built test-server executable: the platform Go
+// compiled it for, the platform its executable header declares, and the libc
+// it links against. The release tooling checks both against the platform
+// matrix, so that a binary never ships in the archive of another platform.
+package binaryinfo
+
+import (
+	"debug/buildinfo"
+	"debug/elf"
+	"debug/macho"
+	"debug/pe"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/google/test-server/internal/platforms"
+)
+
+// Libc values. Static binaries run on every libc.
+const (
+	Static = "static"
+	Glibc  = "glibc"
+	Musl   = "musl"
+)
+
+// Info describes an executable.
+type Info struct {
+	// Path is the main package the binary was built from.
+	Path      string
+	GoVersion string
+	// GOOS and GOARCH are the build settings recorded by the Go toolchain.
+	GOOS   string
+	GOARCH string
+	CGO    bool
+	// HeaderOS and HeaderArch are what the executable format declares, in Go
+	// terms. ELF does not name an OS; HeaderOS is "linux" for it.
+	HeaderOS   string
+	HeaderArch string
+	// Libc is Static, Glibc or Musl for ELF executables and empty otherwise.
+	Libc        string
+	Interpreter string
+	Libraries   []string
+}
+
+var (
+	elfArch = map[elf.Machine]string{
+		elf.EM_X86_64:  "amd64",
+		elf.EM_386:     "386",
+		elf.EM_AARCH64: "arm64",
+		elf.EM_ARM:     "arm",
+	}
+	machoArch = map[macho.Cpu]string{
+		macho.CpuAmd64: "amd64",
+		macho.CpuArm64: "arm64",
+	}
+	peArch = map[uint16]string{
+		pe.IMAGE_FILE_MACHINE_AMD64: "amd64",
+		pe.IMAGE_FILE_MACHINE_I386:  "386",
+		pe.IMAGE_FILE_MACHINE_ARM64: "arm64",
+	}
+)
+
+// Inspect reads the build information and executable header of a Go binary.
+func Inspect(r io.ReaderAt) (*Info, error) {
+	bi, err := buildinfo.Read(r)
+	if err != nil {
+		return nil, fmt.Errorf("no Go build information: %w", err)
+	}
+	info := &Info{Path: bi.Path, GoVersion: bi.GoVersion}
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "GOOS":
+			info.GOOS = s.Value
+		case "GOARCH":
+			info.GOARCH = s.Value
+		case "CGO_ENABLED":
+			info.CGO = s.Value == "1"
+		}
+	}
+
+	if f, err := elf.NewFile(r); err == nil {
+		defer f.Close()
+		info.HeaderOS = "linux"
+		info.HeaderArch = elfArch[f.Machine]
+		return info, inspectELF(f, info)
+	}
+	if f, err := macho.NewFile(r); err == nil {
+		defer f.Close()
+		info.HeaderOS, info.HeaderArch = "darwin", machoArch[f.Cpu]
+		return info, nil
+	}
+	if f, err := pe.NewFile(r); err == nil {
+		defer f.Close()
+		info.HeaderOS, info.HeaderArch = "windows", peArch[f.Machine]
+		return info, nil
+	}
+	return nil, fmt.Errorf("not an ELF, Mach-O or PE executable")
+}
+
+// inspectELF records the dynamic linker and libraries of f and derives the
+// libc from them.
+func inspectELF(f *elf.File, info *Info) error {
+	for _, p := range f.Progs {
+		if p.Type != elf.PT_INTERP {
+			continue
+		}
+		data, err := io.ReadAll(p.Open())
+		if err != nil {
+			return fmt.Errorf("failed to read the ELF interpreter: %w", err)
+		}
+		info.Interpreter = strings.TrimRight(string(data), "\x00")
+	}
+	libs, err := f.ImportedLibraries()
+	if err != nil {
+		return fmt.Errorf("failed to read the ELF dynamic section: %w", err)
+	}
+	info.Libraries = libs
+
+	switch {
+	case info.Interpreter == "" && len(libs) == 0:
+		info.Libc = Static
+	case strings.Contains(info.Interpreter, "musl") || slices.ContainsFunc(libs, func(l string) bool { return strings.Contains(l, "musl") }):
+		info.Libc = Musl
+	default:
+		info.Libc = Glibc
+	}
+	return nil
+}
+
+// Check returns every way info does not match p, the platform whose archive
+// the binary was found in. mainPath is the expected main package.
+func Check(info *Info, p platforms.Platform, mainPath string) []string {
+	var problems []string
+	if info.Path != mainPath {
+		problems = append(problems, fmt.Sprintf("built from %q, want %q", info.Path, mainPath))
+	}
+	if info.GOOS != p.GOOS || info.GOARCH != p.GOARCH {
+		problems = append(problems, fmt.Sprintf("built for %s/%s, want %s/%s", info.GOOS, info.GOARCH, p.GOOS, p.GOARCH))
+	}
+	if info.HeaderOS != p.GOOS || info.HeaderArch != p.GOARCH {
+		header := info.HeaderOS + "/" + info.HeaderArch
+		if info.HeaderArch == "" {
+			header = info.HeaderOS + " executable of an unknown architecture"
+		}
+		problems = append(problems, fmt.Sprintf("executable header is %s, want %s/%s", header, p.GOOS, p.GOARCH))
+	}
+	// A binary released for several libcs must not depend on any of them.
+	switch {
+	case info.HeaderOS != "linux":
+	case len(p.Libc) > 1 && info.Libc != Static:
+		problems = append(problems, fmt.Sprintf("dynamically linked against %s (%s), but released for %s; build with CGO_ENABLED=0",
+			info.Libc, strings.Join(info.Libraries, ", "), strings.Join(p.Libc, " and ")))
+	case len(p.Libc) == 1 && info.Libc != Static && info.Libc != p.Libc[0]:
+		problems = append(problems, fmt.Sprintf("linked against %s, but released for %s", info.Libc, p.Libc[0]))
+	}
+	return problems
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package binaryinfo
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/test-server/internal/platforms"
+	"github.com/stretchr/testify/require"
+)
+
+const mainPath = "example.com/hello"
+
+// build cross-compiles a trivial program for goos/goarch.
+func build(t *testing.T, goos, goarch string) []byte {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module "+mainPath+"\n\ngo 1.23\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0644))
+	cmd := exec.Command("go", "build", "-o", "hello")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOOS="+goos, "GOARCH="+goarch, "CGO_ENABLED=0", "GOFLAGS=")
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(out))
+	data, err := os.ReadFile(filepath.Join(dir, "hello"))
+	require.NoError(t, err)
+	return data
+}
+
+func TestInspect(t *testing.T) {
+	testCases := []struct {
+		goos, goarch string
+		wantLibc     string
+	}{
+		{goos: "linux", goarch: "arm64", wantLibc: Static},
+		{goos: "darwin", goarch: "amd64"},
+		{goos: "windows", goarch: "386"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.goos+"/"+tc.goarch, func(t *testing.T) {
+			info, err := Inspect(bytes.NewReader(build(t, tc.goos, tc.goarch)))
+			require.NoError(t, err)
+			require.Equal(t, mainPath, info.Path)
+			require.Equal(t, tc.goos, info.GOOS)
+			require.Equal(t, tc.goarch, info.GOARCH)
+			require.Equal(t, tc.goos, info.HeaderOS)
+			require.Equal(t, tc.goarch, info.HeaderArch)
+			require.False(t, info.CGO)
+			require.Equal(t, tc.wantLibc, info.Libc)
+		})
+	}
+
+	_, err := Inspect(bytes.NewReader([]byte("#!/bin/sh\n")))
+	require.Error(t, err)
+}
+
+func TestCheck(t *testing.T) {
+	linuxARM := platforms.Platform{GOOS: "linux", GOARCH: "arm64", Archive: "Linux_arm64", Libc: []string{"glibc", "musl"}}
+	good := Info{Path: mainPath, GOOS: "linux", GOARCH: "arm64", HeaderOS: "linux", HeaderArch: "arm64", Libc: Static}
+	testCases := []struct {
+		name     string
+		modify   func(*Info)
+		platform platforms.Platform
+		want     []string
+	}{
+		{name: "matches", modify: func(*Info) {}, platform: linuxARM},
+		{
+			name: "amd64 binary in the arm64 archive",
+			modify: func(i *Info) {
+				i.GOARCH, i.HeaderArch = "amd64", "amd64"
+			},
+			platform: linuxARM,
+			want:     []string{"built for linux/amd64, want linux/arm64", "executable header is linux/amd64, want linux/arm64"},
+		},
+		{
+			name:     "header disagrees with the build settings",
+			modify:   func(i *Info) { i.HeaderArch = "" },
+			platform: linuxARM,
+			want:     []string{"executable header is linux executable of an unknown architecture, want linux/arm64"},
+		},
+		{
+			name: "dynamically linked",
+			modify: func(i *Info) {
+				i.Libc, i.Libraries = Glibc, []string{"libc.so.6"}
+			},
+			platform: linuxARM,
+			want:     []string{"dynamically linked against glibc (libc.so.6), but released for glibc and musl; build with CGO_ENABLED=0"},
+		},
+		{
+			name: "glibc binary released for glibc only",
+			modify: func(i *Info) {
+				i.Libc = Glibc
+			},
+			platform: platforms.Platform{GOOS: "linux", GOARCH: "arm64", Libc: []string{"glibc"}},
+		},
+		{
+			name:     "other main package",
+			modify:   func(i *Info) { i.Path = "example.com/other" },
+			platform: linuxARM,
+			want:     []string{`built from "example.com/other", want "example.com/hello"`},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			info := good
+			tc.modify(&info)
+			require.Equal(t, tc.want, Check(&info, tc.platform, mainPath))
+		})
+	}
+}
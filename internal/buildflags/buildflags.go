@@ -0,0 +1,45 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package buildflags holds the go build settings of the release binaries.
+// .goreleaser.yaml pins the same settings, so that tools can rebuild a
+// release binary bit for bit from the tagged source.
+package buildflags
+
+import "strings"
+
+// Flags are the go build flags of the release binaries. -trimpath keeps the
+// build directory out of the binary.
+var Flags = []string{"-trimpath"}
+
+// LDFlags returns the linker flags for version: stripped, with the version
+// (without the "v" prefix, as goreleaser's .Version) stamped into main.version.
+func LDFlags(version string) string {
+	return "-s -w -X main.version=" + strings.TrimPrefix(version, "v")
+}
+
+// Args returns the go command arguments building the server in the current
+// directory to out.
+func Args(version, out string) []string {
+	args := append([]string{"build"}, Flags...)
+	return append(args, "-ldflags", LDFlags(version), "-o", out, ".")
+}
+
+// Env returns the environment additions cross-compiling for goos/goarch. The
+// binary is built without cgo so that it runs on any libc.
+func Env(goos, goarch string) []string {
+	return []string{"CGO_ENABLED=0", "GOOS=" + goos, "GOARCH=" + goarch}
+}
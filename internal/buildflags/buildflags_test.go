@@ -0,0 +1,53 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package buildflags
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestArgs(t *testing.T) {
+	require.Equal(t,
+		[]string{"build", "-trimpath", "-ldflags", "-s -w -X main.version=0.2.9", "-o", "out/test-server", "."},
+		Args("v0.2.9", "out/test-server"))
+}
+
+func TestGoreleaserMatches(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("..", "..", ".goreleaser.yaml"))
+	require.NoError(t, err)
+	var cfg struct {
+		Builds []struct {
+			Env     []string `yaml:"env"`
+			Flags   []string `yaml:"flags"`
+			LDFlags []string `yaml:"ldflags"`
+		} `yaml:"builds"`
+	}
+	require.NoError(t, yaml.Unmarshal(data, &cfg))
+	require.Len(t, cfg.Builds, 1)
+
+	// goreleaser renders {{ .Version }} to the tag without its "v".
+	b := cfg.Builds[0]
+	require.Equal(t, Flags, b.Flags)
+	require.Equal(t, []string{strings.Replace(LDFlags("v0.0.0"), "0.0.0", "{{ .Version }}", 1)}, b.LDFlags)
+	require.Contains(t, b.Env, Env("", "")[0])
+}
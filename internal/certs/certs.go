@@ -0,0 +1,231 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package certs generates the test CA and the certificates it signs, with
+// which test-server serves TLS and intercepts HTTPS traffic.
+package certs
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CA is a certificate authority that signs the certificates of the hosts
+// test-server answers for.
+type CA struct {
+	cert *x509.Certificate
+	key  crypto.Signer
+	// PEM is the certificate of the CA, for the clients to trust.
+	PEM []byte
+
+	mu    sync.Mutex
+	certs map[string]*tls.Certificate
+}
+
+// NewCA generates a CA.
+func NewCA() (*CA, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	serial, err := serialNumber()
+	if err != nil {
+		return nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "test-server CA", Organization: []string{"test-server"}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().AddDate(10, 0, 0),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	return newCA(cert, key), nil
+}
+
+func newCA(cert *x509.Certificate, key crypto.Signer) *CA {
+	return &CA{
+		cert:  cert,
+		key:   key,
+		PEM:   pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}),
+		certs: make(map[string]*tls.Certificate),
+	}
+}
+
+// LoadCA reads the CA of the PEM files certFile and keyFile, or generates one
+// and writes it to them if certFile does not exist.
+func LoadCA(certFile, keyFile string) (*CA, error) {
+	certPEM, err := os.ReadFile(certFile)
+	if errors.Is(err, os.ErrNotExist) {
+		ca, err := NewCA()
+		if err != nil {
+			return nil, err
+		}
+		if err := WriteFiles(&tls.Certificate{Certificate: [][]byte{ca.cert.Raw}, PrivateKey: ca.key}, certFile, keyFile); err != nil {
+			return nil, err
+		}
+		return ca, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	keyPEM, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	pair, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", certFile, err)
+	}
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", certFile, err)
+	}
+	if !cert.IsCA {
+		return nil, fmt.Errorf("%s: not a CA certificate", certFile)
+	}
+	key, ok := pair.PrivateKey.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("%s: unsupported private key", keyFile)
+	}
+	return newCA(cert, key), nil
+}
+
+// Certificate returns a certificate for hosts, names or IP addresses, signed
+// by the CA, minting it the first time.
+func (ca *CA) Certificate(hosts ...string) (*tls.Certificate, error) {
+	ca.mu.Lock()
+	defer ca.mu.Unlock()
+	id := strings.Join(hosts, ",")
+	if cert, ok := ca.certs[id]; ok {
+		return cert, nil
+	}
+	template := &x509.Certificate{
+		Subject:     pkix.Name{CommonName: hosts[0], Organization: []string{"test-server"}},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+	cert, err := ca.sign(template)
+	if err != nil {
+		return nil, err
+	}
+	ca.certs[id] = cert
+	return cert, nil
+}
+
+// ClientCertificate returns a new TLS client certificate signed by the CA,
+// with the common name name and the subject alternative names emails.
+func (ca *CA) ClientCertificate(name string, emails ...string) (*tls.Certificate, error) {
+	return ca.sign(&x509.Certificate{
+		Subject:        pkix.Name{CommonName: name, Organization: []string{"test-server"}},
+		EmailAddresses: emails,
+		ExtKeyUsage:    []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+}
+
+// sign completes template into a leaf certificate with a new key and signs
+// it.
+func (ca *CA) sign(template *x509.Certificate) (*tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	if template.SerialNumber, err = serialNumber(); err != nil {
+		return nil, err
+	}
+	template.NotBefore = time.Now().Add(-time.Hour)
+	// Clients reject leaf certificates valid for more than 825 days.
+	template.NotAfter = time.Now().AddDate(1, 0, 0)
+	template.KeyUsage = x509.KeyUsageDigitalSignature
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, key.Public(), ca.key)
+	if err != nil {
+		return nil, err
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Certificate{Certificate: [][]byte{der, ca.cert.Raw}, PrivateKey: key, Leaf: leaf}, nil
+}
+
+// Verify reports whether cert was signed by the CA and is valid for hosts
+// until at least a day from now.
+func (ca *CA) Verify(cert *tls.Certificate, hosts ...string) bool {
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return false
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	for _, host := range hosts {
+		_, err := leaf.Verify(x509.VerifyOptions{Roots: roots, DNSName: host, CurrentTime: time.Now().Add(24 * time.Hour)})
+		if err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// WriteFiles writes the certificate chain and the private key of cert to the
+// PEM files certFile and keyFile.
+func WriteFiles(cert *tls.Certificate, certFile, keyFile string) error {
+	var chain []byte
+	for _, der := range cert.Certificate {
+		chain = append(chain, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})...)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(cert.PrivateKey)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600); err != nil {
+		return err
+	}
+	return os.WriteFile(certFile, chain, 0644)
+}
+
+func serialNumber() (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+}
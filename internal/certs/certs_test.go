@@ -0,0 +1,73 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadCA(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "ca.pem"), filepath.Join(dir, "ca-key.pem")
+	generated, err := LoadCA(certFile, keyFile)
+	require.NoError(t, err)
+	loaded, err := LoadCA(certFile, keyFile)
+	require.NoError(t, err)
+	require.Equal(t, generated.PEM, loaded.PEM)
+
+	cert, err := loaded.Certificate("localhost", "127.0.0.1")
+	require.NoError(t, err)
+	require.Equal(t, []string{"localhost"}, cert.Leaf.DNSNames)
+	roots := x509.NewCertPool()
+	require.True(t, roots.AppendCertsFromPEM(generated.PEM))
+	_, err = cert.Leaf.Verify(x509.VerifyOptions{Roots: roots, DNSName: "127.0.0.1"})
+	require.NoError(t, err)
+	require.True(t, generated.Verify(cert, "localhost", "127.0.0.1"))
+	require.False(t, generated.Verify(cert, "example.com"))
+	other, err := NewCA()
+	require.NoError(t, err)
+	require.False(t, other.Verify(cert, "localhost"))
+
+	// The files written are read back.
+	require.NoError(t, WriteFiles(cert, filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")))
+	read, err := tls.LoadX509KeyPair(filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem"))
+	require.NoError(t, err)
+	require.True(t, generated.Verify(&read, "localhost"))
+
+	_, err = LoadCA(keyFile, keyFile)
+	require.Error(t, err)
+}
+
+func TestClientCertificate(t *testing.T) {
+	ca, err := NewCA()
+	require.NoError(t, err)
+	cert, err := ca.ClientCertificate("alice", "alice@example.com")
+	require.NoError(t, err)
+	require.Equal(t, "alice", cert.Leaf.Subject.CommonName)
+	require.Equal(t, []string{"alice@example.com"}, cert.Leaf.EmailAddresses)
+	roots := x509.NewCertPool()
+	require.True(t, roots.AppendCertsFromPEM(ca.PEM))
+	_, err = cert.Leaf.Verify(x509.VerifyOptions{Roots: roots, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}})
+	require.NoError(t, err)
+	_, err = cert.Leaf.Verify(x509.VerifyOptions{Roots: roots})
+	require.Error(t, err, "a client certificate is not a server certificate")
+}
@@ -0,0 +1,277 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package checksumsjson describes checksums.json, the record of the release
+// archive digests that every SDK installer ships with, and migrates it
+// between schema versions.
+//
+// The file maps each version to its archives and their SHA-256 digests.
+// Reserved keys hold other sections: the pre-release channels, the yanked
+// versions and, from schema 2 on, the schema version itself. Every section is
+// a map of strings, so that readers which only know the digests keep parsing
+// the file.
+package checksumsjson
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/test-server/internal/semver"
+	"github.com/google/test-server/internal/yank"
+)
+
+const (
+	// ChannelsKey maps each pre-release channel to its current version.
+	ChannelsKey = "channels"
+	// SchemaKey holds the schema version under "version".
+	SchemaKey = "schema"
+
+	// Current is the schema version the release tools write.
+	Current = 2
+)
+
+// Files are the per-SDK copies of checksums.json, relative to the repository
+// root. They are always identical.
+var Files = []string{
+	"sdks/typescript/checksums.json",
+	"sdks/python/src/test_server_sdk/checksums.json",
+	"sdks/dotnet/checksums.json",
+}
+
+// Document is a parsed checksums.json.
+type Document map[string]map[string]string
+
+// IsReserved reports whether key names a section rather than a version.
+func IsReserved(key string) bool {
+	return key == ChannelsKey || key == yank.ChecksumsKey || key == SchemaKey
+}
+
+// Parse decodes a checksums.json.
+func Parse(data []byte) (Document, error) {
+	var d Document
+	if err := json.Unmarshal(data, &d); err != nil {
+		return nil, err
+	}
+	if d == nil {
+		d = Document{}
+	}
+	return d, nil
+}
+
+// Load reads and decodes the checksums.json at path.
+func Load(path string) (Document, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	d, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return d, nil
+}
+
+// LoadCurrent reads the checksums.json at path for tools that look digests
+// up: it brings the file to the Current schema and fails when it does not
+// validate.
+func LoadCurrent(path string) (Document, error) {
+	d, err := Load(path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := Migrate(d, Current); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if problems := d.Validate(); len(problems) > 0 {
+		return nil, fmt.Errorf("%s is invalid: %s", path, strings.Join(problems, "; "))
+	}
+	return d, nil
+}
+
+// Digests returns the archive digests of version, and whether d has any.
+// Reserved keys are sections, not versions, and never have digests.
+func (d Document) Digests(version string) (map[string]string, bool) {
+	if IsReserved(version) {
+		return nil, false
+	}
+	digests, ok := d[version]
+	return digests, ok && len(digests) > 0
+}
+
+// Marshal encodes d the way update-sdk-checksums writes it: sorted keys,
+// two-space indentation and a trailing newline.
+func (d Document) Marshal() ([]byte, error) {
+	data, err := json.MarshalIndent(map[string]map[string]string(d), "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// Versions returns the versions with digests, oldest first. Keys that are
+// not versions sort last, by name; Validate reports them.
+func (d Document) Versions() []string {
+	var versions []string
+	for key := range d {
+		if !IsReserved(key) {
+			versions = append(versions, key)
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		a, errA := semver.Parse(versions[i])
+		b, errB := semver.Parse(versions[j])
+		if errA != nil || errB != nil {
+			if (errA == nil) != (errB == nil) {
+				return errA == nil
+			}
+			return versions[i] < versions[j]
+		}
+		return semver.Less(a, b)
+	})
+	return versions
+}
+
+// Schema returns the schema version of d. Files without a schema section
+// are schema 1.
+func (d Document) Schema() (int, error) {
+	section, ok := d[SchemaKey]
+	if !ok {
+		return 1, nil
+	}
+	v, err := strconv.Atoi(section["version"])
+	if err != nil || v < 2 {
+		return 0, fmt.Errorf("invalid %s.version %q", SchemaKey, section["version"])
+	}
+	return v, nil
+}
+
+// Migration converts a document from schema To-1 to To, and back.
+type Migration struct {
+	To          int
+	Description string
+	Up          func(Document)
+	Down        func(Document)
+}
+
+// Migrations are every schema change, in order. A new schema adds an entry
+// here, raises Current and is rolled out with scripts/migrate-checksums.
+var Migrations = []Migration{
+	{
+		To:          2,
+		Description: "record the schema version and always include the channels section",
+		Up: func(d Document) {
+			if d[ChannelsKey] == nil {
+				d[ChannelsKey] = map[string]string{}
+			}
+		},
+		Down: func(d Document) {
+			if len(d[ChannelsKey]) == 0 {
+				delete(d, ChannelsKey)
+			}
+		},
+	},
+}
+
+// Migrate converts d in place to schema to and returns the descriptions of
+// the migrations it applied, in order.
+func Migrate(d Document, to int) ([]string, error) {
+	from, err := d.Schema()
+	if err != nil {
+		return nil, err
+	}
+	if to < 1 || to > Current {
+		return nil, fmt.Errorf("unknown schema version %d; the newest is %d", to, Current)
+	}
+	if from > Current {
+		return nil, fmt.Errorf("schema version %d is newer than this tool knows (%d)", from, Current)
+	}
+	var applied []string
+	for _, m := range Migrations {
+		if m.To > from && m.To <= to {
+			m.Up(d)
+			applied = append(applied, fmt.Sprintf("%d -> %d: %s", m.To-1, m.To, m.Description))
+		}
+	}
+	for i := len(Migrations) - 1; i >= 0; i-- {
+		m := Migrations[i]
+		if m.To <= from && m.To > to {
+			m.Down(d)
+			applied = append(applied, fmt.Sprintf("%d -> %d: undo %s", m.To, m.To-1, m.Description))
+		}
+	}
+	if to == 1 {
+		delete(d, SchemaKey)
+	} else {
+		d[SchemaKey] = map[string]string{"version": strconv.Itoa(to)}
+	}
+	return applied, nil
+}
+
+var digestRe = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// Validate returns every problem with d: keys that are not versions, missing
+// or malformed digests, and channels or yanks naming unknown versions.
+func (d Document) Validate() []string {
+	var problems []string
+	if _, err := d.Schema(); err != nil {
+		problems = append(problems, err.Error())
+	}
+	versions := d.Versions()
+	known := make(map[string]bool, len(versions))
+	for _, v := range versions {
+		known[v] = true
+		if _, err := semver.Parse(v); err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		if len(d[v]) == 0 {
+			problems = append(problems, fmt.Sprintf("%s: no archives", v))
+		}
+		for _, archive := range sortedKeys(d[v]) {
+			if !digestRe.MatchString(d[v][archive]) {
+				problems = append(problems, fmt.Sprintf("%s: %s: %q is not a SHA-256 digest", v, archive, d[v][archive]))
+			}
+		}
+	}
+	for _, channel := range sortedKeys(d[ChannelsKey]) {
+		if v := d[ChannelsKey][channel]; !known[v] {
+			problems = append(problems, fmt.Sprintf("%s: %s points to %s, which has no digests", ChannelsKey, channel, v))
+		}
+	}
+	for _, v := range sortedKeys(d[yank.ChecksumsKey]) {
+		if !known[v] {
+			problems = append(problems, fmt.Sprintf("%s: %s has no digests", yank.ChecksumsKey, v))
+		}
+		if r := d[yank.ChecksumsKey][v]; !known[r] {
+			problems = append(problems, fmt.Sprintf("%s: the replacement %s of %s has no digests", yank.ChecksumsKey, r, v))
+		}
+	}
+	return problems
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
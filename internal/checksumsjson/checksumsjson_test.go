@@ -0,0 +1,153 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checksumsjson
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var digest = strings.Repeat("ab", 32)
+
+func TestMigrate(t *testing.T) {
+	v1 := `{"v0.2.8": {"test-server_Linux_x86_64.tar.gz": "` + digest + `"}}`
+	testCases := []struct {
+		name        string
+		input       string
+		to          int
+		wantSchema  int
+		wantApplied int
+		wantErr     string
+	}{
+		{name: "up", input: v1, to: 2, wantSchema: 2, wantApplied: 1},
+		{name: "already current", input: v1, to: 1, wantSchema: 1},
+		{name: "down", input: `{"schema": {"version": "2"}, "channels": {}, "v0.2.8": {"a": "` + digest + `"}}`, to: 1, wantSchema: 1, wantApplied: 1},
+		{name: "unknown target", input: v1, to: Current + 1, wantErr: "unknown schema version"},
+		{name: "newer than known", input: `{"schema": {"version": "99"}}`, to: 2, wantErr: "newer than this tool knows"},
+		{name: "invalid schema", input: `{"schema": {"version": "two"}}`, to: 2, wantErr: "invalid schema.version"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			d, err := Parse([]byte(tc.input))
+			require.NoError(t, err)
+			applied, err := Migrate(d, tc.to)
+			if tc.wantErr != "" {
+				require.ErrorContains(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, applied, tc.wantApplied)
+			schema, err := d.Schema()
+			require.NoError(t, err)
+			require.Equal(t, tc.wantSchema, schema)
+			require.Empty(t, d.Validate())
+		})
+	}
+}
+
+func TestMigrateRoundTrip(t *testing.T) {
+	input := `{
+  "channels": {
+    "beta": "v0.3.0-beta.1"
+  },
+  "v0.2.8": {
+    "test-server_Linux_x86_64.tar.gz": "` + digest + `"
+  },
+  "v0.3.0-beta.1": {
+    "test-server_Linux_x86_64.tar.gz": "` + digest + `"
+  }
+}
+`
+	d, err := Parse([]byte(input))
+	require.NoError(t, err)
+	_, err = Migrate(d, Current)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"version": "2"}, d[SchemaKey])
+	_, err = Migrate(d, 1)
+	require.NoError(t, err)
+	out, err := d.Marshal()
+	require.NoError(t, err)
+	require.Equal(t, input, string(out))
+}
+
+func TestVersions(t *testing.T) {
+	d := Document{
+		"v0.10.0": {}, "v0.2.0": {}, "v0.2.0-rc.1": {}, "latest": {},
+		ChannelsKey: {}, SchemaKey: {}, "yanked": {},
+	}
+	require.Equal(t, []string{"v0.2.0-rc.1", "v0.2.0", "v0.10.0", "latest"}, d.Versions())
+}
+
+func TestValidate(t *testing.T) {
+	d := Document{
+		"v0.2.8":    {"a.tar.gz": digest, "b.zip": "deadbeef"},
+		"v0.2.9":    {},
+		"latest":    {"a.tar.gz": digest},
+		ChannelsKey: {"beta": "v0.3.0-beta.1"},
+		"yanked":    {"v0.2.7": "v0.2.8"},
+	}
+	require.Equal(t, []string{
+		`v0.2.8: b.zip: "deadbeef" is not a SHA-256 digest`,
+		"v0.2.9: no archives",
+		`"latest" is not a semantic version`,
+		"channels: beta points to v0.3.0-beta.1, which has no digests",
+		"yanked: v0.2.7 has no digests",
+	}, d.Validate())
+}
+
+func TestLoadCurrentV2WithReservedKeys(t *testing.T) {
+	v2 := `{
+  "channels": {"beta": "v0.3.0-beta.1"},
+  "schema": {"version": "2"},
+  "v0.2.7": {"test-server_Linux_x86_64.tar.gz": "` + digest + `"},
+  "v0.2.8": {"test-server_Linux_x86_64.tar.gz": "` + digest + `", "test-server_Windows_x86_64.zip": "` + digest + `"},
+  "v0.3.0-beta.1": {"test-server_Linux_x86_64.tar.gz": "` + digest + `"},
+  "yanked": {"v0.2.7": "v0.2.8"}
+}
+`
+	path := filepath.Join(t.TempDir(), "checksums.json")
+	require.NoError(t, os.WriteFile(path, []byte(v2), 0644))
+
+	d, err := LoadCurrent(path)
+	require.NoError(t, err)
+	require.Equal(t, []string{"v0.2.7", "v0.2.8", "v0.3.0-beta.1"}, d.Versions())
+	digests, ok := d.Digests("v0.2.8")
+	require.True(t, ok)
+	require.Equal(t, digest, digests["test-server_Windows_x86_64.zip"])
+	for _, key := range []string{ChannelsKey, SchemaKey, "yanked", "v0.2.9"} {
+		_, ok := d.Digests(key)
+		require.False(t, ok, key)
+	}
+
+	// A schema 1 file is migrated, a file from a newer tool is refused.
+	require.NoError(t, os.WriteFile(path, []byte(`{"v0.2.8": {"a.tar.gz": "`+digest+`"}}`), 0644))
+	d, err = LoadCurrent(path)
+	require.NoError(t, err)
+	schema, err := d.Schema()
+	require.NoError(t, err)
+	require.Equal(t, Current, schema)
+	require.NoError(t, os.WriteFile(path, []byte(`{"schema": {"version": "99"}}`), 0644))
+	_, err = LoadCurrent(path)
+	require.ErrorContains(t, err, "newer than this tool knows")
+	require.NoError(t, os.WriteFile(path, []byte(`{"v0.2.8": {"a.tar.gz": "deadbeef"}}`), 0644))
+	_, err = LoadCurrent(path)
+	require.ErrorContains(t, err, "is not a SHA-256 digest")
+}
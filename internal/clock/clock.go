@@ -0,0 +1,104 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package clock is the time of the server, which tests can freeze, set and
+// advance to exercise time-dependent code, such as token refresh, without
+// waiting.
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is a time that runs with the wall clock, at an offset, until it is
+// frozen.
+type Clock struct {
+	mu     sync.Mutex
+	offset time.Duration
+	frozen bool
+	at     time.Time // the time while frozen
+}
+
+// Server is the clock of the templates, tokens and rate limits of every
+// endpoint.
+var Server = &Clock{}
+
+// Now returns the time of c.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now()
+}
+
+func (c *Clock) now() time.Time {
+	if c.frozen {
+		return c.at
+	}
+	return time.Now().Add(c.offset)
+}
+
+// Frozen reports whether c is frozen.
+func (c *Clock) Frozen() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.frozen
+}
+
+// Freeze stops c at its current time.
+func (c *Clock) Freeze() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.at, c.frozen = c.now(), true
+}
+
+// Resume makes a frozen c run again from its time.
+func (c *Clock) Resume() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.frozen {
+		c.offset, c.frozen = time.Until(c.at), false
+	}
+}
+
+// Set moves c to t. A frozen clock stays frozen.
+func (c *Clock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.frozen {
+		c.at = t
+		return
+	}
+	c.offset = time.Until(t)
+}
+
+// Advance moves c forward by d, or back if d is negative.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.frozen {
+		c.at = c.at.Add(d)
+		return
+	}
+	c.offset += d
+}
+
+// Reset makes c the wall clock again.
+func (c *Clock) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.offset, c.frozen, c.at = 0, false, time.Time{}
+}
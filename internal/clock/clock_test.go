@@ -0,0 +1,56 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClock(t *testing.T) {
+	c := &Clock{}
+	require.WithinDuration(t, time.Now(), c.Now(), time.Second)
+
+	c.Freeze()
+	require.True(t, c.Frozen())
+	frozen := c.Now()
+	time.Sleep(10 * time.Millisecond)
+	require.Equal(t, frozen, c.Now())
+
+	c.Advance(time.Hour)
+	require.Equal(t, frozen.Add(time.Hour), c.Now())
+	at := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	c.Set(at)
+	require.Equal(t, at, c.Now())
+
+	// A resumed clock runs from where it stopped.
+	c.Resume()
+	require.False(t, c.Frozen())
+	time.Sleep(10 * time.Millisecond)
+	require.True(t, c.Now().After(at))
+	require.WithinDuration(t, at, c.Now(), time.Second)
+	c.Advance(-24 * time.Hour)
+	require.WithinDuration(t, at.Add(-24*time.Hour), c.Now(), time.Second)
+
+	c.Set(at)
+	require.WithinDuration(t, at, c.Now(), time.Second)
+
+	c.Reset()
+	require.WithinDuration(t, time.Now(), c.Now(), time.Second)
+}
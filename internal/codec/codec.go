@@ -0,0 +1,196 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package codec encodes and decodes HTTP bodies with the content codings
+// gzip, deflate, br and zstd.
+package codec
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Encodings are the content codings Encode supports.
+var Encodings = []string{"gzip", "deflate", "br", "zstd"}
+
+// ErrUnsupported reports a content coding that cannot be decoded.
+var ErrUnsupported = errors.New("unsupported content coding")
+
+// zstdEncoder and zstdDecoder are shared: their EncodeAll and DecodeAll are
+// safe for concurrent use.
+var (
+	zstdEncoder, _ = zstd.NewWriter(nil)
+	zstdDecoder, _ = zstd.NewReader(nil)
+)
+
+// normalize returns the name of coding, a Content-Encoding token.
+func normalize(coding string) string {
+	coding = strings.ToLower(strings.TrimSpace(coding))
+	if coding == "x-gzip" {
+		return "gzip"
+	}
+	return coding
+}
+
+// Supported reports whether Encode supports coding.
+func Supported(coding string) bool {
+	switch normalize(coding) {
+	case "gzip", "deflate", "br", "zstd", "identity":
+		return true
+	}
+	return false
+}
+
+// Encode returns data encoded with coding.
+func Encode(coding string, data []byte) ([]byte, error) {
+	var b bytes.Buffer
+	var w io.WriteCloser
+	switch normalize(coding) {
+	case "", "identity":
+		return data, nil
+	case "gzip":
+		w = gzip.NewWriter(&b)
+	case "deflate":
+		// HTTP deflate is the zlib format.
+		w = zlib.NewWriter(&b)
+	case "br":
+		w = brotli.NewWriter(&b)
+	case "zstd":
+		return zstdEncoder.EncodeAll(data, nil), nil
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnsupported, coding)
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// Decode returns data decoded from coding, a Content-Encoding header value
+// that may list several codings in the order they were applied.
+func Decode(coding string, data []byte) ([]byte, error) {
+	codings := strings.Split(coding, ",")
+	for i := len(codings) - 1; i >= 0; i-- {
+		var r io.Reader
+		var err error
+		switch c := normalize(codings[i]); c {
+		case "", "identity":
+			continue
+		case "gzip":
+			r, err = gzip.NewReader(bytes.NewReader(data))
+		case "deflate":
+			// Some clients send raw deflate instead of zlib.
+			if r, err = zlib.NewReader(bytes.NewReader(data)); err != nil {
+				r, err = flate.NewReader(bytes.NewReader(data)), nil
+			}
+		case "br":
+			r = brotli.NewReader(bytes.NewReader(data))
+		case "zstd":
+			if data, err = zstdDecoder.DecodeAll(data, nil); err != nil {
+				return nil, err
+			}
+			continue
+		default:
+			return nil, fmt.Errorf("%w %q", ErrUnsupported, c)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if data, err = io.ReadAll(r); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// DecodeRequest replaces the encoded body of req with the decoded one and
+// drops its Content-Encoding.
+func DecodeRequest(req *http.Request) error {
+	coding := req.Header.Get("Content-Encoding")
+	if coding == "" || req.Body == nil {
+		return nil
+	}
+	data, err := io.ReadAll(req.Body)
+	if err != nil {
+		return err
+	}
+	req.Body.Close()
+	if data, err = Decode(coding, data); err != nil {
+		return err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(data))
+	req.ContentLength = int64(len(data))
+	req.Header.Set("Content-Length", strconv.Itoa(len(data)))
+	req.Header.Del("Content-Encoding")
+	return nil
+}
+
+// Accepts reports whether the Accept-Encoding header value accept allows
+// coding.
+func Accepts(accept, coding string) bool {
+	coding = normalize(coding)
+	if coding == "identity" {
+		return true
+	}
+	star := false
+	for _, item := range strings.Split(accept, ",") {
+		name, params, _ := strings.Cut(item, ";")
+		name = normalize(name)
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			q, _ = strconv.ParseFloat(v, 64)
+		}
+		switch name {
+		case coding:
+			return q > 0
+		case "*":
+			star = q > 0
+		}
+	}
+	return star
+}
+
+// Decodable returns the codings of the Accept-Encoding header value accept
+// that Decode supports, so that the responses to a request sent on with it
+// can be decoded.
+func Decodable(accept string) string {
+	var kept []string
+	for _, item := range strings.Split(accept, ",") {
+		name, _, _ := strings.Cut(item, ";")
+		switch normalize(name) {
+		case "gzip", "deflate", "br", "zstd", "identity":
+			kept = append(kept, strings.TrimSpace(item))
+		}
+	}
+	if kept == nil {
+		return "identity"
+	}
+	return strings.Join(kept, ", ")
+}
@@ -0,0 +1,134 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package codec
+
+import (
+	"bytes"
+	"compress/flate"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoundTrip(t *testing.T) {
+	data := []byte(strings.Repeat(`{"text": "hello"}`, 1000))
+	for _, coding := range []string{"gzip", "x-gzip", "deflate", "br", "zstd", "identity", ""} {
+		encoded, err := Encode(coding, data)
+		require.NoError(t, err, coding)
+		if coding != "identity" && coding != "" {
+			require.Less(t, len(encoded), len(data)/10, coding)
+		}
+		decoded, err := Decode(coding, encoded)
+		require.NoError(t, err, coding)
+		require.Equal(t, data, decoded, coding)
+	}
+
+	// Several codings are undone in reverse order.
+	gz, err := Encode("gzip", data)
+	require.NoError(t, err)
+	both, err := Encode("deflate", gz)
+	require.NoError(t, err)
+	decoded, err := Decode("gzip, deflate", both)
+	require.NoError(t, err)
+	require.Equal(t, data, decoded)
+
+	var raw bytes.Buffer
+	w, err := flate.NewWriter(&raw, flate.DefaultCompression)
+	require.NoError(t, err)
+	w.Write(data)
+	w.Close()
+	decoded, err = Decode("deflate", raw.Bytes())
+	require.NoError(t, err)
+	require.Equal(t, data, decoded)
+
+	_, err = Decode("compress", data)
+	require.ErrorIs(t, err, ErrUnsupported)
+	_, err = Encode("compress", data)
+	require.ErrorIs(t, err, ErrUnsupported)
+	_, err = Decode("zstd", data)
+	require.Error(t, err)
+	_, err = Decode("br", data)
+	require.Error(t, err)
+}
+
+func TestForeignStreams(t *testing.T) {
+	// Streams written by other encoders: an empty brotli stream, a brotli
+	// stream with an uncompressed meta-block and a zstd frame with a raw
+	// block.
+	for _, tc := range []struct {
+		coding string
+		data   []byte
+		want   string
+	}{
+		{"br", []byte{0x06}, ""},
+		{"br", []byte{0x10, 0x00, 0x10, 'h', 'i', 0x03}, "hi"},
+		{"zstd", []byte{0x28, 0xb5, 0x2f, 0xfd, 0xe0, 2, 0, 0, 0, 0, 0, 0, 0, 0x11, 0, 0, 'h', 'i'}, "hi"},
+	} {
+		got, err := Decode(tc.coding, tc.data)
+		require.NoError(t, err, tc.coding)
+		require.Equal(t, tc.want, string(got), tc.coding)
+	}
+}
+
+func TestAccepts(t *testing.T) {
+	require.True(t, Accepts("gzip, deflate, br", "br"))
+	require.True(t, Accepts("GZIP;q=0.5", "x-gzip"))
+	require.False(t, Accepts("gzip;q=0", "gzip"))
+	require.False(t, Accepts("gzip", "zstd"))
+	require.True(t, Accepts("*", "zstd"))
+	require.False(t, Accepts("*, zstd;q=0", "zstd"))
+	require.False(t, Accepts("", "gzip"))
+	require.True(t, Accepts("", "identity"))
+
+	require.Equal(t, "gzip, br, deflate;q=0.5, zstd", Decodable("gzip, br, deflate;q=0.5, zstd"))
+	require.Equal(t, "br", Decodable("br, compress"))
+	require.Equal(t, "identity", Decodable("compress"))
+}
+
+func TestDecodeRequest(t *testing.T) {
+	body, err := Encode("gzip", []byte(`{"a": 1}`))
+	require.NoError(t, err)
+	req, err := http.NewRequest("POST", "http://example.com", bytes.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Encoding", "gzip")
+	require.NoError(t, DecodeRequest(req))
+	data, err := io.ReadAll(req.Body)
+	require.NoError(t, err)
+	require.Equal(t, `{"a": 1}`, string(data))
+	require.Equal(t, int64(8), req.ContentLength)
+	require.Empty(t, req.Header.Get("Content-Encoding"))
+
+	for _, coding := range []string{"br", "zstd"} {
+		body, err := Encode(coding, []byte(`{"a": 1}`))
+		require.NoError(t, err)
+		req, err := http.NewRequest("POST", "http://example.com", bytes.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Encoding", coding)
+		require.NoError(t, DecodeRequest(req), coding)
+		data, err := io.ReadAll(req.Body)
+		require.NoError(t, err)
+		require.Equal(t, `{"a": 1}`, string(data), coding)
+	}
+
+	req, err = http.NewRequest("POST", "http://example.com", strings.NewReader("x"))
+	require.NoError(t, err)
+	req.Header.Set("Content-Encoding", "compress")
+	require.ErrorIs(t, DecodeRequest(req), ErrUnsupported)
+}
@@ -0,0 +1,141 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package compat decides which server releases each SDK release line works
+// with, from the protocol versions the server speaks and the server range
+// each line declares in sdks/versioning.json.
+package compat
+
+import (
+	"fmt"
+
+	"github.com/google/test-server/internal/semver"
+)
+
+// Protocol is the version of the contract between the SDKs and the server
+// binary: the CLI modes and flags, the config schema and the recording
+// format. Increment it for changes that would break an SDK driving the
+// server, and record the change in ServerProtocols.
+const Protocol = 1
+
+// ProtocolRange records which server releases speak a protocol version.
+type ProtocolRange struct {
+	Protocol int
+	Since    string // first server release speaking it
+	Until    string // last server release speaking it; empty while still spoken
+}
+
+// ServerProtocols is the history of the protocol versions the server speaks.
+var ServerProtocols = []ProtocolRange{
+	{Protocol: 1, Since: "v0.0.1"},
+}
+
+// Line is an SDK release line (e.g. typescript 0.2.x) as declared in
+// sdks/versioning.json.
+type Line struct {
+	Line      string `json:"line"`
+	Protocol  int    `json:"protocol"`
+	MinServer string `json:"min_server"`
+	MaxServer string `json:"max_server,omitempty"` // empty for no upper bound
+	Supported bool   `json:"supported"`
+}
+
+// Status is the compatibility of one SDK line with one server release.
+type Status string
+
+const (
+	// Compatible means the server is in the line's range and speaks its protocol.
+	Compatible Status = "compatible"
+	// OutOfRange means the line declares the server as unsupported.
+	OutOfRange Status = "out-of-range"
+	// ProtocolUnsupported means the line expects to work with the server,
+	// but the server no longer (or not yet) speaks the line's protocol.
+	ProtocolUnsupported Status = "protocol-unsupported"
+)
+
+// Validate checks that the line's fields are well formed.
+func (l Line) Validate() error {
+	if l.Line == "" {
+		return fmt.Errorf("line name is empty")
+	}
+	if l.Protocol <= 0 {
+		return fmt.Errorf("line %s: protocol must be positive", l.Line)
+	}
+	if _, err := semver.Parse(l.MinServer); err != nil {
+		return fmt.Errorf("line %s: min_server: %w", l.Line, err)
+	}
+	if l.MaxServer != "" {
+		if _, err := semver.Parse(l.MaxServer); err != nil {
+			return fmt.Errorf("line %s: max_server: %w", l.Line, err)
+		}
+	}
+	return nil
+}
+
+// inRange reports whether v is within [since, until]; an empty until is unbounded.
+func inRange(v semver.Version, since, until string) (bool, error) {
+	lo, err := semver.Parse(since)
+	if err != nil {
+		return false, err
+	}
+	if semver.Less(v, lo) {
+		return false, nil
+	}
+	if until == "" {
+		return true, nil
+	}
+	hi, err := semver.Parse(until)
+	if err != nil {
+		return false, err
+	}
+	return !semver.Less(hi, v), nil
+}
+
+// Speaks reports whether the server release speaks protocol according to ranges.
+func Speaks(ranges []ProtocolRange, server semver.Version, protocol int) (bool, error) {
+	for _, r := range ranges {
+		if r.Protocol != protocol {
+			continue
+		}
+		ok, err := inRange(server, r.Since, r.Until)
+		if err != nil {
+			return false, fmt.Errorf("protocol %d: %w", r.Protocol, err)
+		}
+		if ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Check returns the compatibility of line with the server release.
+func Check(ranges []ProtocolRange, line Line, server semver.Version) (Status, error) {
+	ok, err := inRange(server, line.MinServer, line.MaxServer)
+	if err != nil {
+		return "", fmt.Errorf("line %s: %w", line.Line, err)
+	}
+	if !ok {
+		return OutOfRange, nil
+	}
+	speaks, err := Speaks(ranges, server, line.Protocol)
+	if err != nil {
+		return "", err
+	}
+	if !speaks {
+		return ProtocolUnsupported, nil
+	}
+	return Compatible, nil
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package compat
+
+import (
+	"testing"
+
+	"github.com/google/test-server/internal/semver"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheck(t *testing.T) {
+	ranges := []ProtocolRange{
+		{Protocol: 1, Since: "v0.1.0", Until: "v0.3.5"},
+		{Protocol: 2, Since: "v0.3.0"},
+	}
+	line := Line{Line: "0.2", Protocol: 1, MinServer: "v0.2.0", Supported: true}
+	capped := Line{Line: "0.3", Protocol: 2, MinServer: "v0.3.0", MaxServer: "v0.4.0", Supported: true}
+
+	testCases := []struct {
+		name   string
+		line   Line
+		server string
+		want   Status
+	}{
+		{name: "in range", line: line, server: "v0.2.8", want: Compatible},
+		{name: "below min", line: line, server: "v0.1.9", want: OutOfRange},
+		{name: "last release with protocol", line: line, server: "v0.3.5", want: Compatible},
+		{name: "protocol dropped", line: line, server: "v0.3.6", want: ProtocolUnsupported},
+		{name: "max is inclusive", line: capped, server: "v0.4.0", want: Compatible},
+		{name: "above max", line: capped, server: "v0.4.1", want: OutOfRange},
+		{name: "pre-release of min is below min", line: capped, server: "v0.3.0-rc.1", want: OutOfRange},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server, err := semver.Parse(tc.server)
+			require.NoError(t, err)
+			got, err := Check(ranges, tc.line, server)
+			require.NoError(t, err)
+			require.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	testCases := []struct {
+		name    string
+		line    Line
+		wantErr string
+	}{
+		{name: "valid", line: Line{Line: "0.2", Protocol: 1, MinServer: "v0.2.0"}},
+		{name: "no protocol", line: Line{Line: "0.2", MinServer: "v0.2.0"}, wantErr: "protocol must be positive"},
+		{name: "bad min", line: Line{Line: "0.2", Protocol: 1, MinServer: "latest"}, wantErr: "min_server"},
+		{name: "bad max", line: Line{Line: "0.2", Protocol: 1, MinServer: "v0.2.0", MaxServer: "v1"}, wantErr: "max_server"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.line.Validate()
+			if tc.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorContains(t, err, tc.wantErr)
+		})
+	}
+}
+
+func TestServerProtocolsSpeakCurrent(t *testing.T) {
+	// The newest range must cover the protocol this tree implements.
+	latest := ServerProtocols[len(ServerProtocols)-1]
+	require.Equal(t, Protocol, latest.Protocol)
+	require.Empty(t, latest.Until)
+}
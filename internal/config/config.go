@@ -18,6 +18,7 @@ package config
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/spf13/afero"
 	"gopkg.in/yaml.v2"
@@ -32,6 +33,515 @@ type EndpointConfig struct {
 	Health                     string              `yaml:"health"`
 	RedactRequestHeaders       []string            `yaml:"redact_request_headers"`
 	ResponseHeaderReplacements []HeaderReplacement `yaml:"response_header_replacements"`
+	// MatchOn lists what a request must share with a recording to be
+	// replayed from it; see package match. Empty means the whole request.
+	MatchOn []string `yaml:"match_on"`
+	// IgnoreHeaders adds to the headers the headers criterion of MatchOn
+	// ignores.
+	IgnoreHeaders []string `yaml:"ignore_headers"`
+	// QueryMatch tunes the query criterion of MatchOn.
+	QueryMatch QueryMatch `yaml:"query_match"`
+	// Routes change how the requests they match are answered, in every mode;
+	// see package route.
+	Routes []Route `yaml:"routes"`
+	// StubsDir is a directory of YAML or JSON files, each a list of routes
+	// that come after Routes. The endpoint reloads them when they change.
+	StubsDir string `yaml:"stubs_dir"`
+	// CORS makes the endpoint answer CORS preflight requests and add CORS
+	// headers to its responses, for tests running in a browser.
+	CORS *CORS `yaml:"cors"`
+	// OAuth makes the endpoint issue signed tokens like an OAuth 2.0 and
+	// OpenID Connect provider; see package oauth.
+	OAuth *OAuth `yaml:"oauth"`
+	// Seed seeds the random values of the routes, such as the fake data of
+	// templates, so that every run draws the same ones. Unset draws new
+	// ones every run.
+	Seed *int64 `yaml:"seed"`
+	// OpenAPI checks the requests and responses of the endpoint against an
+	// OpenAPI document, reporting the violations in the request journal.
+	OpenAPI *OpenAPI `yaml:"openapi"`
+	// ClientAuth makes the endpoint, served over TLS, verify the
+	// certificates of its clients.
+	ClientAuth *ClientAuth `yaml:"client_auth"`
+	// Listen is where the endpoint listens instead of SourcePort:
+	// unix:<path> for a Unix domain socket.
+	Listen string `yaml:"listen"`
+	// Listeners are more ports or sockets the endpoint listens on, which
+	// share its routes, stubs, journal and recordings.
+	Listeners []Listener `yaml:"listeners"`
+	// BindAddress is the address the ports of the endpoint are bound to: an
+	// IP address or host name, 0.0.0.0 for every IPv4 address, :: for every
+	// IPv6 address, or empty for every address of both.
+	BindAddress string `yaml:"bind_address"`
+	// HTTP3 serves the TLS ports of the endpoint over HTTP/3 as well, on
+	// the UDP ports of the same numbers, and advertises them with Alt-Svc.
+	HTTP3 bool `yaml:"http3"`
+}
+
+// Listener is a port or socket an endpoint listens on.
+type Listener struct {
+	// Port is the port, any free one if 0 and Listen is not set.
+	Port int64 `yaml:"port"`
+	// Listen is unix:<path> for a Unix domain socket instead of a port.
+	Listen string `yaml:"listen"`
+	// TLS serves the listener over TLS.
+	TLS bool `yaml:"tls"`
+	// BindAddress is the address the port is bound to, by default the
+	// bind_address of the endpoint.
+	BindAddress string `yaml:"bind_address"`
+}
+
+// ClientAuth verifies the client certificates of an endpoint served over TLS
+// against a CA.
+type ClientAuth struct {
+	// CA is the PEM file of the CAs that sign the accepted client
+	// certificates, by default the CA test-server generates in the TLS
+	// directory of its data directory, which the client-cert command uses.
+	CA string `yaml:"ca"`
+	// Optional accepts the clients without a certificate, for the routes to
+	// tell them apart with client_cert. A certificate that is sent must
+	// still be valid.
+	Optional bool `yaml:"optional"`
+}
+
+// OpenAPI configures the validation of an endpoint against an OpenAPI
+// document.
+type OpenAPI struct {
+	// Spec is the path of the OpenAPI 3 document, in YAML or JSON.
+	Spec string `yaml:"spec"`
+	// Strict makes test-server exit with status 1 when stopped if any
+	// request or response violated the document.
+	Strict bool `yaml:"strict"`
+}
+
+// CORS configures the CORS headers of an endpoint.
+type CORS struct {
+	// AllowOrigins lists the allowed origins, e.g. http://localhost:8080 or
+	// https://*.example.com, in which * matches within a label of the host
+	// name. Empty or "*" allows every origin.
+	AllowOrigins []string `yaml:"allow_origins"`
+	// AllowMethods defaults to GET, HEAD, POST, PUT, PATCH and DELETE.
+	AllowMethods []string `yaml:"allow_methods"`
+	// AllowHeaders lists the request headers a preflight allows. Empty allows
+	// every requested header.
+	AllowHeaders []string `yaml:"allow_headers"`
+	// ExposeHeaders lists the response headers a page may read.
+	ExposeHeaders    []string `yaml:"expose_headers"`
+	AllowCredentials bool     `yaml:"allow_credentials"`
+	// MaxAge is how long a browser may cache a preflight response, e.g. 10m.
+	MaxAge string `yaml:"max_age"`
+}
+
+// OAuth configures the token and JWKS endpoints of an endpoint.
+type OAuth struct {
+	// TokenPath defaults to /token.
+	TokenPath string `yaml:"token_path"`
+	// JWKSPath defaults to /.well-known/jwks.json.
+	JWKSPath string `yaml:"jwks_path"`
+	// Issuer defaults to the URL the request reached the endpoint at, e.g.
+	// http://localhost:1443.
+	Issuer string `yaml:"issuer"`
+	// ExpiresIn is the lifetime of the tokens, 1h by default.
+	ExpiresIn string `yaml:"expires_in"`
+	// Claims are added to every token and override the default ones.
+	Claims map[string]any `yaml:"claims"`
+	// SigningKey is a PEM file with the RSA private key signing the tokens.
+	// Without it, a key is generated at startup.
+	SigningKey string `yaml:"signing_key"`
+	// KeyID is the kid of the key, derived from the key by default.
+	KeyID string `yaml:"key_id"`
+}
+
+// QueryMatch tunes how the query criterion of match_on compares parameters,
+// which are always percent-decoded first.
+type QueryMatch struct {
+	// Ignore lists the parameters left out of the comparison.
+	Ignore []string `yaml:"ignore"`
+	// Unordered compares the values of a repeated parameter in any order.
+	Unordered bool `yaml:"unordered"`
+	// SplitCommas reads a=x,y as a=x&a=y.
+	SplitCommas bool `yaml:"split_commas"`
+}
+
+// Route selects requests by method and path and says what to do with them.
+type Route struct {
+	// Name identifies the route in the request journal, routes[<index>] by
+	// default.
+	Name string `yaml:"name"`
+	// Method is the HTTP method, or empty for any.
+	Method string `yaml:"method"`
+	// Path is a pattern in which * matches any part of a path segment and
+	// {name} a path parameter, e.g. /v1/models/{model}:generateContent, or
+	// empty for any path.
+	Path string `yaml:"path"`
+	// Body restricts the route to requests whose body satisfies it.
+	Body *BodyMatch `yaml:"body"`
+	// Headers and Query restrict the route to requests whose headers and
+	// query parameters, by name, satisfy these conditions.
+	Headers map[string]ValueMatch `yaml:"headers"`
+	Query   map[string]ValueMatch `yaml:"query"`
+	// URLRegex restricts the route to requests whose path, followed by the
+	// query if there is one, e.g. /v1/items?page=2, fully matches this
+	// regular expression.
+	URLRegex  string     `yaml:"url_regex"`
+	Fault     *Fault     `yaml:"fault"`
+	Delay     *Delay     `yaml:"delay"`
+	RateLimit *RateLimit `yaml:"rate_limit"`
+	Auth      *Auth      `yaml:"auth"`
+	// ClientCert restricts the route to requests whose TLS client
+	// certificate has attributes satisfying these conditions: common_name,
+	// organization, organizational_unit, serial_number (in hex), issuer,
+	// dns_name, email, uri or fingerprint (the SHA-256 of the certificate, in
+	// hex). A request without a certificate has none of the attributes.
+	ClientCert map[string]ValueMatch `yaml:"client_cert"`
+	// Upload makes the route emulate the resumable upload protocol of Google
+	// APIs, answering the finished upload with Response if it is set.
+	Upload *Upload `yaml:"upload"`
+	// Batch splits a multipart/mixed batch request into the requests of its
+	// application/http parts, answers each as a request to the endpoint and
+	// answers the batch with their responses in a multipart/mixed body.
+	Batch bool `yaml:"batch"`
+	// Redirect answers with a chain of redirects first.
+	Redirect *Redirect `yaml:"redirect"`
+	// Webhooks are sent once the request is answered.
+	Webhooks []Webhook `yaml:"webhooks"`
+	// LongPoll holds the requests open before answering them.
+	LongPoll *LongPoll `yaml:"long_poll"`
+	// ContentEncoding encodes the responses with gzip, deflate, br or zstd.
+	ContentEncoding string `yaml:"content_encoding"`
+	// Ranges answers the GET requests with a Range header with the parts
+	// of a successful response they ask for.
+	Ranges bool `yaml:"ranges"`
+	// Response answers the request with a stub instead of a recorded or
+	// proxied response.
+	Response *Response `yaml:"response"`
+	// Pages answers with a page of a generated collection instead.
+	Pages *Pages `yaml:"pages"`
+	// Events answers with a stream of Server-Sent Events instead.
+	Events []Event `yaml:"events"`
+	// Generate answers with a large body of generated bytes instead.
+	Generate *Generate `yaml:"generate"`
+
+	// Scenario names a state machine shared by the routes naming it. Every
+	// scenario starts in the state "Started".
+	Scenario string `yaml:"scenario"`
+	// RequiredState makes the route match only while its scenario is in this
+	// state, or in any state if empty.
+	RequiredState string `yaml:"required_state"`
+	// NewState is the state the scenario moves to when the route matches.
+	NewState string `yaml:"new_state"`
+
+	// Priority orders the routes matching a request: the highest wins, and
+	// routes of equal priority keep their order. It defaults to 0.
+	Priority int `yaml:"priority"`
+	// Weight makes the choice among matching routes of equal priority random,
+	// in proportion to their weights, if any of them has one. A route without
+	// a weight then weighs 1.
+	Weight *float64 `yaml:"weight"`
+}
+
+// BodyMatch is a predicate on a request body; see package match. It sets
+// exactly one of JSONPath, XPath, Regex, All or Any.
+type BodyMatch struct {
+	JSONPath string `yaml:"json_path"`
+	XPath    string `yaml:"xpath"`
+	// Equals and Contains test the values JSONPath or XPath selects. Without
+	// them, the path must select something.
+	Equals   any `yaml:"equals"`
+	Contains any `yaml:"contains"`
+	// Regex is a regular expression the raw body must match.
+	Regex string `yaml:"regex"`
+	// All and Any combine predicates.
+	All []BodyMatch `yaml:"all"`
+	Any []BodyMatch `yaml:"any"`
+	// Part tests the parts of a multipart body.
+	Part *PartMatch `yaml:"part"`
+}
+
+// ValueMatch holds when a header or query parameter has a value satisfying
+// every condition that is set, or is present if none is. A string is short
+// for Equals.
+type ValueMatch struct {
+	// Present only requires the header or parameter to be present, with any
+	// value.
+	Present  bool   `yaml:"present"`
+	Equals   string `yaml:"equals"`
+	Contains string `yaml:"contains"`
+	// Regex is a regular expression the whole value must match.
+	Regex string `yaml:"regex"`
+	// Absent requires the header or parameter to be missing instead.
+	Absent bool `yaml:"absent"`
+}
+
+// UnmarshalYAML reads a ValueMatch or a string.
+func (v *ValueMatch) UnmarshalYAML(unmarshal func(any) error) error {
+	var equals string
+	if err := unmarshal(&equals); err == nil {
+		*v = ValueMatch{Equals: equals}
+		return nil
+	}
+	type plain ValueMatch
+	return unmarshal((*plain)(v))
+}
+
+// PartMatch holds when a part of a multipart body, e.g. multipart/form-data or
+// a multipart/mixed batch, satisfies every condition that is set.
+type PartMatch struct {
+	// Name is the form field name of the part.
+	Name string `yaml:"name"`
+	// Index is the position of the part from 0.
+	Index *int `yaml:"index"`
+	// ContentType is the media type of the part, without parameters.
+	ContentType string `yaml:"content_type"`
+	// Method and Path test the request in an application/http part of a
+	// batch. Path is a pattern as in path.Match.
+	Method string `yaml:"method"`
+	Path   string `yaml:"path"`
+	// Body tests the body of the part, or of its request for an
+	// application/http part.
+	Body *BodyMatch `yaml:"body"`
+}
+
+// Response is a stub response.
+type Response struct {
+	// Status defaults to 200.
+	Status  int               `yaml:"status"`
+	Headers map[string]string `yaml:"headers"`
+	Body    string            `yaml:"body"`
+	// BodyBase64 is a binary body, e.g. an image or a protobuf message, in
+	// standard base64 instead of Body.
+	BodyBase64 string `yaml:"body_base64"`
+	// BodyFile is a file, relative to the working directory, whose content
+	// is the body instead of Body. It is read at startup.
+	BodyFile string `yaml:"body_file"`
+	// Template makes Body and the header values Go templates with access to
+	// the request; see package route. Binary bodies are never templates.
+	Template bool `yaml:"template"`
+	// ETag adds an ETag computed from the body, unless Headers set one, and
+	// answers a GET with a matching If-None-Match with 304.
+	ETag bool `yaml:"etag"`
+	// LastModified is the Last-Modified time in RFC 3339, answering a GET
+	// with an If-Modified-Since that is not older with 304.
+	LastModified string `yaml:"last_modified"`
+	// Chunks streams the body in parts instead of Body, with chunked
+	// transfer encoding.
+	Chunks []Chunk `yaml:"chunks"`
+}
+
+// Generate is a body of deterministic pseudo-random bytes, streamed without
+// being held in memory, to test large downloads.
+type Generate struct {
+	// Size is the length of the body in bytes, with an optional unit, e.g.
+	// 1048576, 5GB or 512MiB.
+	Size string `yaml:"size"`
+	// Seed selects the bytes: the same seed always gives the same body.
+	Seed int64 `yaml:"seed"`
+	// Rate limits the body to this many bytes per second, e.g. 10MB, or
+	// sends it as fast as possible if empty.
+	Rate string `yaml:"rate"`
+	// ContentType defaults to application/octet-stream.
+	ContentType string `yaml:"content_type"`
+}
+
+// Chunk is a part of a streamed stub response.
+type Chunk struct {
+	Data string `yaml:"data"`
+	// Delay is waited for before the chunk is sent and flushed, e.g. 200ms.
+	Delay string `yaml:"delay"`
+}
+
+// Fault replaces or breaks the response to a request.
+type Fault struct {
+	// Status answers with this status code and an error body instead of the
+	// response.
+	Status int `yaml:"status"`
+	// Preset answers with a built-in cloud API error, e.g. quota_exceeded;
+	// see route.Presets.
+	Preset string `yaml:"preset"`
+	// MalformedJSON answers with a body that is not valid JSON, with Status
+	// or 200.
+	MalformedJSON bool `yaml:"malformed_json"`
+	// Reset resets the connection without answering.
+	Reset bool `yaml:"reset"`
+	// TruncateAfter cuts the connection after this many bytes of the body
+	// of the response.
+	TruncateAfter *int64 `yaml:"truncate_after"`
+	// Probability is the fraction of the matching requests the fault applies
+	// to; unset means all of them.
+	Probability *float64 `yaml:"probability"`
+}
+
+// Redirect makes a route answer with redirects. Every hop but the last
+// redirects to the requested URL with a test_server_hop query parameter
+// counting the hops, and the last one to Location or, without it, to the
+// requested URL, which is then answered as usual.
+type Redirect struct {
+	// Status is 301, 302, 303, 307 or 308; 302 by default.
+	Status int `yaml:"status"`
+	// Location is a template with the data of stub templates, e.g. an URL
+	// on another endpoint for a cross-origin hop.
+	Location string `yaml:"location"`
+	// Hops is the length of the chain, 1 by default.
+	Hops int `yaml:"hops"`
+	// Loop redirects forever instead.
+	Loop bool `yaml:"loop"`
+}
+
+// Webhook is an outbound HTTP call a route makes after answering a request.
+// URL, the header values and Body are templates with the data of stub
+// templates, rendered for the request.
+type Webhook struct {
+	URL string `yaml:"url"`
+	// Method defaults to POST.
+	Method  string            `yaml:"method"`
+	Headers map[string]string `yaml:"headers"`
+	Body    string            `yaml:"body"`
+	// Delay is waited for before the call, e.g. 1s.
+	Delay string `yaml:"delay"`
+	// Retries resend the call after a network error, 429 or 5xx, waiting
+	// RetryDelay, 1s by default, and twice as long before each next retry.
+	Retries    int    `yaml:"retries"`
+	RetryDelay string `yaml:"retry_delay"`
+}
+
+// LongPoll holds requests open until a timeout or a trigger, like a long
+// polling API waiting for a change.
+type LongPoll struct {
+	// Hold is how long a request is held, e.g. 30s. Without it, requests
+	// are held until the trigger fires.
+	Hold string `yaml:"hold"`
+	// Trigger names the trigger that answers the held requests at once when
+	// POST /__admin/triggers/<name> is called.
+	Trigger string `yaml:"trigger"`
+	// TimeoutResponse answers the requests held for Hold without a trigger,
+	// instead of the usual answer of the route.
+	TimeoutResponse *Response `yaml:"timeout_response"`
+}
+
+// Event is a Server-Sent Event. A reconnecting client that sends the ID of an
+// event as Last-Event-ID gets the events after it.
+type Event struct {
+	ID    string `yaml:"id"`
+	Event string `yaml:"event"`
+	// Data is sent as one data line per line.
+	Data string `yaml:"data"`
+	// Retry is the reconnection time the event sets, e.g. 2s.
+	Retry string `yaml:"retry"`
+	// Delay is waited for before the event is sent.
+	Delay string `yaml:"delay"`
+	// Disconnect drops the connection after the event, without ending the
+	// response.
+	Disconnect bool `yaml:"disconnect"`
+}
+
+// Pages serves a generated collection of Total items a page at a time, like
+// the list methods of Google APIs: the pageSize query parameter, or PageSize,
+// sets the size of a page, and each page but the last has a nextPageToken to
+// send as the pageToken of the next request.
+type Pages struct {
+	Total int `yaml:"total"`
+	// PageSize is the size of a page without pageSize, 10 by default.
+	PageSize int `yaml:"page_size"`
+	// MaxPageSize caps pageSize, if set.
+	MaxPageSize int `yaml:"max_page_size"`
+	// ItemsField names the list in the response, "items" by default.
+	ItemsField string `yaml:"items_field"`
+	// Token is the format of the page tokens: "offset", the index of the
+	// first item of the page, "page", the number of the page from 1, or
+	// "opaque", an offset encoded like a real token. The default is opaque.
+	Token string `yaml:"token"`
+	// Item is the template of an item in JSON, with the data of stub
+	// templates plus .Index, the position of the item from 0.
+	Item string `yaml:"item"`
+}
+
+// Upload emulates resumable uploads: a POST with uploadType=resumable starts
+// a session, answered with its URL in the Location header, to which the data
+// is PUT in chunks with Content-Range headers.
+type Upload struct {
+	// Interruptions break every upload as it reaches their offsets.
+	Interruptions []UploadInterruption `yaml:"interruptions"`
+}
+
+// UploadInterruption breaks an upload once, keeping the bytes before Offset.
+type UploadInterruption struct {
+	Offset int64 `yaml:"offset"`
+	// Status is the error status of the interrupted chunk, 503 by default.
+	Status int `yaml:"status"`
+	// Reset resets the connection instead of answering.
+	Reset bool `yaml:"reset"`
+}
+
+// Auth makes a route require credentials, and answer the requests without
+// valid ones with the errors of Google APIs.
+type Auth struct {
+	// APIKeys are the accepted API keys, sent in the X-Goog-Api-Key header or
+	// the key query parameter.
+	APIKeys []string `yaml:"api_keys"`
+	// BearerTokens are the accepted access tokens, sent in the Authorization
+	// header.
+	BearerTokens []string `yaml:"bearer_tokens"`
+	// IssuedTokens accepts the unexpired tokens issued by the oauth token
+	// endpoint of the endpoint.
+	IssuedTokens bool `yaml:"issued_tokens"`
+	// Scopes must all be granted to an issued token.
+	Scopes []string `yaml:"scopes"`
+}
+
+// RateLimit answers 429 Too Many Requests once a client has made Requests
+// requests in the current Window.
+type RateLimit struct {
+	Requests int `yaml:"requests"`
+	// Window is a Go duration, e.g. 1m. Windows are fixed: they start with
+	// the first request of a client and the count resets when they end.
+	Window string `yaml:"window"`
+	// Key tells clients apart: client (the default) by IP address, token by
+	// the Authorization or X-Goog-Api-Key header or the key query parameter,
+	// header:<name> by a header, or global for a single limit.
+	Key string `yaml:"key"`
+}
+
+// Delay slows down the response to a request by a time drawn from a
+// distribution. Durations are Go durations, e.g. 250ms.
+type Delay struct {
+	// Distribution is fixed (the default), uniform, normal or lognormal.
+	Distribution string `yaml:"distribution"`
+	// Duration is the delay of the fixed distribution.
+	Duration string `yaml:"duration"`
+	// Min and Max bound the uniform distribution.
+	Min string `yaml:"min"`
+	Max string `yaml:"max"`
+	// Mean and StdDev define the normal distribution.
+	Mean   string `yaml:"mean"`
+	StdDev string `yaml:"stddev"`
+	// Median and Sigma, the standard deviation of the logarithm, define the
+	// lognormal distribution.
+	Median string  `yaml:"median"`
+	Sigma  float64 `yaml:"sigma"`
+	// Jitter adds a uniform random time between -Jitter and +Jitter.
+	Jitter string `yaml:"jitter"`
+	// ApplyTo is headers (the default) to delay the response headers,
+	// chunks to delay every part of the body after the first, or both.
+	ApplyTo string `yaml:"apply_to"`
+}
+
+// UpstreamURL returns the base URL of the target, e.g. https://example.com:443,
+// for the given scheme family: "http" for plain requests and "ws" for
+// websockets. An empty target_type means https.
+func (e EndpointConfig) UpstreamURL(family string) (string, error) {
+	secure := false
+	switch e.TargetType {
+	case "", "https":
+		secure = true
+	case "http":
+	default:
+		return "", fmt.Errorf("target_type of %s: unsupported value %q, want http or https", e.TargetHost, e.TargetType)
+	}
+	scheme := family
+	if secure {
+		scheme += "s"
+	}
+	return fmt.Sprintf("%s://%s:%d", scheme, e.TargetHost, e.TargetPort), nil
 }
 
 type HeaderReplacement struct {
@@ -41,7 +551,92 @@ type HeaderReplacement struct {
 }
 
 type TestServerConfig struct {
-	Endpoints []EndpointConfig `yaml:"endpoints"`
+	Endpoints   []EndpointConfig  `yaml:"endpoints"`
+	Passthrough PassthroughConfig `yaml:"passthrough"`
+	// Proxy runs a forward proxy in front of the endpoints; see package
+	// proxy.
+	Proxy *ProxyConfig `yaml:"proxy"`
+}
+
+// ProxyConfig is a forward proxy that intercepts the HTTPS traffic to the
+// target hosts of the endpoints, for clients that can be given a proxy but
+// not a base URL.
+type ProxyConfig struct {
+	// Port is the port the proxy listens on.
+	Port int64 `yaml:"port"`
+	// CACert and CAKey are the PEM files of the CA that signs the
+	// certificates of the intercepted hosts, which the clients must trust.
+	// They are generated if CACert does not exist, and default to
+	// test-server-ca.pem and test-server-ca-key.pem.
+	CACert string `yaml:"ca_cert"`
+	CAKey  string `yaml:"ca_key"`
+	// BindAddress is the address the port is bound to, as for an endpoint.
+	BindAddress string `yaml:"bind_address"`
+}
+
+// PassthroughConfig selects the target hosts whose requests without a
+// recording may be proxied live, in replay and auto mode, and those that must
+// be served from recordings. A pattern is a host name, or "*." followed by a
+// domain to match its subdomains.
+type PassthroughConfig struct {
+	// AllowHosts, if set, are the only hosts proxied live, and are proxied
+	// even without replay --passthrough.
+	AllowHosts []string `yaml:"allow_hosts"`
+	// DenyHosts are never proxied live.
+	DenyHosts []string `yaml:"deny_hosts"`
+}
+
+// Allows reports whether requests to host without a recording may be
+// proxied live; enabled says whether passthrough was requested for every host.
+func (p PassthroughConfig) Allows(host string, enabled bool) bool {
+	if matchesHost(p.DenyHosts, host) {
+		return false
+	}
+	if len(p.AllowHosts) > 0 {
+		return matchesHost(p.AllowHosts, host)
+	}
+	return enabled
+}
+
+func matchesHost(patterns []string, host string) bool {
+	host = strings.ToLower(host)
+	for _, p := range patterns {
+		p = strings.ToLower(p)
+		if domain, ok := strings.CutPrefix(p, "*."); ok {
+			if strings.HasSuffix(host, "."+domain) {
+				return true
+			}
+		} else if host == p {
+			return true
+		}
+	}
+	return false
+}
+
+// JSONValue converts a value decoded from YAML, whose maps have keys of any
+// type, into one encoding/json can marshal.
+func JSONValue(v any) any {
+	switch v := v.(type) {
+	case map[any]any:
+		m := make(map[string]any, len(v))
+		for k, e := range v {
+			m[fmt.Sprint(k)] = JSONValue(e)
+		}
+		return m
+	case map[string]any:
+		m := make(map[string]any, len(v))
+		for k, e := range v {
+			m[k] = JSONValue(e)
+		}
+		return m
+	case []any:
+		out := make([]any, len(v))
+		for i, e := range v {
+			out[i] = JSONValue(e)
+		}
+		return out
+	}
+	return v
 }
 
 func ReadConfig(filename string) (*TestServerConfig, error) {
@@ -68,6 +68,28 @@ func TestReadConfigWithFs(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "passthrough hosts",
+			fileContent: `endpoints:
+  - target_host: oauth2.googleapis.com
+    target_port: 443
+    source_port: 1445
+passthrough:
+  allow_hosts:
+    - oauth2.googleapis.com
+  deny_hosts:
+    - "*.aiplatform.googleapis.com"`,
+			filePath: "/test-config.yaml",
+			wantConfig: &TestServerConfig{
+				Endpoints: []EndpointConfig{
+					{TargetHost: "oauth2.googleapis.com", TargetPort: 443, SourcePort: 1445},
+				},
+				Passthrough: PassthroughConfig{
+					AllowHosts: []string{"oauth2.googleapis.com"},
+					DenyHosts:  []string{"*.aiplatform.googleapis.com"},
+				},
+			},
+		},
 		{
 			name:        "non-existent file",
 			fileContent: "",
@@ -117,3 +139,59 @@ func TestReadConfigWithFs(t *testing.T) {
 		})
 	}
 }
+
+func TestUpstreamURL(t *testing.T) {
+	tests := []struct {
+		name       string
+		targetType string
+		family     string
+		want       string
+		wantErr    bool
+	}{
+		{name: "https", targetType: "https", family: "http", want: "https://example.com:8443"},
+		{name: "default is https", targetType: "", family: "http", want: "https://example.com:8443"},
+		{name: "plain http", targetType: "http", family: "http", want: "http://example.com:8443"},
+		{name: "secure websocket", targetType: "https", family: "ws", want: "wss://example.com:8443"},
+		{name: "plain websocket", targetType: "http", family: "ws", want: "ws://example.com:8443"},
+		{name: "unsupported", targetType: "tcp", family: "http", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ep := EndpointConfig{TargetHost: "example.com", TargetPort: 8443, TargetType: tt.targetType}
+			got, err := ep.UpstreamURL(tt.family)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestPassthroughAllows(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  PassthroughConfig
+		host    string
+		enabled bool
+		want    bool
+	}{
+		{name: "no lists follows the flag", host: "api.example.com", enabled: true, want: true},
+		{name: "no lists and no flag", host: "api.example.com"},
+		{name: "allowed without the flag", config: PassthroughConfig{AllowHosts: []string{"oauth2.googleapis.com"}}, host: "oauth2.googleapis.com", want: true},
+		{name: "not allowed despite the flag", config: PassthroughConfig{AllowHosts: []string{"oauth2.googleapis.com"}}, host: "generativelanguage.googleapis.com", enabled: true},
+		{name: "wildcard matches a subdomain", config: PassthroughConfig{AllowHosts: []string{"*.googleapis.com"}}, host: "OAuth2.googleapis.com", want: true},
+		{name: "wildcard does not match the domain", config: PassthroughConfig{AllowHosts: []string{"*.googleapis.com"}}, host: "googleapis.com"},
+		{name: "denied despite the flag", config: PassthroughConfig{DenyHosts: []string{"generativelanguage.googleapis.com"}}, host: "generativelanguage.googleapis.com", enabled: true},
+		{name: "deny wins over allow", config: PassthroughConfig{AllowHosts: []string{"*.googleapis.com"}, DenyHosts: []string{"generativelanguage.googleapis.com"}}, host: "generativelanguage.googleapis.com"},
+		{name: "not denied follows the flag", config: PassthroughConfig{DenyHosts: []string{"generativelanguage.googleapis.com"}}, host: "oauth2.googleapis.com", enabled: true, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.config.Allows(tt.host, tt.enabled))
+		})
+	}
+}
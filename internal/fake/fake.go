@@ -0,0 +1,257 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package fake makes realistic fake values, such as names, emails and
+// addresses, for the templates of stub responses.
+//
+// The values only depend on the random source they are drawn from, so that
+// a seeded source gives the same values on every run.
+package fake
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultLocale is the locale of the values when none is given.
+const DefaultLocale = "en_US"
+
+// locale holds the data of the values of a locale. Names are written
+// "native/ascii" when they are not in the Latin alphabet, and the ascii
+// form makes the emails and user names.
+type locale struct {
+	firstNames []string
+	lastNames  []string
+	// lastFirst puts the last name first in full names.
+	lastFirst bool
+	streets   []string
+	// address formats a street and a house number.
+	address   func(street string, n int) string
+	cities    []string
+	postCode  string // # is a digit
+	phone     string // # is a digit
+	country   string
+	companies []string
+	// company formats a company name from a last name or a noun.
+	company func(name string) string
+	domains []string
+}
+
+var locales = map[string]*locale{
+	"en_US": {
+		firstNames: []string{"James", "Mary", "Robert", "Patricia", "John", "Jennifer", "Michael", "Linda", "David", "Elizabeth", "William", "Barbara", "Richard", "Susan", "Joseph", "Jessica", "Thomas", "Sarah", "Daniel", "Karen"},
+		lastNames:  []string{"Smith", "Johnson", "Williams", "Brown", "Jones", "Garcia", "Miller", "Davis", "Rodriguez", "Martinez", "Hernandez", "Lopez", "Wilson", "Anderson", "Taylor", "Moore", "Jackson", "Martin", "Lee", "Thompson"},
+		streets:    []string{"Main Street", "Oak Avenue", "Maple Drive", "Cedar Lane", "Pine Street", "Elm Street", "Washington Avenue", "Lake Road", "Hill Street", "Park Avenue"},
+		address:    func(s string, n int) string { return fmt.Sprintf("%d %s", n, s) },
+		cities:     []string{"New York", "Los Angeles", "Chicago", "Houston", "Phoenix", "Philadelphia", "San Antonio", "San Diego", "Dallas", "Seattle"},
+		postCode:   "#####",
+		phone:      "(###) 555-####",
+		country:    "United States",
+		companies:  []string{"Systems", "Labs", "Dynamics", "Solutions", "Industries", "Networks"},
+		company:    func(name string) string { return name + " Inc." },
+		domains:    []string{"example.com", "example.org", "example.net"},
+	},
+	"de_DE": {
+		firstNames: []string{"Lukas", "Anna", "Leon", "Lea", "Finn", "Hannah", "Jonas", "Emma", "Paul", "Mia", "Felix", "Sophie", "Maximilian", "Marie", "Elias", "Lena", "Noah", "Laura", "Jürgen", "Jülide"},
+		lastNames:  []string{"Müller", "Schmidt", "Schneider", "Fischer", "Weber", "Meyer", "Wagner", "Becker", "Schulz", "Hoffmann", "Schäfer", "Koch", "Bauer", "Richter", "Klein", "Wolf", "Schröder", "Neumann", "Schwarz", "Zimmermann"},
+		streets:    []string{"Hauptstraße", "Schulstraße", "Gartenstraße", "Bahnhofstraße", "Dorfstraße", "Bergstraße", "Birkenweg", "Lindenstraße", "Kirchstraße", "Waldstraße"},
+		address:    func(s string, n int) string { return fmt.Sprintf("%s %d", s, n) },
+		cities:     []string{"Berlin", "Hamburg", "München", "Köln", "Frankfurt am Main", "Stuttgart", "Düsseldorf", "Leipzig", "Dortmund", "Bremen"},
+		postCode:   "#####",
+		phone:      "+49 30 #######",
+		country:    "Deutschland",
+		companies:  []string{"Technik", "Logistik", "Software", "Maschinenbau", "Handel", "Energie"},
+		company:    func(name string) string { return name + " GmbH" },
+		domains:    []string{"example.de", "example.com"},
+	},
+	"fr_FR": {
+		firstNames: []string{"Gabriel", "Louise", "Léo", "Jade", "Raphaël", "Emma", "Arthur", "Alice", "Louis", "Chloé", "Jules", "Léa", "Adam", "Manon", "Hugo", "Inès", "Lucas", "Camille", "Noé", "Zoé"},
+		lastNames:  []string{"Martin", "Bernard", "Dubois", "Thomas", "Robert", "Richard", "Petit", "Durand", "Leroy", "Moreau", "Simon", "Laurent", "Lefèvre", "Michel", "Garcia", "David", "Bertrand", "Roux", "Vincent", "Fournier"},
+		streets:    []string{"rue de la Paix", "rue Victor Hugo", "avenue des Champs-Élysées", "rue de la République", "boulevard Saint-Michel", "rue du Moulin", "place de l'Église", "rue Pasteur", "avenue Jean Jaurès", "rue des Écoles"},
+		address:    func(s string, n int) string { return fmt.Sprintf("%d %s", n, s) },
+		cities:     []string{"Paris", "Marseille", "Lyon", "Toulouse", "Nice", "Nantes", "Strasbourg", "Montpellier", "Bordeaux", "Lille"},
+		postCode:   "#####",
+		phone:      "+33 1 ## ## ## ##",
+		country:    "France",
+		companies:  []string{"Conseil", "Informatique", "Distribution", "Industries", "Services", "Transports"},
+		company:    func(name string) string { return name + " SARL" },
+		domains:    []string{"example.fr", "example.com"},
+	},
+	"ja_JP": {
+		firstNames: []string{"翔/sho", "蓮/ren", "陽翔/haruto", "大翔/hiroto", "悠真/yuma", "陽菜/hina", "結衣/yui", "さくら/sakura", "美咲/misaki", "葵/aoi", "健太/kenta", "直樹/naoki", "花子/hanako", "太郎/taro", "由美/yumi", "誠/makoto", "愛/ai", "拓海/takumi", "真央/mao", "彩/aya"},
+		lastNames:  []string{"佐藤/sato", "鈴木/suzuki", "高橋/takahashi", "田中/tanaka", "伊藤/ito", "渡辺/watanabe", "山本/yamamoto", "中村/nakamura", "小林/kobayashi", "加藤/kato", "吉田/yoshida", "山田/yamada", "佐々木/sasaki", "山口/yamaguchi", "松本/matsumoto", "井上/inoue", "木村/kimura", "林/hayashi", "清水/shimizu", "山崎/yamazaki"},
+		lastFirst:  true,
+		streets:    []string{"千代田", "丸の内", "銀座", "新宿", "渋谷", "梅田", "栄", "天神", "中央", "本町"},
+		address:    func(s string, n int) string { return fmt.Sprintf("%s%d-%d-%d", s, n%9+1, n%30+1, n%20+1) },
+		cities:     []string{"東京都", "大阪市", "横浜市", "名古屋市", "札幌市", "福岡市", "神戸市", "京都市", "川崎市", "さいたま市"},
+		postCode:   "###-####",
+		phone:      "03-####-####",
+		country:    "日本",
+		companies:  []string{"商事", "電機", "製作所", "工業", "物産", "システム"},
+		company:    func(name string) string { return "株式会社" + name },
+		domains:    []string{"example.jp", "example.com"},
+	},
+}
+
+// Locales returns the locales Value supports.
+func Locales() []string {
+	var out []string
+	for name := range locales {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// words are the words of the text values, in every locale.
+var words = strings.Fields(`lorem ipsum dolor sit amet consectetur adipiscing elit sed do eiusmod
+	tempor incididunt ut labore et dolore magna aliqua enim ad minim veniam quis nostrud exercitation
+	ullamco laboris nisi aliquip ex ea commodo consequat duis aute irure in reprehenderit voluptate velit
+	esse cillum fugiat nulla pariatur excepteur sint occaecat cupidatat non proident sunt culpa qui officia
+	deserunt mollit anim id est laborum`)
+
+// timeRange bounds the times and dates, so that they do not depend on the
+// clock.
+var timeRange = [2]time.Time{
+	time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+	time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+}
+
+// Kinds are the kinds of values Value makes.
+var Kinds = []string{
+	"first_name", "last_name", "name", "username", "email", "phone",
+	"street_address", "city", "postal_code", "country", "company",
+	"word", "sentence", "paragraph", "timestamp", "date", "ipv4", "url",
+}
+
+// Value returns a fake value of kind in locale, the default one if empty,
+// drawn from rng.
+func Value(rng *rand.Rand, kind, localeName string) (string, error) {
+	if localeName == "" {
+		localeName = DefaultLocale
+	}
+	l, ok := locales[localeName]
+	if !ok {
+		return "", fmt.Errorf("unknown locale %q; use one of %s", localeName, strings.Join(Locales(), ", "))
+	}
+	pick := func(list []string) string { return list[rng.Intn(len(list))] }
+	switch kind {
+	case "first_name":
+		return native(pick(l.firstNames)), nil
+	case "last_name":
+		return native(pick(l.lastNames)), nil
+	case "name":
+		first, last := native(pick(l.firstNames)), native(pick(l.lastNames))
+		if l.lastFirst {
+			return last + " " + first, nil
+		}
+		return first + " " + last, nil
+	case "username":
+		return username(rng, l), nil
+	case "email":
+		return username(rng, l) + "@" + pick(l.domains), nil
+	case "phone":
+		return digits(rng, l.phone), nil
+	case "street_address":
+		return l.address(pick(l.streets), 1+rng.Intn(199)), nil
+	case "city":
+		return pick(l.cities), nil
+	case "postal_code":
+		return digits(rng, l.postCode), nil
+	case "country":
+		return l.country, nil
+	case "company":
+		if rng.Intn(2) == 0 {
+			return l.company(native(pick(l.lastNames))), nil
+		}
+		return l.company(native(pick(l.lastNames)) + " " + pick(l.companies)), nil
+	case "word":
+		return pick(words), nil
+	case "sentence":
+		return sentence(rng), nil
+	case "paragraph":
+		s := make([]string, 3+rng.Intn(3))
+		for i := range s {
+			s[i] = sentence(rng)
+		}
+		return strings.Join(s, " "), nil
+	case "timestamp":
+		return randomTime(rng).Format(time.RFC3339), nil
+	case "date":
+		return randomTime(rng).Format(time.DateOnly), nil
+	case "ipv4":
+		// 198.51.100.0/24 is reserved for documentation.
+		return fmt.Sprintf("198.51.100.%d", 1+rng.Intn(254)), nil
+	case "url":
+		return fmt.Sprintf("https://%s/%s", pick(l.domains), pick(words)), nil
+	}
+	return "", fmt.Errorf("unknown kind %q; use one of %s", kind, strings.Join(Kinds, ", "))
+}
+
+// native returns the native form of a name written "native/ascii".
+func native(name string) string {
+	n, _, _ := strings.Cut(name, "/")
+	return n
+}
+
+// asciiReplacer spells the Latin letters of the supported locales in ASCII.
+var asciiReplacer = strings.NewReplacer(
+	"ä", "ae", "ö", "oe", "ü", "ue", "ß", "ss",
+	"é", "e", "è", "e", "ê", "e", "ë", "e", "à", "a", "â", "a", "ç", "c", "î", "i", "ï", "i", "ô", "o", "û", "u",
+)
+
+// ascii returns the ASCII form of a name, in lower case.
+func ascii(name string) string {
+	if _, a, ok := strings.Cut(name, "/"); ok {
+		return a
+	}
+	return asciiReplacer.Replace(strings.ToLower(name))
+}
+
+func username(rng *rand.Rand, l *locale) string {
+	first, last := l.firstNames[rng.Intn(len(l.firstNames))], l.lastNames[rng.Intn(len(l.lastNames))]
+	return fmt.Sprintf("%s.%s%d", ascii(first), ascii(last), rng.Intn(100))
+}
+
+// digits replaces every # of format with a random digit.
+func digits(rng *rand.Rand, format string) string {
+	var b strings.Builder
+	for _, c := range format {
+		if c == '#' {
+			b.WriteByte(byte('0' + rng.Intn(10)))
+			continue
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+func sentence(rng *rand.Rand) string {
+	s := make([]string, 4+rng.Intn(8))
+	for i := range s {
+		s[i] = words[rng.Intn(len(words))]
+	}
+	s[0] = strings.ToUpper(s[0][:1]) + s[0][1:]
+	return strings.Join(s, " ") + "."
+}
+
+func randomTime(rng *rand.Rand) time.Time {
+	span := timeRange[1].Unix() - timeRange[0].Unix()
+	return time.Unix(timeRange[0].Unix()+rng.Int63n(span), 0).UTC()
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"math/rand"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValue(t *testing.T) {
+	for _, locale := range append(Locales(), "") {
+		rng := rand.New(rand.NewSource(1))
+		for _, kind := range Kinds {
+			v, err := Value(rng, kind, locale)
+			require.NoError(t, err, kind, locale)
+			require.NotEmpty(t, v, kind, locale)
+		}
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	email := regexp.MustCompile(`^[a-z]+\.[a-z]+[0-9]+@example\.[a-z]+$`)
+	for range 50 {
+		for _, locale := range Locales() {
+			v, err := Value(rng, "email", locale)
+			require.NoError(t, err)
+			require.Regexp(t, email, v, locale)
+		}
+		v, err := Value(rng, "timestamp", "")
+		require.NoError(t, err)
+		ts, err := time.Parse(time.RFC3339, v)
+		require.NoError(t, err)
+		require.False(t, ts.Before(timeRange[0]) || !ts.Before(timeRange[1]), v)
+	}
+
+	v, err := Value(rand.New(rand.NewSource(1)), "postal_code", "ja_JP")
+	require.NoError(t, err)
+	require.Regexp(t, `^\d{3}-\d{4}$`, v)
+	v, err = Value(rand.New(rand.NewSource(1)), "country", "de_DE")
+	require.NoError(t, err)
+	require.Equal(t, "Deutschland", v)
+}
+
+func TestValueIsDeterministic(t *testing.T) {
+	draw := func(seed int64) []string {
+		rng := rand.New(rand.NewSource(seed))
+		var out []string
+		for _, kind := range Kinds {
+			v, err := Value(rng, kind, "fr_FR")
+			require.NoError(t, err)
+			out = append(out, v)
+		}
+		return out
+	}
+	require.Equal(t, draw(7), draw(7))
+	require.NotEqual(t, draw(7), draw(8))
+}
+
+func TestValueRejectsUnknownKindsAndLocales(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	_, err := Value(rng, "shoe_size", "")
+	require.ErrorContains(t, err, "unknown kind")
+	_, err = Value(rng, "name", "xx_XX")
+	require.ErrorContains(t, err, "unknown locale")
+}
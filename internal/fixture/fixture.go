@@ -0,0 +1,222 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package fixture manages the released server binaries that tests run
+// against, so that test runs never download anything.
+//
+// Dir/fixtures.json pins each version and platform to the SHA-256 of its
+// release archive, as recorded in checksums.json. scripts/pin-fixture
+// downloads the archives into Dir/<sha256>/, next to the extracted binary,
+// and Binary hands out a binary only after checking its archive against the
+// pin again.
+package fixture
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	// Dir is the fixture cache relative to the repository root.
+	Dir = "testdata/bin"
+	// ManifestName is the file in Dir listing the pins.
+	ManifestName = "fixtures.json"
+)
+
+// Pin is the release archive of one version for one platform.
+type Pin struct {
+	Archive string `json:"archive"`
+	SHA256  string `json:"sha256"`
+	Binary  string `json:"binary"` // name of the executable in the archive
+}
+
+// Manifest maps each pinned version to its platforms ("goos/goarch").
+type Manifest struct {
+	Fixtures map[string]map[string]Pin `json:"fixtures"`
+}
+
+// Load reads the manifest in dir. A missing manifest has no pins.
+func Load(dir string) (*Manifest, error) {
+	m := &Manifest{Fixtures: map[string]map[string]Pin{}}
+	path := filepath.Join(dir, ManifestName)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if m.Fixtures == nil {
+		m.Fixtures = map[string]map[string]Pin{}
+	}
+	return m, nil
+}
+
+// Save writes the manifest to dir.
+func (m *Manifest) Save(dir string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, ManifestName), append(data, '\n'), 0644)
+}
+
+// Add pins version on platform, replacing an earlier pin.
+func (m *Manifest) Add(version, platform string, p Pin) {
+	if m.Fixtures[version] == nil {
+		m.Fixtures[version] = map[string]Pin{}
+	}
+	m.Fixtures[version][platform] = p
+}
+
+// Versions returns the pinned versions, sorted.
+func (m *Manifest) Versions() []string {
+	versions := make([]string, 0, len(m.Fixtures))
+	for v := range m.Fixtures {
+		versions = append(versions, v)
+	}
+	sort.Strings(versions)
+	return versions
+}
+
+// Platform returns the key of goos/goarch in the manifest.
+func Platform(goos, goarch string) string {
+	return goos + "/" + goarch
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Cached reports whether the archive of p is in dir and matches the pin.
+func Cached(dir string, p Pin) (bool, error) {
+	sum, err := fileSHA256(filepath.Join(dir, p.SHA256, p.Archive))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if sum != p.SHA256 {
+		return false, fmt.Errorf("cached %s has SHA-256 %s, want %s; delete %s and fetch it again", p.Archive, sum, p.SHA256, filepath.Join(dir, p.SHA256))
+	}
+	_, err = os.Stat(filepath.Join(dir, p.SHA256, p.Binary))
+	return err == nil, nil
+}
+
+// Store verifies archive against p and caches it in dir with its extracted
+// binary.
+func Store(dir string, p Pin, archive []byte) error {
+	sum := sha256.Sum256(archive)
+	if got := hex.EncodeToString(sum[:]); got != p.SHA256 {
+		return fmt.Errorf("checksum mismatch for %s: expected %s, got %s", p.Archive, p.SHA256, got)
+	}
+	binary, err := extract(p.Archive, archive, p.Binary)
+	if err != nil {
+		return err
+	}
+	entry := filepath.Join(dir, p.SHA256)
+	if err := os.MkdirAll(entry, 0755); err != nil {
+		return err
+	}
+	// The binary is written first: an entry whose archive is present is
+	// complete.
+	if err := os.WriteFile(filepath.Join(entry, p.Binary), binary, 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(entry, p.Archive), archive, 0644)
+}
+
+// extract returns the file name from a .tar.gz or .zip archive.
+func extract(archiveName string, data []byte, name string) ([]byte, error) {
+	if strings.HasSuffix(archiveName, ".zip") {
+		zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return nil, err
+		}
+		f, err := zr.Open(name)
+		if err != nil {
+			return nil, fmt.Errorf("%s not found in %s", name, archiveName)
+		}
+		defer f.Close()
+		return io.ReadAll(f)
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("%s not found in %s", name, archiveName)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag == tar.TypeReg && hdr.Name == name {
+			return io.ReadAll(tr)
+		}
+	}
+}
+
+// Binary returns the path of the cached binary of version for goos/goarch,
+// after checking its archive against the pin in dir.
+func Binary(dir, version, goos, goarch string) (string, error) {
+	m, err := Load(dir)
+	if err != nil {
+		return "", err
+	}
+	p, ok := m.Fixtures[version][Platform(goos, goarch)]
+	if !ok {
+		return "", fmt.Errorf("%s is not pinned for %s in %s; run go run ./scripts/pin-fixture %s", version, Platform(goos, goarch), filepath.Join(dir, ManifestName), version)
+	}
+	cached, err := Cached(dir, p)
+	if err != nil {
+		return "", err
+	}
+	if !cached {
+		return "", fmt.Errorf("%s for %s is not in %s; run go run ./scripts/pin-fixture", version, Platform(goos, goarch), dir)
+	}
+	return filepath.Abs(filepath.Join(dir, p.SHA256, p.Binary))
+}
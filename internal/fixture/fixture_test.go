@@ -0,0 +1,130 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fixture
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func tarGz(t *testing.T, name string, content []byte) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "LICENSE", Mode: 0644, Size: 3, Typeflag: tar.TypeReg}))
+	_, err := tw.Write([]byte("MIT"))
+	require.NoError(t, err)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0755, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+	_, err = tw.Write(content)
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	return buf.Bytes()
+}
+
+func zipArchive(t *testing.T, name string, content []byte) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.Create(name)
+	require.NoError(t, err)
+	_, err = w.Write(content)
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func pinFor(archive, binary string, data []byte) Pin {
+	sum := sha256.Sum256(data)
+	return Pin{Archive: archive, SHA256: hex.EncodeToString(sum[:]), Binary: binary}
+}
+
+func TestStoreAndBinary(t *testing.T) {
+	testCases := []struct {
+		name   string
+		goos   string
+		pin    func(t *testing.T) (Pin, []byte)
+		binary string
+	}{
+		{
+			name: "tar.gz",
+			goos: "linux",
+			pin: func(t *testing.T) (Pin, []byte) {
+				data := tarGz(t, "test-server", []byte("linux binary"))
+				return pinFor("test-server_Linux_x86_64.tar.gz", "test-server", data), data
+			},
+			binary: "linux binary",
+		},
+		{
+			name: "zip",
+			goos: "windows",
+			pin: func(t *testing.T) (Pin, []byte) {
+				data := zipArchive(t, "test-server.exe", []byte("windows binary"))
+				return pinFor("test-server_Windows_x86_64.zip", "test-server.exe", data), data
+			},
+			binary: "windows binary",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			pin, data := tc.pin(t)
+			m, err := Load(dir)
+			require.NoError(t, err)
+			m.Add("v0.2.8", Platform(tc.goos, "amd64"), pin)
+			require.NoError(t, m.Save(dir))
+
+			_, err = Binary(dir, "v0.2.8", tc.goos, "amd64")
+			require.ErrorContains(t, err, "is not in")
+			_, err = Binary(dir, "v0.2.8", tc.goos, "arm64")
+			require.ErrorContains(t, err, "is not pinned")
+
+			require.NoError(t, Store(dir, pin, data))
+			path, err := Binary(dir, "v0.2.8", tc.goos, "amd64")
+			require.NoError(t, err)
+			got, err := os.ReadFile(path)
+			require.NoError(t, err)
+			require.Equal(t, tc.binary, string(got))
+
+			// A cache entry that no longer matches its key is refused.
+			require.NoError(t, os.WriteFile(filepath.Join(dir, pin.SHA256, pin.Archive), []byte("tampered"), 0644))
+			_, err = Binary(dir, "v0.2.8", tc.goos, "amd64")
+			require.ErrorContains(t, err, "delete")
+		})
+	}
+}
+
+func TestStoreRejectsMismatch(t *testing.T) {
+	dir := t.TempDir()
+	data := tarGz(t, "test-server", []byte("binary"))
+	pin := pinFor("test-server_Linux_x86_64.tar.gz", "test-server", []byte("something else"))
+	require.ErrorContains(t, Store(dir, pin, data), "checksum mismatch")
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+
+	pin = pinFor("test-server_Linux_x86_64.tar.gz", "other", data)
+	require.ErrorContains(t, Store(dir, pin, data), "other not found")
+}
@@ -0,0 +1,599 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package githubapi is the GitHub REST client of the release tooling: release
+// and asset lookup, asset download and upload, and pull requests.
+//
+// GET responses are cached on disk and revalidated with their ETag, so that
+// polling a release or re-running a script costs no rate limit while nothing
+// changed. Requests are retried on server errors and rate limiting, and list
+// endpoints are followed through every page.
+package githubapi
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/test-server/internal/releaserepo"
+)
+
+const (
+	// APIURL and UploadURL are the GitHub REST endpoints.
+	APIURL    = "https://api.github.com"
+	UploadURL = "https://uploads.github.com"
+
+	apiVersion = "2022-11-28"
+	perPage    = 100
+)
+
+// Client talks to the GitHub API on behalf of one repository.
+type Client struct {
+	Repo releaserepo.Repo
+	// Token authenticates the requests; anonymous requests only see public
+	// data and have a much lower rate limit.
+	Token string
+	// CacheDir holds the cached GET responses. Empty disables the cache.
+	CacheDir string
+	// Retries is how many times a failed request is retried, waiting Backoff,
+	// then twice as long, and so on. Rate limited requests wait until the
+	// limit resets instead.
+	Retries int
+	Backoff time.Duration
+
+	HTTP      *http.Client
+	APIURL    string
+	UploadURL string
+}
+
+// New returns a client for repo, authenticated with Token() and caching in
+// the user cache directory.
+func New(repo releaserepo.Repo) *Client {
+	c := &Client{
+		Repo:      repo,
+		Token:     Token(),
+		Retries:   4,
+		Backoff:   2 * time.Second,
+		HTTP:      &http.Client{Timeout: 5 * time.Minute},
+		APIURL:    APIURL,
+		UploadURL: UploadURL,
+	}
+	if dir, err := os.UserCacheDir(); err == nil {
+		c.CacheDir = filepath.Join(dir, "test-server", "githubapi")
+	}
+	return c
+}
+
+// Token returns GITHUB_TOKEN, GH_TOKEN or the token of the gh CLI login, in
+// that order, or "" when there is none.
+func Token() string {
+	for _, env := range []string{"GITHUB_TOKEN", "GH_TOKEN"} {
+		if t := os.Getenv(env); t != "" {
+			return t
+		}
+	}
+	out, err := exec.Command("gh", "auth", "token").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// Error is an unsuccessful API response.
+type Error struct {
+	Method     string
+	URL        string
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s %s: %d %s: %s", e.Method, e.URL, e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// IsNotFound reports whether err is a 404 response.
+func IsNotFound(err error) bool {
+	var e *Error
+	return errors.As(err, &e) && e.StatusCode == http.StatusNotFound
+}
+
+// Asset is a release asset.
+type Asset struct {
+	ID                 int64     `json:"id"`
+	Name               string    `json:"name"`
+	State              string    `json:"state"`
+	Size               int64     `json:"size"`
+	Digest             string    `json:"digest"`
+	URL                string    `json:"url"`
+	BrowserDownloadURL string    `json:"browser_download_url"`
+	CreatedAt          time.Time `json:"created_at"`
+}
+
+// Release is a GitHub release.
+type Release struct {
+	ID          int64     `json:"id"`
+	TagName     string    `json:"tag_name"`
+	Name        string    `json:"name"`
+	Draft       bool      `json:"draft"`
+	Prerelease  bool      `json:"prerelease"`
+	Body        string    `json:"body"`
+	HTMLURL     string    `json:"html_url"`
+	CreatedAt   time.Time `json:"created_at"`
+	PublishedAt time.Time `json:"published_at"`
+	Assets      []Asset   `json:"assets"`
+}
+
+// Discussion is a GitHub discussion.
+type Discussion struct {
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+// PullRequest is a GitHub pull request.
+type PullRequest struct {
+	Number  int    `json:"number"`
+	State   string `json:"state"`
+	Title   string `json:"title"`
+	HTMLURL string `json:"html_url"`
+	Head    struct {
+		Ref string `json:"ref"`
+	} `json:"head"`
+}
+
+// cacheEntry is a cached GET response.
+type cacheEntry struct {
+	ETag string          `json:"etag"`
+	Next string          `json:"next,omitempty"`
+	Body json.RawMessage `json:"body"`
+}
+
+// cachePath returns where the response to u is cached. The key covers the
+// token, so that a response is never served to another identity.
+func (c *Client) cachePath(u string) string {
+	sum := sha256.Sum256([]byte(c.Token + "\x00" + u))
+	return filepath.Join(c.CacheDir, hex.EncodeToString(sum[:])+".json")
+}
+
+func (c *Client) readCache(u string) *cacheEntry {
+	if c.CacheDir == "" {
+		return nil
+	}
+	data, err := os.ReadFile(c.cachePath(u))
+	if err != nil {
+		return nil
+	}
+	var e cacheEntry
+	if json.Unmarshal(data, &e) != nil || e.ETag == "" {
+		return nil
+	}
+	return &e
+}
+
+func (c *Client) writeCache(u string, e cacheEntry) {
+	if c.CacheDir == "" || e.ETag == "" {
+		return
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	// The cache is an optimisation; failing to write it is not an error.
+	if os.MkdirAll(c.CacheDir, 0700) == nil {
+		os.WriteFile(c.cachePath(u), data, 0600)
+	}
+}
+
+var nextLinkRe = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
+
+// nextPage returns the URL of the next page from the Link header, or "".
+func nextPage(h http.Header) string {
+	m := nextLinkRe.FindStringSubmatch(h.Get("Link"))
+	if m == nil {
+		return ""
+	}
+	return m[1]
+}
+
+// retryAfter returns how long to wait before retrying resp, and whether it
+// can be retried at all.
+func (c *Client) retryAfter(resp *http.Response, attempt int) (time.Duration, bool) {
+	backoff := c.Backoff << attempt
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests,
+		resp.StatusCode == http.StatusForbidden && (resp.Header.Get("Retry-After") != "" || resp.Header.Get("X-RateLimit-Remaining") == "0"):
+		if s, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			return time.Duration(s) * time.Second, true
+		}
+		if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			if wait := time.Until(time.Unix(reset, 0)); wait > 0 {
+				return wait, true
+			}
+		}
+		return backoff, true
+	case resp.StatusCode >= 500:
+		return backoff, true
+	}
+	return 0, false
+}
+
+// do sends the request and returns the successful response; the caller
+// closes its body. body is sent again on every attempt.
+func (c *Client) do(method, u string, header http.Header, body []byte) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		var r io.Reader
+		if body != nil {
+			r = bytes.NewReader(body)
+		}
+		req, err := http.NewRequest(method, u, r)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		c.setHeaders(req)
+		resp, err := c.HTTP.Do(req)
+		if err != nil {
+			if attempt >= c.Retries {
+				return nil, err
+			}
+			time.Sleep(c.Backoff << attempt)
+			continue
+		}
+		if resp.StatusCode < 300 || resp.StatusCode == http.StatusNotModified {
+			return resp, nil
+		}
+		wait, retry := c.retryAfter(resp, attempt)
+		if !retry || attempt >= c.Retries {
+			return nil, c.errorFrom(req, resp)
+		}
+		resp.Body.Close()
+		time.Sleep(wait)
+	}
+}
+
+func (c *Client) setHeaders(req *http.Request) {
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	req.Header.Set("X-GitHub-Api-Version", apiVersion)
+	if req.Header.Get("Accept") == "" {
+		req.Header.Set("Accept", "application/vnd.github+json")
+	}
+}
+
+func (c *Client) errorFrom(req *http.Request, resp *http.Response) error {
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	msg := strings.TrimSpace(string(data))
+	var body struct {
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(data, &body) == nil && body.Message != "" {
+		msg = body.Message
+	}
+	return &Error{Method: req.Method, URL: req.URL.Path, StatusCode: resp.StatusCode, Message: msg}
+}
+
+// get fetches one page of u, revalidating a cached copy by its ETag. It
+// returns the body and the URL of the next page.
+func (c *Client) get(u string) ([]byte, string, error) {
+	header := http.Header{}
+	cached := c.readCache(u)
+	if cached != nil {
+		header.Set("If-None-Match", cached.ETag)
+	}
+	resp, err := c.do(http.MethodGet, u, header, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		return cached.Body, cached.Next, nil
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	next := nextPage(resp.Header)
+	c.writeCache(u, cacheEntry{ETag: resp.Header.Get("ETag"), Next: next, Body: data})
+	return data, next, nil
+}
+
+// getJSON decodes the response to u into out.
+func (c *Client) getJSON(u string, out any) error {
+	data, _, err := c.get(u)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+// getAll decodes every page of the list at u.
+func getAll[T any](c *Client, u string) ([]T, error) {
+	var all []T
+	for u != "" {
+		data, next, err := c.get(u)
+		if err != nil {
+			return nil, err
+		}
+		var page []T
+		if err := json.Unmarshal(data, &page); err != nil {
+			return nil, err
+		}
+		all = append(all, page...)
+		u = next
+	}
+	return all, nil
+}
+
+// send sends a JSON request and decodes the response into out, if not nil.
+func (c *Client) send(method, u string, in, out any) error {
+	var body []byte
+	header := http.Header{}
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return err
+		}
+		header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.do(method, u, header, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if out == nil {
+		_, err = io.Copy(io.Discard, resp.Body)
+		return err
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func (c *Client) repoURL(format string, args ...any) string {
+	return fmt.Sprintf("%s/repos/%s/%s", c.APIURL, c.Repo.Owner, c.Repo.Name) + fmt.Sprintf(format, args...)
+}
+
+// Release returns the published release of tag. Drafts are only found by
+// FindRelease.
+func (c *Client) Release(tag string) (*Release, error) {
+	var r Release
+	if err := c.getJSON(c.repoURL("/releases/tags/%s", url.PathEscape(tag)), &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// Releases returns every release, newest first, including drafts when the
+// token can see them.
+func (c *Client) Releases() ([]Release, error) {
+	return getAll[Release](c, c.repoURL("/releases?per_page=%d", perPage))
+}
+
+// FindRelease returns the release of tag, draft or not.
+func (c *Client) FindRelease(tag string) (*Release, error) {
+	releases, err := c.Releases()
+	if err != nil {
+		return nil, err
+	}
+	for i := range releases {
+		if releases[i].TagName == tag {
+			return &releases[i], nil
+		}
+	}
+	return nil, &Error{Method: http.MethodGet, URL: "/releases", StatusCode: http.StatusNotFound, Message: "no release for " + tag}
+}
+
+// DeleteRelease deletes a release. Its tag is kept; see DeleteTag.
+func (c *Client) DeleteRelease(id int64) error {
+	return c.send(http.MethodDelete, c.repoURL("/releases/%d", id), nil, nil)
+}
+
+// DeleteTag deletes a tag from the repository.
+func (c *Client) DeleteTag(tag string) error {
+	return c.send(http.MethodDelete, c.repoURL("/git/refs/tags/%s", url.PathEscape(tag)), nil, nil)
+}
+
+// Assets returns every asset of the release.
+func (c *Client) Assets(releaseID int64) ([]Asset, error) {
+	return getAll[Asset](c, c.repoURL("/releases/%d/assets?per_page=%d", releaseID, perPage))
+}
+
+// DownloadAsset writes the content of a to w. Unlike the browser download
+// URL, this works for draft releases and private repositories.
+func (c *Client) DownloadAsset(a Asset, w io.Writer) error {
+	resp, err := c.do(http.MethodGet, a.URL, http.Header{"Accept": {"application/octet-stream"}}, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+// Download returns the content of the asset name of the published release of
+// tag. It is found and fetched through the API, so the download is
+// authenticated and retried like every other request.
+func (c *Client) Download(tag, name string) ([]byte, error) {
+	r, err := c.Release(tag)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range r.Assets {
+		if a.Name == name {
+			var buf bytes.Buffer
+			if err := c.DownloadAsset(a, &buf); err != nil {
+				return nil, err
+			}
+			return buf.Bytes(), nil
+		}
+	}
+	return nil, &Error{Method: http.MethodGet, URL: c.repoURL("/releases/tags/%s", url.PathEscape(tag)), StatusCode: http.StatusNotFound, Message: fmt.Sprintf("release %s has no asset %s", tag, name)}
+}
+
+// UploadAsset uploads size bytes from r as the asset name. The upload is not
+// retried, since r cannot be rewound.
+func (c *Client) UploadAsset(releaseID int64, name string, r io.Reader, size int64) (*Asset, error) {
+	u := fmt.Sprintf("%s/repos/%s/%s/releases/%d/assets?name=%s", c.UploadURL, c.Repo.Owner, c.Repo.Name, releaseID, url.QueryEscape(name))
+	req, err := http.NewRequest(http.MethodPost, u, r)
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "application/octet-stream")
+	c.setHeaders(req)
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		return nil, c.errorFrom(req, resp)
+	}
+	defer resp.Body.Close()
+	var a Asset
+	return &a, json.NewDecoder(resp.Body).Decode(&a)
+}
+
+// DeleteAsset deletes a release asset.
+func (c *Client) DeleteAsset(id int64) error {
+	return c.send(http.MethodDelete, c.repoURL("/releases/assets/%d", id), nil, nil)
+}
+
+// PullRequests returns the pull requests from branch in state ("open",
+// "closed" or "all").
+func (c *Client) PullRequests(branch, state string) ([]PullRequest, error) {
+	head := url.QueryEscape(c.Repo.Owner + ":" + branch)
+	return getAll[PullRequest](c, c.repoURL("/pulls?head=%s&state=%s&per_page=%d", head, state, perPage))
+}
+
+// CreatePullRequest opens a pull request from branch into base, or returns
+// the one already open from branch, so that re-runs do not fail.
+func (c *Client) CreatePullRequest(branch, base, title, body string) (*PullRequest, error) {
+	open, err := c.PullRequests(branch, "open")
+	if err != nil {
+		return nil, err
+	}
+	if len(open) > 0 {
+		return &open[0], nil
+	}
+	in := map[string]string{"head": branch, "base": base, "title": title, "body": body}
+	var pr PullRequest
+	if err := c.send(http.MethodPost, c.repoURL("/pulls"), in, &pr); err != nil {
+		return nil, err
+	}
+	return &pr, nil
+}
+
+// ClosePullRequest closes a pull request.
+func (c *Client) ClosePullRequest(number int) error {
+	return c.send(http.MethodPatch, c.repoURL("/pulls/%d", number), map[string]string{"state": "closed"}, nil)
+}
+
+// GraphQL runs a GraphQL query or mutation and decodes its data into out.
+// Discussions have no REST API.
+func (c *Client) GraphQL(query string, variables map[string]any, out any) error {
+	var resp struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	in := map[string]any{"query": query, "variables": variables}
+	if err := c.send(http.MethodPost, c.APIURL+"/graphql", in, &resp); err != nil {
+		return err
+	}
+	if len(resp.Errors) > 0 {
+		msgs := make([]string, len(resp.Errors))
+		for i, e := range resp.Errors {
+			msgs[i] = e.Message
+		}
+		return &Error{Method: http.MethodPost, URL: "/graphql", StatusCode: http.StatusOK, Message: strings.Join(msgs, "; ")}
+	}
+	return json.Unmarshal(resp.Data, out)
+}
+
+const discussionsQuery = `query($owner: String!, $name: String!) {
+  repository(owner: $owner, name: $name) {
+    id
+    discussionCategories(first: 100) { nodes { id name } }
+    discussions(first: 50, orderBy: {field: CREATED_AT, direction: DESC}) { nodes { title url } }
+  }
+}`
+
+const createDiscussionMutation = `mutation($repositoryId: ID!, $categoryId: ID!, $title: String!, $body: String!) {
+  createDiscussion(input: {repositoryId: $repositoryId, categoryId: $categoryId, title: $title, body: $body}) {
+    discussion { title url }
+  }
+}`
+
+// CreateDiscussion starts a discussion in the category named category, or
+// returns a recent one with the same title, so that re-runs do not post twice.
+func (c *Client) CreateDiscussion(category, title, body string) (*Discussion, error) {
+	var q struct {
+		Repository struct {
+			ID                   string `json:"id"`
+			DiscussionCategories struct {
+				Nodes []struct {
+					ID   string `json:"id"`
+					Name string `json:"name"`
+				} `json:"nodes"`
+			} `json:"discussionCategories"`
+			Discussions struct {
+				Nodes []Discussion `json:"nodes"`
+			} `json:"discussions"`
+		} `json:"repository"`
+	}
+	if err := c.GraphQL(discussionsQuery, map[string]any{"owner": c.Repo.Owner, "name": c.Repo.Name}, &q); err != nil {
+		return nil, err
+	}
+	for _, d := range q.Repository.Discussions.Nodes {
+		if d.Title == title {
+			return &d, nil
+		}
+	}
+	var categoryID string
+	for _, cat := range q.Repository.DiscussionCategories.Nodes {
+		if strings.EqualFold(cat.Name, category) {
+			categoryID = cat.ID
+		}
+	}
+	if categoryID == "" {
+		return nil, fmt.Errorf("%s has no discussion category %q; are discussions enabled?", c.Repo, category)
+	}
+	var m struct {
+		CreateDiscussion struct {
+			Discussion Discussion `json:"discussion"`
+		} `json:"createDiscussion"`
+	}
+	vars := map[string]any{"repositoryId": q.Repository.ID, "categoryId": categoryID, "title": title, "body": body}
+	if err := c.GraphQL(createDiscussionMutation, vars, &m); err != nil {
+		return nil, err
+	}
+	return &m.CreateDiscussion.Discussion, nil
+}
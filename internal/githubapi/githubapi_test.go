@@ -0,0 +1,243 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package githubapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/test-server/internal/releaserepo"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	return &Client{
+		Repo:      releaserepo.Production,
+		Token:     "secret",
+		CacheDir:  t.TempDir(),
+		Retries:   2,
+		Backoff:   time.Millisecond,
+		HTTP:      srv.Client(),
+		APIURL:    srv.URL,
+		UploadURL: srv.URL,
+	}
+}
+
+func TestReleaseIsCachedByETag(t *testing.T) {
+	var requests, notModified int
+	var c *Client
+	c = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		require.Equal(t, "/repos/google/test-server/releases/tags/v0.2.8", r.URL.Path)
+		require.Equal(t, "Bearer "+c.Token, r.Header.Get("Authorization"))
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		fmt.Fprint(w, `{"id": 7, "tag_name": "v0.2.8", "assets": [{"id": 1, "name": "a.zip"}]}`)
+	})
+
+	for i := 0; i < 3; i++ {
+		r, err := c.Release("v0.2.8")
+		require.NoError(t, err)
+		require.Equal(t, int64(7), r.ID)
+		require.Equal(t, "a.zip", r.Assets[0].Name)
+	}
+	require.Equal(t, 3, requests)
+	require.Equal(t, 2, notModified)
+
+	// Another token never sees the cached response.
+	c.Token = "other"
+	_, err := c.Release("v0.2.8")
+	require.NoError(t, err)
+	require.Equal(t, 2, notModified)
+}
+
+func TestPagination(t *testing.T) {
+	var srvURL string
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("page") {
+		case "":
+			w.Header().Set("Link", fmt.Sprintf(`<%s/repos/google/test-server/releases?per_page=100&page=2>; rel="next", <%s/x>; rel="last"`, srvURL, srvURL))
+			fmt.Fprint(w, `[{"tag_name": "v0.2.8"}, {"tag_name": "v0.2.7"}]`)
+		case "2":
+			fmt.Fprint(w, `[{"tag_name": "v0.2.6", "draft": true}]`)
+		}
+	})
+	srvURL = c.APIURL
+
+	releases, err := c.Releases()
+	require.NoError(t, err)
+	require.Len(t, releases, 3)
+
+	r, err := c.FindRelease("v0.2.6")
+	require.NoError(t, err)
+	require.True(t, r.Draft)
+
+	_, err = c.FindRelease("v9.9.9")
+	require.True(t, IsNotFound(err))
+}
+
+func TestRetries(t *testing.T) {
+	tests := []struct {
+		name      string
+		failures  int
+		status    int
+		header    map[string]string
+		wantErr   bool
+		wantCalls int
+	}{
+		{name: "server error", failures: 2, status: http.StatusBadGateway, wantCalls: 3},
+		{name: "rate limited", failures: 1, status: http.StatusForbidden, header: map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": "0"}, wantCalls: 2},
+		{name: "gives up", failures: 5, status: http.StatusServiceUnavailable, wantErr: true, wantCalls: 3},
+		{name: "not retried", failures: 1, status: http.StatusNotFound, wantErr: true, wantCalls: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				if calls <= tt.failures {
+					for k, v := range tt.header {
+						w.Header().Set(k, v)
+					}
+					w.WriteHeader(tt.status)
+					fmt.Fprint(w, `{"message": "nope"}`)
+					return
+				}
+				fmt.Fprint(w, `{"id": 1}`)
+			})
+			_, err := c.Release("v0.2.8")
+			if tt.wantErr {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "nope")
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tt.wantCalls, calls)
+		})
+	}
+}
+
+func TestCreatePullRequestReusesOpenOne(t *testing.T) {
+	var created int
+	open := `[]`
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			require.Equal(t, "google:release/checksums-v0.2.9", r.URL.Query().Get("head"))
+			fmt.Fprint(w, open)
+		case http.MethodPost:
+			created++
+			fmt.Fprint(w, `{"number": 42, "state": "open"}`)
+		}
+	})
+
+	pr, err := c.CreatePullRequest("release/checksums-v0.2.9", "main", "title", "body")
+	require.NoError(t, err)
+	require.Equal(t, 42, pr.Number)
+
+	open = `[{"number": 42, "state": "open"}]`
+	pr, err = c.CreatePullRequest("release/checksums-v0.2.9", "main", "title", "body")
+	require.NoError(t, err)
+	require.Equal(t, 42, pr.Number)
+	require.Equal(t, 1, created)
+}
+
+func TestDeleteReleaseAndTag(t *testing.T) {
+	var paths []string
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodDelete, r.Method)
+		paths = append(paths, r.URL.EscapedPath())
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	require.NoError(t, c.DeleteRelease(7))
+	require.NoError(t, c.DeleteTag("v0.2.9-nightly.20261016"))
+	require.Equal(t, []string{
+		"/repos/google/test-server/releases/7",
+		"/repos/google/test-server/git/refs/tags/v0.2.9-nightly.20261016",
+	}, paths)
+}
+
+func TestDownload(t *testing.T) {
+	var srvURL string
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/google/test-server/releases/tags/v0.2.8":
+			fmt.Fprintf(w, `{"id": 7, "tag_name": "v0.2.8", "assets": [{"id": 1, "name": "a.zip", "url": "%s/repos/google/test-server/releases/assets/1"}]}`, srvURL)
+		case "/repos/google/test-server/releases/assets/1":
+			require.Equal(t, "application/octet-stream", r.Header.Get("Accept"))
+			fmt.Fprint(w, "zip bytes")
+		default:
+			t.Errorf("unexpected request %s", r.URL)
+		}
+	})
+	srvURL = c.APIURL
+
+	data, err := c.Download("v0.2.8", "a.zip")
+	require.NoError(t, err)
+	require.Equal(t, "zip bytes", string(data))
+
+	_, err = c.Download("v0.2.8", "b.zip")
+	require.True(t, IsNotFound(err))
+	require.ErrorContains(t, err, "release v0.2.8 has no asset b.zip")
+}
+
+func TestCreateDiscussion(t *testing.T) {
+	var created int
+	existing := `[]`
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/graphql", r.URL.Path)
+		var req struct {
+			Query     string         `json:"query"`
+			Variables map[string]any `json:"variables"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		if strings.HasPrefix(req.Query, "mutation") {
+			created++
+			require.Equal(t, "C2", req.Variables["categoryId"])
+			fmt.Fprint(w, `{"data": {"createDiscussion": {"discussion": {"title": "test-server v0.2.9", "url": "https://example.com/d/1"}}}}`)
+			return
+		}
+		fmt.Fprintf(w, `{"data": {"repository": {"id": "R1",
+			"discussionCategories": {"nodes": [{"id": "C1", "name": "General"}, {"id": "C2", "name": "Announcements"}]},
+			"discussions": {"nodes": %s}}}}`, existing)
+	})
+
+	d, err := c.CreateDiscussion("announcements", "test-server v0.2.9", "body")
+	require.NoError(t, err)
+	require.Equal(t, "https://example.com/d/1", d.URL)
+
+	existing = `[{"title": "test-server v0.2.9", "url": "https://example.com/d/1"}]`
+	d, err = c.CreateDiscussion("announcements", "test-server v0.2.9", "body")
+	require.NoError(t, err)
+	require.Equal(t, "https://example.com/d/1", d.URL)
+	require.Equal(t, 1, created)
+
+	_, err = c.CreateDiscussion("Ideas", "test-server v0.3.0", "body")
+	require.ErrorContains(t, err, `no discussion category "Ideas"`)
+}
@@ -0,0 +1,251 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package har
+
+import (
+	"cmp"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/test-server/internal/store"
+)
+
+// Export returns the interactions of the recordings of paths, each a
+// recording file or a recording directory, as a HAR log written by creator.
+// The body files of the responses are read relative to the directory given,
+// or to the directory of the file given. It also returns warnings about the
+// interactions it leaves out.
+func Export(creator Creator, paths ...string) (*HAR, []string, error) {
+	h := &HAR{Log: Log{Version: "1.2", Creator: creator, Entries: []Entry{}}}
+	var warnings []string
+	for _, path := range paths {
+		files, root, err := recordingFiles(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, file := range files {
+			info, err := os.Stat(file)
+			if err != nil {
+				return nil, nil, err
+			}
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return nil, nil, err
+			}
+			var recording store.RecordFile
+			if err := json.Unmarshal(data, &recording); err != nil {
+				return nil, nil, fmt.Errorf("%s: %w", file, err)
+			}
+			// Recordings do not keep the time of their interactions.
+			started := info.ModTime().UTC().Format(time.RFC3339Nano)
+			for i, interaction := range recording.Interactions {
+				if interaction.Request == nil || interaction.Response == nil {
+					warnings = append(warnings, fmt.Sprintf("%s: interaction %d: skipped, it has no request or response", file, i))
+					continue
+				}
+				entry, err := exportEntry(interaction, root)
+				if err != nil {
+					return nil, nil, fmt.Errorf("%s: interaction %d: %w", file, i, err)
+				}
+				entry.StartedDateTime = started
+				h.Log.Entries = append(h.Log.Entries, *entry)
+			}
+		}
+	}
+	return h, warnings, nil
+}
+
+// recordingFiles returns the recording files of path, and the directory their
+// body files are relative to.
+func recordingFiles(path string) ([]string, string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, "", err
+	}
+	if !info.IsDir() {
+		return []string{path}, filepath.Dir(path), nil
+	}
+	var files []string
+	err = filepath.WalkDir(path, func(p string, d os.DirEntry, err error) error {
+		if err == nil && !d.IsDir() && strings.HasSuffix(p, ".json") {
+			files = append(files, p)
+		}
+		return err
+	})
+	sort.Strings(files)
+	return files, path, err
+}
+
+// exportEntry returns the entry of interaction.
+func exportEntry(interaction *store.RecordInteraction, root string) (*Entry, error) {
+	req, resp := interaction.Request, interaction.Response
+	scheme, defaultPort := "https", int64(443)
+	if req.Protocol == "http" {
+		scheme, defaultPort = "http", 80
+	}
+	host := req.ServerAddress
+	if host == "" {
+		host = "localhost"
+	}
+	if req.Port != 0 && req.Port != defaultPort {
+		host = fmt.Sprintf("%s:%d", host, req.Port)
+	}
+	version := "HTTP/1.1"
+	if fields := strings.Fields(req.Request); len(fields) == 3 {
+		version = fields[2]
+	}
+
+	entry := &Entry{
+		Request: Request{
+			Method:      req.Method,
+			URL:         scheme + "://" + host + req.URL,
+			HTTPVersion: version,
+			Cookies:     []NameValue{},
+			Headers:     nameValues(req.Headers),
+			QueryString: []NameValue{},
+			HeadersSize: -1,
+		},
+		Response: Response{
+			Status:      int(resp.StatusCode),
+			StatusText:  http.StatusText(int(resp.StatusCode)),
+			HTTPVersion: cmp.Or(resp.HTTPVersion, version),
+			Cookies:     []NameValue{},
+			Headers:     nameValues(resp.Headers),
+			HeadersSize: -1,
+		},
+		Timings: Timings{Blocked: -1, DNS: -1, Connect: -1, SSL: -1},
+	}
+	if u, err := url.Parse(req.URL); err == nil {
+		for _, name := range sortedKeys(u.Query()) {
+			for _, value := range u.Query()[name] {
+				entry.Request.QueryString = append(entry.Request.QueryString, NameValue{Name: name, Value: value})
+			}
+		}
+	}
+
+	var reqBody []byte
+	if len(req.Body) > 0 {
+		reqBody = req.Body
+	} else if len(req.BodySegments) > 0 && req.BodySegments[0] != nil {
+		var err error
+		if reqBody, err = json.Marshal(req.BodySegments[0]); err != nil {
+			return nil, err
+		}
+	}
+	if len(reqBody) > 0 {
+		entry.Request.PostData = &PostData{MimeType: req.Headers["Content-Type"], Text: string(reqBody)}
+		entry.Request.BodySize = len(reqBody)
+	}
+
+	body, err := responseBody(resp, req, root)
+	if err != nil {
+		return nil, err
+	}
+	entry.Response.Content = Content{Size: len(body), MimeType: resp.Headers["Content-Type"]}
+	if isText(body) {
+		entry.Response.Content.Text = string(body)
+	} else {
+		entry.Response.Content.Text = base64.StdEncoding.EncodeToString(body)
+		entry.Response.Content.Encoding = "base64"
+	}
+	entry.Response.BodySize = len(body)
+
+	// The segment delays are the only times a recording keeps.
+	for _, value := range resp.SegmentDelays {
+		if d, err := time.ParseDuration(value); err == nil {
+			entry.Timings.Wait += float64(d) / float64(time.Millisecond)
+		}
+	}
+	entry.Time = entry.Timings.Wait
+	return entry, nil
+}
+
+// responseBody returns the body replay sends for resp as the answer to req,
+// before any Content-Encoding.
+func responseBody(resp *store.RecordedResponse, req *store.RecordedRequest, root string) ([]byte, error) {
+	if resp.BodyFile != "" {
+		return os.ReadFile(filepath.Join(root, resp.BodyFile))
+	}
+	if resp.Body != nil {
+		return resp.Body, nil
+	}
+	if len(resp.BodySegments) == 0 {
+		return nil, nil
+	}
+	if !strings.Contains(req.URL, "alt=sse") {
+		return json.Marshal(resp.BodySegments[0])
+	}
+	var body []byte
+	for i, segment := range resp.BodySegments {
+		data, err := json.Marshal(segment)
+		if err != nil {
+			return nil, err
+		}
+		if i < len(resp.Events) {
+			e := resp.Events[i]
+			if e.ID != "" {
+				body = fmt.Appendf(body, "id: %s\n", e.ID)
+			}
+			if e.Event != "" {
+				body = fmt.Appendf(body, "event: %s\n", e.Event)
+			}
+			if e.Retry != 0 {
+				body = fmt.Appendf(body, "retry: %d\n", e.Retry)
+			}
+		}
+		body = fmt.Appendf(body, "data: %s\n\n", data)
+	}
+	return body, nil
+}
+
+// isText reports whether body can be the text of a HAR content as is: UTF-8
+// without control characters other than whitespace.
+func isText(body []byte) bool {
+	for _, b := range body {
+		if b < 0x20 && b != '\t' && b != '\n' && b != '\r' {
+			return false
+		}
+	}
+	return utf8.Valid(body)
+}
+
+// nameValues returns headers as name-value pairs sorted by name.
+func nameValues(headers map[string]string) []NameValue {
+	pairs := []NameValue{}
+	for _, name := range sortedKeys(headers) {
+		pairs = append(pairs, NameValue{Name: name, Value: headers[name]})
+	}
+	return pairs
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
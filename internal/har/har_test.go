@@ -0,0 +1,186 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package har
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/test-server/internal/config"
+	"github.com/google/test-server/internal/redact"
+	"github.com/google/test-server/internal/store"
+	"github.com/stretchr/testify/require"
+)
+
+const capture = `{
+  "log": {
+    "version": "1.2",
+    "creator": {"name": "WebInspector", "version": "537.36"},
+    "entries": [
+      {
+        "startedDateTime": "2025-06-01T10:00:00.000Z",
+        "request": {
+          "method": "POST",
+          "url": "https://api.example.com/v1/items?alt=json",
+          "httpVersion": "h2",
+          "headers": [
+            {"name": ":authority", "value": "api.example.com"},
+            {"name": "content-type", "value": "application/json"},
+            {"name": "authorization", "value": "Bearer s3cret"},
+            {"name": "x-goog-api-key", "value": "key"}
+          ],
+          "postData": {"mimeType": "application/json", "text": "{\"name\": \"a\"}"}
+        },
+        "response": {
+          "status": 201,
+          "headers": [
+            {"name": "content-type", "value": "application/json"},
+            {"name": "content-encoding", "value": "gzip"}
+          ],
+          "content": {"size": 22, "mimeType": "application/json", "text": "{\"id\": 1, \"name\": \"a\"}"}
+        }
+      },
+      {
+        "request": {"method": "GET", "url": "https://cdn.example.com/logo.png", "headers": []},
+        "response": {"status": 200, "headers": [], "content": {"size": 3, "text": "AAEC", "encoding": "base64"}}
+      },
+      {
+        "request": {"method": "GET", "url": "https://api.example.com/v1/items/1/icon", "headers": []},
+        "response": {
+          "status": 200,
+          "headers": [{"name": "Content-Type", "value": "image/png"}],
+          "content": {"size": 3, "mimeType": "image/png", "text": "AAEC", "encoding": "base64"}
+        }
+      },
+      {
+        "request": {"method": "GET", "url": "https://api.example.com/v1/large", "headers": []},
+        "response": {"status": 200, "headers": [], "content": {"size": 1000, "mimeType": "text/plain"}}
+      }
+    ]
+  }
+}`
+
+func TestRecording(t *testing.T) {
+	h, err := Parse([]byte(capture))
+	require.NoError(t, err)
+	require.Equal(t, []string{"api.example.com", "cdn.example.com"}, h.Hosts())
+
+	redactor, err := redact.NewRedact([]string{"s3cret"})
+	require.NoError(t, err)
+	endpoint := config.EndpointConfig{TargetHost: "api.example.com", TargetPort: 443, TargetType: "https", RedactRequestHeaders: []string{"X-Goog-Api-Key"}}
+	file, warnings, err := h.Recording(Options{Endpoint: endpoint, TestName: "items test", Redactor: redactor})
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"entry 3 (GET https://api.example.com/v1/large): the response body was not saved in the HAR file",
+		"skipped 1 entries sent to other hosts than api.example.com",
+	}, warnings)
+	require.Equal(t, "items_test", file.RecordID)
+	require.Len(t, file.Interactions, 3)
+
+	first := file.Interactions[0]
+	require.Equal(t, "POST /v1/items?alt=json HTTP/1.1", first.Request.Request)
+	require.Equal(t, map[string]string{
+		"Content-Type":  "application/json",
+		"Authorization": "Bearer REDACTED",
+		"Test-Name":     "items test",
+	}, first.Request.Headers)
+	require.Equal(t, []map[string]any{{"name": "a"}}, first.Request.BodySegments)
+	require.Equal(t, store.HeadSHA, first.Request.PreviousRequest)
+	require.Equal(t, first.Request.ComputeSum(), first.SHASum)
+	require.Equal(t, int32(201), first.Response.StatusCode)
+	require.Equal(t, "gzip", first.Response.Headers["Content-Encoding"])
+	require.Equal(t, []map[string]any{{"id": 1.0, "name": "a"}}, first.Response.BodySegments)
+
+	// The requests of a test are chained.
+	second := file.Interactions[1]
+	require.Equal(t, first.SHASum, second.Request.PreviousRequest)
+	require.Equal(t, []byte{0, 1, 2}, second.Response.Body)
+
+	// Without a test name, every request is recorded on its own.
+	file, _, err = h.Recording(Options{Endpoint: endpoint})
+	require.NoError(t, err)
+	require.Empty(t, file.RecordID)
+	for _, interaction := range file.Interactions {
+		require.Equal(t, store.HeadSHA, interaction.Request.PreviousRequest)
+	}
+}
+
+func TestExport(t *testing.T) {
+	h, err := Parse([]byte(capture))
+	require.NoError(t, err)
+	endpoint := config.EndpointConfig{TargetHost: "api.example.com", TargetPort: 443, TargetType: "https"}
+	file, _, err := h.Recording(Options{Endpoint: endpoint, TestName: "items"})
+	require.NoError(t, err)
+	file.Interactions = append(file.Interactions, &store.RecordInteraction{
+		Request: &store.RecordedRequest{Method: "POST", URL: "/v1/chat?alt=sse", Request: "POST /v1/chat?alt=sse HTTP/1.1", ServerAddress: "localhost", Port: 8080, Protocol: "http"},
+		Response: &store.RecordedResponse{
+			StatusCode:    200,
+			BodySegments:  []map[string]any{{"text": "a"}, {"text": "b"}},
+			Events:        []store.Event{{ID: "1"}, {ID: "2"}},
+			SegmentDelays: []string{"100ms", "50ms"},
+		},
+	}, &store.RecordInteraction{
+		Request:  &store.RecordedRequest{Method: "GET", URL: "/v1/file", Request: "GET /v1/file HTTP/1.1", ServerAddress: "api.example.com", Port: 443},
+		Response: &store.RecordedResponse{StatusCode: 200, BodyFile: "bodies/file.txt"},
+	}, &store.RecordInteraction{SHASum: "websocket"})
+
+	dir := t.TempDir()
+	data, err := json.Marshal(file)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "items.json"), data, 0644))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "bodies"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "bodies", "file.txt"), []byte("content"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "items.websocket.log"), []byte("ignored"), 0644))
+
+	exported, warnings, err := Export(Creator{Name: "test-server", Version: "v1"}, dir)
+	require.NoError(t, err)
+	require.Len(t, warnings, 1)
+	require.Contains(t, warnings[0], "interaction 5: skipped")
+	require.Equal(t, "1.2", exported.Log.Version)
+	entries := exported.Log.Entries
+	require.Len(t, entries, 5)
+
+	require.Equal(t, "https://api.example.com/v1/items?alt=json", entries[0].Request.URL)
+	require.Equal(t, []NameValue{{Name: "alt", Value: "json"}}, entries[0].Request.QueryString)
+	require.JSONEq(t, `{"name": "a"}`, entries[0].Request.PostData.Text)
+	require.Equal(t, "Created", entries[0].Response.StatusText)
+	require.JSONEq(t, `{"id": 1, "name": "a"}`, entries[0].Response.Content.Text)
+	require.Equal(t, Content{Size: 3, MimeType: "image/png", Text: "AAEC", Encoding: "base64"}, entries[1].Response.Content)
+	require.NotEmpty(t, entries[0].StartedDateTime)
+
+	stream := entries[3]
+	require.Equal(t, "http://localhost:8080/v1/chat?alt=sse", stream.Request.URL)
+	require.Equal(t, "id: 1\ndata: {\"text\":\"a\"}\n\nid: 2\ndata: {\"text\":\"b\"}\n\n", stream.Response.Content.Text)
+	require.Equal(t, 150.0, stream.Time)
+	require.Equal(t, "content", entries[4].Response.Content.Text)
+
+	// An exported recording imports back to the same interactions.
+	data, err = json.Marshal(&HAR{Log: Log{Entries: entries[:3]}})
+	require.NoError(t, err)
+	h, err = Parse(data)
+	require.NoError(t, err)
+	again, _, err := h.Recording(Options{Endpoint: endpoint, TestName: "items"})
+	require.NoError(t, err)
+	for i, interaction := range again.Interactions {
+		require.Equal(t, file.Interactions[i].SHASum, interaction.SHASum)
+	}
+
+	_, err = Parse([]byte(`{"entries": []}`))
+	require.Error(t, err)
+}
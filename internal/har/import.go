@@ -0,0 +1,170 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package har
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/google/test-server/internal/config"
+	"github.com/google/test-server/internal/redact"
+	"github.com/google/test-server/internal/store"
+)
+
+// Options tune how the entries of a HAR file become a recording.
+type Options struct {
+	// Endpoint is the endpoint that replays the recording. Only the entries
+	// sent to its target host are imported, and its redact_request_headers
+	// are removed from them.
+	Endpoint config.EndpointConfig
+	// TestName, if set, is added as the Test-Name header of every request,
+	// which makes the recording the one of that test.
+	TestName string
+	// Redactor redacts secrets from the requests, as when recording.
+	Redactor *redact.Redact
+}
+
+// Recording returns the entries of h as the recording test-server would have
+// made of them, with warnings about what it leaves out.
+func (h *HAR) Recording(opts Options) (*store.RecordFile, []string, error) {
+	var warnings []string
+	file := &store.RecordFile{Interactions: []*store.RecordInteraction{}}
+	prev := store.HeadSHA
+	skipped := 0
+	for i, e := range h.Log.Entries {
+		u, err := url.Parse(e.Request.URL)
+		if err != nil {
+			return nil, nil, fmt.Errorf("entry %d: %w", i, err)
+		}
+		if !strings.EqualFold(u.Hostname(), opts.Endpoint.TargetHost) {
+			skipped++
+			continue
+		}
+		req, err := e.Request.httpRequest(u)
+		if err != nil {
+			return nil, nil, fmt.Errorf("entry %d: %w", i, err)
+		}
+		if opts.TestName != "" {
+			req.Header.Set("Test-Name", opts.TestName)
+		}
+		recReq, err := store.NewRecordedRequest(req, prev, opts.Endpoint)
+		if err != nil {
+			return nil, nil, fmt.Errorf("entry %d: %w", i, err)
+		}
+		recReq.RedactHeaders(opts.Endpoint.RedactRequestHeaders)
+		opts.Redactor.Headers(recReq.Headers)
+		recReq.Request = opts.Redactor.String(recReq.Request)
+		recReq.URL = opts.Redactor.String(recReq.URL)
+		for j, segment := range recReq.BodySegments {
+			recReq.BodySegments[j] = opts.Redactor.Map(segment)
+		}
+		fileName, err := recReq.GetRecordingFileName()
+		if err != nil {
+			return nil, nil, fmt.Errorf("entry %d: %w", i, err)
+		}
+		if opts.TestName != "" {
+			file.RecordID = fileName
+		}
+
+		resp, warned, err := e.Response.recorded()
+		if err != nil {
+			return nil, nil, fmt.Errorf("entry %d: %w", i, err)
+		}
+		for _, w := range warned {
+			warnings = append(warnings, fmt.Sprintf("entry %d (%s %s): %s", i, e.Request.Method, e.Request.URL, w))
+		}
+
+		// The requests are chained like the recorder chains them.
+		shaSum := recReq.ComputeSum()
+		if fileName != shaSum {
+			prev = shaSum
+		}
+		file.Interactions = append(file.Interactions, &store.RecordInteraction{
+			Request:  recReq,
+			SHASum:   shaSum,
+			Response: resp,
+		})
+	}
+	if skipped > 0 {
+		warnings = append(warnings, fmt.Sprintf("skipped %d entries sent to other hosts than %s", skipped, opts.Endpoint.TargetHost))
+	}
+	return file, warnings, nil
+}
+
+// httpRequest returns r as the server receives it, with the path of u.
+func (r Request) httpRequest(u *url.URL) (*http.Request, error) {
+	var body []byte
+	if r.PostData != nil {
+		body = []byte(r.PostData.Text)
+		if r.PostData.Text == "" && len(r.PostData.Params) > 0 {
+			form := url.Values{}
+			for _, p := range r.PostData.Params {
+				form.Add(p.Name, p.Value)
+			}
+			body = []byte(form.Encode())
+		}
+	}
+	req, err := http.NewRequest(r.Method, u.RequestURI(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for _, h := range r.Headers {
+		// HTTP/2 pseudo-headers and Host are not headers of the request the
+		// server handles.
+		if strings.HasPrefix(h.Name, ":") || strings.EqualFold(h.Name, "Host") {
+			continue
+		}
+		req.Header.Add(h.Name, h.Value)
+	}
+	return req, nil
+}
+
+// recorded returns r as a recorded response, with warnings about what it
+// leaves out.
+func (r Response) recorded() (*store.RecordedResponse, []string, error) {
+	var warnings []string
+	body := []byte(r.Content.Text)
+	if r.Content.Encoding == "base64" {
+		var err error
+		if body, err = base64.StdEncoding.DecodeString(r.Content.Text); err != nil {
+			return nil, nil, fmt.Errorf("response content: %w", err)
+		}
+	}
+	if len(body) == 0 && r.Content.Size > 0 {
+		warnings = append(warnings, "the response body was not saved in the HAR file")
+	}
+	header := http.Header{}
+	for _, h := range r.Headers {
+		if !strings.HasPrefix(h.Name, ":") {
+			header.Add(h.Name, h.Value)
+		}
+	}
+	// HAR content is already decoded; the recording keeps the coding in
+	// the Content-Encoding header only.
+	decoded := header.Clone()
+	decoded.Del("Content-Encoding")
+	resp, err := store.NewRecordedResponse(&http.Response{StatusCode: r.Status, Header: decoded}, body)
+	if err != nil {
+		return nil, nil, err
+	}
+	resp.Headers = store.GetHeadersMap(&header)
+	return resp, warnings, nil
+}
@@ -0,0 +1,193 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package installercheck statically checks the SDK installers against the
+// contract the release tools assume: the pinned version comes from the
+// generated constants module, checksums.json and platforms.json ship with the
+// SDK, archives are named <project>_<archive><extension> and every download is
+// checked against its SHA-256 digest before it is used.
+//
+// The checks are regular expressions over the source with comment lines
+// removed. They cannot prove an installer correct, but they catch the edits
+// that quietly break an update, such as a hard-coded version or a dropped
+// checksum comparison.
+package installercheck
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Rule is one requirement on a source file.
+type Rule struct {
+	Name    string
+	Pattern *regexp.Regexp
+	// Forbid inverts the rule: the pattern must not match.
+	Forbid bool
+}
+
+// File is a source file and the rules it must follow.
+type File struct {
+	SDK  string
+	Path string // relative to the repository root
+	// CommentPrefixes mark the lines ignored by the rules.
+	CommentPrefixes []string
+	Rules           []Rule
+}
+
+var (
+	jsComments = []string{"//", "/*", "*"}
+	pyComments = []string{"#"}
+	csComments = []string{"//", "/*", "*"}
+)
+
+// Files are every installer source and packaging file that is checked.
+var Files = []File{
+	{
+		SDK:             "TypeScript",
+		Path:            "sdks/typescript/postinstall.js",
+		CommentPrefixes: jsComments,
+		Rules: []Rule{
+			{Name: "imports the generated constants", Pattern: regexp.MustCompile(`require\(['"]\./constants(\.js)?['"]\)`)},
+			{Name: "does not hard-code the version", Pattern: regexp.MustCompile(`TEST_SERVER_VERSION\s*[:=]\s*['"]`), Forbid: true},
+			{Name: "defaults to TEST_SERVER_VERSION", Pattern: regexp.MustCompile(`return TEST_SERVER_VERSION\b`)},
+			{Name: "reads checksums.json", Pattern: regexp.MustCompile(`require\(['"]\./checksums\.json['"]\)`)},
+			{Name: "reads platforms.json", Pattern: regexp.MustCompile(`require\(['"]\./platforms\.json['"]\)`)},
+			{Name: "takes the extension from archive_extensions", Pattern: regexp.MustCompile(`\.archive_extensions\[`)},
+			{Name: "names the archive <project>_<archive><extension>", Pattern: regexp.MustCompile("`\\$\\{PROJECT_NAME\\}_\\$\\{\\w+\\}\\$\\{\\w+\\}`")},
+			{Name: "downloads the release asset of the version", Pattern: regexp.MustCompile(`/releases/download/\$\{version\}/\$\{archiveName\}`)},
+			{Name: "computes the SHA-256 of the archive", Pattern: regexp.MustCompile(`createHash\(['"]sha256['"]\)`)},
+			{Name: "compares it with checksums.json", Pattern: regexp.MustCompile(`actualChecksum\s*!==?\s*expectedChecksum`)},
+			{Name: "refuses yanked versions", Pattern: regexp.MustCompile(`\.yanked\b`)},
+			{Name: "reads cosign.pub", Pattern: regexp.MustCompile(`path\.join\(__dirname, ['"]cosign\.pub['"]\)`)},
+			{Name: "verifies the cosign signature", Pattern: regexp.MustCompile(`crypto\.verify\(['"]sha256['"]`)},
+		},
+	},
+	{
+		SDK:  "TypeScript",
+		Path: "sdks/typescript/package.json",
+		Rules: []Rule{
+			{Name: "runs postinstall.js", Pattern: regexp.MustCompile(`"postinstall":\s*"node postinstall\.js"`)},
+			{Name: "ships constants.js", Pattern: regexp.MustCompile(`"constants\.js"`)},
+			{Name: "ships checksums.json", Pattern: regexp.MustCompile(`"checksums\.json"`)},
+			{Name: "ships platforms.json", Pattern: regexp.MustCompile(`"platforms\.json"`)},
+			{Name: "ships cosign.pub", Pattern: regexp.MustCompile(`"cosign\.pub"`)},
+		},
+	},
+	{
+		SDK:             "Python",
+		Path:            "sdks/python/src/test_server_sdk/install.py",
+		CommentPrefixes: pyComments,
+		Rules: []Rule{
+			{Name: "imports the generated constants", Pattern: regexp.MustCompile(`(?m)^\s*from \._constants import`)},
+			{Name: "does not hard-code the version", Pattern: regexp.MustCompile(`TEST_SERVER_VERSION\s*=\s*['"]`), Forbid: true},
+			{Name: "defaults to TEST_SERVER_VERSION", Pattern: regexp.MustCompile(`return TEST_SERVER_VERSION\b`)},
+			{Name: "reads checksums.json", Pattern: regexp.MustCompile(`"checksums\.json"`)},
+			{Name: "reads platforms.json", Pattern: regexp.MustCompile(`"platforms\.json"`)},
+			{Name: "takes the extension from archive_extensions", Pattern: regexp.MustCompile(`\["archive_extensions"\]`)},
+			{Name: "names the archive <project>_<archive><extension>", Pattern: regexp.MustCompile(`f"\{PROJECT_NAME\}_\{\w+\}\{\w+\}"`)},
+			{Name: "downloads the release asset of the version", Pattern: regexp.MustCompile(`/releases/download/\{version\}/\{archive_name\}`)},
+			{Name: "computes the SHA-256 of the archive", Pattern: regexp.MustCompile(`hashlib\.sha256\(`)},
+			{Name: "compares it with checksums.json", Pattern: regexp.MustCompile(`actual_checksum\s*!=\s*expected_checksum`)},
+			{Name: "refuses yanked versions", Pattern: regexp.MustCompile(`\.get\(["']yanked["']`)},
+			{Name: "reads cosign.pub", Pattern: regexp.MustCompile(`PROJECT_ROOT / "cosign\.pub"`)},
+			{Name: "verifies the cosign signature", Pattern: regexp.MustCompile(`\bverify_file\(`)},
+		},
+	},
+	{
+		SDK:             "Python",
+		Path:            "sdks/python/pyproject.toml",
+		CommentPrefixes: pyComments,
+		Rules: []Rule{
+			{Name: "ships checksums.json as package data", Pattern: regexp.MustCompile(`(?m)^test_server_sdk = \[[^\]]*"checksums\.json"`)},
+			{Name: "ships platforms.json as package data", Pattern: regexp.MustCompile(`(?m)^test_server_sdk = \[[^\]]*"platforms\.json"`)},
+			{Name: "ships cosign.pub as package data", Pattern: regexp.MustCompile(`(?m)^test_server_sdk = \[[^\]]*"cosign\.pub"`)},
+			{Name: "exposes the installer entry point", Pattern: regexp.MustCompile(`"test_server_sdk\.install:\w+"`)},
+		},
+	},
+	{
+		SDK:             "Dotnet",
+		Path:            "sdks/dotnet/BinaryInstaller.cs",
+		CommentPrefixes: csComments,
+		Rules: []Rule{
+			{Name: "uses the generated constants", Pattern: regexp.MustCompile(`TEST_SERVER_VERSION\s*=\s*ServerConstants\.TestServerVersion\s*;`)},
+			{Name: "does not hard-code the version", Pattern: regexp.MustCompile(`TEST_SERVER_VERSION\s*=\s*"`), Forbid: true},
+			{Name: "defaults to TEST_SERVER_VERSION", Pattern: regexp.MustCompile(`string version = TEST_SERVER_VERSION\b`)},
+			{Name: "reads checksums.json", Pattern: regexp.MustCompile(`"TestServerSdk\.checksums\.json"`)},
+			{Name: "reads platforms.json", Pattern: regexp.MustCompile(`"TestServerSdk\.platforms\.json"`)},
+			{Name: "takes the extension from archive_extensions", Pattern: regexp.MustCompile(`GetProperty\("archive_extensions"\)`)},
+			{Name: "names the archive <project>_<archive><extension>", Pattern: regexp.MustCompile(`\$"\{ProjectName\}_\{\w+\}\{\w+\}"`)},
+			{Name: "downloads the release asset of the version", Pattern: regexp.MustCompile(`/releases/download/\{version\}/\{archiveName\}`)},
+			{Name: "computes the SHA-256 of the archive", Pattern: regexp.MustCompile(`SHA256\.Create\(\)`)},
+			{Name: "compares it with checksums.json", Pattern: regexp.MustCompile(`string\.Equals\(actualChecksum,\s*expectedChecksum`)},
+			{Name: "refuses yanked versions", Pattern: regexp.MustCompile(`"yanked"`)},
+			{Name: "reads cosign.pub", Pattern: regexp.MustCompile(`"TestServerSdk\.cosign\.pub"`)},
+			{Name: "verifies the cosign signature", Pattern: regexp.MustCompile(`\.VerifyData\(`)},
+		},
+	},
+	{
+		SDK:  "Dotnet",
+		Path: "sdks/dotnet/TestServerSdk.csproj",
+		Rules: []Rule{
+			{Name: "embeds checksums.json", Pattern: regexp.MustCompile(`<EmbeddedResource Include="checksums\.json"\s*/>`)},
+			{Name: "embeds platforms.json", Pattern: regexp.MustCompile(`<EmbeddedResource Include="platforms\.json"\s*/>`)},
+			{Name: "embeds cosign.pub", Pattern: regexp.MustCompile(`<EmbeddedResource Include="cosign\.pub"\s*/>`)},
+		},
+	},
+}
+
+// stripComments drops the lines that start with one of prefixes.
+func stripComments(src string, prefixes []string) string {
+	lines := strings.Split(src, "\n")
+	kept := lines[:0]
+	for _, l := range lines {
+		trimmed := strings.TrimSpace(l)
+		comment := false
+		for _, p := range prefixes {
+			if strings.HasPrefix(trimmed, p) {
+				comment = true
+				break
+			}
+		}
+		if !comment {
+			kept = append(kept, l)
+		}
+	}
+	return strings.Join(kept, "\n")
+}
+
+// CheckSource returns the names of the rules of f that src breaks.
+func (f File) CheckSource(src []byte) []string {
+	code := stripComments(string(src), f.CommentPrefixes)
+	var failed []string
+	for _, r := range f.Rules {
+		if r.Pattern.MatchString(code) == r.Forbid {
+			failed = append(failed, r.Name)
+		}
+	}
+	return failed
+}
+
+// Check reads f and returns the names of the rules it breaks.
+func (f File) Check() ([]string, error) {
+	src, err := os.ReadFile(f.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", f.Path, err)
+	}
+	return f.CheckSource(src), nil
+}
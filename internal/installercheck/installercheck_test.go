@@ -0,0 +1,125 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package installercheck
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// repoRoot is the repository root seen from this package's directory.
+const repoRoot = "../.."
+
+func findFile(t *testing.T, path string) File {
+	for _, f := range Files {
+		if f.Path == path {
+			return f
+		}
+	}
+	t.Fatalf("no rules for %s", path)
+	return File{}
+}
+
+func TestInstallersFollowTheContract(t *testing.T) {
+	for _, f := range Files {
+		t.Run(f.Path, func(t *testing.T) {
+			src, err := os.ReadFile(filepath.Join(repoRoot, f.Path))
+			require.NoError(t, err)
+			require.Empty(t, f.CheckSource(src))
+		})
+	}
+}
+
+func TestDriftIsDetected(t *testing.T) {
+	testCases := []struct {
+		name     string
+		path     string
+		old, new string
+		want     string
+	}{
+		{
+			name: "hard-coded TypeScript version",
+			path: "sdks/typescript/postinstall.js",
+			old:  "const BIN_DIR",
+			new:  "const TEST_SERVER_VERSION = 'v0.2.8';\nconst BIN_DIR",
+			want: "does not hard-code the version",
+		},
+		{
+			name: "dropped TypeScript checksum comparison",
+			path: "sdks/typescript/postinstall.js",
+			old:  "actualChecksum !== expectedChecksum",
+			new:  "false",
+			want: "compares it with checksums.json",
+		},
+		{
+			name: "Python archive name without the extension",
+			path: "sdks/python/src/test_server_sdk/install.py",
+			old:  `f"{PROJECT_NAME}_{archive_base_name}{archive_extension}"`,
+			new:  `f"{PROJECT_NAME}_{archive_base_name}.tar.gz"`,
+			want: "names the archive <project>_<archive><extension>",
+		},
+		{
+			name: "Python import commented out",
+			path: "sdks/python/src/test_server_sdk/install.py",
+			old:  "    from ._constants import (",
+			new:  "    # from ._constants import (",
+			want: "imports the generated constants",
+		},
+		{
+			name: "hard-coded .NET version",
+			path: "sdks/dotnet/BinaryInstaller.cs",
+			old:  "TEST_SERVER_VERSION = ServerConstants.TestServerVersion;",
+			new:  `TEST_SERVER_VERSION = "v0.2.8";`,
+			want: "uses the generated constants",
+		},
+		{
+			name: "checksums.json no longer embedded",
+			path: "sdks/dotnet/TestServerSdk.csproj",
+			old:  `<EmbeddedResource Include="checksums.json" />`,
+			new:  "",
+			want: "embeds checksums.json",
+		},
+		{
+			name: "signature no longer verified",
+			path: "sdks/python/src/test_server_sdk/install.py",
+			old:  "verify_file(",
+			new:  "print(",
+			want: "verifies the cosign signature",
+		},
+		{
+			name: "constants.js not published",
+			path: "sdks/typescript/package.json",
+			old:  `"constants.js",`,
+			new:  "",
+			want: "ships constants.js",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			f := findFile(t, tc.path)
+			src, err := os.ReadFile(filepath.Join(repoRoot, tc.path))
+			require.NoError(t, err)
+			require.Contains(t, string(src), tc.old)
+			drifted := strings.Replace(string(src), tc.old, tc.new, 1)
+			require.Contains(t, f.CheckSource([]byte(drifted)), tc.want)
+		})
+	}
+}
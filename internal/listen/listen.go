@@ -0,0 +1,626 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package listen serves the endpoints on their source ports or Unix domain
+// sockets, over plain HTTP or TLS, in HTTP/1.1 or HTTP/2, and optionally
+// HTTP/3.
+//
+// An endpoint is served over TLS with --tls, which applies to every
+// endpoint, or with source_type https. The certificate is the one of
+// --tls-cert and --tls-key, or else one for localhost and the addresses the
+// TLS ports are bound to signed by a test CA, both generated into the TLS
+// directory the first time and reused after, so that the clients can trust
+// the CA once. An endpoint with client_auth also
+// verifies the certificates of its clients.
+//
+// An endpoint with http3, or every one with --http3, also serves each of its
+// TLS ports over HTTP/3 on the UDP port of the same number, and advertises it
+// to the clients with the Alt-Svc header of the responses on the TCP port.
+package listen
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/google/test-server/internal/certs"
+	"github.com/google/test-server/internal/config"
+	"github.com/quic-go/quic-go/http3"
+)
+
+// TLSOptions tune the TLS of the endpoints.
+type TLSOptions struct {
+	// All serves every endpoint over TLS, not only those with source_type
+	// https.
+	All bool
+	// CertFile and KeyFile, if set, are the PEM files of the certificate.
+	CertFile, KeyFile string
+	// Dir is the directory of the generated CA and certificate.
+	Dir string
+	// HTTP3 serves every endpoint over HTTP/3 as well, not only those
+	// with http3.
+	HTTP3 bool
+}
+
+// Files of the TLS directory.
+const (
+	CAFile   = "ca.pem"
+	CAKey    = "ca-key.pem"
+	CertFile = "cert.pem"
+	KeyFile  = "key.pem"
+)
+
+// hosts are the names the generated certificate is always valid for.
+var hosts = []string{"localhost", "127.0.0.1", "::1"}
+
+// certHosts are the addresses the TLS ports are bound to, which the
+// generated certificate is valid for as well.
+var (
+	mu         sync.Mutex
+	tlsOptions TLSOptions
+	cert       *tls.Certificate
+	certHosts  []string
+)
+
+// SetTLS sets the TLS options of the endpoints served after.
+func SetTLS(opts TLSOptions) {
+	mu.Lock()
+	defer mu.Unlock()
+	tlsOptions, cert, certHosts = opts, nil, nil
+}
+
+// UsesTLS reports whether ep is served over TLS.
+func UsesTLS(ep *config.EndpointConfig) bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return tlsOptions.All || strings.EqualFold(ep.SourceType, "https")
+}
+
+// UsesHTTP3 reports whether the TLS listeners of ep are served over HTTP/3
+// as well.
+func UsesHTTP3(ep *config.EndpointConfig) bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return tlsOptions.HTTP3 || ep.HTTP3
+}
+
+// Listeners returns the listeners of ep: the one of its source port or listen
+// address first, then its additional listeners, with TLS set on those served
+// over TLS.
+func Listeners(ep *config.EndpointConfig) []config.Listener {
+	all := []config.Listener{{Port: ep.SourcePort, Listen: ep.Listen, TLS: UsesTLS(ep), BindAddress: ep.BindAddress}}
+	mu.Lock()
+	defer mu.Unlock()
+	for _, l := range ep.Listeners {
+		l.TLS = l.TLS || tlsOptions.All
+		if l.BindAddress == "" {
+			l.BindAddress = ep.BindAddress
+		}
+		all = append(all, l)
+	}
+	return all
+}
+
+// Serve serves handler on the listeners of ep until one fails, or until
+// Shutdown stops them, when it returns nil.
+func Serve(ep *config.EndpointConfig, handler http.Handler) error {
+	listeners := Listeners(ep)
+	tlsConfig, err := listenersTLS(ep, listeners)
+	if err != nil {
+		return err
+	}
+	h3 := UsesHTTP3(ep)
+	lns := make([]net.Listener, len(listeners))
+	for i, l := range listeners {
+		ln, err := Listen(l)
+		if err != nil {
+			for _, ln := range lns[:i] {
+				ln.Close()
+			}
+			return err
+		}
+		lns[i] = ln
+	}
+	var conns []net.PacketConn
+	closeAll := func() {
+		for _, ln := range lns {
+			ln.Close()
+		}
+		for _, conn := range conns {
+			conn.Close()
+		}
+	}
+	errs := make(chan error, 2*len(listeners))
+	for i, l := range listeners {
+		handler := handler
+		if h3 && l.TLS && Socket(l) == "" {
+			port := lns[i].Addr().(*net.TCPAddr).Port
+			conn, err := net.ListenPacket(udp(l.BindAddress), net.JoinHostPort(l.BindAddress, strconv.Itoa(port)))
+			if err != nil {
+				closeAll()
+				return fmt.Errorf("http3: %w", err)
+			}
+			conns = append(conns, conn)
+			server := &http3.Server{Handler: handler, TLSConfig: http3.ConfigureTLSConfig(tlsConfig)}
+			go func() { errs <- run(server, func() error { return server.Serve(conn) }) }()
+			handler = altSvc(handler, port)
+		}
+		server := &http.Server{
+			Handler: handler,
+			// HTTP/2 is negotiated over TLS, and spoken in cleartext (h2c) by
+			// the clients that start with its preface.
+			Protocols: new(http.Protocols),
+		}
+		server.Protocols.SetHTTP1(true)
+		server.Protocols.SetHTTP2(true)
+		server.Protocols.SetUnencryptedHTTP2(true)
+		go func(ln net.Listener) {
+			if l.TLS {
+				server.TLSConfig = tlsConfig
+				errs <- run(server, func() error { return server.ServeTLS(ln, "", "") })
+				return
+			}
+			errs <- run(server, func() error { return server.Serve(ln) })
+		}(lns[i])
+	}
+	err = <-errs
+	closeAll()
+	return err
+}
+
+// altSvc advertises HTTP/3 on the UDP port to the clients of handler, in
+// place of the Alt-Svc header of a recorded or proxied response, which
+// names the port of the target host.
+func altSvc(handler http.Handler, port int) http.Handler {
+	value := fmt.Sprintf(`h3=":%d"; ma=86400`, port)
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		handler.ServeHTTP(&altSvcWriter{ResponseWriter: w, value: value}, req)
+	})
+}
+
+// altSvcWriter sets the Alt-Svc header of the response when its header is
+// written.
+type altSvcWriter struct {
+	http.ResponseWriter
+	value string
+	wrote bool
+}
+
+func (a *altSvcWriter) WriteHeader(status int) {
+	if !a.wrote && status >= 200 {
+		a.wrote = true
+		a.Header().Set("Alt-Svc", a.value)
+	}
+	a.ResponseWriter.WriteHeader(status)
+}
+
+func (a *altSvcWriter) Write(data []byte) (int, error) {
+	if !a.wrote {
+		a.WriteHeader(http.StatusOK)
+	}
+	return a.ResponseWriter.Write(data)
+}
+
+func (a *altSvcWriter) Flush() {
+	if !a.wrote {
+		a.WriteHeader(http.StatusOK)
+	}
+	if f, ok := a.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack hands the connection over, e.g. to a WebSocket, without the header.
+func (a *altSvcWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := a.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("the connection cannot be hijacked")
+	}
+	return hj.Hijack()
+}
+
+func (a *altSvcWriter) Unwrap() http.ResponseWriter {
+	return a.ResponseWriter
+}
+
+// server is an HTTP or HTTP/3 server.
+type server interface {
+	Shutdown(ctx context.Context) error
+	Close() error
+}
+
+// servers are the servers Shutdown stops, and stopping is set once it has
+// been called.
+var (
+	serversMu sync.Mutex
+	servers   = map[server]bool{}
+	stopping  bool
+)
+
+// ServeListener serves server on ln until it fails, or until Shutdown stops
+// it, when it returns nil.
+func ServeListener(server *http.Server, ln net.Listener) error {
+	return run(server, func() error { return server.Serve(ln) })
+}
+
+// run calls serve, which serves server, with server registered for Shutdown.
+func run(server server, serve func() error) error {
+	serversMu.Lock()
+	if stopping {
+		// serve then returns at once, closing its listener.
+		server.Close()
+	}
+	servers[server] = true
+	serversMu.Unlock()
+	err := serve()
+	serversMu.Lock()
+	delete(servers, server)
+	serversMu.Unlock()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// Shutdown stops the servers from accepting connections, which removes their
+// Unix domain sockets, and waits for their requests in flight to complete
+// until ctx is done, when it closes the connections left and returns the
+// error of ctx. The hijacked connections, such as those of WebSockets and
+// proxy tunnels, are not waited for. The servers started after are stopped
+// at once.
+func Shutdown(ctx context.Context) error {
+	serversMu.Lock()
+	stopping = true
+	var all []server
+	for server := range servers {
+		all = append(all, server)
+	}
+	serversMu.Unlock()
+	var wg sync.WaitGroup
+	for _, server := range all {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// The errors of closing the listeners that Serve closed already
+			// do not matter.
+			if server.Shutdown(ctx) != nil {
+				server.Close()
+			}
+		}()
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+// listenersTLS returns the TLS configuration of listeners, the listeners of
+// ep, or nil if none is served over TLS, which client_auth and http3 need.
+func listenersTLS(ep *config.EndpointConfig, listeners []config.Listener) (*tls.Config, error) {
+	for _, l := range listeners {
+		if l.TLS {
+			return serverTLS(ep, listeners)
+		}
+	}
+	if ep.ClientAuth != nil {
+		return nil, errors.New("client_auth needs TLS: set source_type to https or pass --tls")
+	}
+	if UsesHTTP3(ep) {
+		return nil, errors.New("http3 needs TLS: set source_type to https or pass --tls")
+	}
+	return nil, nil
+}
+
+// serverTLS returns the TLS configuration of the TLS listeners of ep among
+// listeners.
+func serverTLS(ep *config.EndpointConfig, listeners []config.Listener) (*tls.Config, error) {
+	c, err := certificate(boundHosts(listeners)...)
+	if err != nil {
+		return nil, fmt.Errorf("TLS certificate: %w", err)
+	}
+	config := &tls.Config{Certificates: []tls.Certificate{*c}}
+	if ep.ClientAuth != nil {
+		if config.ClientCAs, err = clientCAs(ep.ClientAuth); err != nil {
+			return nil, fmt.Errorf("client_auth.ca: %w", err)
+		}
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+		if ep.ClientAuth.Optional {
+			config.ClientAuth = tls.VerifyClientCertIfGiven
+		}
+	}
+	return config, nil
+}
+
+// bound are the listeners opened by Bind, by address.
+var (
+	boundMu sync.Mutex
+	bound   = map[string]net.Listener{}
+)
+
+// Bind opens the listeners of ep ahead of Serve, which serves them then, and
+// sets the ports of ep that are 0 to the free ports picked for them. It loads
+// or generates the TLS certificate of ep first, so that the clients find the
+// CA to trust once the ports are known.
+func Bind(ep *config.EndpointConfig) error {
+	if _, err := listenersTLS(ep, Listeners(ep)); err != nil {
+		return err
+	}
+	var opened []net.Listener
+	fail := func(err error) error {
+		for _, ln := range opened {
+			ln.Close()
+		}
+		return err
+	}
+	for i, l := range Listeners(ep) {
+		ln, err := open(l)
+		if err != nil {
+			return fail(err)
+		}
+		opened = append(opened, ln)
+		if addr, ok := ln.Addr().(*net.TCPAddr); ok {
+			l.Port = int64(addr.Port)
+			if i == 0 {
+				ep.SourcePort = l.Port
+			} else {
+				ep.Listeners[i-1].Port = l.Port
+			}
+		}
+		boundMu.Lock()
+		bound[address(l)] = ln
+		boundMu.Unlock()
+	}
+	return nil
+}
+
+// Listen returns the listener of l: the one Bind opened, or else a new one
+// on the Unix domain socket of its listen address if it has one, or on its
+// port.
+func Listen(l config.Listener) (net.Listener, error) {
+	boundMu.Lock()
+	ln, ok := bound[address(l)]
+	delete(bound, address(l))
+	boundMu.Unlock()
+	if ok {
+		return ln, nil
+	}
+	return open(l)
+}
+
+// Open opens a port of ep's own, any free one if port is 0, bound to the
+// bind_address of ep and over TLS if ep is served over TLS, with its
+// client_auth. It returns the listener and the Listener of the port it
+// opened.
+func Open(ep *config.EndpointConfig, port int64) (net.Listener, config.Listener, error) {
+	l := config.Listener{Port: port, TLS: UsesTLS(ep), BindAddress: ep.BindAddress}
+	tlsConfig, err := listenersTLS(ep, []config.Listener{l})
+	if err != nil {
+		return nil, l, err
+	}
+	ln, err := open(l)
+	if err != nil {
+		return nil, l, err
+	}
+	l.Port = int64(ln.Addr().(*net.TCPAddr).Port)
+	if tlsConfig != nil {
+		tlsConfig.NextProtos = []string{"h2", "http/1.1"}
+		ln = tls.NewListener(ln, tlsConfig)
+	}
+	return ln, l, nil
+}
+
+// address identifies the listener of l.
+func address(l config.Listener) string {
+	if l.Listen != "" {
+		return l.Listen
+	}
+	return net.JoinHostPort(l.BindAddress, strconv.FormatInt(l.Port, 10))
+}
+
+// Network returns the network to listen on bindAddress with: tcp4 for an IPv4
+// address, tcp6 for an IPv6 one, which makes :: bind the IPv6 addresses only,
+// or else tcp, which binds both.
+func Network(bindAddress string) string {
+	ip := net.ParseIP(bindAddress)
+	switch {
+	case ip == nil:
+		return "tcp"
+	case ip.To4() != nil:
+		return "tcp4"
+	default:
+		return "tcp6"
+	}
+}
+
+// udp returns the network of the HTTP/3 port bound to bindAddress, like
+// Network.
+func udp(bindAddress string) string {
+	return "udp" + strings.TrimPrefix(Network(bindAddress), "tcp")
+}
+
+// host returns the host to reach a port bound to bindAddress at from the same
+// machine: localhost, which resolves to the IPv4 and IPv6 loopback addresses,
+// for a port bound to both.
+func host(bindAddress string) string {
+	ip := net.ParseIP(bindAddress)
+	switch {
+	case bindAddress == "":
+		return "localhost"
+	case ip == nil || !ip.IsUnspecified():
+		return bindAddress
+	case ip.To4() != nil:
+		return "127.0.0.1"
+	default:
+		return "::1"
+	}
+}
+
+func open(l config.Listener) (net.Listener, error) {
+	if path := Socket(l); path != "" {
+		// The socket of a previous run that did not stop cleanly would fail
+		// the bind.
+		if info, err := os.Stat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+			os.Remove(path)
+		}
+		return net.Listen("unix", path)
+	}
+	if l.Listen != "" {
+		return nil, fmt.Errorf("listen: unsupported address %q, want unix:<path>", l.Listen)
+	}
+	return net.Listen(Network(l.BindAddress), address(l))
+}
+
+// Socket returns the path of the Unix domain socket of l, or "".
+func Socket(l config.Listener) string {
+	path, _ := strings.CutPrefix(l.Listen, "unix:")
+	if path == l.Listen {
+		return ""
+	}
+	return path
+}
+
+// Dial returns the network and address to dial to reach l from the same
+// machine.
+func Dial(l config.Listener) (network, address string) {
+	if path := Socket(l); path != "" {
+		return "unix", path
+	}
+	return "tcp", net.JoinHostPort(host(l.BindAddress), strconv.FormatInt(l.Port, 10))
+}
+
+// BaseURL returns the URL of the root of l from the same machine, on
+// localhost unless l is bound to a single address. The port is left out for a
+// Unix domain socket.
+func BaseURL(l config.Listener) string {
+	scheme := "http"
+	if l.TLS {
+		scheme = "https"
+	}
+	if Socket(l) != "" {
+		return scheme + "://localhost"
+	}
+	return scheme + "://" + net.JoinHostPort(host(l.BindAddress), strconv.FormatInt(l.Port, 10))
+}
+
+// boundHosts returns the addresses the TLS ports of listeners are bound to,
+// apart from those bound to every address, which the clients reach on
+// localhost.
+func boundHosts(listeners []config.Listener) []string {
+	var bound []string
+	for _, l := range listeners {
+		ip := net.ParseIP(l.BindAddress)
+		if !l.TLS || Socket(l) != "" || l.BindAddress == "" || ip != nil && ip.IsUnspecified() {
+			continue
+		}
+		bound = append(bound, l.BindAddress)
+	}
+	return bound
+}
+
+// clientCAs returns the CAs of the client certificates auth accepts, loading
+// or generating the CA of the TLS directory if auth names none.
+func clientCAs(auth *config.ClientAuth) (*x509.CertPool, error) {
+	pool := x509.NewCertPool()
+	if auth.CA != "" {
+		data, err := os.ReadFile(auth.CA)
+		if err != nil {
+			return nil, err
+		}
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("%s: no PEM certificate", auth.CA)
+		}
+		return pool, nil
+	}
+	// The lock keeps the endpoints from generating the CA at the same time.
+	mu.Lock()
+	defer mu.Unlock()
+	dir := tlsOptions.Dir
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	ca, err := certs.LoadCA(filepath.Join(dir, CAFile), filepath.Join(dir, CAKey))
+	if err != nil {
+		return nil, err
+	}
+	pool.AppendCertsFromPEM(ca.PEM)
+	return pool, nil
+}
+
+// certificate returns the certificate of the endpoints, loading or
+// generating it the first time. A generated certificate is replaced by one
+// valid for bound as well if it is not.
+func certificate(bound ...string) (*tls.Certificate, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	added := false
+	for _, host := range bound {
+		if !slices.Contains(certHosts, host) {
+			certHosts, added = append(certHosts, host), true
+		}
+	}
+	opts := tlsOptions
+	provided := opts.CertFile != "" || opts.KeyFile != ""
+	if cert != nil && (!added || provided) {
+		return cert, nil
+	}
+	if provided {
+		if opts.CertFile == "" || opts.KeyFile == "" {
+			return nil, errors.New("--tls-cert and --tls-key go together")
+		}
+		c, err := tls.LoadX509KeyPair(opts.CertFile, opts.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		cert = &c
+		return cert, nil
+	}
+
+	if err := os.MkdirAll(opts.Dir, 0755); err != nil {
+		return nil, err
+	}
+	ca, err := certs.LoadCA(filepath.Join(opts.Dir, CAFile), filepath.Join(opts.Dir, CAKey))
+	if err != nil {
+		return nil, err
+	}
+	valid := append(slices.Clone(hosts), certHosts...)
+	certFile, keyFile := filepath.Join(opts.Dir, CertFile), filepath.Join(opts.Dir, KeyFile)
+	if c, err := tls.LoadX509KeyPair(certFile, keyFile); err == nil && ca.Verify(&c, valid...) {
+		fmt.Printf("Serving TLS with the certificate %s, signed by the CA %s for the clients to trust\n", certFile, filepath.Join(opts.Dir, CAFile))
+		cert = &c
+		return cert, nil
+	}
+	// A missing, expiring or foreign certificate, or one for other
+	// addresses, is replaced.
+	c, err := ca.Certificate(valid...)
+	if err != nil {
+		return nil, err
+	}
+	if err := certs.WriteFiles(c, certFile, keyFile); err != nil {
+		return nil, err
+	}
+	fmt.Printf("Generated the TLS certificate %s, signed by the CA %s for the clients to trust\n", certFile, filepath.Join(opts.Dir, CAFile))
+	cert = c
+	return cert, nil
+}
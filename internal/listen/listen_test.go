@@ -0,0 +1,452 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package listen
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/test-server/internal/certs"
+	"github.com/google/test-server/internal/config"
+	"github.com/quic-go/quic-go/http3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUsesTLS(t *testing.T) {
+	SetTLS(TLSOptions{})
+	require.False(t, UsesTLS(&config.EndpointConfig{SourceType: "http"}))
+	require.True(t, UsesTLS(&config.EndpointConfig{SourceType: "https"}))
+	SetTLS(TLSOptions{All: true})
+	require.True(t, UsesTLS(&config.EndpointConfig{SourceType: "http"}))
+}
+
+func TestCertificate(t *testing.T) {
+	dir := t.TempDir()
+	SetTLS(TLSOptions{Dir: dir})
+	c, err := certificate()
+	require.NoError(t, err)
+	for _, name := range []string{CAFile, CAKey, CertFile, KeyFile} {
+		require.FileExists(t, filepath.Join(dir, name))
+	}
+
+	// The generated certificate is reused by the next runs.
+	SetTLS(TLSOptions{Dir: dir})
+	again, err := certificate()
+	require.NoError(t, err)
+	require.Equal(t, c.Certificate, again.Certificate)
+
+	// A certificate of another CA is replaced.
+	require.NoError(t, os.Remove(filepath.Join(dir, CAFile)))
+	require.NoError(t, os.Remove(filepath.Join(dir, CAKey)))
+	SetTLS(TLSOptions{Dir: dir})
+	replaced, err := certificate()
+	require.NoError(t, err)
+	require.NotEqual(t, c.Certificate, replaced.Certificate)
+
+	// Provided files are used as they are.
+	SetTLS(TLSOptions{CertFile: filepath.Join(dir, CertFile), KeyFile: filepath.Join(dir, KeyFile), Dir: t.TempDir()})
+	provided, err := certificate()
+	require.NoError(t, err)
+	require.Equal(t, replaced.Certificate, provided.Certificate)
+
+	SetTLS(TLSOptions{CertFile: filepath.Join(dir, CertFile)})
+	_, err = certificate()
+	require.ErrorContains(t, err, "go together")
+
+	// The certificate is valid for the addresses the TLS ports are bound
+	// to, and replaced when they change.
+	dir = t.TempDir()
+	SetTLS(TLSOptions{Dir: dir})
+	_, err = certificate()
+	require.NoError(t, err)
+	_, err = serverTLS(&config.EndpointConfig{}, []config.Listener{
+		{TLS: true, BindAddress: "192.0.2.7"},
+		{TLS: true, BindAddress: "0.0.0.0"},
+		{BindAddress: "192.0.2.8"},
+	})
+	require.NoError(t, err)
+	ca, err := certs.LoadCA(filepath.Join(dir, CAFile), filepath.Join(dir, CAKey))
+	require.NoError(t, err)
+	bound, err := tls.LoadX509KeyPair(filepath.Join(dir, CertFile), filepath.Join(dir, KeyFile))
+	require.NoError(t, err)
+	require.True(t, ca.Verify(&bound, "localhost", "::1", "192.0.2.7"))
+	require.False(t, ca.Verify(&bound, "192.0.2.8"))
+}
+
+// start serves handler for ep on a free port and returns the port once it
+// accepts connections.
+func start(t *testing.T, ep *config.EndpointConfig, handler http.Handler) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+	ep.SourcePort = int64(port)
+	go Serve(ep, handler)
+	require.Eventually(t, func() bool {
+		conn, err := net.Dial("tcp", l.Addr().String())
+		if err == nil {
+			conn.Close()
+		}
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+	return strconv.Itoa(port)
+}
+
+// client returns a client that trusts the CA of dir and presents certs.
+func client(t *testing.T, dir string, certs ...tls.Certificate) *http.Client {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, CAFile))
+	require.NoError(t, err)
+	roots := x509.NewCertPool()
+	require.True(t, roots.AppendCertsFromPEM(data))
+	return &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots, Certificates: certs}}}
+}
+
+func get(t *testing.T, c *http.Client, url string) string {
+	t.Helper()
+	resp, err := c.Get(url)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return string(body)
+}
+
+func TestServe(t *testing.T) {
+	dir := t.TempDir()
+	SetTLS(TLSOptions{All: true, Dir: dir})
+	port := start(t, &config.EndpointConfig{SourceType: "http"}, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		io.WriteString(w, "secure")
+	}))
+
+	// The clients trust the server through the exported CA.
+	require.Equal(t, "secure", get(t, client(t, dir), "https://localhost:"+port+"/"))
+}
+
+func TestClientAuth(t *testing.T) {
+	dir := t.TempDir()
+	SetTLS(TLSOptions{Dir: dir})
+	hello := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		name := "anonymous"
+		if len(req.TLS.PeerCertificates) > 0 {
+			name = req.TLS.PeerCertificates[0].Subject.CommonName
+		}
+		io.WriteString(w, "hello "+name)
+	})
+	required := start(t, &config.EndpointConfig{SourceType: "https", ClientAuth: &config.ClientAuth{}}, hello)
+	optional := start(t, &config.EndpointConfig{SourceType: "https", ClientAuth: &config.ClientAuth{Optional: true}}, hello)
+
+	// The client certificates are signed by the CA of the TLS directory by
+	// default.
+	ca, err := certs.LoadCA(filepath.Join(dir, CAFile), filepath.Join(dir, CAKey))
+	require.NoError(t, err)
+	alice, err := ca.ClientCertificate("alice")
+	require.NoError(t, err)
+	require.Equal(t, "hello alice", get(t, client(t, dir, *alice), "https://localhost:"+required+"/"))
+	_, err = client(t, dir).Get("https://localhost:" + required + "/")
+	require.ErrorContains(t, err, "certificate required")
+	other, err := certs.NewCA()
+	require.NoError(t, err)
+	mallory, err := other.ClientCertificate("mallory")
+	require.NoError(t, err)
+	_, err = client(t, dir, *mallory).Get("https://localhost:" + required + "/")
+	require.ErrorContains(t, err, "unknown certificate authority")
+
+	require.Equal(t, "hello anonymous", get(t, client(t, dir), "https://localhost:"+optional+"/"))
+	require.Equal(t, "hello alice", get(t, client(t, dir, *alice), "https://localhost:"+optional+"/"))
+	_, err = client(t, dir, *mallory).Get("https://localhost:" + optional + "/")
+	require.ErrorContains(t, err, "unknown certificate authority")
+
+	require.ErrorContains(t, Serve(&config.EndpointConfig{ClientAuth: &config.ClientAuth{}}, hello), "client_auth needs TLS")
+}
+
+func TestHTTP2(t *testing.T) {
+	dir := t.TempDir()
+	SetTLS(TLSOptions{Dir: dir})
+	proto := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		io.WriteString(w, req.Proto)
+	})
+	plain := start(t, &config.EndpointConfig{SourceType: "http"}, proto)
+	secure := start(t, &config.EndpointConfig{SourceType: "https"}, proto)
+
+	require.Equal(t, "HTTP/1.1", get(t, http.DefaultClient, "http://localhost:"+plain+"/"))
+	h2c := &http.Transport{Protocols: new(http.Protocols)}
+	h2c.Protocols.SetUnencryptedHTTP2(true)
+	require.Equal(t, "HTTP/2.0", get(t, &http.Client{Transport: h2c}, "http://localhost:"+plain+"/"))
+
+	c := client(t, dir)
+	c.Transport.(*http.Transport).ForceAttemptHTTP2 = true
+	require.Equal(t, "HTTP/2.0", get(t, c, "https://localhost:"+secure+"/"))
+	require.Equal(t, "HTTP/1.1", get(t, client(t, dir), "https://localhost:"+secure+"/"))
+}
+
+func TestHTTP3(t *testing.T) {
+	dir := t.TempDir()
+	SetTLS(TLSOptions{Dir: dir})
+	proto := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		// A recorded response advertises the port of the target host.
+		w.Header().Set("Alt-Svc", `h3=":443"; ma=2592000`)
+		io.WriteString(w, req.Proto)
+	})
+	port := start(t, &config.EndpointConfig{SourceType: "https", HTTP3: true}, proto)
+
+	// The TCP port advertises the UDP port of the same number.
+	resp, err := client(t, dir).Get("https://localhost:" + port + "/")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, `h3=":`+port+`"; ma=86400`, resp.Header.Get("Alt-Svc"))
+
+	h3 := &http3.Transport{TLSClientConfig: client(t, dir).Transport.(*http.Transport).TLSClientConfig}
+	t.Cleanup(func() { h3.Close() })
+	require.Eventually(t, func() bool {
+		resp, err := (&http.Client{Transport: h3}).Get("https://localhost:" + port + "/")
+		if err != nil {
+			return false
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return string(body) == "HTTP/3.0"
+	}, 5*time.Second, 50*time.Millisecond)
+
+	require.ErrorContains(t, Serve(&config.EndpointConfig{HTTP3: true}, proto), "http3 needs TLS")
+}
+
+func TestUnixSocket(t *testing.T) {
+	SetTLS(TLSOptions{})
+	path := filepath.Join(t.TempDir(), "test-server.sock")
+	// A socket left behind by a run that did not stop cleanly.
+	stale, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	ep := &config.EndpointConfig{Listen: "unix:" + path}
+	l := Listeners(ep)[0]
+	require.Equal(t, path, Socket(l))
+	require.Equal(t, "http://localhost", BaseURL(l))
+	network, address := Dial(l)
+	require.Equal(t, "unix", network)
+	go Serve(ep, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		io.WriteString(w, "unix")
+	}))
+	c := &http.Client{Transport: &http.Transport{DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+		return new(net.Dialer).DialContext(ctx, network, address)
+	}}}
+	require.Eventually(t, func() bool {
+		resp, err := c.Get("http://localhost/")
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Equal(t, "unix", get(t, c, "http://localhost/"))
+
+	require.ErrorContains(t, Serve(&config.EndpointConfig{Listen: "tcp:1443"}, http.NotFoundHandler()), "unsupported address")
+}
+
+func TestBind(t *testing.T) {
+	SetTLS(TLSOptions{})
+	ep := &config.EndpointConfig{SourcePort: 0}
+	require.NoError(t, Bind(ep))
+	require.NotZero(t, ep.SourcePort)
+	port := strconv.FormatInt(ep.SourcePort, 10)
+	require.Equal(t, "http://localhost:"+port, BaseURL(Listeners(ep)[0]))
+
+	// The port accepts connections before Serve, which serves it then.
+	conn, err := net.Dial("tcp", "127.0.0.1:"+port)
+	require.NoError(t, err)
+	conn.Close()
+	go Serve(ep, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		io.WriteString(w, "bound")
+	}))
+	require.Equal(t, "bound", get(t, http.DefaultClient, "http://localhost:"+port+"/"))
+
+	require.Error(t, Bind(&config.EndpointConfig{SourcePort: ep.SourcePort}), "the port is taken")
+
+	// The certificate exists once the ports are bound.
+	dir := t.TempDir()
+	SetTLS(TLSOptions{Dir: dir})
+	require.NoError(t, Bind(&config.EndpointConfig{SourceType: "https"}))
+	require.FileExists(t, filepath.Join(dir, CAFile))
+	require.ErrorContains(t, Bind(&config.EndpointConfig{ClientAuth: &config.ClientAuth{}}), "client_auth needs TLS")
+}
+
+func TestListeners(t *testing.T) {
+	dir := t.TempDir()
+	SetTLS(TLSOptions{Dir: dir})
+	path := filepath.Join(dir, "test-server.sock")
+	ep := &config.EndpointConfig{Listeners: []config.Listener{{TLS: true}, {Listen: "unix:" + path}}}
+	require.NoError(t, Bind(ep))
+	listeners := Listeners(ep)
+	require.Len(t, listeners, 3)
+	require.NotZero(t, listeners[0].Port)
+	require.NotZero(t, listeners[1].Port)
+	require.Equal(t, "https://localhost:"+strconv.FormatInt(listeners[1].Port, 10), BaseURL(listeners[1]))
+
+	// The listeners share the handler, and so its state.
+	var mu sync.Mutex
+	count := 0
+	go Serve(ep, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		count++
+		fmt.Fprintf(w, "%d", count)
+	}))
+	require.Equal(t, "1", get(t, http.DefaultClient, BaseURL(listeners[0])+"/"))
+	require.Equal(t, "2", get(t, client(t, dir), BaseURL(listeners[1])+"/"))
+	network, address := Dial(listeners[2])
+	unix := &http.Client{Transport: &http.Transport{DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+		return new(net.Dialer).DialContext(ctx, network, address)
+	}}}
+	require.Equal(t, "3", get(t, unix, BaseURL(listeners[2])+"/"))
+}
+
+func TestBindAddress(t *testing.T) {
+	require.Equal(t, "tcp", Network(""))
+	require.Equal(t, "tcp", Network("localhost"))
+	require.Equal(t, "tcp4", Network("0.0.0.0"))
+	require.Equal(t, "tcp6", Network("::"))
+
+	SetTLS(TLSOptions{})
+	ok := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		io.WriteString(w, "ok")
+	})
+	serve := func(bind string) config.Listener {
+		ep := &config.EndpointConfig{BindAddress: bind}
+		require.NoError(t, Bind(ep))
+		go Serve(ep, ok)
+		return Listeners(ep)[0]
+	}
+	l := serve("127.0.0.1")
+	require.Equal(t, "http://127.0.0.1:"+strconv.FormatInt(l.Port, 10), BaseURL(l))
+	require.Equal(t, "ok", get(t, http.DefaultClient, BaseURL(l)+"/"))
+	l = serve("0.0.0.0")
+	require.Equal(t, "http://127.0.0.1:"+strconv.FormatInt(l.Port, 10), BaseURL(l))
+	require.Equal(t, "ok", get(t, http.DefaultClient, BaseURL(l)+"/"))
+
+	if ln, err := net.Listen("tcp6", "[::1]:0"); err != nil {
+		t.Skip("no IPv6:", err)
+	} else {
+		ln.Close()
+	}
+	l = serve("::")
+	require.Equal(t, "http://[::1]:"+strconv.FormatInt(l.Port, 10), BaseURL(l))
+	require.Equal(t, "ok", get(t, http.DefaultClient, BaseURL(l)+"/"))
+	// :: binds the IPv6 addresses only.
+	_, err := http.Get("http://127.0.0.1:" + strconv.FormatInt(l.Port, 10) + "/")
+	require.Error(t, err)
+	l = serve("")
+	require.Equal(t, "ok", get(t, http.DefaultClient, "http://[::1]:"+strconv.FormatInt(l.Port, 10)+"/"))
+	require.Equal(t, "ok", get(t, http.DefaultClient, "http://127.0.0.1:"+strconv.FormatInt(l.Port, 10)+"/"))
+}
+
+func TestShutdown(t *testing.T) {
+	SetTLS(TLSOptions{})
+	t.Cleanup(func() {
+		serversMu.Lock()
+		stopping = false
+		serversMu.Unlock()
+	})
+	path := filepath.Join(t.TempDir(), "test-server.sock")
+	ep := &config.EndpointConfig{Listen: "unix:" + path, Listeners: []config.Listener{{}}}
+	require.NoError(t, Bind(ep))
+	started, release := make(chan bool), make(chan bool)
+	served := make(chan error, 1)
+	go func() {
+		served <- Serve(ep, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			started <- true
+			<-release
+			io.WriteString(w, "drained")
+		}))
+	}()
+	port := strconv.FormatInt(Listeners(ep)[1].Port, 10)
+	body := make(chan string)
+	go func() {
+		resp, err := http.Get("http://localhost:" + port + "/")
+		if err != nil {
+			body <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		body <- string(data)
+	}()
+	<-started
+
+	stopped := make(chan error)
+	go func() { stopped <- Shutdown(context.Background()) }()
+	// The listeners are closed at once, and the request in flight completes.
+	require.Eventually(t, func() bool {
+		_, err := os.Stat(path)
+		return os.IsNotExist(err)
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Eventually(t, func() bool {
+		conn, err := net.Dial("tcp", "localhost:"+port)
+		if err == nil {
+			conn.Close()
+		}
+		return err != nil
+	}, 5*time.Second, 10*time.Millisecond)
+	close(release)
+	require.Equal(t, "drained", <-body)
+	require.NoError(t, <-stopped)
+	require.NoError(t, <-served)
+
+	// A server started after is stopped at once.
+	require.NoError(t, ServeListener(&http.Server{}, newListener(t)))
+
+	// The requests still in flight at the deadline are cut short.
+	serversMu.Lock()
+	stopping = false
+	serversMu.Unlock()
+	hung := make(chan bool)
+	defer close(hung)
+	ln := newListener(t)
+	go ServeListener(&http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		started <- true
+		<-hung
+	})}, ln)
+	failed := make(chan error)
+	go func() {
+		_, err := http.Get("http://" + ln.Addr().String() + "/")
+		failed <- err
+	}()
+	<-started
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, Shutdown(ctx), context.DeadlineExceeded)
+	require.Error(t, <-failed)
+}
+
+func newListener(t *testing.T) net.Listener {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	return ln
+}
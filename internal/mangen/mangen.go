@@ -0,0 +1,173 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package mangen renders section 1 man pages for a cobra command tree.
+package mangen
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// Header is the .TH line shared by all pages.
+type Header struct {
+	Source string // e.g. "test-server 0.3.0"
+	Manual string // e.g. "test-server Manual"
+	Date   time.Time
+}
+
+// Pages returns the man page of cmd and of every available subcommand below
+// it, keyed by file name (e.g. "test-server-replay.1").
+func Pages(cmd *cobra.Command, h Header) map[string][]byte {
+	pages := map[string][]byte{}
+	var walk func(c *cobra.Command)
+	walk = func(c *cobra.Command) {
+		pages[pageName(c)+".1"] = Page(c, h)
+		for _, sub := range c.Commands() {
+			if sub.IsAvailableCommand() && !sub.IsAdditionalHelpTopicCommand() {
+				walk(sub)
+			}
+		}
+	}
+	walk(cmd)
+	return pages
+}
+
+// Page renders the man page of cmd.
+func Page(cmd *cobra.Command, h Header) []byte {
+	cmd.InitDefaultHelpFlag()
+	if !cmd.HasParent() {
+		cmd.InitDefaultVersionFlag()
+	}
+	name := pageName(cmd)
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, ".TH \"%s\" \"1\" \"%s\" \"%s\" \"%s\"\n", escape(strings.ToUpper(name)), h.Date.UTC().Format("2006-01-02"), escape(h.Source), escape(h.Manual))
+	fmt.Fprintf(&b, ".SH NAME\n%s \\- %s\n", escape(name), escape(cmd.Short))
+	fmt.Fprintf(&b, ".SH SYNOPSIS\n.B %s\n", escape(cmd.UseLine()))
+	if cmd.HasAvailableSubCommands() {
+		fmt.Fprintf(&b, ".br\n.B %s [command]\n", escape(cmd.CommandPath()))
+	}
+
+	b.WriteString(".SH DESCRIPTION\n")
+	desc := cmd.Long
+	if desc == "" {
+		desc = cmd.Short
+	}
+	for i, para := range strings.Split(strings.TrimSpace(desc), "\n\n") {
+		if i > 0 {
+			b.WriteString(".PP\n")
+		}
+		b.WriteString(escape(para) + "\n")
+	}
+
+	writeFlags(&b, "OPTIONS", cmd.NonInheritedFlags())
+	writeFlags(&b, "OPTIONS INHERITED FROM PARENT COMMANDS", cmd.InheritedFlags())
+
+	if cmd.Example != "" {
+		fmt.Fprintf(&b, ".SH EXAMPLES\n.nf\n%s\n.fi\n", escape(strings.TrimRight(cmd.Example, "\n")))
+	}
+
+	var related []string
+	if cmd.HasParent() {
+		related = append(related, pageName(cmd.Parent()))
+	}
+	for _, sub := range cmd.Commands() {
+		if sub.IsAvailableCommand() && !sub.IsAdditionalHelpTopicCommand() {
+			related = append(related, pageName(sub))
+		}
+	}
+	if len(related) > 0 {
+		sort.Strings(related)
+		b.WriteString(".SH SEE ALSO\n")
+		for i, r := range related {
+			sep := ""
+			if i < len(related)-1 {
+				sep = ","
+			}
+			fmt.Fprintf(&b, ".BR %s (1)%s\n", escape(r), sep)
+		}
+	}
+	return b.Bytes()
+}
+
+func writeFlags(b *bytes.Buffer, section string, flags *pflag.FlagSet) {
+	if !flags.HasAvailableFlags() {
+		return
+	}
+	fmt.Fprintf(b, ".SH %s\n", section)
+	flags.VisitAll(func(f *pflag.Flag) {
+		if f.Hidden || f.Deprecated != "" {
+			return
+		}
+		varname, usage := pflag.UnquoteUsage(f)
+		b.WriteString(".TP\n")
+		if f.Shorthand != "" && f.ShorthandDeprecated == "" {
+			fmt.Fprintf(b, "\\fB\\-%s\\fR, ", f.Shorthand)
+		}
+		fmt.Fprintf(b, "\\fB\\-\\-%s\\fR", escape(f.Name))
+		if varname != "" {
+			fmt.Fprintf(b, " \\fI%s\\fR", escape(varname))
+		}
+		b.WriteString("\n" + escape(usage))
+		if !zeroDefault(f) {
+			fmt.Fprintf(b, " (default %s)", escape(quoteDefault(f)))
+		}
+		b.WriteString("\n")
+	})
+}
+
+// zeroDefault reports whether the flag default is not worth printing, as
+// pflag does in its own usage output.
+func zeroDefault(f *pflag.Flag) bool {
+	switch f.DefValue {
+	case "", "false", "0", "[]", "<nil>", "0s":
+		return true
+	}
+	return false
+}
+
+func quoteDefault(f *pflag.Flag) string {
+	if f.Value.Type() == "string" {
+		return fmt.Sprintf("%q", f.DefValue)
+	}
+	return f.DefValue
+}
+
+// pageName is the man page name of cmd: its command path joined with dashes.
+func pageName(cmd *cobra.Command) string {
+	return strings.ReplaceAll(cmd.CommandPath(), " ", "-")
+}
+
+// escape makes s safe as roff text: backslashes and dashes are escaped, and
+// lines starting with a control character are guarded.
+func escape(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\e`)
+	s = strings.ReplaceAll(s, "-", `\-`)
+	lines := strings.Split(s, "\n")
+	for i, l := range lines {
+		if strings.HasPrefix(l, ".") || strings.HasPrefix(l, "'") {
+			lines[i] = `\&` + l
+		}
+	}
+	return strings.Join(lines, "\n")
+}
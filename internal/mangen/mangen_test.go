@@ -0,0 +1,78 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mangen
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/require"
+)
+
+func testTree() *cobra.Command {
+	root := &cobra.Command{Use: "tool", Short: "A tool", Version: "1.0.0"}
+	root.PersistentFlags().String("config", "", "config file")
+	sub := &cobra.Command{
+		Use:   "serve",
+		Short: "Serve files",
+		Long:  "Serves files.\n\n.dot lines are guarded.",
+		Run:   func(*cobra.Command, []string) {},
+	}
+	sub.Flags().StringP("dir", "d", "files", "Directory to serve")
+	sub.Flags().Int("port", 0, "Port to listen on")
+	hidden := &cobra.Command{Use: "debug", Hidden: true, Run: func(*cobra.Command, []string) {}}
+	root.AddCommand(sub, hidden)
+	return root
+}
+
+func TestPages(t *testing.T) {
+	pages := Pages(testTree(), Header{Source: "tool 1.0.0", Manual: "tool Manual", Date: time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)})
+	require.Len(t, pages, 2)
+	require.Contains(t, pages, "tool.1")
+	require.Contains(t, pages, "tool-serve.1")
+
+	root := string(pages["tool.1"])
+	require.Contains(t, root, `.TH "TOOL" "1" "2025-06-01" "tool 1.0.0" "tool Manual"`)
+	require.Contains(t, root, `\fB\-v\fR, \fB\-\-version\fR`)
+	require.Contains(t, root, ".BR tool\\-serve (1)\n")
+	require.NotContains(t, root, "debug")
+
+	serve := string(pages["tool-serve.1"])
+	require.Contains(t, serve, "tool\\-serve \\- Serve files\n")
+	require.Contains(t, serve, ".PP\n\\&.dot lines are guarded.\n")
+	require.Contains(t, serve, "\\fB\\-d\\fR, \\fB\\-\\-dir\\fR \\fIstring\\fR\nDirectory to serve (default \"files\")\n")
+	require.Contains(t, serve, "\\fB\\-\\-port\\fR \\fIint\\fR\nPort to listen on\n")
+	require.Contains(t, serve, ".SH OPTIONS INHERITED FROM PARENT COMMANDS\n.TP\n\\fB\\-\\-config\\fR")
+}
+
+func TestEscape(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"plain", "plain"},
+		{"--flag", `\-\-flag`},
+		{`a\b`, `a\eb`},
+		{"first\n.second\n'third", "first\n\\&.second\n\\&'third"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			require.Equal(t, tt.want, escape(tt.in))
+		})
+	}
+}
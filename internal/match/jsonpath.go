@@ -0,0 +1,181 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package match
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// jsonPath is a compiled JSONPath expression. It supports the subset that
+// selects values without filters: $, .name, ['name'], [index] (negative from
+// the end), [*], .* and the recursive descent ..name and ..*.
+type jsonPath []jsonStep
+
+type jsonStep struct {
+	// descend replaces the current values with themselves and every value
+	// below them, for the step after a "..".
+	descend bool
+	// name is the member to select, or "" for every member or element.
+	name string
+	// index selects an array element when isIndex is set.
+	index   int
+	isIndex bool
+}
+
+func compileJSONPath(expr string) (jsonPath, error) {
+	rest, ok := strings.CutPrefix(expr, "$")
+	if !ok {
+		return nil, fmt.Errorf("JSONPath %q does not start with $", expr)
+	}
+	// Not nil, so that $ alone selects the whole document.
+	p := jsonPath{}
+	for rest != "" {
+		switch {
+		case strings.HasPrefix(rest, ".."):
+			p = append(p, jsonStep{descend: true})
+			rest = rest[2:]
+			if strings.HasPrefix(rest, "[") {
+				continue
+			}
+		case rest[0] == '.':
+			rest = rest[1:]
+		case rest[0] == '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("JSONPath %q has an unclosed [", expr)
+			}
+			s, err := parseSubscript(rest[1:end])
+			if err != nil {
+				return nil, fmt.Errorf("JSONPath %q: %w", expr, err)
+			}
+			p = append(p, s)
+			rest = rest[end+1:]
+			continue
+		default:
+			return nil, fmt.Errorf("JSONPath %q: unexpected %q", expr, rest)
+		}
+		// A dot is followed by a name or *.
+		end := strings.IndexAny(rest, ".[")
+		if end < 0 {
+			end = len(rest)
+		}
+		switch name := rest[:end]; name {
+		case "":
+			return nil, fmt.Errorf("JSONPath %q has an empty name", expr)
+		case "*":
+			p = append(p, jsonStep{})
+		default:
+			p = append(p, jsonStep{name: name})
+		}
+		rest = rest[end:]
+	}
+	return p, nil
+}
+
+// parseSubscript parses what is between the brackets of a subscript.
+func parseSubscript(inner string) (jsonStep, error) {
+	switch {
+	case inner == "*":
+		return jsonStep{}, nil
+	case len(inner) >= 3 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0]:
+		return jsonStep{name: inner[1 : len(inner)-1]}, nil
+	}
+	i, err := strconv.Atoi(inner)
+	if err != nil {
+		return jsonStep{}, fmt.Errorf("invalid subscript [%s]", inner)
+	}
+	return jsonStep{index: i, isIndex: true}, nil
+}
+
+// eval returns the values p selects in doc, a value decoded by encoding/json.
+func (p jsonPath) eval(doc any) []any {
+	values := []any{doc}
+	for _, s := range p {
+		var next []any
+		for _, v := range values {
+			if s.descend {
+				next = appendDescendants(next, v)
+			} else {
+				next = s.apply(next, v)
+			}
+		}
+		values = next
+	}
+	return values
+}
+
+// apply appends what s selects in v to out.
+func (s jsonStep) apply(out []any, v any) []any {
+	switch v := v.(type) {
+	case map[string]any:
+		if s.isIndex {
+			return out
+		}
+		if s.name != "" {
+			if child, ok := v[s.name]; ok {
+				out = append(out, child)
+			}
+			return out
+		}
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			out = append(out, v[k])
+		}
+	case []any:
+		switch {
+		case s.isIndex:
+			i := s.index
+			if i < 0 {
+				i += len(v)
+			}
+			if i >= 0 && i < len(v) {
+				out = append(out, v[i])
+			}
+		case s.name == "":
+			out = append(out, v...)
+		}
+	}
+	return out
+}
+
+// appendDescendants appends v and every value below it to out.
+func appendDescendants(out []any, v any) []any {
+	out = append(out, v)
+	switch v := v.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			out = appendDescendants(out, v[k])
+		}
+	case []any:
+		for _, child := range v {
+			out = appendDescendants(out, child)
+		}
+	}
+	return out
+}
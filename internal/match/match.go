@@ -0,0 +1,249 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package match decides which recorded interaction answers a request in
+// replay mode when an endpoint configures match_on.
+//
+// Without match_on a request only matches a recording whose SHA-256 over the
+// whole redacted request, including every header and the previous request of
+// the test, is identical. That breaks as soon as an SDK in another language
+// sends a different User-Agent, so match_on names the parts that matter.
+package match
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/google/test-server/internal/config"
+	"github.com/google/test-server/internal/store"
+)
+
+// Criteria accepted in match_on.
+const (
+	Method = "method"
+	Path   = "path"
+	Query  = "query"
+	Body   = "body"
+	// Headers compares every header but the ignored ones.
+	Headers = "headers"
+	// HeaderPrefix is followed by a header name, e.g. "header:Content-Type".
+	HeaderPrefix = "header:"
+	// BodyPrefix is followed by a JSONPath, e.g. "body:$.contents", to
+	// compare only the part of the body it selects.
+	BodyPrefix = "body:"
+)
+
+// DefaultIgnoredHeaders are the headers the headers criterion ignores because
+// they differ between SDKs, runs or connections rather than between requests.
+var DefaultIgnoredHeaders = []string{
+	"Accept-Encoding",
+	"Connection",
+	"Content-Length",
+	"Date",
+	"Last-Event-Id",
+	"Test-Name",
+	"Traceparent",
+	"Tracestate",
+	"User-Agent",
+	"X-Cloud-Trace-Context",
+	"X-Goog-Api-Client",
+	"X-Request-Id",
+}
+
+// Matcher compares requests on the configured criteria.
+type Matcher struct {
+	method, path, query, body bool
+	headers                   []string
+	bodyPaths                 []jsonPath
+	// allHeaders compares every header whose name is not in ignored.
+	allHeaders bool
+	ignored    map[string]bool
+	queryMatch config.QueryMatch
+}
+
+// New returns a Matcher for the match_on criteria of cfg. It returns nil for
+// no criteria, which means replay keeps matching on the request SHA-256.
+func New(cfg *config.EndpointConfig) (*Matcher, error) {
+	if len(cfg.MatchOn) == 0 {
+		return nil, nil
+	}
+	m := &Matcher{queryMatch: cfg.QueryMatch}
+	for _, c := range cfg.MatchOn {
+		switch c {
+		case Headers:
+			m.allHeaders = true
+			m.ignored = map[string]bool{}
+			for _, name := range DefaultIgnoredHeaders {
+				m.ignored[name] = true
+			}
+			for _, name := range cfg.IgnoreHeaders {
+				m.ignored[http.CanonicalHeaderKey(name)] = true
+			}
+		case Method:
+			m.method = true
+		case Path:
+			m.path = true
+		case Query:
+			m.query = true
+		case Body:
+			m.body = true
+		default:
+			if expr, ok := strings.CutPrefix(c, BodyPrefix); ok {
+				p, err := compileJSONPath(expr)
+				if err != nil {
+					return nil, fmt.Errorf("match_on: %w", err)
+				}
+				m.bodyPaths = append(m.bodyPaths, p)
+				continue
+			}
+			name, ok := strings.CutPrefix(c, HeaderPrefix)
+			if !ok || name == "" {
+				return nil, fmt.Errorf("match_on: unknown criterion %q, want method, path, query, body, body:<JSONPath>, headers or header:<name>", c)
+			}
+			m.headers = append(m.headers, http.CanonicalHeaderKey(name))
+		}
+	}
+	return m, nil
+}
+
+// Match reports whether the incoming request matches the recorded one.
+func (m *Matcher) Match(recorded, incoming *store.RecordedRequest) bool {
+	if m.method && recorded.Method != incoming.Method {
+		return false
+	}
+	if m.path || m.query {
+		ru, err1 := url.Parse(recorded.URL)
+		iu, err2 := url.Parse(incoming.URL)
+		if err1 != nil || err2 != nil {
+			return false
+		}
+		if m.path && ru.Path != iu.Path {
+			return false
+		}
+		// Parameter order is not significant.
+		if m.query && !reflect.DeepEqual(m.normalizeQuery(ru.Query()), m.normalizeQuery(iu.Query())) {
+			return false
+		}
+	}
+	if m.headers != nil || m.allHeaders {
+		rh, ih := canonicalHeaders(recorded.Headers), canonicalHeaders(incoming.Headers)
+		for _, h := range m.headers {
+			if rh[h] != ih[h] {
+				return false
+			}
+		}
+		if m.allHeaders && !m.sameHeaders(rh, ih) {
+			return false
+		}
+	}
+	if m.body && !reflect.DeepEqual(normalizeBody(recorded.BodySegments), normalizeBody(incoming.BodySegments)) {
+		return false
+	}
+	if len(m.bodyPaths) > 0 {
+		rb, ib := bodyDocument(recorded), bodyDocument(incoming)
+		for _, p := range m.bodyPaths {
+			if !reflect.DeepEqual(p.eval(rb), p.eval(ib)) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// normalizeQuery applies the query_match options to the decoded parameters
+// q.
+func (m *Matcher) normalizeQuery(q url.Values) url.Values {
+	for _, name := range m.queryMatch.Ignore {
+		q.Del(name)
+	}
+	for name, values := range q {
+		if m.queryMatch.SplitCommas {
+			var split []string
+			for _, v := range values {
+				split = append(split, strings.Split(v, ",")...)
+			}
+			values = split
+		}
+		if m.queryMatch.Unordered {
+			sort.Strings(values)
+		}
+		q[name] = values
+	}
+	return q
+}
+
+// sameHeaders reports whether two canonical header maps agree on every header
+// that is not ignored.
+func (m *Matcher) sameHeaders(a, b map[string]string) bool {
+	for name, value := range a {
+		if other, ok := b[name]; !m.ignored[name] && (!ok || other != value) {
+			return false
+		}
+	}
+	for name := range b {
+		if _, ok := a[name]; !m.ignored[name] && !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// spaceRe matches a run of whitespace, with the separators it surrounds.
+var spaceRe = regexp.MustCompile(`\s*([,;])\s*|\s+`)
+
+// canonicalHeaders returns headers with canonical names, e.g. Content-Type
+// for content-type, and values without leading or trailing whitespace,
+// whitespace runs shortened to one space and no whitespace around commas and
+// semicolons, so "application/json; charset=utf-8" equals
+// "application/json;charset=utf-8".
+func canonicalHeaders(headers map[string]string) map[string]string {
+	out := make(map[string]string, len(headers))
+	for name, value := range headers {
+		out[http.CanonicalHeaderKey(name)] = spaceRe.ReplaceAllStringFunc(strings.TrimSpace(value), func(s string) string {
+			if t := strings.TrimSpace(s); t != "" {
+				return t
+			}
+			return " "
+		})
+	}
+	return out
+}
+
+// bodyDocument returns the JSON body of r for a JSONPath, or nil.
+func bodyDocument(r *store.RecordedRequest) any {
+	if segments := normalizeBody(r.BodySegments); len(segments) > 0 {
+		return segments[0]
+	}
+	return nil
+}
+
+// normalizeBody treats a missing body and an empty one alike: a request
+// without a body records one nil segment, and a recording read back from
+// JSON may have none.
+func normalizeBody(segments []map[string]any) []map[string]any {
+	var out []map[string]any
+	for _, s := range segments {
+		if len(s) > 0 {
+			out = append(out, s)
+		}
+	}
+	return out
+}
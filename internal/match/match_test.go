@@ -0,0 +1,250 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package match
+
+import (
+	"testing"
+
+	"github.com/google/test-server/internal/config"
+	"github.com/google/test-server/internal/store"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	m, err := New(&config.EndpointConfig{})
+	require.NoError(t, err)
+	require.Nil(t, m)
+
+	m, err = New(&config.EndpointConfig{MatchOn: []string{"method", "path", "query", "body", "header:content-type"}})
+	require.NoError(t, err)
+	require.Equal(t, &Matcher{method: true, path: true, query: true, body: true, headers: []string{"Content-Type"}}, m)
+
+	for _, bad := range []string{"uri", "header:", "Method", "body:prompt"} {
+		_, err := New(&config.EndpointConfig{MatchOn: []string{bad}})
+		require.Error(t, err, bad)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	recorded := &store.RecordedRequest{
+		Method:       "POST",
+		URL:          "/v1/models:generate?alt=sse&key=REDACTED",
+		Headers:      map[string]string{"Content-Type": "application/json", "User-Agent": "node"},
+		BodySegments: []map[string]any{{"prompt": "hi", "n": float64(1)}},
+	}
+	tests := []struct {
+		name     string
+		criteria []string
+		incoming store.RecordedRequest
+		want     bool
+	}{
+		{
+			name:     "method and path ignore the rest",
+			criteria: []string{"method", "path"},
+			incoming: store.RecordedRequest{Method: "POST", URL: "/v1/models:generate", Headers: map[string]string{"User-Agent": "python"}},
+			want:     true,
+		},
+		{
+			name:     "method differs",
+			criteria: []string{"method", "path"},
+			incoming: store.RecordedRequest{Method: "GET", URL: "/v1/models:generate"},
+		},
+		{
+			name:     "path differs",
+			criteria: []string{"path"},
+			incoming: store.RecordedRequest{Method: "POST", URL: "/v1/models:count"},
+		},
+		{
+			name:     "query in another order",
+			criteria: []string{"query"},
+			incoming: store.RecordedRequest{URL: "/v1/models:generate?key=REDACTED&alt=sse"},
+			want:     true,
+		},
+		{
+			name:     "query differs",
+			criteria: []string{"query"},
+			incoming: store.RecordedRequest{URL: "/v1/models:generate?alt=json&key=REDACTED"},
+		},
+		{
+			name:     "selected header matches",
+			criteria: []string{"header:Content-Type"},
+			incoming: store.RecordedRequest{Headers: map[string]string{"Content-Type": "application/json", "User-Agent": "python"}},
+			want:     true,
+		},
+		{
+			name:     "selected header missing",
+			criteria: []string{"header:Content-Type"},
+			incoming: store.RecordedRequest{Headers: map[string]string{"User-Agent": "node"}},
+		},
+		{
+			name:     "body with keys in another order",
+			criteria: []string{"body"},
+			incoming: store.RecordedRequest{BodySegments: []map[string]any{{"n": float64(1), "prompt": "hi"}}},
+			want:     true,
+		},
+		{
+			name:     "body differs",
+			criteria: []string{"body"},
+			incoming: store.RecordedRequest{BodySegments: []map[string]any{{"prompt": "bye", "n": float64(1)}}},
+		},
+		{
+			name:     "selected body part matches",
+			criteria: []string{"body:$.prompt"},
+			incoming: store.RecordedRequest{BodySegments: []map[string]any{{"prompt": "hi", "n": float64(2)}}},
+			want:     true,
+		},
+		{
+			name:     "selected body part differs",
+			criteria: []string{"body:$.n"},
+			incoming: store.RecordedRequest{BodySegments: []map[string]any{{"prompt": "hi", "n": float64(2)}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := New(&config.EndpointConfig{MatchOn: tt.criteria})
+			require.NoError(t, err)
+			require.Equal(t, tt.want, m.Match(recorded, &tt.incoming))
+		})
+	}
+}
+
+func TestMatchHeaders(t *testing.T) {
+	recorded := &store.RecordedRequest{Headers: map[string]string{
+		"Content-Type":      "application/json; charset=utf-8",
+		"User-Agent":        "node",
+		"X-Goog-Api-Client": "gl-node/20.0.0",
+		"X-Custom":          "a,  b",
+		"X-Session":         "1",
+	}}
+	tests := []struct {
+		name     string
+		criteria []string
+		ignore   []string
+		incoming map[string]string
+		want     bool
+	}{
+		{
+			name:     "volatile headers and formatting differ",
+			criteria: []string{"headers"},
+			ignore:   []string{"x-session"},
+			incoming: map[string]string{
+				"content-type":      "application/json;charset=utf-8",
+				"User-Agent":        "python",
+				"X-Goog-Api-Client": "gl-python/3.12",
+				"X-Custom":          " a , b ",
+				"X-Session":         "2",
+			},
+			want: true,
+		},
+		{
+			name:     "header value differs",
+			criteria: []string{"headers"},
+			incoming: map[string]string{"Content-Type": "text/plain", "X-Custom": "a, b", "X-Session": "1"},
+		},
+		{
+			name:     "header missing",
+			criteria: []string{"headers"},
+			incoming: map[string]string{"Content-Type": "application/json; charset=utf-8", "X-Custom": "a, b"},
+		},
+		{
+			name:     "extra header",
+			criteria: []string{"headers"},
+			incoming: map[string]string{"Content-Type": "application/json; charset=utf-8", "X-Custom": "a, b", "X-Session": "1", "X-Extra": "1"},
+		},
+		{
+			name:     "a named header is compared even if ignored",
+			criteria: []string{"headers", "header:user-agent"},
+			incoming: map[string]string{"Content-Type": "application/json; charset=utf-8", "User-Agent": "python", "X-Custom": "a, b", "X-Session": "1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := New(&config.EndpointConfig{MatchOn: tt.criteria, IgnoreHeaders: tt.ignore})
+			require.NoError(t, err)
+			require.Equal(t, tt.want, m.Match(recorded, &store.RecordedRequest{Headers: tt.incoming}))
+		})
+	}
+}
+
+func TestMatchQueryOptions(t *testing.T) {
+	recorded := &store.RecordedRequest{URL: "/v1/files?fields=name,size&tag=b&tag=a&name=hello%20world&requestId=1"}
+	tests := []struct {
+		name     string
+		options  config.QueryMatch
+		incoming string
+		want     bool
+	}{
+		{
+			name:     "other encoding",
+			options:  config.QueryMatch{Ignore: []string{"requestId"}},
+			incoming: "/v1/files?name=hello+world&fields=name%2Csize&tag=b&tag=a&requestId=2",
+			want:     true,
+		},
+		{
+			name:     "ignored parameter differs",
+			incoming: "/v1/files?fields=name,size&tag=b&tag=a&name=hello%20world&requestId=2",
+		},
+		{
+			name:     "repeated values in another order",
+			options:  config.QueryMatch{Unordered: true},
+			incoming: "/v1/files?fields=name,size&tag=a&tag=b&name=hello%20world&requestId=1",
+			want:     true,
+		},
+		{
+			name:     "repeated values in another order, ordered",
+			incoming: "/v1/files?fields=name,size&tag=a&tag=b&name=hello%20world&requestId=1",
+		},
+		{
+			name:     "repeated instead of comma-separated",
+			options:  config.QueryMatch{SplitCommas: true},
+			incoming: "/v1/files?fields=name&fields=size&tag=b,a&name=hello%20world&requestId=1",
+			want:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := New(&config.EndpointConfig{MatchOn: []string{"query"}, QueryMatch: tt.options})
+			require.NoError(t, err)
+			require.Equal(t, tt.want, m.Match(recorded, &store.RecordedRequest{URL: tt.incoming}))
+		})
+	}
+}
+
+func TestMatchEmptyBodies(t *testing.T) {
+	m, err := New(&config.EndpointConfig{MatchOn: []string{"body"}})
+	require.NoError(t, err)
+	require.True(t, m.Match(&store.RecordedRequest{}, &store.RecordedRequest{BodySegments: []map[string]any{nil}}))
+}
+
+func TestDiff(t *testing.T) {
+	recorded := &store.RecordedRequest{Method: "POST", URL: "/v1/generate", Headers: map[string]string{"User-Agent": "node"}}
+	incoming := &store.RecordedRequest{Method: "POST", URL: "/v1/generate", Headers: map[string]string{"User-Agent": "python"}}
+	diff := Diff(recorded, incoming)
+	require.Equal(t, []string{
+		" {",
+		`   "method": "POST",`,
+		`   "url": "/v1/generate",`,
+		`   "headers": {`,
+		`-    "User-Agent": "node"`,
+		`+    "User-Agent": "python"`,
+		"   }",
+		" }",
+	}, diff)
+	require.Equal(t, 2, Changes(diff))
+	require.Zero(t, Changes(Diff(recorded, recorded)))
+}
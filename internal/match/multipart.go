@@ -0,0 +1,181 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package match
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/google/test-server/internal/config"
+)
+
+// partPredicate tests the parts of a multipart body.
+type partPredicate struct {
+	name        string
+	index       *int
+	contentType string
+	method      string
+	path        string
+	body        *Predicate
+}
+
+func compilePart(c *config.PartMatch) (*partPredicate, error) {
+	p := &partPredicate{
+		name:        c.Name,
+		index:       c.Index,
+		contentType: strings.ToLower(c.ContentType),
+		method:      strings.ToUpper(c.Method),
+		path:        c.Path,
+	}
+	if p.index != nil && *p.index < 0 {
+		return nil, fmt.Errorf("part.index: must not be negative")
+	}
+	if _, err := path.Match(p.path, ""); err != nil {
+		return nil, fmt.Errorf("part.path: %w", err)
+	}
+	if c.Body != nil {
+		var err error
+		if p.body, err = NewPredicate(c.Body); err != nil {
+			return nil, fmt.Errorf("part.body.%w", err)
+		}
+	}
+	return p, nil
+}
+
+func (p *partPredicate) test(b *body) bool {
+	parts, err := b.decodeParts()
+	if err != nil {
+		return false
+	}
+	for i, part := range parts {
+		if p.testPart(i, part) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *partPredicate) testPart(i int, part *bodyPart) bool {
+	switch {
+	case p.index != nil && *p.index != i,
+		p.name != "" && p.name != part.name,
+		p.contentType != "" && p.contentType != part.contentType:
+		return false
+	}
+	raw := part.raw
+	if p.method != "" || p.path != "" || part.contentType == "application/http" {
+		if part.req == nil {
+			return false
+		}
+		if p.method != "" && p.method != part.req.Method {
+			return false
+		}
+		if ok, _ := path.Match(p.path, part.req.URL.Path); p.path != "" && !ok {
+			return false
+		}
+		raw = part.reqBody
+	}
+	return p.body == nil || p.body.Test(raw)
+}
+
+// bodyPart is a part of a multipart body.
+type bodyPart struct {
+	name        string
+	contentType string
+	raw         []byte
+	// req and reqBody are the request in an application/http part.
+	req     *http.Request
+	reqBody []byte
+}
+
+func (b *body) decodeParts() ([]*bodyPart, error) {
+	if !b.partsDone {
+		b.partsDone = true
+		b.parts, b.partsErr = parseMultipart(b.raw)
+	}
+	return b.parts, b.partsErr
+}
+
+// sniffBoundary returns the boundary of a multipart body from its first
+// delimiter line, since a predicate sees the body without its Content-Type.
+func sniffBoundary(raw []byte) (string, bool) {
+	line, _, _ := bytes.Cut(bytes.TrimLeft(raw, "\r\n"), []byte("\n"))
+	boundary, ok := bytes.CutPrefix(bytes.TrimRight(line, "\r"), []byte("--"))
+	if !ok || len(boundary) == 0 || len(boundary) > 70 {
+		return "", false
+	}
+	return string(boundary), true
+}
+
+// parseMultipart splits raw, a multipart body, into its parts.
+func parseMultipart(raw []byte) ([]*bodyPart, error) {
+	boundary, ok := sniffBoundary(raw)
+	if !ok {
+		return nil, fmt.Errorf("not a multipart body")
+	}
+	r := multipart.NewReader(bytes.NewReader(raw), boundary)
+	var parts []*bodyPart
+	for {
+		p, err := r.NextPart()
+		if err == io.EOF {
+			return parts, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(p)
+		if err != nil {
+			return nil, err
+		}
+		part := &bodyPart{name: p.FormName(), raw: data}
+		part.contentType, _, _ = mime.ParseMediaType(p.Header.Get("Content-Type"))
+		if part.contentType == "application/http" {
+			part.req, part.reqBody, _ = ReadPartRequest(data)
+		}
+		parts = append(parts, part)
+	}
+}
+
+// ReadPartRequest parses the request in an application/http part of a batch,
+// returning it and its body. The body runs to the end of the part when the
+// request has no Content-Length.
+func ReadPartRequest(data []byte) (*http.Request, []byte, error) {
+	br := bufio.NewReader(bytes.NewReader(bytes.TrimLeft(data, "\r\n")))
+	req, err := http.ReadRequest(br)
+	if err != nil {
+		return nil, nil, err
+	}
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(body) == 0 {
+		if body, err = io.ReadAll(br); err != nil {
+			return nil, nil, err
+		}
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.ContentLength = int64(len(body))
+	return req, body, nil
+}
@@ -0,0 +1,272 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package match
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/google/test-server/internal/config"
+)
+
+// Predicate tests a request body against a config.BodyMatch. It holds when
+// any of the values a JSONPath or XPath selects satisfies equals or contains,
+// so the order of JSON keys and of unrelated fields never matters.
+type Predicate struct {
+	jsonPath jsonPath
+	xPath    xPath
+	regex    *regexp.Regexp
+	// equals and contains are the JSON forms of the config values.
+	equals, contains any
+	hasEquals        bool
+	hasContains      bool
+	all, any         []*Predicate
+	part             *partPredicate
+}
+
+// NewPredicate compiles c.
+func NewPredicate(c *config.BodyMatch) (*Predicate, error) {
+	p := &Predicate{}
+	kinds := 0
+	var err error
+	if c.JSONPath != "" {
+		kinds++
+		if p.jsonPath, err = compileJSONPath(c.JSONPath); err != nil {
+			return nil, err
+		}
+	}
+	if c.XPath != "" {
+		kinds++
+		if p.xPath, err = compileXPath(c.XPath); err != nil {
+			return nil, err
+		}
+	}
+	if c.Regex != "" {
+		kinds++
+		if p.regex, err = regexp.Compile(c.Regex); err != nil {
+			return nil, fmt.Errorf("regex: %w", err)
+		}
+	}
+	if c.All != nil {
+		kinds++
+		if p.all, err = compileList("all", c.All); err != nil {
+			return nil, err
+		}
+	}
+	if c.Any != nil {
+		kinds++
+		if p.any, err = compileList("any", c.Any); err != nil {
+			return nil, err
+		}
+	}
+	if c.Part != nil {
+		kinds++
+		if p.part, err = compilePart(c.Part); err != nil {
+			return nil, err
+		}
+	}
+	if kinds != 1 {
+		return nil, fmt.Errorf("set exactly one of json_path, xpath, regex, all, any or part")
+	}
+
+	p.hasEquals, p.hasContains = c.Equals != nil, c.Contains != nil
+	if (p.hasEquals || p.hasContains) && p.jsonPath == nil && p.xPath == nil {
+		return nil, fmt.Errorf("equals and contains need a json_path or xpath")
+	}
+	if p.hasEquals && p.hasContains {
+		return nil, fmt.Errorf("equals and contains cannot be combined")
+	}
+	if p.equals, err = toJSON(c.Equals); err != nil {
+		return nil, fmt.Errorf("equals: %w", err)
+	}
+	if p.contains, err = toJSON(c.Contains); err != nil {
+		return nil, fmt.Errorf("contains: %w", err)
+	}
+	return p, nil
+}
+
+func compileList(name string, list []config.BodyMatch) ([]*Predicate, error) {
+	if len(list) == 0 {
+		return nil, fmt.Errorf("%s is empty", name)
+	}
+	out := make([]*Predicate, len(list))
+	for i := range list {
+		var err error
+		if out[i], err = NewPredicate(&list[i]); err != nil {
+			return nil, fmt.Errorf("%s[%d]: %w", name, i, err)
+		}
+	}
+	return out, nil
+}
+
+// toJSON turns a value decoded from YAML into the form encoding/json decodes
+// the same value to, so that it compares equal to a request body.
+func toJSON(v any) (any, error) {
+	data, err := json.Marshal(config.JSONValue(v))
+	if err != nil {
+		return nil, err
+	}
+	var out any
+	err = json.Unmarshal(data, &out)
+	return out, err
+}
+
+// body is a request body with its decoded forms, parsed on first use.
+type body struct {
+	raw []byte
+
+	jsonDone bool
+	json     any
+	jsonErr  error
+
+	xmlDone bool
+	xml     *xmlNode
+	xmlErr  error
+
+	partsDone bool
+	parts     []*bodyPart
+	partsErr  error
+}
+
+func (b *body) decodeJSON() (any, error) {
+	if !b.jsonDone {
+		b.jsonDone = true
+		b.jsonErr = json.Unmarshal(b.raw, &b.json)
+	}
+	return b.json, b.jsonErr
+}
+
+func (b *body) decodeXML() (*xmlNode, error) {
+	if !b.xmlDone {
+		b.xmlDone = true
+		b.xml, b.xmlErr = parseXML(b.raw)
+	}
+	return b.xml, b.xmlErr
+}
+
+// Test reports whether raw, a request body, satisfies p.
+func (p *Predicate) Test(raw []byte) bool {
+	return p.test(&body{raw: raw})
+}
+
+func (p *Predicate) test(b *body) bool {
+	switch {
+	case p.all != nil:
+		for _, sub := range p.all {
+			if !sub.test(b) {
+				return false
+			}
+		}
+		return true
+	case p.any != nil:
+		for _, sub := range p.any {
+			if sub.test(b) {
+				return true
+			}
+		}
+		return false
+	case p.regex != nil:
+		return p.regex.Match(b.raw)
+	case p.part != nil:
+		return p.part.test(b)
+	case p.jsonPath != nil:
+		doc, err := b.decodeJSON()
+		if err != nil {
+			return false
+		}
+		for _, v := range p.jsonPath.eval(doc) {
+			if p.testValue(v) {
+				return true
+			}
+		}
+		return false
+	default:
+		doc, err := b.decodeXML()
+		if err != nil {
+			return false
+		}
+		for _, s := range p.xPath.eval(doc) {
+			if p.testText(s) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// testText tests a string XPath selects against the text of equals or
+// contains.
+func (p *Predicate) testText(s string) bool {
+	switch {
+	case p.hasEquals:
+		return s == text(p.equals)
+	case p.hasContains:
+		return strings.Contains(s, text(p.contains))
+	}
+	return true
+}
+
+// testValue tests a value a JSONPath selects against equals or contains.
+func (p *Predicate) testValue(v any) bool {
+	switch {
+	case p.hasEquals:
+		return reflect.DeepEqual(v, p.equals)
+	case p.hasContains:
+		return contains(v, p.contains)
+	}
+	return true
+}
+
+// contains reports whether a JSON value contains another: a substring of a
+// string, an element of an array, or a subset of the members of an object.
+func contains(v, part any) bool {
+	switch v := v.(type) {
+	case string:
+		s, ok := part.(string)
+		return ok && strings.Contains(v, s)
+	case []any:
+		for _, e := range v {
+			if reflect.DeepEqual(e, part) {
+				return true
+			}
+		}
+	case map[string]any:
+		members, ok := part.(map[string]any)
+		if !ok {
+			return false
+		}
+		for k, want := range members {
+			if got, ok := v[k]; !ok || !reflect.DeepEqual(got, want) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// text returns the XML text of a config value, e.g. 1 for the number 1.
+func text(v any) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	data, _ := json.Marshal(v)
+	return string(data)
+}
@@ -0,0 +1,201 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package match
+
+import (
+	"testing"
+
+	"github.com/google/test-server/internal/config"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestJSONPath(t *testing.T) {
+	doc := map[string]any{
+		"model": "gemini",
+		"contents": []any{
+			map[string]any{"role": "user", "parts": []any{map[string]any{"text": "hi"}}},
+			map[string]any{"role": "model", "parts": []any{map[string]any{"text": "hello"}}},
+		},
+		"config": map[string]any{"temperature": 0.5},
+	}
+	tests := []struct {
+		expr string
+		want []any
+	}{
+		{"$", []any{doc}},
+		{"$.model", []any{"gemini"}},
+		{"$['model']", []any{"gemini"}},
+		{"$.contents[1].role", []any{"model"}},
+		{"$.contents[-1].role", []any{"model"}},
+		{"$.contents[5].role", nil},
+		{"$.contents[*].role", []any{"user", "model"}},
+		{"$.config.*", []any{0.5}},
+		{"$..text", []any{"hi", "hello"}},
+		{"$.missing.deeper", nil},
+	}
+	for _, tt := range tests {
+		p, err := compileJSONPath(tt.expr)
+		require.NoError(t, err, tt.expr)
+		require.Equal(t, tt.want, p.eval(doc), tt.expr)
+	}
+	for _, bad := range []string{"model", "$.", "$[model]", "$[0", "$model"} {
+		_, err := compileJSONPath(bad)
+		require.Error(t, err, bad)
+	}
+}
+
+func TestXPath(t *testing.T) {
+	doc, err := parseXML([]byte(`<?xml version="1.0"?>
+<a:envelope xmlns:a="urn:x"><a:body>
+  <item id="1">one</item>
+  <item id="2">two<sub>!</sub></item>
+</a:body></a:envelope>`))
+	require.NoError(t, err)
+	tests := []struct {
+		expr string
+		want []string
+	}{
+		{"/envelope/body/item", []string{"one", "two!"}},
+		{"/a:envelope/a:body/item[2]", []string{"two!"}},
+		{"//item[@id='2']/text()", []string{"two"}},
+		{"//item/@id", []string{"1", "2"}},
+		{"//sub", []string{"!"}},
+		{"/*/*/item[3]", nil},
+		{"/body", nil},
+	}
+	for _, tt := range tests {
+		p, err := compileXPath(tt.expr)
+		require.NoError(t, err, tt.expr)
+		require.Equal(t, tt.want, p.eval(doc), tt.expr)
+	}
+	for _, bad := range []string{"item", "/item[last()]", "/@id/item", "/item[1"} {
+		_, err := compileXPath(bad)
+		require.Error(t, err, bad)
+	}
+	_, err = parseXML([]byte(`{"not": "xml"}`))
+	require.Error(t, err)
+}
+
+// predicate compiles a BodyMatch written in YAML, as in a config file.
+func predicate(t *testing.T, src string) *Predicate {
+	t.Helper()
+	var c config.BodyMatch
+	require.NoError(t, yaml.Unmarshal([]byte(src), &c))
+	p, err := NewPredicate(&c)
+	require.NoError(t, err, src)
+	return p
+}
+
+func TestPredicate(t *testing.T) {
+	body := []byte(`{"contents": [{"role": "user", "parts": [{"text": "Hello there"}]}],
+		"generationConfig": {"temperature": 0.5, "candidateCount": 1}, "model": "gemini"}`)
+	tests := []struct {
+		src  string
+		want bool
+	}{
+		{`json_path: $.model`, true},
+		{`json_path: $.tools`, false},
+		{`{json_path: $.model, equals: gemini}`, true},
+		{`{json_path: $.model, equals: other}`, false},
+		{`{json_path: $.generationConfig.temperature, equals: 0.5}`, true},
+		{`{json_path: $.generationConfig, equals: {candidateCount: 1, temperature: 0.5}}`, true},
+		{`{json_path: $.generationConfig, contains: {candidateCount: 1}}`, true},
+		{`{json_path: "$..text", contains: Hello}`, true},
+		{`{json_path: "$.contents[*].role", equals: model}`, false},
+		{`{json_path: $.contents, contains: {role: user, parts: [{text: Hello there}]}}`, true},
+		{`{json_path: $, contains: {model: gemini}}`, true},
+		{`regex: '"role":\s*"user"'`, true},
+		{`{all: [{json_path: $.model, equals: gemini}, {regex: Hello}]}`, true},
+		{`{all: [{json_path: $.model, equals: gemini}, {regex: Goodbye}]}`, false},
+		{`{any: [{json_path: $.model, equals: other}, {regex: Hello}]}`, true},
+		{`{xpath: /request/model}`, false},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.want, predicate(t, tt.src).Test(body), tt.src)
+	}
+
+	xml := []byte(`<request><model version="2">gemini</model><n>1</n></request>`)
+	require.True(t, predicate(t, `{xpath: /request/model, equals: gemini}`).Test(xml))
+	require.True(t, predicate(t, `{xpath: /request/n, equals: 1}`).Test(xml))
+	require.True(t, predicate(t, `{xpath: //model/@version, contains: "2"}`).Test(xml))
+	require.False(t, predicate(t, `{xpath: /request/model, contains: pro}`).Test(xml))
+	require.False(t, predicate(t, `json_path: $.model`).Test(xml))
+}
+
+func TestPartPredicate(t *testing.T) {
+	form := []byte("--xyz\r\n" +
+		"Content-Disposition: form-data; name=\"metadata\"\r\n" +
+		"Content-Type: application/json; charset=UTF-8\r\n\r\n" +
+		`{"name": "a.txt"}` + "\r\n" +
+		"--xyz\r\n" +
+		"Content-Disposition: form-data; name=\"file\"; filename=\"a.txt\"\r\n" +
+		"Content-Type: text/plain\r\n\r\n" +
+		"hello\r\n" +
+		"--xyz--\r\n")
+	batch := []byte("--batch_1\r\n" +
+		"Content-Type: application/http\r\n" +
+		"Content-ID: <item1>\r\n\r\n" +
+		"GET /storage/v1/b/bucket/o/a HTTP/1.1\r\n\r\n" +
+		"\r\n--batch_1\r\n" +
+		"Content-Type: application/http\r\n" +
+		"Content-ID: <item2>\r\n\r\n" +
+		"PATCH /storage/v1/b/bucket/o/b HTTP/1.1\r\n" +
+		"Content-Type: application/json\r\n\r\n" +
+		`{"metadata": {"k": "v"}}` +
+		"\r\n--batch_1--\r\n")
+	tests := []struct {
+		src  string
+		body []byte
+		want bool
+	}{
+		{`part: {name: metadata, body: {json_path: $.name, equals: a.txt}}`, form, true},
+		{`part: {name: metadata, body: {json_path: $.name, equals: b.txt}}`, form, false},
+		{`part: {name: file, content_type: text/plain, body: {regex: ^hello$}}`, form, true},
+		{`part: {index: 1, content_type: application/json}`, form, false},
+		{`part: {index: 2}`, form, false},
+		{`part: {method: GET, path: /storage/v1/b/*/o/a}`, batch, true},
+		{`part: {method: PATCH, path: /storage/v1/b/*/o/a}`, batch, false},
+		{`part: {index: 1, body: {json_path: $.metadata.k, equals: v}}`, batch, true},
+		{`part: {index: 0, body: {json_path: $.metadata}}`, batch, false},
+		{`part: {name: metadata}`, []byte(`{"name": "metadata"}`), false},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.want, predicate(t, tt.src).Test(tt.body), tt.src)
+	}
+}
+
+func TestNewPredicateRejectsInvalidConfigs(t *testing.T) {
+	for _, src := range []string{
+		`{}`,
+		`{json_path: $.a, regex: a}`,
+		`{regex: a, equals: b}`,
+		`{json_path: $.a, equals: b, contains: c}`,
+		`{all: []}`,
+		`{any: [{regex: "("}]}`,
+		`json_path: a`,
+		`xpath: a`,
+		`{part: {index: -1}}`,
+		`{part: {path: "["}}`,
+		`{part: {body: {}}}`,
+	} {
+		var c config.BodyMatch
+		require.NoError(t, yaml.Unmarshal([]byte(src), &c))
+		_, err := NewPredicate(&c)
+		require.Error(t, err, src)
+	}
+}
@@ -0,0 +1,256 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package match
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// xmlNode is an element of a parsed XML document.
+type xmlNode struct {
+	name     string // the local name, without the namespace
+	attrs    map[string]string
+	children []*xmlNode
+	// text is the character data of the element and its descendants.
+	text strings.Builder
+	// ownText is the character data directly inside the element.
+	ownText strings.Builder
+}
+
+// parseXML returns a node whose only child is the root element of data.
+func parseXML(data []byte) (*xmlNode, error) {
+	doc := &xmlNode{}
+	stack := []*xmlNode{doc}
+	d := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			if errors.Is(err, io.EOF) && len(stack) == 1 && len(doc.children) == 1 {
+				return doc, nil
+			}
+			return nil, fmt.Errorf("invalid XML: %v", err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			n := &xmlNode{name: t.Name.Local, attrs: map[string]string{}}
+			for _, a := range t.Attr {
+				n.attrs[a.Name.Local] = a.Value
+			}
+			parent := stack[len(stack)-1]
+			parent.children = append(parent.children, n)
+			stack = append(stack, n)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			stack[len(stack)-1].ownText.Write(t)
+			for _, n := range stack {
+				n.text.Write(t)
+			}
+		}
+	}
+}
+
+// xPath is a compiled XPath expression. It supports absolute location paths
+// of element names, * and // steps, with [n] and [@name='value'] predicates,
+// ending in an element, @name or text().
+type xPath []xStep
+
+type xStep struct {
+	// descend applies the step to every element below the current ones too,
+	// for a step after "//".
+	descend bool
+	// name is the element or attribute name, or * for any element.
+	name string
+	attr bool
+	text bool
+	// position is the 1-based [n] predicate, or 0. It applies after the
+	// attribute predicate.
+	position int
+	// attrName and attrValue are the [@name='value'] predicate, if any.
+	attrName, attrValue string
+}
+
+var (
+	xNameRe     = regexp.MustCompile(`^(\*|[A-Za-z_][\w.-]*(:[A-Za-z_][\w.-]*)?)`)
+	xAttrPredRe = regexp.MustCompile(`^@([A-Za-z_][\w.:-]*)\s*=\s*(?:'([^']*)'|"([^"]*)")$`)
+	xPositionRe = regexp.MustCompile(`^[1-9][0-9]*$`)
+	xTerminalRe = regexp.MustCompile(`^(@[A-Za-z_][\w.:-]*|text\(\))$`)
+)
+
+func compileXPath(expr string) (xPath, error) {
+	if !strings.HasPrefix(expr, "/") {
+		return nil, fmt.Errorf("XPath %q is not an absolute path", expr)
+	}
+	var p xPath
+	rest := expr
+	for rest != "" {
+		var s xStep
+		switch {
+		case strings.HasPrefix(rest, "//"):
+			s.descend = true
+			rest = rest[2:]
+		case strings.HasPrefix(rest, "/"):
+			rest = rest[1:]
+		default:
+			return nil, fmt.Errorf("XPath %q: unexpected %q", expr, rest)
+		}
+		end := stepEnd(rest)
+		step := rest[:end]
+		rest = rest[end:]
+		if xTerminalRe.MatchString(step) {
+			if rest != "" {
+				return nil, fmt.Errorf("XPath %q: %s must be the last step", expr, step)
+			}
+			if step == "text()" {
+				s.text = true
+			} else {
+				s.attr, s.name = true, localName(step[1:])
+			}
+			p = append(p, s)
+			break
+		}
+		name := xNameRe.FindString(step)
+		if name == "" {
+			return nil, fmt.Errorf("XPath %q: invalid step %q", expr, step)
+		}
+		s.name = localName(name)
+		for preds := step[len(name):]; preds != ""; {
+			if preds[0] != '[' {
+				return nil, fmt.Errorf("XPath %q: invalid step %q", expr, step)
+			}
+			close := strings.IndexByte(preds, ']')
+			if close < 0 {
+				return nil, fmt.Errorf("XPath %q has an unclosed [", expr)
+			}
+			pred := strings.TrimSpace(preds[1:close])
+			preds = preds[close+1:]
+			if m := xAttrPredRe.FindStringSubmatch(pred); m != nil {
+				s.attrName, s.attrValue = localName(m[1]), m[2]+m[3]
+			} else if xPositionRe.MatchString(pred) {
+				s.position, _ = strconv.Atoi(pred)
+			} else {
+				return nil, fmt.Errorf("XPath %q: unsupported predicate [%s]", expr, pred)
+			}
+		}
+		p = append(p, s)
+	}
+	if len(p) == 0 {
+		return nil, fmt.Errorf("XPath %q selects nothing", expr)
+	}
+	return p, nil
+}
+
+// stepEnd returns the index of the slash ending the first step of path, or
+// its length, skipping the slashes inside predicates.
+func stepEnd(path string) int {
+	depth := 0
+	for i := 0; i < len(path); i++ {
+		switch path[i] {
+		case '[':
+			depth++
+		case ']':
+			depth--
+		case '/':
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(path)
+}
+
+// localName drops the namespace prefix of name.
+func localName(name string) string {
+	if _, local, ok := strings.Cut(name, ":"); ok {
+		return local
+	}
+	return name
+}
+
+// eval returns the string values of what p selects in doc.
+func (p xPath) eval(doc *xmlNode) []string {
+	nodes := []*xmlNode{doc}
+	for _, s := range p {
+		if s.descend {
+			var all []*xmlNode
+			for _, n := range nodes {
+				all = appendXMLDescendants(all, n)
+			}
+			nodes = all
+		}
+		switch {
+		case s.attr:
+			var values []string
+			for _, n := range nodes {
+				if v, ok := n.attrs[s.name]; ok {
+					values = append(values, v)
+				}
+			}
+			return values
+		case s.text:
+			var values []string
+			for _, n := range nodes {
+				if n != doc {
+					values = append(values, n.ownText.String())
+				}
+			}
+			return values
+		}
+		var next []*xmlNode
+		for _, n := range nodes {
+			var matched []*xmlNode
+			for _, c := range n.children {
+				if s.name != "*" && c.name != s.name {
+					continue
+				}
+				if s.attrName != "" && c.attrs[s.attrName] != s.attrValue {
+					continue
+				}
+				matched = append(matched, c)
+			}
+			if s.position > 0 {
+				if s.position > len(matched) {
+					continue
+				}
+				matched = matched[s.position-1 : s.position]
+			}
+			next = append(next, matched...)
+		}
+		nodes = next
+	}
+	var values []string
+	for _, n := range nodes {
+		values = append(values, n.text.String())
+	}
+	return values
+}
+
+// appendXMLDescendants appends n and every element below it to out.
+func appendXMLDescendants(out []*xmlNode, n *xmlNode) []*xmlNode {
+	out = append(out, n)
+	for _, c := range n.children {
+		out = appendXMLDescendants(out, c)
+	}
+	return out
+}
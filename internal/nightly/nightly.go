@@ -0,0 +1,163 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package nightly reads and updates nightly.json, the manifest of the nightly
+// snapshot builds. Unlike releases, snapshots are not added to the SDK
+// checksums.json files: the SDK installers resolve the nightly channel from
+// the published manifest, and snapshots expire after a few days.
+package nightly
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/test-server/internal/semver"
+)
+
+const (
+	// Channel is the TEST_SERVER_CHANNEL value the installers resolve through
+	// the manifest.
+	Channel = "nightly"
+	// ManifestName is the file name of the manifest on GitHub Pages.
+	ManifestName = "nightly.json"
+	// ManifestURL is where the installers read the manifest from.
+	ManifestURL = "https://google.github.io/test-server/" + ManifestName
+
+	schemaVersion = 1
+	prerelease    = "nightly."
+)
+
+// Asset is one archive of a snapshot.
+type Asset struct {
+	OS      string `json:"os"`
+	Arch    string `json:"arch"`
+	Archive string `json:"archive"`
+	URL     string `json:"url"`
+	SHA256  string `json:"sha256"`
+}
+
+// Snapshot is one nightly build.
+type Snapshot struct {
+	Version string    `json:"version"`
+	Commit  string    `json:"commit"`
+	Date    time.Time `json:"date"`
+	Assets  []Asset   `json:"assets"`
+}
+
+// Manifest is the published nightly.json, snapshots newest first.
+type Manifest struct {
+	SchemaVersion int        `json:"schema_version"`
+	GeneratedAt   string     `json:"generated_at"`
+	RetentionDays int        `json:"retention_days"`
+	Latest        string     `json:"latest"`
+	Snapshots     []Snapshot `json:"snapshots"`
+}
+
+// Parse parses a manifest. An empty input is an empty manifest, for the
+// first run.
+func Parse(data []byte) (*Manifest, error) {
+	m := &Manifest{SchemaVersion: schemaVersion}
+	if len(strings.TrimSpace(string(data))) == 0 {
+		return m, nil
+	}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", ManifestName, err)
+	}
+	if m.SchemaVersion != schemaVersion {
+		return nil, fmt.Errorf("%s has schema version %d, want %d", ManifestName, m.SchemaVersion, schemaVersion)
+	}
+	return m, nil
+}
+
+// Version returns the snapshot version built on date after the stable release
+// latest: the next patch version with a "nightly.YYYYMMDD" pre-release, so
+// that it sorts after latest and before the release that follows it.
+func Version(latest semver.Version, date time.Time) string {
+	next := semver.Version{Major: latest.Major, Minor: latest.Minor, Patch: latest.Patch + 1, Pre: prerelease + date.UTC().Format("20060102")}
+	return next.Tag()
+}
+
+// IsSnapshot reports whether tag is a nightly snapshot version.
+func IsSnapshot(tag string) bool {
+	v, err := semver.Parse(tag)
+	return err == nil && strings.HasPrefix(v.Pre, prerelease)
+}
+
+// Find returns the snapshot with the given version.
+func (m *Manifest) Find(version string) (Snapshot, bool) {
+	for _, s := range m.Snapshots {
+		if s.Version == version {
+			return s, true
+		}
+	}
+	return Snapshot{}, false
+}
+
+// Add adds s, replacing a snapshot with the same version, and makes the
+// newest snapshot the latest.
+func (m *Manifest) Add(s Snapshot) {
+	kept := m.Snapshots[:0]
+	for _, old := range m.Snapshots {
+		if old.Version != s.Version {
+			kept = append(kept, old)
+		}
+	}
+	m.Snapshots = append(kept, s)
+	m.sort()
+}
+
+// Expire removes and returns the snapshots older than retention at now,
+// always keeping the newest keep snapshots so that the channel never goes
+// empty when the nightly build breaks for a while.
+func (m *Manifest) Expire(now time.Time, retention time.Duration, keep int) []Snapshot {
+	m.sort()
+	var kept, expired []Snapshot
+	for i, s := range m.Snapshots {
+		if i >= keep && now.Sub(s.Date) > retention {
+			expired = append(expired, s)
+		} else {
+			kept = append(kept, s)
+		}
+	}
+	m.Snapshots = kept
+	m.sort()
+	return expired
+}
+
+func (m *Manifest) sort() {
+	sort.SliceStable(m.Snapshots, func(i, j int) bool { return m.Snapshots[i].Date.After(m.Snapshots[j].Date) })
+	m.Latest = ""
+	if len(m.Snapshots) > 0 {
+		m.Latest = m.Snapshots[0].Version
+	}
+}
+
+// Marshal returns the manifest as published, stamped with now.
+func (m *Manifest) Marshal(now time.Time) ([]byte, error) {
+	m.SchemaVersion = schemaVersion
+	m.GeneratedAt = now.UTC().Format(time.RFC3339)
+	if m.Snapshots == nil {
+		m.Snapshots = []Snapshot{}
+	}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
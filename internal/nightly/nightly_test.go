@@ -0,0 +1,98 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nightly
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/test-server/internal/semver"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVersion(t *testing.T) {
+	date := time.Date(2026, 10, 16, 3, 0, 0, 0, time.UTC)
+	tests := []struct {
+		latest string
+		want   string
+	}{
+		{"v0.2.8", "v0.2.9-nightly.20261016"},
+		{"v1.0.0", "v1.0.1-nightly.20261016"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.latest, func(t *testing.T) {
+			latest, err := semver.Parse(tt.latest)
+			require.NoError(t, err)
+			got := Version(latest, date)
+			require.Equal(t, tt.want, got)
+			require.True(t, IsSnapshot(got))
+
+			v, err := semver.Parse(got)
+			require.NoError(t, err)
+			require.True(t, semver.Less(latest, v))
+		})
+	}
+	require.False(t, IsSnapshot("v0.3.0-beta.1"))
+	require.False(t, IsSnapshot("nightly"))
+}
+
+func TestAddAndExpire(t *testing.T) {
+	now := time.Date(2026, 10, 16, 3, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	m, err := Parse(nil)
+	require.NoError(t, err)
+	for i := 20; i >= 0; i-- {
+		date := now.Add(-time.Duration(i) * day)
+		m.Add(Snapshot{Version: "v0.2.9-nightly." + date.Format("20060102"), Date: date})
+	}
+	require.Len(t, m.Snapshots, 21)
+	require.Equal(t, "v0.2.9-nightly.20261016", m.Latest)
+
+	// A rebuild of the same day replaces that day's snapshot.
+	m.Add(Snapshot{Version: "v0.2.9-nightly.20261016", Commit: "abc", Date: now.Add(time.Hour)})
+	require.Len(t, m.Snapshots, 21)
+	s, ok := m.Find("v0.2.9-nightly.20261016")
+	require.True(t, ok)
+	require.Equal(t, "abc", s.Commit)
+
+	expired := m.Expire(now, 14*day, 3)
+	require.Len(t, expired, 6)
+	require.Len(t, m.Snapshots, 15)
+	require.Equal(t, "v0.2.9-nightly.20261002", m.Snapshots[len(m.Snapshots)-1].Version)
+
+	// The newest snapshots are kept however old they are.
+	expired = m.Expire(now.Add(100*day), 14*day, 3)
+	require.Len(t, expired, 12)
+	require.Len(t, m.Snapshots, 3)
+	require.Equal(t, "v0.2.9-nightly.20261016", m.Latest)
+}
+
+func TestParse(t *testing.T) {
+	m, err := Parse([]byte(`{"schema_version": 2}`))
+	require.Error(t, err)
+	require.Nil(t, m)
+
+	orig := &Manifest{RetentionDays: 14}
+	orig.Add(Snapshot{Version: "v0.2.9-nightly.20261016", Commit: "abc", Date: time.Date(2026, 10, 16, 3, 0, 0, 0, time.UTC),
+		Assets: []Asset{{OS: "linux", Arch: "amd64", Archive: "test-server_Linux_x86_64.tar.gz", SHA256: "00"}}})
+	data, err := orig.Marshal(time.Now())
+	require.NoError(t, err)
+	m, err = Parse(data)
+	require.NoError(t, err)
+	require.Equal(t, orig.Snapshots, m.Snapshots)
+	require.Equal(t, "v0.2.9-nightly.20261016", m.Latest)
+}
@@ -0,0 +1,364 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package oauth emulates the token endpoint of an OAuth 2.0 authorization
+// server and the JWKS and discovery documents of an OpenID Connect provider,
+// so that the auth flows of an SDK can run end to end without a real
+// identity provider. Every grant succeeds: the endpoint issues tokens, it
+// does not check credentials.
+package oauth
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/test-server/internal/clock"
+	"github.com/google/test-server/internal/config"
+)
+
+// Default paths of the endpoints.
+const (
+	DefaultTokenPath = "/token"
+	DefaultJWKSPath  = "/.well-known/jwks.json"
+	// DiscoveryPath serves the OpenID Connect discovery document.
+	DiscoveryPath = "/.well-known/openid-configuration"
+)
+
+// JWTBearerGrant is the grant type of a service account exchanging a signed
+// assertion for a token.
+const JWTBearerGrant = "urn:ietf:params:oauth:grant-type:jwt-bearer"
+
+// grantTypes are the grant types the token endpoint accepts.
+var grantTypes = []string{"authorization_code", "client_credentials", "password", "refresh_token", JWTBearerGrant}
+
+// Server issues tokens signed with RS256.
+type Server struct {
+	tokenPath, jwksPath string
+	issuer              string
+	expiresIn           time.Duration
+	claims              map[string]any
+	key                 *rsa.PrivateKey
+	keyID               string
+	// now is the clock of the iat and exp claims.
+	now func() time.Time
+}
+
+// New validates c and returns its Server, loading or generating its key.
+func New(c *config.OAuth) (*Server, error) {
+	s := &Server{
+		tokenPath: c.TokenPath,
+		jwksPath:  c.JWKSPath,
+		issuer:    strings.TrimSuffix(c.Issuer, "/"),
+		expiresIn: time.Hour,
+		keyID:     c.KeyID,
+		now:       clock.Server.Now,
+	}
+	if s.tokenPath == "" {
+		s.tokenPath = DefaultTokenPath
+	}
+	if s.jwksPath == "" {
+		s.jwksPath = DefaultJWKSPath
+	}
+	if c.ExpiresIn != "" {
+		d, err := time.ParseDuration(c.ExpiresIn)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("expires_in: invalid duration %q", c.ExpiresIn)
+		}
+		s.expiresIn = d
+	}
+	if c.Claims != nil {
+		s.claims = config.JSONValue(c.Claims).(map[string]any)
+	}
+	var err error
+	if c.SigningKey != "" {
+		if s.key, err = loadKey(c.SigningKey); err != nil {
+			return nil, fmt.Errorf("signing_key: %w", err)
+		}
+	} else if s.key, err = rsa.GenerateKey(rand.Reader, 2048); err != nil {
+		return nil, err
+	}
+	if s.keyID == "" {
+		sum := sha256.Sum256(s.key.PublicKey.N.Bytes())
+		s.keyID = hex.EncodeToString(sum[:8])
+	}
+	return s, nil
+}
+
+// loadKey reads an RSA private key in PKCS #1 or PKCS #8 PEM.
+func loadKey(path string) (*rsa.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s is not a PEM file", path)
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s does not hold an RSA key", path)
+	}
+	return rsaKey, nil
+}
+
+// Wrap returns a handler serving the token, JWKS and discovery endpoints and
+// passing every other request to next.
+func (s *Server) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case s.tokenPath:
+			s.serveToken(w, req)
+		case s.jwksPath:
+			s.serveJWKS(w)
+		case DiscoveryPath:
+			s.serveDiscovery(w, req)
+		default:
+			next.ServeHTTP(w, req)
+		}
+	})
+}
+
+// issuerOf returns the configured issuer, or the URL req reached.
+func (s *Server) issuerOf(req *http.Request) string {
+	if s.issuer != "" {
+		return s.issuer
+	}
+	scheme := "http"
+	if req.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + req.Host
+}
+
+func (s *Server) serveToken(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, "invalid_request", "the token endpoint only accepts POST")
+		return
+	}
+	if err := req.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	grant := req.PostForm.Get("grant_type")
+	switch {
+	case grant == "":
+		writeError(w, http.StatusBadRequest, "invalid_request", "grant_type is missing")
+		return
+	case !slices.Contains(grantTypes, grant):
+		writeError(w, http.StatusBadRequest, "unsupported_grant_type", fmt.Sprintf("grant_type %s is not supported", grant))
+		return
+	case grant == JWTBearerGrant && req.PostForm.Get("assertion") == "":
+		writeError(w, http.StatusBadRequest, "invalid_request", "assertion is missing")
+		return
+	}
+
+	now := s.now()
+	claims := map[string]any{
+		"iss": s.issuerOf(req),
+		"sub": subject(req),
+		"iat": now.Unix(),
+		"exp": now.Add(s.expiresIn).Unix(),
+	}
+	scope := req.PostForm.Get("scope")
+	if scope != "" {
+		claims["scope"] = scope
+	}
+	if clientID := clientID(req); clientID != "" {
+		claims["aud"] = clientID
+		claims["azp"] = clientID
+	}
+	for k, v := range s.claims {
+		claims[k] = v
+	}
+	accessToken, err := s.Sign(claims)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+
+	resp := map[string]any{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   int(s.expiresIn.Seconds()),
+	}
+	if scope != "" {
+		resp["scope"] = scope
+	}
+	if slices.Contains(strings.Fields(scope), "openid") {
+		if resp["id_token"], err = s.Sign(claims); err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+	}
+	if grant == "authorization_code" || grant == "password" {
+		resp["refresh_token"] = randomToken()
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// subject is the sub of the tokens issued to req: the client, the subject or
+// issuer of a JWT bearer assertion, or the user of a password grant.
+func subject(req *http.Request) string {
+	if assertion := req.PostForm.Get("assertion"); assertion != "" {
+		if claims, err := decodeClaims(assertion); err == nil {
+			for _, k := range []string{"sub", "iss"} {
+				if v, ok := claims[k].(string); ok && v != "" {
+					return v
+				}
+			}
+		}
+	}
+	if user := req.PostForm.Get("username"); user != "" {
+		return user
+	}
+	if id := clientID(req); id != "" {
+		return id
+	}
+	return "test-server"
+}
+
+// clientID returns the client of req, from the form or HTTP basic auth.
+func clientID(req *http.Request) string {
+	if id := req.PostForm.Get("client_id"); id != "" {
+		return id
+	}
+	id, _, _ := req.BasicAuth()
+	return id
+}
+
+// decodeClaims returns the claims of a JWT without verifying it.
+func decodeClaims(token string) (map[string]any, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("not a JWT")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, err
+	}
+	var claims map[string]any
+	err = json.Unmarshal(payload, &claims)
+	return claims, err
+}
+
+// Sign returns a JWT with claims signed by the key of s.
+func (s *Server) Sign(claims map[string]any) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": s.keyID})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// Verify returns the claims of token if s signed it and it has not expired.
+func (s *Server) Verify(token string) (map[string]any, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("not a JWT")
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(&s.key.PublicKey, crypto.SHA256, digest[:], sig); err != nil {
+		return nil, fmt.Errorf("the token was not issued by this endpoint")
+	}
+	claims, err := decodeClaims(token)
+	if err != nil {
+		return nil, err
+	}
+	if exp, ok := claims["exp"].(float64); !ok || s.now().Unix() >= int64(exp) {
+		return nil, fmt.Errorf("the token has expired")
+	}
+	return claims, nil
+}
+
+func (s *Server) serveJWKS(w http.ResponseWriter) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"kid": s.keyID,
+			"n":   base64.RawURLEncoding.EncodeToString(s.key.PublicKey.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(s.key.PublicKey.E)).Bytes()),
+		}},
+	})
+}
+
+func (s *Server) serveDiscovery(w http.ResponseWriter, req *http.Request) {
+	issuer := s.issuerOf(req)
+	writeJSON(w, http.StatusOK, map[string]any{
+		"issuer":                                issuer,
+		"token_endpoint":                        issuer + s.tokenPath,
+		"jwks_uri":                              issuer + s.jwksPath,
+		"grant_types_supported":                 grantTypes,
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"response_types_supported":              []string{"code", "token", "id_token"},
+		"subject_types_supported":               []string{"public"},
+	})
+}
+
+// writeError answers with an OAuth 2.0 error, as in RFC 6749 section 5.2.
+func writeError(w http.ResponseWriter, status int, code, description string) {
+	writeJSON(w, status, map[string]string{"error": code, "error_description": description})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func randomToken() string {
+	var b [24]byte
+	rand.Read(b[:])
+	return base64.RawURLEncoding.EncodeToString(b[:])
+}
@@ -0,0 +1,228 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oauth
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/test-server/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+// start serves s on a test server whose other requests answer 404.
+func start(t *testing.T, s *Server) *httptest.Server {
+	t.Helper()
+	s.now = func() time.Time { return time.Unix(1700000000, 0) }
+	server := httptest.NewServer(s.Wrap(http.NotFoundHandler()))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func get(t *testing.T, target string) (int, map[string]any) {
+	t.Helper()
+	resp, err := http.Get(target)
+	return decode(t, resp, err)
+}
+
+func post(t *testing.T, target string, form url.Values) (int, map[string]any) {
+	t.Helper()
+	resp, err := http.PostForm(target, form)
+	return decode(t, resp, err)
+}
+
+func decode(t *testing.T, resp *http.Response, err error) (int, map[string]any) {
+	t.Helper()
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	var body map[string]any
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	return resp.StatusCode, body
+}
+
+// verify checks the signature of token against the JWKS of server and
+// returns its claims.
+func verify(t *testing.T, server *httptest.Server, token string) map[string]any {
+	t.Helper()
+	_, jwks := get(t, server.URL+DefaultJWKSPath)
+	key := jwks["keys"].([]any)[0].(map[string]any)
+	n, err := base64.RawURLEncoding.DecodeString(key["n"].(string))
+	require.NoError(t, err)
+	e, err := base64.RawURLEncoding.DecodeString(key["e"].(string))
+	require.NoError(t, err)
+	pub := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+
+	parts := strings.Split(token, ".")
+	require.Len(t, parts, 3)
+	header, err := base64.RawURLEncoding.DecodeString(parts[0])
+	require.NoError(t, err)
+	require.JSONEq(t, `{"alg": "RS256", "typ": "JWT", "kid": "`+key["kid"].(string)+`"}`, string(header))
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	require.NoError(t, err)
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	require.NoError(t, rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], sig))
+
+	claims, err := decodeClaims(token)
+	require.NoError(t, err)
+	return claims
+}
+
+func TestToken(t *testing.T) {
+	s, err := New(&config.OAuth{ExpiresIn: "30m", Claims: map[string]any{"email": "tester@example.com", "aud": "my-api"}})
+	require.NoError(t, err)
+	server := start(t, s)
+
+	code, body := post(t, server.URL+DefaultTokenPath, url.Values{
+		"grant_type": {"client_credentials"},
+		"client_id":  {"my-client"},
+		"scope":      {"openid email"},
+	})
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, "Bearer", body["token_type"])
+	require.Equal(t, float64(1800), body["expires_in"])
+	require.Equal(t, "openid email", body["scope"])
+	require.Nil(t, body["refresh_token"])
+
+	claims := verify(t, server, body["access_token"].(string))
+	require.Equal(t, map[string]any{
+		"iss":   server.URL,
+		"sub":   "my-client",
+		"aud":   "my-api",
+		"azp":   "my-client",
+		"email": "tester@example.com",
+		"scope": "openid email",
+		"iat":   float64(1700000000),
+		"exp":   float64(1700001800),
+	}, claims)
+	require.Equal(t, claims, verify(t, server, body["id_token"].(string)))
+}
+
+func TestVerify(t *testing.T) {
+	s, err := New(&config.OAuth{})
+	require.NoError(t, err)
+	now := time.Unix(1700000000, 0)
+	s.now = func() time.Time { return now }
+	token, err := s.Sign(map[string]any{"sub": "me", "exp": now.Add(time.Minute).Unix()})
+	require.NoError(t, err)
+
+	claims, err := s.Verify(token)
+	require.NoError(t, err)
+	require.Equal(t, "me", claims["sub"])
+
+	other, err := New(&config.OAuth{})
+	require.NoError(t, err)
+	_, err = other.Verify(token)
+	require.ErrorContains(t, err, "not issued")
+	_, err = s.Verify("opaque")
+	require.Error(t, err)
+	now = now.Add(time.Minute)
+	_, err = s.Verify(token)
+	require.ErrorContains(t, err, "expired")
+}
+
+func TestJWTBearerGrant(t *testing.T) {
+	s, err := New(&config.OAuth{Issuer: "https://auth.test/"})
+	require.NoError(t, err)
+	server := start(t, s)
+
+	payload := base64.RawURLEncoding.EncodeToString([]byte(`{"iss": "sa@project.iam.gserviceaccount.com", "scope": "https://www.googleapis.com/auth/cloud-platform"}`))
+	code, body := post(t, server.URL+DefaultTokenPath, url.Values{
+		"grant_type": {JWTBearerGrant},
+		"assertion":  {"e30." + payload + ".c2ln"},
+	})
+	require.Equal(t, http.StatusOK, code)
+	claims := verify(t, server, body["access_token"].(string))
+	require.Equal(t, "sa@project.iam.gserviceaccount.com", claims["sub"])
+	require.Equal(t, "https://auth.test", claims["iss"])
+	require.Equal(t, float64(1700003600), claims["exp"])
+
+	_, discovery := get(t, server.URL+DiscoveryPath)
+	require.Equal(t, "https://auth.test", discovery["issuer"])
+	require.Equal(t, "https://auth.test/token", discovery["token_endpoint"])
+	require.Equal(t, "https://auth.test/.well-known/jwks.json", discovery["jwks_uri"])
+}
+
+func TestTokenErrors(t *testing.T) {
+	s, err := New(&config.OAuth{TokenPath: "/oauth2/v4/token"})
+	require.NoError(t, err)
+	server := start(t, s)
+
+	tests := []struct {
+		form       url.Values
+		wantStatus int
+		wantError  string
+	}{
+		{url.Values{}, http.StatusBadRequest, "invalid_request"},
+		{url.Values{"grant_type": {"implicit"}}, http.StatusBadRequest, "unsupported_grant_type"},
+		{url.Values{"grant_type": {JWTBearerGrant}}, http.StatusBadRequest, "invalid_request"},
+	}
+	for _, tt := range tests {
+		code, body := post(t, server.URL+"/oauth2/v4/token", tt.form)
+		require.Equal(t, tt.wantStatus, code, tt.form)
+		require.Equal(t, tt.wantError, body["error"], tt.form)
+	}
+	code, _ := get(t, server.URL+"/oauth2/v4/token")
+	require.Equal(t, http.StatusMethodNotAllowed, code)
+
+	// The default token path is not served when another one is set.
+	resp, err := http.PostForm(server.URL+DefaultTokenPath, url.Values{"grant_type": {"password"}})
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestSigningKey(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	dir := t.TempDir()
+	pkcs1 := filepath.Join(dir, "pkcs1.pem")
+	require.NoError(t, os.WriteFile(pkcs1, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}), 0600))
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	pkcs8 := filepath.Join(dir, "pkcs8.pem")
+	require.NoError(t, os.WriteFile(pkcs8, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600))
+
+	for _, path := range []string{pkcs1, pkcs8} {
+		s, err := New(&config.OAuth{SigningKey: path, KeyID: "test-key"})
+		require.NoError(t, err, path)
+		require.True(t, key.Equal(s.key), path)
+		require.Equal(t, "test-key", s.keyID)
+	}
+
+	for _, c := range []*config.OAuth{
+		{SigningKey: filepath.Join(dir, "missing.pem")},
+		{SigningKey: filepath.Join(dir, "pkcs1.pem"), ExpiresIn: "-1h"},
+	} {
+		_, err := New(c)
+		require.Error(t, err)
+	}
+}
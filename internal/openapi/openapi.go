@@ -0,0 +1,323 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package openapi reads OpenAPI 3 documents, in YAML or JSON, and turns
+// their operations into test-server stubs, answering with the examples the
+// document declares or with values made up from the response schemas.
+//
+// Only the parts of a document that describe requests and responses are
+// read, and only local references, e.g. #/components/schemas/Model, are
+// followed.
+package openapi
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Document is an OpenAPI 3.0 or 3.1 document.
+type Document struct {
+	OpenAPI    string               `yaml:"openapi"`
+	Servers    []Server             `yaml:"servers"`
+	Paths      map[string]*PathItem `yaml:"paths"`
+	Components Components           `yaml:"components"`
+}
+
+// Server is a base URL of the API, e.g. https://example.com/{version}.
+type Server struct {
+	URL       string `yaml:"url"`
+	Variables map[string]struct {
+		Default string `yaml:"default"`
+	} `yaml:"variables"`
+}
+
+// Components holds the objects the document refers to.
+type Components struct {
+	Schemas       map[string]*Schema      `yaml:"schemas"`
+	Responses     map[string]*Response    `yaml:"responses"`
+	Parameters    map[string]*Parameter   `yaml:"parameters"`
+	Examples      map[string]*Example     `yaml:"examples"`
+	RequestBodies map[string]*RequestBody `yaml:"requestBodies"`
+	Headers       map[string]*Header      `yaml:"headers"`
+}
+
+// PathItem holds the operations of a path.
+type PathItem struct {
+	Parameters []*Parameter `yaml:"parameters"`
+	Get        *Operation   `yaml:"get"`
+	Put        *Operation   `yaml:"put"`
+	Post       *Operation   `yaml:"post"`
+	Delete     *Operation   `yaml:"delete"`
+	Options    *Operation   `yaml:"options"`
+	Head       *Operation   `yaml:"head"`
+	Patch      *Operation   `yaml:"patch"`
+	Trace      *Operation   `yaml:"trace"`
+}
+
+// Operations returns the operations of p by method, in the order of the
+// specification.
+func (p *PathItem) Operations() []MethodOperation {
+	var out []MethodOperation
+	for _, m := range []MethodOperation{
+		{"GET", p.Get}, {"PUT", p.Put}, {"POST", p.Post}, {"DELETE", p.Delete},
+		{"OPTIONS", p.Options}, {"HEAD", p.Head}, {"PATCH", p.Patch}, {"TRACE", p.Trace},
+	} {
+		if m.Operation != nil {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// MethodOperation is an operation with its method.
+type MethodOperation struct {
+	Method string
+	*Operation
+}
+
+// Operation is an API call.
+type Operation struct {
+	OperationID string               `yaml:"operationId"`
+	Parameters  []*Parameter         `yaml:"parameters"`
+	RequestBody *RequestBody         `yaml:"requestBody"`
+	Responses   map[string]*Response `yaml:"responses"`
+}
+
+// Parameter is a path, query, header or cookie parameter.
+type Parameter struct {
+	Ref      string  `yaml:"$ref"`
+	Name     string  `yaml:"name"`
+	In       string  `yaml:"in"`
+	Required bool    `yaml:"required"`
+	Schema   *Schema `yaml:"schema"`
+}
+
+// RequestBody is the body of the requests of an operation.
+type RequestBody struct {
+	Ref      string                `yaml:"$ref"`
+	Required bool                  `yaml:"required"`
+	Content  map[string]*MediaType `yaml:"content"`
+}
+
+// Response is a response of an operation.
+type Response struct {
+	Ref     string                `yaml:"$ref"`
+	Headers map[string]*Header    `yaml:"headers"`
+	Content map[string]*MediaType `yaml:"content"`
+}
+
+// Header is a response header.
+type Header struct {
+	Ref      string  `yaml:"$ref"`
+	Required bool    `yaml:"required"`
+	Schema   *Schema `yaml:"schema"`
+	Example  any     `yaml:"example"`
+}
+
+// MediaType is the content of a body of one media type.
+type MediaType struct {
+	Schema   *Schema             `yaml:"schema"`
+	Example  any                 `yaml:"example"`
+	Examples map[string]*Example `yaml:"examples"`
+}
+
+// Example is a named example of a body.
+type Example struct {
+	Ref   string `yaml:"$ref"`
+	Value any    `yaml:"value"`
+}
+
+// Load reads the document at path.
+func Load(path string) (*Document, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return doc, nil
+}
+
+// Parse reads a document in YAML or JSON.
+func Parse(data []byte) (*Document, error) {
+	var doc Document
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		return nil, fmt.Errorf("openapi: %q is not an OpenAPI 3 document", doc.OpenAPI)
+	}
+	return &doc, nil
+}
+
+// SortedPaths returns the paths of d in order.
+func (d *Document) SortedPaths() []string {
+	paths := make([]string, 0, len(d.Paths))
+	for path := range d.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// BasePath returns the path of the first server URL, with its variables
+// set to their defaults, e.g. /v1 for https://example.com/v1, or "".
+func (d *Document) BasePath() string {
+	if len(d.Servers) == 0 {
+		return ""
+	}
+	s := d.Servers[0]
+	url := s.URL
+	for name, v := range s.Variables {
+		url = strings.ReplaceAll(url, "{"+name+"}", v.Default)
+	}
+	if i := strings.Index(url, "://"); i >= 0 {
+		url = url[i+3:]
+		if j := strings.Index(url, "/"); j >= 0 {
+			url = url[j:]
+		} else {
+			url = ""
+		}
+	}
+	return strings.TrimRight(url, "/")
+}
+
+// component returns the name of the component ref points to in the section
+// of the components, e.g. Model for #/components/schemas/Model.
+func component(ref, section string) (string, error) {
+	prefix := "#/components/" + section + "/"
+	if !strings.HasPrefix(ref, prefix) {
+		return "", fmt.Errorf("unsupported reference %q: only %s... is followed", ref, prefix)
+	}
+	name := strings.TrimPrefix(ref, prefix)
+	return strings.ReplaceAll(strings.ReplaceAll(name, "~1", "/"), "~0", "~"), nil
+}
+
+// maxRefs bounds the references followed in a row, which only a cycle of
+// references exceeds.
+const maxRefs = 32
+
+// response returns r with its reference followed.
+func (d *Document) response(r *Response) (*Response, error) {
+	for i := 0; r.Ref != ""; i++ {
+		name, err := component(r.Ref, "responses")
+		if err != nil {
+			return nil, err
+		}
+		next, ok := d.Components.Responses[name]
+		if !ok || i == maxRefs {
+			return nil, fmt.Errorf("unresolved reference %q", r.Ref)
+		}
+		r = next
+	}
+	return r, nil
+}
+
+// parameter returns p with its reference followed.
+func (d *Document) parameter(p *Parameter) (*Parameter, error) {
+	for i := 0; p.Ref != ""; i++ {
+		name, err := component(p.Ref, "parameters")
+		if err != nil {
+			return nil, err
+		}
+		next, ok := d.Components.Parameters[name]
+		if !ok || i == maxRefs {
+			return nil, fmt.Errorf("unresolved reference %q", p.Ref)
+		}
+		p = next
+	}
+	return p, nil
+}
+
+// requestBody returns b with its reference followed.
+func (d *Document) requestBody(b *RequestBody) (*RequestBody, error) {
+	for i := 0; b.Ref != ""; i++ {
+		name, err := component(b.Ref, "requestBodies")
+		if err != nil {
+			return nil, err
+		}
+		next, ok := d.Components.RequestBodies[name]
+		if !ok || i == maxRefs {
+			return nil, fmt.Errorf("unresolved reference %q", b.Ref)
+		}
+		b = next
+	}
+	return b, nil
+}
+
+// example returns e with its reference followed.
+func (d *Document) example(e *Example) (*Example, error) {
+	for i := 0; e.Ref != ""; i++ {
+		name, err := component(e.Ref, "examples")
+		if err != nil {
+			return nil, err
+		}
+		next, ok := d.Components.Examples[name]
+		if !ok || i == maxRefs {
+			return nil, fmt.Errorf("unresolved reference %q", e.Ref)
+		}
+		e = next
+	}
+	return e, nil
+}
+
+// schema returns s with its reference followed.
+func (d *Document) schema(s *Schema) (*Schema, error) {
+	for i := 0; s.Ref != ""; i++ {
+		name, err := component(s.Ref, "schemas")
+		if err != nil {
+			return nil, err
+		}
+		next, ok := d.Components.Schemas[name]
+		if !ok || i == maxRefs {
+			return nil, fmt.Errorf("unresolved reference %q", s.Ref)
+		}
+		s = next
+	}
+	return s, nil
+}
+
+// jsonValue converts a value decoded from YAML into one encoding/json can
+// write.
+func jsonValue(v any) any {
+	switch v := v.(type) {
+	case map[any]any:
+		m := make(map[string]any, len(v))
+		for k, value := range v {
+			m[fmt.Sprint(k)] = jsonValue(value)
+		}
+		return m
+	case map[string]any:
+		m := make(map[string]any, len(v))
+		for k, value := range v {
+			m[k] = jsonValue(value)
+		}
+		return m
+	case []any:
+		out := make([]any, len(v))
+		for i, value := range v {
+			out[i] = jsonValue(value)
+		}
+		return out
+	}
+	return v
+}
@@ -0,0 +1,209 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openapi
+
+import (
+	"testing"
+
+	"github.com/google/test-server/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+const spec = `
+openapi: 3.0.3
+servers:
+  - url: https://api.example.com/{version}
+    variables:
+      version:
+        default: v2
+paths:
+  /pets:
+    get:
+      operationId: listPets
+      responses:
+        "200":
+          description: The pets.
+          headers:
+            X-Total:
+              required: true
+              schema: {type: integer, minimum: 3}
+          content:
+            application/json:
+              schema:
+                type: array
+                items: {$ref: "#/components/schemas/Pet"}
+    post:
+      responses:
+        "201":
+          $ref: "#/components/responses/Created"
+        "400":
+          description: Invalid.
+  /pets/{pet-id}:
+    get:
+      responses:
+        default:
+          description: A pet.
+          content:
+            text/plain:
+              example: Rex
+            application/json:
+              examples:
+                rex: {value: {id: 7, name: Rex}}
+    delete:
+      responses:
+        "204":
+          description: Deleted.
+components:
+  responses:
+    Created:
+      description: Created.
+      content:
+        application/json:
+          schema: {$ref: "#/components/schemas/Dog"}
+  schemas:
+    Pet:
+      type: object
+      required: [id]
+      properties:
+        id: {type: integer, format: int64}
+        name: {type: string, example: Rex}
+        born: {type: string, format: date}
+        tags: {type: array, items: {type: string, enum: [good, loud]}}
+        owner: {type: [string, "null"], format: email}
+    Dog:
+      allOf:
+        - $ref: "#/components/schemas/Pet"
+        - type: object
+          properties:
+            barks: {type: boolean}
+            weight: {type: number, exclusiveMinimum: 2}
+`
+
+func TestStubs(t *testing.T) {
+	doc, err := Parse([]byte(spec))
+	require.NoError(t, err)
+	require.Equal(t, "/v2", doc.BasePath())
+
+	routes, err := doc.Stubs(doc.BasePath())
+	require.NoError(t, err)
+	require.Equal(t, []config.Route{
+		{
+			Name: "listPets", Method: "GET", Path: "/v2/pets",
+			Response: &config.Response{
+				Status:  200,
+				Headers: map[string]string{"Content-Type": "application/json", "X-Total": "3"},
+				Body: `[
+  {
+    "born": "2025-01-01",
+    "id": 1,
+    "name": "Rex",
+    "owner": "user@example.com",
+    "tags": [
+      "good"
+    ]
+  }
+]
+`,
+			},
+		},
+		{
+			Name: "POST /v2/pets", Method: "POST", Path: "/v2/pets",
+			Response: &config.Response{
+				Status:  201,
+				Headers: map[string]string{"Content-Type": "application/json"},
+				Body: `{
+  "barks": true,
+  "born": "2025-01-01",
+  "id": 1,
+  "name": "Rex",
+  "owner": "user@example.com",
+  "tags": [
+    "good"
+  ],
+  "weight": 3
+}
+`,
+			},
+		},
+		{
+			Name: "GET /v2/pets/{pet-id}", Method: "GET", Path: "/v2/pets/{pet_id}",
+			Response: &config.Response{
+				Status:  200,
+				Headers: map[string]string{"Content-Type": "application/json"},
+				Body:    "{\n  \"id\": 7,\n  \"name\": \"Rex\"\n}\n",
+			},
+		},
+		{
+			Name: "DELETE /v2/pets/{pet-id}", Method: "DELETE", Path: "/v2/pets/{pet_id}",
+			Response: &config.Response{Status: 204},
+		},
+	}, routes)
+}
+
+func TestStubsErrors(t *testing.T) {
+	_, err := Parse([]byte("swagger: '2.0'\n"))
+	require.ErrorContains(t, err, "not an OpenAPI 3 document")
+
+	doc, err := Parse([]byte(`
+openapi: 3.1.0
+paths:
+  /a:
+    get:
+      responses:
+        "200":
+          content:
+            application/json:
+              schema: {$ref: "#/components/schemas/Missing"}
+  /b:
+    get:
+      responses:
+        "200":
+          content:
+            application/json:
+              schema: {$ref: "other.yaml#/Model"}
+`))
+	require.NoError(t, err)
+	_, err = doc.Stubs("")
+	require.ErrorContains(t, err, `GET /a: 200 application/json: unresolved reference "#/components/schemas/Missing"`)
+
+	delete(doc.Paths, "/a")
+	_, err = doc.Stubs("")
+	require.ErrorContains(t, err, `unsupported reference "other.yaml#/Model"`)
+}
+
+func TestExampleRecursive(t *testing.T) {
+	doc, err := Parse([]byte(`
+openapi: 3.0.0
+paths: {}
+components:
+  schemas:
+    Node:
+      type: object
+      properties:
+        value: {type: string, minLength: 8, maxLength: 10}
+        next: {$ref: "#/components/schemas/Node"}
+`))
+	require.NoError(t, err)
+	v, err := doc.Example(&Schema{Ref: "#/components/schemas/Node"})
+	require.NoError(t, err)
+	depth := 0
+	for node, ok := v.(map[string]any); ok; node, ok = node["next"].(map[string]any) {
+		require.Equal(t, "stringxx", node["value"])
+		depth++
+	}
+	require.Equal(t, maxDepth, depth)
+}
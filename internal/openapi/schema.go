@@ -0,0 +1,320 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openapi
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Schema is a JSON schema as OpenAPI uses it.
+type Schema struct {
+	Ref                  string             `yaml:"$ref"`
+	Type                 Types              `yaml:"type"`
+	Format               string             `yaml:"format"`
+	Nullable             bool               `yaml:"nullable"`
+	Enum                 []any              `yaml:"enum"`
+	Const                any                `yaml:"const"`
+	Example              any                `yaml:"example"`
+	Examples             []any              `yaml:"examples"`
+	Default              any                `yaml:"default"`
+	Properties           map[string]*Schema `yaml:"properties"`
+	Required             []string           `yaml:"required"`
+	AdditionalProperties *Additional        `yaml:"additionalProperties"`
+	Items                *Schema            `yaml:"items"`
+	AllOf                []*Schema          `yaml:"allOf"`
+	OneOf                []*Schema          `yaml:"oneOf"`
+	AnyOf                []*Schema          `yaml:"anyOf"`
+	Minimum              *float64           `yaml:"minimum"`
+	Maximum              *float64           `yaml:"maximum"`
+	ExclusiveMinimum     any                `yaml:"exclusiveMinimum"`
+	ExclusiveMaximum     any                `yaml:"exclusiveMaximum"`
+	MultipleOf           *float64           `yaml:"multipleOf"`
+	MinLength            *int               `yaml:"minLength"`
+	MaxLength            *int               `yaml:"maxLength"`
+	Pattern              string             `yaml:"pattern"`
+	MinItems             *int               `yaml:"minItems"`
+	MaxItems             *int               `yaml:"maxItems"`
+	UniqueItems          bool               `yaml:"uniqueItems"`
+	MinProperties        *int               `yaml:"minProperties"`
+	// ReadOnly properties are only in responses, and WriteOnly ones only
+	// in requests.
+	ReadOnly      bool `yaml:"readOnly"`
+	WriteOnly     bool `yaml:"writeOnly"`
+	MaxProperties *int `yaml:"maxProperties"`
+}
+
+// Additional is the additionalProperties of a schema: false, true or the
+// schema of the properties the schema does not list.
+type Additional struct {
+	Forbidden bool
+	Schema    *Schema
+}
+
+// UnmarshalYAML reads a boolean or a schema.
+func (a *Additional) UnmarshalYAML(unmarshal func(any) error) error {
+	var allowed bool
+	if err := unmarshal(&allowed); err == nil {
+		a.Forbidden = !allowed
+		return nil
+	}
+	return unmarshal(&a.Schema)
+}
+
+// Types is the type of a schema, one name in OpenAPI 3.0 and one or a list
+// of names in 3.1.
+type Types []string
+
+// UnmarshalYAML reads a name or a list of names.
+func (t *Types) UnmarshalYAML(unmarshal func(any) error) error {
+	var name string
+	if err := unmarshal(&name); err == nil {
+		*t = Types{name}
+		return nil
+	}
+	var names []string
+	if err := unmarshal(&names); err != nil {
+		return err
+	}
+	*t = names
+	return nil
+}
+
+// Has reports whether name is one of t.
+func (t Types) Has(name string) bool {
+	for _, n := range t {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// first returns the first type of t that is not null, or "".
+func (t Types) first() string {
+	for _, n := range t {
+		if n != "null" {
+			return n
+		}
+	}
+	return ""
+}
+
+// maxDepth bounds the nesting of made up values, which only recursive
+// schemas reach.
+const maxDepth = 8
+
+// Example returns a value that s accepts, the same for every call: the
+// example or default the schema declares or else a value made up from its
+// type, format and bounds. Objects get every property.
+func (d *Document) Example(s *Schema) (any, error) {
+	v, err := d.synth(s, 0)
+	if err != nil {
+		return nil, err
+	}
+	return jsonValue(v), nil
+}
+
+func (d *Document) synth(s *Schema, depth int) (any, error) {
+	s, err := d.schema(s)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case s.Example != nil:
+		return s.Example, nil
+	case len(s.Examples) > 0:
+		return s.Examples[0], nil
+	case s.Const != nil:
+		return s.Const, nil
+	case s.Default != nil:
+		return s.Default, nil
+	case len(s.Enum) > 0:
+		return s.Enum[0], nil
+	}
+	if depth > maxDepth {
+		return nil, nil
+	}
+	if len(s.AllOf) > 0 {
+		return d.allOf(s, depth)
+	}
+	if len(s.OneOf) > 0 {
+		return d.synth(s.OneOf[0], depth+1)
+	}
+	if len(s.AnyOf) > 0 {
+		return d.synth(s.AnyOf[0], depth+1)
+	}
+
+	switch t := s.Type.first(); {
+	case (t == "object" || t == "array" || t == "") && depth == maxDepth:
+		return nil, nil
+	case t == "object" || t == "" && s.Properties != nil:
+		return d.object(s, depth)
+	case t == "array" || t == "" && s.Items != nil:
+		n := 1
+		if s.MinItems != nil && *s.MinItems > n {
+			n = *s.MinItems
+		}
+		if s.MaxItems != nil && *s.MaxItems < n {
+			n = *s.MaxItems
+		}
+		items := make([]any, 0, n)
+		if s.Items == nil {
+			return items, nil
+		}
+		for i := 0; i < n; i++ {
+			item, err := d.synth(s.Items, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	case t == "string":
+		return stringExample(s), nil
+	case t == "integer":
+		return int64(number(s, 1)), nil
+	case t == "number":
+		return number(s, 1.5), nil
+	case t == "boolean":
+		return true, nil
+	case t == "null":
+		return nil, nil
+	case t == "":
+		// No type accepts anything.
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown schema type %q", t)
+	}
+}
+
+// object returns an object with every property of s.
+func (d *Document) object(s *Schema, depth int) (map[string]any, error) {
+	out := map[string]any{}
+	names := make([]string, 0, len(s.Properties))
+	for name := range s.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		v, err := d.synth(s.Properties[name], depth+1)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		out[name] = v
+	}
+	return out, nil
+}
+
+// allOf merges the examples of the schemas of s.AllOf, which are objects in
+// practice, e.g. a base model extended with more properties.
+func (d *Document) allOf(s *Schema, depth int) (any, error) {
+	merged := map[any]any{}
+	var last any
+	for _, sub := range append([]*Schema{{Properties: s.Properties}}, s.AllOf...) {
+		v, err := d.synth(sub, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		switch v := v.(type) {
+		case map[string]any:
+			for k, value := range v {
+				merged[k] = value
+			}
+		case map[any]any:
+			for k, value := range v {
+				merged[k] = value
+			}
+		case nil:
+		default:
+			last = v
+		}
+	}
+	if len(merged) == 0 && last != nil {
+		return last, nil
+	}
+	return merged, nil
+}
+
+// number returns fallback moved within the bounds of s.
+func number(s *Schema, fallback float64) float64 {
+	n := fallback
+	if s.Minimum != nil {
+		n = *s.Minimum
+	} else if m, ok := toFloat(s.ExclusiveMinimum); ok {
+		n = m + 1
+	}
+	if s.Maximum != nil && n > *s.Maximum {
+		n = *s.Maximum
+	} else if m, ok := toFloat(s.ExclusiveMaximum); ok && n >= m {
+		n = m - 1
+	}
+	if s.MultipleOf != nil && *s.MultipleOf > 0 {
+		m := *s.MultipleOf
+		n = float64(int64(n/m+0.999999)) * m
+	}
+	return n
+}
+
+// toFloat returns the number v is, which is a bound in OpenAPI 3.1 and a
+// flag in 3.0.
+func toFloat(v any) (float64, bool) {
+	switch v := v.(type) {
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+// formats are the made up values of the string formats.
+var formats = map[string]string{
+	"date":      "2025-01-01",
+	"date-time": "2025-01-01T00:00:00Z",
+	"time":      "00:00:00Z",
+	"duration":  "P1D",
+	"email":     "user@example.com",
+	"hostname":  "example.com",
+	"ipv4":      "192.0.2.1",
+	"ipv6":      "2001:db8::1",
+	"uri":       "https://example.com/",
+	"url":       "https://example.com/",
+	"uuid":      "00000000-0000-4000-8000-000000000000",
+	"byte":      "c3RyaW5n",
+	"binary":    "string",
+	"password":  "password",
+}
+
+// stringExample returns a string of the format and length of s.
+func stringExample(s *Schema) string {
+	v, ok := formats[s.Format]
+	if !ok {
+		v = "string"
+	}
+	if s.MinLength != nil {
+		for len(v) < *s.MinLength {
+			v += "x"
+		}
+	}
+	if s.MaxLength != nil && len(v) > *s.MaxLength {
+		v = v[:*s.MaxLength]
+	}
+	return v
+}
@@ -0,0 +1,244 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/test-server/internal/config"
+)
+
+// Stubs returns a stub route per operation of d, in the order of their
+// paths and methods, with prefix, e.g. d.BasePath(), before every path.
+//
+// A route answers with the lowest 2xx response of its operation, or else
+// its default response as 200, preferring JSON content. The body is the
+// first example of the content, or else made up from its schema.
+func (d *Document) Stubs(prefix string) ([]config.Route, error) {
+	var routes []config.Route
+	for _, path := range d.SortedPaths() {
+		item := d.Paths[path]
+		if item == nil {
+			continue
+		}
+		for _, op := range item.Operations() {
+			route, err := d.stub(prefix+path, op)
+			if err != nil {
+				return nil, fmt.Errorf("%s %s: %w", op.Method, path, err)
+			}
+			routes = append(routes, route)
+		}
+	}
+	return routes, nil
+}
+
+// paramRe matches the path parameters of a path template.
+var paramRe = regexp.MustCompile(`\{([^{}]*)\}`)
+
+// routePath returns the route pattern of an OpenAPI path template, whose
+// parameter names may be any string.
+func routePath(path string) string {
+	return paramRe.ReplaceAllStringFunc(path, func(p string) string {
+		name := []byte(p[1 : len(p)-1])
+		for i, c := range name {
+			if !(c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || i > 0 && '0' <= c && c <= '9') {
+				name[i] = '_'
+			}
+		}
+		if len(name) == 0 {
+			return "*"
+		}
+		return "{" + string(name) + "}"
+	})
+}
+
+func (d *Document) stub(path string, op MethodOperation) (config.Route, error) {
+	name := op.OperationID
+	if name == "" {
+		name = op.Method + " " + path
+	}
+	route := config.Route{Name: name, Method: op.Method, Path: routePath(path)}
+	status, resp, err := d.successResponse(op.Operation)
+	if err != nil {
+		return route, err
+	}
+	route.Response = &config.Response{Status: status}
+	if resp == nil {
+		return route, nil
+	}
+	if err := d.setHeaders(route.Response, resp); err != nil {
+		return route, err
+	}
+	mediaType, content := pickContent(resp.Content)
+	if content == nil || op.Method == "HEAD" {
+		return route, nil
+	}
+	body, err := d.body(mediaType, content)
+	if err != nil {
+		return route, fmt.Errorf("%d %s: %w", status, mediaType, err)
+	}
+	if route.Response.Headers == nil {
+		route.Response.Headers = map[string]string{}
+	}
+	route.Response.Headers["Content-Type"] = mediaType
+	route.Response.Body = body
+	return route, nil
+}
+
+// successResponse returns the response a stub of op answers with and its
+// status.
+func (d *Document) successResponse(op *Operation) (int, *Response, error) {
+	codes := make([]string, 0, len(op.Responses))
+	for code := range op.Responses {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	pick := ""
+	for _, code := range codes {
+		if strings.HasPrefix(code, "2") {
+			pick = code
+			break
+		}
+	}
+	if pick == "" {
+		if _, ok := op.Responses["default"]; ok {
+			pick = "default"
+		} else if len(codes) > 0 {
+			pick = codes[0]
+		}
+	}
+	if pick == "" || op.Responses[pick] == nil {
+		return 200, nil, nil
+	}
+	status := 200
+	if n, err := strconv.Atoi(pick); err == nil {
+		status = n
+	} else if len(pick) == 3 && strings.HasSuffix(strings.ToUpper(pick), "XX") && pick[0] != '2' {
+		// 4XX and the like.
+		status, _ = strconv.Atoi(pick[:1] + "00")
+	}
+	resp, err := d.response(op.Responses[pick])
+	if err != nil {
+		return 0, nil, err
+	}
+	return status, resp, nil
+}
+
+// setHeaders adds the headers of resp that are required or have an example.
+func (d *Document) setHeaders(out *config.Response, resp *Response) error {
+	for name, h := range resp.Headers {
+		if strings.EqualFold(name, "Content-Type") {
+			continue
+		}
+		for i := 0; h.Ref != ""; i++ {
+			ref, err := component(h.Ref, "headers")
+			if err != nil {
+				return err
+			}
+			next, ok := d.Components.Headers[ref]
+			if !ok || i == maxRefs {
+				return fmt.Errorf("unresolved reference %q", h.Ref)
+			}
+			h = next
+		}
+		v := h.Example
+		if v == nil && h.Schema != nil && h.Required {
+			var err error
+			if v, err = d.Example(h.Schema); err != nil {
+				return fmt.Errorf("header %s: %w", name, err)
+			}
+		}
+		if v == nil {
+			continue
+		}
+		if out.Headers == nil {
+			out.Headers = map[string]string{}
+		}
+		out.Headers[name] = fmt.Sprint(v)
+	}
+	return nil
+}
+
+// isJSON reports whether mediaType is JSON, e.g. application/json or
+// application/problem+json.
+func isJSON(mediaType string) bool {
+	mediaType, _, _ = strings.Cut(mediaType, ";")
+	mediaType = strings.TrimSpace(mediaType)
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// pickContent returns the content of a body a stub answers with, JSON if
+// there is one.
+func pickContent(content map[string]*MediaType) (string, *MediaType) {
+	types := make([]string, 0, len(content))
+	for t := range content {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	for _, t := range types {
+		if isJSON(t) {
+			return t, content[t]
+		}
+	}
+	for _, t := range types {
+		// Wildcards cannot be a Content-Type.
+		if !strings.Contains(t, "*") {
+			return t, content[t]
+		}
+	}
+	return "", nil
+}
+
+// body returns the body of content: its first example, or else one made up
+// from its schema.
+func (d *Document) body(mediaType string, content *MediaType) (string, error) {
+	v := content.Example
+	if v == nil && len(content.Examples) > 0 {
+		names := make([]string, 0, len(content.Examples))
+		for name := range content.Examples {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		e, err := d.example(content.Examples[names[0]])
+		if err != nil {
+			return "", err
+		}
+		v = e.Value
+	}
+	if v == nil && content.Schema != nil {
+		var err error
+		if v, err = d.Example(content.Schema); err != nil {
+			return "", err
+		}
+	}
+	if v == nil {
+		return "", nil
+	}
+	if s, ok := v.(string); ok && !isJSON(mediaType) {
+		return s, nil
+	}
+	data, err := json.MarshalIndent(jsonValue(v), "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data) + "\n", nil
+}
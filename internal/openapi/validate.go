@@ -0,0 +1,576 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openapi
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"mime"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Validator checks requests and responses against the operations of a
+// document.
+type Validator struct {
+	doc *Document
+	ops []*operation
+}
+
+// operation is an operation of the document with its parts resolved.
+type operation struct {
+	name   string
+	method string
+	path   *regexp.Regexp
+	// names are the path parameters in the order of path.
+	names       []string
+	params      []*Parameter
+	requestBody *RequestBody
+	responses   map[string]*Response
+}
+
+// NewValidator returns the Validator of d, whose paths match with or
+// without d.BasePath() before them.
+func NewValidator(d *Document) (*Validator, error) {
+	v := &Validator{doc: d}
+	base := regexp.QuoteMeta(d.BasePath())
+	for _, path := range d.SortedPaths() {
+		item := d.Paths[path]
+		if item == nil {
+			continue
+		}
+		var pattern strings.Builder
+		var names []string
+		last := 0
+		for _, m := range paramRe.FindAllStringSubmatchIndex(path, -1) {
+			pattern.WriteString(regexp.QuoteMeta(path[last:m[0]]))
+			pattern.WriteString("([^/]+)")
+			names = append(names, path[m[2]:m[3]])
+			last = m[1]
+		}
+		pattern.WriteString(regexp.QuoteMeta(path[last:]))
+		re, err := regexp.Compile("^(?:" + base + ")?" + pattern.String() + "$")
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		for _, mo := range item.Operations() {
+			op, err := d.operation(item, mo)
+			if err != nil {
+				return nil, fmt.Errorf("%s %s: %w", mo.Method, path, err)
+			}
+			op.path, op.names = re, names
+			if op.name == "" {
+				op.name = mo.Method + " " + path
+			}
+			v.ops = append(v.ops, op)
+		}
+	}
+	// A literal path wins over a template matching it, e.g. /pets/mine over
+	// /pets/{id}.
+	sort.SliceStable(v.ops, func(i, j int) bool { return len(v.ops[i].names) < len(v.ops[j].names) })
+	return v, nil
+}
+
+// operation resolves the references of the operation mo of item.
+func (d *Document) operation(item *PathItem, mo MethodOperation) (*operation, error) {
+	op := &operation{name: mo.OperationID, method: mo.Method, responses: map[string]*Response{}}
+	// The parameters of the operation override the ones of the path.
+	index := map[string]int{}
+	for _, p := range append(append([]*Parameter{}, item.Parameters...), mo.Parameters...) {
+		p, err := d.parameter(p)
+		if err != nil {
+			return nil, err
+		}
+		key := p.In + " " + p.Name
+		if p.In == "header" {
+			key = strings.ToLower(key)
+		}
+		if i, ok := index[key]; ok {
+			op.params[i] = p
+			continue
+		}
+		index[key] = len(op.params)
+		op.params = append(op.params, p)
+	}
+	if mo.RequestBody != nil {
+		b, err := d.requestBody(mo.RequestBody)
+		if err != nil {
+			return nil, err
+		}
+		op.requestBody = b
+	}
+	for code, r := range mo.Responses {
+		if r == nil {
+			continue
+		}
+		r, err := d.response(r)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", code, err)
+		}
+		op.responses[strings.ToUpper(code)] = r
+	}
+	return op, nil
+}
+
+// find returns the operation of method and path, with the values of its
+// path parameters, or nil.
+func (v *Validator) find(method, path string) (*operation, map[string]string) {
+	for _, op := range v.ops {
+		if op.method != method {
+			continue
+		}
+		m := op.path.FindStringSubmatch(path)
+		if m == nil {
+			continue
+		}
+		values := map[string]string{}
+		for i, name := range op.names {
+			value, err := url.PathUnescape(m[i+1])
+			if err != nil {
+				value = m[i+1]
+			}
+			values[name] = value
+		}
+		return op, values
+	}
+	return nil, nil
+}
+
+// Request returns how req and its body violate the document, nothing if
+// they do not. A nil body is not checked, e.g. when it is too long to keep.
+func (v *Validator) Request(req *http.Request, body []byte) []string {
+	op, values := v.find(req.Method, req.URL.Path)
+	if op == nil {
+		return []string{fmt.Sprintf("request: no operation of the OpenAPI document matches %s %s", req.Method, req.URL.Path)}
+	}
+	var out []string
+	query := req.URL.Query()
+	for _, p := range op.params {
+		var raw []string
+		switch p.In {
+		case "path":
+			raw = []string{values[p.Name]}
+		case "query":
+			raw = query[p.Name]
+		case "header":
+			raw = req.Header.Values(p.Name)
+		case "cookie":
+			if c, err := req.Cookie(p.Name); err == nil {
+				raw = []string{c.Value}
+			}
+		default:
+			continue
+		}
+		if len(raw) == 0 {
+			if p.Required {
+				out = append(out, fmt.Sprintf("request: missing required %s parameter %s", p.In, p.Name))
+			}
+			continue
+		}
+		if p.Schema == nil {
+			continue
+		}
+		value, err := v.doc.coerce(p.Schema, raw)
+		if err != nil {
+			out = append(out, fmt.Sprintf("request: %s parameter %s: %v", p.In, p.Name, err))
+			continue
+		}
+		for _, violation := range v.doc.check(p.Schema, value, "$", false, 0) {
+			out = append(out, fmt.Sprintf("request: %s parameter %s: %s", p.In, p.Name, violation))
+		}
+	}
+
+	b := op.requestBody
+	switch {
+	case body == nil || b == nil:
+	case len(body) == 0:
+		if b.Required {
+			out = append(out, "request: missing required body")
+		}
+	default:
+		out = append(out, v.doc.checkBody("request body", b.Content, req.Header.Get("Content-Type"), body, false)...)
+	}
+	return out
+}
+
+// Response returns how the response to req violates the document, nothing
+// if it does not or req is not an operation of the document. A nil body is
+// not checked.
+func (v *Validator) Response(req *http.Request, status int, header http.Header, body []byte) []string {
+	op, _ := v.find(req.Method, req.URL.Path)
+	if op == nil {
+		return nil
+	}
+	r := op.responses[strconv.Itoa(status)]
+	if r == nil {
+		r = op.responses[strconv.Itoa(status/100)+"XX"]
+	}
+	if r == nil {
+		r = op.responses["DEFAULT"]
+	}
+	if r == nil {
+		return []string{fmt.Sprintf("response: status %d is not a response of %s", status, op.name)}
+	}
+	var out []string
+	names := make([]string, 0, len(r.Headers))
+	for name := range r.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		h := r.Headers[name]
+		if h != nil && h.Required && header.Get(name) == "" && !strings.EqualFold(name, "Content-Type") {
+			out = append(out, fmt.Sprintf("response: missing required header %s", name))
+		}
+	}
+	if body == nil || len(body) == 0 || len(r.Content) == 0 || req.Method == http.MethodHead {
+		return out
+	}
+	if coding := header.Get("Content-Encoding"); coding != "" && coding != "identity" {
+		// The body is not decoded to be checked.
+		return out
+	}
+	return append(out, v.doc.checkBody("response body", r.Content, header.Get("Content-Type"), body, true)...)
+}
+
+// checkBody checks that body has one of the media types of content and, if
+// it is JSON, the schema of its media type.
+func (d *Document) checkBody(what string, content map[string]*MediaType, contentType string, body []byte, response bool) []string {
+	if len(content) == 0 {
+		return nil
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = strings.ToLower(strings.TrimSpace(contentType))
+	}
+	mt, ok := mediaTypeOf(content, mediaType)
+	if !ok {
+		declared := make([]string, 0, len(content))
+		for t := range content {
+			declared = append(declared, t)
+		}
+		sort.Strings(declared)
+		return []string{fmt.Sprintf("%s: content type %q is not one of %s", what, contentType, strings.Join(declared, ", "))}
+	}
+	if mt == nil || mt.Schema == nil || !isJSON(mediaType) {
+		return nil
+	}
+	var value any
+	if err := json.Unmarshal(body, &value); err != nil {
+		return []string{fmt.Sprintf("%s: invalid JSON: %v", what, err)}
+	}
+	var out []string
+	for _, violation := range d.check(mt.Schema, value, "$", response, 0) {
+		out = append(out, what+": "+violation)
+	}
+	return out
+}
+
+// mediaTypeOf returns the content of mediaType, which may match a range
+// like application/* or */*.
+func mediaTypeOf(content map[string]*MediaType, mediaType string) (*MediaType, bool) {
+	byType := map[string]*MediaType{}
+	for t, mt := range content {
+		if parsed, _, err := mime.ParseMediaType(t); err == nil {
+			t = parsed
+		}
+		byType[strings.ToLower(t)] = mt
+	}
+	major, _, _ := strings.Cut(mediaType, "/")
+	for _, t := range []string{mediaType, major + "/*", "*/*"} {
+		if mt, ok := byType[t]; ok {
+			return mt, true
+		}
+	}
+	return nil, false
+}
+
+// coerce converts the raw values of a parameter to the type of s.
+func (d *Document) coerce(s *Schema, raw []string) (any, error) {
+	s, err := d.schema(s)
+	if err != nil {
+		return nil, err
+	}
+	if s.Type.first() == "array" {
+		if len(raw) == 1 {
+			raw = strings.Split(raw[0], ",")
+		}
+		items := make([]any, len(raw))
+		for i, value := range raw {
+			items[i] = value
+			if s.Items != nil {
+				if items[i], err = d.coerce(s.Items, []string{value}); err != nil {
+					return nil, err
+				}
+			}
+		}
+		return items, nil
+	}
+	value := raw[0]
+	switch s.Type.first() {
+	case "integer", "number":
+		if n, err := strconv.ParseFloat(value, 64); err == nil {
+			return n, nil
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b, nil
+		}
+	}
+	// A value of the wrong type stays a string, which check reports.
+	return value, nil
+}
+
+// maxCheckDepth bounds the schemas check goes through for a value, which
+// only a cycle of allOf, anyOf or oneOf references exceeds.
+const maxCheckDepth = 64
+
+// check returns how v, decoded from JSON, violates s, each violation
+// starting with the location of the value under at, e.g. $.pets[0].id. The
+// required read-only properties are only required in responses, and the
+// write-only ones only in requests.
+func (d *Document) check(s *Schema, v any, at string, response bool, depth int) []string {
+	if s == nil || depth > maxCheckDepth {
+		return nil
+	}
+	s, err := d.schema(s)
+	if err != nil {
+		return []string{fmt.Sprintf("%s: %v", at, err)}
+	}
+	var out []string
+	fail := func(format string, args ...any) {
+		out = append(out, at+": "+fmt.Sprintf(format, args...))
+	}
+	if len(s.Enum) > 0 && !oneOf(v, s.Enum) {
+		fail("%s is not one of %s", show(v), show(s.Enum))
+	}
+	if s.Const != nil && !equal(v, s.Const) {
+		fail("%s is not %s", show(v), show(s.Const))
+	}
+	for _, sub := range s.AllOf {
+		out = append(out, d.check(sub, v, at, response, depth+1)...)
+	}
+	// Loosely written schemas often let an object match several schemas
+	// of oneOf, so one match is enough for oneOf like for anyOf.
+	for _, alternatives := range []struct {
+		keyword string
+		schemas []*Schema
+	}{{"anyOf", s.AnyOf}, {"oneOf", s.OneOf}} {
+		if len(alternatives.schemas) == 0 {
+			continue
+		}
+		matched := false
+		for _, sub := range alternatives.schemas {
+			if len(d.check(sub, v, at, response, depth+1)) == 0 {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			fail("%s matches no schema of %s", show(v), alternatives.keyword)
+		}
+	}
+
+	kind := kindOf(v)
+	if len(s.Type) > 0 || s.Nullable {
+		ok := kind == "null" && (s.Nullable || s.Type.Has("null"))
+		for _, t := range s.Type {
+			ok = ok || t == kind || t == "number" && kind == "integer"
+		}
+		if !ok {
+			fail("expected %s, got %s", strings.Join(s.Type, " or "), kind)
+			return out
+		}
+	}
+
+	switch v := v.(type) {
+	case string:
+		n := utf8.RuneCountInString(v)
+		if s.MinLength != nil && n < *s.MinLength {
+			fail("%s is shorter than %d characters", show(v), *s.MinLength)
+		}
+		if s.MaxLength != nil && n > *s.MaxLength {
+			fail("%s is longer than %d characters", show(v), *s.MaxLength)
+		}
+		// Patterns that are not RE2 are not checked.
+		if re, err := regexp.Compile(s.Pattern); err == nil && s.Pattern != "" && !re.MatchString(v) {
+			fail("%s does not match %s", show(v), s.Pattern)
+		}
+		if !validFormat(s.Format, v) {
+			fail("%s is not a valid %s", show(v), s.Format)
+		}
+	case float64:
+		if s.Minimum != nil && (v < *s.Minimum || s.ExclusiveMinimum == true && v == *s.Minimum) {
+			fail("%v is less than the minimum %v", v, *s.Minimum)
+		}
+		if s.Maximum != nil && (v > *s.Maximum || s.ExclusiveMaximum == true && v == *s.Maximum) {
+			fail("%v is more than the maximum %v", v, *s.Maximum)
+		}
+		if m, ok := toFloat(s.ExclusiveMinimum); ok && v <= m {
+			fail("%v is not more than %v", v, m)
+		}
+		if m, ok := toFloat(s.ExclusiveMaximum); ok && v >= m {
+			fail("%v is not less than %v", v, m)
+		}
+		if m := s.MultipleOf; m != nil && *m > 0 {
+			if q := v / *m; math.Abs(q-math.Round(q)) > 1e-9 {
+				fail("%v is not a multiple of %v", v, *m)
+			}
+		}
+	case []any:
+		if s.MinItems != nil && len(v) < *s.MinItems {
+			fail("%d items are fewer than %d", len(v), *s.MinItems)
+		}
+		if s.MaxItems != nil && len(v) > *s.MaxItems {
+			fail("%d items are more than %d", len(v), *s.MaxItems)
+		}
+		seen := map[string]int{}
+		for i, item := range v {
+			if s.UniqueItems {
+				key := show(item)
+				if j, ok := seen[key]; ok {
+					fail("items %d and %d are equal", j, i)
+				}
+				seen[key] = i
+			}
+			out = append(out, d.check(s.Items, item, fmt.Sprintf("%s[%d]", at, i), response, depth+1)...)
+		}
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, ok := v[name]; ok {
+				continue
+			}
+			if p, err := d.schema(s.Properties[name]); err == nil && p != nil && (p.ReadOnly && !response || p.WriteOnly && response) {
+				continue
+			}
+			fail("missing required property %q", name)
+		}
+		if s.MinProperties != nil && len(v) < *s.MinProperties {
+			fail("%d properties are fewer than %d", len(v), *s.MinProperties)
+		}
+		if s.MaxProperties != nil && len(v) > *s.MaxProperties {
+			fail("%d properties are more than %d", len(v), *s.MaxProperties)
+		}
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if p, ok := s.Properties[name]; ok {
+				out = append(out, d.check(p, v[name], at+"."+name, response, depth+1)...)
+				continue
+			}
+			switch a := s.AdditionalProperties; {
+			case a == nil:
+			case a.Forbidden:
+				fail("unexpected property %q", name)
+			default:
+				out = append(out, d.check(a.Schema, v[name], at+"."+name, response, depth+1)...)
+			}
+		}
+	}
+	return out
+}
+
+// kindOf returns the JSON schema type of v, integer for whole numbers.
+func kindOf(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64:
+		if v == math.Trunc(v) && !math.IsInf(v, 0) {
+			return "integer"
+		}
+		return "number"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// show returns v as JSON for the violations.
+func show(v any) string {
+	data, err := json.Marshal(jsonValue(v))
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+// equal reports whether v, decoded from JSON, equals the value of a schema,
+// decoded from YAML.
+func equal(v, schemaValue any) bool {
+	return show(v) == show(schemaValue)
+}
+
+func oneOf(v any, values []any) bool {
+	for _, value := range values {
+		if equal(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+var uuidRe = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// validFormat reports whether v has format, true for the formats it does
+// not know.
+func validFormat(format, v string) bool {
+	var err error
+	switch format {
+	case "date-time":
+		_, err = time.Parse(time.RFC3339, v)
+	case "date":
+		_, err = time.Parse(time.DateOnly, v)
+	case "email":
+		_, err = mail.ParseAddress(v)
+	case "uuid":
+		return uuidRe.MatchString(v)
+	case "ipv4":
+		ip := net.ParseIP(v)
+		return ip != nil && ip.To4() != nil && !strings.Contains(v, ":")
+	case "ipv6":
+		return net.ParseIP(v) != nil && strings.Contains(v, ":")
+	case "uri":
+		var u *url.URL
+		u, err = url.Parse(v)
+		if err == nil && u.Scheme == "" {
+			return false
+		}
+	case "byte":
+		_, err = base64.StdEncoding.DecodeString(v)
+	}
+	return err == nil
+}
@@ -0,0 +1,142 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const validated = `
+openapi: 3.1.0
+servers: [{url: https://api.example.com/v1}]
+paths:
+  /pets:
+    post:
+      operationId: createPet
+      parameters:
+        - {name: X-Request-Id, in: header, required: true, schema: {type: string, format: uuid}}
+        - {name: dry_run, in: query, schema: {type: boolean}}
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema: {$ref: "#/components/schemas/Pet"}
+      responses:
+        "201":
+          description: Created.
+          headers:
+            Location: {required: true, schema: {type: string}}
+          content:
+            application/json:
+              schema: {$ref: "#/components/schemas/Pet"}
+        4XX:
+          description: Failed.
+  /pets/{id}:
+    parameters:
+      - {name: id, in: path, required: true, schema: {type: integer, minimum: 1}}
+    get:
+      responses:
+        "200":
+          description: A pet.
+          content:
+            application/json:
+              schema: {$ref: "#/components/schemas/Pet"}
+  /pets/mine:
+    get:
+      responses:
+        "200":
+          description: My pet.
+components:
+  schemas:
+    Pet:
+      type: object
+      additionalProperties: false
+      required: [id, name, tags]
+      properties:
+        id: {type: integer, readOnly: true}
+        name: {type: string, minLength: 1}
+        tags: {type: array, uniqueItems: true, items: {type: string, enum: [good, loud]}}
+        born: {type: string, format: date}
+        owner: {type: [string, "null"], format: email}
+        size: {oneOf: [{type: string}, {type: number, exclusiveMinimum: 0}]}
+`
+
+func TestValidator(t *testing.T) {
+	doc, err := Parse([]byte(validated))
+	require.NoError(t, err)
+	v, err := NewValidator(doc)
+	require.NoError(t, err)
+
+	request := func(method, target, body string) *http.Request {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Request-Id", "00000000-0000-4000-8000-000000000000")
+		return req
+	}
+	req := request("POST", "/v1/pets?dry_run=true", `{"name": "Rex", "tags": ["good"], "owner": null, "size": 2.5}`)
+	require.Empty(t, v.Request(req, []byte(`{"name": "Rex", "tags": ["good"], "owner": null, "size": 2.5}`)))
+	// The server path is optional.
+	req = request("POST", "/pets", "")
+	require.Equal(t, []string{"request: missing required body"}, v.Request(req, []byte{}))
+	require.Empty(t, v.Request(req, nil))
+
+	req = request("POST", "/pets?dry_run=maybe", "")
+	req.Header.Del("X-Request-Id")
+	body := `{"id": 1, "name": "", "tags": ["good", "good", "bad"], "born": "yesterday", "owner": "nobody", "size": -1, "color": "red"}`
+	require.Equal(t, []string{
+		"request: missing required header parameter X-Request-Id",
+		"request: query parameter dry_run: $: expected boolean, got string",
+		`request body: $.born: "yesterday" is not a valid date`,
+		`request body: $: unexpected property "color"`,
+		`request body: $.name: "" is shorter than 1 characters`,
+		`request body: $.owner: "nobody" is not a valid email`,
+		`request body: $.size: -1 matches no schema of oneOf`,
+		`request body: $.tags: items 0 and 1 are equal`,
+		`request body: $.tags[2]: "bad" is not one of ["good","loud"]`,
+	}, v.Request(req, []byte(body)))
+
+	req = request("POST", "/v1/pets", "")
+	req.Header.Set("Content-Type", "text/plain")
+	require.Equal(t, []string{`request body: content type "text/plain" is not one of application/json`}, v.Request(req, []byte("Rex")))
+	req.Header.Set("Content-Type", "application/json")
+	require.Equal(t, []string{`request body: invalid JSON: unexpected end of JSON input`}, v.Request(req, []byte("{")))
+
+	require.Equal(t, []string{"request: path parameter id: $: 0 is less than the minimum 1"}, v.Request(request("GET", "/v1/pets/0", ""), nil))
+	require.Equal(t, []string{"request: path parameter id: $: expected integer, got string"}, v.Request(request("GET", "/v1/pets/rex", ""), nil))
+	require.Empty(t, v.Request(request("GET", "/v1/pets/mine", ""), nil))
+	require.Equal(t, []string{"request: no operation of the OpenAPI document matches DELETE /v1/pets/1"}, v.Request(request("DELETE", "/v1/pets/1", ""), nil))
+
+	// The responses must be declared, and the read-only id is required in
+	// them.
+	req = request("POST", "/v1/pets", "")
+	header := http.Header{"Content-Type": {"application/json"}, "Location": {"/v1/pets/1"}}
+	require.Empty(t, v.Response(req, 201, header, []byte(`{"id": 1, "name": "Rex", "tags": []}`)))
+	require.Empty(t, v.Response(req, 404, http.Header{}, []byte("not found")))
+	require.Equal(t, []string{"response: status 500 is not a response of createPet"}, v.Response(req, 500, http.Header{}, nil))
+	require.Equal(t, []string{
+		"response: missing required header Location",
+		`response body: $: missing required property "id"`,
+		"response body: $.name: expected string, got integer",
+	}, v.Response(req, 201, http.Header{"Content-Type": {"application/json; charset=utf-8"}}, []byte(`{"name": 7, "tags": []}`)))
+	require.Empty(t, v.Response(request("GET", "/v1/pets/1", ""), 200, http.Header{"Content-Encoding": {"gzip"}}, []byte{0x1f, 0x8b}))
+	require.Empty(t, v.Response(request("DELETE", "/v1/pets/1", ""), 200, http.Header{}, nil))
+}
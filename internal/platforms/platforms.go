@@ -0,0 +1,141 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package platforms reads platforms.json, the single list of the platforms
+// test-server is released for and the names each packaging ecosystem uses
+// for them.
+package platforms
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// File is the path of the platform matrix relative to the repository root,
+// which is where the release scripts run from.
+const File = "platforms.json"
+
+// Platform is one released OS/architecture combination. Ecosystem specific
+// names are empty when the platform is not published to that ecosystem.
+type Platform struct {
+	GOOS    string   `json:"goos"`
+	GOARCH  string   `json:"goarch"`
+	Archive string   `json:"archive"` // e.g. "Linux_x86_64", as in the goreleaser name_template
+	Libc    []string `json:"libc,omitempty"`
+	Node    string   `json:"node,omitempty"`   // process.platform-process.arch
+	Python  []string `json:"python,omitempty"` // wheel platform tags
+	Dotnet  string   `json:"dotnet,omitempty"` // runtime identifier
+	Deb     string   `json:"deb,omitempty"`
+	RPM     string   `json:"rpm,omitempty"`
+	Docker  string   `json:"docker,omitempty"`
+}
+
+// Matrix is the parsed platforms.json.
+type Matrix struct {
+	ArchiveExtensions map[string]string `json:"archive_extensions"`
+	Platforms         []Platform        `json:"platforms"`
+}
+
+// Load reads and validates the matrix at path.
+func Load(path string) (*Matrix, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	m, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", path, err)
+	}
+	return m, nil
+}
+
+// Parse decodes and validates a platform matrix.
+func Parse(data []byte) (*Matrix, error) {
+	var m Matrix
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	if len(m.Platforms) == 0 {
+		return nil, fmt.Errorf("no platforms defined")
+	}
+	seen := make(map[string]bool)
+	for _, p := range m.Platforms {
+		if p.GOOS == "" || p.GOARCH == "" || p.Archive == "" {
+			return nil, fmt.Errorf("platform %+v must set goos, goarch and archive", p)
+		}
+		if _, ok := m.ArchiveExtensions[p.GOOS]; !ok {
+			return nil, fmt.Errorf("no archive extension for %s", p.GOOS)
+		}
+		key := p.GOOS + "/" + p.GOARCH
+		if seen[key] {
+			return nil, fmt.Errorf("duplicate platform %s", key)
+		}
+		seen[key] = true
+	}
+	return &m, nil
+}
+
+// ArchiveName returns the release archive for p, e.g.
+// "test-server_Linux_x86_64.tar.gz".
+func (m *Matrix) ArchiveName(projectName string, p Platform) string {
+	return fmt.Sprintf("%s_%s%s", projectName, p.Archive, m.ArchiveExtensions[p.GOOS])
+}
+
+// ArchiveNames returns the release archives of every platform, in matrix order.
+func (m *Matrix) ArchiveNames(projectName string) []string {
+	names := make([]string, 0, len(m.Platforms))
+	for _, p := range m.Platforms {
+		names = append(names, m.ArchiveName(projectName, p))
+	}
+	return names
+}
+
+// BinaryName returns the name of the executable inside the archive of p.
+func BinaryName(projectName string, p Platform) string {
+	if p.GOOS == "windows" {
+		return projectName + ".exe"
+	}
+	return projectName
+}
+
+// InstallerName returns the Windows installer of version for p, e.g.
+// "test-server_0.2.9_Windows_x86_64_setup.exe".
+func InstallerName(projectName, version string, p Platform) string {
+	return fmt.Sprintf("%s_%s_%s_setup.exe", projectName, strings.TrimPrefix(version, "v"), p.Archive)
+}
+
+// Find returns the platform for goos/goarch.
+func (m *Matrix) Find(goos, goarch string) (Platform, bool) {
+	for _, p := range m.Platforms {
+		if p.GOOS == goos && p.GOARCH == goarch {
+			return p, true
+		}
+	}
+	return Platform{}, false
+}
+
+// Filter returns the platforms for which keep returns true, in matrix order.
+func (m *Matrix) Filter(keep func(Platform) bool) []Platform {
+	var out []Platform
+	for _, p := range m.Platforms {
+		if keep(p) {
+			out = append(out, p)
+		}
+	}
+	return out
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package platforms
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		name    string
+		input   string
+		wantErr string
+	}{
+		{
+			name:  "valid",
+			input: `{"archive_extensions": {"linux": ".tar.gz"}, "platforms": [{"goos": "linux", "goarch": "amd64", "archive": "Linux_x86_64"}]}`,
+		},
+		{
+			name:    "no platforms",
+			input:   `{"archive_extensions": {"linux": ".tar.gz"}, "platforms": []}`,
+			wantErr: "no platforms defined",
+		},
+		{
+			name:    "missing archive",
+			input:   `{"archive_extensions": {"linux": ".tar.gz"}, "platforms": [{"goos": "linux", "goarch": "amd64"}]}`,
+			wantErr: "must set goos, goarch and archive",
+		},
+		{
+			name:    "missing extension",
+			input:   `{"archive_extensions": {}, "platforms": [{"goos": "linux", "goarch": "amd64", "archive": "Linux_x86_64"}]}`,
+			wantErr: "no archive extension for linux",
+		},
+		{
+			name: "duplicate",
+			input: `{"archive_extensions": {"linux": ".tar.gz"}, "platforms": [
+				{"goos": "linux", "goarch": "amd64", "archive": "Linux_x86_64"},
+				{"goos": "linux", "goarch": "amd64", "archive": "Linux_amd64"}]}`,
+			wantErr: "duplicate platform linux/amd64",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Parse([]byte(tc.input))
+			if tc.wantErr != "" {
+				require.ErrorContains(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestMatrixNames(t *testing.T) {
+	m, err := Parse([]byte(`{
+		"archive_extensions": {"linux": ".tar.gz", "windows": ".zip"},
+		"platforms": [
+			{"goos": "linux", "goarch": "amd64", "archive": "Linux_x86_64"},
+			{"goos": "windows", "goarch": "arm64", "archive": "Windows_arm64"}
+		]}`))
+	require.NoError(t, err)
+
+	require.Equal(t, []string{"test-server_Linux_x86_64.tar.gz", "test-server_Windows_arm64.zip"}, m.ArchiveNames("test-server"))
+
+	p, ok := m.Find("windows", "arm64")
+	require.True(t, ok)
+	require.Equal(t, "test-server.exe", BinaryName("test-server", p))
+	require.Equal(t, "test-server_0.2.9_Windows_arm64_setup.exe", InstallerName("test-server", "v0.2.9", p))
+
+	_, ok = m.Find("darwin", "arm64")
+	require.False(t, ok)
+}
+
+func TestRepositoryMatrix(t *testing.T) {
+	m, err := Load(filepath.Join("..", "..", File))
+	require.NoError(t, err)
+
+	// goreleaser builds the default goarch set for every goos.
+	for _, p := range m.Platforms {
+		require.Contains(t, []string{"amd64", "arm64", "386"}, p.GOARCH, p.Archive)
+		require.Contains(t, []string{"darwin", "linux", "windows"}, p.GOOS, p.Archive)
+	}
+}
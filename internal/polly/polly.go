@@ -0,0 +1,140 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package polly reads Polly.JS recordings, HAR files with a few extensions,
+// into HAR logs, which package har turns into recordings, and converts the
+// Polly.JS matchRequestsBy options into match_on criteria.
+package polly
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/google/test-server/internal/har"
+)
+
+// Recording is a Polly.JS recording.
+type Recording struct {
+	// Name is the recording name of the test that made the recording.
+	Name string
+	HAR  *har.HAR
+}
+
+// Parse reads a Polly.JS recording.har file.
+func Parse(data []byte) (*Recording, error) {
+	h, err := har.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	var extensions struct {
+		Log struct {
+			RecordingName string `json:"_recordingName"`
+			Entries       []struct {
+				Response struct {
+					Content struct {
+						IsBinary bool `json:"_isBinary"`
+					} `json:"content"`
+				} `json:"response"`
+			} `json:"entries"`
+		} `json:"log"`
+	}
+	if err := json.Unmarshal(data, &extensions); err != nil {
+		return nil, err
+	}
+	for i, e := range extensions.Log.Entries {
+		if !e.Response.Content.IsBinary {
+			continue
+		}
+		if err := joinChunks(&h.Log.Entries[i].Response.Content); err != nil {
+			return nil, fmt.Errorf("entry %d: %w", i, err)
+		}
+	}
+	return &Recording{Name: extensions.Log.RecordingName, HAR: h}, nil
+}
+
+// Load reads the Polly.JS recording at path.
+func Load(path string) (*Recording, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	r, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return r, nil
+}
+
+// joinChunks turns the text of a binary content, the base64 of the body or a
+// JSON array of the base64 of its chunks, as the Node.js HTTP adapter saves
+// them, into the base64 of the body.
+func joinChunks(content *har.Content) error {
+	content.Encoding = "base64"
+	var chunks []string
+	if json.Unmarshal([]byte(content.Text), &chunks) != nil {
+		return nil
+	}
+	var body []byte
+	for _, chunk := range chunks {
+		data, err := base64.StdEncoding.DecodeString(chunk)
+		if err != nil {
+			return fmt.Errorf("binary response content: %w", err)
+		}
+		body = append(body, data...)
+	}
+	content.Text = base64.StdEncoding.EncodeToString(body)
+	return nil
+}
+
+// DefaultMatchRequestsBy are the matchRequestsBy options Polly.JS enables by
+// default.
+var DefaultMatchRequestsBy = []string{"method", "headers", "body", "order", "url"}
+
+// MatchOn returns the match_on criteria of an endpoint that replays like
+// Polly.JS with the matchRequestsBy options enabled, e.g. url.pathname, with
+// warnings about the options without an equivalent. The options on the
+// protocol, credentials, host, port and fragment of the URL always hold, as
+// an endpoint has one target and fragments are not sent, and order holds as
+// the repeated requests of a test are replayed in order.
+func MatchOn(options []string) ([]string, []string) {
+	var criteria, warnings []string
+	add := func(names ...string) {
+		for _, name := range names {
+			if !slices.Contains(criteria, name) {
+				criteria = append(criteria, name)
+			}
+		}
+	}
+	for _, o := range options {
+		switch o {
+		case "method", "headers", "body":
+			add(o)
+		case "url":
+			add("path", "query")
+		case "url.pathname":
+			add("path")
+		case "url.query":
+			add("query")
+		case "order", "url.protocol", "url.username", "url.password", "url.hostname", "url.port", "url.hash":
+		default:
+			warnings = append(warnings, fmt.Sprintf("the matchRequestsBy option %q has no equivalent in match_on", o))
+		}
+	}
+	return criteria, warnings
+}
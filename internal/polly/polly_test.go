@@ -0,0 +1,100 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package polly
+
+import (
+	"testing"
+
+	"github.com/google/test-server/internal/config"
+	"github.com/google/test-server/internal/har"
+	"github.com/stretchr/testify/require"
+)
+
+const recording = `{
+  "log": {
+    "_recordingName": "users/fetches a user",
+    "creator": {"comment": "persister:fs", "name": "Polly.JS", "version": "6.0.6"},
+    "version": "1.2",
+    "pages": [],
+    "entries": [
+      {
+        "_id": "5c1d2a8e0c6f3d3c1e0b0a9f8e7d6c5b",
+        "_order": 0,
+        "request": {
+          "method": "GET",
+          "url": "https://api.example.com/users/1",
+          "httpVersion": "HTTP/1.1",
+          "headers": [{"_fromType": "array", "name": "accept", "value": "application/json"}],
+          "queryString": []
+        },
+        "response": {
+          "status": 200,
+          "statusText": "OK",
+          "headers": [{"name": "content-type", "value": "application/json"}],
+          "content": {"mimeType": "application/json", "size": 12, "text": "{\"id\":1}"}
+        }
+      },
+      {
+        "_id": "0a1b2c3d4e5f60718293a4b5c6d7e8f9",
+        "_order": 0,
+        "request": {"method": "GET", "url": "https://api.example.com/users/1/avatar", "headers": []},
+        "response": {
+          "status": 200,
+          "headers": [{"name": "content-type", "value": "image/png"}],
+          "content": {"_isBinary": true, "mimeType": "image/png", "size": 4, "text": "[\"AAE=\",\"AgM=\"]"}
+        }
+      },
+      {
+        "_id": "f9e8d7c6b5a4938271605f4e3d2c1b0a",
+        "_order": 0,
+        "request": {"method": "GET", "url": "https://api.example.com/users/1/banner", "headers": []},
+        "response": {
+          "status": 200,
+          "headers": [],
+          "content": {"_isBinary": true, "mimeType": "image/png", "size": 2, "text": "BAU="}
+        }
+      }
+    ]
+  }
+}`
+
+func TestParse(t *testing.T) {
+	r, err := Parse([]byte(recording))
+	require.NoError(t, err)
+	require.Equal(t, "users/fetches a user", r.Name)
+
+	file, _, err := r.HAR.Recording(har.Options{Endpoint: config.EndpointConfig{TargetHost: "api.example.com", TargetPort: 443}})
+	require.NoError(t, err)
+	require.Len(t, file.Interactions, 3)
+	require.Equal(t, map[string]string{"Accept": "application/json"}, file.Interactions[0].Request.Headers)
+	require.Equal(t, []map[string]any{{"id": 1.0}}, file.Interactions[0].Response.BodySegments)
+	require.Equal(t, []byte{0, 1, 2, 3}, file.Interactions[1].Response.Body)
+	require.Equal(t, []byte{4, 5}, file.Interactions[2].Response.Body)
+
+	_, err = Parse([]byte(`{"log": {"entries": [{"response": {"content": {"_isBinary": true, "text": "[\"!\"]"}}}]}}`))
+	require.ErrorContains(t, err, "entry 0")
+}
+
+func TestMatchOn(t *testing.T) {
+	criteria, warnings := MatchOn(DefaultMatchRequestsBy)
+	require.Equal(t, []string{"method", "headers", "body", "path", "query"}, criteria)
+	require.Empty(t, warnings)
+
+	criteria, warnings = MatchOn([]string{"method", "url.pathname", "url.hostname", "custom"})
+	require.Equal(t, []string{"method", "path"}, criteria)
+	require.Equal(t, []string{`the matchRequestsBy option "custom" has no equivalent in match_on`}, warnings)
+}
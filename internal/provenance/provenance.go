@@ -0,0 +1,243 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provenance
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/test-server/internal/verify"
+)
+
+const (
+	// StatementType is the in-toto statement type emitted by NewStatement.
+	StatementType = "https://in-toto.io/Statement/v1"
+	// PredicateType is the SLSA provenance predicate type.
+	PredicateType = "https://slsa.dev/provenance/v1"
+	// BuildType identifies how test-server release archives are produced.
+	BuildType = "https://github.com/google/test-server/goreleaser@v1"
+	// DefaultBuilderID is the identity of the trusted release builder, the
+	// release workflow run on the pushed tag.
+	DefaultBuilderID = "https://github.com/google/test-server/.github/workflows/release.yml"
+	// PayloadType is the DSSE payload type of an in-toto statement.
+	PayloadType = "application/vnd.in-toto+json"
+
+	// localBuilderPrefix marks builder IDs of provenance written on a
+	// developer machine, which no one can vouch for.
+	localBuilderPrefix = "local://"
+)
+
+// Envelope is a DSSE envelope carrying a signed statement. It is the content
+// of the provenance release asset.
+type Envelope struct {
+	PayloadType string      `json:"payloadType"`
+	Payload     string      `json:"payload"`
+	Signatures  []Signature `json:"signatures"`
+}
+
+// Signature is a base64 ASN.1 ECDSA signature over the PAE of an envelope,
+// as written by `cosign sign-blob --output-signature`.
+type Signature struct {
+	KeyID string `json:"keyid,omitempty"`
+	Sig   string `json:"sig"`
+}
+
+// Statement is an in-toto statement carrying a SLSA provenance predicate.
+type Statement struct {
+	Type          string    `json:"_type"`
+	Subject       []Subject `json:"subject"`
+	PredicateType string    `json:"predicateType"`
+	Predicate     Predicate `json:"predicate"`
+}
+
+// Subject is a single artifact covered by the provenance.
+type Subject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+type Predicate struct {
+	BuildDefinition BuildDefinition `json:"buildDefinition"`
+	RunDetails      RunDetails      `json:"runDetails"`
+}
+
+type BuildDefinition struct {
+	BuildType            string               `json:"buildType"`
+	ExternalParameters   map[string]string    `json:"externalParameters"`
+	ResolvedDependencies []ResourceDescriptor `json:"resolvedDependencies,omitempty"`
+}
+
+type ResourceDescriptor struct {
+	URI    string            `json:"uri"`
+	Digest map[string]string `json:"digest"`
+}
+
+type RunDetails struct {
+	Builder  Builder  `json:"builder"`
+	Metadata Metadata `json:"metadata"`
+}
+
+type Builder struct {
+	ID string `json:"id"`
+}
+
+type Metadata struct {
+	InvocationID string `json:"invocationId,omitempty"`
+	StartedOn    string `json:"startedOn,omitempty"`
+	FinishedOn   string `json:"finishedOn,omitempty"`
+}
+
+// NewStatement creates a provenance statement for the given archive checksums
+// (archive name to sha256), built from repo at commit for tag.
+func NewStatement(checksums map[string]string, builderID, repo, tag, commit, invocationID string, startedOn time.Time) *Statement {
+	names := make([]string, 0, len(checksums))
+	for name := range checksums {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	stmt := &Statement{
+		Type:          StatementType,
+		PredicateType: PredicateType,
+		Predicate: Predicate{
+			BuildDefinition: BuildDefinition{
+				BuildType: BuildType,
+				ExternalParameters: map[string]string{
+					"repository": repo,
+					"ref":        "refs/tags/" + tag,
+				},
+				ResolvedDependencies: []ResourceDescriptor{{
+					URI:    fmt.Sprintf("git+%s@refs/tags/%s", repo, tag),
+					Digest: map[string]string{"gitCommit": commit},
+				}},
+			},
+			RunDetails: RunDetails{
+				Builder: Builder{ID: builderID},
+				Metadata: Metadata{
+					InvocationID: invocationID,
+					StartedOn:    startedOn.UTC().Format(time.RFC3339),
+					FinishedOn:   time.Now().UTC().Format(time.RFC3339),
+				},
+			},
+		},
+	}
+	for _, name := range names {
+		stmt.Subject = append(stmt.Subject, Subject{Name: name, Digest: map[string]string{"sha256": checksums[name]}})
+	}
+	return stmt
+}
+
+// PAE returns the DSSE pre-authentication encoding of payload, the bytes a
+// signature of the envelope covers.
+func PAE(payloadType string, payload []byte) []byte {
+	return []byte(fmt.Sprintf("DSSEv1 %d %s %d %s", len(payloadType), payloadType, len(payload), payload))
+}
+
+// NewEnvelope wraps payload, an encoded statement, with signature, the cosign
+// signature of PAE(PayloadType, payload).
+func NewEnvelope(payload, signature []byte) *Envelope {
+	return &Envelope{
+		PayloadType: PayloadType,
+		Payload:     base64.StdEncoding.EncodeToString(payload),
+		Signatures:  []Signature{{Sig: string(bytes.TrimSpace(signature))}},
+	}
+}
+
+// Open decodes a DSSE envelope, checks that one of its signatures was made by
+// the private half of pub and returns the statement it carries.
+func Open(data []byte, pub *ecdsa.PublicKey) (*Statement, error) {
+	env := &Envelope{}
+	if err := json.Unmarshal(data, env); err != nil {
+		return nil, fmt.Errorf("failed to parse provenance envelope: %w", err)
+	}
+	if env.PayloadType != PayloadType {
+		return nil, fmt.Errorf("unexpected payload type %q, expected %q (provenance written before it was signed cannot be verified)", env.PayloadType, PayloadType)
+	}
+	payload, err := base64.StdEncoding.DecodeString(env.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode provenance payload: %w", err)
+	}
+	if len(env.Signatures) == 0 {
+		return nil, errors.New("provenance is not signed")
+	}
+	pae := PAE(env.PayloadType, payload)
+	for _, sig := range env.Signatures {
+		if err = verify.Blob(pub, bytes.NewReader(pae), []byte(sig.Sig)); err == nil {
+			return Parse(payload)
+		}
+	}
+	return nil, fmt.Errorf("provenance signature: %w", err)
+}
+
+// Parse decodes a provenance statement.
+func Parse(data []byte) (*Statement, error) {
+	stmt := &Statement{}
+	if err := json.Unmarshal(data, stmt); err != nil {
+		return nil, fmt.Errorf("failed to parse provenance: %w", err)
+	}
+	return stmt, nil
+}
+
+// Verify checks that the statement was produced by the expected builder for
+// the expected tag and that it covers every archive in checksums with a
+// matching sha256 digest. A builder ID matches when it equals expectedBuilder
+// or is expectedBuilder followed by an "@<ref>" suffix.
+func Verify(stmt *Statement, expectedBuilder, tag string, checksums map[string]string) error {
+	if stmt.Type != StatementType {
+		return fmt.Errorf("unexpected statement type %q", stmt.Type)
+	}
+	if stmt.PredicateType != PredicateType {
+		return fmt.Errorf("unexpected predicate type %q", stmt.PredicateType)
+	}
+	builder := stmt.Predicate.RunDetails.Builder.ID
+	if strings.HasPrefix(builder, localBuilderPrefix) {
+		return fmt.Errorf("provenance was written on a local machine (builder %q); only provenance from the release workflow can be verified", builder)
+	}
+	if builder != expectedBuilder && !strings.HasPrefix(builder, expectedBuilder+"@") {
+		return fmt.Errorf("untrusted builder %q, expected %q", builder, expectedBuilder)
+	}
+	if ref := stmt.Predicate.BuildDefinition.ExternalParameters["ref"]; ref != "refs/tags/"+tag {
+		return fmt.Errorf("provenance was produced for %q, expected refs/tags/%s", ref, tag)
+	}
+
+	digests := make(map[string]string, len(stmt.Subject))
+	for _, s := range stmt.Subject {
+		digests[s.Name] = s.Digest["sha256"]
+	}
+	for name, sum := range checksums {
+		got, ok := digests[name]
+		if !ok {
+			return fmt.Errorf("provenance does not cover %s", name)
+		}
+		if !strings.EqualFold(got, sum) {
+			return fmt.Errorf("digest mismatch for %s: provenance has %s, checksums have %s", name, got, sum)
+		}
+	}
+	return nil
+}
+
+// FileName returns the release asset name of the provenance for version.
+func FileName(projectName, version string) string {
+	return fmt.Sprintf("%s_%s.intoto.json", projectName, strings.TrimPrefix(version, "v"))
+}
@@ -0,0 +1,193 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provenance
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerify(t *testing.T) {
+	checksums := map[string]string{
+		"test-server_Linux_x86_64.tar.gz": "aaaa",
+		"test-server_Windows_x86_64.zip":  "bbbb",
+	}
+	newStmt := func(builder, tag string, sums map[string]string) *Statement {
+		return NewStatement(sums, builder, "https://github.com/google/test-server", tag, "deadbeef", "1", time.Now())
+	}
+
+	testCases := []struct {
+		name    string
+		stmt    *Statement
+		wantErr string
+	}{
+		{
+			name: "Valid provenance",
+			stmt: newStmt(DefaultBuilderID, "v1.0.0", checksums),
+		},
+		{
+			name: "Builder with ref suffix",
+			stmt: newStmt(DefaultBuilderID+"@refs/tags/v1.0.0", "v1.0.0", checksums),
+		},
+		{
+			name:    "Untrusted builder",
+			stmt:    newStmt("https://example.com/builder", "v1.0.0", checksums),
+			wantErr: "untrusted builder",
+		},
+		{
+			name:    "Local builder",
+			stmt:    newStmt("local://laptop/v1.0.0", "v1.0.0", checksums),
+			wantErr: "written on a local machine",
+		},
+		{
+			name:    "Builder prefix without separator",
+			stmt:    newStmt(DefaultBuilderID+"-fork", "v1.0.0", checksums),
+			wantErr: "untrusted builder",
+		},
+		{
+			name:    "Wrong tag",
+			stmt:    newStmt(DefaultBuilderID, "v0.9.0", checksums),
+			wantErr: "expected refs/tags/v1.0.0",
+		},
+		{
+			name:    "Missing subject",
+			stmt:    newStmt(DefaultBuilderID, "v1.0.0", map[string]string{"test-server_Linux_x86_64.tar.gz": "aaaa"}),
+			wantErr: "does not cover test-server_Windows_x86_64.zip",
+		},
+		{
+			name: "Digest mismatch",
+			stmt: newStmt(DefaultBuilderID, "v1.0.0", map[string]string{
+				"test-server_Linux_x86_64.tar.gz": "cccc",
+				"test-server_Windows_x86_64.zip":  "bbbb",
+			}),
+			wantErr: "digest mismatch for test-server_Linux_x86_64.tar.gz",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Verify(tc.stmt, DefaultBuilderID, "v1.0.0", checksums)
+			if tc.wantErr == "" {
+				require.NoError(t, err)
+			} else {
+				require.ErrorContains(t, err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseRoundTrip(t *testing.T) {
+	stmt := NewStatement(map[string]string{"a.tar.gz": "1234"}, DefaultBuilderID, "https://github.com/google/test-server", "v1.0.0", "deadbeef", "", time.Now())
+	data, err := json.Marshal(stmt)
+	require.NoError(t, err)
+
+	parsed, err := Parse(data)
+	require.NoError(t, err)
+	require.Equal(t, stmt, parsed)
+}
+
+// sign returns the cosign-style signature of PAE(PayloadType, payload).
+func sign(t *testing.T, key *ecdsa.PrivateKey, payload []byte) []byte {
+	t.Helper()
+	digest := sha256.Sum256(PAE(PayloadType, payload))
+	sig, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+	require.NoError(t, err)
+	return []byte(base64.StdEncoding.EncodeToString(sig) + "\n")
+}
+
+func TestOpen(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	stmt := NewStatement(map[string]string{"a.tar.gz": "1234"}, DefaultBuilderID, "https://github.com/google/test-server", "v1.0.0", "deadbeef", "1", time.Now())
+	payload, err := json.Marshal(stmt)
+	require.NoError(t, err)
+	tampered, err := json.Marshal(NewStatement(map[string]string{"a.tar.gz": "5678"}, DefaultBuilderID, "https://github.com/google/test-server", "v1.0.0", "deadbeef", "1", time.Now()))
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name    string
+		env     *Envelope
+		wantErr string
+	}{
+		{
+			name: "Valid signature",
+			env:  NewEnvelope(payload, sign(t, key, payload)),
+		},
+		{
+			name: "Tampered payload",
+			env: func() *Envelope {
+				env := NewEnvelope(payload, sign(t, key, payload))
+				env.Payload = base64.StdEncoding.EncodeToString(tampered)
+				return env
+			}(),
+			wantErr: "invalid signature",
+		},
+		{
+			name:    "Wrong key",
+			env:     NewEnvelope(payload, sign(t, otherKey, payload)),
+			wantErr: "invalid signature",
+		},
+		{
+			name: "Unsigned",
+			env: func() *Envelope {
+				env := NewEnvelope(payload, nil)
+				env.Signatures = nil
+				return env
+			}(),
+			wantErr: "not signed",
+		},
+		{
+			name: "Wrong payload type",
+			env: func() *Envelope {
+				env := NewEnvelope(payload, sign(t, key, payload))
+				env.PayloadType = "application/json"
+				return env
+			}(),
+			wantErr: "unexpected payload type",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := json.Marshal(tc.env)
+			require.NoError(t, err)
+			opened, err := Open(data, &key.PublicKey)
+			if tc.wantErr == "" {
+				require.NoError(t, err)
+				require.Equal(t, stmt, opened)
+			} else {
+				require.ErrorContains(t, err, tc.wantErr)
+			}
+		})
+	}
+
+	t.Run("Bare statement", func(t *testing.T) {
+		_, err := Open(payload, &key.PublicKey)
+		require.ErrorContains(t, err, "unexpected payload type")
+	})
+}
@@ -0,0 +1,311 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package proxy is a forward proxy in front of the endpoints, for the
+// clients that can be configured with an HTTP proxy but not with a base URL.
+//
+// The proxy intercepts a CONNECT to the target host of an endpoint: it
+// answers the TLS handshake with a certificate for the host signed by a test
+// CA, which the client must trust, and hands the decrypted requests to the
+// server of the endpoint, which records or replays them as usual. Plain HTTP
+// requests to a target host are handed over the same way. A CONNECT to any
+// other host is tunneled to it untouched if passthrough allows the host, and
+// refused otherwise.
+package proxy
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/google/test-server/internal/certs"
+	"github.com/google/test-server/internal/config"
+	"github.com/google/test-server/internal/listen"
+)
+
+// Default files of the CA.
+const (
+	DefaultCACert = "test-server-ca.pem"
+	DefaultCAKey  = "test-server-ca-key.pem"
+)
+
+// Proxy is the forward proxy. It is an http.Handler.
+type Proxy struct {
+	endpoints []config.EndpointConfig
+	// forwards are the handlers of forward for the endpoints, by index.
+	forwards    []http.Handler
+	ca          *certs.CA
+	passthrough func(host string) bool
+}
+
+// New returns a proxy for the endpoints of cfg that intercepts their target
+// hosts with certificates signed by ca and tunnels the CONNECTs to the other
+// hosts for which passthrough returns true.
+func New(cfg *config.TestServerConfig, ca *certs.CA, passthrough func(host string) bool) *Proxy {
+	p := &Proxy{endpoints: cfg.Endpoints, ca: ca, passthrough: passthrough}
+	for i := range p.endpoints {
+		p.forwards = append(p.forwards, forward(&p.endpoints[i]))
+	}
+	return p
+}
+
+// bound is the listener opened by Bind.
+var (
+	boundMu sync.Mutex
+	bound   net.Listener
+)
+
+// Bind opens the port of cfg.Proxy ahead of Serve, which serves it then, and
+// sets the port to the free one picked for it if it is 0. It loads or
+// generates the CA first, so that the clients find it to trust once the port
+// is known.
+func Bind(cfg *config.TestServerConfig) error {
+	if _, _, err := loadCA(cfg.Proxy); err != nil {
+		return err
+	}
+	ln, err := open(cfg.Proxy)
+	if err != nil {
+		return err
+	}
+	cfg.Proxy.Port = int64(ln.Addr().(*net.TCPAddr).Port)
+	boundMu.Lock()
+	bound = ln
+	boundMu.Unlock()
+	return nil
+}
+
+// Serve serves the proxy of cfg.Proxy, with its CA loaded or generated, on
+// the port Bind opened or else a new one, until it fails or listen.Shutdown
+// stops it.
+func Serve(cfg *config.TestServerConfig, passthrough func(host string) bool) error {
+	ca, certFile, err := loadCA(cfg.Proxy)
+	if err != nil {
+		return err
+	}
+	boundMu.Lock()
+	ln := bound
+	bound = nil
+	boundMu.Unlock()
+	if ln == nil {
+		if ln, err = open(cfg.Proxy); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("Proxy listening on port %d, with the CA certificate %s to trust\n", ln.Addr().(*net.TCPAddr).Port, certFile)
+	return listen.ServeListener(&http.Server{Handler: New(cfg, ca, passthrough)}, ln)
+}
+
+// loadCA loads or generates the CA of p, and returns it with its certificate
+// file.
+func loadCA(p *config.ProxyConfig) (*certs.CA, string, error) {
+	certFile, keyFile := p.CACert, p.CAKey
+	if certFile == "" {
+		certFile = DefaultCACert
+	}
+	if keyFile == "" {
+		keyFile = DefaultCAKey
+	}
+	ca, err := certs.LoadCA(certFile, keyFile)
+	if err != nil {
+		return nil, "", fmt.Errorf("proxy CA: %w", err)
+	}
+	return ca, certFile, nil
+}
+
+// open opens the port of p.
+func open(p *config.ProxyConfig) (net.Listener, error) {
+	return net.Listen(listen.Network(p.BindAddress), net.JoinHostPort(p.BindAddress, strconv.FormatInt(p.Port, 10)))
+}
+
+// endpoint returns the index of the endpoint whose target is host and,
+// preferably, port, or -1.
+func (p *Proxy) endpoint(host, port string) int {
+	found := -1
+	for i, ep := range p.endpoints {
+		if !strings.EqualFold(ep.TargetHost, host) {
+			continue
+		}
+		if strconv.FormatInt(ep.TargetPort, 10) == port {
+			return i
+		}
+		if found == -1 {
+			found = i
+		}
+	}
+	return found
+}
+
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method == http.MethodConnect {
+		p.connect(w, req)
+		return
+	}
+	if !req.URL.IsAbs() {
+		http.Error(w, "test-server proxy: the request is not for a proxy", http.StatusBadRequest)
+		return
+	}
+	port := req.URL.Port()
+	if port == "" {
+		port = "80"
+	}
+	ep := p.endpoint(req.URL.Hostname(), port)
+	if ep == -1 {
+		http.Error(w, fmt.Sprintf("test-server proxy: no endpoint for %s", req.URL.Host), http.StatusBadGateway)
+		return
+	}
+	p.forwards[ep].ServeHTTP(w, req)
+}
+
+// connect intercepts or tunnels a CONNECT.
+func (p *Proxy) connect(w http.ResponseWriter, req *http.Request) {
+	host, port, err := net.SplitHostPort(req.Host)
+	if err != nil {
+		host, port = req.Host, "443"
+	}
+	ep := p.endpoint(host, port)
+	if ep == -1 && (p.passthrough == nil || !p.passthrough(host)) {
+		fmt.Printf("Proxy refused CONNECT to %s: no endpoint for the host\n", req.Host)
+		http.Error(w, fmt.Sprintf("test-server proxy: no endpoint for %s", req.Host), http.StatusForbidden)
+		return
+	}
+	var upstream net.Conn
+	if ep == -1 {
+		if upstream, err = net.Dial("tcp", net.JoinHostPort(host, port)); err != nil {
+			http.Error(w, fmt.Sprintf("test-server proxy: %v", err), http.StatusBadGateway)
+			return
+		}
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "test-server proxy: cannot take over the connection", http.StatusInternalServerError)
+		return
+	}
+	conn, buf, err := hijacker.Hijack()
+	if err != nil {
+		return
+	}
+	if _, err := io.WriteString(conn, "HTTP/1.1 200 Connection Established\r\n\r\n"); err != nil {
+		conn.Close()
+		return
+	}
+	client := &bufferedConn{Conn: conn, r: buf.Reader}
+	if ep == -1 {
+		tunnel(client, upstream)
+		return
+	}
+
+	tlsConn := tls.Server(client, &tls.Config{
+		GetCertificate: func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+			name := hello.ServerName
+			if name == "" {
+				name = host
+			}
+			return p.ca.Certificate(name)
+		},
+		NextProtos: []string{"h2", "http/1.1"},
+	})
+	server := &http.Server{Handler: p.forwards[ep]}
+	server.Serve(&connListener{conn: tlsConn})
+}
+
+// forward returns a handler that hands the requests to the server of ep,
+// unchanged but for the hop-by-hop headers, in the HTTP version they came in.
+func forward(ep *config.EndpointConfig) http.Handler {
+	network, address := listen.Dial(listen.Listeners(ep)[0])
+	target := &url.URL{Scheme: "http", Host: address}
+	if network == "unix" {
+		target.Host = "localhost"
+	}
+	dial := func(ctx context.Context, _, _ string) (net.Conn, error) {
+		return new(net.Dialer).DialContext(ctx, network, address)
+	}
+	// The certificate of the endpoint is for localhost, not the target host,
+	// and the connection does not leave the machine.
+	http1 := &http.Transport{DialContext: dial, TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
+	http2 := &http.Transport{DialContext: dial, TLSClientConfig: &tls.Config{InsecureSkipVerify: true}, Protocols: new(http.Protocols)}
+	if listen.UsesTLS(ep) {
+		target.Scheme = "https"
+		http2.Protocols.SetHTTP2(true)
+	} else {
+		http2.Protocols.SetUnencryptedHTTP2(true)
+	}
+	rewrite := func(r *httputil.ProxyRequest) {
+		r.SetURL(target)
+		r.Out.Host = r.In.Host
+	}
+	proxy1 := &httputil.ReverseProxy{Rewrite: rewrite, Transport: http1}
+	proxy2 := &httputil.ReverseProxy{Rewrite: rewrite, Transport: http2}
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.ProtoMajor == 2 {
+			proxy2.ServeHTTP(w, req)
+			return
+		}
+		proxy1.ServeHTTP(w, req)
+	})
+}
+
+// tunnel copies the bytes between a and b until either is closed.
+func tunnel(a, b net.Conn) {
+	var wg sync.WaitGroup
+	wg.Add(2)
+	copy := func(dst, src net.Conn) {
+		defer wg.Done()
+		io.Copy(dst, src)
+		dst.Close()
+	}
+	go copy(a, b)
+	go copy(b, a)
+	wg.Wait()
+}
+
+// bufferedConn is a hijacked connection whose first bytes may have been read
+// into r already.
+type bufferedConn struct {
+	net.Conn
+	r io.Reader
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	return c.r.Read(p)
+}
+
+// connListener accepts conn once, for an http.Server to serve a single
+// connection.
+type connListener struct {
+	conn net.Conn
+	once sync.Once
+}
+
+func (l *connListener) Accept() (net.Conn, error) {
+	var conn net.Conn
+	l.once.Do(func() { conn = l.conn })
+	if conn == nil {
+		return nil, errors.New("connection already accepted")
+	}
+	return conn, nil
+}
+
+func (l *connListener) Close() error   { return nil }
+func (l *connListener) Addr() net.Addr { return l.conn.LocalAddr() }
@@ -0,0 +1,123 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package proxy
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/google/test-server/internal/certs"
+	"github.com/google/test-server/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+// client returns a client that goes through the proxy at proxyURL and trusts
+// the roots.
+func client(proxyURL string, roots *x509.CertPool) *http.Client {
+	u, _ := url.Parse(proxyURL)
+	return &http.Client{Transport: &http.Transport{
+		Proxy:             http.ProxyURL(u),
+		TLSClientConfig:   &tls.Config{RootCAs: roots},
+		ForceAttemptHTTP2: true,
+	}}
+}
+
+func get(t *testing.T, c *http.Client, target string) (*http.Response, string) {
+	t.Helper()
+	resp, err := c.Get(target)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp, string(body)
+}
+
+func TestProxy(t *testing.T) {
+	// The server of the endpoint echoes what it receives, and speaks h2c
+	// like the endpoints do.
+	endpoint := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprintf(w, "%s %s %s %s %q", req.Proto, req.Method, req.Host, req.URL, req.Header.Get("X-Forwarded-For"))
+	}))
+	endpoint.Config.Protocols = new(http.Protocols)
+	endpoint.Config.Protocols.SetHTTP1(true)
+	endpoint.Config.Protocols.SetUnencryptedHTTP2(true)
+	endpoint.Start()
+	defer endpoint.Close()
+	_, port, _ := net.SplitHostPort(endpoint.Listener.Addr().String())
+	sourcePort, _ := strconv.ParseInt(port, 10, 64)
+	other := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		io.WriteString(w, "tunneled")
+	}))
+	defer other.Close()
+
+	ca, err := certs.NewCA()
+	require.NoError(t, err)
+	cfg := &config.TestServerConfig{Endpoints: []config.EndpointConfig{
+		{TargetHost: "api.example.com", TargetPort: 443, SourcePort: sourcePort},
+	}}
+	p := httptest.NewServer(New(cfg, ca, func(host string) bool { return host == "127.0.0.1" }))
+	defer p.Close()
+
+	roots := x509.NewCertPool()
+	require.True(t, roots.AppendCertsFromPEM(ca.PEM))
+	c := client(p.URL, roots)
+
+	resp, body := get(t, c, "https://api.example.com/v1/models?alt=json")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, `HTTP/2.0 GET api.example.com /v1/models?alt=json ""`, body)
+	require.Equal(t, "api.example.com", resp.TLS.PeerCertificates[0].Subject.CommonName)
+	require.Equal(t, "HTTP/2.0", resp.Proto)
+
+	resp, body = get(t, c, "http://api.example.com/v1/files")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, `HTTP/1.1 GET api.example.com /v1/files ""`, body)
+
+	// A host without an endpoint is refused, unless passthrough allows it.
+	_, err = c.Get("https://unknown.example.com/")
+	require.ErrorContains(t, err, "Forbidden")
+	otherRoots := x509.NewCertPool()
+	otherRoots.AddCert(other.Certificate())
+	_, body = get(t, client(p.URL, otherRoots), other.URL)
+	require.Equal(t, "tunneled", body)
+}
+
+func TestBind(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &config.TestServerConfig{Proxy: &config.ProxyConfig{
+		CACert:      filepath.Join(dir, "ca.pem"),
+		CAKey:       filepath.Join(dir, "ca-key.pem"),
+		BindAddress: "127.0.0.1",
+	}}
+
+	// The CA exists and the port is picked before Serve.
+	require.NoError(t, Bind(cfg))
+	require.NotZero(t, cfg.Proxy.Port)
+	require.FileExists(t, cfg.Proxy.CACert)
+	go Serve(cfg, nil)
+	resp, body := get(t, client(fmt.Sprintf("http://127.0.0.1:%d", cfg.Proxy.Port), nil), "http://api.example.com/")
+	require.Equal(t, http.StatusBadGateway, resp.StatusCode)
+	require.Contains(t, body, "no endpoint for api.example.com")
+}
@@ -17,39 +17,78 @@ limitations under the License.
 package record
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
 	"sync"
+	"syscall"
+	"time"
 
 	"github.com/google/test-server/internal/config"
+	"github.com/google/test-server/internal/listen"
+	"github.com/google/test-server/internal/proxy"
 	"github.com/google/test-server/internal/redact"
+	"github.com/google/test-server/internal/route"
 )
 
-func Record(cfg *config.TestServerConfig, recordingDir string, redactor *redact.Redact) error {
-	// Create recording directory if it doesn't exist
-	if err := os.MkdirAll(recordingDir, 0755); err != nil {
-		return fmt.Errorf("failed to create recording directory: %w", err)
+// Record proxies the requests to the endpoints of cfg to their targets and
+// records them in recordingDir until interrupted. It calls ready, if not nil,
+// once the servers are about to accept connections.
+func Record(cfg *config.TestServerConfig, recordingDir string, redactor *redact.Redact, ready func()) error {
+	if err := Validate(cfg, recordingDir); err != nil {
+		return err
 	}
 
 	fmt.Printf("Recording to directory: %s\n", recordingDir)
+	var servers []func() error
+	for _, endpoint := range cfg.Endpoints {
+		fmt.Printf("Starting server for %v\n", endpoint)
+		server := NewRecordingHTTPSProxy(&endpoint, recordingDir, redactor)
+		handler, err := server.Handler()
+		if err != nil {
+			return fmt.Errorf("endpoint %s: %w", endpoint.TargetHost, err)
+		}
+		servers = append(servers, func() error {
+			if err := server.Serve(handler); err != nil {
+				return fmt.Errorf("proxy error for %s:%d: %w", endpoint.TargetHost, endpoint.TargetPort, err)
+			}
+			return nil
+		})
+	}
+
+	interrupted := make(chan os.Signal, 1)
+	signal.Notify(interrupted, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(interrupted)
+	if ready != nil {
+		ready()
+	}
+
 	var wg sync.WaitGroup
-	errChan := make(chan error, len(cfg.Endpoints))
+	errChan := make(chan error, len(cfg.Endpoints)+1)
 
 	// Start a proxy for each endpoint
-	for _, endpoint := range cfg.Endpoints {
+	for _, serve := range servers {
 		wg.Add(1)
-		go func(ep config.EndpointConfig) {
+		go func() {
 			defer wg.Done()
+			if err := serve(); err != nil {
+				errChan <- err
+			}
+		}()
+	}
 
-			fmt.Printf("Starting server for %v\n", endpoint)
-			proxy := NewRecordingHTTPSProxy(&endpoint, recordingDir, redactor)
-			err := proxy.Start()
-
-			if err != nil {
-				errChan <- fmt.Errorf("proxy error for %s:%d: %w",
-					ep.TargetHost, ep.TargetPort, err)
+	// Start the forward proxy in front of them, which tunnels the CONNECTs to
+	// the other hosts.
+	if cfg.Proxy != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := proxy.Serve(cfg, func(string) bool { return true }); err != nil {
+				errChan <- fmt.Errorf("forward proxy error: %w", err)
 			}
-		}(endpoint)
+		}()
 	}
 
 	// Wait for all proxies to complete (they shouldn't unless there's an error)
@@ -58,11 +97,70 @@ func Record(cfg *config.TestServerConfig, recordingDir string, redactor *redact.
 		close(errChan)
 	}()
 
-	// Return the first error encountered, if any
-	for err := range errChan {
-		return err
+	// Return the first error encountered, if any, blocking until then (or
+	// until interrupted).
+	for {
+		select {
+		case err, ok := <-errChan:
+			if ok {
+				return err
+			}
+			// Every proxy stopped without an error.
+			errChan = nil
+		case <-interrupted:
+			Shutdown(os.Stdout, interrupted)
+			return nil
+		}
+	}
+}
+
+// Validate rejects an unsupported target_type, invalid routes or CORS in the
+// endpoints of cfg, and creates recordingDir if it is missing, so that Record
+// fails on them before any port is bound.
+func Validate(cfg *config.TestServerConfig, recordingDir string) error {
+	if err := os.MkdirAll(recordingDir, 0755); err != nil {
+		return fmt.Errorf("failed to create recording directory: %w", err)
+	}
+	for _, endpoint := range cfg.Endpoints {
+		if _, err := endpoint.UpstreamURL("http"); err != nil {
+			return err
+		}
+		if err := route.Validate(&endpoint); err != nil {
+			return fmt.Errorf("endpoint %s: %w", endpoint.TargetHost, err)
+		}
 	}
+	return nil
+}
+
+// shutdownTimeout is how long Shutdown waits for the requests in flight.
+var shutdownTimeout = 10 * time.Second
+
+// SetShutdownTimeout sets how long the servers get to complete their requests
+// in flight once interrupted.
+func SetShutdownTimeout(d time.Duration) {
+	shutdownTimeout = d
+}
 
-	// Block forever (or until interrupted)
-	select {}
+// Shutdown stops the servers once interrupted and gives their requests in
+// flight and the webhooks being sent up to the shutdown timeout to complete,
+// so that the recordings of the last requests are written, or until
+// interrupted again. It then writes the summary of the requests to out.
+func Shutdown(out io.Writer, interrupted <-chan os.Signal) {
+	fmt.Fprintf(out, "Shutting down, waiting up to %v for the requests in flight (interrupt again to stop now)\n", shutdownTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	go func() {
+		select {
+		case <-interrupted:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	if err := listen.Shutdown(ctx); err != nil {
+		fmt.Fprintf(out, "Closed the connections of the requests still in flight: %v\n", err)
+	}
+	if err := route.WaitWebhooks(ctx); err != nil {
+		fmt.Fprintf(out, "Stopped sending the webhooks left: %v\n", err)
+	}
+	route.WriteSummary(out)
 }
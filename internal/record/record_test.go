@@ -0,0 +1,80 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package record
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/test-server/internal/config"
+	"github.com/google/test-server/internal/redact"
+	"github.com/google/test-server/internal/store"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordPlainHTTPUpstream(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"path":"` + req.URL.Path + `","query":"` + req.URL.RawQuery + `"}`))
+	}))
+	defer upstream.Close()
+	host, port, err := net.SplitHostPort(strings.TrimPrefix(upstream.URL, "http://"))
+	require.NoError(t, err)
+	targetPort, err := strconv.ParseInt(port, 10, 64)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	redactor, err := redact.NewRedact(nil)
+	require.NoError(t, err)
+	proxy := NewRecordingHTTPSProxy(&config.EndpointConfig{
+		TargetType: "http",
+		TargetHost: host,
+		TargetPort: targetPort,
+	}, dir, redactor)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/models?alt=json", strings.NewReader(`{"prompt":"hi"}`))
+	req.Header.Set("Test-Name", "plain upstream")
+	rec := httptest.NewRecorder()
+	proxy.handleRequest(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.JSONEq(t, `{"path":"/v1/models","query":"alt=json"}`, rec.Body.String())
+
+	data, err := os.ReadFile(filepath.Join(dir, "plain_upstream.json"))
+	require.NoError(t, err)
+	var file store.RecordFile
+	require.NoError(t, json.Unmarshal(data, &file))
+	require.Len(t, file.Interactions, 1)
+	require.Equal(t, "http", file.Interactions[0].Request.Protocol)
+	require.Equal(t, map[string]any{"prompt": "hi"}, file.Interactions[0].Request.BodySegments[0])
+	require.Equal(t, map[string]any{"path": "/v1/models", "query": "alt=json"}, file.Interactions[0].Response.BodySegments[0])
+}
+
+func TestRecordRejectsUnsupportedTargetType(t *testing.T) {
+	redactor, err := redact.NewRedact(nil)
+	require.NoError(t, err)
+	cfg := &config.TestServerConfig{Endpoints: []config.EndpointConfig{{TargetType: "tcp", TargetHost: "example.com", TargetPort: 1}}}
+	require.ErrorContains(t, Validate(cfg, t.TempDir()), "unsupported value")
+	// Record fails on it before announcing that it is ready.
+	require.ErrorContains(t, Record(cfg, t.TempDir(), redactor, func() { t.Error("ready called") }), "unsupported value")
+}
@@ -26,8 +26,11 @@ import (
 	"path/filepath"
 	"regexp"
 
+	"github.com/google/test-server/internal/codec"
 	"github.com/google/test-server/internal/config"
+	"github.com/google/test-server/internal/listen"
 	"github.com/google/test-server/internal/redact"
+	"github.com/google/test-server/internal/route"
 	"github.com/google/test-server/internal/store"
 	"github.com/gorilla/websocket"
 )
@@ -38,6 +41,11 @@ type RecordingHTTPSProxy struct {
 	config         *config.EndpointConfig
 	recordingDir   string
 	redactor       *redact.Redact
+	// appendExisting makes the first interaction of a recording file append
+	// to what the file already holds instead of replacing it.
+	appendExisting bool
+	// forwardOnly makes Forward and ForwardWebsocket proxy without recording.
+	forwardOnly bool
 }
 
 func NewRecordingHTTPSProxy(cfg *config.EndpointConfig, recordingDir string, redactor *redact.Redact) *RecordingHTTPSProxy {
@@ -54,16 +62,52 @@ func (r *RecordingHTTPSProxy) ResetChain() {
 	r.prevRequestSHA = store.HeadSHA
 }
 
-func (r *RecordingHTTPSProxy) Start() error {
-	addr := fmt.Sprintf(":%d", r.config.SourcePort)
-	server := &http.Server{
-		Addr:    addr,
-		Handler: http.HandlerFunc(r.handleRequest),
+// SetAppend makes the proxy add new interactions to existing recording files
+// instead of overwriting them, as auto mode needs.
+func (r *RecordingHTTPSProxy) SetAppend(appendExisting bool) {
+	r.appendExisting = appendExisting
+}
+
+// SetForwardOnly makes Forward and ForwardWebsocket only proxy to the target,
+// without recording anything, as replay mode's passthrough needs.
+func (r *RecordingHTTPSProxy) SetForwardOnly(forwardOnly bool) {
+	r.forwardOnly = forwardOnly
+}
+
+// Forward proxies req to the target and, unless the proxy is forward-only,
+// records the interaction as recReq, which the caller has already redacted
+// and chained, in the recording file fileName. It returns the SHA-256 of recReq. On error nothing was recorded,
+// but the response may have been written already.
+func (r *RecordingHTTPSProxy) Forward(w http.ResponseWriter, req *http.Request, recReq *store.RecordedRequest, fileName string) (string, error) {
+	resp, respBody, err := r.proxyRequest(w, req)
+	if err != nil {
+		return "", fmt.Errorf("error proxying request: %w", err)
 	}
-	if err := server.ListenAndServe(); err != nil {
-		panic(err)
+	shaSum := recReq.ComputeSum()
+	if r.forwardOnly {
+		return shaSum, nil
 	}
-	return nil
+	if err := r.recordResponse(recReq, resp, fileName, shaSum, respBody); err != nil {
+		return "", fmt.Errorf("error recording response: %w", err)
+	}
+	return shaSum, nil
+}
+
+// ForwardWebsocket proxies a websocket upgrade request to the target and,
+// unless the proxy is forward-only, records the messages in the recording
+// file fileName.
+func (r *RecordingHTTPSProxy) ForwardWebsocket(w http.ResponseWriter, req *http.Request, fileName string) {
+	r.proxyWebsocket(w, req, fileName)
+}
+
+// Handler returns the handler of the endpoint: the proxy behind its routes.
+func (r *RecordingHTTPSProxy) Handler() (http.Handler, error) {
+	return route.Handler(r.config, r.redactor, http.HandlerFunc(r.handleRequest))
+}
+
+// Serve serves handler, the one of Handler, on the listeners of the endpoint.
+func (r *RecordingHTTPSProxy) Serve(handler http.Handler) error {
+	return listen.Serve(r.config, handler)
 }
 
 func (r *RecordingHTTPSProxy) handleRequest(w http.ResponseWriter, req *http.Request) {
@@ -85,6 +129,10 @@ func (r *RecordingHTTPSProxy) handleRequest(w http.ResponseWriter, req *http.Req
 		http.Error(w, fmt.Sprintf("Invalid recording file name: %v", err), http.StatusInternalServerError)
 		return
 	}
+	if session := route.SessionOf(req); session != "" {
+		// Every session records to a directory of its own.
+		fileName = filepath.Join(route.SessionsDir, session, fileName)
+	}
 	if _, ok := r.seenFiles[fileName]; !ok {
 		// Reset to HeadSHA when first time seen a request from the given file.
 		recReq.PreviousRequest = store.HeadSHA
@@ -96,17 +144,10 @@ func (r *RecordingHTTPSProxy) handleRequest(w http.ResponseWriter, req *http.Req
 		return
 	}
 
-	resp, respBody, err := r.proxyRequest(w, req)
-	if err != nil {
-		fmt.Printf("Error proxying request: %v\n", err)
-		http.Error(w, fmt.Sprintf("Error proxying request: %v", err), http.StatusInternalServerError)
-		return
-	}
-	shaSum := recReq.ComputeSum()
-	err = r.recordResponse(recReq, resp, fileName, shaSum, respBody)
+	shaSum, err := r.Forward(w, req, recReq, fileName)
 	if err != nil {
-		fmt.Printf("Error recording response: %v\n", err)
-		http.Error(w, fmt.Sprintf("Error recording response: %v", err), http.StatusInternalServerError)
+		fmt.Printf("%v\n", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 	if fileName != shaSum {
@@ -135,7 +176,11 @@ func (r *RecordingHTTPSProxy) redactRequest(req *http.Request) (*store.RecordedR
 }
 
 func (r *RecordingHTTPSProxy) proxyRequest(w http.ResponseWriter, req *http.Request) (*http.Response, []byte, error) {
-	url := fmt.Sprintf("https://%s:%d%s", r.config.TargetHost, r.config.TargetPort, req.URL.Path)
+	base, err := r.config.UpstreamURL("http")
+	if err != nil {
+		return nil, nil, err
+	}
+	url := base + req.URL.Path
 	if req.URL.RawQuery != "" {
 		url += "?" + req.URL.RawQuery
 	}
@@ -156,6 +201,10 @@ func (r *RecordingHTTPSProxy) proxyRequest(w http.ResponseWriter, req *http.Requ
 			proxyReq.Header.Add(name, value)
 		}
 	}
+	// Only ask for codings the recording can decode.
+	if accept := proxyReq.Header.Get("Accept-Encoding"); accept != "" {
+		proxyReq.Header.Set("Accept-Encoding", codec.Decodable(accept))
+	}
 
 	resp, err := http.DefaultClient.Do(proxyReq)
 	if err != nil {
@@ -187,10 +236,21 @@ func (r *RecordingHTTPSProxy) recordResponse(recReq *store.RecordedRequest, resp
 		return err
 	}
 
+	recordPath := filepath.Join(r.recordingDir, fileName+".json")
+
 	recordFile, ok := r.seenFiles[fileName]
 	if !ok {
 		r.seenFiles[fileName] = store.RecordFile{RecordID: fileName, Interactions: []*store.RecordInteraction{}}
 		recordFile = r.seenFiles[fileName]
+		if r.appendExisting {
+			existing, err := readRecordFile(recordPath)
+			if err != nil {
+				return err
+			}
+			if existing != nil {
+				recordFile = *existing
+			}
+		}
 	}
 
 	var recordInteraction store.RecordInteraction
@@ -201,8 +261,6 @@ func (r *RecordingHTTPSProxy) recordResponse(recReq *store.RecordedRequest, resp
 	recordFile.Interactions = append(recordFile.Interactions, &recordInteraction)
 	r.seenFiles[fileName] = recordFile
 
-	recordPath := filepath.Join(r.recordingDir, fileName+".json")
-
 	recordDir := filepath.Dir(recordPath)
     if err := os.MkdirAll(recordDir, 0755); err != nil {
         return err
@@ -229,6 +287,22 @@ func (r *RecordingHTTPSProxy) recordResponse(recReq *store.RecordedRequest, resp
 	return nil
 }
 
+// readRecordFile returns the recording at path, or nil if there is none.
+func readRecordFile(path string) (*store.RecordFile, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var recordFile store.RecordFile
+	if err := json.Unmarshal(data, &recordFile); err != nil {
+		return nil, fmt.Errorf("unable to deserialize %s to RecordFile: %w", path, err)
+	}
+	return &recordFile, nil
+}
+
 // applyResponseHeaderReplacements applies the header replacements defined in the EndpointConfig to the request headers.
 func (r *RecordingHTTPSProxy) applyResponseHeaderReplacements(headers http.Header) {
 	for _, replacement := range r.config.ResponseHeaderReplacements {
@@ -263,14 +337,18 @@ func (r *RecordingHTTPSProxy) proxyWebsocket(w http.ResponseWriter, req *http.Re
 	go r.pumpWebsocket(clientConn, conn, c, quit, ">")
 	go r.pumpWebsocket(conn, clientConn, c, quit, "<")
 
-	recordPath := filepath.Join(r.recordingDir, fileName+".websocket.log")
-	f, err := os.Create(recordPath)
-	if err != nil {
-		fmt.Printf("Error creating websocket recording file: %v\n", err)
-		http.Error(w, fmt.Sprintf("Error proxying websocket: %v", err), http.StatusInternalServerError)
-		return
+	var f io.Writer = io.Discard
+	if !r.forwardOnly {
+		recordPath := filepath.Join(r.recordingDir, fileName+".websocket.log")
+		file, err := os.Create(recordPath)
+		if err != nil {
+			fmt.Printf("Error creating websocket recording file: %v\n", err)
+			http.Error(w, fmt.Sprintf("Error proxying websocket: %v", err), http.StatusInternalServerError)
+			return
+		}
+		defer file.Close()
+		f = file
 	}
-	defer f.Close()
 
 	quitCount := 0
 	for {
@@ -315,7 +393,11 @@ func (r *RecordingHTTPSProxy) pumpWebsocket(src, dst *websocket.Conn, c chan []b
 }
 
 func (r *RecordingHTTPSProxy) upgradeConnectionToWebsocket(w http.ResponseWriter, req *http.Request) (*websocket.Conn, *websocket.Conn, error) {
-	url := fmt.Sprintf("wss://%s:%d%s", r.config.TargetHost, r.config.TargetPort, req.URL.Path)
+	base, err := r.config.UpstreamURL("ws")
+	if err != nil {
+		return nil, nil, err
+	}
+	url := base + req.URL.Path
 	if req.URL.RawQuery != "" {
 		url += "?" + req.URL.RawQuery
 	}
@@ -0,0 +1,195 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package releasenotes renders the release notes of a range of conventional
+// commits, grouped by component and commit type.
+package releasenotes
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	githubOwner = "google"
+	githubRepo  = "test-server"
+)
+
+// OtherType collects the commits that do not follow the conventional format,
+// and those whose type is not one of commitTypes, e.g. security: or a typo
+// like fixes:, which keep their whole subject.
+const OtherType = "other"
+
+// commitTypes lists the conventional-commit types in the order their sections
+// appear in the notes, along with the section heading used for each.
+var commitTypes = []struct {
+	Type    string
+	Heading string
+}{
+	{"feat", "Features"},
+	{"fix", "Bug Fixes"},
+	{"perf", "Performance Improvements"},
+	{"refactor", "Code Refactoring"},
+	{"docs", "Documentation"},
+	{"test", "Tests"},
+	{"build", "Build System"},
+	{"ci", "Continuous Integration"},
+	{"chore", "Miscellaneous Chores"},
+	{OtherType, "Other Changes"},
+}
+
+// components maps a path prefix to the component heading it belongs to.
+// Anything that does not match an SDK directory is attributed to the server.
+var components = []struct {
+	Prefix string
+	Name   string
+}{
+	{"sdks/typescript/", "TypeScript SDK"},
+	{"sdks/python/", "Python SDK"},
+	{"sdks/dotnet/", "Dotnet SDK"},
+}
+
+const serverComponent = "test-server"
+
+var conventionalRe = regexp.MustCompile(`^(\w+)(?:\(([^)]*)\))?(!)?:\s*(.+)$`)
+
+// Commit is a commit of the release.
+type Commit struct {
+	Hash     string
+	Type     string
+	Scope    string
+	Subject  string
+	Breaking bool
+	Files    []string
+}
+
+// Parse returns the commit hash with the subject and body, touching files.
+func Parse(hash, subject, body string, files []string) Commit {
+	c := Commit{Hash: hash, Type: OtherType, Subject: subject, Files: files}
+	m := conventionalRe.FindStringSubmatch(subject)
+	if m == nil {
+		return c
+	}
+	c.Breaking = m[3] == "!" || strings.Contains(body, "BREAKING CHANGE")
+	if !known(strings.ToLower(m[1])) {
+		return c
+	}
+	c.Type = strings.ToLower(m[1])
+	c.Scope = m[2]
+	c.Subject = m[4]
+	return c
+}
+
+func known(commitType string) bool {
+	for _, t := range commitTypes {
+		if t.Type == commitType {
+			return true
+		}
+	}
+	return false
+}
+
+// componentsFor returns the set of components touched by the commit's files.
+func componentsFor(files []string) []string {
+	seen := map[string]bool{}
+	var result []string
+	for _, f := range files {
+		name := serverComponent
+		for _, c := range components {
+			if strings.HasPrefix(f, c.Prefix) {
+				name = c.Name
+				break
+			}
+		}
+		if !seen[name] {
+			seen[name] = true
+			result = append(result, name)
+		}
+	}
+	if len(result) == 0 {
+		result = append(result, serverComponent)
+	}
+	return result
+}
+
+// Render returns the Markdown notes of the commits between the tags from and
+// to.
+func Render(from, to string, commits []Commit) string {
+	var b strings.Builder
+
+	var breaking []Commit
+	for _, c := range commits {
+		if c.Breaking {
+			breaking = append(breaking, c)
+		}
+	}
+	if len(breaking) > 0 {
+		b.WriteString("## ⚠ Breaking Changes\n\n")
+		for _, c := range breaking {
+			writeEntry(&b, c)
+		}
+		b.WriteString("\n")
+	}
+
+	componentOrder := []string{serverComponent}
+	for _, c := range components {
+		componentOrder = append(componentOrder, c.Name)
+	}
+	for _, component := range componentOrder {
+		var section strings.Builder
+		for _, t := range commitTypes {
+			var entries []Commit
+			for _, c := range commits {
+				if c.Type != t.Type {
+					continue
+				}
+				for _, cc := range componentsFor(c.Files) {
+					if cc == component {
+						entries = append(entries, c)
+						break
+					}
+				}
+			}
+			if len(entries) == 0 {
+				continue
+			}
+			fmt.Fprintf(&section, "### %s\n\n", t.Heading)
+			for _, c := range entries {
+				writeEntry(&section, c)
+			}
+			section.WriteString("\n")
+		}
+		if section.Len() > 0 {
+			fmt.Fprintf(&b, "## %s\n\n%s", component, section.String())
+		}
+	}
+
+	fmt.Fprintf(&b, "**Full Changelog**: https://github.com/%s/%s/compare/%s...%s\n", githubOwner, githubRepo, from, to)
+	return b.String()
+}
+
+func writeEntry(b *strings.Builder, c Commit) {
+	short := c.Hash
+	if len(short) > 7 {
+		short = short[:7]
+	}
+	if c.Scope != "" {
+		fmt.Fprintf(b, "- **%s:** %s (%s)\n", c.Scope, c.Subject, short)
+	} else {
+		fmt.Fprintf(b, "- %s (%s)\n", c.Subject, short)
+	}
+}
@@ -0,0 +1,61 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package releasenotes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	c := Parse("abc", "feat(replay)!: serve HTTP/2", "", nil)
+	require.Equal(t, Commit{Hash: "abc", Type: "feat", Scope: "replay", Subject: "serve HTTP/2", Breaking: true}, c)
+
+	// Unknown types are kept whole under Other Changes, not dropped.
+	for _, subject := range []string{"security: bump the TLS minimum", "fixes: typo in the README", "Merge the docs"} {
+		c := Parse("abc", subject, "", nil)
+		require.Equal(t, OtherType, c.Type, subject)
+		require.Equal(t, subject, c.Subject)
+	}
+}
+
+func TestRender(t *testing.T) {
+	notes := Render("v0.2.7", "v0.2.8", []Commit{
+		Parse("1111111aaa", "fix: close the listeners", "", []string{"internal/listen/listen.go"}),
+		Parse("2222222bbb", "deps: bump cobra", "", []string{"go.mod"}),
+		Parse("3333333ccc", "feat: retry the download", "", []string{"sdks/python/src/install.py"}),
+	})
+	require.Equal(t, `## test-server
+
+### Bug Fixes
+
+- close the listeners (1111111)
+
+### Other Changes
+
+- deps: bump cobra (2222222)
+
+## Python SDK
+
+### Features
+
+- retry the download (3333333)
+
+**Full Changelog**: https://github.com/google/test-server/compare/v0.2.7...v0.2.8
+`, notes)
+}
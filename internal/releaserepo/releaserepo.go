@@ -0,0 +1,83 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package releaserepo names the GitHub repository the release tooling reads
+// releases from. That is google/test-server, unless a staging rehearsal
+// (scripts/release --staging) points the tooling and the SDK installers at a
+// scratch repository through EnvVar.
+package releaserepo
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+)
+
+// EnvVar overrides the release repository, as "owner/name".
+const EnvVar = "TEST_SERVER_RELEASE_REPO"
+
+// Repo is a GitHub repository.
+type Repo struct {
+	Owner string
+	Name  string
+}
+
+// Production is the repository real releases are published to.
+var Production = Repo{Owner: "google", Name: "test-server"}
+
+var repoRe = regexp.MustCompile(`^([A-Za-z0-9][A-Za-z0-9-]*)/([A-Za-z0-9._-]+)$`)
+
+// Parse parses "owner/name".
+func Parse(s string) (Repo, error) {
+	m := repoRe.FindStringSubmatch(s)
+	if m == nil {
+		return Repo{}, fmt.Errorf("invalid repository %q, want owner/name", s)
+	}
+	return Repo{Owner: m[1], Name: m[2]}, nil
+}
+
+// FromEnv returns the repository named by EnvVar, or Production when it is
+// unset.
+func FromEnv() (Repo, error) {
+	s := os.Getenv(EnvVar)
+	if s == "" {
+		return Production, nil
+	}
+	r, err := Parse(s)
+	if err != nil {
+		return Repo{}, fmt.Errorf("%s: %w", EnvVar, err)
+	}
+	return r, nil
+}
+
+func (r Repo) String() string {
+	return r.Owner + "/" + r.Name
+}
+
+// Staging reports whether r is not the production repository.
+func (r Repo) Staging() bool {
+	return r != Production
+}
+
+// DownloadURL returns the URL of a release asset.
+func (r Repo) DownloadURL(tag, asset string) string {
+	return fmt.Sprintf("https://github.com/%s/%s/releases/download/%s/%s", r.Owner, r.Name, tag, asset)
+}
+
+// ReleaseURL returns the URL of the release page of tag.
+func (r Repo) ReleaseURL(tag string) string {
+	return fmt.Sprintf("https://github.com/%s/%s/releases/tag/%s", r.Owner, r.Name, tag)
+}
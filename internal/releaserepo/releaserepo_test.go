@@ -0,0 +1,67 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package releaserepo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Repo
+		wantErr bool
+	}{
+		{in: "google/test-server", want: Production},
+		{in: "someone/test-server-staging", want: Repo{Owner: "someone", Name: "test-server-staging"}},
+		{in: "test-server", wantErr: true},
+		{in: "a/b/c", wantErr: true},
+		{in: "/test-server", wantErr: true},
+		{in: "https://github.com/google/test-server", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := Parse(tt.in)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	t.Setenv(EnvVar, "")
+	r, err := FromEnv()
+	require.NoError(t, err)
+	require.False(t, r.Staging())
+
+	t.Setenv(EnvVar, "someone/scratch")
+	r, err = FromEnv()
+	require.NoError(t, err)
+	require.True(t, r.Staging())
+	require.Equal(t, "https://github.com/someone/scratch/releases/download/v0.3.0/test-server_0.3.0_checksums.txt",
+		r.DownloadURL("v0.3.0", "test-server_0.3.0_checksums.txt"))
+
+	t.Setenv(EnvVar, "not a repo")
+	_, err = FromEnv()
+	require.Error(t, err)
+}
@@ -17,42 +17,204 @@ limitations under the License.
 package replay
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
 
 	"github.com/google/test-server/internal/config"
+	"github.com/google/test-server/internal/match"
+	"github.com/google/test-server/internal/proxy"
+	"github.com/google/test-server/internal/record"
 	"github.com/google/test-server/internal/redact"
+	"github.com/google/test-server/internal/route"
 )
 
-// Replay serves recorded responses for HTTP requests
-func Replay(cfg *config.TestServerConfig, recordingDir string, redactor *redact.Redact) error {
-	// Validate recording directory exists
+// ErrUnmatched is returned by Replay in strict mode when it is interrupted
+// after a request without a recording.
+var ErrUnmatched = errors.New("requests without a recording were made in strict mode")
+
+// Options tune replay mode.
+type Options struct {
+	// Strict answers the requests without a recording with 501 Not
+	// Implemented and the diff from the closest recorded request, and makes
+	// Replay return ErrUnmatched once interrupted if there were any.
+	Strict bool
+	// Passthrough proxies the requests without a recording to the target
+	// instead of failing them, and RecordPassthrough also adds them to the
+	// recordings like auto mode. The passthrough section of the config can
+	// restrict and extend the targets it applies to.
+	Passthrough       bool
+	RecordPassthrough bool
+}
+
+// Validate checks that recordingDir exists and rejects what Replay with opts
+// would fail on in the endpoints of cfg, before any port is bound.
+func Validate(cfg *config.TestServerConfig, recordingDir string, opts Options) error {
 	if _, err := os.Stat(recordingDir); os.IsNotExist(err) {
 		return fmt.Errorf("recording directory does not exist: %s", recordingDir)
 	}
+	return validate(cfg, func(ep *config.EndpointConfig) bool {
+		return cfg.Passthrough.Allows(ep.TargetHost, opts.Passthrough)
+	})
+}
+
+// Replay serves recorded responses for HTTP requests, calling ready, if not
+// nil, once the servers are about to accept connections.
+//
+// The requests without a recording that are passed through reach the
+// target, so strict mode does not apply to them.
+func Replay(cfg *config.TestServerConfig, recordingDir string, redactor *redact.Redact, opts Options, ready func()) error {
+	if err := Validate(cfg, recordingDir, opts); err != nil {
+		return err
+	}
+	forwards := func(ep *config.EndpointConfig) bool {
+		return cfg.Passthrough.Allows(ep.TargetHost, opts.Passthrough)
+	}
 
 	fmt.Printf("Replaying from directory: %s\n", recordingDir)
+	var unmatched atomic.Int64
+	passthrough := func(host string) bool {
+		return cfg.Passthrough.Allows(host, opts.Passthrough)
+	}
+	err := serve(cfg, recordingDir, redactor, passthrough, ready, func(server *ReplayHTTPServer, ep *config.EndpointConfig) {
+		if opts.Strict {
+			server.SetStrict(&unmatched)
+		}
+		if forwards(ep) {
+			proxy := record.NewRecordingHTTPSProxy(ep, recordingDir, redactor)
+			proxy.SetAppend(true)
+			proxy.SetForwardOnly(!opts.RecordPassthrough)
+			server.SetFallback(proxy)
+		}
+	})
+	if err == nil && unmatched.Load() > 0 {
+		err = fmt.Errorf("%w: %d requests", ErrUnmatched, unmatched.Load())
+	}
+	return err
+}
 
-	// Start a server for each endpoint
-	errChan := make(chan error, len(cfg.Endpoints))
+// ValidateAuto creates recordingDir if it is missing and rejects what Auto
+// would fail on in the endpoints of cfg, before any port is bound.
+func ValidateAuto(cfg *config.TestServerConfig, recordingDir string) error {
+	if err := os.MkdirAll(recordingDir, 0755); err != nil {
+		return fmt.Errorf("failed to create recording directory: %w", err)
+	}
+	return validate(cfg, func(ep *config.EndpointConfig) bool {
+		return cfg.Passthrough.Allows(ep.TargetHost, true)
+	})
+}
+
+// Auto serves recorded responses like Replay, and records the requests that
+// have no recording from the target like Record, adding them to the
+// recordings so the next run replays them. Targets excluded by the
+// passthrough section of the config are only replayed. It calls ready, if
+// not nil, once the servers are about to accept connections.
+func Auto(cfg *config.TestServerConfig, recordingDir string, redactor *redact.Redact, ready func()) error {
+	if err := ValidateAuto(cfg, recordingDir); err != nil {
+		return err
+	}
+	forwards := func(ep *config.EndpointConfig) bool {
+		return cfg.Passthrough.Allows(ep.TargetHost, true)
+	}
 
+	fmt.Printf("Replaying from and recording to directory: %s\n", recordingDir)
+	passthrough := func(host string) bool {
+		return cfg.Passthrough.Allows(host, true)
+	}
+	return serve(cfg, recordingDir, redactor, passthrough, ready, func(server *ReplayHTTPServer, ep *config.EndpointConfig) {
+		if !forwards(ep) {
+			return
+		}
+		proxy := record.NewRecordingHTTPSProxy(ep, recordingDir, redactor)
+		proxy.SetAppend(true)
+		server.SetFallback(proxy)
+	})
+}
+
+// validate rejects an invalid match_on, routes or CORS, and a target that cannot be reached
+// when forwards says its requests may be forwarded, before any server starts
+// listening.
+func validate(cfg *config.TestServerConfig, forwards func(*config.EndpointConfig) bool) error {
 	for _, endpoint := range cfg.Endpoints {
-		go func(ep config.EndpointConfig) {
-			server := NewReplayHTTPServer(&endpoint, recordingDir, redactor)
-			err := server.Start()
-			if err != nil {
-				errChan <- fmt.Errorf("replay error for %s:%d: %w",
-					ep.TargetHost, ep.TargetPort, err)
+		if _, err := match.New(&endpoint); err != nil {
+			return fmt.Errorf("endpoint %s: %w", endpoint.TargetHost, err)
+		}
+		if err := route.Validate(&endpoint); err != nil {
+			return fmt.Errorf("endpoint %s: %w", endpoint.TargetHost, err)
+		}
+		if forwards(&endpoint) {
+			if _, err := endpoint.UpstreamURL("http"); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// serve starts a server for each endpoint, configured by setup if it is not
+// nil, and the forward proxy of cfg, if any, which tunnels the CONNECTs to the
+// other hosts for which passthrough returns true. It calls ready, if not nil,
+// once the handlers of the servers are built. It returns the first server
+// error, or nil once interrupted, after draining the requests in flight and
+// writing the summary of the requests.
+func serve(cfg *config.TestServerConfig, recordingDir string, redactor *redact.Redact, passthrough func(host string) bool, ready func(), setup func(*ReplayHTTPServer, *config.EndpointConfig)) error {
+	var servers []func() error
+	for _, endpoint := range cfg.Endpoints {
+		server, err := NewReplayHTTPServer(&endpoint, recordingDir, redactor)
+		if err != nil {
+			return fmt.Errorf("endpoint %s: %w", endpoint.TargetHost, err)
+		}
+		if setup != nil {
+			setup(server, &endpoint)
+		}
+		handler, err := server.Handler()
+		if err != nil {
+			return fmt.Errorf("endpoint %s: %w", endpoint.TargetHost, err)
+		}
+		servers = append(servers, func() error {
+			if err := server.Serve(handler); err != nil {
+				return fmt.Errorf("replay error for %s:%d: %w", endpoint.TargetHost, endpoint.TargetPort, err)
+			}
+			return nil
+		})
+	}
+
+	interrupted := make(chan os.Signal, 1)
+	signal.Notify(interrupted, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(interrupted)
+	if ready != nil {
+		ready()
+	}
+
+	// Start a server for each endpoint
+	errChan := make(chan error, len(cfg.Endpoints)+1)
+
+	for _, serve := range servers {
+		go func() {
+			if err := serve(); err != nil {
+				errChan <- err
+			}
+		}()
+	}
+
+	if cfg.Proxy != nil {
+		go func() {
+			if err := proxy.Serve(cfg, passthrough); err != nil {
+				errChan <- fmt.Errorf("forward proxy error: %w", err)
 			}
-		}(endpoint)
+		}()
 	}
 
-	// Return the first error encountered, if any
+	// Return the first error encountered, if any, blocking until then (or
+	// until interrupted).
 	select {
 	case err := <-errChan:
 		return err
-	default:
-		// Block forever (or until interrupted)
-		select {}
+	case <-interrupted:
+		record.Shutdown(os.Stdout, interrupted)
+		return nil
 	}
 }
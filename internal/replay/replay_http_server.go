@@ -19,49 +19,155 @@ package replay
 import (
 	"bufio"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 	"unicode"
 
+	"github.com/google/test-server/internal/codec"
 	"github.com/google/test-server/internal/config"
+	"github.com/google/test-server/internal/listen"
+	"github.com/google/test-server/internal/match"
 	"github.com/google/test-server/internal/redact"
+	"github.com/google/test-server/internal/route"
 	"github.com/google/test-server/internal/store"
+	"github.com/google/test-server/internal/watch"
 	"github.com/gorilla/websocket"
 )
 
+// reloadInterval is how often the recordings are checked for changes.
+const reloadInterval = time.Second
+
+// errNoRecording reports that a request has no recorded interaction.
+var errNoRecording = errors.New("no recorded interaction")
+
+// missing reports whether err means that there is nothing recorded for a
+// request, as opposed to a broken recording.
+func missing(err error) bool {
+	return errors.Is(err, errNoRecording) || errors.Is(err, fs.ErrNotExist)
+}
+
+// UnmatchedHeader is set on the 501 Not Implemented response to a request
+// without a recording in strict mode, to tell it apart from a recorded 501.
+const UnmatchedHeader = "X-Test-Server-Unmatched"
+
+// Fallback serves the requests that have no recording, e.g. by recording
+// them from the target in auto mode.
+type Fallback interface {
+	// Forward answers req, whose redacted and chained form is recReq, and
+	// returns the SHA-256 of recReq.
+	Forward(w http.ResponseWriter, req *http.Request, recReq *store.RecordedRequest, fileName string) (string, error)
+	// ForwardWebsocket answers a websocket upgrade request.
+	ForwardWebsocket(w http.ResponseWriter, req *http.Request, fileName string)
+}
+
 type ReplayHTTPServer struct {
+	// chainMu guards prevRequestSHA and seenFiles, which chain the requests
+	// of a recording file.
+	chainMu        sync.Mutex
 	prevRequestSHA string
 	seenFiles      map[string]struct{}
 	config         *config.EndpointConfig
 	recordingDir   string
 	redactor       *redact.Redact
+	// matcher is nil unless the endpoint configures match_on.
+	matcher *match.Matcher
+	// replayed holds, per recording file, the interactions already served
+	// through the matcher.
+	replayed   map[string]map[int]bool
+	replayedMu sync.Mutex
+	// recordings caches the recording files the matcher reads, by path, until
+	// they change on disk.
+	recordings   map[string]cachedRecording
+	recordingsMu sync.Mutex
+	// fallback is nil unless requests without a recording are forwarded.
+	fallback Fallback
+	// unmatched counts the requests without a recording in strict mode, and
+	// is nil otherwise.
+	unmatched *atomic.Int64
 }
 
-func NewReplayHTTPServer(cfg *config.EndpointConfig, recordingDir string, redactor *redact.Redact) *ReplayHTTPServer {
+func NewReplayHTTPServer(cfg *config.EndpointConfig, recordingDir string, redactor *redact.Redact) (*ReplayHTTPServer, error) {
+	matcher, err := match.New(cfg)
+	if err != nil {
+		return nil, err
+	}
 	return &ReplayHTTPServer{
 		prevRequestSHA: store.HeadSHA,
 		seenFiles:      make(map[string]struct{}),
 		config:         cfg,
 		recordingDir:   recordingDir,
 		redactor:       redactor,
-	}
+		matcher:        matcher,
+		replayed:       make(map[string]map[int]bool),
+		recordings:     make(map[string]cachedRecording),
+	}, nil
+}
+
+// cachedRecording is a recording file, or the error of reading it, with the
+// modification time and size it was read at.
+type cachedRecording struct {
+	modTime time.Time
+	size    int64
+	file    *store.RecordFile
+	err     error
+}
+
+// SetFallback makes the server hand the requests it has no recording for to
+// fallback instead of failing them.
+func (r *ReplayHTTPServer) SetFallback(fallback Fallback) {
+	r.fallback = fallback
+}
+
+// SetStrict makes the server answer the requests it has no recording for
+// with 501 Not Implemented and the diff from the closest recorded request,
+// and count them in unmatched.
+func (r *ReplayHTTPServer) SetStrict(unmatched *atomic.Int64) {
+	r.unmatched = unmatched
 }
 
-func (r *ReplayHTTPServer) Start() error {
-	addr := fmt.Sprintf(":%d", r.config.SourcePort)
-	server := &http.Server{
-		Addr:    addr,
-		Handler: http.HandlerFunc(r.handleRequest),
+// Handler returns the handler of the endpoint: the recordings behind its
+// routes.
+func (r *ReplayHTTPServer) Handler() (http.Handler, error) {
+	return route.Handler(r.config, r.redactor, http.HandlerFunc(r.handleRequest))
+}
+
+// Serve serves handler, the one of Handler, on the listeners of the endpoint,
+// reloading the recordings as they change.
+func (r *ReplayHTTPServer) Serve(handler http.Handler) error {
+	if r.fallback == nil {
+		// With a fallback, the server writes the recordings itself.
+		watch.Dir(r.recordingDir, reloadInterval, nil, r.reloadRecordings)
 	}
-	if err := server.ListenAndServe(); err != nil {
-		panic(err)
+	return listen.Serve(r.config, handler)
+}
+
+// reloadRecordings makes the recordings that changed on disk replay from
+// their first interaction again.
+func (r *ReplayHTTPServer) reloadRecordings(paths []string) {
+	files := make([]string, len(paths))
+	r.replayedMu.Lock()
+	for i, path := range paths {
+		delete(r.replayed, path)
+		rel, _ := filepath.Rel(r.recordingDir, path)
+		files[i] = filepath.ToSlash(rel)
 	}
-	return nil
+	r.replayedMu.Unlock()
+	route.PublishEvent(route.AdminEvent{
+		Type:     "recordings_reloaded",
+		Endpoint: r.config.TargetHost,
+		Message:  fmt.Sprintf("Reloaded the recordings of %s: %s", r.config.TargetHost, strings.Join(files, ", ")),
+		Files:    files,
+	})
 }
 
 func (r *ReplayHTTPServer) handleRequest(w http.ResponseWriter, req *http.Request) {
@@ -83,14 +189,30 @@ func (r *ReplayHTTPServer) handleRequest(w http.ResponseWriter, req *http.Reques
 		http.Error(w, fmt.Sprintf("Invalid recording file name: %v", err), http.StatusInternalServerError)
 		return
 	}
+	if session := route.SessionOf(req); session != "" {
+		// Every session records to a directory of its own.
+		fileName = filepath.Join(route.SessionsDir, session, fileName)
+	}
+	r.chainMu.Lock()
 	if _, ok := r.seenFiles[fileName]; !ok {
 		// Reset to HeadSHA when first time seen request from the given file.
 		redactedReq.PreviousRequest = store.HeadSHA
 	}
+	r.chainMu.Unlock()
 	if req.Header.Get("Upgrade") == "websocket" {
 		fmt.Printf("Upgrading connection to websocket...\n")
 
 		chunks, err := r.loadWebsocketChunks(fileName)
+		if err != nil && r.fallback != nil && missing(err) {
+			fmt.Printf("No recorded websocket for %s, forwarding it to the target\n", fileName)
+			r.fallback.ForwardWebsocket(w, req, fileName)
+			return
+		}
+		if err != nil && r.unmatched != nil && missing(err) {
+			r.unmatched.Add(1)
+			r.writeUnmatched(w, fileName, redactedReq, err, false)
+			return
+		}
 		if err != nil {
 			fmt.Printf("Error loading websocket response: %v\n", err)
 			http.Error(w, fmt.Sprintf("Error loading websocket response: %v", err), http.StatusInternalServerError)
@@ -102,7 +224,28 @@ func (r *ReplayHTTPServer) handleRequest(w http.ResponseWriter, req *http.Reques
 	}
 	fmt.Printf("Replaying http request: %s\n", redactedReq.Request)
 	shaSum := redactedReq.ComputeSum()
-	resp, err := r.loadResponse(fileName, shaSum)
+	var resp *store.RecordedResponse
+	if r.matcher != nil {
+		resp, err = r.matchResponse(fileName, redactedReq)
+	} else {
+		resp, err = r.loadResponse(fileName, shaSum)
+	}
+	if err != nil && r.fallback != nil && missing(err) {
+		fmt.Printf("No recording for %s, forwarding it to the target\n", redactedReq.Request)
+		shaSum, err = r.fallback.Forward(w, req, redactedReq, fileName)
+		if err != nil {
+			fmt.Printf("%v\n", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		r.chain(fileName, shaSum)
+		return
+	}
+	if err != nil && r.unmatched != nil && missing(err) {
+		r.unmatched.Add(1)
+		r.writeUnmatched(w, fileName, redactedReq, err, true)
+		return
+	}
 	if err != nil {
 		fmt.Printf("Error loading response: %v\n", err)
 		http.Error(w, fmt.Sprintf("Error loading response: %v", err), http.StatusInternalServerError)
@@ -114,6 +257,14 @@ func (r *ReplayHTTPServer) handleRequest(w http.ResponseWriter, req *http.Reques
 		fmt.Printf("Error writing response: %v\n", err)
 		panic(err)
 	}
+	r.chain(fileName, shaSum)
+}
+
+// chain makes shaSum, the SHA-256 of a request answered from fileName, the
+// previous request of the next one.
+func (r *ReplayHTTPServer) chain(fileName, shaSum string) {
+	r.chainMu.Lock()
+	defer r.chainMu.Unlock()
 	if fileName != shaSum {
 		r.prevRequestSHA = shaSum
 	}
@@ -121,7 +272,10 @@ func (r *ReplayHTTPServer) handleRequest(w http.ResponseWriter, req *http.Reques
 }
 
 func (r *ReplayHTTPServer) createRedactedRequest(req *http.Request) (*store.RecordedRequest, error) {
-	recordedRequest, err := store.NewRecordedRequest(req, r.prevRequestSHA, *r.config)
+	r.chainMu.Lock()
+	prev := r.prevRequestSHA
+	r.chainMu.Unlock()
+	recordedRequest, err := store.NewRecordedRequest(req, prev, *r.config)
 	if err != nil {
 		return nil, err
 	}
@@ -144,6 +298,21 @@ func (r *ReplayHTTPServer) loadResponse(fileName string, shaSum string) (*store.
 	// Open the replay log file for reading.
 	filePath := filepath.Join(r.recordingDir, fileName+".json")
 	fmt.Printf("loading response from : %s with shaSum: %s\n", filePath, shaSum)
+	recordFile, err := readRecordFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, interaction := range recordFile.Interactions {
+		if interaction.SHASum == shaSum {
+			return interaction.Response, nil
+		}
+	}
+
+	return nil, fmt.Errorf("response with shaSum %s not found in file: %w", shaSum, errNoRecording)
+}
+
+func readRecordFile(filePath string) (*store.RecordFile, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("could not open file %s: %w", filePath, err)
@@ -160,26 +329,227 @@ func (r *ReplayHTTPServer) loadResponse(fileName string, shaSum string) (*store.
 	if err != nil {
 		return nil, fmt.Errorf("unable to deserialize data to RecordFile: %w", err)
 	}
+	return &recordFile, nil
+}
 
-	for _, interaction := range recordFile.Interactions {
-		if interaction.SHASum == shaSum {
-			return interaction.Response, nil
+// loadRecording returns the recording file at path, read again only once it
+// has changed on disk.
+func (r *ReplayHTTPServer) loadRecording(path string) (*store.RecordFile, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open file %s: %w", path, err)
+	}
+	r.recordingsMu.Lock()
+	cached, ok := r.recordings[path]
+	r.recordingsMu.Unlock()
+	if ok && cached.modTime.Equal(info.ModTime()) && cached.size == info.Size() {
+		return cached.file, cached.err
+	}
+	file, err := readRecordFile(path)
+	r.recordingsMu.Lock()
+	r.recordings[path] = cachedRecording{modTime: info.ModTime(), size: info.Size(), file: file, err: err}
+	r.recordingsMu.Unlock()
+	if err != nil && !missing(err) {
+		fmt.Printf("%s is not a recording: %v\n", path, err)
+	}
+	return file, err
+}
+
+// recording is a recording file a request may be answered from.
+type recording struct {
+	path string
+	file *store.RecordFile
+}
+
+// candidates returns the recordings a request may be answered from: the file
+// of its test when it has a Test-Name header, otherwise every recording of its
+// session, or of the endpoint outside of the sessions. The .json files of the
+// directory that are not recordings are skipped.
+func (r *ReplayHTTPServer) candidates(fileName string, req *store.RecordedRequest) ([]recording, error) {
+	if req.Headers["Test-Name"] != "" {
+		path := filepath.Join(r.recordingDir, fileName+".json")
+		file, err := r.loadRecording(path)
+		if err != nil {
+			return nil, err
 		}
+		return []recording{{path, file}}, nil
 	}
+	files, err := r.candidateFiles(fileName)
+	if err != nil {
+		return nil, err
+	}
+	var recordings []recording
+	for _, path := range files {
+		file, err := r.loadRecording(path)
+		if err != nil {
+			continue
+		}
+		recordings = append(recordings, recording{path, file})
+	}
+	return recordings, nil
+}
+
+// candidateFiles returns the .json files of the directory of fileName and its
+// subdirectories, apart from the directories of the sessions when it is the
+// recording directory.
+func (r *ReplayHTTPServer) candidateFiles(fileName string) ([]string, error) {
+	var files []string
+	sessions := filepath.Join(r.recordingDir, route.SessionsDir)
+	err := filepath.WalkDir(filepath.Join(r.recordingDir, filepath.Dir(fileName)), func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && path == sessions {
+			return filepath.SkipDir
+		}
+		if !d.IsDir() && strings.HasSuffix(path, ".json") {
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files, err
+}
+
+// matchResponse returns the response of the first recorded interaction that
+// the matcher accepts and that has not been replayed yet, so a test that
+// repeats a request gets the recorded responses in order. Once every match
+// has been replayed, the last one is served again.
+func (r *ReplayHTTPServer) matchResponse(fileName string, req *store.RecordedRequest) (*store.RecordedResponse, error) {
+	recordings, err := r.candidates(fileName, req)
+	if err != nil {
+		return nil, err
+	}
+	r.replayedMu.Lock()
+	defer r.replayedMu.Unlock()
+	var last *store.RecordedResponse
+	for _, rec := range recordings {
+		path, recordFile := rec.path, rec.file
+		if r.replayed[path] == nil {
+			r.replayed[path] = make(map[int]bool)
+		}
+		for i, interaction := range recordFile.Interactions {
+			if interaction.Request == nil || !r.matcher.Match(interaction.Request, req) {
+				continue
+			}
+			if !r.replayed[path][i] {
+				r.replayed[path][i] = true
+				return interaction.Response, nil
+			}
+			last = interaction.Response
+		}
+	}
+	if last != nil {
+		return last, nil
+	}
+	return nil, fmt.Errorf("%w matches %s on %v", errNoRecording, req.Request, r.config.MatchOn)
+}
+
+// writeUnmatched answers a request without a recording in strict mode. With
+// compare, the answer shows how the request differs from the closest recorded
+// one.
+func (r *ReplayHTTPServer) writeUnmatched(w http.ResponseWriter, fileName string, req *store.RecordedRequest, cause error, compare bool) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "test-server: no recorded interaction for %s\n%v\n", req.Request, cause)
+	if compare {
+		path, index, diff := r.closest(fileName, req)
+		if diff == nil {
+			b.WriteString("There is no recorded request to compare it with.\n")
+		} else {
+			fmt.Fprintf(&b, "Closest recorded request, interaction %d of %s:\n--- recorded\n+++ request\n%s\n", index, path, strings.Join(diff, "\n"))
+		}
+	}
+	fmt.Print(b.String())
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set(UnmatchedHeader, "true")
+	w.WriteHeader(http.StatusNotImplemented)
+	io.WriteString(w, b.String())
+}
 
-	return nil, fmt.Errorf("response with shaSum %s not found in file", shaSum)
+// closest returns the recorded request, among those req could have matched,
+// that differs from req in the fewest lines, with the diff from it to req.
+// The diff is nil if there is no recorded request.
+func (r *ReplayHTTPServer) closest(fileName string, req *store.RecordedRequest) (string, int, []string) {
+	recordings, err := r.candidates(fileName, req)
+	if err != nil {
+		return "", 0, nil
+	}
+	var (
+		bestPath  string
+		bestIndex int
+		bestDiff  []string
+	)
+	for _, rec := range recordings {
+		for i, interaction := range rec.file.Interactions {
+			if interaction.Request == nil {
+				continue
+			}
+			diff := match.Diff(interaction.Request, req)
+			if bestDiff == nil || match.Changes(diff) < match.Changes(bestDiff) {
+				bestPath, bestIndex, bestDiff = rec.path, i, diff
+			}
+		}
+	}
+	return bestPath, bestIndex, bestDiff
 }
 
 func (r *ReplayHTTPServer) writeResponse(w http.ResponseWriter, resp *store.RecordedResponse, req *store.RecordedRequest) error {
+	// A raw body is one segment.
+	delays := make([]time.Duration, max(len(resp.BodySegments), 1))
+	for i, value := range resp.SegmentDelays {
+		d, err := time.ParseDuration(value)
+		if err != nil || d < 0 {
+			http.Error(w, fmt.Sprintf("Invalid segment delay %q in the recording", value), http.StatusInternalServerError)
+			return nil
+		}
+		if i < len(delays) {
+			delays[i] = d
+		}
+	}
+	flusher, _ := w.(http.Flusher)
+
+	raw := resp.Body
+	if resp.BodyFile != "" {
+		var err error
+		if raw, err = os.ReadFile(filepath.Join(r.recordingDir, resp.BodyFile)); err != nil {
+			http.Error(w, fmt.Sprintf("Error reading the body file of the recording: %v", err), http.StatusInternalServerError)
+			return nil
+		}
+	}
+
+	// The recorded coding is kept if the client accepts it, except for empty
+	// bodies and streams, which are sent as they come.
+	coding := resp.Headers["Content-Encoding"]
+	if raw != nil {
+		if !codec.Supported(coding) || !codec.Accepts(req.Headers["Accept-Encoding"], coding) {
+			coding = ""
+		}
+	} else if len(resp.BodySegments) == 0 || !codec.Supported(coding) || !codec.Accepts(req.Headers["Accept-Encoding"], coding) || strings.Contains(req.URL, "alt=sse") {
+		coding = ""
+	}
 	for key, value := range resp.Headers {
 		if key == "Content-Length" || key == "Content-Encoding" {
 			continue
 		}
 		w.Header().Add(key, value)
 	}
+	if coding != "" {
+		w.Header().Set("Content-Encoding", coding)
+	}
 
 	w.WriteHeader(int(resp.StatusCode))
 
+	if raw != nil {
+		if coding != "" {
+			var err error
+			if raw, err = codec.Encode(coding, raw); err != nil {
+				return err
+			}
+		}
+		time.Sleep(delays[0])
+		_, err := w.Write(raw)
+		return err
+	}
+
 	// When the response body is empty we return directly with the headers.
 	if len(resp.BodySegments) == 0 {
 		return nil
@@ -191,21 +561,57 @@ func (r *ReplayHTTPServer) writeResponse(w http.ResponseWriter, resp *store.Reco
 			return err
 		}
 
+		if coding != "" {
+			if jsonBytes, err = codec.Encode(coding, jsonBytes); err != nil {
+				return err
+			}
+		}
+		time.Sleep(delays[0])
 		_, err = w.Write(jsonBytes)
 		return err
 	} else {
-		for _, bodySegment := range resp.BodySegments {
+		// A reconnecting client gets the events after its Last-Event-ID.
+		start := 0
+		if last := req.Headers["Last-Event-Id"]; last != "" {
+			for i, e := range resp.Events {
+				if e.ID == last {
+					start = i + 1
+				}
+			}
+		}
+		for i, bodySegment := range resp.BodySegments {
+			if i < start {
+				continue
+			}
+			time.Sleep(delays[i])
 			jsonBytes, err := json.Marshal(bodySegment)
 			if err != nil {
 				return err
 			}
 
-			line := append([]byte("data: "), jsonBytes...)
+			var line []byte
+			if i < len(resp.Events) {
+				e := resp.Events[i]
+				if e.ID != "" {
+					line = fmt.Appendf(line, "id: %s\n", e.ID)
+				}
+				if e.Event != "" {
+					line = fmt.Appendf(line, "event: %s\n", e.Event)
+				}
+				if e.Retry != 0 {
+					line = fmt.Appendf(line, "retry: %d\n", e.Retry)
+				}
+			}
+			line = append(line, []byte("data: ")...)
+			line = append(line, jsonBytes...)
 			line = append(line, []byte("\n\n")...)
 
 			if _, err := w.Write(line); err != nil {
 				return err
 			}
+			if flusher != nil {
+				flusher.Flush()
+			}
 		}
 	}
 
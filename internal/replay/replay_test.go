@@ -0,0 +1,427 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package replay
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/test-server/internal/codec"
+	"github.com/google/test-server/internal/config"
+	"github.com/google/test-server/internal/record"
+	"github.com/google/test-server/internal/redact"
+	"github.com/google/test-server/internal/route"
+	"github.com/google/test-server/internal/store"
+	"github.com/stretchr/testify/require"
+)
+
+// writeRecording stores interactions answering POST /v1/generate with the
+// given replies, in order.
+func writeRecording(t *testing.T, dir, name string, replies ...string) {
+	t.Helper()
+	file := store.RecordFile{RecordID: name}
+	for _, reply := range replies {
+		file.Interactions = append(file.Interactions, &store.RecordInteraction{
+			Request: &store.RecordedRequest{
+				Method:       "POST",
+				URL:          "/v1/generate",
+				Headers:      map[string]string{"User-Agent": "node", "Test-Name": name},
+				BodySegments: []map[string]any{{"prompt": "hi"}},
+			},
+			SHASum:   "recorded-with-another-sdk",
+			Response: &store.RecordedResponse{StatusCode: 200, BodySegments: []map[string]any{{"reply": reply}}},
+		})
+	}
+	data, err := json.Marshal(file)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name+".json"), data, 0644))
+}
+
+func send(t *testing.T, server *ReplayHTTPServer, testName string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/v1/generate", strings.NewReader(`{"prompt":"hi"}`))
+	req.Header.Set("User-Agent", "python")
+	if testName != "" {
+		req.Header.Set("Test-Name", testName)
+	}
+	rec := httptest.NewRecorder()
+	server.handleRequest(rec, req)
+	return rec
+}
+
+func TestReplayWithMatchers(t *testing.T) {
+	dir := t.TempDir()
+	writeRecording(t, dir, "generate", "first", "second")
+	redactor, err := redact.NewRedact(nil)
+	require.NoError(t, err)
+
+	cfg := &config.EndpointConfig{TargetHost: "example.com", MatchOn: []string{"method", "path", "body"}}
+	server, err := NewReplayHTTPServer(cfg, dir, redactor)
+	require.NoError(t, err)
+	for _, want := range []string{"first", "second", "second"} {
+		rec := send(t, server, "generate")
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.JSONEq(t, `{"reply":"`+want+`"}`, rec.Body.String())
+	}
+
+	// Without a Test-Name header every recording is searched.
+	server, err = NewReplayHTTPServer(cfg, dir, redactor)
+	require.NoError(t, err)
+	rec := send(t, server, "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.JSONEq(t, `{"reply":"first"}`, rec.Body.String())
+
+	// Matching on the whole request fails on the other User-Agent.
+	server, err = NewReplayHTTPServer(&config.EndpointConfig{TargetHost: "example.com"}, dir, redactor)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusInternalServerError, send(t, server, "generate").Code)
+}
+
+func TestReplaySearchesOnlyRecordings(t *testing.T) {
+	dir := t.TempDir()
+	writeRecording(t, dir, "generate", "first")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "fixtures.json"), []byte(`[{"prompt": "hi"}]`), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "broken.json"), []byte(`{`), 0644))
+	redactor, err := redact.NewRedact(nil)
+	require.NoError(t, err)
+	server, err := NewReplayHTTPServer(&config.EndpointConfig{TargetHost: "example.com", MatchOn: []string{"method", "path", "body"}}, dir, redactor)
+	require.NoError(t, err)
+
+	// The other .json files of the directory are not recordings.
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rec := send(t, server, "")
+			require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+			require.JSONEq(t, `{"reply":"first"}`, rec.Body.String())
+		}()
+	}
+	wg.Wait()
+
+	// The recordings are read once, and again once they change.
+	path := filepath.Join(dir, "generate.json")
+	cached := server.recordings[path].file
+	send(t, server, "")
+	require.Same(t, cached, server.recordings[path].file)
+	writeRecording(t, dir, "generate", "changed")
+	require.JSONEq(t, `{"reply":"changed"}`, send(t, server, "").Body.String())
+}
+
+func TestReplayPacesSegments(t *testing.T) {
+	dir := t.TempDir()
+	file := store.RecordFile{RecordID: "stream", Interactions: []*store.RecordInteraction{{
+		Request: &store.RecordedRequest{Method: "POST", URL: "/v1/generate?alt=sse"},
+		Response: &store.RecordedResponse{
+			StatusCode:    200,
+			BodySegments:  []map[string]any{{"text": "a"}, {"text": "b"}},
+			SegmentDelays: []string{"0s", "50ms"},
+		},
+	}}}
+	data, err := json.Marshal(file)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "stream.json"), data, 0644))
+	redactor, err := redact.NewRedact(nil)
+	require.NoError(t, err)
+	server, err := NewReplayHTTPServer(&config.EndpointConfig{TargetHost: "example.com", MatchOn: []string{"method", "path"}}, dir, redactor)
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/generate?alt=sse", strings.NewReader(`{"prompt":"hi"}`))
+	req.Header.Set("Test-Name", "stream")
+	rec := httptest.NewRecorder()
+	start := time.Now()
+	server.handleRequest(rec, req)
+	require.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.True(t, rec.Flushed)
+	require.Equal(t, "data: {\"text\":\"a\"}\n\ndata: {\"text\":\"b\"}\n\n", rec.Body.String())
+}
+
+func TestReplayResumesEvents(t *testing.T) {
+	dir := t.TempDir()
+	file := store.RecordFile{RecordID: "stream", Interactions: []*store.RecordInteraction{{
+		Request: &store.RecordedRequest{Method: "GET", URL: "/v1/watch?alt=sse"},
+		Response: &store.RecordedResponse{
+			StatusCode:   200,
+			BodySegments: []map[string]any{{"n": 1.0}, {"n": 2.0}},
+			Events:       []store.Event{{ID: "1", Event: "delta", Retry: 500}, {ID: "2"}},
+		},
+	}}}
+	data, err := json.Marshal(file)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "stream.json"), data, 0644))
+	redactor, err := redact.NewRedact(nil)
+	require.NoError(t, err)
+	server, err := NewReplayHTTPServer(&config.EndpointConfig{TargetHost: "example.com", MatchOn: []string{"method", "path"}}, dir, redactor)
+	require.NoError(t, err)
+
+	for _, tc := range []struct{ last, want string }{
+		{"", "id: 1\nevent: delta\nretry: 500\ndata: {\"n\":1}\n\nid: 2\ndata: {\"n\":2}\n\n"},
+		{"1", "id: 2\ndata: {\"n\":2}\n\n"},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/v1/watch?alt=sse", nil)
+		req.Header.Set("Test-Name", "stream")
+		if tc.last != "" {
+			req.Header.Set("Last-Event-ID", tc.last)
+		}
+		rec := httptest.NewRecorder()
+		server.handleRequest(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, tc.want, rec.Body.String())
+	}
+}
+
+func TestReplayKeepsContentEncoding(t *testing.T) {
+	dir := t.TempDir()
+	file := store.RecordFile{RecordID: "gzip", Interactions: []*store.RecordInteraction{{
+		Request: &store.RecordedRequest{Method: "GET", URL: "/v1/models"},
+		Response: &store.RecordedResponse{
+			StatusCode:   200,
+			Headers:      map[string]string{"Content-Encoding": "gzip", "Content-Type": "application/json"},
+			BodySegments: []map[string]any{{"name": "gemini"}},
+		},
+	}}}
+	data, err := json.Marshal(file)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "gzip.json"), data, 0644))
+	redactor, err := redact.NewRedact(nil)
+	require.NoError(t, err)
+	server, err := NewReplayHTTPServer(&config.EndpointConfig{TargetHost: "example.com", MatchOn: []string{"method", "path"}}, dir, redactor)
+	require.NoError(t, err)
+
+	for _, accept := range []string{"gzip, deflate", "br", ""} {
+		req := httptest.NewRequest(http.MethodGet, "/v1/models", nil)
+		req.Header.Set("Test-Name", "gzip")
+		if accept != "" {
+			req.Header.Set("Accept-Encoding", accept)
+		}
+		rec := httptest.NewRecorder()
+		server.handleRequest(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+		body := rec.Body.Bytes()
+		if accept == "gzip, deflate" {
+			require.Equal(t, "gzip", rec.Header().Get("Content-Encoding"))
+			body, err = codec.Decode("gzip", body)
+			require.NoError(t, err)
+		} else {
+			require.Empty(t, rec.Header().Get("Content-Encoding"), accept)
+		}
+		require.JSONEq(t, `{"name": "gemini"}`, string(body))
+	}
+}
+
+func TestReplayBinaryBodies(t *testing.T) {
+	dir := t.TempDir()
+	image := []byte("\x89PNG\r\n\x1a\n\x00\xff")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "image.png"), image, 0644))
+	file := store.RecordFile{RecordID: "binary", Interactions: []*store.RecordInteraction{
+		{
+			Request:  &store.RecordedRequest{Method: "GET", URL: "/v1/blob"},
+			Response: &store.RecordedResponse{StatusCode: 200, Headers: map[string]string{"Content-Type": "application/x-protobuf"}, Body: []byte{0x08, 0x96, 0x01, 0x00}},
+		},
+		{
+			Request:  &store.RecordedRequest{Method: "GET", URL: "/v1/image"},
+			Response: &store.RecordedResponse{StatusCode: 200, Headers: map[string]string{"Content-Type": "image/png"}, BodyFile: "image.png"},
+		},
+	}}
+	data, err := json.Marshal(file)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "binary.json"), data, 0644))
+	redactor, err := redact.NewRedact(nil)
+	require.NoError(t, err)
+	server, err := NewReplayHTTPServer(&config.EndpointConfig{TargetHost: "example.com", MatchOn: []string{"method", "path"}}, dir, redactor)
+	require.NoError(t, err)
+
+	for path, want := range map[string][]byte{"/v1/blob": {0x08, 0x96, 0x01, 0x00}, "/v1/image": image} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Test-Name", "binary")
+		rec := httptest.NewRecorder()
+		server.handleRequest(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code, path)
+		require.Equal(t, want, rec.Body.Bytes(), path)
+	}
+}
+
+func TestReplayReloadsRecordings(t *testing.T) {
+	dir := t.TempDir()
+	writeRecording(t, dir, "generate", "first", "second")
+	redactor, err := redact.NewRedact(nil)
+	require.NoError(t, err)
+	server, err := NewReplayHTTPServer(&config.EndpointConfig{TargetHost: "example.com", MatchOn: []string{"method", "path", "body"}}, dir, redactor)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"reply":"first"}`, send(t, server, "generate").Body.String())
+
+	// A changed recording replays from its start.
+	writeRecording(t, dir, "generate", "edited", "second")
+	server.reloadRecordings([]string{filepath.Join(dir, "generate.json")})
+	require.JSONEq(t, `{"reply":"edited"}`, send(t, server, "generate").Body.String())
+	require.JSONEq(t, `{"reply":"second"}`, send(t, server, "generate").Body.String())
+}
+
+func TestReplaySessionRecordings(t *testing.T) {
+	dir := t.TempDir()
+	writeRecording(t, dir, "generate", "shared")
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, route.SessionsDir, "a"), 0755))
+	writeRecording(t, filepath.Join(dir, route.SessionsDir, "a"), "generate", "of a")
+	redactor, err := redact.NewRedact(nil)
+	require.NoError(t, err)
+	cfg := &config.EndpointConfig{TargetHost: "example.com", MatchOn: []string{"method", "path", "body"}}
+	server, err := NewReplayHTTPServer(cfg, dir, redactor)
+	require.NoError(t, err)
+	handler, err := route.Handler(cfg, nil, http.HandlerFunc(server.handleRequest))
+	require.NoError(t, err)
+	call := func(method, target, body string, headers ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		for i := 0; i < len(headers); i += 2 {
+			req.Header.Set(headers[i], headers[i+1])
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+	require.Equal(t, http.StatusCreated, call("POST", route.SessionsPath, `{"id": "a"}`).Code)
+	t.Cleanup(func() { call("DELETE", route.SessionsPath, "") })
+
+	// A session replays the recordings in its directory.
+	for _, testName := range []string{"generate", ""} {
+		rec := call("POST", "/v1/generate", `{"prompt":"hi"}`, route.SessionHeader, "a", "Test-Name", testName)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		require.JSONEq(t, `{"reply":"of a"}`, rec.Body.String())
+	}
+	// The requests outside of the sessions are not replayed from them.
+	for _, testName := range []string{"generate", ""} {
+		rec := call("POST", "/v1/generate", `{"prompt":"hi"}`, "Test-Name", testName)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		require.JSONEq(t, `{"reply":"shared"}`, rec.Body.String())
+	}
+}
+
+func TestReplayRejectsUnknownMatcher(t *testing.T) {
+	_, err := NewReplayHTTPServer(&config.EndpointConfig{MatchOn: []string{"uri"}}, t.TempDir(), nil)
+	require.Error(t, err)
+}
+
+// upstreamEndpoint returns the endpoint of a plain HTTP target served by
+// handler.
+func upstreamEndpoint(t *testing.T, handler http.HandlerFunc) *config.EndpointConfig {
+	t.Helper()
+	upstream := httptest.NewServer(handler)
+	t.Cleanup(upstream.Close)
+	host, port, err := net.SplitHostPort(strings.TrimPrefix(upstream.URL, "http://"))
+	require.NoError(t, err)
+	targetPort, err := strconv.ParseInt(port, 10, 64)
+	require.NoError(t, err)
+	return &config.EndpointConfig{TargetType: "http", TargetHost: host, TargetPort: targetPort}
+}
+
+func TestAutoRecordsOnlyWhatIsMissing(t *testing.T) {
+	calls := 0
+	cfg := upstreamEndpoint(t, func(w http.ResponseWriter, req *http.Request) {
+		calls++
+		w.Write([]byte(`{"reply":"live ` + strconv.Itoa(calls) + `"}`))
+	})
+
+	dir := t.TempDir()
+	writeRecording(t, dir, "other", "recorded")
+	redactor, err := redact.NewRedact(nil)
+	require.NoError(t, err)
+	auto := func() *ReplayHTTPServer {
+		server, err := NewReplayHTTPServer(cfg, dir, redactor)
+		require.NoError(t, err)
+		proxy := record.NewRecordingHTTPSProxy(cfg, dir, redactor)
+		proxy.SetAppend(true)
+		server.SetFallback(proxy)
+		return server
+	}
+
+	// The first run records both requests of the test.
+	server := auto()
+	require.JSONEq(t, `{"reply":"live 1"}`, send(t, server, "generate").Body.String())
+	require.JSONEq(t, `{"reply":"live 2"}`, send(t, server, "generate").Body.String())
+
+	// The next run replays them and records a new third one.
+	server = auto()
+	require.JSONEq(t, `{"reply":"live 1"}`, send(t, server, "generate").Body.String())
+	require.JSONEq(t, `{"reply":"live 2"}`, send(t, server, "generate").Body.String())
+	require.JSONEq(t, `{"reply":"live 3"}`, send(t, server, "generate").Body.String())
+	require.Equal(t, 3, calls)
+
+	data, err := os.ReadFile(filepath.Join(dir, "generate.json"))
+	require.NoError(t, err)
+	var file store.RecordFile
+	require.NoError(t, json.Unmarshal(data, &file))
+	require.Len(t, file.Interactions, 3)
+}
+
+func TestStrictReplay(t *testing.T) {
+	dir := t.TempDir()
+	writeRecording(t, dir, "generate", "first")
+	redactor, err := redact.NewRedact(nil)
+	require.NoError(t, err)
+
+	var unmatched atomic.Int64
+	server, err := NewReplayHTTPServer(&config.EndpointConfig{TargetHost: "example.com"}, dir, redactor)
+	require.NoError(t, err)
+	server.SetStrict(&unmatched)
+
+	rec := send(t, server, "generate")
+	require.Equal(t, http.StatusNotImplemented, rec.Code)
+	require.Equal(t, "true", rec.Header().Get(UnmatchedHeader))
+	body := rec.Body.String()
+	require.Contains(t, body, "no recorded interaction for POST /v1/generate")
+	require.Contains(t, body, "interaction 0 of "+filepath.Join(dir, "generate.json"))
+	require.Contains(t, body, `-    "User-Agent": "node"`)
+	require.Contains(t, body, `+    "User-Agent": "python"`)
+
+	rec = send(t, server, "missing")
+	require.Equal(t, http.StatusNotImplemented, rec.Code)
+	require.Contains(t, rec.Body.String(), "There is no recorded request to compare it with.")
+	require.Equal(t, int64(2), unmatched.Load())
+}
+
+func TestPassthroughWithoutRecording(t *testing.T) {
+	cfg := upstreamEndpoint(t, func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(`{"reply":"live"}`))
+	})
+	dir := t.TempDir()
+	redactor, err := redact.NewRedact(nil)
+	require.NoError(t, err)
+
+	server, err := NewReplayHTTPServer(cfg, dir, redactor)
+	require.NoError(t, err)
+	proxy := record.NewRecordingHTTPSProxy(cfg, dir, redactor)
+	proxy.SetForwardOnly(true)
+	server.SetFallback(proxy)
+
+	rec := send(t, server, "generate")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.JSONEq(t, `{"reply":"live"}`, rec.Body.String())
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+}
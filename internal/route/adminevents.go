@@ -0,0 +1,133 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package route
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AdminEventsPath is the path of the admin call that lists the events of the
+// server, such as reloads, or streams them as Server-Sent Events.
+const AdminEventsPath = "/__admin/events"
+
+// maxAdminEvents is how many events the server keeps.
+const maxAdminEvents = 1000
+
+// AdminEvent is something that happened to the server rather than a
+// request, e.g. a reload of its stub files.
+type AdminEvent struct {
+	Seq  int       `json:"seq"`
+	Time time.Time `json:"time"`
+	// Type is e.g. stubs_reloaded or recordings_reloaded.
+	Type     string   `json:"type"`
+	Endpoint string   `json:"endpoint,omitempty"`
+	Message  string   `json:"message"`
+	Files    []string `json:"files,omitempty"`
+	// Error is set if the event is a failure, e.g. a stub file that does
+	// not parse.
+	Error string `json:"error,omitempty"`
+}
+
+// adminEvents keeps the last events and the channels of the streams.
+var adminEvents struct {
+	mu          sync.Mutex
+	events      []AdminEvent
+	seq         int
+	subscribers map[chan AdminEvent]bool
+}
+
+// PublishEvent logs the message of e and adds e to the events of the
+// server.
+func PublishEvent(e AdminEvent) {
+	if e.Error != "" {
+		fmt.Printf("%s: %s\n", e.Message, e.Error)
+	} else {
+		fmt.Println(e.Message)
+	}
+	adminEvents.mu.Lock()
+	defer adminEvents.mu.Unlock()
+	adminEvents.seq++
+	e.Seq, e.Time = adminEvents.seq, time.Now()
+	adminEvents.events = append(adminEvents.events, e)
+	if n := len(adminEvents.events); n > maxAdminEvents {
+		adminEvents.events = adminEvents.events[n-maxAdminEvents:]
+	}
+	for ch := range adminEvents.subscribers {
+		select {
+		case ch <- e:
+		default:
+			// A stream that does not keep up misses events rather than
+			// holding up the server.
+		}
+	}
+}
+
+// serveAdminEvents answers GET /__admin/events with the events of the
+// server, or, if the request accepts text/event-stream, with a stream of the
+// events that happen from then on.
+func serveAdminEvents(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeError(w, http.StatusMethodNotAllowed, "INVALID_ARGUMENT", "The events are read with GET")
+		return
+	}
+	if !strings.Contains(req.Header.Get("Accept"), "text/event-stream") {
+		adminEvents.mu.Lock()
+		events := append([]AdminEvent{}, adminEvents.events...)
+		adminEvents.mu.Unlock()
+		writeJSON(w, http.StatusOK, map[string]any{"events": events})
+		return
+	}
+
+	ch := make(chan AdminEvent, 16)
+	adminEvents.mu.Lock()
+	if adminEvents.subscribers == nil {
+		adminEvents.subscribers = map[chan AdminEvent]bool{}
+	}
+	adminEvents.subscribers[ch] = true
+	adminEvents.mu.Unlock()
+	defer func() {
+		adminEvents.mu.Lock()
+		delete(adminEvents.subscribers, ch)
+		adminEvents.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	if flusher != nil {
+		flusher.Flush()
+	}
+	for {
+		select {
+		case <-req.Context().Done():
+			return
+		case e := <-ch:
+			data, _ := json.Marshal(e)
+			fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.Seq, e.Type, data)
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+	}
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package route
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/google/test-server/internal/config"
+)
+
+// TokenVerifier returns the claims of a token the endpoint issued, or an
+// error if it did not issue it or it has expired.
+type TokenVerifier func(token string) (map[string]any, error)
+
+func validateAuth(a *config.Auth) error {
+	if len(a.APIKeys) == 0 && len(a.BearerTokens) == 0 && !a.IssuedTokens {
+		return fmt.Errorf("set api_keys, bearer_tokens or issued_tokens")
+	}
+	if len(a.Scopes) > 0 && !a.IssuedTokens {
+		return fmt.Errorf("scopes need issued_tokens")
+	}
+	return nil
+}
+
+// authenticate checks the credentials of req against a and answers with an
+// error if they are missing or invalid. It reports whether req may go on.
+func (r *Router) authenticate(w http.ResponseWriter, req *http.Request, a *config.Auth) bool {
+	key := req.Header.Get("X-Goog-Api-Key")
+	if key == "" {
+		key = req.URL.Query().Get("key")
+	}
+	token, bearer := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+
+	switch {
+	case bearer:
+		if slices.Contains(a.BearerTokens, token) {
+			return true
+		}
+		if a.IssuedTokens && r.verify != nil {
+			if claims, err := r.verify(token); err == nil {
+				if granted(claims, a.Scopes) {
+					return true
+				}
+				w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm=%q, error="insufficient_scope", scope=%q`, r.service, strings.Join(a.Scopes, " ")))
+				Presets["scope_insufficient"].write(w, r.service)
+				return false
+			}
+		}
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm=%q, error="invalid_token"`, r.service))
+		Presets["unauthenticated"].write(w, r.service)
+	case key != "":
+		if slices.Contains(a.APIKeys, key) {
+			return true
+		}
+		Presets["api_key_invalid"].write(w, r.service)
+	default:
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf("Bearer realm=%q", r.service))
+		Presets["credentials_missing"].write(w, r.service)
+	}
+	return false
+}
+
+// granted reports whether the scope claim of a token includes every scope.
+func granted(claims map[string]any, scopes []string) bool {
+	claim, _ := claims["scope"].(string)
+	have := strings.Fields(claim)
+	for _, s := range scopes {
+		if !slices.Contains(have, s) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package route
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/test-server/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuth(t *testing.T) {
+	handler, err := Handler(&config.EndpointConfig{
+		TargetHost: "example.googleapis.com",
+		OAuth:      &config.OAuth{},
+		Routes: []config.Route{
+			{Path: "/v1/keys", Auth: &config.Auth{APIKeys: []string{"good-key"}, BearerTokens: []string{"good-token"}}},
+			{Path: "/v1/scoped", Auth: &config.Auth{IssuedTokens: true, Scopes: []string{"cloud-platform"}}},
+		},
+	}, nil, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	require.NoError(t, err)
+	send := func(req *http.Request) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+	request := func(target string, headers ...string) *http.Request {
+		req := httptest.NewRequest("GET", target, nil)
+		for i := 0; i < len(headers); i += 2 {
+			req.Header.Set(headers[i], headers[i+1])
+		}
+		return req
+	}
+	token := func(scope string) string {
+		form := url.Values{"grant_type": {"client_credentials"}, "scope": {scope}}
+		req := httptest.NewRequest("POST", "/token", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := send(req)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		var body struct {
+			AccessToken string `json:"access_token"`
+		}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+		return body.AccessToken
+	}
+
+	tests := []struct {
+		name       string
+		req        *http.Request
+		wantStatus int
+		wantReason string
+	}{
+		{"API key header", request("/v1/keys", "X-Goog-Api-Key", "good-key"), http.StatusOK, ""},
+		{"API key parameter", request("/v1/keys?key=good-key"), http.StatusOK, ""},
+		{"bearer token", request("/v1/keys", "Authorization", "Bearer good-token"), http.StatusOK, ""},
+		{"no credentials", request("/v1/keys"), http.StatusUnauthorized, "CREDENTIALS_MISSING"},
+		{"wrong API key", request("/v1/keys?key=bad-key"), http.StatusBadRequest, "API_KEY_INVALID"},
+		{"wrong bearer token", request("/v1/keys", "Authorization", "Bearer bad-token"), http.StatusUnauthorized, "ACCESS_TOKEN_EXPIRED"},
+		{"static token on issued route", request("/v1/scoped", "Authorization", "Bearer good-token"), http.StatusUnauthorized, "ACCESS_TOKEN_EXPIRED"},
+		{"issued token", request("/v1/scoped", "Authorization", "Bearer "+token("email cloud-platform")), http.StatusOK, ""},
+		{"issued token without scope", request("/v1/scoped", "Authorization", "Bearer "+token("email")), http.StatusForbidden, "ACCESS_TOKEN_SCOPE_INSUFFICIENT"},
+		{"unprotected route", request("/v1/other"), http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		rec := send(tt.req)
+		require.Equal(t, tt.wantStatus, rec.Code, tt.name)
+		if tt.wantReason != "" {
+			require.Contains(t, rec.Body.String(), `"reason":"`+tt.wantReason+`"`, tt.name)
+		}
+	}
+	rec := send(request("/v1/keys"))
+	require.Equal(t, `Bearer realm="example.googleapis.com"`, rec.Header().Get("WWW-Authenticate"))
+}
+
+func TestAuthNeedsOAuthForIssuedTokens(t *testing.T) {
+	err := Validate(&config.EndpointConfig{Routes: []config.Route{{Auth: &config.Auth{IssuedTokens: true}}}})
+	require.ErrorContains(t, err, "routes[0].auth: issued_tokens needs oauth")
+	err = Validate(&config.EndpointConfig{Routes: []config.Route{{Auth: &config.Auth{}}}})
+	require.ErrorContains(t, err, "routes[0].auth")
+	err = Validate(&config.EndpointConfig{Routes: []config.Route{{Auth: &config.Auth{APIKeys: []string{"k"}, Scopes: []string{"s"}}}}})
+	require.ErrorContains(t, err, "scopes need issued_tokens")
+}
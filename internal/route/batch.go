@@ -0,0 +1,129 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package route
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strings"
+
+	"github.com/google/test-server/internal/match"
+)
+
+// batchPart is a request of a batch.
+type batchPart struct {
+	contentID string
+	req       *http.Request
+}
+
+// readBatch returns the requests of req, a multipart/mixed batch request.
+func readBatch(req *http.Request, boundary string) ([]batchPart, error) {
+	r := multipart.NewReader(req.Body, boundary)
+	var parts []batchPart
+	for i := 0; ; i++ {
+		p, err := r.NextPart()
+		if err == io.EOF {
+			return parts, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if mediaType, _, _ := mime.ParseMediaType(p.Header.Get("Content-Type")); mediaType != "application/http" {
+			return nil, fmt.Errorf("part %d is %q, not application/http", i, mediaType)
+		}
+		data, err := io.ReadAll(p)
+		if err != nil {
+			return nil, err
+		}
+		inner, _, err := match.ReadPartRequest(data)
+		if err != nil {
+			return nil, fmt.Errorf("part %d: %w", i, err)
+		}
+		inner = inner.WithContext(req.Context())
+		inner.RequestURI = ""
+		inner.RemoteAddr = req.RemoteAddr
+		if inner.Host == "" {
+			inner.Host = req.Host
+		}
+		inner.URL.Host = ""
+		// The headers of the batch, like Authorization, apply to every
+		// request that does not set them.
+		for name, values := range req.Header {
+			switch name {
+			case "Content-Type", "Content-Length", "Content-Id", "Content-Transfer-Encoding":
+				continue
+			}
+			if _, ok := inner.Header[name]; !ok {
+				inner.Header[name] = values
+			}
+		}
+		parts = append(parts, batchPart{contentID: p.Header.Get("Content-ID"), req: inner})
+	}
+}
+
+// serveBatch answers the requests of the batch req with h. It reports false,
+// answering nothing, if req is not a multipart/mixed request.
+func serveBatch(w http.ResponseWriter, req *http.Request, h http.Handler) bool {
+	mediaType, params, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/mixed" || params["boundary"] == "" {
+		return false
+	}
+	parts, err := readBatch(req, params["boundary"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_ARGUMENT", fmt.Sprintf("Invalid batch request: %v", err))
+		return true
+	}
+	fmt.Printf("Serving the %d requests of the batch %s %s\n", len(parts), req.Method, req.URL.Path)
+
+	var b bytes.Buffer
+	mw := multipart.NewWriter(&b)
+	for _, part := range parts {
+		rec := &bufferingWriter{header: http.Header{}}
+		h.ServeHTTP(rec, part.req)
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+		header := textproto.MIMEHeader{"Content-Type": {"application/http"}}
+		if part.contentID != "" {
+			// Google APIs answer <id> with <response-id>.
+			header.Set("Content-ID", "<response-"+strings.Trim(part.contentID, "<>")+">")
+		}
+		pw, err := mw.CreatePart(header)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Error writing the batch response: %v", err), http.StatusInternalServerError)
+			return true
+		}
+		resp := &http.Response{
+			StatusCode:    rec.status,
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        rec.header,
+			Body:          io.NopCloser(bytes.NewReader(rec.body.Bytes())),
+			ContentLength: int64(rec.body.Len()),
+		}
+		resp.Write(pw)
+	}
+	mw.Close()
+	w.Header().Set("Content-Type", "multipart/mixed; boundary="+mw.Boundary())
+	w.Write(b.Bytes())
+	return true
+}
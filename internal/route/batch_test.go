@@ -0,0 +1,105 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package route
+
+import (
+	"bufio"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/test-server/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatch(t *testing.T) {
+	server := serve(t,
+		config.Route{Method: "POST", Path: "/batch/storage/v1", Batch: true},
+		config.Route{
+			Method:   "GET",
+			Path:     "/storage/v1/b/bucket/o/{object}",
+			Auth:     &config.Auth{APIKeys: []string{"secret"}},
+			Response: &config.Response{Template: true, Body: `{"name": "{{.PathParams.object}}"}`},
+		},
+		config.Route{Method: "DELETE", Path: "/storage/v1/b/bucket/o/missing", Fault: &config.Fault{Preset: "not_found"}},
+	)
+
+	batch := "--batch_1\r\n" +
+		"Content-Type: application/http\r\n" +
+		"Content-ID: <item1>\r\n\r\n" +
+		"GET /storage/v1/b/bucket/o/a HTTP/1.1\r\n\r\n" +
+		"\r\n--batch_1\r\n" +
+		"Content-Type: application/http\r\n" +
+		"Content-ID: <item2>\r\n\r\n" +
+		"DELETE /storage/v1/b/bucket/o/missing HTTP/1.1\r\n\r\n" +
+		"\r\n--batch_1\r\n" +
+		"Content-Type: application/http\r\n" +
+		"Content-ID: <item3>\r\n\r\n" +
+		"PATCH /storage/v1/b/bucket/o/b HTTP/1.1\r\n" +
+		"Content-Type: application/json\r\n\r\n" +
+		`{"metadata": {"k": "v"}}` +
+		"\r\n--batch_1--\r\n"
+	resp, body, err := do(t, "POST", server.URL+"/batch/storage/v1", "", batch,
+		"Content-Type", "multipart/mixed; boundary=batch_1", "X-Goog-Api-Key", "secret")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode, body)
+	mediaType, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	require.Equal(t, "multipart/mixed", mediaType)
+
+	type answer struct {
+		contentID string
+		status    int
+		body      string
+	}
+	var answers []answer
+	r := multipart.NewReader(strings.NewReader(body), params["boundary"])
+	for {
+		part, err := r.NextPart()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		require.Equal(t, "application/http", part.Header.Get("Content-Type"))
+		inner, err := http.ReadResponse(bufio.NewReader(part), nil)
+		require.NoError(t, err)
+		data, err := io.ReadAll(inner.Body)
+		require.NoError(t, err)
+		answers = append(answers, answer{part.Header.Get("Content-ID"), inner.StatusCode, string(data)})
+	}
+	require.Len(t, answers, 3)
+	// The API key of the batch applies to its requests.
+	require.Equal(t, answer{"<response-item1>", http.StatusOK, `{"name": "a"}`}, answers[0])
+	require.Equal(t, "<response-item2>", answers[1].contentID)
+	require.Equal(t, http.StatusNotFound, answers[1].status)
+	require.Equal(t, answer{"<response-item3>", http.StatusOK, `{"ok":true}`}, answers[2])
+
+	// Other requests to the route are not batches.
+	resp, body, err = do(t, "POST", server.URL+"/batch/storage/v1", "", "{}", "Content-Type", "application/json")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, `{"ok":true}`, body)
+
+	resp, body, err = do(t, "POST", server.URL+"/batch/storage/v1", "", "--batch_1\r\nContent-Type: text/plain\r\n\r\nhi\r\n--batch_1--\r\n",
+		"Content-Type", "multipart/mixed; boundary=batch_1")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	require.Contains(t, body, "not application/http")
+}
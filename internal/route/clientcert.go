@@ -0,0 +1,85 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package route
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+
+	"github.com/google/test-server/internal/config"
+)
+
+// clientCertAttributes return the values of the attributes of a client
+// certificate that the client_cert conditions of a route test.
+var clientCertAttributes = map[string]func(*x509.Certificate) []string{
+	"common_name":  func(c *x509.Certificate) []string { return []string{c.Subject.CommonName} },
+	"organization": func(c *x509.Certificate) []string { return c.Subject.Organization },
+	"organizational_unit": func(c *x509.Certificate) []string {
+		return c.Subject.OrganizationalUnit
+	},
+	"serial_number": func(c *x509.Certificate) []string { return []string{c.SerialNumber.Text(16)} },
+	"issuer":        func(c *x509.Certificate) []string { return []string{c.Issuer.CommonName} },
+	"dns_name":      func(c *x509.Certificate) []string { return c.DNSNames },
+	"email":         func(c *x509.Certificate) []string { return c.EmailAddresses },
+	"uri": func(c *x509.Certificate) []string {
+		uris := make([]string, len(c.URIs))
+		for i, u := range c.URIs {
+			uris[i] = u.String()
+		}
+		return uris
+	},
+	"fingerprint": func(c *x509.Certificate) []string {
+		sum := sha256.Sum256(c.Raw)
+		return []string{hex.EncodeToString(sum[:])}
+	},
+}
+
+// compileClientCert compiles the client_cert conditions of a route.
+func compileClientCert(conditions map[string]config.ValueMatch) ([]valueMatch, error) {
+	for name := range conditions {
+		if _, ok := clientCertAttributes[name]; !ok {
+			return nil, fmt.Errorf("%s: unknown attribute", name)
+		}
+	}
+	return compileValues(conditions)
+}
+
+// matchesClientCert reports whether the TLS client certificate of req
+// satisfies the client_cert conditions of c.
+func (c *compiled) matchesClientCert(req *http.Request) bool {
+	var cert *x509.Certificate
+	if req.TLS != nil && len(req.TLS.PeerCertificates) > 0 {
+		cert = req.TLS.PeerCertificates[0]
+	}
+	for i := range c.clientCert {
+		var values []string
+		if cert != nil {
+			for _, v := range clientCertAttributes[c.clientCert[i].name](cert) {
+				if v != "" {
+					values = append(values, v)
+				}
+			}
+		}
+		if !c.clientCert[i].test(values) {
+			return false
+		}
+	}
+	return true
+}
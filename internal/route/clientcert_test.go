@@ -0,0 +1,64 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package route
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/test-server/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientCert(t *testing.T) {
+	handler, err := Handler(&config.EndpointConfig{
+		TargetHost: "example.googleapis.com",
+		Routes: []config.Route{
+			{Name: "admin", ClientCert: map[string]config.ValueMatch{
+				"common_name":  {Equals: "alice"},
+				"organization": {Equals: "admins"},
+			}, Response: &config.Response{Body: "admin"}},
+			{Name: "user", ClientCert: map[string]config.ValueMatch{"serial_number": {Present: true}}, Response: &config.Response{Body: "user"}},
+			{Name: "anonymous", ClientCert: map[string]config.ValueMatch{"common_name": {Absent: true}}, Response: &config.Response{Status: http.StatusUnauthorized}},
+		},
+	}, nil, http.NotFoundHandler())
+	require.NoError(t, err)
+	send := func(cert *x509.Certificate) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "https://localhost/v1/models", nil)
+		if cert != nil {
+			req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := send(&x509.Certificate{SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: "alice", Organization: []string{"users", "admins"}}})
+	require.Equal(t, "admin", rec.Body.String())
+	rec = send(&x509.Certificate{SerialNumber: big.NewInt(2), Subject: pkix.Name{CommonName: "bob", Organization: []string{"users"}}})
+	require.Equal(t, "user", rec.Body.String())
+	rec = send(nil)
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	_, err = New("example.googleapis.com", []config.Route{{ClientCert: map[string]config.ValueMatch{"country": {Equals: "FR"}}}})
+	require.ErrorContains(t, err, "routes[0].client_cert.country: unknown attribute")
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package route
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/test-server/internal/clock"
+)
+
+// ClockPath is the path of the admin calls that read and control the time
+// of the server, e.g. POST /__admin/clock/advance.
+const ClockPath = "/__admin/clock"
+
+// clockRequest is the body of the clock calls that take one, e.g.
+// {"time": "2030-01-01T00:00:00Z"} or {"by": "1h"}.
+type clockRequest struct {
+	Time string `json:"time"`
+	By   string `json:"by"`
+}
+
+// serveClock answers GET /__admin/clock with the time of the server and
+// POST /__admin/clock/<action> by freezing, resuming, setting, advancing or
+// resetting it.
+func serveClock(w http.ResponseWriter, req *http.Request) {
+	action := strings.TrimPrefix(strings.TrimPrefix(req.URL.Path, ClockPath), "/")
+	allow := http.MethodPost
+	if action == "" {
+		allow = http.MethodGet
+	}
+	if req.Method != allow {
+		w.Header().Set("Allow", allow)
+		writeError(w, http.StatusMethodNotAllowed, "INVALID_ARGUMENT", "The clock is read with GET and changed with POST")
+		return
+	}
+	var body clockRequest
+	if data, _ := io.ReadAll(req.Body); len(data) > 0 {
+		if err := json.Unmarshal(data, &body); err != nil {
+			writeError(w, http.StatusBadRequest, "INVALID_ARGUMENT", fmt.Sprintf("Invalid clock request: %v", err))
+			return
+		}
+	}
+	var at time.Time
+	if body.Time != "" {
+		var err error
+		if at, err = time.Parse(time.RFC3339Nano, body.Time); err != nil {
+			writeError(w, http.StatusBadRequest, "INVALID_ARGUMENT", fmt.Sprintf("Invalid time: %v", err))
+			return
+		}
+	}
+	c := clock.Server
+	switch action {
+	case "":
+	case "freeze":
+		c.Freeze()
+		if body.Time != "" {
+			c.Set(at)
+		}
+	case "resume":
+		c.Resume()
+	case "set":
+		if body.Time == "" {
+			writeError(w, http.StatusBadRequest, "INVALID_ARGUMENT", "Setting the clock needs a time")
+			return
+		}
+		c.Set(at)
+	case "advance":
+		d, err := time.ParseDuration(body.By)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "INVALID_ARGUMENT", fmt.Sprintf("Invalid duration to advance by: %v", err))
+			return
+		}
+		c.Advance(d)
+	case "reset":
+		c.Reset()
+	default:
+		writeError(w, http.StatusNotFound, "NOT_FOUND", fmt.Sprintf("Unknown clock action %q", action))
+		return
+	}
+	if action != "" {
+		fmt.Printf("Clock %s: %s\n", action, c.Now().Format(time.RFC3339Nano))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"now": c.Now().Format(time.RFC3339Nano), "frozen": c.Frozen()})
+}
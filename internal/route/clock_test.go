@@ -0,0 +1,98 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package route
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/test-server/internal/clock"
+	"github.com/google/test-server/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClock(t *testing.T) {
+	t.Cleanup(clock.Server.Reset)
+	handler, err := Handler(&config.EndpointConfig{
+		TargetHost: "example.googleapis.com",
+		OAuth:      &config.OAuth{ExpiresIn: "1h"},
+		Routes: []config.Route{
+			{Path: "/v1/time", Response: &config.Response{Template: true, Body: `{{now.UTC.Format "2006-01-02T15:04:05Z07:00"}}`}},
+			{Path: "/v1/private", Auth: &config.Auth{IssuedTokens: true}, Response: &config.Response{Body: "ok"}},
+		},
+	}, nil, http.NotFoundHandler())
+	require.NoError(t, err)
+	send := func(method, target, body string, headers ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		for i := 0; i < len(headers); i += 2 {
+			req.Header.Set(headers[i], headers[i+1])
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+	state := func(rec *httptest.ResponseRecorder) (now string, frozen bool) {
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		var s struct {
+			Now    string
+			Frozen bool
+		}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &s))
+		return s.Now, s.Frozen
+	}
+
+	now, frozen := state(send("POST", "/__admin/clock/freeze", `{"time": "2030-01-01T00:00:00Z"}`))
+	require.Equal(t, "2030-01-01T00:00:00Z", now)
+	require.True(t, frozen)
+	require.Equal(t, "2030-01-01T00:00:00Z", send("GET", "/v1/time", "").Body.String())
+
+	form := url.Values{"grant_type": {"client_credentials"}}
+	rec := send("POST", "/token", form.Encode(), "Content-Type", "application/x-www-form-urlencoded")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &token))
+	require.Equal(t, http.StatusOK, send("GET", "/v1/private", "", "Authorization", "Bearer "+token.AccessToken).Code)
+
+	// The token expires without waiting an hour.
+	now, _ = state(send("POST", "/__admin/clock/advance", `{"by": "61m"}`))
+	require.Equal(t, "2030-01-01T01:01:00Z", now)
+	require.Equal(t, "2030-01-01T01:01:00Z", send("GET", "/v1/time", "").Body.String())
+	require.Equal(t, http.StatusUnauthorized, send("GET", "/v1/private", "", "Authorization", "Bearer "+token.AccessToken).Code)
+
+	now, _ = state(send("GET", "/__admin/clock", ""))
+	require.Equal(t, "2030-01-01T01:01:00Z", now)
+	_, frozen = state(send("POST", "/__admin/clock/resume", ""))
+	require.False(t, frozen)
+	now, _ = state(send("POST", "/__admin/clock/reset", ""))
+	at, err := time.Parse(time.RFC3339Nano, now)
+	require.NoError(t, err)
+	require.WithinDuration(t, time.Now(), at, time.Minute)
+
+	require.Equal(t, http.StatusMethodNotAllowed, send("POST", "/__admin/clock", "").Code)
+	require.Equal(t, http.StatusMethodNotAllowed, send("GET", "/__admin/clock/freeze", "").Code)
+	require.Equal(t, http.StatusNotFound, send("POST", "/__admin/clock/rewind", "").Code)
+	require.Equal(t, http.StatusBadRequest, send("POST", "/__admin/clock/set", "").Code)
+	require.Equal(t, http.StatusBadRequest, send("POST", "/__admin/clock/set", `{"time": "tomorrow"}`).Code)
+	require.Equal(t, http.StatusBadRequest, send("POST", "/__admin/clock/advance", `{"by": "a while"}`).Code)
+}
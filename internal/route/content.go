@@ -0,0 +1,150 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package route
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/test-server/internal/codec"
+)
+
+// bufferingWriter holds a response back so that it can be served again in
+// part.
+type bufferingWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (b *bufferingWriter) Header() http.Header { return b.header }
+
+func (b *bufferingWriter) WriteHeader(status int) {
+	if b.status == 0 {
+		b.status = status
+	}
+}
+
+func (b *bufferingWriter) Write(p []byte) (int, error) {
+	b.WriteHeader(http.StatusOK)
+	return b.body.Write(p)
+}
+
+// Flush does nothing: the response is only sent once it is complete.
+func (b *bufferingWriter) Flush() {}
+
+// serveContent answers req with the response of next, applying the Range,
+// If-Range and conditional headers of req to it with http.ServeContent if it
+// succeeds.
+func serveContent(w http.ResponseWriter, req *http.Request, next http.Handler) {
+	b := &bufferingWriter{header: http.Header{}}
+	next.ServeHTTP(b, req)
+	for name, values := range b.header {
+		w.Header()[name] = values
+	}
+	if b.status != http.StatusOK {
+		if b.status != 0 {
+			w.WriteHeader(b.status)
+		}
+		w.Write(b.body.Bytes())
+		return
+	}
+	// ServeContent sets the length of what it serves.
+	w.Header().Del("Content-Length")
+	modtime, _ := http.ParseTime(b.header.Get("Last-Modified"))
+	http.ServeContent(w, req, "", modtime, bytes.NewReader(b.body.Bytes()))
+}
+
+// bodyETag returns a strong ETag for body.
+func bodyETag(body string) string {
+	sum := sha256.Sum256([]byte(body))
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// notModified reports whether the If-None-Match or, without it, the
+// If-Modified-Since header of req shows that the client has the response with
+// etag and modtime, either of which may be empty.
+func notModified(req *http.Request, etag string, modtime time.Time) bool {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return false
+	}
+	if match := req.Header.Get("If-None-Match"); match != "" {
+		if etag == "" {
+			return false
+		}
+		for _, tag := range strings.Split(match, ",") {
+			// If-None-Match uses the weak comparison.
+			tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+			if tag == "*" || tag == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		}
+		return false
+	}
+	since, err := http.ParseTime(req.Header.Get("If-Modified-Since"))
+	if err != nil || modtime.IsZero() {
+		return false
+	}
+	// HTTP dates have no fractions of a second.
+	return !modtime.Truncate(time.Second).After(since)
+}
+
+// encodeContent answers req with the response of next encoded with coding.
+func encodeContent(w http.ResponseWriter, req *http.Request, next http.Handler, coding string) {
+	b := &bufferingWriter{header: http.Header{}}
+	next.ServeHTTP(b, req)
+	for name, values := range b.header {
+		w.Header()[name] = values
+	}
+	body, err := codec.Encode(coding, b.body.Bytes())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error encoding the response: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Del("Content-Length")
+	if coding != "identity" {
+		w.Header().Set("Content-Encoding", coding)
+		w.Header().Add("Vary", "Accept-Encoding")
+	}
+	if b.status == 0 {
+		b.status = http.StatusOK
+	}
+	w.WriteHeader(b.status)
+	w.Write(body)
+}
+
+// decodeRequests decodes the gzip and deflate request bodies for next,
+// answering the requests with other codings with 415.
+func decodeRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if err := codec.DecodeRequest(req); err != nil {
+			status := http.StatusBadRequest
+			if errors.Is(err, codec.ErrUnsupported) {
+				status = http.StatusUnsupportedMediaType
+			}
+			writeError(w, status, "INVALID_ARGUMENT", fmt.Sprintf("Cannot decode the request body: %v", err))
+			return
+		}
+		next.ServeHTTP(w, req)
+	})
+}
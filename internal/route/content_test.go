@@ -0,0 +1,219 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package route
+
+import (
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/test-server/internal/codec"
+	"github.com/google/test-server/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRanges(t *testing.T) {
+	server := serve(t,
+		config.Route{
+			Path:     "/v1/files/data",
+			Ranges:   true,
+			Response: &config.Response{Headers: map[string]string{"ETag": `"v1"`, "Content-Type": "text/plain"}, Body: "0123456789"},
+		},
+		config.Route{Path: "/v1/files/missing", Ranges: true, Fault: &config.Fault{Preset: "not_found"}},
+		config.Route{Path: "/v1/files/*", Ranges: true},
+	)
+
+	resp, body, err := do(t, "GET", server.URL+"/v1/files/data", "", "")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "bytes", resp.Header.Get("Accept-Ranges"))
+	require.Equal(t, "0123456789", body)
+
+	resp, body, err = do(t, "GET", server.URL+"/v1/files/data", "", "", "Range", "bytes=4-")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusPartialContent, resp.StatusCode)
+	require.Equal(t, "bytes 4-9/10", resp.Header.Get("Content-Range"))
+	require.Equal(t, `"v1"`, resp.Header.Get("ETag"))
+	require.Equal(t, "456789", body)
+
+	resp, body, err = do(t, "GET", server.URL+"/v1/files/data", "", "", "Range", "bytes=-3")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusPartialContent, resp.StatusCode)
+	require.Equal(t, "789", body)
+
+	// A stale If-Range gets the whole body.
+	resp, body, err = do(t, "GET", server.URL+"/v1/files/data", "", "", "Range", "bytes=4-", "If-Range", `"v0"`)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "0123456789", body)
+	resp, body, err = do(t, "GET", server.URL+"/v1/files/data", "", "", "Range", "bytes=4-", "If-Range", `"v1"`)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusPartialContent, resp.StatusCode)
+	require.Equal(t, "456789", body)
+
+	resp, _, err = do(t, "GET", server.URL+"/v1/files/data", "", "", "Range", "bytes=20-")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusRequestedRangeNotSatisfiable, resp.StatusCode)
+	require.Equal(t, "bytes */10", resp.Header.Get("Content-Range"))
+
+	// Errors are never cut.
+	resp, body, err = do(t, "GET", server.URL+"/v1/files/missing", "", "", "Range", "bytes=0-1")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	require.Contains(t, body, "NOT_FOUND")
+
+	// Without a stub, the response of next is cut.
+	resp, body, err = do(t, "GET", server.URL+"/v1/files/other", "", "", "Range", "bytes=1-4")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusPartialContent, resp.StatusCode)
+	require.Equal(t, `"ok"`, body)
+}
+
+func TestMultipleRanges(t *testing.T) {
+	server := serve(t, config.Route{
+		Path:     "/v1/files/data",
+		Ranges:   true,
+		Response: &config.Response{Headers: map[string]string{"Content-Type": "text/plain"}, Body: "0123456789"},
+	})
+
+	resp, body, err := do(t, "GET", server.URL+"/v1/files/data", "", "", "Range", "bytes=0-1,5-6")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusPartialContent, resp.StatusCode)
+	mediaType, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	require.Equal(t, "multipart/byteranges", mediaType)
+
+	r := multipart.NewReader(strings.NewReader(body), params["boundary"])
+	var parts []string
+	for {
+		part, err := r.NextPart()
+		if err != nil {
+			break
+		}
+		data, err := io.ReadAll(part)
+		require.NoError(t, err)
+		require.Equal(t, "text/plain", part.Header.Get("Content-Type"))
+		parts = append(parts, part.Header.Get("Content-Range")+" "+string(data))
+	}
+	require.Equal(t, []string{"bytes 0-1/10 01", "bytes 5-6/10 56"}, parts)
+}
+
+func TestConditionalStubs(t *testing.T) {
+	server := serve(t,
+		config.Route{Path: "/v1/models/a", Response: &config.Response{Body: `{"name": "a"}`, ETag: true}},
+		config.Route{Path: "/v1/models/b", Response: &config.Response{Body: `{"name": "b"}`, Headers: map[string]string{"ETag": `W/"b1"`}, ETag: true}},
+		config.Route{Path: "/v1/models/c", Response: &config.Response{Body: `{"name": "c"}`, LastModified: "2025-01-02T03:04:05Z"}},
+		config.Route{Path: "/v1/models/d", Response: &config.Response{Body: `{"name": "d"}`, Headers: map[string]string{"ETag": `"d1"`}}},
+	)
+
+	resp, body, err := do(t, "GET", server.URL+"/v1/models/a", "", "")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, `{"name": "a"}`, body)
+	etag := resp.Header.Get("ETag")
+	require.Regexp(t, `^"[0-9a-f]{32}"$`, etag)
+
+	resp, body, err = do(t, "GET", server.URL+"/v1/models/a", "", "", "If-None-Match", `"other", `+etag)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNotModified, resp.StatusCode)
+	require.Equal(t, etag, resp.Header.Get("ETag"))
+	require.Empty(t, body)
+	resp, _, err = do(t, "GET", server.URL+"/v1/models/a", "", "", "If-None-Match", `"other"`)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	// Only reads are conditional.
+	resp, _, err = do(t, "POST", server.URL+"/v1/models/a", "", "", "If-None-Match", etag)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// A configured ETag is kept and compared weakly.
+	resp, _, err = do(t, "GET", server.URL+"/v1/models/b", "", "", "If-None-Match", `"b1"`)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNotModified, resp.StatusCode)
+	require.Equal(t, `W/"b1"`, resp.Header.Get("ETag"))
+
+	resp, _, err = do(t, "GET", server.URL+"/v1/models/c", "", "")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "Thu, 02 Jan 2025 03:04:05 GMT", resp.Header.Get("Last-Modified"))
+	resp, _, err = do(t, "GET", server.URL+"/v1/models/c", "", "", "If-Modified-Since", "Thu, 02 Jan 2025 03:04:05 GMT")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNotModified, resp.StatusCode)
+	resp, _, err = do(t, "GET", server.URL+"/v1/models/c", "", "", "If-Modified-Since", "Thu, 02 Jan 2025 03:04:04 GMT")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// Without etag or last_modified, stubs are not conditional.
+	resp, _, err = do(t, "GET", server.URL+"/v1/models/d", "", "", "If-None-Match", `"d1"`)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestContentEncoding(t *testing.T) {
+	handler, err := Handler(&config.EndpointConfig{Routes: []config.Route{
+		{Path: "/v1/br", ContentEncoding: "br", Response: &config.Response{Body: `{"a": 1}`}},
+		{Path: "/v1/gzip", ContentEncoding: "gzip", Response: &config.Response{Status: 201, Body: `{"a": 1}`}},
+		{Path: "/v1/echo", Response: &config.Response{Template: true, Body: "{{.RawBody}}"}},
+	}}, nil, http.NotFoundHandler())
+	require.NoError(t, err)
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	resp, body, err := do(t, "GET", server.URL+"/v1/br", "", "", "Accept-Encoding", "br")
+	require.NoError(t, err)
+	require.Equal(t, "br", resp.Header.Get("Content-Encoding"))
+	data, err := codec.Decode("br", []byte(body))
+	require.NoError(t, err)
+	require.Equal(t, `{"a": 1}`, string(data))
+
+	// The client decodes gzip itself.
+	r, err := http.Get(server.URL + "/v1/gzip")
+	require.NoError(t, err)
+	defer r.Body.Close()
+	require.Equal(t, http.StatusCreated, r.StatusCode)
+	require.True(t, r.Uncompressed)
+	data, err = io.ReadAll(r.Body)
+	require.NoError(t, err)
+	require.Equal(t, `{"a": 1}`, string(data))
+
+	// Request bodies are decoded before the routes see them.
+	gz, err := codec.Encode("gzip", []byte(`{"prompt": "hi"}`))
+	require.NoError(t, err)
+	resp, body, err = do(t, "POST", server.URL+"/v1/echo", "", string(gz), "Content-Encoding", "gzip")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, `{"prompt": "hi"}`, body)
+	zst, err := codec.Encode("zstd", []byte(`{"prompt": "hi"}`))
+	require.NoError(t, err)
+	resp, body, err = do(t, "POST", server.URL+"/v1/echo", "", string(zst), "Content-Encoding", "zstd")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, `{"prompt": "hi"}`, body)
+	resp, _, err = do(t, "POST", server.URL+"/v1/echo", "", "x", "Content-Encoding", "compress")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusUnsupportedMediaType, resp.StatusCode)
+	resp, _, err = do(t, "POST", server.URL+"/v1/echo", "", "not gzip", "Content-Encoding", "gzip")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	_, err = New("example.googleapis.com", []config.Route{{ContentEncoding: "compress"}})
+	require.Error(t, err)
+}
@@ -0,0 +1,232 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package route
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/test-server/internal/config"
+)
+
+// defaultCORSMethods are the methods a preflight allows without
+// allow_methods.
+var defaultCORSMethods = []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE"}
+
+// cors applies a config.CORS.
+type cors struct {
+	// origins is nil when every origin is allowed.
+	origins     []*regexp.Regexp
+	methods     []string
+	headers     []string // canonical names, nil to allow any
+	expose      string
+	credentials bool
+	maxAge      string // seconds, or "" for none
+}
+
+func newCORS(c *config.CORS) (*cors, error) {
+	p := &cors{
+		methods:     defaultCORSMethods,
+		expose:      strings.Join(c.ExposeHeaders, ", "),
+		credentials: c.AllowCredentials,
+	}
+	for _, origin := range c.AllowOrigins {
+		if origin == "*" {
+			p.origins = nil
+			break
+		}
+		if !strings.Contains(origin, "://") {
+			return nil, fmt.Errorf("allow_origins: %q is not an origin such as https://example.com", origin)
+		}
+		pattern := strings.ReplaceAll(regexp.QuoteMeta(strings.ToLower(strings.TrimSuffix(origin, "/"))), `\*`, `[^/.:]*`)
+		p.origins = append(p.origins, regexp.MustCompile("^"+pattern+"$"))
+	}
+	if len(c.AllowMethods) > 0 {
+		p.methods = nil
+		for _, m := range c.AllowMethods {
+			p.methods = append(p.methods, strings.ToUpper(m))
+		}
+	}
+	for _, h := range c.AllowHeaders {
+		p.headers = append(p.headers, http.CanonicalHeaderKey(h))
+	}
+	if c.MaxAge != "" {
+		d, err := time.ParseDuration(c.MaxAge)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("max_age: invalid duration %q", c.MaxAge)
+		}
+		p.maxAge = strconv.Itoa(int(d.Seconds()))
+	}
+	return p, nil
+}
+
+func (p *cors) allowsOrigin(origin string) bool {
+	if p.origins == nil {
+		return true
+	}
+	origin = strings.ToLower(origin)
+	for _, re := range p.origins {
+		if re.MatchString(origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowOrigin is the Access-Control-Allow-Origin of a response to origin.
+func (p *cors) allowOrigin(origin string) string {
+	// A credentialed request needs its own origin, not *.
+	if p.origins == nil && !p.credentials {
+		return "*"
+	}
+	return origin
+}
+
+// Wrap returns a handler answering CORS preflight requests and adding CORS
+// headers to the responses of next to allowed origins. Requests without an
+// Origin header pass through unchanged.
+func (p *cors) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		origin := req.Header.Get("Origin")
+		if origin == "" {
+			next.ServeHTTP(w, req)
+			return
+		}
+		w.Header().Add("Vary", "Origin")
+		requested := req.Header.Get("Access-Control-Request-Method")
+		if req.Method == http.MethodOptions && requested != "" {
+			p.preflight(w, req, origin, requested)
+			return
+		}
+		if !p.allowsOrigin(origin) {
+			next.ServeHTTP(w, req)
+			return
+		}
+		next.ServeHTTP(&corsWriter{ResponseWriter: w, cors: p, origin: origin}, req)
+	})
+}
+
+func (p *cors) preflight(w http.ResponseWriter, req *http.Request, origin, method string) {
+	if !p.allowsOrigin(origin) {
+		http.Error(w, fmt.Sprintf("CORS: origin %s is not allowed", origin), http.StatusForbidden)
+		return
+	}
+	if !contains(p.methods, strings.ToUpper(method)) {
+		http.Error(w, fmt.Sprintf("CORS: method %s is not allowed", method), http.StatusForbidden)
+		return
+	}
+	var headers []string
+	for _, h := range strings.Split(req.Header.Get("Access-Control-Request-Headers"), ",") {
+		if h = strings.TrimSpace(h); h == "" {
+			continue
+		}
+		if p.headers != nil && !contains(p.headers, http.CanonicalHeaderKey(h)) {
+			http.Error(w, fmt.Sprintf("CORS: header %s is not allowed", h), http.StatusForbidden)
+			return
+		}
+		headers = append(headers, h)
+	}
+	if p.headers != nil {
+		headers = p.headers
+	}
+
+	h := w.Header()
+	h.Add("Vary", "Access-Control-Request-Method")
+	h.Add("Vary", "Access-Control-Request-Headers")
+	h.Set("Access-Control-Allow-Origin", p.allowOrigin(origin))
+	h.Set("Access-Control-Allow-Methods", strings.Join(p.methods, ", "))
+	if len(headers) > 0 {
+		h.Set("Access-Control-Allow-Headers", strings.Join(headers, ", "))
+	}
+	if p.credentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+	if p.maxAge != "" {
+		h.Set("Access-Control-Max-Age", p.maxAge)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func contains(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
+
+// corsWriter replaces the CORS headers of a response, e.g. recorded ones,
+// with those of the endpoint.
+type corsWriter struct {
+	http.ResponseWriter
+	cors        *cors
+	origin      string
+	wroteHeader bool
+}
+
+func (c *corsWriter) WriteHeader(status int) {
+	if c.wroteHeader {
+		return
+	}
+	c.wroteHeader = true
+	h := c.Header()
+	for name := range h {
+		if strings.HasPrefix(name, "Access-Control-") {
+			h.Del(name)
+		}
+	}
+	h.Set("Access-Control-Allow-Origin", c.cors.allowOrigin(c.origin))
+	if c.cors.credentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+	if c.cors.expose != "" {
+		h.Set("Access-Control-Expose-Headers", c.cors.expose)
+	}
+	c.ResponseWriter.WriteHeader(status)
+}
+
+func (c *corsWriter) Write(b []byte) (int, error) {
+	if !c.wroteHeader {
+		c.WriteHeader(http.StatusOK)
+	}
+	return c.ResponseWriter.Write(b)
+}
+
+func (c *corsWriter) Flush() {
+	if !c.wroteHeader {
+		c.WriteHeader(http.StatusOK)
+	}
+	if f, ok := c.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack lets websocket upgrades and reset faults through.
+func (c *corsWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := c.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("the response writer cannot be hijacked")
+	}
+	return hj.Hijack()
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package route
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/test-server/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCORS(t *testing.T) {
+	handler, err := Handler(&config.EndpointConfig{
+		TargetHost: "example.googleapis.com",
+		CORS: &config.CORS{
+			AllowOrigins:     []string{"http://localhost:8080", "https://*.example.com"},
+			AllowHeaders:     []string{"content-type", "x-goog-api-key"},
+			ExposeHeaders:    []string{"X-Request-Id"},
+			AllowCredentials: true,
+			MaxAge:           "10m",
+		},
+	}, nil, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		// A recorded response may carry the CORS headers of the target.
+		w.Header().Set("Access-Control-Allow-Origin", "https://aistudio.google.com")
+		w.Write([]byte("ok"))
+	}))
+	require.NoError(t, err)
+	send := func(method, origin string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/v1/models", nil)
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := send("OPTIONS", "https://app.example.com", map[string]string{
+		"Access-Control-Request-Method":  "POST",
+		"Access-Control-Request-Headers": "Content-Type, X-Goog-Api-Key",
+	})
+	require.Equal(t, http.StatusNoContent, rec.Code)
+	require.Equal(t, "https://app.example.com", rec.Header().Get("Access-Control-Allow-Origin"))
+	require.Equal(t, "GET, HEAD, POST, PUT, PATCH, DELETE", rec.Header().Get("Access-Control-Allow-Methods"))
+	require.Equal(t, "Content-Type, X-Goog-Api-Key", rec.Header().Get("Access-Control-Allow-Headers"))
+	require.Equal(t, "true", rec.Header().Get("Access-Control-Allow-Credentials"))
+	require.Equal(t, "600", rec.Header().Get("Access-Control-Max-Age"))
+
+	rec = send("OPTIONS", "http://localhost:8080", map[string]string{
+		"Access-Control-Request-Method":  "POST",
+		"Access-Control-Request-Headers": "X-Other",
+	})
+	require.Equal(t, http.StatusForbidden, rec.Code)
+	rec = send("OPTIONS", "http://localhost:8080", map[string]string{"Access-Control-Request-Method": "TRACE"})
+	require.Equal(t, http.StatusForbidden, rec.Code)
+	rec = send("OPTIONS", "https://a.b.example.com", map[string]string{"Access-Control-Request-Method": "GET"})
+	require.Equal(t, http.StatusForbidden, rec.Code)
+
+	rec = send("GET", "http://localhost:8080", nil)
+	require.Equal(t, "ok", rec.Body.String())
+	require.Equal(t, "http://localhost:8080", rec.Header().Get("Access-Control-Allow-Origin"))
+	require.Equal(t, "X-Request-Id", rec.Header().Get("Access-Control-Expose-Headers"))
+	require.Equal(t, "Origin", rec.Header().Get("Vary"))
+
+	// Without an allowed origin, the response is left alone.
+	rec = send("GET", "http://evil.test", nil)
+	require.Equal(t, "https://aistudio.google.com", rec.Header().Get("Access-Control-Allow-Origin"))
+	rec = send("GET", "", nil)
+	require.Equal(t, "https://aistudio.google.com", rec.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestCORSAnyOrigin(t *testing.T) {
+	handler, err := Handler(&config.EndpointConfig{CORS: &config.CORS{}}, nil, http.NotFoundHandler())
+	require.NoError(t, err)
+	req := httptest.NewRequest("OPTIONS", "/v1/models", nil)
+	req.Header.Set("Origin", "http://localhost:3000")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	req.Header.Set("Access-Control-Request-Headers", "x-custom")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusNoContent, rec.Code)
+	require.Equal(t, "*", rec.Header().Get("Access-Control-Allow-Origin"))
+	require.Equal(t, "x-custom", rec.Header().Get("Access-Control-Allow-Headers"))
+
+	for _, bad := range []*config.CORS{{AllowOrigins: []string{"localhost"}}, {MaxAge: "soon"}} {
+		require.ErrorContains(t, Validate(&config.EndpointConfig{CORS: bad}), "cors.")
+	}
+}
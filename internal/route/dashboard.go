@@ -0,0 +1,92 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package route
+
+import (
+	_ "embed"
+	"net/http"
+	"sort"
+	"sync/atomic"
+)
+
+// DashboardPath is the path of the web dashboard of an endpoint, which shows
+// its requests, stubs and scenario states as they change.
+const DashboardPath = "/__admin/dashboard"
+
+// ScenariosPath is the path of the admin call that lists the states of the
+// scenarios of an endpoint.
+const ScenariosPath = "/__admin/scenarios"
+
+//go:embed dashboard.html
+var dashboardHTML []byte
+
+// dashboard is set once the dashboard is enabled.
+var dashboard atomic.Bool
+
+// EnableDashboard serves the dashboard of every endpoint at DashboardPath.
+func EnableDashboard() {
+	dashboard.Store(true)
+}
+
+// serveDashboard answers GET /__admin/dashboard with the dashboard, if it is
+// enabled.
+func serveDashboard(w http.ResponseWriter, req *http.Request) {
+	if !dashboard.Load() {
+		writeError(w, http.StatusNotFound, "NOT_FOUND", "The dashboard is disabled; start test-server with --dashboard")
+		return
+	}
+	if req.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeError(w, http.StatusMethodNotAllowed, "INVALID_ARGUMENT", "The dashboard is read with GET")
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write(dashboardHTML)
+}
+
+// scenarioState is a scenario in the answer of GET /__admin/scenarios.
+type scenarioState struct {
+	Name  string `json:"name"`
+	State string `json:"state"`
+}
+
+// serveScenarios answers GET /__admin/scenarios with the state of every
+// scenario of the routes of r, ordered by name.
+func (r *Router) serveScenarios(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeError(w, http.StatusMethodNotAllowed, "INVALID_ARGUMENT", "The scenarios are read with GET")
+		return
+	}
+	names := map[string]bool{}
+	r.routesMu.RLock()
+	for _, route := range r.config {
+		if route.Scenario != "" {
+			names[route.Scenario] = true
+		}
+	}
+	r.routesMu.RUnlock()
+	out := []scenarioState{}
+	r.scenarios.mu.Lock()
+	for name := range names {
+		out = append(out, scenarioState{Name: name, State: r.scenarios.state(name)})
+	}
+	r.scenarios.mu.Unlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	writeJSON(w, http.StatusOK, map[string]any{"scenarios": out})
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package route
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/test-server/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDashboard(t *testing.T) {
+	t.Cleanup(func() { dashboard.Store(false) })
+	handler, err := Handler(&config.EndpointConfig{
+		TargetHost: "example.googleapis.com",
+		Routes: []config.Route{
+			{Path: "/v1/jobs", Scenario: "job", NewState: "done", Response: &config.Response{Body: `{}`}},
+			{Path: "/v1/other", Scenario: "other", Response: &config.Response{Body: `{}`}},
+		},
+	}, nil, http.NotFoundHandler())
+	require.NoError(t, err)
+	send := func(method, target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(method, target, nil))
+		return rec
+	}
+
+	require.Equal(t, http.StatusNotFound, send("GET", DashboardPath).Code)
+	EnableDashboard()
+	rec := send("GET", DashboardPath)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "text/html; charset=utf-8", rec.Header().Get("Content-Type"))
+	require.Contains(t, rec.Body.String(), "<title>test-server dashboard</title>")
+	require.Equal(t, http.StatusMethodNotAllowed, send("POST", DashboardPath).Code)
+
+	scenarios := func() string {
+		rec := send("GET", ScenariosPath)
+		require.Equal(t, http.StatusOK, rec.Code)
+		var v struct{ Scenarios []scenarioState }
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &v))
+		var states []string
+		for _, s := range v.Scenarios {
+			states = append(states, s.Name+"="+s.State)
+		}
+		return strings.Join(states, ",")
+	}
+	require.Equal(t, "job=Started,other=Started", scenarios())
+	send("GET", "/v1/jobs")
+	require.Equal(t, "job=done,other=Started", scenarios())
+}
@@ -0,0 +1,176 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package route
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/google/test-server/internal/config"
+)
+
+// delay is a parsed config.Delay.
+type delay struct {
+	distribution string
+	// a and b are the parameters of the distribution: the duration, min and
+	// max, mean and standard deviation, or median.
+	a, b    time.Duration
+	sigma   float64
+	jitter  time.Duration
+	headers bool
+	chunks  bool
+}
+
+func parseDelay(d *config.Delay) (*delay, error) {
+	out := &delay{distribution: d.Distribution, sigma: d.Sigma}
+	duration := func(name, value string, required bool) (time.Duration, error) {
+		if value == "" {
+			if required {
+				return 0, fmt.Errorf("%s is required for the %s distribution", name, out.distribution)
+			}
+			return 0, nil
+		}
+		v, err := time.ParseDuration(value)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", name, err)
+		}
+		if v < 0 {
+			return 0, fmt.Errorf("%s must not be negative", name)
+		}
+		return v, nil
+	}
+
+	var err error
+	if out.distribution == "" {
+		out.distribution = "fixed"
+	}
+	switch out.distribution {
+	case "fixed":
+		out.a, err = duration("duration", d.Duration, true)
+	case "uniform":
+		if out.a, err = duration("min", d.Min, true); err == nil {
+			out.b, err = duration("max", d.Max, true)
+		}
+		if err == nil && out.b < out.a {
+			err = fmt.Errorf("max is less than min")
+		}
+	case "normal":
+		if out.a, err = duration("mean", d.Mean, true); err == nil {
+			out.b, err = duration("stddev", d.StdDev, true)
+		}
+	case "lognormal":
+		out.a, err = duration("median", d.Median, true)
+		if err == nil && d.Sigma <= 0 {
+			err = fmt.Errorf("sigma must be positive for the lognormal distribution")
+		}
+	default:
+		err = fmt.Errorf("unknown distribution %q, want fixed, uniform, normal or lognormal", d.Distribution)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if out.jitter, err = duration("jitter", d.Jitter, false); err != nil {
+		return nil, err
+	}
+	switch d.ApplyTo {
+	case "", "headers":
+		out.headers = true
+	case "chunks":
+		out.chunks = true
+	case "both":
+		out.headers, out.chunks = true, true
+	default:
+		return nil, fmt.Errorf("unknown apply_to %q, want headers, chunks or both", d.ApplyTo)
+	}
+	return out, nil
+}
+
+// sample draws a delay, never negative.
+func (d *delay) sample(rng *rand.Rand) time.Duration {
+	var v float64
+	switch d.distribution {
+	case "fixed":
+		v = float64(d.a)
+	case "uniform":
+		v = float64(d.a) + rng.Float64()*float64(d.b-d.a)
+	case "normal":
+		v = float64(d.a) + rng.NormFloat64()*float64(d.b)
+	case "lognormal":
+		v = float64(d.a) * math.Exp(rng.NormFloat64()*d.sigma)
+	}
+	if d.jitter > 0 {
+		v += (rng.Float64()*2 - 1) * float64(d.jitter)
+	}
+	return time.Duration(max(v, 0))
+}
+
+// delayingWriter sleeps before the response headers and between the writes
+// of the body, as configured, flushing every write so that the delays reach
+// the client.
+type delayingWriter struct {
+	http.ResponseWriter
+	delay       *delay
+	sample      func() time.Duration
+	wroteHeader bool
+	wroteBody   bool
+}
+
+func (d *delayingWriter) WriteHeader(status int) {
+	if d.wroteHeader {
+		return
+	}
+	d.wroteHeader = true
+	if d.delay.headers {
+		time.Sleep(d.sample())
+	}
+	d.ResponseWriter.WriteHeader(status)
+}
+
+func (d *delayingWriter) Write(b []byte) (int, error) {
+	if !d.wroteHeader {
+		d.WriteHeader(http.StatusOK)
+	}
+	if d.delay.chunks && d.wroteBody {
+		time.Sleep(d.sample())
+	}
+	d.wroteBody = true
+	n, err := d.ResponseWriter.Write(b)
+	if d.delay.chunks {
+		d.Flush()
+	}
+	return n, err
+}
+
+func (d *delayingWriter) Flush() {
+	if f, ok := d.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack lets a reset fault through.
+func (d *delayingWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := d.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("the response writer cannot be hijacked")
+	}
+	return hj.Hijack()
+}
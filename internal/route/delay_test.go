@@ -0,0 +1,118 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package route
+
+import (
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/test-server/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDelay(t *testing.T) {
+	tests := []struct {
+		name    string
+		delay   config.Delay
+		wantErr bool
+	}{
+		{name: "fixed", delay: config.Delay{Duration: "100ms"}},
+		{name: "fixed without duration", delay: config.Delay{}, wantErr: true},
+		{name: "uniform", delay: config.Delay{Distribution: "uniform", Min: "10ms", Max: "20ms"}},
+		{name: "uniform max below min", delay: config.Delay{Distribution: "uniform", Min: "20ms", Max: "10ms"}, wantErr: true},
+		{name: "normal", delay: config.Delay{Distribution: "normal", Mean: "1s", StdDev: "100ms", ApplyTo: "both"}},
+		{name: "normal without stddev", delay: config.Delay{Distribution: "normal", Mean: "1s"}, wantErr: true},
+		{name: "lognormal", delay: config.Delay{Distribution: "lognormal", Median: "200ms", Sigma: 0.5}},
+		{name: "lognormal without sigma", delay: config.Delay{Distribution: "lognormal", Median: "200ms"}, wantErr: true},
+		{name: "unknown distribution", delay: config.Delay{Distribution: "pareto", Duration: "1s"}, wantErr: true},
+		{name: "bad duration", delay: config.Delay{Duration: "soon"}, wantErr: true},
+		{name: "negative jitter", delay: config.Delay{Duration: "1s", Jitter: "-1ms"}, wantErr: true},
+		{name: "unknown apply_to", delay: config.Delay{Duration: "1s", ApplyTo: "trailers"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseDelay(&tt.delay)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestSample(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	draw := func(d config.Delay) []time.Duration {
+		parsed, err := parseDelay(&d)
+		require.NoError(t, err)
+		out := make([]time.Duration, 1001)
+		for i := range out {
+			out[i] = parsed.sample(rng)
+		}
+		sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+		return out
+	}
+
+	fixed := draw(config.Delay{Duration: "100ms", Jitter: "10ms"})
+	require.GreaterOrEqual(t, fixed[0], 90*time.Millisecond)
+	require.LessOrEqual(t, fixed[len(fixed)-1], 110*time.Millisecond)
+
+	uniform := draw(config.Delay{Distribution: "uniform", Min: "10ms", Max: "20ms"})
+	require.GreaterOrEqual(t, uniform[0], 10*time.Millisecond)
+	require.LessOrEqual(t, uniform[len(uniform)-1], 20*time.Millisecond)
+
+	// A wide normal distribution is clamped at zero.
+	normal := draw(config.Delay{Distribution: "normal", Mean: "10ms", StdDev: "50ms"})
+	require.Zero(t, normal[0])
+
+	lognormal := draw(config.Delay{Distribution: "lognormal", Median: "100ms", Sigma: 0.5})
+	require.InDelta(t, float64(100*time.Millisecond), float64(lognormal[500]), float64(10*time.Millisecond))
+}
+
+func TestDelays(t *testing.T) {
+	router, err := New("example.googleapis.com", []config.Route{
+		{Path: "/headers", Delay: &config.Delay{Duration: "50ms"}},
+		{Path: "/chunks", Delay: &config.Delay{Duration: "50ms", ApplyTo: "chunks"}},
+	})
+	require.NoError(t, err)
+	server := httptest.NewServer(router.Wrap(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("data: 1\n\n"))
+		w.Write([]byte("data: 2\n\n"))
+		w.Write([]byte("data: 3\n\n"))
+	})))
+	defer server.Close()
+
+	for path, want := range map[string]time.Duration{
+		"/headers": 50 * time.Millisecond,
+		"/chunks":  100 * time.Millisecond,
+		"/other":   0,
+	} {
+		start := time.Now()
+		_, body, err := get(t, server.URL+path)
+		require.NoError(t, err)
+		require.Equal(t, "data: 1\n\ndata: 2\n\ndata: 3\n\n", body)
+		elapsed := time.Since(start)
+		require.GreaterOrEqual(t, elapsed, want, path)
+		require.Less(t, elapsed, want+40*time.Millisecond, path)
+	}
+}
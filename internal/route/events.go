@@ -0,0 +1,115 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package route
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/test-server/internal/config"
+)
+
+// event is a parsed config.Event.
+type event struct {
+	config.Event
+	retry, delay time.Duration
+}
+
+func parseEvents(events []config.Event) ([]event, error) {
+	out := make([]event, len(events))
+	ids := map[string]bool{}
+	for i, e := range events {
+		out[i].Event = e
+		for _, f := range []struct {
+			name, value string
+			d           *time.Duration
+		}{{"retry", e.Retry, &out[i].retry}, {"delay", e.Delay, &out[i].delay}} {
+			if f.value == "" {
+				continue
+			}
+			d, err := time.ParseDuration(f.value)
+			if err != nil {
+				return nil, fmt.Errorf("[%d].%s: %w", i, f.name, err)
+			}
+			if d < 0 {
+				return nil, fmt.Errorf("[%d].%s: must not be negative", i, f.name)
+			}
+			*f.d = d
+		}
+		if strings.ContainsAny(e.ID+e.Event, "\r\n") {
+			return nil, fmt.Errorf("[%d]: id and event must be single lines", i)
+		}
+		if e.ID != "" && ids[e.ID] {
+			return nil, fmt.Errorf("[%d].id: %q is used twice", i, e.ID)
+		}
+		ids[e.ID] = true
+	}
+	return out, nil
+}
+
+// format returns e in the text/event-stream format.
+func (e *event) format() string {
+	var b strings.Builder
+	if e.ID != "" {
+		fmt.Fprintf(&b, "id: %s\n", e.ID)
+	}
+	if e.Event.Event != "" {
+		fmt.Fprintf(&b, "event: %s\n", e.Event.Event)
+	}
+	if e.Retry != "" {
+		fmt.Fprintf(&b, "retry: %d\n", e.retry.Milliseconds())
+	}
+	for _, line := range strings.Split(strings.ReplaceAll(e.Data, "\r\n", "\n"), "\n") {
+		fmt.Fprintf(&b, "data: %s\n", line)
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+// serveEvents streams events, starting after the one whose ID is the
+// Last-Event-ID of req, if any.
+func serveEvents(w http.ResponseWriter, req *http.Request, events []event) {
+	start := 0
+	if last := req.Header.Get("Last-Event-ID"); last != "" {
+		for i, e := range events {
+			if e.ID == last {
+				start = i + 1
+				break
+			}
+		}
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	for _, e := range events[start:] {
+		time.Sleep(e.delay)
+		if _, err := fmt.Fprint(w, e.format()); err != nil {
+			return
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		if e.Disconnect {
+			fmt.Printf("Disconnecting the event stream of %s %s after event %q\n", req.Method, req.URL.Path, e.ID)
+			// Aborting closes the connection without the end of the body.
+			panic(http.ErrAbortHandler)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package route
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/google/test-server/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvents(t *testing.T) {
+	server := serve(t, config.Route{
+		Path: "/v1/watch",
+		Events: []config.Event{
+			{ID: "1", Event: "delta", Data: "{\"n\": 1}", Retry: "1500ms"},
+			{ID: "2", Data: "line one\nline two", Disconnect: true},
+			{ID: "3", Data: "{\"n\": 3}", Delay: "10ms"},
+		},
+	})
+
+	req, err := http.NewRequest("GET", server.URL+"/v1/watch", nil)
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	r := bufio.NewReader(resp.Body)
+	var lines []string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			// The stream is cut after event 2.
+			require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+			break
+		}
+		lines = append(lines, line)
+	}
+	require.Equal(t, []string{
+		"id: 1\n", "event: delta\n", "retry: 1500\n", "data: {\"n\": 1}\n", "\n",
+		"id: 2\n", "data: line one\n", "data: line two\n", "\n",
+	}, lines)
+
+	// Reconnecting resumes after the last event.
+	resp, body, err := do(t, "GET", server.URL+"/v1/watch", "", "", "Last-Event-ID", "2")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "id: 3\ndata: {\"n\": 3}\n\n", body)
+}
+
+func TestEventsRejectInvalidConfig(t *testing.T) {
+	for _, route := range []config.Route{
+		{Events: []config.Event{{Delay: "soon"}}},
+		{Events: []config.Event{{Retry: "-1s"}}},
+		{Events: []config.Event{{ID: "a\nb"}}},
+		{Events: []config.Event{{ID: "1"}, {ID: "1"}}},
+		{Events: []config.Event{{}}, Response: &config.Response{}},
+	} {
+		_, err := New("example.googleapis.com", []config.Route{route})
+		require.Error(t, err)
+	}
+}
@@ -0,0 +1,168 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package route
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/test-server/internal/config"
+)
+
+// generated answers with a body of deterministic pseudo-random bytes that is
+// computed as it is sent, so that its size is not limited by memory.
+type generated struct {
+	size        int64
+	seed        uint64
+	rate        int64 // bytes per second, or 0 for no limit
+	contentType string
+}
+
+func newGenerated(g *config.Generate) (*generated, error) {
+	size, err := parseSize(g.Size)
+	if err != nil {
+		return nil, fmt.Errorf("size: %w", err)
+	}
+	out := &generated{size: size, seed: uint64(g.Seed), contentType: g.ContentType}
+	if g.Rate != "" {
+		if out.rate, err = parseSize(g.Rate); err != nil {
+			return nil, fmt.Errorf("rate: %w", err)
+		}
+		if out.rate == 0 {
+			return nil, fmt.Errorf("rate: must be positive")
+		}
+	}
+	if out.contentType == "" {
+		out.contentType = "application/octet-stream"
+	}
+	return out, nil
+}
+
+// sizeUnits are the multipliers of the units parseSize accepts.
+var sizeUnits = map[string]int64{
+	"": 1, "B": 1,
+	"KB": 1e3, "MB": 1e6, "GB": 1e9, "TB": 1e12,
+	"KIB": 1 << 10, "MIB": 1 << 20, "GIB": 1 << 30, "TIB": 1 << 40,
+}
+
+// parseSize parses a number of bytes with an optional unit, e.g. 1000, 5GB
+// or 512MiB.
+func parseSize(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	i := strings.IndexFunc(s, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+	if i < 0 {
+		i = len(s)
+	}
+	unit, ok := sizeUnits[strings.ToUpper(strings.TrimSpace(s[i:]))]
+	if !ok {
+		return 0, fmt.Errorf("unknown unit in %q", s)
+	}
+	n, err := strconv.ParseFloat(s[:i], 64)
+	if err != nil || n < 0 || n*float64(unit) > 1<<62 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return int64(n * float64(unit)), nil
+}
+
+// word returns the eight bytes of the body at offset 8*k, the k-th output of
+// a splitmix64 generator, which can be computed for any k without the ones
+// before it.
+func (g *generated) word(k uint64) uint64 {
+	z := g.seed + (k+1)*0x9e3779b97f4a7c15
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
+// ReadAt reads the body at off.
+func (g *generated) ReadAt(p []byte, off int64) (int, error) {
+	if off >= g.size {
+		return 0, io.EOF
+	}
+	if left := g.size - off; int64(len(p)) > left {
+		p = p[:left]
+	}
+	for i := 0; i < len(p); {
+		pos := off + int64(i)
+		w := g.word(uint64(pos / 8))
+		for b := pos % 8; b < 8 && i < len(p); b++ {
+			p[i] = byte(w >> (8 * b))
+			i++
+		}
+	}
+	if off+int64(len(p)) == g.size {
+		return len(p), io.EOF
+	}
+	return len(p), nil
+}
+
+func (g *generated) serve(w http.ResponseWriter, req *http.Request) {
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", g.contentType)
+	}
+	// The body only depends on the seed and the size, so that If-Range
+	// can resume a download.
+	w.Header().Set("ETag", fmt.Sprintf(`"%x-%x"`, g.seed, g.size))
+	if g.rate > 0 {
+		w = &throttlingWriter{ResponseWriter: w, rate: g.rate}
+	}
+	// ServeContent sets the Content-Length and answers ranges.
+	http.ServeContent(w, req, "", time.Time{}, io.NewSectionReader(g, 0, g.size))
+}
+
+// throttlingWriter sends at most rate bytes per second, flushing as it goes
+// so that the client sees the progress.
+type throttlingWriter struct {
+	http.ResponseWriter
+	rate  int64
+	start time.Time
+	sent  int64
+}
+
+func (t *throttlingWriter) Write(b []byte) (int, error) {
+	if t.start.IsZero() {
+		t.start = time.Now()
+	}
+	// A tenth of a second at a time keeps the pace smooth.
+	step := max(t.rate/10, 1)
+	written := 0
+	for len(b) > 0 {
+		n := min(int64(len(b)), step)
+		m, err := t.ResponseWriter.Write(b[:n])
+		written += m
+		if err != nil {
+			return written, err
+		}
+		if f, ok := t.ResponseWriter.(http.Flusher); ok {
+			f.Flush()
+		}
+		t.sent += n
+		b = b[n:]
+		time.Sleep(time.Until(t.start.Add(time.Duration(float64(t.sent) / float64(t.rate) * float64(time.Second)))))
+	}
+	return written, nil
+}
+
+func (t *throttlingWriter) Flush() {
+	if f, ok := t.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
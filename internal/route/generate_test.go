@@ -0,0 +1,105 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package route
+
+import (
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/test-server/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSize(t *testing.T) {
+	for s, want := range map[string]int64{"1000": 1000, "1KB": 1000, "1.5 MB": 1500000, "5GB": 5e9, "512MiB": 512 << 20, "2kib": 2048, "0": 0} {
+		got, err := parseSize(s)
+		require.NoError(t, err, s)
+		require.Equal(t, want, got, s)
+	}
+	for _, s := range []string{"", "MB", "5 parsecs", "-1", "1e30TB"} {
+		_, err := parseSize(s)
+		require.Error(t, err, s)
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	server := serve(t,
+		config.Route{Path: "/small", Generate: &config.Generate{Size: "1MiB", Seed: 42}},
+		config.Route{Path: "/other", Generate: &config.Generate{Size: "1MiB", Seed: 7, ContentType: "video/mp4"}},
+		config.Route{Path: "/huge", Generate: &config.Generate{Size: "5GB"}},
+		config.Route{Path: "/slow", Generate: &config.Generate{Size: "2000", Rate: "4KB"}},
+	)
+
+	resp, body, err := get(t, server.URL+"/small")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, int64(1<<20), resp.ContentLength)
+	require.Equal(t, "application/octet-stream", resp.Header.Get("Content-Type"))
+	require.Len(t, body, 1<<20)
+	_, again, err := get(t, server.URL+"/small")
+	require.NoError(t, err)
+	require.Equal(t, body, again)
+	resp, other, err := get(t, server.URL+"/other")
+	require.NoError(t, err)
+	require.Equal(t, "video/mp4", resp.Header.Get("Content-Type"))
+	require.NotEqual(t, body, other)
+
+	// Any part can be read on its own.
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/small", nil)
+	require.NoError(t, err)
+	req.Header.Set("Range", "bytes=1001-2002")
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	part, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	require.Equal(t, http.StatusPartialContent, resp.StatusCode)
+	require.Equal(t, "bytes 1001-2002/1048576", resp.Header.Get("Content-Range"))
+	require.Equal(t, body[1001:2003], string(part))
+
+	// The size is not limited by memory.
+	resp, err = http.Head(server.URL + "/huge")
+	require.NoError(t, err)
+	require.Equal(t, int64(5e9), resp.ContentLength)
+	req, err = http.NewRequest(http.MethodGet, server.URL+"/huge", nil)
+	require.NoError(t, err)
+	req.Header.Set("Range", "bytes=-16")
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, "bytes 4999999984-4999999999/5000000000", resp.Header.Get("Content-Range"))
+
+	start := time.Now()
+	_, body, err = get(t, server.URL+"/slow")
+	require.NoError(t, err)
+	require.Len(t, body, 2000)
+	require.GreaterOrEqual(t, time.Since(start), 400*time.Millisecond)
+}
+
+func TestGenerateRejectsInvalidConfig(t *testing.T) {
+	for _, r := range []config.Route{
+		{Generate: &config.Generate{Size: "big"}},
+		{Generate: &config.Generate{Size: "1MB", Rate: "0"}},
+		{Generate: &config.Generate{Size: "1MB"}, Ranges: true},
+		{Generate: &config.Generate{Size: "1MB"}, Response: &config.Response{}},
+	} {
+		_, err := New("example.googleapis.com", []config.Route{r})
+		require.Error(t, err)
+	}
+}
@@ -0,0 +1,431 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package route
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/test-server/internal/config"
+	"github.com/google/test-server/internal/match"
+	"github.com/google/test-server/internal/redact"
+	"gopkg.in/yaml.v2"
+)
+
+// JournalPath is the path of the admin calls that list, count and verify
+// the requests the server received, e.g. POST /__admin/requests/verify.
+const JournalPath = "/__admin/requests"
+
+// maxJournalBody is the part of a request body the journal keeps.
+const maxJournalBody = 64 << 10
+
+// DefaultJournalSize is the number of requests the journal of an endpoint
+// keeps by default.
+const DefaultJournalSize = 1000
+
+// journalSize is the number of requests the journals keep; see
+// SetJournalSize.
+var journalSize = DefaultJournalSize
+
+// SetJournalSize makes the journals of the endpoints and sessions created
+// later keep the last size requests, dropping the oldest ones, or none if
+// size is 0.
+func SetJournalSize(size int) {
+	journalSize = max(size, 0)
+}
+
+// Entry is a request in the journal.
+type Entry struct {
+	// Seq orders the requests of the endpoint, from 1 after the journal is
+	// cleared. It keeps counting the requests the journal dropped.
+	Seq      int               `json:"seq"`
+	Time     time.Time         `json:"time"`
+	Endpoint string            `json:"endpoint"`
+	Method   string            `json:"method"`
+	URL      string            `json:"url"`
+	Headers  map[string]string `json:"headers,omitempty"`
+	Body     string            `json:"body,omitempty"`
+	// Truncated is set if Body is only the start of the body.
+	Truncated bool `json:"truncated,omitempty"`
+	// Route is the name of the route that answered the request, if any.
+	Route string `json:"route,omitempty"`
+	// Violations are how the request and its response violate the OpenAPI
+	// document of the endpoint, if it has one.
+	Violations []string `json:"violations,omitempty"`
+
+	path string
+}
+
+// key is what the summary counts e under.
+func (e *Entry) key() string {
+	if e.Route != "" {
+		return fmt.Sprintf("%s route %s", e.Endpoint, e.Route)
+	}
+	return fmt.Sprintf("%s %s %s", e.Endpoint, e.Method, e.path)
+}
+
+// journal holds the last requests of an endpoint, or of a session, in the
+// order they came in, with the secrets of the redactor redacted and the
+// redact_request_headers of their endpoint masked.
+type journal struct {
+	mu sync.Mutex
+	// entries is a ring of up to size entries, the oldest at start.
+	entries []*Entry
+	start   int
+	size    int
+	seq     int
+	// dropped counts the entries the ring overwrote, and counts the
+	// requests of the summary, dropped or not, by key.
+	dropped  int
+	counts   map[string]int
+	redactor *redact.Redact
+}
+
+// newJournal returns a journal keeping the number of requests of
+// SetJournalSize, redacting the secrets of redactor.
+func newJournal(redactor *redact.Redact) *journal {
+	return &journal{size: journalSize, counts: map[string]int{}, redactor: redactor}
+}
+
+// add records req, keeping the start of its body and putting the body back
+// for the handler.
+func (j *journal) add(r *Router, req *http.Request, now time.Time) *Entry {
+	e := &Entry{
+		Time:     now,
+		Endpoint: r.service,
+		Method:   req.Method,
+		URL:      j.redactor.String(req.URL.String()),
+		Headers:  map[string]string{},
+		path:     req.URL.Path,
+	}
+	for name, values := range req.Header {
+		e.Headers[name] = strings.Join(values, ", ")
+	}
+	for _, name := range r.redactHeaders {
+		if _, ok := e.Headers[http.CanonicalHeaderKey(name)]; ok {
+			e.Headers[http.CanonicalHeaderKey(name)] = redact.REDACTED
+		}
+	}
+	j.redactor.Headers(e.Headers)
+	if req.Body != nil {
+		// A read error leaves the body short, which the handler then runs
+		// into as well.
+		head, _ := io.ReadAll(io.LimitReader(req.Body, maxJournalBody+1))
+		req.Body = readCloser{io.MultiReader(bytes.NewReader(head), req.Body), req.Body}
+		e.Truncated = len(head) > maxJournalBody
+		e.Body = j.redactor.String(string(head[:min(len(head), maxJournalBody)]))
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.seq++
+	e.Seq = j.seq
+	j.counts[e.key()]++
+	switch {
+	case j.size == 0:
+		j.dropped++
+	case len(j.entries) < j.size:
+		j.entries = append(j.entries, e)
+	default:
+		j.entries[j.start] = e
+		j.start = (j.start + 1) % j.size
+		j.dropped++
+	}
+	return e
+}
+
+// setRoute names the route that answered e.
+func (j *journal) setRoute(e *Entry, name string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.counts[e.key()]--
+	e.Route = name
+	j.counts[e.key()]++
+}
+
+// addViolations adds violations to e.
+func (j *journal) addViolations(e *Entry, violations []string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	e.Violations = append(e.Violations, violations...)
+}
+
+// list returns a copy of the entries for which keep returns true, oldest
+// first.
+func (j *journal) list(keep func(*Entry) bool) []Entry {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	out := []Entry{}
+	for i := range j.entries {
+		if e := j.entries[(j.start+i)%len(j.entries)]; keep(e) {
+			out = append(out, *e)
+		}
+	}
+	return out
+}
+
+// reset clears the journal and returns the number of requests it held.
+func (j *journal) reset() int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	n := len(j.entries)
+	j.entries, j.start, j.seq, j.dropped = nil, 0, 0, 0
+	j.counts = map[string]int{}
+	return n
+}
+
+// summary adds the requests of the journal, dropped or not, to counts.
+func (j *journal) summary(counts map[string]int) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for key, n := range j.counts {
+		if n > 0 {
+			counts[key] += n
+		}
+	}
+}
+
+// readCloser reads from a replacement reader and closes the original body.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// RequestPattern selects requests in the journal. Every field that is set
+// must match.
+type RequestPattern struct {
+	Method string `yaml:"method"`
+	// Path is a pattern like the path of a route, e.g. /v1/models/*.
+	Path string `yaml:"path"`
+	// Route is the name of the route that answered the request.
+	Route    string `yaml:"route"`
+	Endpoint string `yaml:"endpoint"`
+	// Headers are values the headers must have.
+	Headers map[string]string `yaml:"headers"`
+	// Body is a predicate on the body like the body of a route.
+	Body *config.BodyMatch `yaml:"body"`
+	// Violations selects the requests that violated the OpenAPI document
+	// of their endpoint, or with false the ones that did not.
+	Violations *bool `yaml:"violations"`
+}
+
+// compiledPattern is a RequestPattern with its parsed parts.
+type compiledPattern struct {
+	RequestPattern
+	path *regexp.Regexp
+	body *match.Predicate
+}
+
+func compilePattern(p RequestPattern) (*compiledPattern, error) {
+	c := &compiledPattern{RequestPattern: p}
+	var err error
+	if p.Path != "" {
+		if c.path, err = compilePath(p.Path); err != nil {
+			return nil, fmt.Errorf("path: %w", err)
+		}
+	}
+	if p.Body != nil {
+		if c.body, err = match.NewPredicate(p.Body); err != nil {
+			return nil, fmt.Errorf("body: %w", err)
+		}
+	}
+	return c, nil
+}
+
+func (p *compiledPattern) matches(e *Entry) bool {
+	if p.Method != "" && !strings.EqualFold(p.Method, e.Method) ||
+		p.Route != "" && p.Route != e.Route ||
+		p.Endpoint != "" && p.Endpoint != e.Endpoint ||
+		p.path != nil && !p.path.MatchString(e.path) ||
+		p.Violations != nil && *p.Violations != (len(e.Violations) > 0) {
+		return false
+	}
+	headers := http.Header{}
+	for name, value := range e.Headers {
+		headers.Set(name, value)
+	}
+	for name, value := range p.Headers {
+		if headers.Get(name) != value {
+			return false
+		}
+	}
+	return p.body == nil || p.body.Test([]byte(e.Body))
+}
+
+// verification is the body of POST /__admin/requests/verify: a pattern
+// with the number of requests it must match, or a sequence of patterns the
+// requests must match in order.
+type verification struct {
+	RequestPattern `yaml:",inline"`
+	// Count is the exact number of matching requests, and AtLeast and
+	// AtMost bound it. Without any of them, at least one must match.
+	Count   *int `yaml:"count"`
+	AtLeast *int `yaml:"at_least"`
+	AtMost  *int `yaml:"at_most"`
+	// Sequence lists patterns that requests must match one after the
+	// other, with any other requests in between.
+	Sequence []RequestPattern `yaml:"sequence"`
+}
+
+// serve answers the calls under JournalPath:
+//
+//	GET    /__admin/requests         lists the requests
+//	DELETE /__admin/requests         clears the journal
+//	POST   /__admin/requests/find    lists the requests matching a pattern
+//	POST   /__admin/requests/count   counts them
+//	POST   /__admin/requests/verify  checks a count or an order
+func (j *journal) serve(w http.ResponseWriter, req *http.Request) {
+	action := strings.TrimPrefix(strings.TrimPrefix(req.URL.Path, JournalPath), "/")
+	switch {
+	case action == "" && req.Method == http.MethodGet:
+		entries := j.list(func(*Entry) bool { return true })
+		j.mu.Lock()
+		dropped := j.dropped
+		j.mu.Unlock()
+		writeJSON(w, http.StatusOK, map[string]any{"requests": entries, "dropped": dropped})
+		return
+	case action == "" && req.Method == http.MethodDelete:
+		writeJSON(w, http.StatusOK, map[string]any{"cleared": j.reset()})
+		return
+	case action == "":
+		w.Header().Set("Allow", "GET, DELETE")
+		writeError(w, http.StatusMethodNotAllowed, "INVALID_ARGUMENT", "The journal is read with GET and cleared with DELETE")
+		return
+	case action != "find" && action != "count" && action != "verify":
+		writeError(w, http.StatusNotFound, "NOT_FOUND", fmt.Sprintf("Unknown journal call %q", action))
+		return
+	case req.Method != http.MethodPost:
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, "INVALID_ARGUMENT", fmt.Sprintf("%s is called with POST", action))
+		return
+	}
+
+	// The patterns are written like routes; JSON is valid YAML.
+	data, err := io.ReadAll(req.Body)
+	var v verification
+	if err == nil {
+		err = yaml.UnmarshalStrict(data, &v)
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_ARGUMENT", fmt.Sprintf("Invalid request pattern: %v", err))
+		return
+	}
+	if v.Sequence != nil && action == "verify" {
+		j.verifySequence(w, v.Sequence)
+		return
+	}
+	p, err := compilePattern(v.RequestPattern)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_ARGUMENT", fmt.Sprintf("Invalid request pattern: %v", err))
+		return
+	}
+	found := j.list(p.matches)
+	switch action {
+	case "find":
+		writeJSON(w, http.StatusOK, map[string]any{"requests": found})
+	case "count":
+		writeJSON(w, http.StatusOK, map[string]any{"count": len(found)})
+	case "verify":
+		n := len(found)
+		var failure string
+		switch {
+		case v.Count != nil && n != *v.Count:
+			failure = fmt.Sprintf("expected %d matching requests, got %d", *v.Count, n)
+		case v.AtLeast != nil && n < *v.AtLeast:
+			failure = fmt.Sprintf("expected at least %d matching requests, got %d", *v.AtLeast, n)
+		case v.AtMost != nil && n > *v.AtMost:
+			failure = fmt.Sprintf("expected at most %d matching requests, got %d", *v.AtMost, n)
+		case v.Count == nil && v.AtLeast == nil && v.AtMost == nil && n == 0:
+			failure = "expected a matching request, got none"
+		}
+		if failure != "" {
+			writeJSON(w, http.StatusExpectationFailed, map[string]any{"ok": false, "count": n, "message": failure, "requests": found})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"ok": true, "count": n})
+	}
+}
+
+// verifySequence checks that requests matched patterns in order.
+func (j *journal) verifySequence(w http.ResponseWriter, patterns []RequestPattern) {
+	compiled := make([]*compiledPattern, len(patterns))
+	for i, p := range patterns {
+		c, err := compilePattern(p)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "INVALID_ARGUMENT", fmt.Sprintf("Invalid request pattern sequence[%d]: %v", i, err))
+			return
+		}
+		compiled[i] = c
+	}
+	var matched []int
+	next := 0
+	j.list(func(e *Entry) bool {
+		if next < len(compiled) && compiled[next].matches(e) {
+			matched = append(matched, e.Seq)
+			next++
+		}
+		return false
+	})
+	if next < len(compiled) {
+		writeJSON(w, http.StatusExpectationFailed, map[string]any{
+			"ok":      false,
+			"matched": matched,
+			"message": fmt.Sprintf("no request matched sequence[%d] after the ones matching the patterns before it", next),
+		})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true, "matched": matched})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// WriteSummary writes how many requests each route and each other method
+// and path of the endpoints received, for the end of a run. It counts the
+// requests the journals dropped too, but not the ones of sessions.
+func WriteSummary(out io.Writer) {
+	counts := map[string]int{}
+	seeding.mu.Lock()
+	routers := seeding.routers
+	seeding.mu.Unlock()
+	for _, r := range routers {
+		r.journal.summary(counts)
+	}
+	if len(counts) == 0 {
+		fmt.Fprintln(out, "No requests were received.")
+		return
+	}
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	fmt.Fprintln(out, "Requests received:")
+	for _, k := range keys {
+		fmt.Fprintf(out, "  %6d  %s\n", counts[k], k)
+	}
+}
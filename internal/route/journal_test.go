@@ -0,0 +1,204 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package route
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/test-server/internal/config"
+	"github.com/google/test-server/internal/redact"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJournal(t *testing.T) {
+	// The summary counts the requests of this test only.
+	seeding.mu.Lock()
+	routers := seeding.routers
+	seeding.routers = nil
+	seeding.mu.Unlock()
+	t.Cleanup(func() {
+		seeding.mu.Lock()
+		defer seeding.mu.Unlock()
+		seeding.routers = append(routers, seeding.routers...)
+	})
+	handler, err := Handler(&config.EndpointConfig{
+		TargetHost: "example.googleapis.com",
+		Routes: []config.Route{
+			{Name: "generate", Method: "POST", Path: "/v1/models/{model}:generateContent", Response: &config.Response{Body: `{}`}},
+			{Path: "/v1/files", Response: &config.Response{Body: `[]`}},
+		},
+	}, nil, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("proxied"))
+	}))
+	require.NoError(t, err)
+	send := func(method, target, body string, headers ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		for i := 0; i < len(headers); i += 2 {
+			req.Header.Set(headers[i], headers[i+1])
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+	decode := func(rec *httptest.ResponseRecorder) map[string]any {
+		var v map[string]any
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &v), rec.Body.String())
+		return v
+	}
+
+	send("GET", "/v1/files", "")
+	send("POST", "/v1/models/gemini:generateContent", `{"prompt": "hi"}`)
+	send("POST", "/v1/models/gemini:generateContent", `{"prompt": "hi"}`, "X-Retry-Attempt", "1")
+	// The handler still gets the whole body.
+	require.Equal(t, "proxied", send("POST", "/v1/other", "data").Body.String())
+
+	rec := send("GET", JournalPath, "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	entries := decode(rec)["requests"].([]any)
+	require.Len(t, entries, 4)
+	first := entries[1].(map[string]any)
+	require.Equal(t, float64(2), first["seq"])
+	require.Equal(t, "generate", first["route"])
+	require.Equal(t, "example.googleapis.com", first["endpoint"])
+	require.Equal(t, `{"prompt": "hi"}`, first["body"])
+	require.Equal(t, "routes[1]", entries[0].(map[string]any)["route"])
+	require.Nil(t, entries[3].(map[string]any)["route"])
+
+	count := func(pattern string) float64 {
+		rec := send("POST", JournalPath+"/count", pattern)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		return decode(rec)["count"].(float64)
+	}
+	require.Equal(t, float64(2), count(`{"route": "generate"}`))
+	require.Equal(t, float64(2), count(`{"method": "post", "path": "/v1/models/*"}`))
+	require.Equal(t, float64(1), count(`{"route": "generate", "headers": {"x-retry-attempt": "1"}}`))
+	require.Equal(t, float64(2), count(`{"body": {"json_path": "$.prompt", "equals": "hi"}}`))
+	require.Equal(t, float64(4), count(``))
+
+	rec = send("POST", JournalPath+"/find", `{"path": "/v1/files"}`)
+	require.Len(t, decode(rec)["requests"], 1)
+
+	// Exactly one retry happened.
+	require.Equal(t, http.StatusOK, send("POST", JournalPath+"/verify", `{"route": "generate", "count": 2}`).Code)
+	rec = send("POST", JournalPath+"/verify", `{"route": "generate", "count": 3}`)
+	require.Equal(t, http.StatusExpectationFailed, rec.Code)
+	require.Equal(t, "expected 3 matching requests, got 2", decode(rec)["message"])
+	require.Equal(t, http.StatusOK, send("POST", JournalPath+"/verify", `{"path": "/v1/files", "at_least": 1, "at_most": 1}`).Code)
+	require.Equal(t, http.StatusExpectationFailed, send("POST", JournalPath+"/verify", `{"path": "/v1/nothing"}`).Code)
+
+	rec = send("POST", JournalPath+"/verify", `{"sequence": [{"path": "/v1/files"}, {"route": "generate"}, {"headers": {"X-Retry-Attempt": "1"}}]}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.Equal(t, []any{float64(1), float64(2), float64(3)}, decode(rec)["matched"])
+	rec = send("POST", JournalPath+"/verify", `{"sequence": [{"route": "generate"}, {"path": "/v1/files"}]}`)
+	require.Equal(t, http.StatusExpectationFailed, rec.Code)
+
+	require.Equal(t, http.StatusBadRequest, send("POST", JournalPath+"/count", `{"colour": "red"}`).Code)
+	require.Equal(t, http.StatusBadRequest, send("POST", JournalPath+"/count", `{"path": "/{a}/{a}"}`).Code)
+	require.Equal(t, http.StatusNotFound, send("POST", JournalPath+"/explain", ``).Code)
+	require.Equal(t, http.StatusMethodNotAllowed, send("GET", JournalPath+"/count", ``).Code)
+
+	var summary bytes.Buffer
+	WriteSummary(&summary)
+	require.Equal(t, "Requests received:\n"+
+		"       1  example.googleapis.com POST /v1/other\n"+
+		"       2  example.googleapis.com route generate\n"+
+		"       1  example.googleapis.com route routes[1]\n", summary.String())
+
+	require.Equal(t, float64(4), decode(send("DELETE", JournalPath, ""))["cleared"])
+	require.Equal(t, float64(0), count(``))
+	summary.Reset()
+	WriteSummary(&summary)
+	require.Equal(t, "No requests were received.\n", summary.String())
+}
+
+func TestJournalRing(t *testing.T) {
+	SetJournalSize(2)
+	t.Cleanup(func() { SetJournalSize(DefaultJournalSize) })
+	redactor, err := redact.NewRedact([]string{"sk-secret"})
+	require.NoError(t, err)
+	handler, err := Handler(&config.EndpointConfig{TargetHost: "example.googleapis.com"}, redactor, http.NotFoundHandler())
+	require.NoError(t, err)
+	send := func(method, target, body string) map[string]any {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer sk-secret")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		var v map[string]any
+		json.Unmarshal(rec.Body.Bytes(), &v)
+		return v
+	}
+
+	send("POST", "/v1/first", "")
+	send("POST", "/v1/second?key=sk-secret", `{"key": "sk-secret"}`)
+	send("POST", "/v1/third", strings.Repeat("a", maxJournalBody+1))
+
+	// The oldest request is dropped, and the secrets are redacted.
+	journal := send("GET", JournalPath, "")
+	require.Equal(t, float64(1), journal["dropped"])
+	entries := journal["requests"].([]any)
+	require.Len(t, entries, 2)
+	second, third := entries[0].(map[string]any), entries[1].(map[string]any)
+	require.Equal(t, float64(2), second["seq"])
+	require.Equal(t, "/v1/second?key=REDACTED", second["url"])
+	require.Equal(t, `{"key": "REDACTED"}`, second["body"])
+	require.Equal(t, "Bearer REDACTED", second["headers"].(map[string]any)["Authorization"])
+	require.Equal(t, float64(3), third["seq"])
+	require.Equal(t, true, third["truncated"])
+	require.Len(t, third["body"], maxJournalBody)
+}
+
+func TestJournalRedactsHeaders(t *testing.T) {
+	t.Cleanup(func() { deleteSessions("") })
+	handler, err := Handler(&config.EndpointConfig{
+		TargetHost:           "generativelanguage.googleapis.com",
+		RedactRequestHeaders: []string{"X-Goog-Api-Key", "authorization"},
+	}, nil, http.NotFoundHandler())
+	require.NoError(t, err)
+	send := func(method, target, body string, headers ...string) map[string]any {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("X-Goog-Api-Key", "AIza-key")
+		req.Header.Set("Authorization", "Bearer token")
+		req.Header.Set("User-Agent", "sdk")
+		for i := 0; i < len(headers); i += 2 {
+			req.Header.Set(headers[i], headers[i+1])
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		var v map[string]any
+		json.Unmarshal(rec.Body.Bytes(), &v)
+		return v
+	}
+	send("POST", SessionsPath, `{"id": "redacted"}`)
+	send("GET", "/v1/models", "")
+	send("GET", "/v1/models", "", SessionHeader, "redacted")
+
+	// The redact_request_headers of the endpoint are masked in its journal
+	// and in the ones of the sessions.
+	for _, headers := range [][]string{nil, {SessionHeader, "redacted"}} {
+		entries := send("GET", JournalPath, "", headers...)["requests"].([]any)
+		require.NotEmpty(t, entries)
+		got := entries[len(entries)-1].(map[string]any)["headers"].(map[string]any)
+		require.Equal(t, redact.REDACTED, got["X-Goog-Api-Key"])
+		require.Equal(t, redact.REDACTED, got["Authorization"])
+		require.Equal(t, "sdk", got["User-Agent"])
+	}
+}
@@ -0,0 +1,176 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package route
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/test-server/internal/config"
+)
+
+// longPoll is a parsed config.LongPoll.
+type longPoll struct {
+	hold    time.Duration
+	trigger string
+	// timeout answers the requests that were not triggered, if set.
+	timeout *stub
+}
+
+// triggerRe limits trigger names to what fits in a path segment.
+var triggerRe = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+func (r *Router) newLongPoll(c *config.LongPoll) (*longPoll, error) {
+	lp := &longPoll{trigger: c.Trigger}
+	if c.Hold != "" {
+		var err error
+		if lp.hold, err = time.ParseDuration(c.Hold); err != nil {
+			return nil, fmt.Errorf("hold: %w", err)
+		}
+		if lp.hold <= 0 {
+			return nil, fmt.Errorf("hold: must be positive")
+		}
+	}
+	if lp.trigger != "" && !triggerRe.MatchString(lp.trigger) {
+		return nil, fmt.Errorf("trigger: invalid name %q", lp.trigger)
+	}
+	if lp.hold == 0 && lp.trigger == "" {
+		return nil, fmt.Errorf("set hold, trigger or both")
+	}
+	if c.TimeoutResponse != nil {
+		if lp.hold == 0 {
+			return nil, fmt.Errorf("timeout_response needs hold")
+		}
+		var err error
+		if lp.timeout, err = r.newStub(c.TimeoutResponse); err != nil {
+			return nil, fmt.Errorf("timeout_response: %w", err)
+		}
+	}
+	return lp, nil
+}
+
+// triggers releases the requests held by long poll routes. A trigger fired
+// while no request waits for it releases the next one.
+type triggers struct {
+	mu      sync.Mutex
+	waiting map[string][]chan struct{}
+	pending map[string]bool
+}
+
+// wait returns a channel that is closed when name fires.
+func (t *triggers) wait(name string) chan struct{} {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	ch := make(chan struct{})
+	if t.pending[name] {
+		delete(t.pending, name)
+		close(ch)
+		return ch
+	}
+	if t.waiting == nil {
+		t.waiting = map[string][]chan struct{}{}
+	}
+	t.waiting[name] = append(t.waiting[name], ch)
+	return ch
+}
+
+// stop stops ch from waiting for name.
+func (t *triggers) stop(name string, ch chan struct{}) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	chans := t.waiting[name]
+	for i, c := range chans {
+		if c == ch {
+			t.waiting[name] = append(chans[:i:i], chans[i+1:]...)
+			return
+		}
+	}
+}
+
+// fire releases the requests waiting for name and returns their number.
+func (t *triggers) fire(name string) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	chans := t.waiting[name]
+	delete(t.waiting, name)
+	for _, ch := range chans {
+		close(ch)
+	}
+	if len(chans) == 0 {
+		if t.pending == nil {
+			t.pending = map[string]bool{}
+		}
+		t.pending[name] = true
+	}
+	return len(chans)
+}
+
+// hold holds req as lp says. It reports whether a trigger released it, and
+// false for ok if the client went away.
+func (r *Router) hold(req *http.Request, lp *longPoll) (triggered, ok bool) {
+	var fired chan struct{}
+	if lp.trigger != "" {
+		fired = r.triggers.wait(lp.trigger)
+		defer r.triggers.stop(lp.trigger, fired)
+	}
+	var timeout <-chan time.Time
+	if lp.hold > 0 {
+		timer := time.NewTimer(lp.hold)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case <-fired:
+		return true, true
+	case <-timeout:
+		return false, true
+	case <-req.Context().Done():
+		return false, false
+	}
+}
+
+// TriggerPath is the path prefix of the admin calls that fire the triggers
+// of long poll routes, e.g. POST /__admin/triggers/job-done.
+const TriggerPath = "/__admin/triggers/"
+
+// serveTrigger fires the trigger named by the path of req.
+func (r *Router) serveTrigger(w http.ResponseWriter, req *http.Request) {
+	name := strings.TrimPrefix(req.URL.Path, TriggerPath)
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, "INVALID_ARGUMENT", "Triggers are fired with POST")
+		return
+	}
+	known := false
+	r.routesMu.RLock()
+	for _, route := range r.routes {
+		known = known || route.longPoll != nil && route.longPoll.trigger == name
+	}
+	r.routesMu.RUnlock()
+	if !known {
+		writeError(w, http.StatusNotFound, "NOT_FOUND", fmt.Sprintf("No route has the trigger %q", name))
+		return
+	}
+	released := r.triggers.fire(name)
+	fmt.Printf("Fired the trigger %s, releasing %d requests\n", name, released)
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprintf(w, "{\"released\": %d}\n", released)
+}
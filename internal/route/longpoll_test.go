@@ -0,0 +1,95 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package route
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/test-server/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLongPoll(t *testing.T) {
+	server := serve(t,
+		config.Route{
+			Path:     "/v1/operations/short:wait",
+			LongPoll: &config.LongPoll{Hold: "50ms", TimeoutResponse: &config.Response{Body: `{"done": false}`}},
+			Response: &config.Response{Body: `{"done": true}`},
+		},
+		config.Route{
+			Path:     "/v1/operations/long:wait",
+			LongPoll: &config.LongPoll{Hold: "10s", Trigger: "op-done", TimeoutResponse: &config.Response{Body: `{"done": false}`}},
+			Response: &config.Response{Body: `{"done": true}`},
+		},
+	)
+
+	start := time.Now()
+	_, body, err := get(t, server.URL+"/v1/operations/short:wait")
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+	require.Equal(t, `{"done": false}`, body)
+
+	// The trigger answers the waiting request at once.
+	done := make(chan string)
+	go func() {
+		_, body, _ := get(t, server.URL+"/v1/operations/long:wait")
+		done <- body
+	}()
+	time.Sleep(50 * time.Millisecond)
+	resp, _, err := do(t, "POST", server.URL+"/__admin/triggers/op-done", "", "")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	select {
+	case body := <-done:
+		require.Equal(t, `{"done": true}`, body)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the trigger did not release the request")
+	}
+
+	// A trigger without waiting requests releases the next one.
+	_, body, err = do(t, "POST", server.URL+"/__admin/triggers/op-done", "", "")
+	require.NoError(t, err)
+	require.JSONEq(t, `{"released": 0}`, body)
+	start = time.Now()
+	_, body, err = get(t, server.URL+"/v1/operations/long:wait")
+	require.NoError(t, err)
+	require.Less(t, time.Since(start), 5*time.Second)
+	require.Equal(t, `{"done": true}`, body)
+
+	resp, _, err = do(t, "POST", server.URL+"/__admin/triggers/unknown", "", "")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	resp, _, err = get(t, server.URL+"/__admin/triggers/op-done")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+}
+
+func TestLongPollRejectsInvalidConfig(t *testing.T) {
+	for _, lp := range []*config.LongPoll{
+		{},
+		{Hold: "forever"},
+		{Hold: "0s", Trigger: "a"},
+		{Trigger: "a/b"},
+		{Trigger: "a", TimeoutResponse: &config.Response{}},
+		{Hold: "1s", TimeoutResponse: &config.Response{Status: 1}},
+	} {
+		_, err := New("example.googleapis.com", []config.Route{{LongPoll: lp}})
+		require.Error(t, err)
+	}
+}
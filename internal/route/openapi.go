@@ -0,0 +1,140 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package route
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/google/test-server/internal/config"
+	"github.com/google/test-server/internal/openapi"
+)
+
+// strictViolations counts the requests that violated the OpenAPI document of
+// an endpoint with strict validation, or whose response did.
+var strictViolations atomic.Int64
+
+// StrictViolations returns how many requests violated the OpenAPI document
+// of an endpoint with openapi.strict, or got a response violating it.
+func StrictViolations() int64 {
+	return strictViolations.Load()
+}
+
+// validator checks the requests and responses of an endpoint against its
+// OpenAPI document.
+type validator struct {
+	*openapi.Validator
+	strict bool
+}
+
+func newValidator(cfg *config.OpenAPI) (*validator, error) {
+	if cfg.Spec == "" {
+		return nil, fmt.Errorf("spec: the path of the OpenAPI document is required")
+	}
+	doc, err := openapi.Load(cfg.Spec)
+	if err != nil {
+		return nil, fmt.Errorf("spec: %w", err)
+	}
+	v, err := openapi.NewValidator(doc)
+	if err != nil {
+		return nil, fmt.Errorf("spec: %s: %w", cfg.Spec, err)
+	}
+	return &validator{Validator: v, strict: cfg.Strict}, nil
+}
+
+// check checks req, journaled as e, and returns the writer of its response
+// with the function checking the response once it is written.
+func (v *validator) check(w http.ResponseWriter, req *http.Request, e *Entry, j *journal) (http.ResponseWriter, func()) {
+	var body []byte
+	if !e.Truncated {
+		body = []byte(e.Body)
+	}
+	violations := v.Request(req, body)
+	cw := &capturingWriter{ResponseWriter: w}
+	return cw, func() {
+		if !cw.hijacked {
+			var body []byte
+			if !cw.truncated {
+				body = cw.body.Bytes()
+			}
+			status := cw.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			violations = append(violations, v.Response(req, status, cw.Header(), body)...)
+		}
+		if len(violations) == 0 {
+			return
+		}
+		j.addViolations(e, violations)
+		if v.strict {
+			strictViolations.Add(1)
+		}
+		fmt.Printf("%s %s violates the OpenAPI document:\n  %s\n", req.Method, req.URL.Path, strings.Join(violations, "\n  "))
+	}
+}
+
+// capturingWriter keeps the status and the start of the body of a response.
+type capturingWriter struct {
+	http.ResponseWriter
+	status    int
+	body      bytes.Buffer
+	truncated bool
+	hijacked  bool
+}
+
+func (c *capturingWriter) WriteHeader(status int) {
+	if c.status == 0 {
+		c.status = status
+	}
+	c.ResponseWriter.WriteHeader(status)
+}
+
+func (c *capturingWriter) Write(b []byte) (int, error) {
+	if c.status == 0 {
+		c.status = http.StatusOK
+	}
+	if keep := maxJournalBody - c.body.Len(); len(b) > keep {
+		c.body.Write(b[:max(keep, 0)])
+		c.truncated = true
+	} else {
+		c.body.Write(b)
+	}
+	return c.ResponseWriter.Write(b)
+}
+
+func (c *capturingWriter) Flush() {
+	if f, ok := c.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack lets websocket upgrades and reset faults through, leaving the
+// response unchecked.
+func (c *capturingWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := c.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("the response writer cannot be hijacked")
+	}
+	c.hijacked = true
+	return hj.Hijack()
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package route
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/test-server/internal/config"
+	"github.com/google/test-server/internal/openapi"
+	"github.com/stretchr/testify/require"
+)
+
+const petsSpec = `
+openapi: 3.0.3
+servers: [{url: /v1}]
+paths:
+  /pets/{id}:
+    get:
+      parameters:
+        - {name: id, in: path, required: true, schema: {type: integer}}
+      responses:
+        "200":
+          description: A pet.
+          content:
+            application/json:
+              schema:
+                type: object
+                required: [name]
+                properties:
+                  name: {type: string}
+`
+
+func TestOpenAPIValidation(t *testing.T) {
+	spec := filepath.Join(t.TempDir(), "pets.yaml")
+	require.NoError(t, os.WriteFile(spec, []byte(petsSpec), 0644))
+	doc, err := openapi.Load(spec)
+	require.NoError(t, err)
+	// The stubs generated from the document follow it.
+	stubs, err := doc.Stubs(doc.BasePath())
+	require.NoError(t, err)
+	cfg := &config.EndpointConfig{
+		TargetHost: "pets.example.com",
+		Routes: append([]config.Route{
+			{Path: "/v1/pets/2", Response: &config.Response{Body: `{"name": 2}`, Headers: map[string]string{"Content-Type": "application/json"}}},
+		}, stubs...),
+		OpenAPI: &config.OpenAPI{Spec: spec, Strict: true},
+	}
+	_, handler, err := build(cfg, http.NotFoundHandler())
+	require.NoError(t, err)
+	send := func(method, target, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return rec
+	}
+
+	before := StrictViolations()
+	require.Equal(t, http.StatusOK, send("GET", "/v1/pets/1", "").Code)
+	require.Equal(t, http.StatusOK, send("GET", "/v1/pets/2", "").Code)
+	require.Equal(t, http.StatusOK, send("GET", "/v1/pets/rex", "").Code)
+	require.Equal(t, http.StatusNotFound, send("GET", "/v1/owners", "").Code)
+	require.Equal(t, before+3, StrictViolations())
+
+	var journaled struct{ Requests []Entry }
+	require.NoError(t, json.Unmarshal(send("GET", JournalPath, "").Body.Bytes(), &journaled))
+	require.Len(t, journaled.Requests, 4)
+	require.Empty(t, journaled.Requests[0].Violations)
+	require.Equal(t, []string{"response body: $.name: expected string, got integer"}, journaled.Requests[1].Violations)
+	require.Equal(t, []string{"request: path parameter id: $: expected integer, got string"}, journaled.Requests[2].Violations)
+	require.Equal(t, []string{"request: no operation of the OpenAPI document matches GET /v1/owners"}, journaled.Requests[3].Violations)
+	rec := send("POST", JournalPath+"/verify", `{"violations": true, "count": 3}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	rec = send("POST", JournalPath+"/count", `{"violations": false}`)
+	require.JSONEq(t, `{"count": 1}`, rec.Body.String())
+
+	cfg.OpenAPI.Spec = filepath.Join(t.TempDir(), "missing.yaml")
+	require.ErrorContains(t, Validate(cfg), "openapi.spec: open ")
+}
@@ -0,0 +1,180 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package route
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/google/test-server/internal/config"
+)
+
+// defaultPageSize is the page size of pages without page_size.
+const defaultPageSize = 10
+
+// defaultPageItem is the item template of pages without item.
+const defaultPageItem = `{"name": "items/{{.Index}}"}`
+
+// pages serves a generated collection a page at a time.
+type pages struct {
+	total, pageSize, maxPageSize int
+	itemsField, token            string
+	item                         *template.Template
+}
+
+// PageItemData is what the item template of pages can use: the data of stub
+// templates and the Index of the item in the collection.
+type PageItemData struct {
+	TemplateData
+	Index int
+}
+
+func (r *Router) newPages(c *config.Pages) (*pages, error) {
+	p := &pages{total: c.Total, pageSize: c.PageSize, maxPageSize: c.MaxPageSize, itemsField: c.ItemsField, token: c.Token}
+	switch {
+	case p.total < 0:
+		return nil, fmt.Errorf("total: must not be negative")
+	case p.pageSize < 0:
+		return nil, fmt.Errorf("page_size: must not be negative")
+	case p.maxPageSize < 0:
+		return nil, fmt.Errorf("max_page_size: must not be negative")
+	}
+	if p.pageSize == 0 {
+		p.pageSize = defaultPageSize
+	}
+	if p.maxPageSize > 0 && p.pageSize > p.maxPageSize {
+		p.pageSize = p.maxPageSize
+	}
+	if p.itemsField == "" {
+		p.itemsField = "items"
+	}
+	switch p.token {
+	case "":
+		p.token = "opaque"
+	case "offset", "page", "opaque":
+	default:
+		return nil, fmt.Errorf("token: unknown format %q", p.token)
+	}
+	item := c.Item
+	if item == "" {
+		item = defaultPageItem
+	}
+	var err error
+	if p.item, err = template.New("item").Option("missingkey=zero").Funcs(r.templateFuncs()).Parse(item); err != nil {
+		return nil, fmt.Errorf("item: %w", err)
+	}
+	return p, nil
+}
+
+// encodeToken returns the token of the page starting at offset.
+func (p *pages) encodeToken(offset, size int) string {
+	switch p.token {
+	case "offset":
+		return strconv.Itoa(offset)
+	case "page":
+		return strconv.Itoa(offset/size + 1)
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte("offset=" + strconv.Itoa(offset)))
+}
+
+// decodeToken returns the offset of the page of token.
+func (p *pages) decodeToken(token string, size int) (int, error) {
+	if token == "" {
+		return 0, nil
+	}
+	var n int
+	var err error
+	switch p.token {
+	case "offset":
+		n, err = strconv.Atoi(token)
+	case "page":
+		if n, err = strconv.Atoi(token); err == nil {
+			if n < 1 {
+				err = fmt.Errorf("no page %d", n)
+			}
+			n = (n - 1) * size
+		}
+	default:
+		var raw []byte
+		if raw, err = base64.RawURLEncoding.DecodeString(token); err == nil {
+			value, ok := strings.CutPrefix(string(raw), "offset=")
+			if !ok {
+				return 0, fmt.Errorf("malformed token")
+			}
+			n, err = strconv.Atoi(value)
+		}
+	}
+	if err == nil && (n < 0 || n > p.total) {
+		err = fmt.Errorf("offset %d is out of range", n)
+	}
+	return n, err
+}
+
+func (p *pages) serve(w http.ResponseWriter, req *http.Request, params map[string]string) {
+	query := req.URL.Query()
+	size := p.pageSize
+	if s := query.Get("pageSize"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, "INVALID_ARGUMENT", fmt.Sprintf("Invalid pageSize %q", s))
+			return
+		}
+		if n > 0 {
+			size = n
+		}
+		if p.maxPageSize > 0 && size > p.maxPageSize {
+			size = p.maxPageSize
+		}
+	}
+	offset, err := p.decodeToken(query.Get("pageToken"), size)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_ARGUMENT", fmt.Sprintf("Invalid pageToken: %v", err))
+		return
+	}
+	data, err := newTemplateData(req, params)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error reading request: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	end := min(offset+size, p.total)
+	items := make([]json.RawMessage, 0, end-offset)
+	for i := offset; i < end; i++ {
+		var b bytes.Buffer
+		if err := p.item.Execute(&b, PageItemData{TemplateData: data, Index: i}); err != nil {
+			http.Error(w, fmt.Sprintf("Error rendering item %d: %v", i, err), http.StatusInternalServerError)
+			return
+		}
+		if !json.Valid(b.Bytes()) {
+			http.Error(w, fmt.Sprintf("Item %d is not valid JSON: %s", i, b.String()), http.StatusInternalServerError)
+			return
+		}
+		items = append(items, b.Bytes())
+	}
+	resp := map[string]any{p.itemsField: items}
+	if end < p.total {
+		resp["nextPageToken"] = p.encodeToken(end, size)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package route
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/google/test-server/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+// listAll follows the page tokens of the collection at target and returns the
+// names of its items and the tokens.
+func listAll(t *testing.T, target string) (names, tokens []string) {
+	t.Helper()
+	token := ""
+	for {
+		u := target
+		if token != "" {
+			u += "&pageToken=" + url.QueryEscape(token)
+		}
+		resp, body, err := get(t, u)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode, body)
+		var page struct {
+			Files []struct {
+				Name string `json:"name"`
+			} `json:"files"`
+			NextPageToken string `json:"nextPageToken"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(body), &page))
+		for _, f := range page.Files {
+			names = append(names, f.Name)
+		}
+		if page.NextPageToken == "" {
+			return names, tokens
+		}
+		token = page.NextPageToken
+		tokens = append(tokens, token)
+	}
+}
+
+func TestPages(t *testing.T) {
+	for _, tc := range []struct {
+		token  string
+		tokens []string
+	}{
+		{"offset", []string{"2", "4"}},
+		{"page", []string{"2", "3"}},
+		{"", []string{"b2Zmc2V0PTI", "b2Zmc2V0PTQ"}},
+	} {
+		t.Run(tc.token, func(t *testing.T) {
+			server := serve(t, config.Route{
+				Path: "/v1/{parent}/files",
+				Pages: &config.Pages{
+					Total:      5,
+					ItemsField: "files",
+					Token:      tc.token,
+					Item:       `{"name": "{{.PathParams.parent}}/files/{{.Index}}"}`,
+				},
+			})
+			names, tokens := listAll(t, server.URL+"/v1/p/files?pageSize=2")
+			require.Equal(t, []string{"p/files/0", "p/files/1", "p/files/2", "p/files/3", "p/files/4"}, names)
+			require.Equal(t, tc.tokens, tokens)
+		})
+	}
+}
+
+func TestPageSizes(t *testing.T) {
+	server := serve(t,
+		config.Route{Path: "/v1/files", Pages: &config.Pages{Total: 25, ItemsField: "files", MaxPageSize: 20}},
+		config.Route{Path: "/v1/empty", Pages: &config.Pages{ItemsField: "files"}},
+	)
+
+	names, tokens := listAll(t, server.URL+"/v1/files?")
+	require.Len(t, names, 25)
+	require.Equal(t, "items/24", names[24])
+	require.Len(t, tokens, 2)
+	_, tokens = listAll(t, server.URL+"/v1/files?pageSize=100")
+	require.Len(t, tokens, 1)
+
+	resp, body, err := get(t, server.URL+"/v1/empty")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.JSONEq(t, `{"files": []}`, body)
+
+	for _, query := range []string{"pageSize=-1", "pageSize=two", "pageToken=bogus", "pageToken=b2Zmc2V0PTk5"} {
+		resp, body, err := get(t, server.URL+"/v1/files?"+query)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode, query)
+		require.Contains(t, body, "INVALID_ARGUMENT")
+	}
+}
+
+func TestPagesRejectInvalidConfig(t *testing.T) {
+	for _, route := range []config.Route{
+		{Pages: &config.Pages{Total: -1}},
+		{Pages: &config.Pages{PageSize: -1}},
+		{Pages: &config.Pages{Token: "cursor"}},
+		{Pages: &config.Pages{Item: "{{.Index"}},
+		{Pages: &config.Pages{}, Response: &config.Response{}},
+	} {
+		_, err := New("example.googleapis.com", []config.Route{route})
+		require.Error(t, err)
+	}
+}
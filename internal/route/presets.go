@@ -0,0 +1,185 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package route
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// Preset is a built-in error of a Google Cloud API, with the error details
+// that client libraries map to their exceptions. {service} in the message and
+// details is replaced by the target host.
+type Preset struct {
+	Code    int
+	Status  string
+	Message string
+	Details []map[string]any
+}
+
+// The type URLs of the error details.
+const (
+	errorInfoType    = "type.googleapis.com/google.rpc.ErrorInfo"
+	quotaFailureType = "type.googleapis.com/google.rpc.QuotaFailure"
+	badRequestType   = "type.googleapis.com/google.rpc.BadRequest"
+	helpType         = "type.googleapis.com/google.rpc.Help"
+	retryInfoType    = "type.googleapis.com/google.rpc.RetryInfo"
+)
+
+// testProject is the project named in the preset errors.
+const testProject = "projects/123456789012"
+
+func errorInfo(reason string, metadata map[string]any) map[string]any {
+	return map[string]any{"@type": errorInfoType, "reason": reason, "domain": "googleapis.com", "metadata": metadata}
+}
+
+func help(description, url string) map[string]any {
+	return map[string]any{"@type": helpType, "links": []any{map[string]any{"description": description, "url": url}}}
+}
+
+// Presets are the errors a fault can answer with, by name.
+var Presets = map[string]Preset{
+	"quota_exceeded": {
+		Code:    http.StatusTooManyRequests,
+		Status:  "RESOURCE_EXHAUSTED",
+		Message: "Quota exceeded for quota metric 'Requests' and limit 'Requests per minute' of service '{service}' for consumer '" + testProject + "'.",
+		Details: []map[string]any{
+			errorInfo("RATE_LIMIT_EXCEEDED", map[string]any{
+				"service":      "{service}",
+				"consumer":     testProject,
+				"quota_metric": "{service}/requests",
+				"quota_limit":  "RequestsPerMinutePerProject",
+			}),
+			{"@type": quotaFailureType, "violations": []any{map[string]any{
+				"subject":     testProject,
+				"description": "Requests per minute exceeded.",
+			}}},
+			{"@type": retryInfoType, "retryDelay": "30s"},
+		},
+	},
+	"billing_disabled": {
+		Code:    http.StatusForbidden,
+		Status:  "PERMISSION_DENIED",
+		Message: "This API method requires billing to be enabled. Please enable billing on project #123456789012 and retry.",
+		Details: []map[string]any{
+			errorInfo("BILLING_DISABLED", map[string]any{"service": "{service}", "consumer": testProject}),
+			help("Google developers console billing", "https://console.developers.google.com/billing/enable?project=123456789012"),
+		},
+	},
+	"service_disabled": {
+		Code:    http.StatusForbidden,
+		Status:  "PERMISSION_DENIED",
+		Message: "{service} has not been used in project 123456789012 before or it is disabled.",
+		Details: []map[string]any{
+			errorInfo("SERVICE_DISABLED", map[string]any{"service": "{service}", "consumer": testProject}),
+			help("Google developers console API activation", "https://console.developers.google.com/apis/api/{service}/overview?project=123456789012"),
+		},
+	},
+	"permission_denied": {
+		Code:    http.StatusForbidden,
+		Status:  "PERMISSION_DENIED",
+		Message: "Permission denied on resource project 123456789012.",
+		Details: []map[string]any{
+			errorInfo("IAM_PERMISSION_DENIED", map[string]any{"service": "{service}", "resource": testProject}),
+		},
+	},
+	"api_key_invalid": {
+		Code:    http.StatusBadRequest,
+		Status:  "INVALID_ARGUMENT",
+		Message: "API key not valid. Please pass a valid API key.",
+		Details: []map[string]any{
+			errorInfo("API_KEY_INVALID", map[string]any{"service": "{service}"}),
+		},
+	},
+	"unauthenticated": {
+		Code:    http.StatusUnauthorized,
+		Status:  "UNAUTHENTICATED",
+		Message: "Request had invalid authentication credentials. Expected OAuth 2 access token, login cookie or other valid authentication credential.",
+		Details: []map[string]any{
+			errorInfo("ACCESS_TOKEN_EXPIRED", map[string]any{"service": "{service}", "method": "unknown"}),
+		},
+	},
+	"credentials_missing": {
+		Code:    http.StatusUnauthorized,
+		Status:  "UNAUTHENTICATED",
+		Message: "Request is missing required authentication credential. Expected OAuth 2 access token, login cookie or other valid authentication credential.",
+		Details: []map[string]any{
+			errorInfo("CREDENTIALS_MISSING", map[string]any{"service": "{service}", "method": "unknown"}),
+		},
+	},
+	"scope_insufficient": {
+		Code:    http.StatusForbidden,
+		Status:  "PERMISSION_DENIED",
+		Message: "Request had insufficient authentication scopes.",
+		Details: []map[string]any{
+			errorInfo("ACCESS_TOKEN_SCOPE_INSUFFICIENT", map[string]any{"service": "{service}", "method": "unknown"}),
+		},
+	},
+	"invalid_argument": {
+		Code:    http.StatusBadRequest,
+		Status:  "INVALID_ARGUMENT",
+		Message: "Request contains an invalid argument.",
+		Details: []map[string]any{
+			{"@type": badRequestType, "fieldViolations": []any{map[string]any{
+				"field":       "contents",
+				"description": "contents is not specified",
+			}}},
+		},
+	},
+	"not_found": {
+		Code:    http.StatusNotFound,
+		Status:  "NOT_FOUND",
+		Message: "Requested entity was not found.",
+	},
+	"internal": {
+		Code:    http.StatusInternalServerError,
+		Status:  "INTERNAL",
+		Message: "An internal error has occurred. Please retry.",
+	},
+	"unavailable": {
+		Code:    http.StatusServiceUnavailable,
+		Status:  "UNAVAILABLE",
+		Message: "The service is currently unavailable.",
+	},
+	"deadline_exceeded": {
+		Code:    http.StatusGatewayTimeout,
+		Status:  "DEADLINE_EXCEEDED",
+		Message: "Deadline expired before operation could complete.",
+	},
+}
+
+// Body returns the JSON error body of p for service.
+func (p Preset) Body(service string) []byte {
+	e := map[string]any{"code": p.Code, "message": p.Message, "status": p.Status}
+	if len(p.Details) > 0 {
+		e["details"] = p.Details
+	}
+	data, err := json.Marshal(map[string]any{"error": e})
+	if err != nil {
+		// The presets only hold strings, numbers, maps and slices.
+		panic(err)
+	}
+	// The placeholder and the host need no escaping in JSON.
+	return []byte(strings.ReplaceAll(string(data), "{service}", service))
+}
+
+func (p Preset) write(w http.ResponseWriter, service string) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(p.Code)
+	w.Write(p.Body(service))
+}
@@ -0,0 +1,63 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package route
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/test-server/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPresetBodies(t *testing.T) {
+	for name, p := range Presets {
+		body := p.Body("example.googleapis.com")
+		require.NotContains(t, string(body), "{service}", name)
+		var parsed struct {
+			Error struct {
+				Code    int              `json:"code"`
+				Message string           `json:"message"`
+				Status  string           `json:"status"`
+				Details []map[string]any `json:"details"`
+			} `json:"error"`
+		}
+		require.NoError(t, json.Unmarshal(body, &parsed), name)
+		require.Equal(t, p.Code, parsed.Error.Code, name)
+		require.Equal(t, p.Status, parsed.Error.Status, name)
+		require.NotEmpty(t, parsed.Error.Message, name)
+		for _, d := range parsed.Error.Details {
+			require.True(t, strings.HasPrefix(d["@type"].(string), "type.googleapis.com/google.rpc."), name)
+		}
+	}
+}
+
+func TestPresetFault(t *testing.T) {
+	_, err := New("example.googleapis.com", []config.Route{{Fault: &config.Fault{Preset: "quota_exceded"}}})
+	require.ErrorContains(t, err, "unknown preset")
+	_, err = New("example.googleapis.com", []config.Route{{Fault: &config.Fault{Preset: "quota_exceeded", Status: 500}}})
+	require.Error(t, err)
+
+	server := serve(t, config.Route{Fault: &config.Fault{Preset: "billing_disabled"}})
+	resp, body, err := get(t, server.URL+"/v1/models")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+	require.Contains(t, body, `"reason":"BILLING_DISABLED"`)
+	require.Contains(t, body, `"service":"example.googleapis.com"`)
+}
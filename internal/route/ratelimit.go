@@ -0,0 +1,125 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package route
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/test-server/internal/config"
+)
+
+// limiter counts the requests of every client in fixed windows.
+type limiter struct {
+	requests int
+	window   time.Duration
+	key      func(*http.Request) string
+
+	mu      sync.Mutex
+	windows map[string]*window
+}
+
+type window struct {
+	start time.Time
+	count int
+}
+
+func newLimiter(c *config.RateLimit) (*limiter, error) {
+	if c.Requests <= 0 {
+		return nil, fmt.Errorf("requests must be positive")
+	}
+	length, err := time.ParseDuration(c.Window)
+	if err != nil {
+		return nil, fmt.Errorf("window: %w", err)
+	}
+	if length <= 0 {
+		return nil, fmt.Errorf("window must be positive")
+	}
+	l := &limiter{requests: c.Requests, window: length, windows: make(map[string]*window)}
+	switch c.Key {
+	case "", "client":
+		l.key = clientKey
+	case "token":
+		l.key = tokenKey
+	case "global":
+		l.key = func(*http.Request) string { return "" }
+	default:
+		name, ok := strings.CutPrefix(c.Key, "header:")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("unknown key %q, want client, token, header:<name> or global", c.Key)
+		}
+		l.key = func(req *http.Request) string { return req.Header.Get(name) }
+	}
+	return l, nil
+}
+
+func clientKey(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
+
+func tokenKey(req *http.Request) string {
+	if v := req.Header.Get("Authorization"); v != "" {
+		return v
+	}
+	if v := req.Header.Get("X-Goog-Api-Key"); v != "" {
+		return v
+	}
+	return req.URL.Query().Get("key")
+}
+
+// allow counts req at now and reports whether it is within the limit. It
+// sets the rate limit headers on w either way: the RateLimit-* headers of the
+// IETF draft, with the reset in seconds, the common X-RateLimit-* headers,
+// with the reset as a Unix time, and Retry-After when the limit is exceeded.
+func (l *limiter) allow(w http.ResponseWriter, req *http.Request, now time.Time) bool {
+	key := l.key(req)
+	l.mu.Lock()
+	win, ok := l.windows[key]
+	if !ok || !now.Before(win.start.Add(l.window)) {
+		win = &window{start: now}
+		l.windows[key] = win
+	}
+	win.count++
+	count := win.count
+	reset := win.start.Add(l.window)
+	l.mu.Unlock()
+
+	remaining := max(l.requests-count, 0)
+	// Round up so that a client waiting that long is in the next window.
+	resetSeconds := strconv.FormatInt(int64((reset.Sub(now)+time.Second-1)/time.Second), 10)
+	h := w.Header()
+	h.Set("RateLimit-Limit", strconv.Itoa(l.requests))
+	h.Set("RateLimit-Remaining", strconv.Itoa(remaining))
+	h.Set("RateLimit-Reset", resetSeconds)
+	h.Set("X-RateLimit-Limit", strconv.Itoa(l.requests))
+	h.Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+	h.Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+	if count > l.requests {
+		h.Set("Retry-After", resetSeconds)
+		return false
+	}
+	return true
+}
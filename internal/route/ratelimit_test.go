@@ -0,0 +1,80 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package route
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/test-server/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewLimiterRejectsInvalidConfig(t *testing.T) {
+	for name, c := range map[string]config.RateLimit{
+		"no requests":  {Window: "1m"},
+		"bad window":   {Requests: 1, Window: "a while"},
+		"empty window": {Requests: 1},
+		"unknown key":  {Requests: 1, Window: "1m", Key: "user"},
+		"empty header": {Requests: 1, Window: "1m", Key: "header:"},
+	} {
+		_, err := newLimiter(&c)
+		require.Error(t, err, name)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	router, err := New("example.googleapis.com", []config.Route{{Path: "/v1/*", RateLimit: &config.RateLimit{Requests: 2, Window: "10s", Key: "token"}}})
+	require.NoError(t, err)
+	now := time.Unix(1700000000, 0)
+	router.now = func() time.Time { return now }
+	handler := router.Wrap(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	send := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/v1/models", nil)
+		req.Header.Set("X-Goog-Api-Key", token)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := send("a")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "2", rec.Header().Get("RateLimit-Limit"))
+	require.Equal(t, "1", rec.Header().Get("RateLimit-Remaining"))
+	require.Equal(t, "10", rec.Header().Get("RateLimit-Reset"))
+	require.Equal(t, strconv.FormatInt(now.Unix()+10, 10), rec.Header().Get("X-RateLimit-Reset"))
+
+	now = now.Add(2500 * time.Millisecond)
+	require.Equal(t, http.StatusOK, send("a").Code)
+	rec = send("a")
+	require.Equal(t, http.StatusTooManyRequests, rec.Code)
+	require.Equal(t, "8", rec.Header().Get("Retry-After"))
+	require.Equal(t, "0", rec.Header().Get("X-RateLimit-Remaining"))
+	require.JSONEq(t, `{"error": {"code": 429, "message": "Rate limit exceeded by test-server", "status": "RESOURCE_EXHAUSTED"}}`, rec.Body.String())
+
+	// Another token has its own window.
+	require.Equal(t, http.StatusOK, send("b").Code)
+
+	// The next window starts afresh.
+	now = now.Add(7500 * time.Millisecond)
+	require.Equal(t, http.StatusOK, send("a").Code)
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package route
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+	"text/template"
+
+	"github.com/google/test-server/internal/config"
+)
+
+// HopParam is the query parameter counting the hops of a redirect chain.
+const HopParam = "test_server_hop"
+
+// redirect is a parsed config.Redirect.
+type redirect struct {
+	status   int
+	location *template.Template
+	hops     int
+	loop     bool
+}
+
+func (r *Router) newRedirect(c *config.Redirect) (*redirect, error) {
+	rd := &redirect{status: c.Status, hops: c.Hops, loop: c.Loop}
+	switch rd.status {
+	case 0:
+		rd.status = http.StatusFound
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+	default:
+		return nil, fmt.Errorf("status: %d is not a redirect", c.Status)
+	}
+	if rd.hops < 0 {
+		return nil, fmt.Errorf("hops: must not be negative")
+	}
+	if rd.hops == 0 {
+		rd.hops = 1
+	}
+	if c.Location != "" {
+		var err error
+		if rd.location, err = template.New("location").Option("missingkey=zero").Funcs(r.templateFuncs()).Parse(c.Location); err != nil {
+			return nil, fmt.Errorf("location: %w", err)
+		}
+	}
+	return rd, nil
+}
+
+// serve redirects req to the next hop of the chain, or, at its end, answers
+// it with next, without the hop parameter.
+func (rd *redirect) serve(w http.ResponseWriter, req *http.Request, params map[string]string, next http.Handler) {
+	query := req.URL.Query()
+	hop, _ := strconv.Atoi(query.Get(HopParam))
+	query.Del(HopParam)
+
+	var location string
+	switch {
+	case rd.loop || hop < rd.hops-1 || hop == rd.hops-1 && rd.location == nil:
+		u := *req.URL
+		query.Set(HopParam, strconv.Itoa(hop+1))
+		u.RawQuery = query.Encode()
+		location = u.RequestURI()
+	case hop == rd.hops-1:
+		data, err := newTemplateData(req, params)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Error reading request: %v", err), http.StatusInternalServerError)
+			return
+		}
+		var b bytes.Buffer
+		if err := rd.location.Execute(&b, data); err != nil {
+			http.Error(w, fmt.Sprintf("Error rendering the redirect location: %v", err), http.StatusInternalServerError)
+			return
+		}
+		location = b.String()
+	default:
+		// The chain ended at the requested URL.
+		req.URL.RawQuery = query.Encode()
+		req.RequestURI = req.URL.RequestURI()
+		next.ServeHTTP(w, req)
+		return
+	}
+	fmt.Printf("Redirecting %s %s to %s\n", req.Method, req.URL.Path, location)
+	w.Header().Set("Location", location)
+	w.WriteHeader(rd.status)
+}
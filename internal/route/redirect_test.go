@@ -0,0 +1,79 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package route
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/google/test-server/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+// follow gets target without following redirects and returns the statuses
+// and locations of the chain, up to max hops, and the final body.
+func follow(t *testing.T, target string, max int) (hops []string, body string) {
+	t.Helper()
+	client := &http.Client{CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		hops = append(hops, req.Response.Status[:3]+" "+req.URL.RequestURI())
+		if len(via) >= max {
+			return errors.New("too many redirects")
+		}
+		return nil
+	}}
+	resp, err := client.Get(target)
+	if err != nil {
+		return hops, ""
+	}
+	defer resp.Body.Close()
+	b, _ := io.ReadAll(resp.Body)
+	return hops, string(b)
+}
+
+func TestRedirects(t *testing.T) {
+	other := serve(t, config.Route{Path: "/v2/files/{name}", Response: &config.Response{Template: true, Body: "moved {{.PathParams.name}}"}})
+	server := serve(t,
+		config.Route{Path: "/v1/files/{name}", Redirect: &config.Redirect{Status: 307, Hops: 2, Location: other.URL + "/v2/files/{{.PathParams.name}}"}},
+		config.Route{Path: "/v1/models", Redirect: &config.Redirect{Hops: 2}, Response: &config.Response{Body: "models"}},
+		config.Route{Path: "/v1/loop", Redirect: &config.Redirect{Status: 301, Loop: true}},
+	)
+
+	hops, body := follow(t, server.URL+"/v1/files/a.txt?alt=media", 10)
+	require.Equal(t, []string{"307 /v1/files/a.txt?alt=media&test_server_hop=1", "307 /v2/files/a.txt"}, hops)
+	require.Equal(t, "moved a.txt", body)
+
+	// Without a location, the chain ends at the requested URL.
+	hops, body = follow(t, server.URL+"/v1/models", 10)
+	require.Equal(t, []string{"302 /v1/models?test_server_hop=1", "302 /v1/models?test_server_hop=2"}, hops)
+	require.Equal(t, "models", body)
+
+	hops, _ = follow(t, server.URL+"/v1/loop", 3)
+	require.Equal(t, []string{"301 /v1/loop?test_server_hop=1", "301 /v1/loop?test_server_hop=2", "301 /v1/loop?test_server_hop=3"}, hops)
+}
+
+func TestRedirectsRejectInvalidConfig(t *testing.T) {
+	for _, rd := range []*config.Redirect{
+		{Status: 200},
+		{Hops: -1},
+		{Location: "{{.Path"},
+	} {
+		_, err := New("example.googleapis.com", []config.Route{{Redirect: rd}})
+		require.Error(t, err)
+	}
+}
@@ -0,0 +1,815 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package route applies the routes of an endpoint: rules selecting requests
+// by method and path that change how they are answered, whatever the mode,
+// e.g. by injecting faults to exercise the error handling of an SDK, or by
+// answering with a stub response instead of a recorded one.
+package route
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/test-server/internal/clock"
+	"github.com/google/test-server/internal/codec"
+	"github.com/google/test-server/internal/config"
+	"github.com/google/test-server/internal/match"
+	"github.com/google/test-server/internal/oauth"
+	"github.com/google/test-server/internal/redact"
+)
+
+// Router matches requests against routes. The first matching route of the
+// highest priority wins, unless the routes of that priority have weights.
+type Router struct {
+	// service is the target host, named in the preset errors.
+	service string
+	// routesMu guards routes, readsBody and config, which the stub admin
+	// calls change.
+	routesMu sync.RWMutex
+	routes   []*compiled
+	// now is the clock of the rate limits and templates.
+	now func() time.Time
+	// scenarios are the states of the scenarios of the routes.
+	scenarios scenarios
+	// triggers release the requests of long poll routes.
+	triggers triggers
+	// readsBody is set if a route matches on the request body.
+	readsBody bool
+	// verify checks the tokens the endpoint issued, for the routes accepting
+	// issued_tokens.
+	verify TokenVerifier
+	// config is the routes of the router, each with its name, and initial
+	// the ones of the configuration and stub files.
+	config  []config.Route
+	initial []config.Route
+	// stubsMu serializes the changes of the routes and guards initial, and
+	// stubs counts the stubs added without a name.
+	stubsMu sync.Mutex
+	stubs   int
+	// journal keeps the requests of the router, with the values of the
+	// redactHeaders of its endpoint masked.
+	journal       *journal
+	redactHeaders []string
+	// session is the session of a router answering for one, and nil for
+	// the router of an endpoint.
+	session *session
+	// handler is the handler of the endpoint, which the dedicated ports of
+	// sessions serve with the listener settings of endpoint; see Handler.
+	handler  http.Handler
+	endpoint *config.EndpointConfig
+	// openapi checks the requests and responses against the OpenAPI
+	// document of the endpoint, if it has one.
+	openapi *validator
+
+	// rng draws whether a fault with a probability applies, the delays and
+	// the random values of templates.
+	rngMu sync.Mutex
+	rng   *rand.Rand
+}
+
+// compiled is a route with its parsed parts, nil where there is none.
+type compiled struct {
+	config.Route
+	// name is the name of the route, or routes[<index>].
+	name      string
+	path      *regexp.Regexp
+	body      *match.Predicate
+	headers   []valueMatch
+	query     []valueMatch
+	url       *regexp.Regexp
+	delay     *delay
+	limiter   *limiter
+	stub      *stub
+	pages     *pages
+	events    []event
+	generated *generated
+	longPoll  *longPoll
+	redirect  *redirect
+	webhooks  []*webhook
+	upload    *uploads
+	// clientCert are the conditions on the TLS client certificate.
+	clientCert []valueMatch
+}
+
+// Handler returns the handler of the endpoint cfg: next behind the routes,
+// the token endpoints and the CORS policy of the endpoint, with the request
+// bodies decoded. The journal of the endpoint redacts the secrets of
+// redactor, which may be nil.
+func Handler(cfg *config.EndpointConfig, redactor *redact.Redact, next http.Handler) (http.Handler, error) {
+	router, h, err := build(cfg, next)
+	if err != nil {
+		return nil, err
+	}
+	router.journal.redactor = redactor
+	router.redactHeaders = cfg.RedactRequestHeaders
+	register(router, cfg.Seed != nil)
+	router.handler, router.endpoint = h, cfg
+	if cfg.StubsDir != "" {
+		// The stub files are watched for as long as the server runs.
+		router.watchStubs(router.initial[:len(cfg.Routes)], cfg.StubsDir)
+	}
+	return h, nil
+}
+
+// Validate returns the error Handler would return for cfg.
+func Validate(cfg *config.EndpointConfig) error {
+	_, _, err := build(cfg, http.NotFoundHandler())
+	return err
+}
+
+// build returns the Router of cfg and the handler of Handler.
+func build(cfg *config.EndpointConfig, next http.Handler) (*Router, http.Handler, error) {
+	routes := cfg.Routes
+	if cfg.StubsDir != "" {
+		files, err := loadStubsDir(cfg.TargetHost, cfg.StubsDir)
+		if err != nil {
+			return nil, nil, fmt.Errorf("stubs_dir: %w", err)
+		}
+		routes = append(append([]config.Route{}, cfg.Routes...), files...)
+	}
+	router, err := New(cfg.TargetHost, routes)
+	if err != nil {
+		return nil, nil, err
+	}
+	if cfg.Seed != nil {
+		router.Seed(*cfg.Seed)
+	}
+	if cfg.OpenAPI != nil {
+		if router.openapi, err = newValidator(cfg.OpenAPI); err != nil {
+			return nil, nil, fmt.Errorf("openapi.%w", err)
+		}
+	}
+	var o *oauth.Server
+	if cfg.OAuth != nil {
+		if o, err = oauth.New(cfg.OAuth); err != nil {
+			return nil, nil, fmt.Errorf("oauth.%w", err)
+		}
+		router.verify = o.Verify
+	}
+	for i, route := range cfg.Routes {
+		if route.Auth != nil && route.Auth.IssuedTokens && o == nil {
+			return nil, nil, fmt.Errorf("routes[%d].auth: issued_tokens needs oauth on the endpoint", i)
+		}
+	}
+	for _, route := range router.initial[len(cfg.Routes):] {
+		if route.Auth != nil && route.Auth.IssuedTokens && o == nil {
+			return nil, nil, fmt.Errorf("stubs_dir: %s.auth: issued_tokens needs oauth on the endpoint", route.Name)
+		}
+	}
+	h := router.Wrap(next)
+	if o != nil {
+		h = o.Wrap(h)
+	}
+	if cfg.CORS != nil {
+		c, err := newCORS(cfg.CORS)
+		if err != nil {
+			return nil, nil, fmt.Errorf("cors.%w", err)
+		}
+		h = c.Wrap(h)
+	}
+	return router, decodeRequests(h), nil
+}
+
+// New validates the routes of the endpoint of host and returns their Router.
+func New(host string, routes []config.Route) (*Router, error) {
+	r := &Router{service: host, now: clock.Server.Now, rng: rand.New(rand.NewSource(rand.Int63())), journal: newJournal(nil)}
+	named := make([]config.Route, len(routes))
+	for i, route := range routes {
+		if route.Name == "" {
+			route.Name = fmt.Sprintf("routes[%d]", i)
+		}
+		named[i] = route
+	}
+	if err := r.setRoutes(named); err != nil {
+		return nil, err
+	}
+	r.initial = named
+	return r, nil
+}
+
+// setRoutes compiles routes, which all have a name, and makes them the
+// routes of r.
+func (r *Router) setRoutes(routes []config.Route) error {
+	var all []*compiled
+	readsBody := false
+	for i, route := range routes {
+		c, err := r.compile(route)
+		if err != nil {
+			return fmt.Errorf("routes[%d]%w", i, err)
+		}
+		c.name = route.Name
+		all = append(all, c)
+		readsBody = readsBody || c.body != nil
+	}
+	sort.SliceStable(all, func(i, j int) bool { return all[i].Priority > all[j].Priority })
+	r.routesMu.Lock()
+	defer r.routesMu.Unlock()
+	r.routes, r.readsBody, r.config = all, readsBody, routes
+	return nil
+}
+
+// Seed makes the random values of r, from the delays to the fake data of
+// templates, the same on every run with the same seed and requests.
+func (r *Router) Seed(seed int64) {
+	r.rngMu.Lock()
+	defer r.rngMu.Unlock()
+	r.rng = rand.New(rand.NewSource(seed))
+}
+
+// compile parses route. Its errors start with the field at fault, e.g.
+// ".delay: ...", for New to prefix with the route.
+func (r *Router) compile(route config.Route) (*compiled, error) {
+	c := &compiled{Route: route}
+	if err := validateScenario(&route); err != nil {
+		return nil, fmt.Errorf(".scenario: %w", err)
+	}
+	var err error
+	if route.Path != "" {
+		if c.path, err = compilePath(route.Path); err != nil {
+			return nil, fmt.Errorf(".path: %w", err)
+		}
+	}
+	if route.Body != nil {
+		if c.body, err = match.NewPredicate(route.Body); err != nil {
+			return nil, fmt.Errorf(".body: %w", err)
+		}
+	}
+	if c.headers, err = compileValues(route.Headers); err != nil {
+		return nil, fmt.Errorf(".headers.%w", err)
+	}
+	if c.query, err = compileValues(route.Query); err != nil {
+		return nil, fmt.Errorf(".query.%w", err)
+	}
+	if c.clientCert, err = compileClientCert(route.ClientCert); err != nil {
+		return nil, fmt.Errorf(".client_cert.%w", err)
+	}
+	if route.URLRegex != "" {
+		if c.url, err = regexp.Compile("^(?:" + route.URLRegex + ")$"); err != nil {
+			return nil, fmt.Errorf(".url_regex: %w", err)
+		}
+	}
+	if route.Weight != nil && *route.Weight < 0 {
+		return nil, fmt.Errorf(".weight: must not be negative")
+	}
+	if route.Fault != nil {
+		if err := validateFault(route.Fault); err != nil {
+			return nil, fmt.Errorf(".fault: %w", err)
+		}
+	}
+	if route.Delay != nil {
+		if c.delay, err = parseDelay(route.Delay); err != nil {
+			return nil, fmt.Errorf(".delay: %w", err)
+		}
+	}
+	if route.Auth != nil {
+		if err := validateAuth(route.Auth); err != nil {
+			return nil, fmt.Errorf(".auth: %w", err)
+		}
+	}
+	if route.RateLimit != nil {
+		if c.limiter, err = newLimiter(route.RateLimit); err != nil {
+			return nil, fmt.Errorf(".rate_limit: %w", err)
+		}
+	}
+	if route.Response != nil {
+		if c.stub, err = r.newStub(route.Response); err != nil {
+			return nil, fmt.Errorf(".response: %w", err)
+		}
+	}
+	if route.Pages != nil {
+		if route.Response != nil {
+			return nil, fmt.Errorf(".pages: cannot be combined with response")
+		}
+		if c.pages, err = r.newPages(route.Pages); err != nil {
+			return nil, fmt.Errorf(".pages.%w", err)
+		}
+	}
+	if route.Events != nil {
+		if route.Response != nil || route.Pages != nil {
+			return nil, fmt.Errorf(".events: cannot be combined with response or pages")
+		}
+		if c.events, err = parseEvents(route.Events); err != nil {
+			return nil, fmt.Errorf(".events%w", err)
+		}
+	}
+	if route.Generate != nil {
+		if route.Response != nil || route.Pages != nil || route.Events != nil || route.Ranges || route.ContentEncoding != "" {
+			return nil, fmt.Errorf(".generate: cannot be combined with response, pages, events, ranges or content_encoding")
+		}
+		if c.generated, err = newGenerated(route.Generate); err != nil {
+			return nil, fmt.Errorf(".generate.%w", err)
+		}
+	}
+	if route.ContentEncoding != "" && !codec.Supported(route.ContentEncoding) {
+		return nil, fmt.Errorf(".content_encoding: unknown coding %q", route.ContentEncoding)
+	}
+	if route.Redirect != nil {
+		if c.redirect, err = r.newRedirect(route.Redirect); err != nil {
+			return nil, fmt.Errorf(".redirect.%w", err)
+		}
+	}
+	for i := range route.Webhooks {
+		h, err := r.newWebhook(&route.Webhooks[i])
+		if err != nil {
+			return nil, fmt.Errorf(".webhooks[%d].%w", i, err)
+		}
+		c.webhooks = append(c.webhooks, h)
+	}
+	if route.LongPoll != nil {
+		if c.longPoll, err = r.newLongPoll(route.LongPoll); err != nil {
+			return nil, fmt.Errorf(".long_poll.%w", err)
+		}
+	}
+	if route.Upload != nil {
+		if c.upload, err = newUploads(route.Upload); err != nil {
+			return nil, fmt.Errorf(".upload.%w", err)
+		}
+	}
+	return c, nil
+}
+
+// paramRe matches a path parameter, e.g. {model}.
+var paramRe = regexp.MustCompile(`\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// compilePath turns a route path into a regular expression in which * matches
+// any part of a path segment and {name} a non-empty part, captured as the path
+// parameter name.
+func compilePath(pattern string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("^")
+	seen := map[string]bool{}
+	for pattern != "" {
+		if loc := paramRe.FindStringSubmatchIndex(pattern); loc != nil && loc[0] == 0 {
+			name := pattern[loc[2]:loc[3]]
+			if seen[name] {
+				return nil, fmt.Errorf("parameter {%s} appears twice", name)
+			}
+			seen[name] = true
+			fmt.Fprintf(&b, "(?P<%s>[^/]+?)", name)
+			pattern = pattern[loc[1]:]
+			continue
+		}
+		switch c := pattern[0]; c {
+		case '*':
+			b.WriteString("[^/]*")
+		case '{', '}':
+			return nil, fmt.Errorf("invalid parameter at %q", pattern)
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+		pattern = pattern[1:]
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
+
+func validateFault(f *config.Fault) error {
+	kinds := 0
+	if f.Status != 0 || f.MalformedJSON {
+		kinds++
+	}
+	if f.Preset != "" {
+		if _, ok := Presets[f.Preset]; !ok {
+			return fmt.Errorf("unknown preset %q", f.Preset)
+		}
+		kinds++
+	}
+	if f.Reset {
+		kinds++
+	}
+	if f.TruncateAfter != nil {
+		kinds++
+	}
+	switch {
+	case kinds == 0:
+		return fmt.Errorf("set one of status, malformed_json, preset, reset or truncate_after")
+	case kinds > 1:
+		return fmt.Errorf("preset, reset and truncate_after cannot be combined with another fault")
+	case f.Status != 0 && (f.Status < 100 || f.Status > 999):
+		return fmt.Errorf("invalid status %d", f.Status)
+	case f.TruncateAfter != nil && *f.TruncateAfter < 0:
+		return fmt.Errorf("truncate_after must not be negative")
+	case f.Probability != nil && (*f.Probability < 0 || *f.Probability > 1):
+		return fmt.Errorf("probability must be between 0 and 1")
+	}
+	return nil
+}
+
+// Match returns the first route matching req, or nil. Unlike serving req, it
+// does not move a scenario to a new state.
+func (r *Router) Match(req *http.Request) *config.Route {
+	if c, _ := r.match(req, false); c != nil {
+		return &c.Route
+	}
+	return nil
+}
+
+// match returns the first route matching req with its path parameters, or
+// nil. With transition, the scenario of the route moves to its new state.
+func (r *Router) match(req *http.Request, transition bool) (*compiled, map[string]string) {
+	r.routesMu.RLock()
+	defer r.routesMu.RUnlock()
+	var body []byte
+	if r.readsBody && req.Body != nil {
+		// The body is put back for the handler; a read error leaves it short,
+		// which the handler then runs into as well.
+		body, _ = io.ReadAll(req.Body)
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	// Holding the lock until the transition keeps two concurrent requests
+	// from both matching in the same state.
+	r.scenarios.mu.Lock()
+	defer r.scenarios.mu.Unlock()
+	var (
+		candidates []*compiled
+		paramsOf   []map[string]string
+		weighted   bool
+	)
+	for _, c := range r.routes {
+		if len(candidates) > 0 && (!weighted || c.Priority < candidates[0].Priority) {
+			break
+		}
+		params, ok := r.matches(c, req, body)
+		if !ok {
+			continue
+		}
+		candidates = append(candidates, c)
+		paramsOf = append(paramsOf, params)
+		if len(candidates) == 1 {
+			weighted = r.weighted(c.Priority)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+	i := 0
+	if weighted {
+		i = r.pick(candidates)
+	}
+	if transition {
+		r.scenarios.transition(&candidates[i].Route)
+	}
+	return candidates[i], paramsOf[i]
+}
+
+// matches reports whether c matches req, whose body is body, in the current
+// state of its scenario, and returns the path parameters. The caller holds
+// scenarios.mu.
+func (r *Router) matches(c *compiled, req *http.Request, body []byte) (map[string]string, bool) {
+	if c.Method != "" && !strings.EqualFold(c.Method, req.Method) {
+		return nil, false
+	}
+	if !r.scenarios.allows(&c.Route) {
+		return nil, false
+	}
+	params := map[string]string{}
+	if c.path != nil {
+		m := c.path.FindStringSubmatch(req.URL.Path)
+		if m == nil {
+			return nil, false
+		}
+		for i, name := range c.path.SubexpNames() {
+			if name != "" {
+				params[name] = m[i]
+			}
+		}
+	}
+	if !c.matchesValues(req) || !c.matchesClientCert(req) {
+		return nil, false
+	}
+	if c.body != nil && !c.body.Test(body) {
+		return nil, false
+	}
+	return params, true
+}
+
+// weighted reports whether a route of the given priority has a weight.
+func (r *Router) weighted(priority int) bool {
+	for _, c := range r.routes {
+		if c.Priority == priority && c.Weight != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// pick draws one of candidates in proportion to their weights, the first one
+// if they all weigh 0.
+func (r *Router) pick(candidates []*compiled) int {
+	weights := make([]float64, len(candidates))
+	total := 0.0
+	for i, c := range candidates {
+		weights[i] = 1
+		if c.Weight != nil {
+			weights[i] = *c.Weight
+		}
+		total += weights[i]
+	}
+	if total == 0 {
+		return 0
+	}
+	r.rngMu.Lock()
+	x := r.rng.Float64() * total
+	r.rngMu.Unlock()
+	for i, w := range weights {
+		if x < w {
+			return i
+		}
+		x -= w
+	}
+	return len(candidates) - 1
+}
+
+// chance reports whether an event of probability p, nil meaning 1, happens.
+func (r *Router) chance(p *float64) bool {
+	if p == nil {
+		return true
+	}
+	r.rngMu.Lock()
+	defer r.rngMu.Unlock()
+	return r.rng.Float64() < *p
+}
+
+// sampleDelay draws from d.
+func (r *Router) sampleDelay(d *delay) time.Duration {
+	r.rngMu.Lock()
+	defer r.rngMu.Unlock()
+	return d.sample(r.rng)
+}
+
+// Wrap returns a handler applying the routes before next, journaling the
+// requests and answering the admin calls.
+func (r *Router) Wrap(next http.Handler) http.Handler {
+	// h answers the requests of batches.
+	var h http.HandlerFunc
+	h = func(w http.ResponseWriter, req *http.Request) {
+		if r.session == nil {
+			if req.URL.Path == SessionsPath || strings.HasPrefix(req.URL.Path, SessionsPath+"/") {
+				r.serveSessions(w, req)
+				return
+			}
+			s, sessionReq, ok := r.sessionOf(w, req)
+			if !ok {
+				return
+			}
+			if s != nil {
+				s.handler(r, next).ServeHTTP(w, sessionReq)
+				return
+			}
+		}
+		if req.URL.Path == StubsPath || strings.HasPrefix(req.URL.Path, StubsPath+"/") {
+			r.serveStubs(w, req)
+			return
+		}
+		if req.URL.Path == ResetPath {
+			r.serveReset(w, req)
+			return
+		}
+		if req.URL.Path == ScenariosPath {
+			r.serveScenarios(w, req)
+			return
+		}
+		if req.URL.Path == DashboardPath {
+			serveDashboard(w, req)
+			return
+		}
+		if req.URL.Path == AdminEventsPath {
+			serveAdminEvents(w, req)
+			return
+		}
+		if strings.HasPrefix(req.URL.Path, TriggerPath) {
+			r.serveTrigger(w, req)
+			return
+		}
+		if req.URL.Path == SeedPath {
+			serveSeed(w, req)
+			return
+		}
+		if req.URL.Path == ClockPath || strings.HasPrefix(req.URL.Path, ClockPath+"/") {
+			serveClock(w, req)
+			return
+		}
+		if strings.HasPrefix(req.URL.Path, JournalPath) {
+			r.journal.serve(w, req)
+			return
+		}
+		entry := r.journal.add(r, req, r.now())
+		if r.openapi != nil {
+			var checkResponse func()
+			w, checkResponse = r.openapi.check(w, req, entry, r.journal)
+			defer checkResponse()
+		}
+		route, params := r.match(req, true)
+		if route == nil {
+			next.ServeHTTP(w, req)
+			return
+		}
+		r.journal.setRoute(entry, route.name)
+		if d := route.delay; d != nil {
+			w = &delayingWriter{ResponseWriter: w, delay: d, sample: func() time.Duration { return r.sampleDelay(d) }}
+		}
+		if route.Auth != nil && !r.authenticate(w, req, route.Auth) {
+			fmt.Printf("Rejecting the credentials of %s %s\n", req.Method, req.URL.Path)
+			return
+		}
+		if l := route.limiter; l != nil && !l.allow(w, req, r.now()) {
+			fmt.Printf("Rate limiting %s %s\n", req.Method, req.URL.Path)
+			writeError(w, http.StatusTooManyRequests, "RESOURCE_EXHAUSTED", "Rate limit exceeded by test-server")
+			return
+		}
+		answer := next
+		if route.stub != nil {
+			answer = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				route.stub.serve(w, req, params)
+			})
+		}
+		if route.pages != nil {
+			answer = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				route.pages.serve(w, req, params)
+			})
+		}
+		if route.events != nil {
+			answer = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				serveEvents(w, req, route.events)
+			})
+		}
+		if g := route.generated; g != nil {
+			answer = http.HandlerFunc(g.serve)
+		}
+		if route.upload != nil {
+			other := answer
+			answer = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				r.serveUpload(route.upload, w, req, other, func(w http.ResponseWriter, req *http.Request, s *uploadSession) {
+					if route.stub != nil {
+						route.stub.serve(w, req, params)
+						return
+					}
+					w.Header().Set("Content-Type", "application/json")
+					json.NewEncoder(w).Encode(s.resource())
+				})
+			})
+		}
+		if route.Batch {
+			other := answer
+			answer = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				if !serveBatch(w, req, h) {
+					other.ServeHTTP(w, req)
+				}
+			})
+		}
+		if coding := route.ContentEncoding; coding != "" {
+			plain := answer
+			answer = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				encodeContent(w, req, plain, coding)
+			})
+		}
+		if route.Ranges && (req.Method == http.MethodGet || req.Method == http.MethodHead) {
+			whole := answer
+			answer = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				serveContent(w, req, whole)
+			})
+		}
+		if rd := route.redirect; rd != nil {
+			end := answer
+			answer = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				rd.serve(w, req, params, end)
+			})
+		}
+		if route.webhooks != nil {
+			calls, err := renderWebhooks(req, params, route.webhooks)
+			if err != nil {
+				fmt.Printf("Error rendering the webhooks of %s %s: %v\n", req.Method, req.URL.Path, err)
+			}
+			// The webhooks are sent once the request is answered.
+			defer func() {
+				for _, c := range calls {
+					startSending()
+					go c.send()
+				}
+			}()
+		}
+		if lp := route.longPoll; lp != nil {
+			triggered, ok := r.hold(req, lp)
+			if !ok {
+				return
+			}
+			if !triggered && lp.timeout != nil {
+				answer = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+					lp.timeout.serve(w, req, params)
+				})
+			}
+		}
+		if route.Fault == nil || !r.chance(route.Fault.Probability) {
+			answer.ServeHTTP(w, req)
+			return
+		}
+		fmt.Printf("Injecting a fault into %s %s\n", req.Method, req.URL.Path)
+		r.injectFault(w, req, route.Fault, answer)
+	}
+	return h
+}
+
+// malformedJSON is what a malformed_json fault answers.
+const malformedJSON = `{"error": {"code": 500, "message": "truncated`
+
+func (r *Router) injectFault(w http.ResponseWriter, req *http.Request, f *config.Fault, next http.Handler) {
+	switch {
+	case f.Preset != "":
+		Presets[f.Preset].write(w, r.service)
+	case f.Reset:
+		reset(w)
+	case f.TruncateAfter != nil:
+		next.ServeHTTP(&truncatingWriter{ResponseWriter: w, left: *f.TruncateAfter}, req)
+	default:
+		status := f.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		if f.MalformedJSON {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(status)
+			w.Write([]byte(malformedJSON))
+			return
+		}
+		writeError(w, status, strings.ToUpper(strings.ReplaceAll(http.StatusText(status), " ", "_")), "Fault injected by test-server")
+	}
+}
+
+// writeError answers with a JSON error body in the format of Google APIs.
+func writeError(w http.ResponseWriter, code int, status, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	fmt.Fprintf(w, `{"error": {"code": %d, "message": %q, "status": %q}}`, code, message, status)
+}
+
+// reset closes the connection of w so that the client gets a TCP RST.
+func reset(w http.ResponseWriter) {
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		panic(http.ErrAbortHandler)
+	}
+	conn, _, err := hj.Hijack()
+	if err != nil {
+		panic(http.ErrAbortHandler)
+	}
+	if tcp, ok := conn.(*net.TCPConn); ok {
+		// Discard unsent data and send RST instead of FIN on close.
+		tcp.SetLinger(0)
+	}
+	conn.Close()
+}
+
+// truncatingWriter passes on the first left bytes of the body and then cuts
+// the connection.
+type truncatingWriter struct {
+	http.ResponseWriter
+	left        int64
+	wroteHeader bool
+}
+
+func (t *truncatingWriter) WriteHeader(status int) {
+	// The client must not be able to tell the body is short by its length.
+	t.Header().Del("Content-Length")
+	t.wroteHeader = true
+	t.ResponseWriter.WriteHeader(status)
+}
+
+func (t *truncatingWriter) Write(b []byte) (int, error) {
+	if !t.wroteHeader {
+		t.WriteHeader(http.StatusOK)
+	}
+	if int64(len(b)) <= t.left {
+		t.left -= int64(len(b))
+		return t.ResponseWriter.Write(b)
+	}
+	t.ResponseWriter.Write(b[:t.left])
+	if f, ok := t.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+	// Aborting makes the server close the connection mid-body.
+	panic(http.ErrAbortHandler)
+}
@@ -0,0 +1,224 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package route
+
+import (
+	"io"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/test-server/internal/config"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func ptr[T any](v T) *T { return &v }
+
+// serve starts a server answering {"ok":true} behind the routes.
+func serve(t *testing.T, routes ...config.Route) *httptest.Server {
+	t.Helper()
+	router, err := New("example.googleapis.com", routes)
+	require.NoError(t, err)
+	server := httptest.NewServer(router.Wrap(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true}`))
+	})))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func get(t *testing.T, url string) (*http.Response, string, error) {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	return resp, string(body), err
+}
+
+func TestNewRejectsInvalidRoutes(t *testing.T) {
+	for name, r := range map[string]config.Route{
+		"bad path":          {Path: "/v1/{model", Fault: &config.Fault{Status: 503}},
+		"empty fault":       {Fault: &config.Fault{}},
+		"reset and status":  {Fault: &config.Fault{Reset: true, Status: 503}},
+		"truncate and JSON": {Fault: &config.Fault{TruncateAfter: ptr(int64(1)), MalformedJSON: true}},
+		"bad status":        {Fault: &config.Fault{Status: 42}},
+		"negative truncate": {Fault: &config.Fault{TruncateAfter: ptr(int64(-1))}},
+		"bad probability":   {Fault: &config.Fault{Status: 503, Probability: ptr(1.5)}},
+	} {
+		_, err := New("example.googleapis.com", []config.Route{r})
+		require.Error(t, err, name)
+	}
+}
+
+func TestFaults(t *testing.T) {
+	server := serve(t,
+		config.Route{Method: "GET", Path: "/status", Fault: &config.Fault{Status: 503}},
+		config.Route{Path: "/malformed", Fault: &config.Fault{MalformedJSON: true}},
+		config.Route{Path: "/reset", Fault: &config.Fault{Reset: true}},
+		config.Route{Path: "/truncate/*", Fault: &config.Fault{TruncateAfter: ptr(int64(4))}},
+		config.Route{Path: "/never", Fault: &config.Fault{Status: 503, Probability: ptr(0.0)}},
+	)
+
+	resp, body, err := get(t, server.URL+"/status")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	require.JSONEq(t, `{"error": {"code": 503, "message": "Fault injected by test-server", "status": "SERVICE_UNAVAILABLE"}}`, body)
+
+	resp, body, err = get(t, server.URL+"/malformed")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, malformedJSON, body)
+
+	_, _, err = get(t, server.URL+"/reset")
+	require.Error(t, err)
+
+	_, body, err = get(t, server.URL+"/truncate/this")
+	require.Error(t, err)
+	require.Equal(t, `{"ok`, body)
+
+	for _, path := range []string{"/never", "/truncate/a/b", "/other"} {
+		resp, body, err = get(t, server.URL+path)
+		require.NoError(t, err, path)
+		require.Equal(t, http.StatusOK, resp.StatusCode, path)
+		require.Equal(t, `{"ok":true}`, body, path)
+	}
+
+	// The method of the route must match.
+	resp, err = http.Post(server.URL+"/status", "application/json", strings.NewReader("{}"))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestBodyPredicates(t *testing.T) {
+	router, err := New("example.googleapis.com", []config.Route{
+		{
+			Method: "POST",
+			Body:   &config.BodyMatch{JSONPath: "$.generationConfig.candidateCount", Equals: 2},
+			Fault:  &config.Fault{Preset: "invalid_argument"},
+		},
+	})
+	require.NoError(t, err)
+	var reached []string
+	handler := router.Wrap(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, err := io.ReadAll(req.Body)
+		require.NoError(t, err)
+		reached = append(reached, string(body))
+	}))
+
+	for _, body := range []string{`{"generationConfig": {"candidateCount": 1}}`, `{"generationConfig": {"candidateCount": 2}}`} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("POST", "/v1/models/gemini:generateContent", strings.NewReader(body)))
+	}
+	// The first request reaches the handler with its body intact; the
+	// second gets the fault.
+	require.Equal(t, []string{`{"generationConfig": {"candidateCount": 1}}`}, reached)
+
+	_, err = New("example.googleapis.com", []config.Route{{Body: &config.BodyMatch{}}})
+	require.ErrorContains(t, err, "routes[0].body")
+}
+
+func TestHeadersAndQuery(t *testing.T) {
+	var routes []config.Route
+	require.NoError(t, yaml.UnmarshalStrict([]byte(`
+- name: keyed
+  headers:
+    X-Api-Key: secret
+    X-Debug: {absent: true}
+  query:
+    alt: {regex: json|sse}
+  response: {body: keyed}
+- name: paged
+  url_regex: /v1/items\?(.*&)?page=[0-9]+(&.*)?
+  response: {body: paged}
+- name: traced
+  headers:
+    Traceparent: {present: true}
+    User-Agent: {contains: test}
+  response: {body: traced}
+`), &routes))
+	router, err := New("example.googleapis.com", routes)
+	require.NoError(t, err)
+	handler := router.Wrap(http.NotFoundHandler())
+	answer := func(target string, headers ...string) string {
+		req := httptest.NewRequest("GET", target, nil)
+		for i := 0; i < len(headers); i += 2 {
+			req.Header.Add(headers[i], headers[i+1])
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Body.String()
+	}
+	require.Equal(t, "keyed", answer("/v1/items?alt=sse", "X-Api-Key", "secret"))
+	require.Contains(t, answer("/v1/items?alt=sse", "X-Api-Key", "secret", "X-Debug", "1"), "404")
+	require.Contains(t, answer("/v1/items?alt=ssex", "X-Api-Key", "secret"), "404")
+	require.Contains(t, answer("/v1/items?alt=json", "X-Api-Key", "other"), "404")
+	require.Equal(t, "paged", answer("/v1/items?size=2&page=3"))
+	require.Contains(t, answer("/v1/items?page=x"), "404")
+	require.Equal(t, "traced", answer("/", "Traceparent", "", "User-Agent", "go test"))
+	require.Contains(t, answer("/", "User-Agent", "go test"), "404")
+
+	_, err = New("example.googleapis.com", []config.Route{{Query: map[string]config.ValueMatch{"a": {Regex: "("}}}})
+	require.ErrorContains(t, err, "routes[0].query.a.regex")
+	_, err = New("example.googleapis.com", []config.Route{{Headers: map[string]config.ValueMatch{"a": {Absent: true, Equals: "b"}}}})
+	require.ErrorContains(t, err, "absent cannot be combined")
+}
+
+func TestPriority(t *testing.T) {
+	router, err := New("example.googleapis.com", []config.Route{
+		{Path: "/v1/*", Response: &config.Response{Body: "any"}},
+		{Path: "/v1/models", Priority: 1, Response: &config.Response{Body: "models"}},
+		{Path: "/v1/models", Priority: -1, Response: &config.Response{Body: "fallback"}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "models", router.Match(httptest.NewRequest("GET", "/v1/models", nil)).Response.Body)
+	require.Equal(t, "any", router.Match(httptest.NewRequest("GET", "/v1/files", nil)).Response.Body)
+}
+
+func TestWeights(t *testing.T) {
+	router, err := New("example.googleapis.com", []config.Route{
+		{Path: "/v1/models", Weight: ptr(3.0), Response: &config.Response{Body: "ok"}},
+		{Path: "/v1/models", Fault: &config.Fault{Status: 503}},
+		{Path: "/v1/models", Weight: ptr(0.0), Fault: &config.Fault{Reset: true}},
+		{Path: "/v1/models", Priority: -1, Fault: &config.Fault{Status: 500}},
+	})
+	require.NoError(t, err)
+	router.rng = rand.New(rand.NewSource(1))
+	counts := map[string]int{}
+	for i := 0; i < 1000; i++ {
+		route := router.Match(httptest.NewRequest("GET", "/v1/models", nil))
+		switch {
+		case route.Response != nil:
+			counts["ok"]++
+		case route.Fault.Status == 503:
+			counts["503"]++
+		default:
+			t.Fatalf("matched %+v", route)
+		}
+	}
+	require.InDelta(t, 750, counts["ok"], 60)
+	require.InDelta(t, 250, counts["503"], 60)
+
+	_, err = New("example.googleapis.com", []config.Route{{Weight: ptr(-1.0)}})
+	require.ErrorContains(t, err, "routes[0].weight")
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package route
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/google/test-server/internal/config"
+)
+
+// StartedState is the state every scenario starts in.
+const StartedState = "Started"
+
+// scenarios holds the current state of every scenario.
+type scenarios struct {
+	mu     sync.Mutex
+	states map[string]string
+}
+
+// state returns the state of name. The caller holds mu.
+func (s *scenarios) state(name string) string {
+	if state, ok := s.states[name]; ok {
+		return state
+	}
+	return StartedState
+}
+
+// allows reports whether route can match in the current state of its
+// scenario. The caller holds mu.
+func (s *scenarios) allows(route *config.Route) bool {
+	return route.Scenario == "" || route.RequiredState == "" || s.state(route.Scenario) == route.RequiredState
+}
+
+// transition moves the scenario of route to its new state, if any. The caller
+// holds mu.
+func (s *scenarios) transition(route *config.Route) {
+	if route.Scenario == "" || route.NewState == "" {
+		return
+	}
+	if old := s.state(route.Scenario); old != route.NewState {
+		fmt.Printf("Scenario %q moved from %q to %q\n", route.Scenario, old, route.NewState)
+	}
+	if s.states == nil {
+		s.states = map[string]string{}
+	}
+	s.states[route.Scenario] = route.NewState
+}
+
+// reset moves every scenario back to StartedState.
+func (s *scenarios) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.states = nil
+}
+
+func validateScenario(route *config.Route) error {
+	if route.Scenario == "" && (route.RequiredState != "" || route.NewState != "") {
+		return fmt.Errorf("required_state and new_state need a scenario")
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package route
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/test-server/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScenarioLifecycle(t *testing.T) {
+	server := serve(t,
+		config.Route{
+			Method: "POST", Path: "/v1/files",
+			Scenario: "file", RequiredState: StartedState, NewState: "created",
+			Response: &config.Response{Body: `{"name": "files/abc"}`},
+		},
+		config.Route{
+			Method: "GET", Path: "/v1/files/abc",
+			Scenario: "file", RequiredState: "created",
+			Response: &config.Response{Body: `{"name": "files/abc", "state": "ACTIVE"}`},
+		},
+		config.Route{
+			Method: "DELETE", Path: "/v1/files/abc",
+			Scenario: "file", RequiredState: "created", NewState: "deleted",
+			Response: &config.Response{Body: `{}`},
+		},
+		config.Route{
+			Path:     "/v1/files/abc",
+			Scenario: "file",
+			Fault:    &config.Fault{Preset: "not_found"},
+		},
+	)
+	send := func(method, path string) (int, string) {
+		t.Helper()
+		req, err := http.NewRequest(method, server.URL+path, nil)
+		require.NoError(t, err)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		b, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(b)
+	}
+
+	code, _ := send("GET", "/v1/files/abc")
+	require.Equal(t, http.StatusNotFound, code, "before the file is created")
+	code, body := send("POST", "/v1/files")
+	require.Equal(t, http.StatusOK, code)
+	require.JSONEq(t, `{"name": "files/abc"}`, body)
+	code, _ = send("POST", "/v1/files")
+	require.Equal(t, http.StatusOK, code, "a second create falls through to the next handler")
+	code, body = send("GET", "/v1/files/abc")
+	require.Equal(t, http.StatusOK, code)
+	require.JSONEq(t, `{"name": "files/abc", "state": "ACTIVE"}`, body)
+	code, _ = send("DELETE", "/v1/files/abc")
+	require.Equal(t, http.StatusOK, code)
+	code, _ = send("GET", "/v1/files/abc")
+	require.Equal(t, http.StatusNotFound, code, "after the file is deleted")
+}
+
+func TestMatchDoesNotTransition(t *testing.T) {
+	router, err := New("example.googleapis.com", []config.Route{
+		{Path: "/next", Scenario: "s", RequiredState: StartedState, NewState: "done"},
+	})
+	require.NoError(t, err)
+	req := httptest.NewRequest("GET", "/next", nil)
+	require.NotNil(t, router.Match(req))
+	require.NotNil(t, router.Match(req))
+}
+
+func TestScenarioNeedsAName(t *testing.T) {
+	_, err := New("example.googleapis.com", []config.Route{{Path: "/x", NewState: "done"}})
+	require.ErrorContains(t, err, "routes[0].scenario")
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package route
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"sync"
+)
+
+// SeedPath is the path of the admin calls that read and change the seed of
+// the random behavior of every endpoint.
+const SeedPath = "/__admin/seed"
+
+// seeding is the seed of the server, with the routers it applies to.
+var seeding struct {
+	mu      sync.Mutex
+	seed    *int64
+	routers []*Router
+}
+
+// SetSeed seeds the random behavior of every endpoint, from the weights of
+// routes, fault probabilities and delay jitter to the fake data of
+// templates, so that a run can be reproduced from its seed. It reseeds the
+// existing routers and the ones created later, unless their endpoint has a
+// seed of its own.
+func SetSeed(seed int64) {
+	seeding.mu.Lock()
+	defer seeding.mu.Unlock()
+	seeding.seed = &seed
+	for _, r := range seeding.routers {
+		r.Seed(seed)
+	}
+}
+
+// register seeds r with the seed of the server, if there is one, and
+// reseeds it with the later ones.
+func register(r *Router, ownSeed bool) {
+	seeding.mu.Lock()
+	defer seeding.mu.Unlock()
+	if seeding.seed != nil && !ownSeed {
+		r.Seed(*seeding.seed)
+	}
+	seeding.routers = append(seeding.routers, r)
+}
+
+// serveSeed answers GET /__admin/seed with the seed of the server and POST
+// /__admin/seed by reseeding every endpoint with the seed of the body, e.g.
+// {"seed": 42}, or a random one without a body.
+func serveSeed(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		var body struct {
+			Seed *int64 `json:"seed"`
+		}
+		if data, _ := io.ReadAll(req.Body); len(data) > 0 {
+			if err := json.Unmarshal(data, &body); err != nil {
+				writeError(w, http.StatusBadRequest, "INVALID_ARGUMENT", fmt.Sprintf("Invalid seed request: %v", err))
+				return
+			}
+		}
+		seed := rand.Int63()
+		if body.Seed != nil {
+			seed = *body.Seed
+		}
+		SetSeed(seed)
+		fmt.Printf("Random seed: %d\n", seed)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		writeError(w, http.StatusMethodNotAllowed, "INVALID_ARGUMENT", "The seed is read with GET and changed with POST")
+		return
+	}
+	seeding.mu.Lock()
+	seed := seeding.seed
+	seeding.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"seed": seed})
+}
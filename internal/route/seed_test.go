@@ -0,0 +1,73 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package route
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/test-server/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSeed(t *testing.T) {
+	t.Cleanup(func() {
+		seeding.seed, seeding.routers = nil, nil
+	})
+	own := int64(7)
+	endpoint := func(seed *int64) http.Handler {
+		handler, err := Handler(&config.EndpointConfig{Seed: seed, Routes: []config.Route{{
+			Path:     "/v1/users",
+			Response: &config.Response{Template: true, Body: `{"id": "{{uuid}}", "name": "{{fake "name"}}", "n": {{random 1 1000000}}}`},
+		}}}, nil, http.NotFoundHandler())
+		require.NoError(t, err)
+		return handler
+	}
+	send := func(h http.Handler, method, target, body string) string {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		return rec.Body.String()
+	}
+
+	SetSeed(42)
+	a, b, c := endpoint(nil), endpoint(nil), endpoint(&own)
+	first := send(a, "GET", "/v1/users", "")
+	require.Equal(t, first, send(b, "GET", "/v1/users", ""))
+	// An endpoint with a seed of its own keeps it.
+	require.NotEqual(t, first, send(c, "GET", "/v1/users", ""))
+	require.JSONEq(t, `{"seed": 42}`, send(a, "GET", SeedPath, ""))
+
+	// Reseeding through any endpoint restarts every one.
+	require.JSONEq(t, `{"seed": 42}`, send(b, "POST", SeedPath, `{"seed": 42}`))
+	require.Equal(t, first, send(a, "GET", "/v1/users", ""))
+	require.Equal(t, first, send(c, "GET", "/v1/users", ""))
+
+	var random struct{ Seed int64 }
+	require.NoError(t, json.Unmarshal([]byte(send(a, "POST", SeedPath, "")), &random))
+	require.JSONEq(t, send(a, "GET", SeedPath, ""), send(c, "GET", SeedPath, ""))
+
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest("DELETE", SeedPath, nil))
+	require.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	rec = httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest("POST", SeedPath, strings.NewReader(`{"seed": "x"}`)))
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+}
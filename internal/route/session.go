@@ -0,0 +1,326 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package route
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/google/test-server/internal/config"
+	"github.com/google/test-server/internal/listen"
+	"gopkg.in/yaml.v2"
+)
+
+// SessionsPath is the path of the admin calls that create, list and delete
+// sessions, e.g. DELETE /__admin/sessions/<id>.
+const SessionsPath = "/__admin/sessions"
+
+// SessionHeader names the session of a request.
+const SessionHeader = "Test-Server-Session"
+
+// SessionPrefix starts the paths of the requests of a session, e.g.
+// /__sessions/<id>/v1/models. The server strips it before answering.
+const SessionPrefix = "/__sessions/"
+
+// SessionsDir is the directory of the recording directory that keeps the
+// recordings of the sessions, each in a directory named by its id.
+const SessionsDir = "__sessions"
+
+// sessionIDRe limits the session ids to what is safe in a path and a file
+// name.
+var sessionIDRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// session isolates the requests of a test from the other tests sharing the
+// server: its requests are answered by its own routes before the ones of the
+// endpoint, with their own scenario states, and go to its own journal and
+// recordings.
+type session struct {
+	id      string
+	routes  []config.Route
+	journal *journal
+	// port is the dedicated port of the session, or 0 if it has none. It
+	// is bound and served over TLS like the endpoint the session was
+	// created with.
+	port   int
+	server *http.Server
+
+	// handlers answer the requests of the session, per endpoint.
+	mu       sync.Mutex
+	handlers map[*Router]http.Handler
+}
+
+// sessions are the sessions of the server, which apply to every endpoint.
+var sessions = struct {
+	mu   sync.Mutex
+	byID map[string]*session
+}{byID: map[string]*session{}}
+
+type sessionKey struct{}
+
+// SessionOf returns the id of the session of req, or "" if it has none.
+func SessionOf(req *http.Request) string {
+	if s, ok := req.Context().Value(sessionKey{}).(*session); ok {
+		return s.id
+	}
+	return ""
+}
+
+// sessionOf returns the session of req, with req as the session answers it:
+// without the session header or path prefix. It returns a nil session for a
+// request outside of sessions, and false after answering a request for a
+// session that does not exist.
+func (r *Router) sessionOf(w http.ResponseWriter, req *http.Request) (*session, *http.Request, bool) {
+	if s, ok := req.Context().Value(sessionKey{}).(*session); ok {
+		return s, req, true
+	}
+	id, prefixed := req.Header.Get(SessionHeader), false
+	if id == "" && strings.HasPrefix(req.URL.Path, SessionPrefix) {
+		id, _, _ = strings.Cut(strings.TrimPrefix(req.URL.Path, SessionPrefix), "/")
+		prefixed = true
+	}
+	if id == "" {
+		return nil, req, true
+	}
+	sessions.mu.Lock()
+	s := sessions.byID[id]
+	sessions.mu.Unlock()
+	if s == nil {
+		writeError(w, http.StatusNotFound, "NOT_FOUND", fmt.Sprintf("Session %q does not exist", id))
+		return nil, req, false
+	}
+	req = req.Clone(context.WithValue(req.Context(), sessionKey{}, s))
+	req.Header.Del(SessionHeader)
+	if prefixed {
+		prefix := SessionPrefix + id
+		req.URL.Path = strings.TrimPrefix(req.URL.Path, prefix)
+		req.URL.RawPath = strings.TrimPrefix(req.URL.RawPath, prefix)
+		if req.URL.Path == "" {
+			req.URL.Path = "/"
+		}
+		req.RequestURI = req.URL.RequestURI()
+	}
+	return s, req, true
+}
+
+// handler returns the handler of the requests of s for the endpoint of
+// parent, which next answers when no route matches.
+func (s *session) handler(parent *Router, next http.Handler) http.Handler {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if h, ok := s.handlers[parent]; ok {
+		return h
+	}
+	child, err := parent.child(s)
+	if err != nil {
+		// The routes were validated when the session was created, against
+		// the endpoint that created it.
+		return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			writeError(w, http.StatusInternalServerError, "INTERNAL", fmt.Sprintf("Session %q: %v", s.id, err))
+		})
+	}
+	h := child.Wrap(next)
+	s.handlers[parent] = h
+	return h
+}
+
+// child returns the Router of the requests of s: the routes of s, then the
+// ones of r, all with their own scenario states.
+func (r *Router) child(s *session) (*Router, error) {
+	var routes []config.Route
+	for i, route := range s.routes {
+		if route.Name == "" {
+			route.Name = fmt.Sprintf("sessions[%s].routes[%d]", s.id, i)
+		}
+		routes = append(routes, route)
+	}
+	r.routesMu.RLock()
+	routes = append(routes, r.config...)
+	r.routesMu.RUnlock()
+	child, err := New(r.service, routes)
+	if err != nil {
+		return nil, err
+	}
+	child.now, child.verify, child.openapi, child.redactHeaders = r.now, r.verify, r.openapi, r.redactHeaders
+	child.journal, child.session = s.journal, s
+	seeding.mu.Lock()
+	if seeding.seed != nil {
+		child.Seed(*seeding.seed)
+	}
+	seeding.mu.Unlock()
+	return child, nil
+}
+
+// sessionRequest is the body of POST /__admin/sessions. JSON is valid YAML.
+type sessionRequest struct {
+	// ID is the id of the session, a random one if empty.
+	ID     string         `yaml:"id"`
+	Routes []config.Route `yaml:"routes"`
+	// Port asks for a dedicated port, any free one if 0.
+	Port *int `yaml:"port"`
+}
+
+// sessionInfo describes a session in the answers of the admin calls.
+type sessionInfo struct {
+	ID     string `json:"id"`
+	Port   int    `json:"port,omitempty"`
+	Routes int    `json:"routes"`
+}
+
+func (s *session) info() sessionInfo {
+	return sessionInfo{ID: s.id, Port: s.port, Routes: len(s.routes)}
+}
+
+// serveSessions answers the calls under SessionsPath:
+//
+//	GET    /__admin/sessions       lists the sessions
+//	POST   /__admin/sessions       creates a session
+//	DELETE /__admin/sessions       deletes every session
+//	GET    /__admin/sessions/<id>  describes a session
+//	DELETE /__admin/sessions/<id>  deletes a session
+func (r *Router) serveSessions(w http.ResponseWriter, req *http.Request) {
+	id := strings.TrimPrefix(strings.TrimPrefix(req.URL.Path, SessionsPath), "/")
+	switch {
+	case id == "" && req.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, map[string]any{"sessions": listSessions()})
+	case id == "" && req.Method == http.MethodPost:
+		r.createSession(w, req)
+	case id == "" && req.Method == http.MethodDelete:
+		writeJSON(w, http.StatusOK, map[string]any{"deleted": deleteSessions("")})
+	case id == "":
+		w.Header().Set("Allow", "GET, POST, DELETE")
+		writeError(w, http.StatusMethodNotAllowed, "INVALID_ARGUMENT", "Sessions are listed with GET, created with POST and deleted with DELETE")
+	case req.Method == http.MethodGet:
+		sessions.mu.Lock()
+		s := sessions.byID[id]
+		sessions.mu.Unlock()
+		if s == nil {
+			writeError(w, http.StatusNotFound, "NOT_FOUND", fmt.Sprintf("Session %q does not exist", id))
+			return
+		}
+		writeJSON(w, http.StatusOK, s.info())
+	case req.Method == http.MethodDelete:
+		if deleteSessions(id) == 0 {
+			writeError(w, http.StatusNotFound, "NOT_FOUND", fmt.Sprintf("Session %q does not exist", id))
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"deleted": 1})
+	default:
+		w.Header().Set("Allow", "GET, DELETE")
+		writeError(w, http.StatusMethodNotAllowed, "INVALID_ARGUMENT", "A session is read with GET and deleted with DELETE")
+	}
+}
+
+// createSession creates the session of the body of req, validating its
+// routes against the endpoint of r.
+func (r *Router) createSession(w http.ResponseWriter, req *http.Request) {
+	data, err := io.ReadAll(req.Body)
+	var body sessionRequest
+	if err == nil {
+		err = yaml.UnmarshalStrict(data, &body)
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_ARGUMENT", fmt.Sprintf("Invalid session request: %v", err))
+		return
+	}
+	if body.ID == "" {
+		body.ID = fmt.Sprintf("%016x", rand.Uint64())
+	}
+	if !sessionIDRe.MatchString(body.ID) {
+		writeError(w, http.StatusBadRequest, "INVALID_ARGUMENT", fmt.Sprintf("Invalid session id %q: only letters, digits, '_', '.' and '-' are allowed", body.ID))
+		return
+	}
+	if _, err := New(r.service, body.Routes); err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_ARGUMENT", fmt.Sprintf("Invalid session: %v", err))
+		return
+	}
+	for i, route := range body.Routes {
+		if route.Auth != nil && route.Auth.IssuedTokens && r.verify == nil {
+			writeError(w, http.StatusBadRequest, "INVALID_ARGUMENT", fmt.Sprintf("Invalid session: routes[%d].auth: issued_tokens needs oauth on the endpoint", i))
+			return
+		}
+	}
+	if body.Port != nil && r.handler == nil {
+		writeError(w, http.StatusBadRequest, "FAILED_PRECONDITION", "The endpoint cannot serve a session on a port of its own")
+		return
+	}
+
+	s := &session{id: body.ID, routes: body.Routes, journal: newJournal(r.journal.redactor), handlers: map[*Router]http.Handler{}}
+	sessions.mu.Lock()
+	defer sessions.mu.Unlock()
+	if _, ok := sessions.byID[s.id]; ok {
+		writeError(w, http.StatusConflict, "ALREADY_EXISTS", fmt.Sprintf("Session %q already exists", s.id))
+		return
+	}
+	if body.Port != nil {
+		ln, l, err := listen.Open(r.endpoint, int64(*body.Port))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "INVALID_ARGUMENT", fmt.Sprintf("Cannot listen for session %q: %v", s.id, err))
+			return
+		}
+		s.port = int(l.Port)
+		s.server = &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			r.handler.ServeHTTP(w, req.WithContext(context.WithValue(req.Context(), sessionKey{}, s)))
+		})}
+		go listen.ServeListener(s.server, ln)
+	}
+	sessions.byID[s.id] = s
+	if s.port != 0 {
+		fmt.Printf("Created session %s on port %d\n", s.id, s.port)
+	} else {
+		fmt.Printf("Created session %s\n", s.id)
+	}
+	writeJSON(w, http.StatusCreated, s.info())
+}
+
+// listSessions returns the sessions ordered by id.
+func listSessions() []sessionInfo {
+	sessions.mu.Lock()
+	defer sessions.mu.Unlock()
+	out := []sessionInfo{}
+	for _, s := range sessions.byID {
+		out = append(out, s.info())
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+// deleteSessions deletes the session id, or every session if id is empty,
+// closing their ports. It returns how many it deleted.
+func deleteSessions(id string) int {
+	sessions.mu.Lock()
+	defer sessions.mu.Unlock()
+	n := 0
+	for _, s := range sessions.byID {
+		if id != "" && s.id != id {
+			continue
+		}
+		if s.server != nil {
+			s.server.Close()
+		}
+		delete(sessions.byID, s.id)
+		fmt.Printf("Deleted session %s\n", s.id)
+		n++
+	}
+	return n
+}
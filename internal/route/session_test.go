@@ -0,0 +1,164 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package route
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/test-server/internal/config"
+	"github.com/google/test-server/internal/listen"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessions(t *testing.T) {
+	t.Cleanup(func() { deleteSessions("") })
+	handler, err := Handler(&config.EndpointConfig{
+		TargetHost: "example.googleapis.com",
+		Routes: []config.Route{
+			{Path: "/v1/models", Response: &config.Response{Body: `shared`}},
+		},
+	}, nil, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprintf(w, "proxied %s for %q", req.URL.Path, SessionOf(req))
+	}))
+	require.NoError(t, err)
+	send := func(method, target, body string, headers ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		for i := 0; i < len(headers); i += 2 {
+			req.Header.Set(headers[i], headers[i+1])
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+	decode := func(rec *httptest.ResponseRecorder) map[string]any {
+		var v map[string]any
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &v), rec.Body.String())
+		return v
+	}
+
+	rec := send("POST", SessionsPath, `{"id": "a", "routes": [{"path": "/v1/files", "response": {"body": "files of a"}}]}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	require.Equal(t, "a", decode(rec)["id"])
+	require.Equal(t, http.StatusCreated, send("POST", SessionsPath, `{"id": "b"}`).Code)
+	require.Equal(t, http.StatusConflict, send("POST", SessionsPath, `{"id": "b"}`).Code)
+	require.Equal(t, http.StatusBadRequest, send("POST", SessionsPath, `{"id": "../c"}`).Code)
+	require.Equal(t, http.StatusBadRequest, send("POST", SessionsPath, `{"routes": [{"path": "/{a}/{a}"}]}`).Code)
+
+	// The routes of a session come before the ones of the endpoint, and
+	// only apply to the session.
+	require.Equal(t, "files of a", send("GET", "/v1/files", "", SessionHeader, "a").Body.String())
+	require.Equal(t, "files of a", send("GET", SessionPrefix+"a/v1/files", "").Body.String())
+	require.Equal(t, "shared", send("GET", SessionPrefix+"a/v1/models", "").Body.String())
+	require.Equal(t, `proxied /v1/files for "b"`, send("GET", "/v1/files", "", SessionHeader, "b").Body.String())
+	require.Equal(t, `proxied /v1/files for ""`, send("GET", "/v1/files", "").Body.String())
+	require.Equal(t, http.StatusNotFound, send("GET", "/v1/files", "", SessionHeader, "c").Code)
+
+	// Every session has its own journal.
+	count := func(headers ...string) int {
+		return len(decode(send("GET", JournalPath, "", headers...))["requests"].([]any))
+	}
+	require.Equal(t, 3, count(SessionHeader, "a"))
+	require.Equal(t, 1, count(SessionHeader, "b"))
+	require.Equal(t, 1, count())
+	require.Equal(t, float64(3), decode(send("DELETE", SessionPrefix+"a"+JournalPath, ""))["cleared"])
+	require.Equal(t, 0, count(SessionHeader, "a"))
+	require.Equal(t, 1, count())
+
+	// A session on a port of its own needs neither the header nor the
+	// prefix.
+	rec = send("POST", SessionsPath, `{"id": "d", "port": 0, "routes": [{"path": "/v1/files", "response": {"body": "files of d"}}]}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	port := int(decode(rec)["port"].(float64))
+	resp, err := http.Get(fmt.Sprintf("http://localhost:%d/v1/files", port))
+	require.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.Equal(t, "files of d", string(body))
+
+	sessions := decode(send("GET", SessionsPath, ""))["sessions"].([]any)
+	require.Len(t, sessions, 3)
+	require.Equal(t, "a", sessions[0].(map[string]any)["id"])
+	require.Equal(t, http.StatusOK, send("GET", SessionsPath+"/d", "").Code)
+
+	require.Equal(t, http.StatusOK, send("DELETE", SessionsPath+"/d", "").Code)
+	require.Equal(t, http.StatusNotFound, send("DELETE", SessionsPath+"/d", "").Code)
+	_, err = http.Get(fmt.Sprintf("http://localhost:%d/v1/files", port))
+	require.Error(t, err)
+	require.Equal(t, float64(2), decode(send("DELETE", SessionsPath, ""))["deleted"])
+	require.Equal(t, http.StatusNotFound, send("GET", SessionPrefix+"a/v1/files", "").Code)
+}
+
+func TestSessionPort(t *testing.T) {
+	t.Cleanup(func() { deleteSessions("") })
+	dir := t.TempDir()
+	listen.SetTLS(listen.TLSOptions{Dir: dir})
+	t.Cleanup(func() { listen.SetTLS(listen.TLSOptions{}) })
+	create := func(ep *config.EndpointConfig, id string) int {
+		handler, err := Handler(ep, nil, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			fmt.Fprintf(w, "%s over %v", SessionOf(req), req.TLS != nil)
+		}))
+		require.NoError(t, err)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("POST", SessionsPath, strings.NewReader(`{"id": "`+id+`", "port": 0}`)))
+		require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+		var info sessionInfo
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &info))
+		return info.Port
+	}
+	get := func(c *http.Client, url string) string {
+		resp, err := c.Get(url)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return string(body)
+	}
+
+	// The port of a session on an https endpoint is served over TLS with
+	// the certificate of the endpoint.
+	port := create(&config.EndpointConfig{TargetHost: "example.googleapis.com", SourceType: "https"}, "secure")
+	data, err := os.ReadFile(filepath.Join(dir, listen.CAFile))
+	require.NoError(t, err)
+	roots := x509.NewCertPool()
+	require.True(t, roots.AppendCertsFromPEM(data))
+	secure := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+	require.Equal(t, "secure over true", get(secure, fmt.Sprintf("https://localhost:%d/", port)))
+	resp, err := http.Get(fmt.Sprintf("http://localhost:%d/", port))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	// It is bound to the bind_address of the endpoint.
+	port = create(&config.EndpointConfig{TargetHost: "example.googleapis.com", BindAddress: "127.0.0.1"}, "bound")
+	require.Equal(t, "bound over false", get(http.DefaultClient, fmt.Sprintf("http://127.0.0.1:%d/", port)))
+	if ln, err := net.Listen("tcp6", "[::1]:0"); err == nil {
+		ln.Close()
+		_, err = http.Get(fmt.Sprintf("http://[::1]:%d/", port))
+		require.Error(t, err)
+	}
+}
@@ -0,0 +1,322 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package route
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/google/test-server/internal/config"
+	"github.com/google/test-server/internal/fake"
+)
+
+// stub answers with a config.Response.
+type stub struct {
+	status  int
+	headers map[string]string
+	body    string
+	// binary is set for a body from body_base64 or body_file.
+	binary bool
+	// etag and modtime make the stub answer conditional requests.
+	etag    bool
+	modtime time.Time
+	// chunks, if set, are streamed instead of body.
+	chunks []chunk
+	// templates holds the parsed header values, by name, the body, under "",
+	// and the chunks, under "chunk <n>", if the response is a template.
+	templates map[string]*template.Template
+}
+
+// chunk is a part of a streamed stub response.
+type chunk struct {
+	data  string
+	delay time.Duration
+}
+
+// TemplateData is what the templates of a stub response can use, e.g.
+// {{.PathParams.model}}, {{.Query.Get "pageSize"}}, {{.Headers.Get
+// "X-Goog-Api-Key"}} or {{.Body.contents}}.
+type TemplateData struct {
+	Method     string
+	Path       string
+	PathParams map[string]string
+	Query      url.Values
+	Headers    http.Header
+	// Body is the JSON request body decoded into maps, slices, strings,
+	// float64s and bools, or nil if it is not JSON.
+	Body any
+	// RawBody is the request body as a string.
+	RawBody string
+}
+
+func (r *Router) newStub(resp *config.Response) (*stub, error) {
+	s := &stub{status: resp.Status, headers: map[string]string{}, body: resp.Body, etag: resp.ETag}
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	if s.status < 100 || s.status > 999 {
+		return nil, fmt.Errorf("invalid status %d", resp.Status)
+	}
+	bodies := 0
+	for _, set := range []bool{resp.Body != "", resp.BodyBase64 != "", resp.BodyFile != "", resp.Chunks != nil} {
+		if set {
+			bodies++
+		}
+	}
+	if bodies > 1 {
+		return nil, fmt.Errorf("body, body_base64, body_file and chunks cannot be combined")
+	}
+	if resp.BodyBase64 != "" {
+		// Block scalars may wrap the encoding over several lines.
+		b, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(resp.BodyBase64), ""))
+		if err != nil {
+			return nil, fmt.Errorf("invalid body_base64: %w", err)
+		}
+		s.body, s.binary = string(b), true
+	}
+	if resp.BodyFile != "" {
+		b, err := os.ReadFile(resp.BodyFile)
+		if err != nil {
+			return nil, fmt.Errorf("body_file: %w", err)
+		}
+		s.body, s.binary = string(b), true
+	}
+	for i, c := range resp.Chunks {
+		var d time.Duration
+		if c.Delay != "" {
+			var err error
+			if d, err = time.ParseDuration(c.Delay); err != nil {
+				return nil, fmt.Errorf("chunks[%d].delay: %w", i, err)
+			}
+			if d < 0 {
+				return nil, fmt.Errorf("chunks[%d].delay: must not be negative", i)
+			}
+		}
+		s.chunks = append(s.chunks, chunk{data: c.Data, delay: d})
+	}
+	if resp.LastModified != "" {
+		t, err := time.Parse(time.RFC3339, resp.LastModified)
+		if err != nil {
+			return nil, fmt.Errorf("invalid last_modified: %w", err)
+		}
+		s.modtime = t
+	}
+	for name, value := range resp.Headers {
+		s.headers[http.CanonicalHeaderKey(name)] = value
+	}
+	if !resp.Template {
+		return s, nil
+	}
+
+	s.templates = map[string]*template.Template{}
+	funcs := r.templateFuncs()
+	for key, text := range s.headers {
+		t, err := template.New("header " + key).Option("missingkey=zero").Funcs(funcs).Parse(text)
+		if err != nil {
+			return nil, err
+		}
+		s.templates[key] = t
+	}
+	if !s.binary {
+		t, err := template.New("body").Option("missingkey=zero").Funcs(funcs).Parse(s.body)
+		if err != nil {
+			return nil, err
+		}
+		s.templates[""] = t
+	}
+	for i, c := range s.chunks {
+		key := fmt.Sprintf("chunk %d", i)
+		var err error
+		if s.templates[key], err = template.New(key).Option("missingkey=zero").Funcs(funcs).Parse(c.data); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// render returns text, the header value of key or the body when key is "",
+// rendered for data if it is a template.
+func (s *stub) render(key, text string, data TemplateData) (string, error) {
+	t := s.templates[key]
+	if t == nil {
+		return text, nil
+	}
+	var b bytes.Buffer
+	err := t.Execute(&b, data)
+	return b.String(), err
+}
+
+// templateFuncs are the helper functions of the templates.
+func (r *Router) templateFuncs() template.FuncMap {
+	return template.FuncMap{
+		// now returns the current time, e.g. {{now.Format "2006-01-02"}} or
+		// {{now.Unix}}.
+		"now": func() time.Time { return r.now() },
+		// uuid returns a random version 4 UUID.
+		"uuid": func() string {
+			var b [16]byte
+			r.rngMu.Lock()
+			r.rng.Read(b[:])
+			r.rngMu.Unlock()
+			b[6] = b[6]&0x0f | 0x40
+			b[8] = b[8]&0x3f | 0x80
+			return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+		},
+		// random returns a random integer between min and max, inclusive.
+		"random": func(min, max int) (int, error) {
+			if max < min {
+				return 0, fmt.Errorf("random: max %d is less than min %d", max, min)
+			}
+			r.rngMu.Lock()
+			defer r.rngMu.Unlock()
+			return min + r.rng.Intn(max-min+1), nil
+		},
+		// fake returns a realistic fake value of a kind, in a locale if one
+		// is given, e.g. {{fake "name"}} or {{fake "email" "de_DE"}}; see
+		// package fake.
+		"fake": func(kind string, locale ...string) (string, error) {
+			if len(locale) > 1 {
+				return "", fmt.Errorf("fake: too many arguments")
+			}
+			r.rngMu.Lock()
+			defer r.rngMu.Unlock()
+			return fake.Value(r.rng, kind, strings.Join(locale, ""))
+		},
+		// json encodes a value as JSON, e.g. to copy a part of the request
+		// body: {{json .Body.contents}}.
+		"json": func(v any) (string, error) {
+			data, err := json.Marshal(v)
+			return string(data), err
+		},
+	}
+}
+
+// newTemplateData returns the template data of req, reading its body.
+func newTemplateData(req *http.Request, params map[string]string) (TemplateData, error) {
+	data := TemplateData{
+		Method:     req.Method,
+		Path:       req.URL.Path,
+		PathParams: params,
+		Query:      req.URL.Query(),
+		Headers:    req.Header,
+	}
+	if req.Body != nil {
+		raw, err := io.ReadAll(req.Body)
+		if err != nil {
+			return data, err
+		}
+		data.RawBody = string(raw)
+		if json.Unmarshal(raw, &data.Body) != nil {
+			data.Body = nil
+		}
+	}
+	return data, nil
+}
+
+func (s *stub) serve(w http.ResponseWriter, req *http.Request, params map[string]string) {
+	data, err := newTemplateData(req, params)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error reading request: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Render everything before answering, so that a template error is not
+	// hidden behind the stub status.
+	names := make([]string, 0, len(s.headers))
+	for name := range s.headers {
+		names = append(names, name)
+	}
+	// A stable order keeps the random values of a seeded run reproducible.
+	sort.Strings(names)
+	headers := map[string]string{}
+	for _, name := range names {
+		value, err := s.render(name, s.headers[name], data)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Error rendering the stub response: %v", err), http.StatusInternalServerError)
+			return
+		}
+		headers[name] = value
+	}
+	body, err := s.render("", s.body, data)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error rendering the stub response: %v", err), http.StatusInternalServerError)
+		return
+	}
+	chunks := make([]string, len(s.chunks))
+	for i, c := range s.chunks {
+		if chunks[i], err = s.render(fmt.Sprintf("chunk %d", i), c.data, data); err != nil {
+			http.Error(w, fmt.Sprintf("Error rendering the stub response: %v", err), http.StatusInternalServerError)
+			return
+		}
+	}
+	if s.chunks != nil {
+		body = strings.Join(chunks, "")
+	}
+
+	for name, value := range headers {
+		w.Header().Set(name, value)
+	}
+	if s.status == http.StatusOK && (s.etag || !s.modtime.IsZero()) {
+		if s.etag && w.Header().Get("ETag") == "" {
+			w.Header().Set("ETag", bodyETag(body))
+		}
+		if !s.modtime.IsZero() {
+			w.Header().Set("Last-Modified", s.modtime.UTC().Format(http.TimeFormat))
+		}
+		if notModified(req, w.Header().Get("ETag"), s.modtime) {
+			w.Header().Del("Content-Type")
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+	if w.Header().Get("Content-Type") == "" {
+		switch {
+		case s.binary:
+			w.Header().Set("Content-Type", "application/octet-stream")
+		case json.Valid([]byte(body)):
+			w.Header().Set("Content-Type", "application/json")
+		}
+	}
+	if s.chunks == nil {
+		w.WriteHeader(s.status)
+		io.WriteString(w, body)
+		return
+	}
+	// Without a Content-Length, flushing makes the response chunked.
+	w.Header().Del("Content-Length")
+	w.WriteHeader(s.status)
+	flusher, _ := w.(http.Flusher)
+	for i, c := range s.chunks {
+		time.Sleep(c.delay)
+		if _, err := io.WriteString(w, chunks[i]); err != nil {
+			return
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,213 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package route
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/test-server/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompilePath(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    map[string]string // nil when the path does not match
+	}{
+		{pattern: "/v1/models", path: "/v1/models", want: map[string]string{}},
+		{pattern: "/v1/models", path: "/v1/models/x"},
+		{pattern: "/v1/*", path: "/v1/models", want: map[string]string{}},
+		{pattern: "/v1/*", path: "/v1/models/x"},
+		{pattern: "/v1/models/{model}:generateContent", path: "/v1/models/gemini-pro:generateContent", want: map[string]string{"model": "gemini-pro"}},
+		{pattern: "/v1/{collection}/{id}", path: "/v1/files/abc", want: map[string]string{"collection": "files", "id": "abc"}},
+		{pattern: "/v1/{collection}/{id}", path: "/v1/files/"},
+		{pattern: "/v1/a.b", path: "/v1/axb"},
+	}
+	for _, tt := range tests {
+		re, err := compilePath(tt.pattern)
+		require.NoError(t, err, tt.pattern)
+		m := re.FindStringSubmatch(tt.path)
+		if tt.want == nil {
+			require.Nil(t, m, "%s on %s", tt.pattern, tt.path)
+			continue
+		}
+		require.NotNil(t, m, "%s on %s", tt.pattern, tt.path)
+		got := map[string]string{}
+		for i, name := range re.SubexpNames() {
+			if name != "" {
+				got[name] = m[i]
+			}
+		}
+		require.Equal(t, tt.want, got, "%s on %s", tt.pattern, tt.path)
+	}
+
+	for _, bad := range []string{"/v1/{id}/{id}", "/v1/{", "/v1/{1x}"} {
+		_, err := compilePath(bad)
+		require.Error(t, err, bad)
+	}
+}
+
+func TestStubs(t *testing.T) {
+	router, err := New("example.googleapis.com", []config.Route{
+		{
+			Method: "POST",
+			Path:   "/v1/models/{model}:generateContent",
+			Response: &config.Response{
+				Template: true,
+				Headers:  map[string]string{"x-request-id": "{{uuid}}", "X-Model": "{{.PathParams.model}}"},
+				Body: `{"model": "{{.PathParams.model}}", "echo": {{json .Body.contents}}, ` +
+					`"page": "{{.Query.Get "page"}}", "key": "{{.Headers.Get "X-Goog-Api-Key"}}", ` +
+					`"day": "{{now.Format "2006-01-02"}}", "n": {{random 3 3}}}`,
+			},
+		},
+		{Path: "/plain", Response: &config.Response{Status: 201, Body: "{{not a template}}"}},
+		{Path: "/broken", Response: &config.Response{Template: true, Body: "{{random 2 1}}"}},
+		{Path: "/unavailable", Response: &config.Response{Body: `{"ok": true}`}, Fault: &config.Fault{Status: 503}},
+	})
+	require.NoError(t, err)
+	router.now = func() time.Time { return time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC) }
+	handler := router.Wrap(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		t.Errorf("%s reached the next handler", req.URL.Path)
+	}))
+	send := func(method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("X-Goog-Api-Key", "secret")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := send("POST", "/v1/models/gemini-pro:generateContent?page=2", `{"contents": [{"text": "hi"}]}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.JSONEq(t, `{"model": "gemini-pro", "echo": [{"text": "hi"}], "page": "2", "key": "secret", "day": "2025-06-01", "n": 3}`, rec.Body.String())
+	require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	require.Equal(t, "gemini-pro", rec.Header().Get("X-Model"))
+	require.Regexp(t, `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`, rec.Header().Get("X-Request-Id"))
+
+	rec = send("GET", "/plain", "")
+	require.Equal(t, http.StatusCreated, rec.Code)
+	require.Equal(t, "{{not a template}}", rec.Body.String())
+
+	rec = send("GET", "/broken", "")
+	require.Equal(t, http.StatusInternalServerError, rec.Code)
+	require.Contains(t, rec.Body.String(), "max 1 is less than min 2")
+
+	require.Equal(t, http.StatusServiceUnavailable, send("GET", "/unavailable", "").Code)
+}
+
+func TestChunkedStubs(t *testing.T) {
+	server := serve(t, config.Route{
+		Path: "/v1/models/{model}:streamGenerateContent",
+		Response: &config.Response{
+			Headers:  map[string]string{"Content-Type": "text/event-stream"},
+			Template: true,
+			Chunks: []config.Chunk{
+				{Data: "data: {\"model\": \"{{.PathParams.model}}\"}\n\n"},
+				{Data: "data: {\"done\": true}\n\n", Delay: "50ms"},
+			},
+		},
+	})
+
+	start := time.Now()
+	resp, body, err := get(t, server.URL+"/v1/models/gemini:streamGenerateContent")
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, []string{"chunked"}, resp.TransferEncoding)
+	require.Equal(t, "data: {\"model\": \"gemini\"}\n\ndata: {\"done\": true}\n\n", body)
+}
+
+func TestBinaryStubs(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\xff")
+	file := filepath.Join(t.TempDir(), "image.png")
+	require.NoError(t, os.WriteFile(file, png, 0644))
+	server := serve(t,
+		config.Route{Path: "/inline", Response: &config.Response{
+			Headers:    map[string]string{"Content-Type": "image/png", "X-Path": "{{.Path}}"},
+			Template:   true,
+			BodyBase64: base64.StdEncoding.EncodeToString(png)[:12] + "\n  " + base64.StdEncoding.EncodeToString(png)[12:],
+		}},
+		config.Route{Path: "/file", Response: &config.Response{BodyFile: file}},
+	)
+
+	resp, body, err := get(t, server.URL+"/inline")
+	require.NoError(t, err)
+	require.Equal(t, string(png), body)
+	require.Equal(t, "image/png", resp.Header.Get("Content-Type"))
+	require.Equal(t, "/inline", resp.Header.Get("X-Path"))
+
+	resp, body, err = get(t, server.URL+"/file")
+	require.NoError(t, err)
+	require.Equal(t, string(png), body)
+	require.Equal(t, "application/octet-stream", resp.Header.Get("Content-Type"))
+
+	_, err = New("example.googleapis.com", []config.Route{{Response: &config.Response{BodyBase64: "not base64!"}}})
+	require.Error(t, err)
+	_, err = New("example.googleapis.com", []config.Route{{Response: &config.Response{BodyFile: filepath.Join(t.TempDir(), "missing")}}})
+	require.Error(t, err)
+	_, err = New("example.googleapis.com", []config.Route{{Response: &config.Response{Body: "a", BodyFile: file}}})
+	require.Error(t, err)
+}
+
+func TestFakeTemplates(t *testing.T) {
+	fetch := func(seed int64) string {
+		handler, err := Handler(&config.EndpointConfig{Seed: &seed, Routes: []config.Route{{
+			Path: "/v1/users",
+			Response: &config.Response{Template: true, Body: `[` +
+				`{"name": "{{fake "name"}}", "email": "{{fake "email"}}", "id": "{{uuid}}"},` +
+				`{"name": "{{fake "name" "ja_JP"}}", "city": "{{fake "city" "de_DE"}}", "n": {{random 1 100}}}]`},
+		}}}, nil, http.NotFoundHandler())
+		require.NoError(t, err)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", "/v1/users", nil))
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		var users []map[string]any
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &users), rec.Body.String())
+		return rec.Body.String()
+	}
+	// A seeded endpoint gives the same data on every run.
+	require.Equal(t, fetch(42), fetch(42))
+	require.NotEqual(t, fetch(42), fetch(43))
+
+	server := serve(t, config.Route{Path: "/bad", Response: &config.Response{Template: true, Body: `{{fake "shoe_size"}}`}})
+	resp, body, err := get(t, server.URL+"/bad")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	require.Contains(t, body, "unknown kind")
+}
+
+func TestStubRejectsInvalidTemplates(t *testing.T) {
+	_, err := New("example.googleapis.com", []config.Route{{Response: &config.Response{Template: true, Body: "{{.Body"}}})
+	require.Error(t, err)
+	_, err = New("example.googleapis.com", []config.Route{{Response: &config.Response{Status: 7}}})
+	require.Error(t, err)
+	_, err = New("example.googleapis.com", []config.Route{{Response: &config.Response{LastModified: "yesterday"}}})
+	require.Error(t, err)
+	_, err = New("example.googleapis.com", []config.Route{{Response: &config.Response{Body: "a", Chunks: []config.Chunk{{Data: "b"}}}}})
+	require.Error(t, err)
+	_, err = New("example.googleapis.com", []config.Route{{Response: &config.Response{Chunks: []config.Chunk{{Data: "b", Delay: "-1s"}}}}})
+	require.Error(t, err)
+}
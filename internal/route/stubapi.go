@@ -0,0 +1,225 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package route
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/google/test-server/internal/config"
+	"gopkg.in/yaml.v2"
+)
+
+// AdminPath is the path under which an endpoint answers the admin calls.
+const AdminPath = "/__admin"
+
+// StubsPath is the path of the admin calls that list, create, update and
+// delete the routes of an endpoint while it runs, e.g. PUT
+// /__admin/stubs/<name>.
+const StubsPath = "/__admin/stubs"
+
+// ResetPath is the path of the admin call that puts an endpoint back in the
+// state it started in.
+const ResetPath = "/__admin/reset"
+
+// serveStubs answers the calls under StubsPath:
+//
+//	GET    /__admin/stubs         lists the routes, in the order they match
+//	POST   /__admin/stubs         adds a route before the others
+//	DELETE /__admin/stubs         restores the routes of the configuration
+//	GET    /__admin/stubs/<name>  returns a route
+//	PUT    /__admin/stubs/<name>  replaces a route
+//	DELETE /__admin/stubs/<name>  deletes a route
+//
+// The routes are written like the ones of the configuration; JSON is valid
+// YAML.
+func (r *Router) serveStubs(w http.ResponseWriter, req *http.Request) {
+	name := strings.TrimPrefix(strings.TrimPrefix(req.URL.Path, StubsPath), "/")
+	r.stubsMu.Lock()
+	defer r.stubsMu.Unlock()
+	r.routesMu.RLock()
+	routes := append([]config.Route(nil), r.config...)
+	r.routesMu.RUnlock()
+	i := -1
+	for j, route := range routes {
+		if route.Name == name {
+			i = j
+			break
+		}
+	}
+
+	switch {
+	case name == "" && req.Method == http.MethodGet:
+		stubs := make([]any, len(routes))
+		for j, route := range routes {
+			stubs[j] = jsonRoute(route)
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"stubs": stubs})
+	case name == "" && req.Method == http.MethodPost:
+		route, ok := readRoute(w, req)
+		if !ok {
+			return
+		}
+		if route.Name == "" {
+			r.stubs++
+			route.Name = fmt.Sprintf("stubs[%d]", r.stubs)
+		}
+		for _, other := range routes {
+			if other.Name == route.Name {
+				writeError(w, http.StatusConflict, "ALREADY_EXISTS", fmt.Sprintf("A stub named %q already exists", route.Name))
+				return
+			}
+		}
+		if !r.changeStubs(w, append([]config.Route{route}, routes...)) {
+			return
+		}
+		fmt.Printf("Added the stub %s\n", route.Name)
+		writeJSON(w, http.StatusCreated, jsonRoute(route))
+	case name == "" && req.Method == http.MethodDelete:
+		if !r.changeStubs(w, r.initial) {
+			return
+		}
+		fmt.Printf("Restored the %d configured stubs\n", len(r.initial))
+		writeJSON(w, http.StatusOK, map[string]any{"stubs": len(r.initial)})
+	case name == "":
+		w.Header().Set("Allow", "GET, POST, DELETE")
+		writeError(w, http.StatusMethodNotAllowed, "INVALID_ARGUMENT", "Stubs are listed with GET, added with POST and restored with DELETE")
+	case req.Method != http.MethodGet && req.Method != http.MethodPut && req.Method != http.MethodDelete:
+		w.Header().Set("Allow", "GET, PUT, DELETE")
+		writeError(w, http.StatusMethodNotAllowed, "INVALID_ARGUMENT", "A stub is read with GET, replaced with PUT and deleted with DELETE")
+	case i < 0:
+		writeError(w, http.StatusNotFound, "NOT_FOUND", fmt.Sprintf("No stub is named %q", name))
+	case req.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, jsonRoute(routes[i]))
+	case req.Method == http.MethodPut:
+		route, ok := readRoute(w, req)
+		if !ok {
+			return
+		}
+		route.Name = name
+		routes[i] = route
+		if !r.changeStubs(w, routes) {
+			return
+		}
+		fmt.Printf("Replaced the stub %s\n", name)
+		writeJSON(w, http.StatusOK, jsonRoute(route))
+	case req.Method == http.MethodDelete:
+		if !r.changeStubs(w, append(routes[:i], routes[i+1:]...)) {
+			return
+		}
+		fmt.Printf("Deleted the stub %s\n", name)
+		writeJSON(w, http.StatusOK, map[string]any{"deleted": name})
+	}
+}
+
+// readRoute reads the route of the body of req, answering false with an
+// error if it is invalid.
+func readRoute(w http.ResponseWriter, req *http.Request) (config.Route, bool) {
+	var route config.Route
+	data, err := io.ReadAll(req.Body)
+	if err == nil {
+		err = yaml.UnmarshalStrict(data, &route)
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_ARGUMENT", fmt.Sprintf("Invalid stub: %v", err))
+		return route, false
+	}
+	return route, true
+}
+
+// changeStubs makes routes the routes of r, answering false with an error if
+// one of them is invalid.
+func (r *Router) changeStubs(w http.ResponseWriter, routes []config.Route) bool {
+	for _, route := range routes {
+		if route.Auth != nil && route.Auth.IssuedTokens && r.verify == nil {
+			writeError(w, http.StatusBadRequest, "INVALID_ARGUMENT", fmt.Sprintf("Invalid stub %s: auth: issued_tokens needs oauth on the endpoint", route.Name))
+			return false
+		}
+	}
+	if err := r.setRoutes(routes); err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_ARGUMENT", fmt.Sprintf("Invalid stub: %v", err))
+		return false
+	}
+	return true
+}
+
+// serveReset answers POST /__admin/reset by restoring the routes of the
+// configuration, moving every scenario back to its start and clearing the
+// journal.
+func (r *Router) serveReset(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, "INVALID_ARGUMENT", "The endpoint is reset with POST")
+		return
+	}
+	r.stubsMu.Lock()
+	defer r.stubsMu.Unlock()
+	if !r.changeStubs(w, r.initial) {
+		return
+	}
+	r.scenarios.reset()
+	cleared := r.journal.reset()
+	fmt.Printf("Reset the endpoint %s\n", r.service)
+	writeJSON(w, http.StatusOK, map[string]any{"stubs": len(r.initial), "cleared": cleared})
+}
+
+// jsonRoute returns route as the JSON of its configuration, without the
+// fields that are not set.
+func jsonRoute(route config.Route) any {
+	data, err := yaml.Marshal(route)
+	if err != nil {
+		return nil
+	}
+	var v any
+	if err := yaml.Unmarshal(data, &v); err != nil {
+		return nil
+	}
+	return plain(v)
+}
+
+// plain converts a value decoded from YAML into one encoding/json can write,
+// dropping the empty values.
+func plain(v any) any {
+	switch v := v.(type) {
+	case map[any]any:
+		m := map[string]any{}
+		for k, value := range v {
+			if value = plain(value); value != nil {
+				m[fmt.Sprint(k)] = value
+			}
+		}
+		if len(m) == 0 {
+			return nil
+		}
+		return m
+	case []any:
+		if len(v) == 0 {
+			return nil
+		}
+		for i, value := range v {
+			v[i] = plain(value)
+		}
+		return v
+	case string, bool, int:
+		if v == "" || v == false || v == 0 {
+			return nil
+		}
+	}
+	return v
+}
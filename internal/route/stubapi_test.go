@@ -0,0 +1,100 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package route
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/test-server/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStubAPI(t *testing.T) {
+	handler, err := Handler(&config.EndpointConfig{
+		TargetHost: "example.googleapis.com",
+		Routes: []config.Route{
+			{Path: "/v1/models", Response: &config.Response{Body: `configured`}},
+			{Name: "retry", Path: "/v1/retry", Scenario: "retry", RequiredState: StartedState, NewState: "retried", Response: &config.Response{Status: 503}},
+		},
+	}, nil, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("proxied"))
+	}))
+	require.NoError(t, err)
+	send := func(method, target, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return rec
+	}
+	decode := func(rec *httptest.ResponseRecorder) map[string]any {
+		var v map[string]any
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &v), rec.Body.String())
+		return v
+	}
+	names := func() []string {
+		var out []string
+		for _, stub := range decode(send("GET", StubsPath, ""))["stubs"].([]any) {
+			out = append(out, stub.(map[string]any)["name"].(string))
+		}
+		return out
+	}
+
+	require.Equal(t, []string{"routes[0]", "retry"}, names())
+	rec := send("GET", StubsPath+"/routes[0]", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, map[string]any{"name": "routes[0]", "path": "/v1/models", "response": map[string]any{"body": "configured"}}, decode(rec))
+
+	// An added stub comes before the others.
+	rec = send("POST", StubsPath, `{"path": "/v1/models", "response": {"body": "added"}}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	require.Equal(t, "stubs[1]", decode(rec)["name"])
+	require.Equal(t, "added", send("GET", "/v1/models", "").Body.String())
+	rec = send("POST", StubsPath, "name: files\npath: /v1/files\nresponse:\n  body: '[]'\n")
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	require.Equal(t, []string{"files", "stubs[1]", "routes[0]", "retry"}, names())
+	require.Equal(t, http.StatusConflict, send("POST", StubsPath, `{"name": "files"}`).Code)
+	require.Equal(t, http.StatusBadRequest, send("POST", StubsPath, `{"path": "/{a}/{a}"}`).Code)
+	require.Equal(t, http.StatusBadRequest, send("POST", StubsPath, `{"colour": "red"}`).Code)
+
+	rec = send("PUT", StubsPath+"/files", `{"path": "/v1/files", "response": {"body": "[1]"}}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.Equal(t, "[1]", send("GET", "/v1/files", "").Body.String())
+	require.Equal(t, http.StatusNotFound, send("PUT", StubsPath+"/nothing", `{}`).Code)
+	require.Equal(t, http.StatusOK, send("DELETE", StubsPath+"/stubs[1]", "").Code)
+	require.Equal(t, "configured", send("GET", "/v1/models", "").Body.String())
+	require.Equal(t, http.StatusOK, send("DELETE", StubsPath+"/routes[0]", "").Code)
+	require.Equal(t, "proxied", send("GET", "/v1/models", "").Body.String())
+	require.Equal(t, http.StatusMethodNotAllowed, send("PATCH", StubsPath+"/files", "").Code)
+
+	// Restoring the configuration drops the changes.
+	require.Equal(t, float64(2), decode(send("DELETE", StubsPath, ""))["stubs"])
+	require.Equal(t, []string{"routes[0]", "retry"}, names())
+
+	// A reset also restarts the scenarios and clears the journal.
+	send("POST", StubsPath, `{"path": "/v1/files"}`)
+	require.Equal(t, http.StatusServiceUnavailable, send("GET", "/v1/retry", "").Code)
+	require.Equal(t, "proxied", send("GET", "/v1/retry", "").Body.String())
+	rec = send("POST", ResetPath, "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, float64(2), decode(rec)["stubs"])
+	require.Equal(t, []string{"routes[0]", "retry"}, names())
+	require.Equal(t, http.StatusServiceUnavailable, send("GET", "/v1/retry", "").Code)
+	require.Len(t, decode(send("GET", JournalPath, ""))["requests"], 1)
+}
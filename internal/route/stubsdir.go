@@ -0,0 +1,157 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package route
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/test-server/internal/config"
+	"github.com/google/test-server/internal/watch"
+	"gopkg.in/yaml.v2"
+)
+
+// reloadInterval is how often the stub files and recordings are checked for
+// changes.
+var reloadInterval = time.Second
+
+// isStubFile reports whether path is a stub file by its extension.
+func isStubFile(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml", ".json":
+		return true
+	}
+	return false
+}
+
+// loadStubsDir returns the routes of the stub files of dir, in the order of
+// their paths. The routes without a name are named after their file, e.g.
+// users.yaml[0].
+func loadStubsDir(host, dir string) ([]config.Route, error) {
+	if _, err := os.Stat(dir); err != nil {
+		return nil, err
+	}
+	snapshot, err := watch.Scan(dir, isStubFile)
+	if err != nil {
+		return nil, err
+	}
+	var routes []config.Route
+	for _, path := range snapshot.Paths() {
+		rel, _ := filepath.Rel(dir, path)
+		rel = filepath.ToSlash(rel)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var file []config.Route
+		if err := yaml.UnmarshalStrict(data, &file); err != nil {
+			return nil, fmt.Errorf("%s: %w", rel, err)
+		}
+		if _, err := New(host, file); err != nil {
+			return nil, fmt.Errorf("%s: %w", rel, err)
+		}
+		for i, route := range file {
+			if route.Name == "" {
+				route.Name = fmt.Sprintf("%s[%d]", rel, i)
+			}
+			routes = append(routes, route)
+		}
+	}
+	return routes, nil
+}
+
+// MarshalStubs returns routes as the content of a stub file, without the
+// fields that are not set.
+func MarshalStubs(routes []config.Route) ([]byte, error) {
+	file := make([]any, len(routes))
+	for i, route := range routes {
+		if file[i] = jsonRoute(route); file[i] == nil {
+			return nil, fmt.Errorf("failed to marshal route %q", route.Name)
+		}
+	}
+	return yaml.Marshal(file)
+}
+
+// watchStubs reloads the stub files of dir when they change, with base,
+// the routes of the configuration, before them, until stop is called.
+func (r *Router) watchStubs(base []config.Route, dir string) (stop func()) {
+	return watch.Dir(dir, reloadInterval, isStubFile, func(paths []string) {
+		r.reloadStubs(base, dir, paths)
+	})
+}
+
+// reloadStubs replaces the routes of the configuration and stub files of r
+// after the files changed. The stubs added with the admin API stay before
+// them; the configured ones replaced or deleted with it are restored.
+func (r *Router) reloadStubs(base []config.Route, dir string, changed []string) {
+	files := make([]string, len(changed))
+	for i, path := range changed {
+		rel, _ := filepath.Rel(dir, path)
+		files[i] = filepath.ToSlash(rel)
+	}
+	fail := func(err error) {
+		PublishEvent(AdminEvent{
+			Type:     "stubs_reload_failed",
+			Endpoint: r.service,
+			Message:  fmt.Sprintf("Kept the stubs of %s, failed to reload %s", r.service, dir),
+			Files:    files,
+			Error:    err.Error(),
+		})
+	}
+	loaded, err := loadStubsDir(r.service, dir)
+	if err != nil {
+		fail(err)
+		return
+	}
+	initial := append(append([]config.Route{}, base...), loaded...)
+
+	r.stubsMu.Lock()
+	defer r.stubsMu.Unlock()
+	configured := map[string]bool{}
+	for _, route := range r.initial {
+		configured[route.Name] = true
+	}
+	var routes []config.Route
+	r.routesMu.RLock()
+	for _, route := range r.config {
+		if !configured[route.Name] {
+			routes = append(routes, route)
+		}
+	}
+	r.routesMu.RUnlock()
+	routes = append(routes, initial...)
+	for _, route := range routes {
+		if route.Auth != nil && route.Auth.IssuedTokens && r.verify == nil {
+			fail(fmt.Errorf("%s.auth: issued_tokens needs oauth on the endpoint", route.Name))
+			return
+		}
+	}
+	if err := r.setRoutes(routes); err != nil {
+		fail(err)
+		return
+	}
+	r.initial = initial
+	PublishEvent(AdminEvent{
+		Type:     "stubs_reloaded",
+		Endpoint: r.service,
+		Message:  fmt.Sprintf("Reloaded the stubs of %s from %s: %s", r.service, dir, strings.Join(files, ", ")),
+		Files:    files,
+	})
+}
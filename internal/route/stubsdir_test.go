@@ -0,0 +1,99 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package route
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/test-server/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStubsDir(t *testing.T) {
+	interval := reloadInterval
+	reloadInterval = 10 * time.Millisecond
+	t.Cleanup(func() { reloadInterval = interval })
+	dir := t.TempDir()
+	write := func(name, content string) {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+		// Every write is a new version, even within the resolution of the
+		// modification times.
+		later := time.Now().Add(time.Duration(len(content)) * time.Second)
+		require.NoError(t, os.Chtimes(path, later, later))
+	}
+	write("users.yaml", "- path: /v1/users\n  response:\n    body: users\n")
+	write("notes.txt", "not a stub")
+
+	cfg := &config.EndpointConfig{
+		TargetHost: "example.googleapis.com",
+		Routes:     []config.Route{{Path: "/v1/users", Method: "DELETE", Response: &config.Response{Status: 204}}},
+		StubsDir:   dir,
+	}
+	router, handler, err := build(cfg, http.NotFoundHandler())
+	require.NoError(t, err)
+	t.Cleanup(router.watchStubs(router.initial[:1], dir))
+	send := func(method, target, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return rec
+	}
+	events := func() []AdminEvent {
+		var v struct{ Events []AdminEvent }
+		require.NoError(t, json.Unmarshal(send("GET", AdminEventsPath, "").Body.Bytes(), &v))
+		return v.Events
+	}
+	require.Equal(t, "users", send("GET", "/v1/users", "").Body.String())
+	require.Equal(t, http.StatusNoContent, send("DELETE", "/v1/users", "").Code)
+	var stubs struct{ Stubs []map[string]any }
+	require.NoError(t, json.Unmarshal(send("GET", StubsPath, "").Body.Bytes(), &stubs))
+	require.Equal(t, "users.yaml[0]", stubs.Stubs[1]["name"])
+
+	// A stub added with the admin API outlives the reloads.
+	require.Equal(t, http.StatusCreated, send("POST", StubsPath, `{"path": "/v1/added", "response": {"body": "added"}}`).Code)
+	seen := len(events())
+	write("users.yaml", "- path: /v1/users\n  response:\n    body: reloaded users\n")
+	write("files.json", `[{"path": "/v1/files", "response": {"body": "files"}}]`)
+	require.Eventually(t, func() bool {
+		return send("GET", "/v1/users", "").Body.String() == "reloaded users" && send("GET", "/v1/files", "").Body.String() == "files"
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Equal(t, "added", send("GET", "/v1/added", "").Body.String())
+	require.Equal(t, http.StatusNoContent, send("DELETE", "/v1/users", "").Code)
+	reloaded := events()[seen]
+	require.Equal(t, "stubs_reloaded", reloaded.Type)
+	require.Equal(t, "example.googleapis.com", reloaded.Endpoint)
+
+	// A broken file keeps the stubs as they were.
+	seen = len(events())
+	write("users.yaml", "- path: /{a}/{a}\n")
+	require.Eventually(t, func() bool { return len(events()) > seen }, 5*time.Second, 10*time.Millisecond)
+	failed := events()[seen]
+	require.Equal(t, "stubs_reload_failed", failed.Type)
+	require.Equal(t, []string{"users.yaml"}, failed.Files)
+	require.Contains(t, failed.Error, "users.yaml: routes[0]")
+	require.Equal(t, "reloaded users", send("GET", "/v1/users", "").Body.String())
+
+	_, err = Handler(&config.EndpointConfig{StubsDir: filepath.Join(dir, "missing")}, nil, http.NotFoundHandler())
+	require.ErrorContains(t, err, "stubs_dir: ")
+}
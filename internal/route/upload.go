@@ -0,0 +1,274 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package route
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/google/test-server/internal/config"
+)
+
+// uploads holds the resumable upload sessions of a route.
+type uploads struct {
+	cfg *config.Upload
+
+	mu       sync.Mutex
+	sessions map[string]*uploadSession
+}
+
+type uploadSession struct {
+	// metadata is the JSON object sent to start the session.
+	metadata    map[string]any
+	contentType string
+	// total is the size of the upload, or -1 until the client says it.
+	total    int64
+	received int64
+	md5      hash.Hash
+	// fired marks the interruptions that broke the session.
+	fired map[int]bool
+	done  bool
+}
+
+func newUploads(c *config.Upload) (*uploads, error) {
+	for i, in := range c.Interruptions {
+		switch {
+		case in.Offset < 0:
+			return nil, fmt.Errorf("interruptions[%d]: offset must not be negative", i)
+		case in.Reset && in.Status != 0:
+			return nil, fmt.Errorf("interruptions[%d]: status and reset cannot be combined", i)
+		case in.Status != 0 && (in.Status < 400 || in.Status > 599):
+			return nil, fmt.Errorf("interruptions[%d]: status %d is not an error", i, in.Status)
+		}
+	}
+	return &uploads{cfg: c, sessions: map[string]*uploadSession{}}, nil
+}
+
+// finisher answers the requests about a finished upload.
+type finisher func(w http.ResponseWriter, req *http.Request, s *uploadSession)
+
+// serveUpload handles the requests of the protocol. A request that neither
+// starts nor continues an upload goes to next.
+func (r *Router) serveUpload(u *uploads, w http.ResponseWriter, req *http.Request, next http.Handler, finish finisher) {
+	q := req.URL.Query()
+	id := q.Get("upload_id")
+	switch {
+	case id != "":
+	case req.Method == http.MethodPost && q.Get("uploadType") == "resumable":
+		r.startUpload(u, w, req)
+		return
+	default:
+		next.ServeHTTP(w, req)
+		return
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	s, ok := u.sessions[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, "NOT_FOUND", "No such upload session.")
+		return
+	}
+	switch req.Method {
+	case http.MethodDelete:
+		delete(u.sessions, id)
+		// 499 is what Google APIs answer a cancelled upload with.
+		w.WriteHeader(499)
+	case http.MethodPut, http.MethodPost:
+		u.chunk(s, w, req, finish)
+	default:
+		w.Header().Set("Allow", "PUT, POST, DELETE")
+		writeError(w, http.StatusMethodNotAllowed, "INVALID_ARGUMENT", "Method not allowed for an upload session.")
+	}
+}
+
+func (r *Router) startUpload(u *uploads, w http.ResponseWriter, req *http.Request) {
+	s := &uploadSession{contentType: req.Header.Get("X-Upload-Content-Type"), total: -1, md5: md5.New(), fired: map[int]bool{}}
+	if n, err := strconv.ParseInt(req.Header.Get("X-Upload-Content-Length"), 10, 64); err == nil && n >= 0 {
+		s.total = n
+	}
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error reading request: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if len(body) > 0 && json.Unmarshal(body, &s.metadata) != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_ARGUMENT", "The upload metadata is not a JSON object.")
+		return
+	}
+
+	var b [12]byte
+	r.rngMu.Lock()
+	r.rng.Read(b[:])
+	r.rngMu.Unlock()
+	id := base64.RawURLEncoding.EncodeToString(b[:])
+	u.mu.Lock()
+	u.sessions[id] = s
+	u.mu.Unlock()
+
+	scheme := "http"
+	if req.TLS != nil {
+		scheme = "https"
+	}
+	q := req.URL.Query()
+	q.Set("upload_id", id)
+	fmt.Printf("Starting upload session %s\n", id)
+	w.Header().Set("Location", fmt.Sprintf("%s://%s%s?%s", scheme, req.Host, req.URL.Path, q.Encode()))
+	w.WriteHeader(http.StatusOK)
+}
+
+// contentRangeRe matches the Content-Range of a chunk, bytes 0-99/1000,
+// bytes 0-99/*, or of a status query, bytes */1000 or bytes */*.
+var contentRangeRe = regexp.MustCompile(`^bytes (?:(\d+)-(\d+)|\*)/(\d+|\*)$`)
+
+// chunk stores a chunk of s, or answers a status query. The caller holds
+// u.mu.
+func (u *uploads) chunk(s *uploadSession, w http.ResponseWriter, req *http.Request, finish finisher) {
+	data, err := io.ReadAll(req.Body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error reading request: %v", err), http.StatusInternalServerError)
+		return
+	}
+	start, total := int64(0), int64(len(data))
+	if cr := req.Header.Get("Content-Range"); cr != "" {
+		m := contentRangeRe.FindStringSubmatch(cr)
+		if m == nil {
+			writeError(w, http.StatusBadRequest, "INVALID_ARGUMENT", fmt.Sprintf("Invalid Content-Range %q.", cr))
+			return
+		}
+		total = -1
+		if m[3] != "*" {
+			total, _ = strconv.ParseInt(m[3], 10, 64)
+		}
+		if m[1] != "" {
+			start, _ = strconv.ParseInt(m[1], 10, 64)
+			end, _ := strconv.ParseInt(m[2], 10, 64)
+			if end < start || end-start+1 != int64(len(data)) {
+				writeError(w, http.StatusBadRequest, "INVALID_ARGUMENT", fmt.Sprintf("Content-Range %q does not match the %d bytes sent.", cr, len(data)))
+				return
+			}
+		} else if len(data) > 0 {
+			writeError(w, http.StatusBadRequest, "INVALID_ARGUMENT", "A status query must not carry data.")
+			return
+		} else {
+			start = s.received
+		}
+	}
+	if total >= 0 {
+		if s.total >= 0 && s.total != total {
+			writeError(w, http.StatusBadRequest, "INVALID_ARGUMENT", fmt.Sprintf("The upload size changed from %d to %d.", s.total, total))
+			return
+		}
+		s.total = total
+	}
+	if s.done {
+		finish(w, req, s)
+		return
+	}
+	if start > s.received {
+		writeError(w, http.StatusBadRequest, "INVALID_ARGUMENT", fmt.Sprintf("The chunk starts at %d but only %d bytes were received.", start, s.received))
+		return
+	}
+	// A chunk sent again after an interruption may overlap what was kept.
+	if skip := s.received - start; skip < int64(len(data)) {
+		data = data[skip:]
+	} else {
+		data = nil
+	}
+	if s.total >= 0 && s.received+int64(len(data)) > s.total {
+		writeError(w, http.StatusBadRequest, "INVALID_ARGUMENT", fmt.Sprintf("The upload exceeds its size of %d bytes.", s.total))
+		return
+	}
+
+	if i, in := u.interruption(s, int64(len(data))); in != nil {
+		s.fired[i] = true
+		s.store(data[:in.Offset-s.received])
+		fmt.Printf("Interrupting an upload at byte %d\n", s.received)
+		if in.Reset {
+			reset(w)
+			return
+		}
+		status := in.Status
+		if status == 0 {
+			status = http.StatusServiceUnavailable
+		}
+		writeError(w, status, strings.ToUpper(strings.ReplaceAll(http.StatusText(status), " ", "_")), "Upload interrupted by test-server")
+		return
+	}
+	s.store(data)
+
+	if s.total >= 0 && s.received == s.total {
+		s.done = true
+		fmt.Printf("Finished an upload of %d bytes\n", s.received)
+		finish(w, req, s)
+		return
+	}
+	if s.received > 0 {
+		w.Header().Set("Range", fmt.Sprintf("bytes=0-%d", s.received-1))
+	}
+	// 308 Resume Incomplete.
+	w.WriteHeader(http.StatusPermanentRedirect)
+}
+
+// interruption returns the first interruption of u that has not broken s yet
+// and whose offset falls within the next n bytes of s.
+func (u *uploads) interruption(s *uploadSession, n int64) (int, *config.UploadInterruption) {
+	order := make([]int, len(u.cfg.Interruptions))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return u.cfg.Interruptions[order[a]].Offset < u.cfg.Interruptions[order[b]].Offset
+	})
+	for _, i := range order {
+		in := &u.cfg.Interruptions[i]
+		if !s.fired[i] && in.Offset >= s.received && in.Offset < s.received+n {
+			return i, in
+		}
+	}
+	return 0, nil
+}
+
+func (s *uploadSession) store(data []byte) {
+	s.md5.Write(data)
+	s.received += int64(len(data))
+}
+
+// resource is the default answer to a finished upload: the metadata it was
+// started with, with its size, content type and MD5 hash.
+func (s *uploadSession) resource() map[string]any {
+	out := map[string]any{}
+	for k, v := range s.metadata {
+		out[k] = v
+	}
+	out["size"] = strconv.FormatInt(s.received, 10)
+	out["md5Hash"] = base64.StdEncoding.EncodeToString(s.md5.Sum(nil))
+	if _, ok := out["contentType"]; !ok && s.contentType != "" {
+		out["contentType"] = s.contentType
+	}
+	return out
+}
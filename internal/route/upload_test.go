@@ -0,0 +1,151 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package route
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/test-server/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+// do sends a request with the given Content-Range, if any, and returns the
+// response and its body.
+func do(t *testing.T, method, target, contentRange, body string, headers ...string) (*http.Response, string, error) {
+	t.Helper()
+	req, err := http.NewRequest(method, target, strings.NewReader(body))
+	require.NoError(t, err)
+	if contentRange != "" {
+		req.Header.Set("Content-Range", contentRange)
+	}
+	for i := 0; i < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	return resp, string(data), err
+}
+
+func TestResumableUpload(t *testing.T) {
+	server := serve(t, config.Route{
+		Path:   "/upload/v1/files",
+		Upload: &config.Upload{Interruptions: []config.UploadInterruption{{Offset: 6}}},
+	})
+
+	resp, _, err := do(t, "POST", server.URL+"/upload/v1/files?uploadType=resumable", "", `{"name": "a.txt"}`,
+		"X-Upload-Content-Type", "text/plain", "X-Upload-Content-Length", "10")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	session := resp.Header.Get("Location")
+	require.Contains(t, session, server.URL+"/upload/v1/files?")
+	require.Contains(t, session, "upload_id=")
+
+	resp, _, err = do(t, "PUT", session, "bytes 0-3/*", "0123")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusPermanentRedirect, resp.StatusCode)
+	require.Equal(t, "bytes=0-3", resp.Header.Get("Range"))
+
+	// The interruption keeps the bytes before offset 6.
+	resp, _, err = do(t, "PUT", session, "bytes 4-9/10", "456789")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	resp, _, err = do(t, "PUT", session, "bytes */10", "")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusPermanentRedirect, resp.StatusCode)
+	require.Equal(t, "bytes=0-5", resp.Header.Get("Range"))
+
+	// Resending from an earlier offset is fine; the overlap is dropped.
+	sum := md5.Sum([]byte("0123456789"))
+	resp, body, err := do(t, "PUT", session, "bytes 4-9/10", "456789")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.JSONEq(t, `{"name": "a.txt", "size": "10", "contentType": "text/plain", "md5Hash": "`+base64.StdEncoding.EncodeToString(sum[:])+`"}`, body)
+	resp, again, err := do(t, "PUT", session, "bytes */10", "")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, body, again)
+
+	resp, _, err = do(t, "PUT", session, "bytes 12-13/10", "ab")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode, "a finished upload ignores more data")
+
+	// Other requests go on to the next handler.
+	_, body, err = do(t, "GET", server.URL+"/upload/v1/files", "", "")
+	require.NoError(t, err)
+	require.Equal(t, `{"ok":true}`, body)
+}
+
+func TestResumableUploadResetAndCancel(t *testing.T) {
+	server := serve(t, config.Route{
+		Path:     "/upload",
+		Upload:   &config.Upload{Interruptions: []config.UploadInterruption{{Offset: 2, Reset: true}}},
+		Response: &config.Response{Status: 201, Body: `{"done": true}`},
+	})
+	resp, _, err := do(t, "POST", server.URL+"/upload?uploadType=resumable", "", "")
+	require.NoError(t, err)
+	session := resp.Header.Get("Location")
+
+	_, _, err = do(t, "PUT", session, "bytes 0-3/4", "abcd")
+	require.Error(t, err)
+	resp, _, err = do(t, "PUT", session, "bytes */4", "")
+	require.NoError(t, err)
+	require.Equal(t, "bytes=0-1", resp.Header.Get("Range"))
+	resp, body, err := do(t, "PUT", session, "bytes 2-3/4", "cd")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	require.Equal(t, `{"done": true}`, body)
+
+	resp, _, err = do(t, "DELETE", session, "", "")
+	require.NoError(t, err)
+	require.Equal(t, 499, resp.StatusCode)
+	resp, _, err = do(t, "PUT", session, "bytes */4", "")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestResumableUploadErrors(t *testing.T) {
+	server := serve(t, config.Route{Path: "/upload", Upload: &config.Upload{}})
+	resp, _, err := do(t, "POST", server.URL+"/upload?uploadType=resumable", "", "", "X-Upload-Content-Length", "4")
+	require.NoError(t, err)
+	session := resp.Header.Get("Location")
+
+	for _, tt := range []struct{ contentRange, body string }{
+		{"bytes 2-3/4", "cd"},    // a gap
+		{"bytes 0-1/8", "ab"},    // another size
+		{"bytes 0-4/*", "abcde"}, // too long
+		{"bytes 0-2/4", "ab"},    // the range does not match the data
+		{"bytes=0-1/4", "ab"},    // not a Content-Range
+	} {
+		resp, _, err := do(t, "PUT", session, tt.contentRange, tt.body)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode, tt.contentRange)
+	}
+
+	for _, bad := range []config.UploadInterruption{{Offset: -1}, {Status: 200}, {Status: 503, Reset: true}} {
+		_, err := New("example.googleapis.com", []config.Route{{Upload: &config.Upload{Interruptions: []config.UploadInterruption{bad}}}})
+		require.ErrorContains(t, err, "routes[0].upload.interruptions[0]")
+	}
+}
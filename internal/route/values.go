@@ -0,0 +1,104 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package route
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/google/test-server/internal/config"
+)
+
+// valueMatch is a config.ValueMatch of a header or query parameter with its
+// regular expression compiled.
+type valueMatch struct {
+	config.ValueMatch
+	name  string
+	regex *regexp.Regexp
+}
+
+// compileValues compiles the conditions of a route on the headers or query
+// parameters, in the order of their names.
+func compileValues(conditions map[string]config.ValueMatch) ([]valueMatch, error) {
+	names := make([]string, 0, len(conditions))
+	for name := range conditions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	out := make([]valueMatch, len(names))
+	for i, name := range names {
+		v := valueMatch{ValueMatch: conditions[name], name: name}
+		if v.Absent && (v.Present || v.Equals != "" || v.Contains != "" || v.Regex != "") {
+			return nil, fmt.Errorf("%s: absent cannot be combined with other conditions", name)
+		}
+		if v.Regex != "" {
+			var err error
+			if v.regex, err = regexp.Compile("^(?:" + v.Regex + ")$"); err != nil {
+				return nil, fmt.Errorf("%s.regex: %w", name, err)
+			}
+		}
+		out[i] = v
+	}
+	return out, nil
+}
+
+// test reports whether values, the values of the header or parameter, satisfy
+// v.
+func (v *valueMatch) test(values []string) bool {
+	if v.Absent {
+		return len(values) == 0
+	}
+	for _, value := range values {
+		if (v.Equals == "" || value == v.Equals) &&
+			strings.Contains(value, v.Contains) &&
+			(v.regex == nil || v.regex.MatchString(value)) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesValues reports whether the headers and query parameters of req
+// satisfy the conditions of c.
+func (c *compiled) matchesValues(req *http.Request) bool {
+	for i := range c.headers {
+		if !c.headers[i].test(req.Header.Values(c.headers[i].name)) {
+			return false
+		}
+	}
+	if len(c.query) > 0 {
+		query := req.URL.Query()
+		for i := range c.query {
+			if !c.query[i].test(query[c.query[i].name]) {
+				return false
+			}
+		}
+	}
+	if c.url != nil {
+		url := req.URL.Path
+		if req.URL.RawQuery != "" {
+			url += "?" + req.URL.RawQuery
+		}
+		if !c.url.MatchString(url) {
+			return false
+		}
+	}
+	return true
+}
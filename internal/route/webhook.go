@@ -0,0 +1,242 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package route
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/google/test-server/internal/config"
+)
+
+// defaultRetryDelay is the first retry delay of webhooks without
+// retry_delay.
+const defaultRetryDelay = time.Second
+
+// webhookClient sends the webhooks.
+var webhookClient = &http.Client{Timeout: 30 * time.Second}
+
+// sending counts the webhooks being sent, and idle is closed while none is,
+// for WaitWebhooks.
+var (
+	sendingMu sync.Mutex
+	sending   int
+	idle      = closed()
+)
+
+func closed() chan struct{} {
+	c := make(chan struct{})
+	close(c)
+	return c
+}
+
+// webhook is a parsed config.Webhook.
+type webhook struct {
+	method     string
+	url, body  *template.Template
+	headers    map[string]*template.Template
+	delay      time.Duration
+	retries    int
+	retryDelay time.Duration
+}
+
+func (r *Router) newWebhook(c *config.Webhook) (*webhook, error) {
+	h := &webhook{method: strings.ToUpper(c.Method), headers: map[string]*template.Template{}, retries: c.Retries, retryDelay: defaultRetryDelay}
+	if h.method == "" {
+		h.method = http.MethodPost
+	}
+	if c.URL == "" {
+		return nil, fmt.Errorf("url is required")
+	}
+	if h.retries < 0 {
+		return nil, fmt.Errorf("retries: must not be negative")
+	}
+	for _, f := range []struct {
+		name, value string
+		d           *time.Duration
+	}{{"delay", c.Delay, &h.delay}, {"retry_delay", c.RetryDelay, &h.retryDelay}} {
+		if f.value == "" {
+			continue
+		}
+		d, err := time.ParseDuration(f.value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.name, err)
+		}
+		if d < 0 {
+			return nil, fmt.Errorf("%s: must not be negative", f.name)
+		}
+		*f.d = d
+	}
+
+	funcs := r.templateFuncs()
+	parse := func(name, text string) (*template.Template, error) {
+		t, err := template.New(name).Option("missingkey=zero").Funcs(funcs).Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		return t, nil
+	}
+	var err error
+	if h.url, err = parse("url", c.URL); err != nil {
+		return nil, err
+	}
+	if h.body, err = parse("body", c.Body); err != nil {
+		return nil, err
+	}
+	for name, value := range c.Headers {
+		name = http.CanonicalHeaderKey(name)
+		if h.headers[name], err = parse("headers."+name, value); err != nil {
+			return nil, err
+		}
+	}
+	return h, nil
+}
+
+// webhookCall is a webhook rendered for a request.
+type webhookCall struct {
+	*webhook
+	url     string
+	headers map[string]string
+	body    string
+}
+
+// renderWebhooks renders hooks for req, leaving its body to be read again.
+func renderWebhooks(req *http.Request, params map[string]string, hooks []*webhook) ([]webhookCall, error) {
+	if req.Body != nil {
+		raw, err := io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(raw))
+		defer func() { req.Body = io.NopCloser(bytes.NewReader(raw)) }()
+	}
+	data, err := newTemplateData(req, params)
+	if err != nil {
+		return nil, err
+	}
+	render := func(t *template.Template) (string, error) {
+		var b bytes.Buffer
+		err := t.Execute(&b, data)
+		return b.String(), err
+	}
+	calls := make([]webhookCall, len(hooks))
+	for i, h := range hooks {
+		c := webhookCall{webhook: h, headers: map[string]string{}}
+		if c.url, err = render(h.url); err != nil {
+			return nil, err
+		}
+		if u, err := url.Parse(c.url); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("webhook URL %q is not an absolute http(s) URL", c.url)
+		}
+		// A stable order keeps the random values of a seeded run reproducible.
+		names := make([]string, 0, len(h.headers))
+		for name := range h.headers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if c.headers[name], err = render(h.headers[name]); err != nil {
+				return nil, err
+			}
+		}
+		if c.body, err = render(h.body); err != nil {
+			return nil, err
+		}
+		calls[i] = c
+	}
+	return calls, nil
+}
+
+// startSending counts a webhook that send is about to send.
+func startSending() {
+	sendingMu.Lock()
+	defer sendingMu.Unlock()
+	if sending == 0 {
+		idle = make(chan struct{})
+	}
+	sending++
+}
+
+// WaitWebhooks waits for the webhooks being sent, with their delays and
+// retries, until ctx is done, when it returns the error of ctx.
+func WaitWebhooks(ctx context.Context) error {
+	sendingMu.Lock()
+	done := idle
+	sendingMu.Unlock()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// send makes the call after its delay, retrying as configured.
+func (c webhookCall) send() {
+	defer func() {
+		sendingMu.Lock()
+		defer sendingMu.Unlock()
+		if sending--; sending == 0 {
+			close(idle)
+		}
+	}()
+	time.Sleep(c.delay)
+	wait := c.retryDelay
+	for attempt := 0; ; attempt++ {
+		status, err := c.try()
+		if err == nil && status != http.StatusTooManyRequests && status < 500 {
+			fmt.Printf("Sent the webhook %s %s: %d\n", c.method, c.url, status)
+			return
+		}
+		if err == nil {
+			err = fmt.Errorf("status %d", status)
+		}
+		if attempt == c.retries {
+			fmt.Printf("Failed to send the webhook %s %s: %v\n", c.method, c.url, err)
+			return
+		}
+		fmt.Printf("Retrying the webhook %s %s in %v: %v\n", c.method, c.url, wait, err)
+		time.Sleep(wait)
+		wait *= 2
+	}
+}
+
+func (c webhookCall) try() (int, error) {
+	req, err := http.NewRequest(c.method, c.url, strings.NewReader(c.body))
+	if err != nil {
+		return 0, err
+	}
+	for name, value := range c.headers {
+		req.Header.Set(name, value)
+	}
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package route
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/test-server/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhooks(t *testing.T) {
+	type call struct{ path, auth, body string }
+	calls := make(chan call, 10)
+	var attempts atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		// The first attempt fails and is retried.
+		if attempts.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := io.ReadAll(req.Body)
+		calls <- call{req.URL.Path, req.Header.Get("Authorization"), string(body)}
+	}))
+	t.Cleanup(receiver.Close)
+
+	server := serve(t, config.Route{
+		Method:   "POST",
+		Path:     "/v1/jobs",
+		Response: &config.Response{Status: 202, Body: `{"state": "PENDING"}`},
+		Webhooks: []config.Webhook{{
+			URL:        "{{.Body.callback}}/done",
+			Headers:    map[string]string{"Authorization": "Bearer {{.Headers.Get \"X-Token\"}}"},
+			Body:       `{"job": "{{.Body.name}}", "state": "DONE"}`,
+			Delay:      "10ms",
+			Retries:    1,
+			RetryDelay: "10ms",
+		}},
+	})
+
+	resp, body, err := do(t, "POST", server.URL+"/v1/jobs", "", `{"name": "j1", "callback": "`+receiver.URL+`"}`, "X-Token", "t0k")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+	require.Equal(t, `{"state": "PENDING"}`, body)
+	// WaitWebhooks waits for the webhook, with its delay and retry.
+	short, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, WaitWebhooks(short), context.DeadlineExceeded)
+	require.NoError(t, WaitWebhooks(context.Background()))
+	require.Len(t, calls, 1)
+	select {
+	case c := <-calls:
+		require.Equal(t, call{"/done", "Bearer t0k", `{"job": "j1", "state": "DONE"}`}, c)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the webhook was not sent")
+	}
+	require.Equal(t, int32(2), attempts.Load())
+}
+
+func TestWebhooksRejectInvalidConfig(t *testing.T) {
+	for _, h := range []config.Webhook{
+		{},
+		{URL: "{{.Body"},
+		{URL: "http://localhost", Delay: "later"},
+		{URL: "http://localhost", Retries: -1},
+		{URL: "http://localhost", RetryDelay: "-1s"},
+	} {
+		_, err := New("example.googleapis.com", []config.Route{{Webhooks: []config.Webhook{h}}})
+		require.Error(t, err)
+	}
+}
@@ -0,0 +1,248 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package sdkconstants generates the constants every SDK installer needs, such
+// as the pinned server version, from sdks/constants.json.
+//
+// The release tools change constants.json and regenerate one small module per
+// SDK instead of patching the hand-written installers, so an edit to an
+// installer can never make an update silently miss its version.
+package sdkconstants
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/google/test-server/internal/nightly"
+	"github.com/google/test-server/internal/semver"
+)
+
+// File is the canonical constants file relative to the repository root.
+const File = "sdks/constants.json"
+
+// Config is the content of constants.json.
+type Config struct {
+	// TestServerVersion is the release the installers download by default.
+	TestServerVersion string `json:"test_server_version"`
+	GitHubOwner       string `json:"github_owner"`
+	GitHubRepo        string `json:"github_repo"`
+	ProjectName       string `json:"project_name"`
+}
+
+// Load reads and validates the config at path.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	var c Config
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if err := c.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", path, err)
+	}
+	return &c, nil
+}
+
+// Save writes c to path.
+func (c *Config) Save(path string) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// nameRe limits the other values to what every target language can quote
+// without escaping.
+var nameRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// Validate checks that every value is set and safe to generate.
+func (c *Config) Validate() error {
+	// The installers use the version as the release tag.
+	if !strings.HasPrefix(c.TestServerVersion, "v") {
+		return fmt.Errorf("test_server_version: %q is not a release tag", c.TestServerVersion)
+	}
+	if _, err := semver.Parse(c.TestServerVersion); err != nil {
+		return fmt.Errorf("test_server_version: %w", err)
+	}
+	for _, f := range []struct{ name, value string }{
+		{"github_owner", c.GitHubOwner},
+		{"github_repo", c.GitHubRepo},
+		{"project_name", c.ProjectName},
+	} {
+		if !nameRe.MatchString(f.value) {
+			return fmt.Errorf("%s: invalid value %q", f.name, f.value)
+		}
+	}
+	return nil
+}
+
+// Target is one generated file.
+type Target struct {
+	SDK  string
+	Path string // relative to the repository root
+	tmpl *template.Template
+}
+
+// Targets are the generated files, one per SDK.
+var Targets = []Target{
+	{SDK: "TypeScript", Path: "sdks/typescript/constants.js", tmpl: parse(jsTemplate)},
+	{SDK: "Python", Path: "sdks/python/src/test_server_sdk/_constants.py", tmpl: parse(pyTemplate)},
+	{SDK: "Dotnet", Path: "sdks/dotnet/ServerConstants.g.cs", tmpl: parse(csTemplate)},
+}
+
+func parse(text string) *template.Template {
+	return template.Must(template.New("").Funcs(template.FuncMap{"license": license}).Parse(text))
+}
+
+// license returns the license header with every line commented by prefix.
+func license(prefix string) string {
+	lines := strings.Split(header, "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimRight(prefix+l, " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Generate renders t for c.
+func (t Target) Generate(c *Config) ([]byte, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	var b bytes.Buffer
+	err := t.tmpl.Execute(&b, struct {
+		*Config
+		NightlyChannel, NightlyManifestURL string
+	}{c, nightly.Channel, nightly.ManifestURL})
+	if err != nil {
+		return nil, fmt.Errorf("generating %s: %w", t.Path, err)
+	}
+	return b.Bytes(), nil
+}
+
+// Outdated returns the targets whose file does not match c.
+func Outdated(c *Config) ([]Target, error) {
+	var out []Target
+	for _, t := range Targets {
+		want, err := t.Generate(c)
+		if err != nil {
+			return nil, err
+		}
+		got, err := os.ReadFile(t.Path)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to read %s: %w", t.Path, err)
+		}
+		if !bytes.Equal(got, want) {
+			out = append(out, t)
+		}
+	}
+	return out, nil
+}
+
+// Write regenerates every outdated target and returns their paths.
+func Write(c *Config) ([]string, error) {
+	outdated, err := Outdated(c)
+	if err != nil {
+		return nil, err
+	}
+	var written []string
+	for _, t := range outdated {
+		data, err := t.Generate(c)
+		if err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(t.Path, data, 0644); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", t.Path, err)
+		}
+		written = append(written, t.Path)
+	}
+	return written, nil
+}
+
+const header = `Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.`
+
+// generatedNotice is the first line after the license in every target.
+const generatedNotice = "Code generated by scripts/gen-sdk-constants from " + File + ". DO NOT EDIT."
+
+const jsTemplate = `/**
+{{license " * "}}
+ */
+
+// ` + generatedNotice + `
+
+module.exports = Object.freeze({
+    TEST_SERVER_VERSION: '{{.TestServerVersion}}',
+    GITHUB_OWNER: '{{.GitHubOwner}}',
+    GITHUB_REPO: '{{.GitHubRepo}}',
+    PROJECT_NAME: '{{.ProjectName}}',
+    NIGHTLY_CHANNEL: '{{.NightlyChannel}}',
+    NIGHTLY_MANIFEST_URL: '{{.NightlyManifestURL}}',
+});
+`
+
+const pyTemplate = `{{license "# "}}
+
+# ` + generatedNotice + `
+
+TEST_SERVER_VERSION = "{{.TestServerVersion}}"
+GITHUB_OWNER = "{{.GitHubOwner}}"
+GITHUB_REPO = "{{.GitHubRepo}}"
+PROJECT_NAME = "{{.ProjectName}}"
+NIGHTLY_CHANNEL = "{{.NightlyChannel}}"
+NIGHTLY_MANIFEST_URL = "{{.NightlyManifestURL}}"
+`
+
+const csTemplate = `/*
+{{license " * "}}
+ */
+
+// ` + generatedNotice + `
+
+namespace TestServerSdk
+{
+  public static class ServerConstants
+  {
+    public const string TestServerVersion = "{{.TestServerVersion}}";
+    public const string GithubOwner = "{{.GitHubOwner}}";
+    public const string GithubRepo = "{{.GitHubRepo}}";
+    public const string ProjectName = "{{.ProjectName}}";
+    public const string NightlyChannel = "{{.NightlyChannel}}";
+    public const string NightlyManifestUrl = "{{.NightlyManifestURL}}";
+  }
+}
+`
@@ -0,0 +1,117 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sdkconstants
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func validConfig() *Config {
+	return &Config{TestServerVersion: "v0.2.9", GitHubOwner: "google", GitHubRepo: "test-server", ProjectName: "test-server"}
+}
+
+func TestValidate(t *testing.T) {
+	testCases := []struct {
+		name    string
+		change  func(c *Config)
+		wantErr string
+	}{
+		{name: "valid", change: func(c *Config) {}},
+		{name: "pre-release", change: func(c *Config) { c.TestServerVersion = "v0.3.0-rc.1" }},
+		{name: "missing v", change: func(c *Config) { c.TestServerVersion = "0.2.9" }, wantErr: "test_server_version"},
+		{name: "empty owner", change: func(c *Config) { c.GitHubOwner = "" }, wantErr: "github_owner"},
+		{name: "quote in repo", change: func(c *Config) { c.GitHubRepo = `test"server` }, wantErr: "github_repo"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := validConfig()
+			tc.change(c)
+			err := c.Validate()
+			if tc.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorContains(t, err, tc.wantErr)
+		})
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	testCases := []struct {
+		sdk  string
+		want []string
+	}{
+		{sdk: "TypeScript", want: []string{"TEST_SERVER_VERSION: 'v0.2.9',", "module.exports", " * Copyright 2025 Google LLC\n"}},
+		{sdk: "Python", want: []string{`TEST_SERVER_VERSION = "v0.2.9"`, "# Copyright 2025 Google LLC\n#\n"}},
+		{sdk: "Dotnet", want: []string{`public const string TestServerVersion = "v0.2.9";`, "namespace TestServerSdk"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.sdk, func(t *testing.T) {
+			var target *Target
+			for i := range Targets {
+				if Targets[i].SDK == tc.sdk {
+					target = &Targets[i]
+				}
+			}
+			require.NotNil(t, target)
+			out, err := target.Generate(validConfig())
+			require.NoError(t, err)
+			for _, w := range tc.want {
+				require.Contains(t, string(out), w)
+			}
+			require.Contains(t, string(out), "DO NOT EDIT.")
+			require.Contains(t, string(out), "https://google.github.io/test-server/nightly.json")
+		})
+	}
+}
+
+func TestWriteAndOutdated(t *testing.T) {
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(dir))
+	t.Cleanup(func() { os.Chdir(wd) })
+	for _, target := range Targets {
+		require.NoError(t, os.MkdirAll(filepath.Dir(target.Path), 0755))
+	}
+
+	c := validConfig()
+	outdated, err := Outdated(c)
+	require.NoError(t, err)
+	require.Len(t, outdated, len(Targets))
+
+	written, err := Write(c)
+	require.NoError(t, err)
+	require.Len(t, written, len(Targets))
+	outdated, err = Outdated(c)
+	require.NoError(t, err)
+	require.Empty(t, outdated)
+
+	c.TestServerVersion = "v0.3.0"
+	outdated, err = Outdated(c)
+	require.NoError(t, err)
+	require.Len(t, outdated, len(Targets))
+
+	require.NoError(t, c.Save("constants.json"))
+	loaded, err := Load("constants.json")
+	require.NoError(t, err)
+	require.Equal(t, c, loaded)
+}
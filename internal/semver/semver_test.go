@@ -0,0 +1,83 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package semver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		input   string
+		want    Version
+		wantErr bool
+	}{
+		{input: "v0.2.8", want: Version{Major: 0, Minor: 2, Patch: 8}},
+		{input: "1.10.0", want: Version{Major: 1, Minor: 10, Patch: 0}},
+		{input: "v0.3.0-rc.1", want: Version{Major: 0, Minor: 3, Patch: 0, Pre: "rc.1"}},
+		{input: "v0.3.0+build.5", want: Version{Major: 0, Minor: 3, Patch: 0}},
+		{input: "v0.3", wantErr: true},
+		{input: "latest", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			got, err := Parse(tc.input)
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	// Each version has lower precedence than the next one.
+	ordered := []string{
+		"v0.1.0",
+		"v0.2.0-alpha",
+		"v0.2.0-alpha.1",
+		"v0.2.0-alpha.beta",
+		"v0.2.0-beta.2",
+		"v0.2.0-beta.11",
+		"v0.2.0-rc.1",
+		"v0.2.0",
+		"v0.2.8",
+		"v0.10.0",
+		"v1.0.0",
+	}
+	for i := 0; i < len(ordered)-1; i++ {
+		a, err := Parse(ordered[i])
+		require.NoError(t, err)
+		b, err := Parse(ordered[i+1])
+		require.NoError(t, err)
+		require.Equal(t, -1, Compare(a, b), "%s < %s", ordered[i], ordered[i+1])
+		require.Equal(t, 1, Compare(b, a), "%s > %s", ordered[i+1], ordered[i])
+		require.Equal(t, 0, Compare(a, a))
+	}
+}
+
+func TestTag(t *testing.T) {
+	v, err := Parse("0.3.0-rc.1")
+	require.NoError(t, err)
+	require.Equal(t, "v0.3.0-rc.1", v.Tag())
+	require.True(t, v.Prerelease())
+}
@@ -19,16 +19,18 @@ package store
 import (
 	"bufio"
 	"bytes"
-	"compress/gzip"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
 
+	"github.com/google/test-server/internal/codec"
 	"github.com/google/test-server/internal/config"
 )
 
@@ -54,6 +56,8 @@ type RecordedRequest struct {
 	Request      string            `json:"request,omitempty"`
 	Headers      map[string]string `json:"headers,omitempty"`
 	BodySegments []map[string]any  `json:"bodySegments,omitempty"`
+	// Body is a request body that is not JSON, e.g. an upload, in base64.
+	Body []byte `json:"body,omitempty"`
 	// The sha256 sum of the previous request in the chain.
 	PreviousRequest string `json:"previousRequest,omitempty"`
 	ServerAddress   string `json:"serverAddress,omitempty"`
@@ -66,12 +70,35 @@ type RecordedResponse struct {
 	Headers             map[string]string `json:"headers,omitempty"`
 	BodySegments        []map[string]any  `json:"bodySegments,omitempty"`
 	SDKResponseSegments []map[string]any  `json:"sdkResponseSegments,omitempty"`
+	// HTTPVersion is the protocol the target answered with, e.g. HTTP/2.0.
+	HTTPVersion string `json:"httpVersion,omitempty"`
+	// Body is a body that is neither JSON nor a stream of JSON events, e.g.
+	// an image or a protobuf message, in base64.
+	Body []byte `json:"body,omitempty"`
+	// BodyFile is a file, relative to the recording directory, whose content
+	// is the body. It is not recorded but can replace Body in a recording.
+	BodyFile string `json:"bodyFile,omitempty"`
+	// SegmentDelays are the times, e.g. "150ms", to wait before replaying
+	// each body segment, to pace a streamed response. They are not recorded
+	// but can be added to a recording.
+	SegmentDelays []string `json:"segmentDelays,omitempty"`
+	// Events are the fields of the Server-Sent Events of the body segments,
+	// by segment, if a streamed response set any.
+	Events []Event `json:"events,omitempty"`
+}
+
+// Event holds the fields of a Server-Sent Event besides its data.
+type Event struct {
+	ID    string `json:"id,omitempty"`
+	Event string `json:"event,omitempty"`
+	// Retry is the reconnection time in milliseconds.
+	Retry int `json:"retry,omitempty"`
 }
 
 // NewRecordedRequest creates a RecordedRequest from an http.Request.
 func NewRecordedRequest(req *http.Request, previousRequest string, cfg config.EndpointConfig) (*RecordedRequest, error) {
 	// Read the body.
-	body, err := readBody(req)
+	body, raw, err := readBody(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read body: %w", err)
 	}
@@ -89,6 +116,7 @@ func NewRecordedRequest(req *http.Request, previousRequest string, cfg config.En
 		Request:         request,
 		Headers:         GetHeadersMap(&header),
 		BodySegments:    []map[string]any{body},
+		Body:            raw,
 		PreviousRequest: previousRequest,
 		ServerAddress:   cfg.TargetHost,
 		Port:            cfg.TargetPort,
@@ -98,26 +126,25 @@ func NewRecordedRequest(req *http.Request, previousRequest string, cfg config.En
 	return recordedRequest, nil
 }
 
-func readBody(req *http.Request) (map[string]any, error) {
+// readBody returns the JSON body of req, or the raw body if it is not JSON.
+func readBody(req *http.Request) (map[string]any, []byte, error) {
 	if req.Body == nil {
-		return map[string]any{}, nil
+		return map[string]any{}, nil, nil
 	}
 	body, err := io.ReadAll(req.Body)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	var resultMap map[string]any
 	if string(body) == "" {
-		return resultMap, nil
-	}
-	err = json.Unmarshal(body, &resultMap)
-	if err != nil {
-		log.Fatalf("Error unmarshaling JSON: %v", err)
-		return nil, err
+		return resultMap, nil, nil
 	}
 	// Restore the request body for further use.
 	req.Body = io.NopCloser(bytes.NewBuffer(body))
-	return resultMap, nil
+	if err := json.Unmarshal(body, &resultMap); err != nil {
+		return nil, body, nil
+	}
+	return resultMap, nil, nil
 }
 
 // ComputeSum computes the SHA256 sum of a RecordedRequest.
@@ -163,24 +190,19 @@ func (r *RecordedRequest) RedactHeaders(headers []string) {
 }
 
 func NewRecordedResponse(resp *http.Response, body []byte) (*RecordedResponse, error) {
-	if resp.Header.Get("Content-Encoding") == "gzip" {
-		gzipReader, err := gzip.NewReader(bytes.NewReader(body))
-		if err != nil {
-			return nil, err
-		}
-		defer gzipReader.Close()
-
-		// Read the uncompressed body.
-		uncompressedBody := new(bytes.Buffer)
-		_, err = uncompressedBody.ReadFrom(gzipReader)
+	// The body is recorded decoded; the Content-Encoding header keeps its
+	// coding for the replay.
+	if coding := resp.Header.Get("Content-Encoding"); coding != "" {
+		decoded, err := codec.Decode(coding, body)
 		if err != nil {
 			return nil, err
 		}
-		body = uncompressedBody.Bytes()
-
+		body = decoded
 	}
 
 	var bodySegments []map[string]any
+	var events []Event
+	hasEvents := false
 	var bodySegment map[string]any
 	err := json.Unmarshal(body, &bodySegment)
 	if err != nil {
@@ -193,9 +215,20 @@ func NewRecordedResponse(resp *http.Response, body []byte) (*RecordedResponse, e
 		buf := make([]byte, ReadBufferSize)
 		scanner.Buffer(buf, ReadBufferSize)
 
+		// The fields of an event apply to the segments of its data lines.
+		var current Event
+		eventStart := 0
+		endEvent := func() {
+			for i := eventStart; i < len(events); i++ {
+				events[i] = current
+			}
+			hasEvents = hasEvents || current != Event{}
+			current, eventStart = Event{}, len(events)
+		}
 		for scanner.Scan() {
 			lineBytes := scanner.Bytes()
 			if len(lineBytes) == 0 {
+				endEvent()
 				continue
 			}
 
@@ -207,10 +240,26 @@ func NewRecordedResponse(resp *http.Response, body []byte) (*RecordedResponse, e
 				}
 
 				bodySegments = append(bodySegments, jsonMap)
+				events = append(events, Event{})
+				continue
+			}
+			name, value, _ := strings.Cut(string(lineBytes), ":")
+			value = strings.TrimPrefix(value, " ")
+			switch name {
+			case "id":
+				current.ID = value
+			case "event":
+				current.Event = value
+			case "retry":
+				current.Retry, _ = strconv.Atoi(value)
 			}
 		}
+		endEvent()
 
-		if err := scanner.Err(); err != nil {
+		if errors.Is(scanner.Err(), bufio.ErrTooLong) {
+			// Not a stream of events but a large body without line breaks.
+			bodySegments, hasEvents = nil, false
+		} else if err := scanner.Err(); err != nil {
 			log.Fatalf("Error reading input bytes: %v", err)
 			return nil, err
 		}
@@ -220,9 +269,18 @@ func NewRecordedResponse(resp *http.Response, body []byte) (*RecordedResponse, e
 
 	recordedResponse := &RecordedResponse{
 		StatusCode:   int32(resp.StatusCode),
+		HTTPVersion:  resp.Proto,
 		Headers:      GetHeadersMap(&resp.Header),
 		BodySegments: bodySegments,
 	}
+	// Any other body is kept byte for byte.
+	if len(bodySegments) == 0 && len(body) > 0 {
+		recordedResponse.Body = body
+		hasEvents = false
+	}
+	if hasEvents {
+		recordedResponse.Events = events
+	}
 	return recordedResponse, nil
 }
 
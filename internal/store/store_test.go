@@ -18,10 +18,14 @@ package store
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 
+	"github.com/google/test-server/internal/codec"
 	"github.com/google/test-server/internal/config"
 	"github.com/stretchr/testify/require"
 )
@@ -361,6 +365,78 @@ func TestRecordedRequest_GetRecordFileName(t *testing.T) {
 	}
 }
 
+func TestNewRecordedResponse_Events(t *testing.T) {
+	resp := &http.Response{StatusCode: 200, Header: http.Header{"Content-Type": {"text/event-stream"}}}
+	body := "retry: 1000\ndata: {\"n\": 0}\n\n" +
+		"id: 1\nevent: delta\ndata: {\"n\": 1}\n\n" +
+		"data: {\"n\": 2}\nid: 2\n\n" +
+		": keep-alive\n\n" +
+		"data: {\"n\": 3}\n"
+	recorded, err := NewRecordedResponse(resp, []byte(body))
+	require.NoError(t, err)
+	require.Len(t, recorded.BodySegments, 4)
+	require.Equal(t, []Event{{Retry: 1000}, {ID: "1", Event: "delta"}, {ID: "2"}, {}}, recorded.Events)
+
+	// Streams with data only are recorded as before.
+	recorded, err = NewRecordedResponse(resp, []byte("data: {\"n\": 0}\n\ndata: {\"n\": 1}\n\n"))
+	require.NoError(t, err)
+	require.Len(t, recorded.BodySegments, 2)
+	require.Nil(t, recorded.Events)
+}
+
+func TestNewRecordedResponse_Encoded(t *testing.T) {
+	for _, coding := range []string{"gzip", "deflate"} {
+		body, err := codec.Encode(coding, []byte(`{"name": "gemini"}`))
+		require.NoError(t, err)
+		resp := &http.Response{StatusCode: 200, Header: http.Header{"Content-Encoding": {coding}}}
+		recorded, err := NewRecordedResponse(resp, body)
+		require.NoError(t, err, coding)
+		require.Equal(t, []map[string]any{{"name": "gemini"}}, recorded.BodySegments, coding)
+		require.Equal(t, coding, recorded.Headers["Content-Encoding"])
+	}
+}
+
+func TestNewRecordedResponse_HTTPVersion(t *testing.T) {
+	resp := &http.Response{StatusCode: 200, Proto: "HTTP/2.0", ProtoMajor: 2, Header: http.Header{}}
+	recorded, err := NewRecordedResponse(resp, []byte(`{}`))
+	require.NoError(t, err)
+	require.Equal(t, "HTTP/2.0", recorded.HTTPVersion)
+}
+
+func TestNewRecordedResponse_Binary(t *testing.T) {
+	body := []byte("\x08\x96\x01\x12\x00\xff\ndata: x\n")
+	resp := &http.Response{StatusCode: 200, Header: http.Header{"Content-Type": {"application/x-protobuf"}}}
+	recorded, err := NewRecordedResponse(resp, body)
+	require.NoError(t, err)
+	require.Empty(t, recorded.BodySegments)
+	require.Equal(t, body, recorded.Body)
+
+	// The body survives a round trip through the recording file.
+	data, err := json.Marshal(recorded)
+	require.NoError(t, err)
+	var read RecordedResponse
+	require.NoError(t, json.Unmarshal(data, &read))
+	require.Equal(t, body, read.Body)
+
+	// So does a long one without line breaks.
+	long := bytes.Repeat([]byte{0xab}, ReadBufferSize+1)
+	recorded, err = NewRecordedResponse(resp, long)
+	require.NoError(t, err)
+	require.Equal(t, long, recorded.Body)
+}
+
+func TestNewRecordedRequest_Binary(t *testing.T) {
+	body := []byte("\x00\x01binary\xff")
+	req := httptest.NewRequest(http.MethodPost, "/upload", bytes.NewReader(body))
+	recorded, err := NewRecordedRequest(req, HeadSHA, config.EndpointConfig{})
+	require.NoError(t, err)
+	require.Equal(t, body, recorded.Body)
+	// The request can still be forwarded.
+	forwarded, err := io.ReadAll(req.Body)
+	require.NoError(t, err)
+	require.Equal(t, body, forwarded)
+}
+
 type errorReader struct{}
 
 func (e *errorReader) Read(p []byte) (n int, err error) {
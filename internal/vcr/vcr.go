@@ -0,0 +1,245 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package vcr converts VCR.py cassettes into HAR logs, which package har
+// turns into recordings, and the VCR.py matchers into match_on criteria.
+package vcr
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"sort"
+
+	"github.com/google/test-server/internal/codec"
+	"github.com/google/test-server/internal/har"
+	"gopkg.in/yaml.v2"
+)
+
+// Cassette is the content of a VCR.py cassette file.
+type Cassette struct {
+	Version      int           `yaml:"version"`
+	Interactions []Interaction `yaml:"interactions"`
+}
+
+// Interaction is a request and its response.
+type Interaction struct {
+	Request  Request  `yaml:"request"`
+	Response Response `yaml:"response"`
+}
+
+// Request is a recorded request. Its body is a string, !!binary or null.
+type Request struct {
+	Method  string         `yaml:"method"`
+	URI     string         `yaml:"uri"`
+	Headers map[string]any `yaml:"headers"`
+	Body    any            `yaml:"body"`
+}
+
+// Response is a recorded response. Its body is {string: ...}, where the
+// string may be !!binary and, unless the cassette was recorded with
+// decode_compressed_response, still encoded with its Content-Encoding.
+type Response struct {
+	Status struct {
+		Code    int    `yaml:"code"`
+		Message string `yaml:"message"`
+	} `yaml:"status"`
+	Headers map[string]any `yaml:"headers"`
+	Body    any            `yaml:"body"`
+}
+
+// Parse reads a VCR.py cassette.
+func Parse(data []byte) (*Cassette, error) {
+	var c Cassette
+	if err := yaml.Unmarshal(data, &c); err != nil {
+		return nil, err
+	}
+	if c.Interactions == nil {
+		return nil, fmt.Errorf("not a VCR.py cassette: no interactions")
+	}
+	return &c, nil
+}
+
+// Load reads the VCR.py cassette at path.
+func Load(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return c, nil
+}
+
+// HAR returns the interactions of c as the entries of a HAR log, with
+// warnings about the interactions it leaves out.
+func (c *Cassette) HAR() (*har.HAR, []string, error) {
+	h := &har.HAR{Log: har.Log{Version: "1.2", Creator: har.Creator{Name: "VCR.py"}}}
+	var warnings []string
+	for i, interaction := range c.Interactions {
+		entry, err := interaction.entry()
+		var unsupported unsupportedCoding
+		if errors.As(err, &unsupported) {
+			warnings = append(warnings, fmt.Sprintf("interaction %d (%s %s): skipped, the response body cannot be decoded from %s", i, interaction.Request.Method, interaction.Request.URI, string(unsupported)))
+			continue
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("interaction %d: %w", i, err)
+		}
+		h.Log.Entries = append(h.Log.Entries, *entry)
+	}
+	return h, warnings, nil
+}
+
+// unsupportedCoding is a Content-Encoding package codec cannot decode.
+type unsupportedCoding string
+
+func (u unsupportedCoding) Error() string {
+	return fmt.Sprintf("unsupported content coding %q", string(u))
+}
+
+// entry returns i as a HAR entry.
+func (i Interaction) entry() (*har.Entry, error) {
+	reqBody, err := body(i.Request.Body)
+	if err != nil {
+		return nil, fmt.Errorf("request body: %w", err)
+	}
+	respBody, err := body(i.Response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("response body: %w", err)
+	}
+	respHeaders := header(i.Response.Headers)
+	if coding := respHeaders.Get("Content-Encoding"); coding != "" && len(respBody) > 0 {
+		decoded, err := codec.Decode(coding, respBody)
+		if errors.Is(err, codec.ErrUnsupported) {
+			return nil, unsupportedCoding(coding)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("response body: %w", err)
+		}
+		respBody = decoded
+	}
+
+	entry := &har.Entry{
+		Request: har.Request{
+			Method:      i.Request.Method,
+			URL:         i.Request.URI,
+			HTTPVersion: "HTTP/1.1",
+			Headers:     nameValues(header(i.Request.Headers)),
+		},
+		Response: har.Response{
+			Status:      i.Response.Status.Code,
+			StatusText:  i.Response.Status.Message,
+			HTTPVersion: "HTTP/1.1",
+			Headers:     nameValues(respHeaders),
+			Content: har.Content{
+				Size:     len(respBody),
+				MimeType: respHeaders.Get("Content-Type"),
+				Text:     base64.StdEncoding.EncodeToString(respBody),
+				Encoding: "base64",
+			},
+		},
+	}
+	if len(reqBody) > 0 {
+		entry.Request.PostData = &har.PostData{MimeType: header(i.Request.Headers).Get("Content-Type"), Text: string(reqBody)}
+	}
+	return entry, nil
+}
+
+// body returns the bytes of a cassette body: a string, null, or a mapping
+// with the string under "string", as VCR.py writes response bodies.
+func body(v any) ([]byte, error) {
+	switch b := v.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []byte(b), nil
+	case map[any]any:
+		return body(b["string"])
+	}
+	return nil, fmt.Errorf("unexpected %T", v)
+}
+
+// header returns the headers of a cassette, each a list of values or a
+// single value.
+func header(headers map[string]any) http.Header {
+	h := http.Header{}
+	for name, v := range headers {
+		switch values := v.(type) {
+		case []any:
+			for _, value := range values {
+				h.Add(name, fmt.Sprint(value))
+			}
+		case nil:
+		default:
+			h.Add(name, fmt.Sprint(values))
+		}
+	}
+	return h
+}
+
+// nameValues returns h as HAR name-value pairs, sorted by name.
+func nameValues(h http.Header) []har.NameValue {
+	names := make([]string, 0, len(h))
+	for name := range h {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var pairs []har.NameValue
+	for _, name := range names {
+		for _, value := range h[name] {
+			pairs = append(pairs, har.NameValue{Name: name, Value: value})
+		}
+	}
+	return pairs
+}
+
+// DefaultMatchOn is the default match_on of VCR.py.
+var DefaultMatchOn = []string{"method", "scheme", "host", "port", "path", "query"}
+
+// MatchOn returns the match_on criteria of an endpoint that replays like the
+// VCR.py matchers, with warnings about the matchers without an equivalent.
+// The scheme, host and port matchers always hold, as an endpoint has one
+// target.
+func MatchOn(matchers []string) ([]string, []string) {
+	var criteria, warnings []string
+	add := func(names ...string) {
+		for _, name := range names {
+			if !slices.Contains(criteria, name) {
+				criteria = append(criteria, name)
+			}
+		}
+	}
+	for _, m := range matchers {
+		switch m {
+		case "method", "path", "query", "body", "headers":
+			add(m)
+		case "uri":
+			add("path", "query")
+		case "raw_body":
+			add("body")
+		case "scheme", "host", "port":
+		default:
+			warnings = append(warnings, fmt.Sprintf("the matcher %q has no equivalent in match_on", m))
+		}
+	}
+	return criteria, warnings
+}
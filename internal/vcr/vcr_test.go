@@ -0,0 +1,110 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vcr
+
+import (
+	"testing"
+
+	"github.com/google/test-server/internal/config"
+	"github.com/google/test-server/internal/har"
+	"github.com/stretchr/testify/require"
+)
+
+const cassette = `interactions:
+- request:
+    body: '{"q": "x"}'
+    headers:
+      Content-Type:
+      - application/json
+    method: POST
+    uri: https://api.example.com/v1/search?limit=2
+  response:
+    body:
+      string: !!binary |
+        H4sIAAwr0moC/6tWKkotLs0pKVayUohWSlSKrQUAAvRB/BIAAAA=
+    headers:
+      Content-Encoding:
+      - gzip
+      Content-Type:
+      - application/json
+    status:
+      code: 200
+      message: OK
+- request:
+    body: null
+    headers: {}
+    method: GET
+    uri: https://api.example.com/v1/logo
+  response:
+    body:
+      string: plain text
+    headers:
+      Content-Type: text/plain
+    status:
+      code: 404
+      message: Not Found
+- request:
+    body: null
+    headers: {}
+    method: GET
+    uri: https://api.example.com/v1/legacy
+  response:
+    body:
+      string: compressed
+    headers:
+      Content-Encoding:
+      - compress
+    status:
+      code: 200
+      message: OK
+version: 1
+`
+
+func TestHAR(t *testing.T) {
+	c, err := Parse([]byte(cassette))
+	require.NoError(t, err)
+	h, warnings, err := c.HAR()
+	require.NoError(t, err)
+	require.Equal(t, []string{`interaction 2 (GET https://api.example.com/v1/legacy): skipped, the response body cannot be decoded from compress`}, warnings)
+	require.Len(t, h.Log.Entries, 2)
+
+	file, _, err := h.Recording(har.Options{Endpoint: config.EndpointConfig{TargetHost: "api.example.com", TargetPort: 443}})
+	require.NoError(t, err)
+	require.Len(t, file.Interactions, 2)
+	search := file.Interactions[0]
+	require.Equal(t, "POST /v1/search?limit=2 HTTP/1.1", search.Request.Request)
+	require.Equal(t, []map[string]any{{"q": "x"}}, search.Request.BodySegments)
+	require.Equal(t, "gzip", search.Response.Headers["Content-Encoding"])
+	require.Equal(t, []map[string]any{{"results": []any{"a"}}}, search.Response.BodySegments)
+	logo := file.Interactions[1]
+	require.Equal(t, int32(404), logo.Response.StatusCode)
+	require.Equal(t, "text/plain", logo.Response.Headers["Content-Type"])
+	require.Equal(t, []byte("plain text"), logo.Response.Body)
+
+	_, err = Parse([]byte("http_interactions: []"))
+	require.Error(t, err)
+}
+
+func TestMatchOn(t *testing.T) {
+	criteria, warnings := MatchOn(DefaultMatchOn)
+	require.Equal(t, []string{"method", "path", "query"}, criteria)
+	require.Empty(t, warnings)
+
+	criteria, warnings = MatchOn([]string{"uri", "raw_body", "path", "headers", "custom"})
+	require.Equal(t, []string{"path", "query", "body", "headers"}, criteria)
+	require.Equal(t, []string{`the matcher "custom" has no equivalent in match_on`}, warnings)
+}
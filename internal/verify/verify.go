@@ -0,0 +1,78 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package verify checks cosign signatures of release assets produced with
+// `cosign sign-blob --key`.
+package verify
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// KeyFile is the public half of the release signing key, committed at the
+// repository root. Each SDK ships a copy to verify the archives it installs.
+const KeyFile = "cosign.pub"
+
+// ErrInvalidSignature is returned when a signature does not match the blob.
+var ErrInvalidSignature = errors.New("invalid signature")
+
+// ParsePublicKey parses a PEM encoded cosign public key.
+func ParsePublicKey(pemBytes []byte) (*ecdsa.PublicKey, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block found in public key")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key: %w", err)
+	}
+	ecKey, ok := key.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("unsupported public key type %T, expected ECDSA", key)
+	}
+	return ecKey, nil
+}
+
+// Blob verifies that signature, as written by cosign's --output-signature
+// (base64 of an ASN.1 ECDSA signature over the SHA256 of the blob), was
+// produced for blob by the private half of pub.
+func Blob(pub *ecdsa.PublicKey, blob io.Reader, signature []byte) error {
+	sig, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(signature)))
+	if err != nil {
+		return fmt.Errorf("failed to decode signature: %w", err)
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, blob); err != nil {
+		return fmt.Errorf("failed to hash blob: %w", err)
+	}
+	if !ecdsa.VerifyASN1(pub, h.Sum(nil), sig) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// SignatureName returns the release asset name holding the signature of asset.
+func SignatureName(asset string) string {
+	return asset + ".sig"
+}
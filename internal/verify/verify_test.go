@@ -0,0 +1,109 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package verify
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func publicKeyPEM(t *testing.T, pub any) []byte {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
+
+func sign(t *testing.T, key *ecdsa.PrivateKey, blob []byte) []byte {
+	digest := sha256.Sum256(blob)
+	sig, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+	require.NoError(t, err)
+	return []byte(base64.StdEncoding.EncodeToString(sig) + "\n")
+}
+
+func TestBlob(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	pub, err := ParsePublicKey(publicKeyPEM(t, &key.PublicKey))
+	require.NoError(t, err)
+
+	blob := []byte("test-server_Linux_x86_64.tar.gz contents")
+
+	testCases := []struct {
+		name      string
+		blob      []byte
+		signature []byte
+		wantErr   bool
+	}{
+		{
+			name:      "Valid signature",
+			blob:      blob,
+			signature: sign(t, key, blob),
+		},
+		{
+			name:      "Tampered blob",
+			blob:      append([]byte("x"), blob...),
+			signature: sign(t, key, blob),
+			wantErr:   true,
+		},
+		{
+			name:      "Signed by another key",
+			blob:      blob,
+			signature: sign(t, otherKey, blob),
+			wantErr:   true,
+		},
+		{
+			name:      "Malformed signature",
+			blob:      blob,
+			signature: []byte("not base64!"),
+			wantErr:   true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Blob(pub, bytes.NewReader(tc.blob), tc.signature)
+			if tc.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestParsePublicKey(t *testing.T) {
+	_, err := ParsePublicKey([]byte("garbage"))
+	require.Error(t, err)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+	_, err = ParsePublicKey(publicKeyPEM(t, &rsaKey.PublicKey))
+	require.ErrorContains(t, err, "expected ECDSA")
+}
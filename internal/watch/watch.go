@@ -0,0 +1,125 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package watch polls directories for files that were added, changed or
+// removed, for reloading stub files and recordings while the server runs.
+// Polling needs no file system notification API and works the same on every
+// platform and file system, at the cost of noticing a change only at the next
+// poll.
+package watch
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// stamp tells the versions of a file apart.
+type stamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// Snapshot is the state of the files of a directory.
+type Snapshot map[string]stamp
+
+// Scan returns the snapshot of the files of dir and its subdirectories for
+// which keep returns true, keeping all of them if keep is nil. A missing dir
+// has no files.
+func Scan(dir string, keep func(path string) bool) (Snapshot, error) {
+	s := Snapshot{}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || keep != nil && !keep(path) {
+			return nil
+		}
+		info, err := d.Info()
+		if os.IsNotExist(err) {
+			// Removed since it was listed.
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		s[path] = stamp{info.ModTime(), info.Size()}
+		return nil
+	})
+	return s, err
+}
+
+// Paths returns the files of s in order.
+func (s Snapshot) Paths() []string {
+	out := make([]string, 0, len(s))
+	for path := range s {
+		out = append(out, path)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Changed returns the files that were added, changed or removed from s to
+// next, in order.
+func (s Snapshot) Changed(next Snapshot) []string {
+	var out []string
+	for path, st := range next {
+		if old, ok := s[path]; !ok || !old.modTime.Equal(st.modTime) || old.size != st.size {
+			out = append(out, path)
+		}
+	}
+	for path := range s {
+		if _, ok := next[path]; !ok {
+			out = append(out, path)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Dir polls dir every interval and calls changed with the files that were
+// added, changed or removed since the previous poll, starting from the files
+// it has when Dir is called, until stop is called. A poll that fails, e.g.
+// on a file it may not read, is retried at the next one.
+func Dir(dir string, interval time.Duration, keep func(path string) bool, changed func(paths []string)) (stop func()) {
+	last, _ := Scan(dir, keep)
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+			next, err := Scan(dir, keep)
+			if err != nil {
+				continue
+			}
+			if paths := last.Changed(next); len(paths) > 0 {
+				changed(paths)
+			}
+			last = next
+		}
+	}()
+	return func() { close(done) }
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package watch
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestChanged(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string, mtime time.Time) {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+		require.NoError(t, os.Chtimes(path, mtime, mtime))
+	}
+	yaml := func(path string) bool { return filepath.Ext(path) == ".yaml" }
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	write("a.yaml", "a", start)
+	write("b.yaml", "b", start)
+	write("notes.txt", "n", start)
+
+	before, err := Scan(dir, yaml)
+	require.NoError(t, err)
+	require.Len(t, before, 2)
+	require.Empty(t, before.Changed(before))
+
+	write("a.yaml", "a", start.Add(time.Second))
+	write("sub/c.yaml", "c", start)
+	write("notes.txt", "changed", start.Add(time.Second))
+	require.NoError(t, os.Remove(filepath.Join(dir, "b.yaml")))
+	after, err := Scan(dir, yaml)
+	require.NoError(t, err)
+	require.Equal(t, []string{filepath.Join(dir, "a.yaml"), filepath.Join(dir, "b.yaml"), filepath.Join(dir, "sub", "c.yaml")}, before.Changed(after))
+
+	missing, err := Scan(filepath.Join(dir, "missing"), nil)
+	require.NoError(t, err)
+	require.Empty(t, missing)
+}
+
+func TestDir(t *testing.T) {
+	dir := t.TempDir()
+	changes := make(chan []string, 10)
+	stop := Dir(dir, 10*time.Millisecond, nil, func(paths []string) { changes <- paths })
+	defer stop()
+
+	path := filepath.Join(dir, "stub.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("- path: /"), 0644))
+	select {
+	case paths := <-changes:
+		require.Equal(t, []string{path}, paths)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the new file was not noticed")
+	}
+}
@@ -0,0 +1,518 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package wiremock converts WireMock stub mappings into test-server routes,
+// keeping their request matchers, responses, delays, faults and scenarios
+// where routes can express them and warning about the rest.
+package wiremock
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/test-server/internal/config"
+)
+
+// Mapping is a WireMock stub mapping.
+type Mapping struct {
+	ID                    string   `json:"id"`
+	UUID                  string   `json:"uuid"`
+	Name                  string   `json:"name"`
+	Priority              *int     `json:"priority"`
+	ScenarioName          string   `json:"scenarioName"`
+	RequiredScenarioState string   `json:"requiredScenarioState"`
+	NewScenarioState      string   `json:"newScenarioState"`
+	Request               Request  `json:"request"`
+	Response              Response `json:"response"`
+	PostServeActions      any      `json:"postServeActions"`
+	ServeEventListeners   any      `json:"serveEventListeners"`
+}
+
+// Request is the request matcher of a mapping.
+type Request struct {
+	Method          string `json:"method"`
+	URL             string `json:"url"`
+	URLPath         string `json:"urlPath"`
+	URLPattern      string `json:"urlPattern"`
+	URLPathPattern  string `json:"urlPathPattern"`
+	URLPathTemplate string `json:"urlPathTemplate"`
+	// The matchers are kept as decoded to warn about the ones without an
+	// equivalent.
+	Headers              map[string]map[string]any `json:"headers"`
+	QueryParameters      map[string]map[string]any `json:"queryParameters"`
+	PathParameters       map[string]map[string]any `json:"pathParameters"`
+	Cookies              map[string]map[string]any `json:"cookies"`
+	BodyPatterns         []map[string]any          `json:"bodyPatterns"`
+	BasicAuthCredentials any                       `json:"basicAuthCredentials"`
+}
+
+// Response is the response definition of a mapping.
+type Response struct {
+	Status                 int            `json:"status"`
+	Headers                map[string]any `json:"headers"`
+	Body                   *string        `json:"body"`
+	JSONBody               any            `json:"jsonBody"`
+	Base64Body             string         `json:"base64Body"`
+	BodyFileName           string         `json:"bodyFileName"`
+	FixedDelayMilliseconds *int           `json:"fixedDelayMilliseconds"`
+	DelayDistribution      *struct {
+		Type   string  `json:"type"`
+		Lower  int     `json:"lower"`
+		Upper  int     `json:"upper"`
+		Median int     `json:"median"`
+		Sigma  float64 `json:"sigma"`
+	} `json:"delayDistribution"`
+	ChunkedDribbleDelay *struct {
+		NumberOfChunks int `json:"numberOfChunks"`
+		TotalDuration  int `json:"totalDuration"`
+	} `json:"chunkedDribbleDelay"`
+	Fault        string   `json:"fault"`
+	Transformers []string `json:"transformers"`
+	ProxyBaseURL string   `json:"proxyBaseUrl"`
+}
+
+// Parse reads a mapping file: one mapping, or {"mappings": [...]}.
+func Parse(data []byte) ([]Mapping, error) {
+	var file struct {
+		Mappings []Mapping `json:"mappings"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+	if file.Mappings != nil {
+		return file.Mappings, nil
+	}
+	var m Mapping
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	return []Mapping{m}, nil
+}
+
+// Load converts the mappings of paths, each a mapping file, a directory of
+// mapping files or the root directory of WireMock, with mappings and
+// __files directories. The body files are read from the __files directory
+// next to the mappings. It returns the routes in the order of the files, and
+// warnings about what the routes leave out.
+func Load(paths ...string) ([]config.Route, []string, error) {
+	var routes []config.Route
+	var warnings []string
+	for _, path := range paths {
+		files, bodies, err := mappingFiles(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, file := range files {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return nil, nil, err
+			}
+			mappings, err := Parse(data)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s: %w", file, err)
+			}
+			for i, m := range mappings {
+				where := fmt.Sprintf("%s: mapping %d", file, i)
+				if m.Name != "" {
+					where = fmt.Sprintf("%s: mapping %q", file, m.Name)
+				}
+				route, warned, err := Convert(m, bodies)
+				if err != nil {
+					return nil, nil, fmt.Errorf("%s: %w", where, err)
+				}
+				for _, w := range warned {
+					warnings = append(warnings, where+": "+w)
+				}
+				if route != nil {
+					routes = append(routes, *route)
+				}
+			}
+		}
+	}
+	return routes, warnings, nil
+}
+
+// mappingFiles returns the mapping files of path and the directory of their
+// body files.
+func mappingFiles(path string) ([]string, string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, "", err
+	}
+	if !info.IsDir() {
+		return []string{path}, filepath.Join(filepath.Dir(filepath.Dir(path)), "__files"), nil
+	}
+	dir, bodies := path, filepath.Join(filepath.Dir(path), "__files")
+	if info, err := os.Stat(filepath.Join(path, "mappings")); err == nil && info.IsDir() {
+		dir, bodies = filepath.Join(path, "mappings"), filepath.Join(path, "__files")
+	}
+	var files []string
+	err = filepath.WalkDir(dir, func(p string, d os.DirEntry, err error) error {
+		if err == nil && !d.IsDir() && strings.EqualFold(filepath.Ext(p), ".json") {
+			files = append(files, p)
+		}
+		return err
+	})
+	sort.Strings(files)
+	return files, bodies, err
+}
+
+// Convert returns the route of m, or nil for a mapping routes cannot serve,
+// with warnings about what the route leaves out. bodies is the directory
+// of the body files.
+func Convert(m Mapping, bodies string) (*config.Route, []string, error) {
+	var warnings []string
+	warn := func(format string, args ...any) {
+		warnings = append(warnings, fmt.Sprintf(format, args...))
+	}
+	if m.Response.ProxyBaseURL != "" {
+		warn("skipped: test-server proxies the requests without a route to %s by itself", m.Response.ProxyBaseURL)
+		return nil, warnings, nil
+	}
+
+	route := &config.Route{
+		Name:          m.Name,
+		Scenario:      m.ScenarioName,
+		RequiredState: m.RequiredScenarioState,
+		NewState:      m.NewScenarioState,
+	}
+	if route.Name == "" {
+		route.Name = m.ID
+	}
+	if route.Name == "" {
+		route.Name = m.UUID
+	}
+	// WireMock prefers the lowest priority, 5 by default, and routes the
+	// highest, 0 by default.
+	if m.Priority != nil {
+		route.Priority = 5 - *m.Priority
+	}
+	if m.PostServeActions != nil || m.ServeEventListeners != nil {
+		warn("the post-serve actions are dropped; see the webhooks of routes")
+	}
+
+	req := m.Request
+	if req.Method != "ANY" {
+		route.Method = strings.ToUpper(req.Method)
+	}
+	switch {
+	case req.URL != "":
+		route.URLRegex = regexp.QuoteMeta(req.URL)
+	case req.URLPath != "" && strings.ContainsAny(req.URLPath, "*{}"):
+		route.URLRegex = regexp.QuoteMeta(req.URLPath) + `(\?.*)?`
+	case req.URLPath != "":
+		route.Path = req.URLPath
+	case req.URLPattern != "":
+		route.URLRegex = req.URLPattern
+	case req.URLPathPattern != "":
+		route.URLRegex = "(?:" + req.URLPathPattern + `)(\?.*)?`
+	case req.URLPathTemplate != "":
+		route.Path = pathTemplate(req.URLPathTemplate)
+	}
+	var err error
+	if route.Headers, err = valueMatches(req.Headers, "header", warn); err != nil {
+		return nil, nil, err
+	}
+	if route.Query, err = valueMatches(req.QueryParameters, "query parameter", warn); err != nil {
+		return nil, nil, err
+	}
+	if len(req.PathParameters) > 0 {
+		warn("the path parameter matchers are dropped")
+	}
+	if len(req.Cookies) > 0 {
+		warn("the cookie matchers are dropped")
+	}
+	if req.BasicAuthCredentials != nil {
+		warn("the basic auth credentials are dropped")
+	}
+	var body []config.BodyMatch
+	for _, p := range req.BodyPatterns {
+		b, err := bodyMatch(p, warn)
+		if err != nil {
+			return nil, nil, fmt.Errorf("bodyPatterns: %w", err)
+		}
+		if b != nil {
+			body = append(body, *b)
+		}
+	}
+	switch len(body) {
+	case 0:
+	case 1:
+		route.Body = &body[0]
+	default:
+		route.Body = &config.BodyMatch{All: body}
+	}
+
+	if err := convertResponse(route, &m.Response, bodies, warn); err != nil {
+		return nil, nil, err
+	}
+	return route, warnings, nil
+}
+
+// sortedKeys returns the keys of m in order, for the warnings to come in
+// the same order every time.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+var templateParamRe = regexp.MustCompile(`\{([^{}]*)\}`)
+
+// pathTemplate returns the route path of a WireMock path template, e.g.
+// /contacts/{contactId}.
+func pathTemplate(template string) string {
+	return templateParamRe.ReplaceAllStringFunc(template, func(p string) string {
+		name := []byte(p[1 : len(p)-1])
+		for i, c := range name {
+			if !(c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || i > 0 && '0' <= c && c <= '9') {
+				name[i] = '_'
+			}
+		}
+		if len(name) == 0 {
+			return "*"
+		}
+		return "{" + string(name) + "}"
+	})
+}
+
+// valueMatches converts the matchers of headers or query parameters.
+func valueMatches(matchers map[string]map[string]any, kind string, warn func(string, ...any)) (map[string]config.ValueMatch, error) {
+	if len(matchers) == 0 {
+		return nil, nil
+	}
+	out := map[string]config.ValueMatch{}
+	for _, name := range sortedKeys(matchers) {
+		m := matchers[name]
+		var v config.ValueMatch
+		caseInsensitive, _ := m["caseInsensitive"].(bool)
+		for _, key := range sortedKeys(m) {
+			value := m[key]
+			if key == "caseInsensitive" {
+				continue
+			}
+			if key == "absent" {
+				v.Absent, _ = value.(bool)
+				continue
+			}
+			s, ok := value.(string)
+			switch {
+			case key != "equalTo" && key != "contains" && key != "matches":
+				warn("the %s matcher of the %s %s is dropped", key, kind, name)
+				continue
+			case !ok:
+				return nil, fmt.Errorf("%s %s: %s is not a string", kind, name, key)
+			case key == "equalTo" && caseInsensitive:
+				v.Regex = "(?i)" + regexp.QuoteMeta(s)
+			case key == "equalTo":
+				v.Equals = s
+			case key == "contains":
+				v.Contains = s
+			default:
+				v.Regex = s
+			}
+		}
+		if v == (config.ValueMatch{}) {
+			v.Present = true
+		}
+		out[name] = v
+	}
+	return out, nil
+}
+
+// bodyMatch converts a body pattern, or returns nil if routes cannot
+// express it.
+func bodyMatch(p map[string]any, warn func(string, ...any)) (*config.BodyMatch, error) {
+	flags := "(?s)"
+	if caseInsensitive, _ := p["caseInsensitive"].(bool); caseInsensitive {
+		flags = "(?is)"
+	}
+	for _, key := range sortedKeys(p) {
+		value := p[key]
+		switch key {
+		case "equalToJson":
+			v := value
+			if s, ok := value.(string); ok {
+				if err := json.Unmarshal([]byte(s), &v); err != nil {
+					return nil, fmt.Errorf("equalToJson: %w", err)
+				}
+			}
+			if ignore, _ := p["ignoreArrayOrder"].(bool); ignore {
+				warn("ignoreArrayOrder is dropped: the arrays must be in order")
+			}
+			if ignore, _ := p["ignoreExtraElements"].(bool); ignore {
+				return &config.BodyMatch{JSONPath: "$", Contains: v}, nil
+			}
+			return &config.BodyMatch{JSONPath: "$", Equals: v}, nil
+		case "matchesJsonPath", "matchesXPath":
+			b := &config.BodyMatch{}
+			expr, ok := value.(string)
+			if m, isMap := value.(map[string]any); isMap {
+				expr, ok = m["expression"].(string)
+				switch {
+				case m["equalTo"] != nil:
+					b.Equals = m["equalTo"]
+				case m["contains"] != nil:
+					b.Contains = m["contains"]
+				case len(m) > 1:
+					warn("the matcher of the %s %s is dropped", key, expr)
+				}
+			}
+			if !ok {
+				return nil, fmt.Errorf("%s: no expression", key)
+			}
+			if key == "matchesJsonPath" {
+				b.JSONPath = expr
+			} else {
+				b.XPath = expr
+			}
+			return b, nil
+		case "equalTo", "contains", "matches":
+			s, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s is not a string", key)
+			}
+			switch key {
+			case "equalTo":
+				return &config.BodyMatch{Regex: flags + `\A` + regexp.QuoteMeta(s) + `\z`}, nil
+			case "contains":
+				return &config.BodyMatch{Regex: flags + regexp.QuoteMeta(s)}, nil
+			}
+			return &config.BodyMatch{Regex: flags + `\A(?:` + s + `)\z`}, nil
+		case "caseInsensitive", "ignoreArrayOrder", "ignoreExtraElements":
+		default:
+			warn("the body pattern %s is dropped", key)
+			return nil, nil
+		}
+	}
+	return nil, nil
+}
+
+// faults are the route faults closest to the WireMock ones.
+var faults = map[string]func() *config.Fault{
+	"CONNECTION_RESET_BY_PEER": func() *config.Fault { return &config.Fault{Reset: true} },
+	"EMPTY_RESPONSE":           func() *config.Fault { return &config.Fault{Reset: true} },
+	"RANDOM_DATA_THEN_CLOSE":   func() *config.Fault { return &config.Fault{MalformedJSON: true} },
+	"MALFORMED_RESPONSE_CHUNK": func() *config.Fault { return &config.Fault{TruncateAfter: new(int64)} },
+}
+
+// convertResponse sets the response, delay and fault of route from r.
+func convertResponse(route *config.Route, r *Response, bodies string, warn func(string, ...any)) error {
+	resp := &config.Response{Status: r.Status}
+	route.Response = resp
+	for name, value := range r.Headers {
+		if resp.Headers == nil {
+			resp.Headers = map[string]string{}
+		}
+		if values, ok := value.([]any); ok {
+			s := make([]string, len(values))
+			for i, v := range values {
+				s[i] = fmt.Sprint(v)
+			}
+			resp.Headers[name] = strings.Join(s, ", ")
+			continue
+		}
+		resp.Headers[name] = fmt.Sprint(value)
+	}
+	switch {
+	case r.Body != nil:
+		resp.Body = *r.Body
+	case r.JSONBody != nil:
+		data, err := json.Marshal(r.JSONBody)
+		if err != nil {
+			return fmt.Errorf("jsonBody: %w", err)
+		}
+		resp.Body = string(data)
+	case r.Base64Body != "":
+		resp.BodyBase64 = r.Base64Body
+	case r.BodyFileName != "":
+		// The body is inlined so that the stub file works from anywhere.
+		data, err := os.ReadFile(filepath.Join(bodies, filepath.FromSlash(r.BodyFileName)))
+		if err != nil {
+			return fmt.Errorf("bodyFileName: %w", err)
+		}
+		if utf8.Valid(data) {
+			resp.Body = string(data)
+		} else {
+			resp.BodyBase64 = base64.StdEncoding.EncodeToString(data)
+		}
+	}
+	for _, t := range r.Transformers {
+		if t == "response-template" {
+			warn("the response template is kept literally: Handlebars templates are not converted")
+		} else {
+			warn("the transformer %s is dropped", t)
+		}
+	}
+
+	if ms := r.FixedDelayMilliseconds; ms != nil {
+		route.Delay = &config.Delay{Duration: fmt.Sprintf("%dms", *ms)}
+	}
+	switch d := r.DelayDistribution; {
+	case d == nil:
+	case route.Delay != nil:
+		warn("the delay distribution is dropped for the fixed delay")
+	case d.Type == "uniform":
+		route.Delay = &config.Delay{Distribution: "uniform", Min: fmt.Sprintf("%dms", d.Lower), Max: fmt.Sprintf("%dms", d.Upper)}
+	case d.Type == "lognormal":
+		route.Delay = &config.Delay{Distribution: "lognormal", Median: fmt.Sprintf("%dms", d.Median), Sigma: d.Sigma}
+	default:
+		warn("the %s delay distribution is dropped", d.Type)
+	}
+	if d := r.ChunkedDribbleDelay; d != nil && d.NumberOfChunks > 0 {
+		if resp.Body == "" {
+			warn("the chunked dribble delay is dropped: only text bodies are chunked")
+		} else {
+			resp.Chunks = chunks(resp.Body, d.NumberOfChunks, d.TotalDuration)
+			resp.Body = ""
+		}
+	}
+
+	if r.Fault != "" {
+		fault, ok := faults[r.Fault]
+		if !ok {
+			warn("the fault %s is dropped", r.Fault)
+			return nil
+		}
+		route.Fault = fault()
+	}
+	return nil
+}
+
+// chunks splits body into n chunks spread over total milliseconds, the first
+// one sent right away.
+func chunks(body string, n, total int) []config.Chunk {
+	runes := []rune(body)
+	n = min(n, len(runes))
+	out := make([]config.Chunk, n)
+	for i := range out {
+		out[i].Data = string(runes[i*len(runes)/n : (i+1)*len(runes)/n])
+		if i > 0 {
+			out[i].Delay = fmt.Sprintf("%dms", total/(n-1))
+		}
+	}
+	return out
+}
@@ -0,0 +1,151 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wiremock
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/test-server/internal/config"
+	"github.com/google/test-server/internal/route"
+	"github.com/stretchr/testify/require"
+)
+
+const mappings = `{
+  "mappings": [
+    {
+      "name": "get user",
+      "priority": 1,
+      "request": {
+        "method": "GET",
+        "urlPathTemplate": "/users/{user-id}",
+        "headers": {"Authorization": {"matches": "Bearer .+"}, "X-Debug": {"absent": true}},
+        "queryParameters": {"fields": {"equalTo": "NAME", "caseInsensitive": true}}
+      },
+      "response": {"status": 200, "jsonBody": {"name": "Ada"}, "headers": {"Content-Type": "application/json", "Vary": ["Accept", "Origin"]}}
+    },
+    {
+      "id": "7f1c",
+      "scenarioName": "checkout",
+      "requiredScenarioState": "Started",
+      "newScenarioState": "Paid",
+      "request": {
+        "method": "POST",
+        "url": "/orders?express=true",
+        "bodyPatterns": [{"equalToJson": "{\"item\": 1}", "ignoreExtraElements": true}, {"matchesJsonPath": "$.card"}]
+      },
+      "response": {"status": 201, "bodyFileName": "order.json", "fixedDelayMilliseconds": 50}
+    },
+    {
+      "request": {"method": "ANY", "urlPathPattern": "/files/[0-9]+", "cookies": {"session": {"equalTo": "x"}}},
+      "response": {"fault": "CONNECTION_RESET_BY_PEER", "transformers": ["response-template"]}
+    },
+    {
+      "request": {"urlPath": "/slow"},
+      "response": {"body": "abcdef", "chunkedDribbleDelay": {"numberOfChunks": 3, "totalDuration": 100}, "delayDistribution": {"type": "uniform", "lower": 10, "upper": 20}}
+    }
+  ]
+}`
+
+func TestLoad(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "mappings"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "__files"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "mappings", "api.json"), []byte(mappings), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "mappings", "proxy.json"), []byte(`{"request": {"urlPattern": ".*"}, "response": {"proxyBaseUrl": "https://example.com"}}`), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "__files", "order.json"), []byte(`{"id": 1}`), 0644))
+
+	routes, warnings, err := Load(root)
+	require.NoError(t, err)
+	api := filepath.Join(root, "mappings", "api.json")
+	require.Equal(t, []string{
+		api + ": mapping 2: the cookie matchers are dropped",
+		api + ": mapping 2: the response template is kept literally: Handlebars templates are not converted",
+		filepath.Join(root, "mappings", "proxy.json") + ": mapping 0: skipped: test-server proxies the requests without a route to https://example.com by itself",
+	}, warnings)
+	require.Equal(t, []config.Route{
+		{
+			Name: "get user", Priority: 4, Method: "GET", Path: "/users/{user_id}",
+			Headers: map[string]config.ValueMatch{"Authorization": {Regex: "Bearer .+"}, "X-Debug": {Absent: true}},
+			Query:   map[string]config.ValueMatch{"fields": {Regex: "(?i)NAME"}},
+			Response: &config.Response{
+				Status:  200,
+				Headers: map[string]string{"Content-Type": "application/json", "Vary": "Accept, Origin"},
+				Body:    `{"name":"Ada"}`,
+			},
+		},
+		{
+			Name: "7f1c", Method: "POST", URLRegex: `/orders\?express=true`,
+			Scenario: "checkout", RequiredState: "Started", NewState: "Paid",
+			Body: &config.BodyMatch{All: []config.BodyMatch{
+				{JSONPath: "$", Contains: map[string]any{"item": float64(1)}},
+				{JSONPath: "$.card"},
+			}},
+			Delay:    &config.Delay{Duration: "50ms"},
+			Response: &config.Response{Status: 201, Body: `{"id": 1}`},
+		},
+		{
+			URLRegex: `(?:/files/[0-9]+)(\?.*)?`,
+			Fault:    &config.Fault{Reset: true},
+			Response: &config.Response{},
+		},
+		{
+			Path:  "/slow",
+			Delay: &config.Delay{Distribution: "uniform", Min: "10ms", Max: "20ms"},
+			Response: &config.Response{Chunks: []config.Chunk{
+				{Data: "ab"}, {Data: "cd", Delay: "50ms"}, {Data: "ef", Delay: "50ms"},
+			}},
+		},
+	}, routes)
+
+	// The routes match like the mappings.
+	router, err := route.New("example.com", routes)
+	require.NoError(t, err)
+	handler := router.Wrap(http.NotFoundHandler())
+	send := func(method, target, body string, headers ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		for i := 0; i < len(headers); i += 2 {
+			req.Header.Set(headers[i], headers[i+1])
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+	require.Equal(t, `{"name":"Ada"}`, send("GET", "/users/1?fields=name", "", "Authorization", "Bearer t").Body.String())
+	require.Equal(t, http.StatusNotFound, send("GET", "/users/1?fields=name", "").Code)
+	require.Equal(t, http.StatusNotFound, send("POST", "/orders?express=true", `{"item": 1}`).Code)
+	require.Equal(t, http.StatusCreated, send("POST", "/orders?express=true", `{"item": 1, "card": "4242"}`).Code)
+	// The scenario moved on.
+	require.Equal(t, http.StatusNotFound, send("POST", "/orders?express=true", `{"item": 1, "card": "4242"}`).Code)
+}
+
+func TestConvertErrors(t *testing.T) {
+	for _, m := range []string{
+		`{"request": {"headers": {"X": {"equalTo": 1}}}}`,
+		`{"request": {"bodyPatterns": [{"equalToJson": "{"}]}}`,
+		`{"request": {}, "response": {"bodyFileName": "missing.json"}}`,
+	} {
+		mappings, err := Parse([]byte(m))
+		require.NoError(t, err)
+		_, _, err = Convert(mappings[0], t.TempDir())
+		require.Error(t, err, m)
+	}
+}
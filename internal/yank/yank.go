@@ -0,0 +1,145 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package yank reads yanked.json, the list of releases withdrawn after they
+// were published, and derives the "yanked" entry of the SDK checksums.json
+// files from it.
+package yank
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/google/test-server/internal/semver"
+)
+
+// File is the name of the yanked release list at the repository root.
+const File = "yanked.json"
+
+// ChecksumsKey is the checksums.json entry mapping each yanked version to its
+// replacement. The SDK installers refuse to install the versions it lists.
+const ChecksumsKey = "yanked"
+
+// Entry describes why a release was yanked and which release replaces it.
+type Entry struct {
+	Reason      string `json:"reason"`
+	Replacement string `json:"replacement"`
+}
+
+// List maps yanked version tags to their entry.
+type List map[string]Entry
+
+// Load reads the list at path. A missing file is an empty list.
+func Load(path string) (List, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return List{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	l := List{}
+	if err := json.Unmarshal(data, &l); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return l, nil
+}
+
+// Save writes the list to path.
+func (l List) Save(path string) error {
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// Versions returns the yanked versions in lexical order.
+func (l List) Versions() []string {
+	versions := make([]string, 0, len(l))
+	for v := range l {
+		versions = append(versions, v)
+	}
+	sort.Strings(versions)
+	return versions
+}
+
+// Validate checks that every entry has a reason and a replacement that is a
+// released, newer and not yanked version. released reports whether a version
+// has checksums in checksums.json.
+func (l List) Validate(released func(string) bool) error {
+	for _, version := range l.Versions() {
+		e := l[version]
+		v, err := semver.Parse(version)
+		if err != nil {
+			return err
+		}
+		if !released(version) {
+			return fmt.Errorf("yanked version %s was never released", version)
+		}
+		if e.Reason == "" {
+			return fmt.Errorf("yanked version %s has no reason", version)
+		}
+		r, err := semver.Parse(e.Replacement)
+		if err != nil {
+			return fmt.Errorf("yanked version %s: replacement: %w", version, err)
+		}
+		if !semver.Less(v, r) {
+			return fmt.Errorf("yanked version %s: replacement %s is not newer", version, e.Replacement)
+		}
+		if !released(e.Replacement) {
+			return fmt.Errorf("yanked version %s: replacement %s was never released", version, e.Replacement)
+		}
+		if _, ok := l[e.Replacement]; ok {
+			return fmt.Errorf("yanked version %s: replacement %s is yanked as well", version, e.Replacement)
+		}
+	}
+	return nil
+}
+
+// Replacements returns the ChecksumsKey entry: each yanked version mapped to
+// its replacement.
+func (l List) Replacements() map[string]string {
+	m := make(map[string]string, len(l))
+	for v, e := range l {
+		m[v] = e.Replacement
+	}
+	return m
+}
+
+// Check returns an error naming the replacement if version is yanked.
+func (l List) Check(version string) error {
+	e, ok := l[version]
+	if !ok {
+		return nil
+	}
+	return fmt.Errorf("%s was yanked (%s); use %s instead", version, e.Reason, e.Replacement)
+}
+
+// CheckVersion is Check against the File in the working directory, for the
+// release tooling that runs from the repository root.
+func CheckVersion(version string) error {
+	l, err := Load(File)
+	if err != nil {
+		return err
+	}
+	return l.Check(version)
+}
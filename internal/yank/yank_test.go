@@ -0,0 +1,80 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package yank
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	released := map[string]bool{"v0.2.7": true, "v0.2.8": true, "v0.2.9": true}
+
+	testCases := []struct {
+		name    string
+		list    List
+		wantErr string
+	}{
+		{name: "valid", list: List{"v0.2.8": {Reason: "broken replay", Replacement: "v0.2.9"}}},
+		{name: "unreleased version", list: List{"v0.2.6": {Reason: "x", Replacement: "v0.2.9"}}, wantErr: "v0.2.6 was never released"},
+		{name: "no reason", list: List{"v0.2.8": {Replacement: "v0.2.9"}}, wantErr: "has no reason"},
+		{name: "missing replacement", list: List{"v0.2.8": {Reason: "x"}}, wantErr: "replacement"},
+		{name: "older replacement", list: List{"v0.2.8": {Reason: "x", Replacement: "v0.2.7"}}, wantErr: "is not newer"},
+		{name: "unreleased replacement", list: List{"v0.2.8": {Reason: "x", Replacement: "v0.3.0"}}, wantErr: "replacement v0.3.0 was never released"},
+		{
+			name: "yanked replacement",
+			list: List{
+				"v0.2.7": {Reason: "x", Replacement: "v0.2.8"},
+				"v0.2.8": {Reason: "y", Replacement: "v0.2.9"},
+			},
+			wantErr: "replacement v0.2.8 is yanked as well",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.list.Validate(func(v string) bool { return released[v] })
+			if tc.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorContains(t, err, tc.wantErr)
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	l := List{"v0.2.8": {Reason: "recordings written with the wrong hash", Replacement: "v0.2.9"}}
+	require.NoError(t, l.Check("v0.2.9"))
+	require.EqualError(t, l.Check("v0.2.8"), "v0.2.8 was yanked (recordings written with the wrong hash); use v0.2.9 instead")
+	require.Equal(t, map[string]string{"v0.2.8": "v0.2.9"}, l.Replacements())
+}
+
+func TestLoadSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), File)
+	l, err := Load(path)
+	require.NoError(t, err)
+	require.Empty(t, l)
+
+	l["v0.2.8"] = Entry{Reason: "x", Replacement: "v0.2.9"}
+	require.NoError(t, l.Save(path))
+	got, err := Load(path)
+	require.NoError(t, err)
+	require.Equal(t, l, got)
+}
@@ -18,6 +18,9 @@ package main
 
 import "github.com/google/test-server/cmd"
 
+// version is set by goreleaser through -ldflags "-X main.version=...".
+var version = "dev"
+
 func main() {
-	cmd.Execute()
+	cmd.Execute(version)
 }
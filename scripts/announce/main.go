@@ -0,0 +1,313 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/test-server/internal/githubapi"
+	"github.com/google/test-server/internal/releaserepo"
+	"github.com/google/test-server/internal/semver"
+)
+
+// --- General Project Configuration ---
+const (
+	projectName = "test-server"
+
+	// slackEnv and discordEnv hold comma separated incoming webhook URLs.
+	slackEnv   = "SLACK_WEBHOOK_URLS"
+	discordEnv = "DISCORD_WEBHOOK_URLS"
+
+	// discordLimit is the longest message Discord accepts.
+	discordLimit = 2000
+)
+
+// sdkPackages are the published SDK packages and where their version is read
+// from.
+var sdkPackages = []struct {
+	Registry string
+	Name     string
+	File     string
+	Pattern  *regexp.Regexp
+}{
+	{"npm", "test-server-sdk", "sdks/typescript/package.json", regexp.MustCompile(`(?m)^  "version": "([^"]+)"`)},
+	{"PyPI", "test-server-sdk", "sdks/python/pyproject.toml", regexp.MustCompile(`(?m)^version = "([^"]+)"`)},
+	{"NuGet", "TestServerSdk", "sdks/dotnet/TestServerSdk.csproj", regexp.MustCompile(`<PackageVersion>([^<]+)</PackageVersion>`)},
+}
+
+// highlightHeadings are the release notes sections (see scripts/release-notes)
+// worth announcing, in order.
+var highlightHeadings = []string{"## ⚠ Breaking Changes", "### Features", "### Bug Fixes"}
+
+// repo is the repository the release is read from and discussed in; see
+// releaserepo.EnvVar.
+var repo = releaserepo.Production
+
+var (
+	maxHighlights = flag.Int("max-highlights", 8, "Announce at most this many entries of the release notes")
+	discussion    = flag.String("discussion", "", "Also start a GitHub Discussion in this category (e.g. Announcements)")
+	prerelease    = flag.Bool("prerelease", false, "Announce a pre-release; they are skipped by default")
+	dryRun        = flag.Bool("dry-run", false, "Print the announcement instead of posting it")
+)
+
+type sdkPackage struct {
+	Registry, Name, Version string
+}
+
+// announcement is the content posted to every target.
+type announcement struct {
+	Version    string
+	URL        string
+	Highlights []string
+	Omitted    int
+	Packages   []sdkPackage
+}
+
+var (
+	commitRefRe = regexp.MustCompile(`\s*\([0-9a-f]{7,40}\)$`)
+	scopeRe     = regexp.MustCompile(`^\*\*([^*]+):\*\*\s*`)
+)
+
+// highlights returns the entries of the highlighted sections of the release
+// notes, breaking changes first, without their commit references.
+func highlights(notes string) []string {
+	sections := make(map[string][]string)
+	current := ""
+	scanner := bufio.NewScanner(strings.NewReader(notes))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \r")
+		if strings.HasPrefix(line, "#") {
+			current = line
+			continue
+		}
+		entry, ok := strings.CutPrefix(line, "- ")
+		if !ok || current == "" {
+			continue
+		}
+		entry = commitRefRe.ReplaceAllString(entry, "")
+		entry = scopeRe.ReplaceAllString(entry, "$1: ")
+		sections[current] = append(sections[current], entry)
+	}
+	var out []string
+	seen := make(map[string]bool)
+	for _, h := range highlightHeadings {
+		for _, entry := range sections[h] {
+			// Commits touching several components are listed under each.
+			if !seen[entry] {
+				seen[entry] = true
+				out = append(out, entry)
+			}
+		}
+	}
+	return out
+}
+
+func readPackages() ([]sdkPackage, error) {
+	var out []sdkPackage
+	for _, p := range sdkPackages {
+		data, err := os.ReadFile(p.File)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", p.File, err)
+		}
+		m := p.Pattern.FindSubmatch(data)
+		if m == nil {
+			return nil, fmt.Errorf("no version found in %s", p.File)
+		}
+		out = append(out, sdkPackage{Registry: p.Registry, Name: p.Name, Version: string(m[1])})
+	}
+	return out, nil
+}
+
+// render formats the announcement; bold wraps emphasised text in the
+// markup of the target.
+func (a *announcement) render(bold string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s%s %s%s is out: %s\n", bold, projectName, a.Version, bold, a.URL)
+	if len(a.Highlights) > 0 {
+		fmt.Fprintf(&b, "\n%sHighlights%s\n", bold, bold)
+		for _, h := range a.Highlights {
+			fmt.Fprintf(&b, "• %s\n", h)
+		}
+		if a.Omitted > 0 {
+			fmt.Fprintf(&b, "• …and %d more in the release notes\n", a.Omitted)
+		}
+	}
+	fmt.Fprintf(&b, "\n%sSDK packages%s\n", bold, bold)
+	for _, p := range a.Packages {
+		fmt.Fprintf(&b, "• %s %s %s\n", p.Registry, p.Name, p.Version)
+	}
+	return b.String()
+}
+
+// markdown formats the announcement for a GitHub Discussion.
+func (a *announcement) markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[%s %s](%s) is out.\n", projectName, a.Version, a.URL)
+	if len(a.Highlights) > 0 {
+		b.WriteString("\n### Highlights\n\n")
+		for _, h := range a.Highlights {
+			fmt.Fprintf(&b, "- %s\n", h)
+		}
+		if a.Omitted > 0 {
+			fmt.Fprintf(&b, "- …and %d more in the [release notes](%s)\n", a.Omitted, a.URL)
+		}
+	}
+	b.WriteString("\n### SDK packages\n\n| Registry | Package | Version |\n| --- | --- | --- |\n")
+	for _, p := range a.Packages {
+		fmt.Fprintf(&b, "| %s | `%s` | %s |\n", p.Registry, p.Name, p.Version)
+	}
+	return b.String()
+}
+
+// webhooks returns the URLs listed in env.
+func webhooks(env string) []string {
+	var urls []string
+	for _, u := range strings.Split(os.Getenv(env), ",") {
+		if u = strings.TrimSpace(u); u != "" {
+			urls = append(urls, u)
+		}
+	}
+	return urls
+}
+
+// post sends payload as JSON to a webhook. The URL is a secret and is never
+// printed.
+func post(url string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	client := &http.Client{Timeout: time.Minute}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("webhook request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("webhook returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: go run ./scripts/announce [--discussion category] [--max-highlights n] [--prerelease] [--dry-run] <version_tag>")
+		fmt.Fprintf(os.Stderr, "Posts a release summary to the Slack and Discord webhooks in %s and %s.\n", slackEnv, discordEnv)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	version := flag.Arg(0)
+	ver, err := semver.Parse(version)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if ver.Prerelease() && !*prerelease {
+		fmt.Printf("%s is a pre-release; not announcing it (pass --prerelease to).\n", version)
+		return
+	}
+	if repo, err = releaserepo.FromEnv(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if repo.Staging() {
+		fmt.Printf("Using the staging release repository %s.\n", repo)
+	}
+	slack, discord := webhooks(slackEnv), webhooks(discordEnv)
+	if len(slack) == 0 && len(discord) == 0 && *discussion == "" && !*dryRun {
+		fmt.Printf("Neither %s, %s nor --discussion is set; nothing to announce to.\n", slackEnv, discordEnv)
+		return
+	}
+
+	c := githubapi.New(repo)
+	release, err := c.Release(version)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading the release %s: %v\n", version, err)
+		os.Exit(1)
+	}
+	packages, err := readPackages()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	a := &announcement{Version: version, URL: release.HTMLURL, Packages: packages}
+	if a.URL == "" {
+		a.URL = repo.ReleaseURL(version)
+	}
+	a.Highlights = highlights(release.Body)
+	if len(a.Highlights) > *maxHighlights {
+		a.Omitted = len(a.Highlights) - *maxHighlights
+		a.Highlights = a.Highlights[:*maxHighlights]
+	}
+
+	if *dryRun {
+		fmt.Printf("--- Slack ---\n%s\n--- Discord ---\n%s\n", a.render("*"), a.render("**"))
+		if *discussion != "" {
+			fmt.Printf("--- GitHub Discussion (%s) ---\n%s", *discussion, a.markdown())
+		}
+		return
+	}
+
+	var failed []string
+	for i, u := range slack {
+		if err := post(u, map[string]string{"text": a.render("*")}); err != nil {
+			fmt.Fprintf(os.Stderr, "Error posting to Slack webhook %d: %v\n", i+1, err)
+			failed = append(failed, fmt.Sprintf("Slack webhook %d", i+1))
+			continue
+		}
+		fmt.Printf("Posted to Slack webhook %d.\n", i+1)
+	}
+	content := a.render("**")
+	if r := []rune(content); len(r) > discordLimit {
+		content = string(r[:discordLimit-1]) + "…"
+	}
+	for i, u := range discord {
+		if err := post(u, map[string]string{"content": content}); err != nil {
+			fmt.Fprintf(os.Stderr, "Error posting to Discord webhook %d: %v\n", i+1, err)
+			failed = append(failed, fmt.Sprintf("Discord webhook %d", i+1))
+			continue
+		}
+		fmt.Printf("Posted to Discord webhook %d.\n", i+1)
+	}
+	if *discussion != "" {
+		d, err := c.CreateDiscussion(*discussion, fmt.Sprintf("%s %s", projectName, version), a.markdown())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating the discussion: %v\n", err)
+			failed = append(failed, "GitHub Discussion")
+		} else {
+			fmt.Printf("Discussion: %s\n", d.URL)
+		}
+	}
+	if len(failed) > 0 {
+		fmt.Fprintf(os.Stderr, "Error: the announcement failed for: %s\n", strings.Join(failed, ", "))
+		os.Exit(1)
+	}
+}
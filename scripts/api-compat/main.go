@@ -0,0 +1,188 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/test-server/internal/semver"
+)
+
+// --- General Project Configuration ---
+const (
+	modulePath = "github.com/google/test-server"
+	// apidiff is pinned so that the same change is judged the same way on
+	// every machine.
+	apidiff = "golang.org/x/exp/cmd/apidiff@v0.0.0-20260820142414-ca536658362e"
+)
+
+var baseTag = flag.String("base", "", "Release to compare against (default: the newest stable release older than the compared version)")
+
+func output(dir, name string, args ...string) (string, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+// previousRelease returns the newest stable release tag older than version,
+// or older than every tag when version is nil.
+func previousRelease(version *semver.Version) (*semver.Version, error) {
+	tags, err := output(".", "git", "tag", "--list", "v*")
+	if err != nil {
+		return nil, fmt.Errorf("git tag failed: %w", err)
+	}
+	var prev *semver.Version
+	for _, tag := range strings.Fields(tags) {
+		v, err := semver.Parse(tag)
+		if err != nil || v.Prerelease() {
+			continue
+		}
+		if version != nil && !semver.Less(v, *version) {
+			continue
+		}
+		if prev == nil || semver.Less(*prev, v) {
+			prev = &v
+		}
+	}
+	return prev, nil
+}
+
+// sourceDir returns the directory holding version: the working tree for
+// "local", otherwise a temporary worktree of the tag.
+func sourceDir(version, workDir string) (string, func(), error) {
+	if version == "local" {
+		return ".", func() {}, nil
+	}
+	dir := filepath.Join(workDir, version)
+	if out, err := exec.Command("git", "worktree", "add", "--detach", dir, version).CombinedOutput(); err != nil {
+		return "", nil, fmt.Errorf("git worktree add %s failed: %v\n%s", version, err, out)
+	}
+	return dir, func() { exec.Command("git", "worktree", "remove", "--force", dir).Run() }, nil
+}
+
+// exportAPI writes the exported API of the module at version to a file in
+// workDir and returns its path. Internal packages are not part of the API.
+func exportAPI(version, workDir string) (string, error) {
+	src, cleanup, err := sourceDir(version, workDir)
+	if err != nil {
+		return "", err
+	}
+	defer cleanup()
+	file, err := filepath.Abs(filepath.Join(workDir, version+".api"))
+	if err != nil {
+		return "", err
+	}
+	cmd := exec.Command("go", "run", apidiff, "-m", "-w", file, modulePath)
+	cmd.Dir = src
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("apidiff failed to export the API of %s: %v\n%s", version, err, out)
+	}
+	return file, nil
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: go run ./scripts/api-compat [--base tag] <version_tag|local>")
+		fmt.Fprintln(os.Stderr, "Compares the exported Go API with the previous release and fails on breaking changes, unless version_tag is a new major version.")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	arg := flag.Arg(0)
+	// "local" is compared as if it were released as the next minor version.
+	var version *semver.Version
+	if arg != "local" {
+		v, err := semver.Parse(arg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		version = &v
+	}
+
+	var base *semver.Version
+	if *baseTag != "" {
+		v, err := semver.Parse(*baseTag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: --base: %v\n", err)
+			os.Exit(1)
+		}
+		base = &v
+	} else {
+		prev, err := previousRelease(version)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if prev == nil {
+			fmt.Printf("No stable release before %s; nothing to compare.\n", arg)
+			return
+		}
+		base = prev
+	}
+
+	workDir, err := os.MkdirTemp("", "api-compat-")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating work directory: %v\n", err)
+		os.Exit(1)
+	}
+	defer os.RemoveAll(workDir)
+
+	fmt.Printf("Comparing the Go API of %s with %s...\n", arg, base.Tag())
+	oldAPI, err := exportAPI(base.Tag(), workDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	newAPI, err := exportAPI(arg, workDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	report, err := output(".", "go", "run", apidiff, "-m", "-incompatible", oldAPI, newAPI)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: apidiff failed: %v\n", err)
+		os.Exit(1)
+	}
+	var breaking []string
+	for _, line := range strings.Split(report, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "- ") {
+			breaking = append(breaking, line)
+		}
+	}
+	if len(breaking) == 0 {
+		fmt.Printf("No breaking changes to the Go API since %s.\n", base.Tag())
+		return
+	}
+
+	fmt.Println(report)
+	if version != nil && version.Major > base.Major {
+		fmt.Printf("%d breaking changes since %s; allowed in the major release %s. List them in the release notes.\n", len(breaking), base.Tag(), arg)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Error: %d breaking changes to the Go API since %s.\n", len(breaking), base.Tag())
+	fmt.Fprintln(os.Stderr, "Programs embedding the server break on them. Keep the old API working (add instead of changing signatures), or hold the change for the next major version.")
+	os.Exit(1)
+}
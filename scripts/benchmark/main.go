@@ -0,0 +1,691 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/google/test-server/internal/checksumsjson"
+	"github.com/google/test-server/internal/config"
+	"github.com/google/test-server/internal/githubapi"
+	"github.com/google/test-server/internal/platforms"
+	"github.com/google/test-server/internal/releaserepo"
+	"github.com/google/test-server/internal/store"
+)
+
+// --- General Project Configuration ---
+const (
+	projectName = "test-server"
+
+	healthPath = "/healthz"
+)
+
+// client downloads the release assets.
+var client *githubapi.Client
+
+var (
+	requests        = flag.Int("requests", 2000, "Measured requests per run")
+	warmup          = flag.Int("warmup", 200, "Unmeasured requests sent before each run; all must succeed")
+	concurrency     = flag.Int("concurrency", 1, "Concurrent clients")
+	runs            = flag.Int("runs", 5, "Runs per version; the median of each metric is reported")
+	maxLatencyReg   = flag.Float64("max-latency-regression", 10, "Fail if p95 latency grows by more than this many percent")
+	maxThroughput   = flag.Float64("max-throughput-regression", 10, "Fail if throughput drops by more than this many percent")
+	maxMemoryReg    = flag.Float64("max-memory-regression", 20, "Fail if peak RSS grows by more than this many percent (Linux only)")
+	reportPath      = flag.String("report", "", "Also write the results as JSON to this file")
+	keepWorkDir     = flag.Bool("keep", false, "Keep the work directory (binaries, recordings, server logs)")
+	serverStartWait = flag.Duration("start-timeout", 10*time.Second, "How long to wait for a server to become healthy")
+)
+
+// endpoint is the replayed endpoint. The target is never contacted in
+// replay mode; it only feeds into the recorded request hashes.
+var endpoint = config.EndpointConfig{
+	TargetType: "https",
+	TargetHost: "bench.test-server.invalid",
+	TargetPort: 443,
+	SourceType: "http",
+	Health:     healthPath,
+}
+
+// workItem is one request of the standardized workload and the response
+// recorded for it.
+type workItem struct {
+	method   string
+	path     string
+	body     string
+	response []map[string]any
+}
+
+// workload returns the standardized workload: small lookups, JSON POSTs with
+// medium responses and streamed (SSE) responses, in a fixed order.
+func workload() []workItem {
+	var items []workItem
+	for i := 0; i < 20; i++ {
+		items = append(items, workItem{
+			method:   http.MethodGet,
+			path:     fmt.Sprintf("/v1/items/%d", i),
+			response: []map[string]any{{"id": i, "name": fmt.Sprintf("item-%d", i)}},
+		})
+	}
+	for i := 0; i < 20; i++ {
+		parts := make([]any, 50)
+		for j := range parts {
+			parts[j] = map[string]any{"index": j, "text": strings.Repeat("lorem ipsum ", 8)}
+		}
+		items = append(items, workItem{
+			method:   http.MethodPost,
+			path:     "/v1/models/bench:generateContent",
+			body:     fmt.Sprintf(`{"prompt":"request %d","temperature":0.5}`, i),
+			response: []map[string]any{{"candidates": parts}},
+		})
+	}
+	for i := 0; i < 10; i++ {
+		var segments []map[string]any
+		for j := 0; j < 20; j++ {
+			segments = append(segments, map[string]any{"chunk": j, "text": strings.Repeat("token ", 16)})
+		}
+		items = append(items, workItem{
+			method:   http.MethodPost,
+			path:     "/v1/models/bench:streamGenerateContent?alt=sse",
+			body:     fmt.Sprintf(`{"prompt":"stream %d"}`, i),
+			response: segments,
+		})
+	}
+	return items
+}
+
+func (w workItem) key() string {
+	return w.method + " " + w.path + " " + w.body
+}
+
+func (w workItem) newRequest(baseURL string) (*http.Request, error) {
+	var body io.Reader
+	if w.body != "" {
+		body = strings.NewReader(w.body)
+	}
+	req, err := http.NewRequest(w.method, baseURL+w.path, body)
+	if err != nil {
+		return nil, err
+	}
+	if w.body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return req, nil
+}
+
+// writeRecordings records the workload the way the server would: every
+// request is sent to a local capture server so the recorded request (and its
+// hash) matches exactly what a replaying server sees from the same client.
+func writeRecordings(dir string, items []workItem, client *http.Client) error {
+	byKey := make(map[string]workItem, len(items))
+	for _, it := range items {
+		byKey[it.key()] = it
+	}
+	var mu sync.Mutex
+	var handlerErr error
+	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		it, ok := byKey[req.Method+" "+req.URL.String()+" "+string(body)]
+		if !ok {
+			http.Error(w, "unknown workload request", http.StatusNotFound)
+			return
+		}
+		recorded, err := store.NewRecordedRequest(req, store.HeadSHA, endpoint)
+		if err == nil {
+			sum := recorded.ComputeSum()
+			file := store.RecordFile{RecordID: sum, Interactions: []*store.RecordInteraction{{
+				Request:  recorded,
+				SHASum:   sum,
+				Response: &store.RecordedResponse{StatusCode: http.StatusOK, Headers: map[string]string{"Content-Type": "application/json"}, BodySegments: it.response},
+			}}}
+			var data []byte
+			if data, err = json.MarshalIndent(file, "", "  "); err == nil {
+				err = os.WriteFile(filepath.Join(dir, sum+".json"), data, 0644)
+			}
+		}
+		if err != nil {
+			mu.Lock()
+			handlerErr = err
+			mu.Unlock()
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{Handler: handler}
+	go srv.Serve(ln)
+	defer srv.Shutdown(context.Background())
+
+	base := "http://" + ln.Addr().String()
+	for _, it := range items {
+		req, err := it.newRequest(base)
+		if err != nil {
+			return err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			mu.Lock()
+			defer mu.Unlock()
+			return fmt.Errorf("recording %s failed: %s (%v)", it.key(), resp.Status, handlerErr)
+		}
+	}
+	return nil
+}
+
+// --- Fetching server binaries ---
+
+// expectedChecksum prefers the digest pinned in the SDKs and falls back to
+// the release's checksums file for versions that were never pinned.
+func expectedChecksum(version, archive string) (string, error) {
+	if d, err := checksumsjson.LoadCurrent(checksumsjson.Files[0]); err == nil {
+		if pinned, ok := d.Digests(version); ok && pinned[archive] != "" {
+			return pinned[archive], nil
+		}
+	}
+	name := fmt.Sprintf("%s_%s_checksums.txt", projectName, strings.TrimPrefix(version, "v"))
+	body, err := client.Download(version, name)
+	if err != nil {
+		return "", err
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		parts := strings.Fields(scanner.Text())
+		if len(parts) == 2 && parts[1] == archive {
+			return parts[0], nil
+		}
+	}
+	return "", fmt.Errorf("no checksum for %s in the %s checksums file", archive, version)
+}
+
+func extractBinary(archive string, data []byte, binaryName string) ([]byte, error) {
+	if strings.HasSuffix(archive, ".zip") {
+		zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return nil, err
+		}
+		for _, zf := range zr.File {
+			if zf.Name == binaryName {
+				rc, err := zf.Open()
+				if err != nil {
+					return nil, err
+				}
+				defer rc.Close()
+				return io.ReadAll(rc)
+			}
+		}
+		return nil, fmt.Errorf("%s not found in %s", binaryName, archive)
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("%s not found in %s", binaryName, archive)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Name == binaryName {
+			return io.ReadAll(tr)
+		}
+	}
+}
+
+// resolveBinary turns a version argument into a runnable binary: "local"
+// builds the working tree, a release tag is downloaded and verified, and
+// anything else is taken as a path to an existing binary.
+func resolveBinary(arg, workDir string, matrix *platforms.Matrix) (string, error) {
+	p, ok := matrix.Find(runtime.GOOS, runtime.GOARCH)
+	if !ok {
+		return "", fmt.Errorf("%s/%s is not in %s", runtime.GOOS, runtime.GOARCH, platforms.File)
+	}
+	binaryName := platforms.BinaryName(projectName, p)
+	dest := filepath.Join(workDir, strings.NewReplacer("/", "_", "\\", "_").Replace(arg), binaryName)
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return "", err
+	}
+
+	switch {
+	case arg == "local":
+		fmt.Println("Building the working tree...")
+		cmd := exec.Command("go", "build", "-o", dest, ".")
+		cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+		if err := cmd.Run(); err != nil {
+			return "", fmt.Errorf("go build failed: %w", err)
+		}
+		return dest, nil
+	case strings.HasPrefix(arg, "v") && !strings.ContainsAny(arg, "/\\"):
+		archive := matrix.ArchiveName(projectName, p)
+		expected, err := expectedChecksum(arg, archive)
+		if err != nil {
+			return "", err
+		}
+		fmt.Printf("Downloading %s %s...\n", archive, arg)
+		data, err := client.Download(arg, archive)
+		if err != nil {
+			return "", err
+		}
+		sum := sha256.Sum256(data)
+		if actual := hex.EncodeToString(sum[:]); actual != expected {
+			return "", fmt.Errorf("checksum mismatch for %s %s: expected %s, got %s", arg, archive, expected, actual)
+		}
+		binary, err := extractBinary(archive, data, binaryName)
+		if err != nil {
+			return "", err
+		}
+		return dest, os.WriteFile(dest, binary, 0755)
+	default:
+		return filepath.Abs(arg)
+	}
+}
+
+// --- Running the workload ---
+
+type runResult struct {
+	P50Ms        float64
+	P95Ms        float64
+	P99Ms        float64
+	Throughput   float64 // requests per second
+	PeakRSSBytes int64   // 0 when unknown
+}
+
+func freePort() (int, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer ln.Close()
+	return ln.Addr().(*net.TCPAddr).Port, nil
+}
+
+// peakRSS returns the process's peak resident set size on Linux.
+func peakRSS(pid int) int64 {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/status", pid))
+	if err != nil {
+		return 0
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if rest, ok := strings.CutPrefix(line, "VmHWM:"); ok {
+			fields := strings.Fields(rest)
+			if len(fields) == 2 && fields[1] == "kB" {
+				kb, _ := strconv.ParseInt(fields[0], 10, 64)
+				return kb * 1024
+			}
+		}
+	}
+	return 0
+}
+
+func percentile(sorted []time.Duration, p float64) float64 {
+	idx := int(float64(len(sorted)-1) * p)
+	return float64(sorted[idx].Microseconds()) / 1000
+}
+
+// drive sends n workload requests from the configured number of clients and
+// returns each request's latency.
+func drive(client *http.Client, base string, items []workItem, n int) ([]time.Duration, error) {
+	latencies := make([]time.Duration, n)
+	var next int
+	var mu sync.Mutex
+	var firstErr error
+	var wg sync.WaitGroup
+	for c := 0; c < *concurrency; c++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				mu.Lock()
+				i := next
+				next++
+				stop := i >= n || firstErr != nil
+				mu.Unlock()
+				if stop {
+					return
+				}
+				it := items[i%len(items)]
+				req, err := it.newRequest(base)
+				if err == nil {
+					start := time.Now()
+					var resp *http.Response
+					if resp, err = client.Do(req); err == nil {
+						_, err = io.Copy(io.Discard, resp.Body)
+						resp.Body.Close()
+						latencies[i] = time.Since(start)
+						if err == nil && resp.StatusCode != http.StatusOK {
+							err = fmt.Errorf("%s: %s", it.key(), resp.Status)
+						}
+					}
+				}
+				if err != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = err
+					}
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return latencies, firstErr
+}
+
+func runOnce(binary, cfgPath, recordingDir, logPath string, port int, items []workItem, client *http.Client) (runResult, error) {
+	var res runResult
+	logFile, err := os.Create(logPath)
+	if err != nil {
+		return res, err
+	}
+	defer logFile.Close()
+	cmd := exec.Command(binary, "replay", "--config", cfgPath, "--recording-dir", recordingDir)
+	cmd.Stdout, cmd.Stderr = logFile, logFile
+	if err := cmd.Start(); err != nil {
+		return res, err
+	}
+	defer func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	}()
+
+	base := fmt.Sprintf("http://127.0.0.1:%d", port)
+	deadline := time.Now().Add(*serverStartWait)
+	for {
+		resp, err := client.Get(base + healthPath)
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				break
+			}
+		}
+		if time.Now().After(deadline) {
+			return res, fmt.Errorf("server did not become healthy within %s (see %s)", *serverStartWait, logPath)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	if _, err := drive(client, base, items, *warmup); err != nil {
+		return res, fmt.Errorf("warm-up failed, the workload may not be compatible with this version (see %s): %w", logPath, err)
+	}
+	start := time.Now()
+	latencies, err := drive(client, base, items, *requests)
+	elapsed := time.Since(start)
+	if err != nil {
+		return res, fmt.Errorf("measured run failed (see %s): %w", logPath, err)
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	res.P50Ms = percentile(latencies, 0.50)
+	res.P95Ms = percentile(latencies, 0.95)
+	res.P99Ms = percentile(latencies, 0.99)
+	res.Throughput = float64(*requests) / elapsed.Seconds()
+	res.PeakRSSBytes = peakRSS(cmd.Process.Pid)
+	return res, nil
+}
+
+func median(values []float64) float64 {
+	sort.Float64s(values)
+	return values[len(values)/2]
+}
+
+// benchmarkRun starts binary on a fresh port and runs the workload once.
+func benchmarkRun(name, binary string, run int, workDir, recordingDir string, items []workItem, client *http.Client) (runResult, error) {
+	port, err := freePort()
+	if err != nil {
+		return runResult{}, err
+	}
+	ep := endpoint
+	ep.SourcePort = int64(port)
+	cfgPath := filepath.Join(workDir, fmt.Sprintf("config-%d.yml", port))
+	cfg := fmt.Sprintf("endpoints:\n  - target_host: %s\n    target_type: %s\n    target_port: %d\n    source_type: %s\n    source_port: %d\n    health: %s\n",
+		ep.TargetHost, ep.TargetType, ep.TargetPort, ep.SourceType, ep.SourcePort, ep.Health)
+	if err := os.WriteFile(cfgPath, []byte(cfg), 0644); err != nil {
+		return runResult{}, err
+	}
+	logPath := filepath.Join(workDir, fmt.Sprintf("%s-run%d.log", strings.NewReplacer("/", "_", "\\", "_").Replace(name), run))
+	r, err := runOnce(binary, cfgPath, recordingDir, logPath, port, items, client)
+	if err != nil {
+		return runResult{}, fmt.Errorf("%s: %w", name, err)
+	}
+	fmt.Printf("  %s run %d: p50 %.2fms, p95 %.2fms, %.0f req/s\n", name, run, r.P50Ms, r.P95Ms, r.Throughput)
+	return r, nil
+}
+
+// aggregate reports the median of each metric over several runs.
+func aggregate(results []runResult) runResult {
+	pick := func(f func(runResult) float64) float64 {
+		values := make([]float64, len(results))
+		for i, r := range results {
+			values[i] = f(r)
+		}
+		return median(values)
+	}
+	return runResult{
+		P50Ms:        pick(func(r runResult) float64 { return r.P50Ms }),
+		P95Ms:        pick(func(r runResult) float64 { return r.P95Ms }),
+		P99Ms:        pick(func(r runResult) float64 { return r.P99Ms }),
+		Throughput:   pick(func(r runResult) float64 { return r.Throughput }),
+		PeakRSSBytes: int64(pick(func(r runResult) float64 { return float64(r.PeakRSSBytes) })),
+	}
+}
+
+// --- Reporting ---
+
+type metric struct {
+	Name       string  `json:"name"`
+	Base       float64 `json:"base"`
+	Candidate  float64 `json:"candidate"`
+	DeltaPct   float64 `json:"delta_pct"`
+	LimitPct   float64 `json:"limit_pct,omitempty"`
+	Regression bool    `json:"regression"`
+}
+
+type report struct {
+	Base        string   `json:"base"`
+	Candidate   string   `json:"candidate"`
+	Requests    int      `json:"requests"`
+	Concurrency int      `json:"concurrency"`
+	Runs        int      `json:"runs"`
+	Metrics     []metric `json:"metrics"`
+}
+
+func deltaPct(base, candidate float64) float64 {
+	if base == 0 {
+		return 0
+	}
+	return (candidate - base) / base * 100
+}
+
+// compare builds the metric table. higherIsWorse says which direction is a
+// regression; a zero limit means the metric is informational.
+func compare(base, cand runResult) []metric {
+	type spec struct {
+		name          string
+		b, c          float64
+		limit         float64
+		higherIsWorse bool
+	}
+	specs := []spec{
+		{"p50 latency (ms)", base.P50Ms, cand.P50Ms, 0, true},
+		{"p95 latency (ms)", base.P95Ms, cand.P95Ms, *maxLatencyReg, true},
+		{"p99 latency (ms)", base.P99Ms, cand.P99Ms, 0, true},
+		{"throughput (req/s)", base.Throughput, cand.Throughput, *maxThroughput, false},
+	}
+	if base.PeakRSSBytes > 0 && cand.PeakRSSBytes > 0 {
+		specs = append(specs, spec{"peak RSS (MiB)", float64(base.PeakRSSBytes) / (1 << 20), float64(cand.PeakRSSBytes) / (1 << 20), *maxMemoryReg, true})
+	}
+	var metrics []metric
+	for _, s := range specs {
+		m := metric{Name: s.name, Base: s.b, Candidate: s.c, DeltaPct: deltaPct(s.b, s.c), LimitPct: s.limit}
+		if s.limit > 0 {
+			worse := m.DeltaPct
+			if !s.higherIsWorse {
+				worse = -worse
+			}
+			m.Regression = worse > s.limit
+		}
+		metrics = append(metrics, m)
+	}
+	return metrics
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: go run ./scripts/benchmark [flags] <base> <candidate>")
+		fmt.Fprintln(os.Stderr, "Each version is a release tag (v0.2.8), \"local\" to build the working tree, or a path to a binary.")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	client = githubapi.New(releaserepo.Production)
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	if *requests <= 0 || *runs <= 0 || *concurrency <= 0 {
+		fmt.Fprintln(os.Stderr, "Error: --requests, --runs and --concurrency must be positive")
+		os.Exit(1)
+	}
+	baseArg, candArg := flag.Arg(0), flag.Arg(1)
+
+	matrix, err := platforms.Load(platforms.File)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	workDir, err := os.MkdirTemp("", "test-server-bench-")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating work directory: %v\n", err)
+		os.Exit(1)
+	}
+	if *keepWorkDir {
+		fmt.Printf("Work directory: %s\n", workDir)
+	} else {
+		defer os.RemoveAll(workDir)
+	}
+	fail := func(format string, args ...any) {
+		fmt.Fprintf(os.Stderr, "Error: "+format+"\n", args...)
+		if !*keepWorkDir {
+			os.RemoveAll(workDir)
+		}
+		os.Exit(1)
+	}
+
+	baseBin, err := resolveBinary(baseArg, filepath.Join(workDir, "bin"), matrix)
+	if err != nil {
+		fail("%s: %v", baseArg, err)
+	}
+	candBin, err := resolveBinary(candArg, filepath.Join(workDir, "bin"), matrix)
+	if err != nil {
+		fail("%s: %v", candArg, err)
+	}
+
+	client := &http.Client{
+		Timeout:   30 * time.Second,
+		Transport: &http.Transport{MaxIdleConnsPerHost: *concurrency, DisableCompression: true},
+	}
+	items := workload()
+	recordingDir := filepath.Join(workDir, "recordings")
+	if err := os.MkdirAll(recordingDir, 0755); err != nil {
+		fail("%v", err)
+	}
+	if err := writeRecordings(recordingDir, items, client); err != nil {
+		fail("writing recordings: %v", err)
+	}
+
+	fmt.Printf("Benchmarking %s against %s (%d requests x %d runs, concurrency %d)...\n", candArg, baseArg, *requests, *runs, *concurrency)
+	// Runs alternate between the versions so that drift in machine load
+	// affects both equally.
+	var baseRuns, candRuns []runResult
+	for i := 1; i <= *runs; i++ {
+		r, err := benchmarkRun(baseArg, baseBin, i, workDir, recordingDir, items, client)
+		if err != nil {
+			fail("%v", err)
+		}
+		baseRuns = append(baseRuns, r)
+		if r, err = benchmarkRun(candArg, candBin, i, workDir, recordingDir, items, client); err != nil {
+			fail("%v", err)
+		}
+		candRuns = append(candRuns, r)
+	}
+	baseRes, candRes := aggregate(baseRuns), aggregate(candRuns)
+
+	rep := report{Base: baseArg, Candidate: candArg, Requests: *requests, Concurrency: *concurrency, Runs: *runs, Metrics: compare(baseRes, candRes)}
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "METRIC\t%s\t%s\tDELTA\tLIMIT\n", baseArg, candArg)
+	var regressions []string
+	for _, m := range rep.Metrics {
+		limit := "-"
+		if m.LimitPct > 0 {
+			limit = fmt.Sprintf("%.0f%%", m.LimitPct)
+		}
+		status := ""
+		if m.Regression {
+			status = "  REGRESSION"
+			regressions = append(regressions, m.Name)
+		}
+		fmt.Fprintf(w, "%s\t%.2f\t%.2f\t%+.1f%%\t%s%s\n", m.Name, m.Base, m.Candidate, m.DeltaPct, limit, status)
+	}
+	w.Flush()
+
+	if *reportPath != "" {
+		data, err := json.MarshalIndent(rep, "", "  ")
+		if err == nil {
+			err = os.WriteFile(*reportPath, append(data, '\n'), 0644)
+		}
+		if err != nil {
+			fail("writing %s: %v", *reportPath, err)
+		}
+		fmt.Printf("Report written to %s.\n", *reportPath)
+	}
+	if len(regressions) > 0 {
+		fail("%s regressed beyond the configured limits: %s", candArg, strings.Join(regressions, ", "))
+	}
+	fmt.Println("No regressions beyond the configured limits.")
+}
@@ -0,0 +1,374 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/google/test-server/internal/buildflags"
+	"github.com/google/test-server/internal/platforms"
+	"github.com/google/test-server/internal/semver"
+)
+
+// --- General Project Configuration ---
+const (
+	projectName = "test-server"
+	healthPath  = "/healthz"
+)
+
+var (
+	historyPath    = flag.String("history", "binary-metrics.json", "Tracked history of the release binary metrics")
+	runs           = flag.Int("runs", 10, "Cold starts measured on this platform; the median is recorded")
+	maxSizeGrowth  = flag.Float64("max-size-growth", 5, "Fail if a binary grows by more than this many percent over the previous release")
+	maxStartupReg  = flag.Float64("max-startup-regression", 50, "Fail if the startup time grows by more than this many percent over the previous release")
+	startupTimeout = flag.Duration("startup-timeout", 10*time.Second, "How long to wait for the server to become healthy")
+	checkOnly      = flag.Bool("check", false, "Compare against the history without recording the release")
+)
+
+// platformMetrics are the measurements of one release binary.
+type platformMetrics struct {
+	Platform  string  `json:"platform"`
+	SizeBytes int64   `json:"size_bytes"`
+	StartupMs float64 `json:"startup_ms,omitempty"` // only measured for the host platform
+}
+
+type releaseMetrics struct {
+	Version    string            `json:"version"`
+	GoVersion  string            `json:"go_version"`
+	Host       string            `json:"host"`
+	RecordedAt string            `json:"recorded_at"`
+	Platforms  []platformMetrics `json:"platforms"`
+}
+
+type history struct {
+	Releases []releaseMetrics `json:"releases"`
+}
+
+func loadHistory(path string) (*history, error) {
+	h := &history{}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return h, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, h); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return h, nil
+}
+
+// record replaces or adds the release and keeps the history ordered by version.
+func (h *history) record(r releaseMetrics) error {
+	kept := h.Releases[:0]
+	for _, old := range h.Releases {
+		if old.Version != r.Version {
+			kept = append(kept, old)
+		}
+	}
+	h.Releases = append(kept, r)
+	var sortErr error
+	sort.SliceStable(h.Releases, func(i, j int) bool {
+		a, err := semver.Parse(h.Releases[i].Version)
+		if err != nil {
+			sortErr = err
+			return false
+		}
+		b, err := semver.Parse(h.Releases[j].Version)
+		if err != nil {
+			sortErr = err
+			return false
+		}
+		return semver.Less(a, b)
+	})
+	return sortErr
+}
+
+// previous returns the newest recorded release older than version, or the
+// newest recorded release when version is nil (the working tree).
+func (h *history) previous(version *semver.Version) (*releaseMetrics, error) {
+	var best *releaseMetrics
+	var bestV semver.Version
+	for i := range h.Releases {
+		v, err := semver.Parse(h.Releases[i].Version)
+		if err != nil {
+			return nil, err
+		}
+		if version != nil && !semver.Less(v, *version) {
+			continue
+		}
+		if best == nil || semver.Less(bestV, v) {
+			best, bestV = &h.Releases[i], v
+		}
+	}
+	return best, nil
+}
+
+func (r *releaseMetrics) find(platform string) *platformMetrics {
+	for i := range r.Platforms {
+		if r.Platforms[i].Platform == platform {
+			return &r.Platforms[i]
+		}
+	}
+	return nil
+}
+
+// build cross-compiles the server in srcDir the way goreleaser does.
+func build(srcDir, out, goos, goarch, version string) error {
+	cmd := exec.Command("go", buildflags.Args(version, out)...)
+	cmd.Dir = srcDir
+	cmd.Env = append(os.Environ(), buildflags.Env(goos, goarch)...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("go build for %s/%s failed: %v\n%s", goos, goarch, err, out)
+	}
+	return nil
+}
+
+func freePort() (int, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer ln.Close()
+	return ln.Addr().(*net.TCPAddr).Port, nil
+}
+
+// startupTime starts the server in replay mode and returns the time until
+// its health endpoint answers, which is what the SDKs wait for.
+func startupTime(binary, workDir string) (time.Duration, error) {
+	port, err := freePort()
+	if err != nil {
+		return 0, err
+	}
+	cfgPath := filepath.Join(workDir, "config.yml")
+	cfg := fmt.Sprintf("endpoints:\n  - target_host: startup.test-server.invalid\n    target_type: https\n    target_port: 443\n    source_type: http\n    source_port: %d\n    health: %s\n", port, healthPath)
+	if err := os.WriteFile(cfgPath, []byte(cfg), 0644); err != nil {
+		return 0, err
+	}
+	recordingDir := filepath.Join(workDir, "recordings")
+	if err := os.MkdirAll(recordingDir, 0755); err != nil {
+		return 0, err
+	}
+
+	client := &http.Client{Timeout: time.Second}
+	url := fmt.Sprintf("http://127.0.0.1:%d%s", port, healthPath)
+	cmd := exec.Command(binary, "replay", "--config", cfgPath, "--recording-dir", recordingDir)
+	start := time.Now()
+	if err := cmd.Start(); err != nil {
+		return 0, err
+	}
+	defer func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	}()
+	for {
+		resp, err := client.Get(url)
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return time.Since(start), nil
+			}
+		}
+		if time.Since(start) > *startupTimeout {
+			return 0, fmt.Errorf("server did not become healthy within %s", *startupTimeout)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func median(values []float64) float64 {
+	sort.Float64s(values)
+	return values[len(values)/2]
+}
+
+func growth(prev, cur float64) float64 {
+	if prev == 0 {
+		return 0
+	}
+	return (cur - prev) / prev * 100
+}
+
+// sourceDir returns the directory to build version from: the working tree
+// for "local", otherwise a temporary worktree of the tag.
+func sourceDir(version, workDir string) (string, func(), error) {
+	if version == "local" {
+		return ".", func() {}, nil
+	}
+	dir := filepath.Join(workDir, "src")
+	if out, err := exec.Command("git", "worktree", "add", "--detach", dir, version).CombinedOutput(); err != nil {
+		return "", nil, fmt.Errorf("git worktree add %s failed: %v\n%s", version, err, out)
+	}
+	return dir, func() { exec.Command("git", "worktree", "remove", "--force", dir).Run() }, nil
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: go run ./scripts/binary-metrics [--check] [--history file] <version_tag|local>")
+		fmt.Fprintln(os.Stderr, "Records the stripped binary size per platform and the startup time of a release and fails on regressions.")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 || *runs < 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	arg := flag.Arg(0)
+	var version *semver.Version
+	if arg != "local" {
+		v, err := semver.Parse(arg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		version = &v
+	}
+
+	matrix, err := platforms.Load(platforms.File)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	hist, err := loadHistory(*historyPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	workDir, err := os.MkdirTemp("", "binary-metrics-")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating work directory: %v\n", err)
+		os.Exit(1)
+	}
+	defer os.RemoveAll(workDir)
+	src, cleanup, err := sourceDir(arg, workDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer cleanup()
+
+	goVersion, _ := exec.Command("go", "env", "GOVERSION").Output()
+	host := runtime.GOOS + "/" + runtime.GOARCH
+	cur := releaseMetrics{
+		Version:    arg,
+		GoVersion:  strings.TrimSpace(string(goVersion)),
+		Host:       host,
+		RecordedAt: time.Now().UTC().Format(time.RFC3339),
+	}
+	for _, p := range matrix.Platforms {
+		name := p.GOOS + "/" + p.GOARCH
+		out := filepath.Join(workDir, "bin", p.GOOS+"_"+p.GOARCH, platforms.BinaryName(projectName, p))
+		fmt.Printf("Building %s...\n", name)
+		if err := build(src, out, p.GOOS, p.GOARCH, arg); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		info, err := os.Stat(out)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		m := platformMetrics{Platform: name, SizeBytes: info.Size()}
+		if name == host {
+			var samples []float64
+			for i := 0; i < *runs; i++ {
+				d, err := startupTime(out, workDir)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error measuring startup: %v\n", err)
+					os.Exit(1)
+				}
+				samples = append(samples, float64(d.Microseconds())/1000)
+			}
+			m.StartupMs = median(samples)
+		}
+		cur.Platforms = append(cur.Platforms, m)
+	}
+
+	prev, err := hist.previous(version)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s: %v\n", *historyPath, err)
+		os.Exit(1)
+	}
+	var regressions []string
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "\nPLATFORM\tSIZE\tPREVIOUS\tCHANGE\tSTARTUP\tPREVIOUS\tCHANGE")
+	for _, m := range cur.Platforms {
+		sizePrev, sizeDelta, startup, startupPrev, startupDelta := "-", "-", "-", "-", "-"
+		if m.StartupMs > 0 {
+			startup = fmt.Sprintf("%.1fms", m.StartupMs)
+		}
+		if prev != nil {
+			if p := prev.find(m.Platform); p != nil {
+				g := growth(float64(p.SizeBytes), float64(m.SizeBytes))
+				sizePrev, sizeDelta = fmt.Sprintf("%d", p.SizeBytes), fmt.Sprintf("%+.1f%%", g)
+				if g > *maxSizeGrowth {
+					regressions = append(regressions, fmt.Sprintf("%s binary grew %.1f%% (limit %.1f%%)", m.Platform, g, *maxSizeGrowth))
+				}
+				// Startup times are only comparable when measured on the same kind of host.
+				if m.StartupMs > 0 && p.StartupMs > 0 && prev.Host == cur.Host {
+					g := growth(p.StartupMs, m.StartupMs)
+					startupPrev, startupDelta = fmt.Sprintf("%.1fms", p.StartupMs), fmt.Sprintf("%+.1f%%", g)
+					if g > *maxStartupReg {
+						regressions = append(regressions, fmt.Sprintf("%s startup slowed %.1f%% (limit %.1f%%)", m.Platform, g, *maxStartupReg))
+					}
+				}
+			}
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\t%s\t%s\n", m.Platform, m.SizeBytes, sizePrev, sizeDelta, startup, startupPrev, startupDelta)
+	}
+	tw.Flush()
+
+	if !*checkOnly && version != nil {
+		if err := hist.record(cur); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s: %v\n", *historyPath, err)
+			os.Exit(1)
+		}
+		data, err := json.MarshalIndent(hist, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error encoding history: %v\n", err)
+			os.Exit(1)
+		}
+		if err := os.WriteFile(*historyPath, append(data, '\n'), 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", *historyPath, err)
+			os.Exit(1)
+		}
+		fmt.Printf("\nRecorded %s in %s.\n", arg, *historyPath)
+	}
+	if len(regressions) > 0 {
+		fmt.Fprintln(os.Stderr, "\nBinary metrics regressed:")
+		for _, r := range regressions {
+			fmt.Fprintf(os.Stderr, "  %s\n", r)
+		}
+		os.Exit(1)
+	}
+	if prev == nil {
+		fmt.Println("\nNo earlier release in the history to compare with.")
+		return
+	}
+	fmt.Printf("\nWithin the limits relative to %s.\n", prev.Version)
+}
@@ -0,0 +1,366 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/google/test-server/internal/checksumsjson"
+	"github.com/google/test-server/internal/platforms"
+	"github.com/google/test-server/internal/sdkconstants"
+	"github.com/google/test-server/internal/yank"
+)
+
+// --- General Project Configuration ---
+const (
+	githubOwner = "google"
+	githubRepo  = "test-server"
+	projectName = "test-server"
+
+	pythonSDKDir      = "sdks/python"
+	packageDir        = pythonSDKDir + "/src/test_server_sdk"
+	packageName       = "test_server_sdk"
+	checksumsJSONPath = packageDir + "/checksums.json"
+	noticesFile       = "THIRD_PARTY_NOTICES"
+)
+
+var outDir = flag.String("out", "dist/wheels", "Directory to write the wheels to")
+
+type projectMetadata struct {
+	Name           string
+	Version        string
+	Summary        string
+	RequiresPython string
+	Dependencies   []string
+	Readme         string
+}
+
+var (
+	nameRe        = regexp.MustCompile(`(?m)^name\s*=\s*"([^"]+)"`)
+	versionRe     = regexp.MustCompile(`(?m)^version\s*=\s*"([^"]+)"`)
+	descriptionRe = regexp.MustCompile(`(?m)^description\s*=\s*"([^"]+)"`)
+	requiresRe    = regexp.MustCompile(`(?m)^requires-python\s*=\s*"([^"]+)"`)
+	depsRe        = regexp.MustCompile(`(?s)\ndependencies\s*=\s*\[(.*?)\]`)
+	quotedRe      = regexp.MustCompile(`"([^"]+)"`)
+)
+
+func readProjectMetadata() (*projectMetadata, error) {
+	data, err := os.ReadFile(filepath.Join(pythonSDKDir, "pyproject.toml"))
+	if err != nil {
+		return nil, err
+	}
+	content := string(data)
+	group := func(re *regexp.Regexp) string {
+		if m := re.FindStringSubmatch(content); m != nil {
+			return m[1]
+		}
+		return ""
+	}
+	meta := &projectMetadata{
+		Name:           group(nameRe),
+		Version:        group(versionRe),
+		Summary:        group(descriptionRe),
+		RequiresPython: group(requiresRe),
+	}
+	if meta.Name == "" || meta.Version == "" {
+		return nil, fmt.Errorf("could not find name and version in pyproject.toml")
+	}
+	if m := depsRe.FindStringSubmatch(content); m != nil {
+		for _, dep := range quotedRe.FindAllStringSubmatch(m[1], -1) {
+			meta.Dependencies = append(meta.Dependencies, dep[1])
+		}
+	}
+	if readme, err := os.ReadFile(filepath.Join(pythonSDKDir, "README.md")); err == nil {
+		meta.Readme = string(readme)
+	}
+	return meta, nil
+}
+
+func loadChecksums(version string) (map[string]string, error) {
+	all, err := checksumsjson.LoadCurrent(checksumsJSONPath)
+	if err != nil {
+		return nil, err
+	}
+	checksums, ok := all.Digests(version)
+	if !ok {
+		return nil, fmt.Errorf("%s has no entry for %s; run update-sdk-checksums first", checksumsJSONPath, version)
+	}
+	return checksums, nil
+}
+
+// pinnedServerVersion returns the server version the installers are pinned
+// to, so the embedded binary always matches what install.py would download.
+func pinnedServerVersion() (string, error) {
+	c, err := sdkconstants.Load(sdkconstants.File)
+	if err != nil {
+		return "", err
+	}
+	return c.TestServerVersion, nil
+}
+
+func fetchVerifiedBinary(version, archive, expected string) ([]byte, error) {
+	url := fmt.Sprintf("https://github.com/%s/%s/releases/download/%s/%s", githubOwner, githubRepo, version, archive)
+	fmt.Printf("Downloading %s...\n", url)
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download %s: status %s", url, resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	if actual := hex.EncodeToString(sum[:]); actual != expected {
+		return nil, fmt.Errorf("checksum mismatch for %s: expected %s, got %s", archive, expected, actual)
+	}
+
+	if strings.HasSuffix(archive, ".zip") {
+		zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return nil, err
+		}
+		for _, zf := range zr.File {
+			if zf.Name == projectName+".exe" {
+				rc, err := zf.Open()
+				if err != nil {
+					return nil, err
+				}
+				defer rc.Close()
+				return io.ReadAll(rc)
+			}
+		}
+		return nil, fmt.Errorf("%s.exe not found in %s", projectName, archive)
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("%s not found in %s", projectName, archive)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Name == projectName {
+			return io.ReadAll(tr)
+		}
+	}
+}
+
+// wheelWriter writes a wheel and accumulates its RECORD.
+type wheelWriter struct {
+	zw     *zip.Writer
+	record []string
+}
+
+func (w *wheelWriter) add(name string, data []byte, mode os.FileMode) error {
+	hdr := &zip.FileHeader{Name: name, Method: zip.Deflate}
+	hdr.SetMode(mode)
+	f, err := w.zw.CreateHeader(hdr)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		return err
+	}
+	sum := sha256.Sum256(data)
+	w.record = append(w.record, fmt.Sprintf("%s,sha256=%s,%d", name, base64.RawURLEncoding.EncodeToString(sum[:]), len(data)))
+	return nil
+}
+
+func metadataFile(meta *projectMetadata) []byte {
+	var b strings.Builder
+	b.WriteString("Metadata-Version: 2.1\n")
+	fmt.Fprintf(&b, "Name: %s\n", meta.Name)
+	fmt.Fprintf(&b, "Version: %s\n", meta.Version)
+	fmt.Fprintf(&b, "Summary: %s\n", meta.Summary)
+	fmt.Fprintf(&b, "Home-page: https://github.com/%s/%s\n", githubOwner, githubRepo)
+	b.WriteString("License: Apache-2.0\n")
+	if meta.RequiresPython != "" {
+		fmt.Fprintf(&b, "Requires-Python: %s\n", meta.RequiresPython)
+	}
+	for _, dep := range meta.Dependencies {
+		fmt.Fprintf(&b, "Requires-Dist: %s\n", dep)
+	}
+	if meta.Readme != "" {
+		b.WriteString("Description-Content-Type: text/markdown\n\n")
+		b.WriteString(meta.Readme)
+	}
+	return []byte(b.String())
+}
+
+func buildWheel(meta *projectMetadata, platformTag string, binaryName string, binary, notices []byte) (string, error) {
+	distName := strings.ReplaceAll(meta.Name, "-", "_")
+	wheelName := fmt.Sprintf("%s-%s-py3-none-%s.whl", distName, meta.Version, platformTag)
+	path := filepath.Join(*outDir, wheelName)
+	f, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	w := &wheelWriter{zw: zip.NewWriter(f)}
+
+	// Package sources, skipping anything a previous local install left behind.
+	var files []string
+	err = filepath.WalkDir(packageDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && (d.Name() == "bin" || d.Name() == "__pycache__") {
+			return filepath.SkipDir
+		}
+		if !d.IsDir() {
+			files = append(files, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	sort.Strings(files)
+	for _, p := range files {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return "", err
+		}
+		rel, _ := filepath.Rel(packageDir, p)
+		if err := w.add(packageName+"/"+filepath.ToSlash(rel), data, 0644); err != nil {
+			return "", err
+		}
+	}
+	if err := w.add(packageName+"/bin/"+binaryName, binary, 0755); err != nil {
+		return "", err
+	}
+
+	distInfo := fmt.Sprintf("%s-%s.dist-info", distName, meta.Version)
+	wheelFile := "Wheel-Version: 1.0\nGenerator: scripts/build-wheels\nRoot-Is-Purelib: false\n"
+	for _, tag := range strings.Split(platformTag, ".") {
+		wheelFile += fmt.Sprintf("Tag: py3-none-%s\n", tag)
+	}
+	entryPoints := "[console_scripts]\ndownload_golang_executable = test_server_sdk.install:main_downloader_function\n"
+	for name, data := range map[string][]byte{
+		"METADATA":         metadataFile(meta),
+		"WHEEL":            []byte(wheelFile),
+		"entry_points.txt": []byte(entryPoints),
+		"top_level.txt":    []byte(packageName + "\n"),
+		noticesFile:        notices,
+	} {
+		if err := w.add(distInfo+"/"+name, data, 0644); err != nil {
+			return "", err
+		}
+	}
+
+	recordName := distInfo + "/RECORD"
+	w.record = append(w.record, recordName+",,")
+	rf, err := w.zw.Create(recordName)
+	if err != nil {
+		return "", err
+	}
+	if _, err := rf.Write([]byte(strings.Join(w.record, "\n") + "\n")); err != nil {
+		return "", err
+	}
+	return path, w.zw.Close()
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: go run ./scripts/build-wheels [--out dir]")
+		fmt.Fprintln(os.Stderr, "Builds platform wheels embedding the server version pinned in sdks/constants.json.")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	meta, err := readProjectMetadata()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading pyproject.toml: %v\n", err)
+		os.Exit(1)
+	}
+	serverVersion, err := pinnedServerVersion()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := yank.CheckVersion(serverVersion); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	matrix, err := platforms.Load(platforms.File)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	checksums, err := loadChecksums(serverVersion)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	notices, err := os.ReadFile(noticesFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", noticesFile, err)
+		os.Exit(1)
+	}
+	if err := os.MkdirAll(*outDir, 0755); err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating %s: %v\n", *outDir, err)
+		os.Exit(1)
+	}
+
+	// The binary is built with CGO_ENABLED=0, so one wheel carries every
+	// platform tag listed for it (e.g. both manylinux and musllinux).
+	for _, p := range matrix.Filter(func(p platforms.Platform) bool { return len(p.Python) > 0 }) {
+		archive := matrix.ArchiveName(projectName, p)
+		tag := strings.Join(p.Python, ".")
+		expected, ok := checksums[archive]
+		if !ok {
+			fmt.Fprintf(os.Stderr, "Error: no checksum for %s in %s\n", archive, serverVersion)
+			os.Exit(1)
+		}
+		binary, err := fetchVerifiedBinary(serverVersion, archive, expected)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		path, err := buildWheel(meta, tag, platforms.BinaryName(projectName, p), binary, notices)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error building wheel for %s: %v\n", tag, err)
+			os.Exit(1)
+		}
+		fmt.Printf("Built %s (test-server %s).\n", path, serverVersion)
+	}
+}
@@ -0,0 +1,239 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/google/test-server/internal/semver"
+)
+
+var (
+	configPath = flag.String("config", "sdks/versioning.json", "SDK versioning config")
+	sdkFilter  = flag.String("sdk", "", "Comma separated SDK names to bump (default: all)")
+	dryRun     = flag.Bool("dry-run", false, "Print the new versions without writing any file")
+)
+
+// sdkEntry is one SDK in the versioning config. SDKs that are not independent
+// are released in lockstep and always share the same version; the first file
+// is the one the current version is read from.
+type sdkEntry struct {
+	Name        string   `json:"name"`
+	Files       []string `json:"files"`
+	Independent bool     `json:"independent"`
+}
+
+type config struct {
+	SDKs []sdkEntry `json:"sdks"`
+}
+
+// bump applies part ("major", "minor", "patch" or an explicit version) to v.
+// Bumping a pre-release by its own level releases it, as npm does.
+func bump(v semver.Version, part string) (semver.Version, error) {
+	switch part {
+	case "major":
+		if v.Pre != "" && v.Minor == 0 && v.Patch == 0 {
+			return semver.Version{Major: v.Major}, nil
+		}
+		return semver.Version{Major: v.Major + 1}, nil
+	case "minor":
+		if v.Pre != "" && v.Patch == 0 {
+			return semver.Version{Major: v.Major, Minor: v.Minor}, nil
+		}
+		return semver.Version{Major: v.Major, Minor: v.Minor + 1}, nil
+	case "patch":
+		if v.Pre != "" {
+			return semver.Version{Major: v.Major, Minor: v.Minor, Patch: v.Patch}, nil
+		}
+		return semver.Version{Major: v.Major, Minor: v.Minor, Patch: v.Patch + 1}, nil
+	}
+	next, err := semver.Parse(part)
+	if err != nil {
+		return semver.Version{}, err
+	}
+	if !semver.Less(v, next) {
+		return semver.Version{}, fmt.Errorf("explicit version %s is not greater than the current %s", next, v)
+	}
+	return next, nil
+}
+
+// versionPattern maps a version file to the regexp locating its version; the
+// version itself is always the second submatch.
+func versionPattern(path string) (*regexp.Regexp, error) {
+	switch base := filepath.Base(path); {
+	case base == "package.json":
+		return regexp.MustCompile(`(?m)^(  "version": ")([^"]+)(")`), nil
+	case base == "package-lock.json":
+		// The root package appears at the top level and under packages[""].
+		return regexp.MustCompile(`(?m)^(  "version": "|      "version": ")([^"]+)(")`), nil
+	case base == "pyproject.toml":
+		return regexp.MustCompile(`(?m)^(version = ")([^"]+)(")`), nil
+	case strings.HasSuffix(base, ".csproj"):
+		return regexp.MustCompile(`(<PackageVersion>)([^<]+)(</PackageVersion>)`), nil
+	}
+	return nil, fmt.Errorf("do not know how to version %s", path)
+}
+
+func readVersion(path string) (semver.Version, error) {
+	re, err := versionPattern(path)
+	if err != nil {
+		return semver.Version{}, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return semver.Version{}, err
+	}
+	m := re.FindSubmatch(data)
+	if m == nil {
+		return semver.Version{}, fmt.Errorf("no version found in %s", path)
+	}
+	return semver.Parse(string(m[2]))
+}
+
+// writeVersion replaces the version in path; only matches holding the old
+// version are touched so dependency entries in lock files are left alone.
+func writeVersion(path string, old, next semver.Version) error {
+	re, err := versionPattern(path)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	replaced := 0
+	updated := re.ReplaceAllFunc(data, func(match []byte) []byte {
+		m := re.FindSubmatch(match)
+		if string(m[2]) != old.String() {
+			return match
+		}
+		replaced++
+		return []byte(string(m[1]) + next.String() + string(m[3]))
+	})
+	if replaced == 0 {
+		return fmt.Errorf("version %s not found in %s", old, path)
+	}
+	if strings.HasSuffix(path, ".json") && !json.Valid(updated) {
+		return fmt.Errorf("refusing to write invalid JSON to %s", path)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, updated, info.Mode())
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: go run ./scripts/bump-sdk-versions [--sdk names] [--dry-run] <major|minor|patch|X.Y.Z>")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	part := flag.Arg(0)
+
+	data, err := os.ReadFile(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", *configPath, err)
+		os.Exit(1)
+	}
+	var cfg config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing %s: %v\n", *configPath, err)
+		os.Exit(1)
+	}
+
+	requested := map[string]bool{}
+	if *sdkFilter != "" {
+		for _, name := range strings.Split(*sdkFilter, ",") {
+			requested[strings.TrimSpace(name)] = true
+		}
+	}
+	isRequested := func(name string) bool { return len(requested) == 0 || requested[name] }
+	unknown := maps.Clone(requested)
+	current := map[string]semver.Version{}
+	var lockstepBase *semver.Version
+	lockstepSelected := false
+	for _, sdk := range cfg.SDKs {
+		if len(sdk.Files) == 0 {
+			fmt.Fprintf(os.Stderr, "Error: SDK %s has no version files\n", sdk.Name)
+			os.Exit(1)
+		}
+		v, err := readVersion(sdk.Files[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		current[sdk.Name] = v
+		if !sdk.Independent {
+			if lockstepBase == nil || semver.Less(*lockstepBase, v) {
+				lockstepBase = &v
+			}
+			if isRequested(sdk.Name) {
+				lockstepSelected = true
+			}
+		}
+		delete(unknown, sdk.Name)
+	}
+	for name := range unknown {
+		fmt.Fprintf(os.Stderr, "Error: unknown SDK %q in --sdk\n", name)
+		os.Exit(1)
+	}
+
+	var lockstepNext semver.Version
+	if lockstepSelected {
+		if lockstepNext, err = bump(*lockstepBase, part); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	for _, sdk := range cfg.SDKs {
+		old := current[sdk.Name]
+		var next semver.Version
+		switch {
+		case !sdk.Independent && lockstepSelected:
+			// Lockstep SDKs move together even when only one of them was named.
+			next = lockstepNext
+		case sdk.Independent && isRequested(sdk.Name):
+			if next, err = bump(old, part); err != nil {
+				fmt.Fprintf(os.Stderr, "Error bumping %s: %v\n", sdk.Name, err)
+				os.Exit(1)
+			}
+		default:
+			continue
+		}
+		fmt.Printf("%-12s %s -> %s\n", sdk.Name, old, next)
+		if *dryRun {
+			continue
+		}
+		for _, f := range sdk.Files {
+			if err := writeVersion(f, old, next); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+	}
+}
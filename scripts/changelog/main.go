@@ -0,0 +1,259 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	fragmentsDir  = ".changes/unreleased"
+	changelogFile = "CHANGELOG.md"
+)
+
+var (
+	configPath = flag.String("config", "sdks/versioning.json", "SDK versioning config listing the SDKs and their version files")
+	notesPath  = flag.String("notes", "", "Also write the combined release notes to this file")
+	dryRun     = flag.Bool("dry-run", false, "Print the notes without updating CHANGELOG.md or removing fragments")
+	date       = flag.String("date", time.Now().Format("2006-01-02"), "Release date recorded in the changelogs")
+)
+
+// changeTypes are the keep-a-changelog sections, in the order they are written.
+var changeTypes = []string{"Added", "Changed", "Deprecated", "Removed", "Fixed", "Security"}
+
+type sdkEntry struct {
+	Name  string   `json:"name"`
+	Files []string `json:"files"`
+}
+
+type fragment struct {
+	Path string
+	Type string
+	Text string
+}
+
+var frontMatterRe = regexp.MustCompile(`(?s)^---\s*\n(.*?)\n---\s*\n?(.*)$`)
+
+// parseFragment reads a changeset-style fragment: a markdown file whose front
+// matter names the keep-a-changelog section, e.g.
+//
+//	---
+//	type: fixed
+//	---
+//	Retry binary downloads on transient network errors.
+func parseFragment(path string) (fragment, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fragment{}, err
+	}
+	m := frontMatterRe.FindStringSubmatch(strings.ReplaceAll(string(data), "\r\n", "\n"))
+	if m == nil {
+		return fragment{}, fmt.Errorf("%s has no front matter", path)
+	}
+	f := fragment{Path: path, Text: strings.TrimSpace(m[2])}
+	for _, line := range strings.Split(m[1], "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if ok && strings.TrimSpace(key) == "type" {
+			f.Type = strings.TrimSpace(value)
+		}
+	}
+	for _, t := range changeTypes {
+		if strings.EqualFold(f.Type, t) {
+			f.Type = t
+		}
+	}
+	if !contains(changeTypes, f.Type) {
+		return fragment{}, fmt.Errorf("%s: type %q is not one of %s", path, f.Type, strings.Join(changeTypes, ", "))
+	}
+	if f.Text == "" {
+		return fragment{}, fmt.Errorf("%s has no description", path)
+	}
+	return f, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+var versionPatterns = map[string]*regexp.Regexp{
+	"package.json":   regexp.MustCompile(`(?m)^  "version": "([^"]+)"`),
+	"pyproject.toml": regexp.MustCompile(`(?m)^version = "([^"]+)"`),
+	".csproj":        regexp.MustCompile(`<PackageVersion>([^<]+)</PackageVersion>`),
+}
+
+func readVersion(path string) (string, error) {
+	re, ok := versionPatterns[filepath.Base(path)]
+	if !ok {
+		re = versionPatterns[filepath.Ext(path)]
+	}
+	if re == nil {
+		return "", fmt.Errorf("do not know how to read the version of %s", path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	m := re.FindSubmatch(data)
+	if m == nil {
+		return "", fmt.Errorf("no version found in %s", path)
+	}
+	return string(m[1]), nil
+}
+
+// renderSections renders the fragments grouped by section, one bullet each.
+func renderSections(fragments []fragment, heading string) string {
+	var b strings.Builder
+	for _, t := range changeTypes {
+		var items []string
+		for _, f := range fragments {
+			if f.Type == t {
+				items = append(items, "- "+strings.ReplaceAll(f.Text, "\n", "\n  "))
+			}
+		}
+		if len(items) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "%s %s\n\n%s\n\n", heading, t, strings.Join(items, "\n"))
+	}
+	return b.String()
+}
+
+const changelogHeader = `# Changelog
+
+All notable changes to this SDK are documented in this file.
+
+The format is based on [Keep a Changelog](https://keepachangelog.com/en/1.1.0/),
+and this project adheres to [Semantic Versioning](https://semver.org/spec/v2.0.0.html).
+
+`
+
+// prependRelease inserts the release section above the newest entry of the
+// changelog at path, creating the file if needed.
+func prependRelease(path, section string) error {
+	existing, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	content := string(existing)
+	if content == "" {
+		content = changelogHeader
+	}
+	if i := strings.Index(content, "\n## "); i >= 0 {
+		content = content[:i+1] + section + content[i+1:]
+	} else {
+		content = strings.TrimRight(content, "\n") + "\n\n" + section
+	}
+	return os.WriteFile(path, []byte(strings.TrimRight(content, "\n")+"\n"), 0644)
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: go run ./scripts/changelog [--notes file] [--dry-run] [--date YYYY-MM-DD]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	data, err := os.ReadFile(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", *configPath, err)
+		os.Exit(1)
+	}
+	var cfg struct {
+		SDKs []sdkEntry `json:"sdks"`
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing %s: %v\n", *configPath, err)
+		os.Exit(1)
+	}
+
+	var notes strings.Builder
+	var consumed []string
+	for _, sdk := range cfg.SDKs {
+		if len(sdk.Files) == 0 {
+			continue
+		}
+		sdkDir := filepath.Dir(sdk.Files[0])
+		paths, err := filepath.Glob(filepath.Join(sdkDir, fragmentsDir, "*.md"))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error listing fragments for %s: %v\n", sdk.Name, err)
+			os.Exit(1)
+		}
+		if len(paths) == 0 {
+			continue
+		}
+		sort.Strings(paths)
+		var fragments []fragment
+		for _, p := range paths {
+			f, err := parseFragment(p)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			fragments = append(fragments, f)
+		}
+		version, err := readVersion(sdk.Files[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Fprintf(&notes, "## %s SDK %s\n\n%s", sdk.Name, version, renderSections(fragments, "###"))
+		if !*dryRun {
+			section := fmt.Sprintf("## [%s] - %s\n\n%s", version, *date, renderSections(fragments, "###"))
+			path := filepath.Join(sdkDir, changelogFile)
+			if err := prependRelease(path, section); err != nil {
+				fmt.Fprintf(os.Stderr, "Error updating %s: %v\n", path, err)
+				os.Exit(1)
+			}
+			fmt.Fprintf(os.Stderr, "Added %d entries to %s.\n", len(fragments), path)
+		}
+		consumed = append(consumed, paths...)
+	}
+
+	if len(consumed) == 0 {
+		fmt.Fprintln(os.Stderr, "No unreleased changelog fragments found.")
+		return
+	}
+	fmt.Print(notes.String())
+	if *notesPath != "" {
+		if err := os.WriteFile(*notesPath, []byte(notes.String()), 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", *notesPath, err)
+			os.Exit(1)
+		}
+	}
+	if *dryRun {
+		return
+	}
+	for _, p := range consumed {
+		if err := os.Remove(p); err != nil {
+			fmt.Fprintf(os.Stderr, "Error removing %s: %v\n", p, err)
+			os.Exit(1)
+		}
+	}
+}
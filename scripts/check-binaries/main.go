@@ -0,0 +1,194 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/google/test-server/internal/binaryinfo"
+	"github.com/google/test-server/internal/githubapi"
+	"github.com/google/test-server/internal/platforms"
+	"github.com/google/test-server/internal/releaserepo"
+)
+
+// --- General Project Configuration ---
+const (
+	projectName = "test-server"
+	modulePath  = "github.com/google/test-server"
+)
+
+// repo is the repository the archives are downloaded from; see
+// releaserepo.EnvVar.
+var repo = releaserepo.Production
+
+// client downloads the release assets of repo.
+var client *githubapi.Client
+
+var localDir = flag.String("dir", "", "Check the archives in this directory (e.g. dist) instead of downloading the release")
+
+// fetchAsset returns the release asset name, read from --dir when set.
+func fetchAsset(version, name string) ([]byte, error) {
+	if *localDir != "" {
+		return os.ReadFile(filepath.Join(*localDir, name))
+	}
+	return client.Download(version, name)
+}
+
+// extractBinary returns the file name from a .tar.gz or .zip archive.
+func extractBinary(archive string, data []byte, name string) ([]byte, error) {
+	if strings.HasSuffix(archive, ".zip") {
+		zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return nil, err
+		}
+		f, err := zr.Open(name)
+		if err != nil {
+			return nil, fmt.Errorf("%s not found in %s", name, archive)
+		}
+		defer f.Close()
+		return io.ReadAll(f)
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("%s not found in %s", name, archive)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag == tar.TypeReg && hdr.Name == name {
+			return io.ReadAll(tr)
+		}
+	}
+}
+
+// result is one row of the printed table.
+type result struct {
+	Archive  string
+	Built    string
+	Header   string
+	Libc     string
+	Problems []string
+}
+
+// checkArchive inspects the binary in the archive of p.
+func checkArchive(version string, matrix *platforms.Matrix, p platforms.Platform) result {
+	archive := matrix.ArchiveName(projectName, p)
+	res := result{Archive: archive, Built: "-", Header: "-", Libc: "-"}
+	data, err := fetchAsset(version, archive)
+	if err != nil {
+		res.Problems = []string{err.Error()}
+		return res
+	}
+	binary, err := extractBinary(archive, data, platforms.BinaryName(projectName, p))
+	if err != nil {
+		res.Problems = []string{err.Error()}
+		return res
+	}
+	info, err := binaryinfo.Inspect(bytes.NewReader(binary))
+	if err != nil {
+		res.Problems = []string{err.Error()}
+		return res
+	}
+	res.Built = info.GOOS + "/" + info.GOARCH
+	res.Header = info.HeaderOS + "/" + info.HeaderArch
+	if info.Libc != "" {
+		res.Libc = info.Libc
+	}
+	res.Problems = binaryinfo.Check(info, p, modulePath)
+	return res
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: go run ./scripts/check-binaries [--dir dist] <version_tag>")
+		fmt.Fprintln(os.Stderr, "Checks that the binary in every release archive was built for the platform of the archive,")
+		fmt.Fprintln(os.Stderr, "and links no libc when the platform is released for several.")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	version := flag.Arg(0)
+	if !strings.HasPrefix(version, "v") {
+		fmt.Fprintln(os.Stderr, "Error: version_tag must start with 'v' (e.g., v0.1.0)")
+		os.Exit(1)
+	}
+	var err error
+	if repo, err = releaserepo.FromEnv(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	client = githubapi.New(repo)
+	if repo.Staging() && *localDir == "" {
+		fmt.Printf("Using the staging release repository %s.\n", repo)
+	}
+	matrix, err := platforms.Load(platforms.File)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	var results []result
+	failed := 0
+	for _, p := range matrix.Platforms {
+		res := checkArchive(version, matrix, p)
+		if len(res.Problems) > 0 {
+			failed++
+		}
+		results = append(results, res)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ARCHIVE\tBUILT FOR\tHEADER\tLIBC\tRESULT")
+	for _, r := range results {
+		status := "ok"
+		if len(r.Problems) > 0 {
+			status = "FAIL"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", r.Archive, r.Built, r.Header, r.Libc, status)
+	}
+	w.Flush()
+
+	if failed > 0 {
+		fmt.Fprintf(os.Stderr, "\nError: %d of %d archives hold a binary that does not match their platform:\n", failed, len(results))
+		for _, r := range results {
+			for _, problem := range r.Problems {
+				fmt.Fprintf(os.Stderr, "  %s: %s\n", r.Archive, problem)
+			}
+		}
+		os.Exit(1)
+	}
+	fmt.Printf("\nAll %d binaries match the platform matrix.\n", len(results))
+}
@@ -0,0 +1,204 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/google/test-server/internal/checksumsjson"
+	"github.com/google/test-server/internal/platforms"
+	"github.com/google/test-server/internal/verify"
+	"github.com/google/test-server/internal/yank"
+)
+
+// checksumsFiles are the per-SDK copies of the release checksums that must
+// stay identical. The first entry is used as the reference in reports.
+var checksumsFiles = []struct {
+	SDK  string
+	Path string
+}{
+	{"TypeScript", "sdks/typescript/checksums.json"},
+	{"Python", "sdks/python/src/test_server_sdk/checksums.json"},
+	{"Dotnet", "sdks/dotnet/checksums.json"},
+}
+
+type checksumsJSON map[string]map[string]string
+
+func load(path string) (checksumsJSON, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	var c checksumsJSON
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return c, nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// compareYanked checks that the installers' list of yanked versions matches
+// yanked.json and that yanked.json itself is valid.
+func compareYanked(f checksumsJSON, yanked yank.List) []string {
+	var problems []string
+	err := yanked.Validate(func(v string) bool {
+		_, ok := f[v]
+		return ok && !checksumsjson.IsReserved(v)
+	})
+	if err != nil {
+		problems = append(problems, fmt.Sprintf("%s: %v", yank.File, err))
+	}
+	want := yanked.Replacements()
+	got := f[yank.ChecksumsKey]
+	for _, v := range sortedKeys(want) {
+		if got[v] != want[v] {
+			problems = append(problems, fmt.Sprintf("%s: %s is yanked in favour of %s in %s but not in %s", yank.ChecksumsKey, v, want[v], yank.File, checksumsFiles[0].Path))
+		}
+	}
+	for _, v := range sortedKeys(got) {
+		if _, ok := want[v]; !ok {
+			problems = append(problems, fmt.Sprintf("%s: %s is yanked in %s but not in %s", yank.ChecksumsKey, v, checksumsFiles[0].Path, yank.File))
+		}
+	}
+	return problems
+}
+
+// compare returns a human readable line for every version, archive or digest
+// that is not the same in all files.
+func compare(files []checksumsJSON) []string {
+	var problems []string
+	versions := map[string]bool{}
+	for _, f := range files {
+		for v := range f {
+			versions[v] = true
+		}
+	}
+	for _, version := range sortedKeys(versions) {
+		archives := map[string]bool{}
+		for i, f := range files {
+			entry, ok := f[version]
+			if !ok {
+				problems = append(problems, fmt.Sprintf("%s: missing from %s", version, checksumsFiles[i].Path))
+				continue
+			}
+			for a := range entry {
+				archives[a] = true
+			}
+		}
+		for _, archive := range sortedKeys(archives) {
+			digests := map[string][]string{}
+			for i, f := range files {
+				entry, ok := f[version]
+				if !ok {
+					continue
+				}
+				digest, ok := entry[archive]
+				if !ok {
+					problems = append(problems, fmt.Sprintf("%s: %s missing from %s", version, archive, checksumsFiles[i].Path))
+					continue
+				}
+				digests[digest] = append(digests[digest], checksumsFiles[i].SDK)
+			}
+			if len(digests) > 1 {
+				what := "digest"
+				switch version {
+				case checksumsjson.ChannelsKey:
+					what = "version"
+				case yank.ChecksumsKey:
+					what = "replacement"
+				case checksumsjson.SchemaKey:
+					what = "value"
+				}
+				var parts []string
+				for _, d := range sortedKeys(digests) {
+					parts = append(parts, fmt.Sprintf("%s=%s", strings.Join(digests[d], ","), d))
+				}
+				problems = append(problems, fmt.Sprintf("%s: %s %s differs: %s", version, archive, what, strings.Join(parts, " ")))
+			}
+		}
+	}
+	return problems
+}
+
+func main() {
+	var files []checksumsJSON
+	for _, f := range checksumsFiles {
+		c, err := load(f.Path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		files = append(files, c)
+	}
+
+	problems := compare(files)
+	yanked, err := yank.Load(yank.File)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	problems = append(problems, compareYanked(files[0], yanked)...)
+	platformsJSON, err := os.ReadFile(platforms.File)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	// The installers read the platform matrix from a copy next to checksums.json.
+	for _, f := range checksumsFiles {
+		copyPath := filepath.Join(filepath.Dir(f.Path), platforms.File)
+		data, err := os.ReadFile(copyPath)
+		if err != nil || !bytes.Equal(data, platformsJSON) {
+			problems = append(problems, fmt.Sprintf("%s: differs from the root %s", copyPath, platforms.File))
+		}
+	}
+	// The installers verify the release signatures with a copy of the key.
+	publicKey, err := os.ReadFile(verify.KeyFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	for _, f := range checksumsFiles {
+		copyPath := filepath.Join(filepath.Dir(f.Path), verify.KeyFile)
+		data, err := os.ReadFile(copyPath)
+		if err != nil || !bytes.Equal(data, publicKey) {
+			problems = append(problems, fmt.Sprintf("%s: differs from the root %s", copyPath, verify.KeyFile))
+		}
+	}
+	if len(problems) > 0 {
+		fmt.Fprintln(os.Stderr, "SDK checksums.json, platforms.json or cosign.pub files are out of sync:")
+		for _, p := range problems {
+			fmt.Fprintf(os.Stderr, "  %s\n", p)
+		}
+		fmt.Fprintln(os.Stderr, "Regenerate them with `go run ./scripts/update-sdk-checksums <version_tag>` or `go run ./scripts/yank` instead of editing by hand.")
+		os.Exit(1)
+	}
+	versions := len(checksumsjson.Document(files[0]).Versions())
+	fmt.Printf("%d checksums.json files agree on %d versions (%d yanked).\n", len(files), versions, len(yanked))
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/google/test-server/internal/installercheck"
+)
+
+var verbose = flag.Bool("v", false, "List every rule, not only the broken ones")
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: go run ./scripts/check-installers [-v]")
+		fmt.Fprintln(os.Stderr, "Statically checks every SDK installer against the contract the release tools assume.")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SDK\tFILE\tSTATUS\tDETAILS")
+	broken := 0
+	for _, f := range installercheck.Files {
+		failed, err := f.Check()
+		if err != nil {
+			fmt.Fprintf(w, "%s\t%s\tFAIL\t%v\n", f.SDK, f.Path, err)
+			broken++
+			continue
+		}
+		status := "PASS"
+		if len(failed) > 0 {
+			status = "FAIL"
+			broken++
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", f.SDK, f.Path, status, strings.Join(failed, "; "))
+		if *verbose {
+			isFailed := make(map[string]bool, len(failed))
+			for _, name := range failed {
+				isFailed[name] = true
+			}
+			for _, r := range f.Rules {
+				mark := "ok"
+				if isFailed[r.Name] {
+					mark = "BROKEN"
+				}
+				fmt.Fprintf(w, "\t\t%s\t%s\n", mark, r.Name)
+			}
+		}
+	}
+	w.Flush()
+	if broken > 0 {
+		fmt.Fprintf(os.Stderr, "Error: %d installer files drifted from the contract; see CONTRIBUTING.md\n", broken)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,177 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/google/test-server/internal/githubapi"
+	"github.com/google/test-server/internal/platforms"
+	"github.com/google/test-server/internal/provenance"
+	"github.com/google/test-server/internal/releaserepo"
+	"github.com/google/test-server/internal/verify"
+)
+
+// --- General Project Configuration ---
+const (
+	projectName = "test-server"
+)
+
+// repo is the repository whose release is checked (see releaserepo.EnvVar).
+var repo = releaserepo.Production
+
+// sbomSubjects are the SBOM subjects written by scripts/sbom.
+var sbomSubjects = []string{projectName, "sdk-typescript", "sdk-python", "sdk-dotnet"}
+
+var (
+	skipSignatures = flag.Bool("skip-signatures", false, "Do not expect cosign .sig files (releases before signing was introduced)")
+	skipSBOMs      = flag.Bool("skip-sboms", false, "Do not expect SBOM files")
+	skipProvenance = flag.Bool("skip-provenance", false, "Do not expect the provenance statement")
+	skipCompat     = flag.Bool("skip-compat", false, "Do not expect the SDK compatibility matrix")
+	skipInstallers = flag.Bool("skip-windows-installers", false, "Do not expect the Windows installers")
+)
+
+// compatAssets are attached by scripts/release from scripts/compat-matrix.
+var compatAssets = []string{"compatibility.json", "compatibility.md"}
+
+// expectedAssets returns every asset name a complete release of version has.
+func expectedAssets(matrix *platforms.Matrix, version string) []string {
+	ver := strings.TrimPrefix(version, "v")
+	signed := append(matrix.ArchiveNames(projectName), fmt.Sprintf("%s_%s_checksums.txt", projectName, ver))
+	assets := append([]string{}, signed...)
+	if !*skipSignatures {
+		for _, a := range signed {
+			assets = append(assets, verify.SignatureName(a))
+		}
+	}
+	if !*skipProvenance {
+		assets = append(assets, provenance.FileName(projectName, version))
+	}
+	if !*skipSBOMs {
+		for _, s := range sbomSubjects {
+			assets = append(assets, fmt.Sprintf("%s_%s.cdx.json", s, ver), fmt.Sprintf("%s_%s.spdx.json", s, ver))
+		}
+	}
+	if !*skipCompat {
+		assets = append(assets, compatAssets...)
+	}
+	if !*skipInstallers {
+		for _, p := range matrix.Filter(func(p platforms.Platform) bool { return p.GOOS == "windows" }) {
+			assets = append(assets, platforms.InstallerName(projectName, version, p))
+		}
+	}
+	sort.Strings(assets)
+	return assets
+}
+
+func releaseAssets(version string) ([]string, error) {
+	c := githubapi.New(repo)
+	rel, err := c.FindRelease(version)
+	if err != nil {
+		return nil, err
+	}
+	assets, err := c.Assets(rel.ID)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(assets))
+	for _, a := range assets {
+		names = append(names, a.Name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// diff returns the names in want but not in got, and those in got but not in want.
+func diff(want, got []string) (missing, unexpected []string) {
+	gotSet := make(map[string]bool, len(got))
+	for _, g := range got {
+		gotSet[g] = true
+	}
+	wantSet := make(map[string]bool, len(want))
+	for _, w := range want {
+		wantSet[w] = true
+		if !gotSet[w] {
+			missing = append(missing, w)
+		}
+	}
+	for _, g := range got {
+		if !wantSet[g] {
+			unexpected = append(unexpected, g)
+		}
+	}
+	return missing, unexpected
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: go run ./scripts/check-release-assets [--skip-signatures] [--skip-sboms] [--skip-provenance] [--skip-compat] [--skip-windows-installers] <version_tag>")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	version := flag.Arg(0)
+	if !strings.HasPrefix(version, "v") {
+		fmt.Fprintln(os.Stderr, "Error: version_tag must start with 'v' (e.g., v0.1.0)")
+		os.Exit(1)
+	}
+	var err error
+	if repo, err = releaserepo.FromEnv(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if repo.Staging() {
+		fmt.Printf("Using the staging release repository %s.\n", repo)
+	}
+
+	matrix, err := platforms.Load(platforms.File)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	got, err := releaseAssets(version)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	want := expectedAssets(matrix, version)
+
+	missing, unexpected := diff(want, got)
+	if len(missing) == 0 && len(unexpected) == 0 {
+		fmt.Printf("Release %s is complete: %d assets, nothing missing or unexpected.\n", version, len(got))
+		return
+	}
+	if len(missing) > 0 {
+		fmt.Fprintf(os.Stderr, "Missing from release %s (%d):\n", version, len(missing))
+		for _, m := range missing {
+			fmt.Fprintf(os.Stderr, "  - %s\n", m)
+		}
+	}
+	if len(unexpected) > 0 {
+		fmt.Fprintf(os.Stderr, "Unexpected on release %s (%d):\n", version, len(unexpected))
+		for _, u := range unexpected {
+			fmt.Fprintf(os.Stderr, "  + %s\n", u)
+		}
+	}
+	os.Exit(1)
+}
@@ -0,0 +1,154 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/test-server/cmd"
+	"github.com/google/test-server/internal/mangen"
+)
+
+// --- General Project Configuration ---
+const (
+	projectName    = "test-server"
+	completionsDir = "completions"
+	manpagesDir    = "manpages"
+)
+
+var outDir = flag.String("out", ".", "Directory to write the completions/ and manpages/ directories to")
+
+// commitDate returns the date stamped into the man pages: SOURCE_DATE_EPOCH
+// when set, otherwise the date of the HEAD commit, so that rebuilding a tag
+// produces the same pages.
+func commitDate() (time.Time, error) {
+	if epoch := os.Getenv("SOURCE_DATE_EPOCH"); epoch != "" {
+		secs, err := strconv.ParseInt(epoch, 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid SOURCE_DATE_EPOCH %q: %w", epoch, err)
+		}
+		return time.Unix(secs, 0), nil
+	}
+	out, err := exec.Command("git", "log", "-1", "--format=%ct").Output()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("git log failed: %w", err)
+	}
+	secs, err := strconv.ParseInt(strings.TrimSpace(string(out)), 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unexpected git log output %q: %w", out, err)
+	}
+	return time.Unix(secs, 0), nil
+}
+
+// completions renders the shell completion scripts, keyed by the file name
+// each shell's completion directory expects.
+func completions() (map[string][]byte, error) {
+	root := cmd.Root()
+	gens := map[string]func(*bytes.Buffer) error{
+		projectName + ".bash": func(b *bytes.Buffer) error { return root.GenBashCompletionV2(b, true) },
+		"_" + projectName:     func(b *bytes.Buffer) error { return root.GenZshCompletion(b) },
+		projectName + ".fish": func(b *bytes.Buffer) error { return root.GenFishCompletion(b, true) },
+		projectName + ".ps1":  func(b *bytes.Buffer) error { return root.GenPowerShellCompletionWithDesc(b) },
+	}
+	files := map[string][]byte{}
+	for name, gen := range gens {
+		var b bytes.Buffer
+		if err := gen(&b); err != nil {
+			return nil, fmt.Errorf("failed to generate %s: %w", name, err)
+		}
+		files[name] = b.Bytes()
+	}
+	return files, nil
+}
+
+// writeDir replaces dir with exactly the given files.
+func writeDir(dir string, files map[string][]byte) error {
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, files[name], 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		fmt.Printf("Wrote %s.\n", path)
+	}
+	return nil
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: go run ./scripts/cli-docs [--out dir] <version_tag>")
+		fmt.Fprintln(os.Stderr, "Generates the bash, zsh, fish and PowerShell completions and the man pages shipped in the release archives.")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	version := flag.Arg(0)
+	if !strings.HasPrefix(version, "v") {
+		fmt.Fprintln(os.Stderr, "Error: version_tag must start with 'v' (e.g., v0.1.0)")
+		os.Exit(1)
+	}
+	date, err := commitDate()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// The binary sets its version in main; the --version flag only shows up
+	// in the pages when it is set.
+	cmd.Root().Version = strings.TrimPrefix(version, "v")
+	comps, err := completions()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	pages := mangen.Pages(cmd.Root(), mangen.Header{
+		Source: fmt.Sprintf("%s %s", projectName, strings.TrimPrefix(version, "v")),
+		Manual: projectName + " Manual",
+		Date:   date,
+	})
+
+	if err := writeDir(filepath.Join(*outDir, completionsDir), comps); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := writeDir(filepath.Join(*outDir, manpagesDir), pages); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("\nGenerated %d completion scripts and %d man pages for %s.\n", len(comps), len(pages), version)
+}
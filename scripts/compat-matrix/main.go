@@ -0,0 +1,234 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/test-server/internal/checksumsjson"
+	"github.com/google/test-server/internal/compat"
+	"github.com/google/test-server/internal/semver"
+)
+
+// --- General Project Configuration ---
+const (
+	checksumsJSONPath = "sdks/typescript/checksums.json"
+)
+
+var (
+	configPath = flag.String("config", "sdks/versioning.json", "SDK versioning config declaring each SDK's release lines")
+	outDir     = flag.String("out", "dist/compat", "Directory to write compatibility.json and compatibility.md to")
+	checkOnly  = flag.Bool("check", false, "Only check that the release breaks no supported SDK line; write nothing")
+)
+
+type sdkEntry struct {
+	Name  string        `json:"name"`
+	Lines []compat.Line `json:"lines"`
+}
+
+type versioningConfig struct {
+	SDKs []sdkEntry `json:"sdks"`
+}
+
+type lineResult struct {
+	SDK string `json:"sdk"`
+	compat.Line
+	Results map[string]compat.Status `json:"results"`
+}
+
+// matrix is the published compatibility artifact.
+type matrix struct {
+	GeneratedAt string       `json:"generated_at"`
+	Release     string       `json:"release"`
+	Protocol    int          `json:"protocol"`
+	Servers     []string     `json:"servers"`
+	Lines       []lineResult `json:"lines"`
+}
+
+// serverVersions returns every released server version plus release, oldest first.
+func serverVersions(release string) ([]semver.Version, error) {
+	all, err := checksumsjson.LoadCurrent(checksumsJSONPath)
+	if err != nil {
+		return nil, err
+	}
+	all[release] = nil
+	var versions []semver.Version
+	for _, tag := range all.Versions() {
+		v, err := semver.Parse(tag)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", checksumsJSONPath, err)
+		}
+		versions = append(versions, v)
+	}
+	sort.Slice(versions, func(i, j int) bool { return semver.Less(versions[i], versions[j]) })
+	return versions, nil
+}
+
+func markdown(m *matrix) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# SDK compatibility with test-server\n\n")
+	fmt.Fprintf(&b, "Generated for %s (protocol %d). `yes`: compatible; `-`: outside the range the SDK line declares; `BROKEN`: declared compatible but the server does not speak the line's protocol.\n\n", m.Release, m.Protocol)
+	b.WriteString("| SDK line | protocol | servers | supported |")
+	for _, s := range m.Servers {
+		fmt.Fprintf(&b, " %s |", s)
+	}
+	b.WriteString("\n|---|---|---|---|")
+	b.WriteString(strings.Repeat("---|", len(m.Servers)))
+	b.WriteString("\n")
+	for _, l := range m.Lines {
+		rng := l.MinServer + " and later"
+		if l.MaxServer != "" {
+			rng = l.MinServer + " to " + l.MaxServer
+		}
+		supported := "no"
+		if l.Supported {
+			supported = "yes"
+		}
+		fmt.Fprintf(&b, "| %s %s.x | %d | %s | %s |", l.SDK, l.Line.Line, l.Protocol, rng, supported)
+		for _, s := range m.Servers {
+			cell := "-"
+			switch l.Results[s] {
+			case compat.Compatible:
+				cell = "yes"
+			case compat.ProtocolUnsupported:
+				cell = "BROKEN"
+			}
+			fmt.Fprintf(&b, " %s |", cell)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: go run ./scripts/compat-matrix [--out dir] [--check] <version_tag>")
+		fmt.Fprintln(os.Stderr, "Writes the SDK/server compatibility matrix and fails if <version_tag> breaks a supported SDK line.")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	release := flag.Arg(0)
+	if !strings.HasPrefix(release, "v") {
+		fmt.Fprintln(os.Stderr, "Error: version_tag must start with 'v' (e.g., v0.1.0)")
+		os.Exit(1)
+	}
+	releaseVersion, err := semver.Parse(release)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	data, err := os.ReadFile(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", *configPath, err)
+		os.Exit(1)
+	}
+	var cfg versioningConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing %s: %v\n", *configPath, err)
+		os.Exit(1)
+	}
+	servers, err := serverVersions(release)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	m := &matrix{
+		GeneratedAt: time.Now().UTC().Format(time.RFC3339),
+		Release:     releaseVersion.Tag(),
+		Protocol:    compat.Protocol,
+	}
+	for _, s := range servers {
+		m.Servers = append(m.Servers, s.Tag())
+	}
+	var broken, undeclared []string
+	for _, sdk := range cfg.SDKs {
+		if len(sdk.Lines) == 0 {
+			fmt.Fprintf(os.Stderr, "Error: %s declares no release lines in %s\n", sdk.Name, *configPath)
+			os.Exit(1)
+		}
+		for _, line := range sdk.Lines {
+			if err := line.Validate(); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %s: %v\n", sdk.Name, err)
+				os.Exit(1)
+			}
+			lr := lineResult{SDK: sdk.Name, Line: line, Results: map[string]compat.Status{}}
+			for _, s := range servers {
+				status, err := compat.Check(compat.ServerProtocols, line, s)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error: %s: %v\n", sdk.Name, err)
+					os.Exit(1)
+				}
+				lr.Results[s.Tag()] = status
+			}
+			if line.Supported {
+				name := fmt.Sprintf("%s %s.x", sdk.Name, line.Line)
+				switch lr.Results[m.Release] {
+				case compat.ProtocolUnsupported:
+					broken = append(broken, fmt.Sprintf("%s (protocol %d)", name, line.Protocol))
+				case compat.OutOfRange:
+					undeclared = append(undeclared, name)
+				}
+			}
+			m.Lines = append(m.Lines, lr)
+		}
+	}
+
+	if !*checkOnly {
+		if err := os.MkdirAll(*outDir, 0755); err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating %s: %v\n", *outDir, err)
+			os.Exit(1)
+		}
+		out, err := json.MarshalIndent(m, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error encoding matrix: %v\n", err)
+			os.Exit(1)
+		}
+		for name, content := range map[string][]byte{
+			"compatibility.json": append(out, '\n'),
+			"compatibility.md":   []byte(markdown(m)),
+		} {
+			path := filepath.Join(*outDir, name)
+			if err := os.WriteFile(path, content, 0644); err != nil {
+				fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", path, err)
+				os.Exit(1)
+			}
+		}
+		fmt.Printf("Wrote the compatibility matrix for %d SDK lines and %d server versions to %s.\n", len(m.Lines), len(m.Servers), *outDir)
+	}
+
+	for _, name := range undeclared {
+		fmt.Printf("Note: %s does not declare support for %s.\n", name, m.Release)
+	}
+	if len(broken) > 0 {
+		fmt.Fprintf(os.Stderr, "Error: %s breaks still-supported SDK lines: %s\n", m.Release, strings.Join(broken, ", "))
+		fmt.Fprintln(os.Stderr, "Keep the protocol in compat.ServerProtocols, cap the lines' max_server, or mark them unsupported in "+*configPath+".")
+		os.Exit(1)
+	}
+	fmt.Printf("%s is compatible with every supported SDK line that declares it.\n", m.Release)
+}
@@ -0,0 +1,208 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/test-server/internal/githubapi"
+	"github.com/google/test-server/internal/platforms"
+	"github.com/google/test-server/internal/releaserepo"
+	"github.com/google/test-server/internal/yank"
+)
+
+// --- General Project Configuration ---
+const (
+	projectName = "test-server"
+	noticesFile = "THIRD_PARTY_NOTICES"
+)
+
+// client downloads the release assets.
+var client *githubapi.Client
+
+//go:embed Dockerfile
+var dockerfile []byte
+
+var (
+	image  = flag.String("image", "ghcr.io/google/test-server", "Image repository to publish to")
+	push   = flag.Bool("push", false, "Push the image after building (otherwise only builds)")
+	latest = flag.Bool("latest", false, "Also tag the image as :latest")
+)
+
+func fetchChecksums(version string) (map[string]string, error) {
+	name := fmt.Sprintf("%s_%s_checksums.txt", projectName, strings.TrimPrefix(version, "v"))
+	fmt.Printf("Downloading %s from %s...\n", name, releaserepo.Production)
+	body, err := client.Download(version, name)
+	if err != nil {
+		return nil, err
+	}
+	checksums := make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(string(body)))
+	for scanner.Scan() {
+		parts := strings.Fields(scanner.Text())
+		if len(parts) == 2 {
+			checksums[parts[1]] = parts[0]
+		}
+	}
+	return checksums, scanner.Err()
+}
+
+// stageBinary downloads archive, verifies it against expected and extracts
+// the test-server binary to dest.
+func stageBinary(version, archive, expected, dest string) error {
+	fmt.Printf("Downloading %s from %s...\n", archive, releaserepo.Production)
+	data, err := client.Download(version, archive)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(data)
+	if actual := hex.EncodeToString(sum[:]); actual != expected {
+		return fmt.Errorf("checksum mismatch for %s: expected %s, got %s", archive, expected, actual)
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return fmt.Errorf("%s not found in %s", projectName, archive)
+		}
+		if err != nil {
+			return err
+		}
+		if hdr.Name != projectName {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return err
+		}
+		out, err := os.OpenFile(dest, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0755)
+		if err != nil {
+			return err
+		}
+		defer out.Close()
+		_, err = io.Copy(out, tr)
+		return err
+	}
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: go run ./scripts/docker-image [--image repo] [--push] [--latest] <version_tag>")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	client = githubapi.New(releaserepo.Production)
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	version := flag.Arg(0)
+	if !strings.HasPrefix(version, "v") {
+		fmt.Fprintln(os.Stderr, "Error: version_tag must start with 'v' (e.g., v0.1.0)")
+		os.Exit(1)
+	}
+	if err := yank.CheckVersion(version); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	matrix, err := platforms.Load(platforms.File)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	checksums, err := fetchChecksums(version)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error fetching checksums: %v\n", err)
+		os.Exit(1)
+	}
+
+	buildContext, err := os.MkdirTemp("", "docker-image-")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating build context: %v\n", err)
+		os.Exit(1)
+	}
+	defer os.RemoveAll(buildContext)
+
+	var imagePlatforms []string
+	for _, p := range matrix.Filter(func(p platforms.Platform) bool { return p.Docker != "" }) {
+		archive := matrix.ArchiveName(projectName, p)
+		expected, ok := checksums[archive]
+		if !ok {
+			fmt.Fprintf(os.Stderr, "Error: checksums file has no entry for %s\n", archive)
+			os.Exit(1)
+		}
+		if err := stageBinary(version, archive, expected, filepath.Join(buildContext, p.Docker, projectName)); err != nil {
+			fmt.Fprintf(os.Stderr, "Error staging %s: %v\n", p.Docker, err)
+			os.Exit(1)
+		}
+		fmt.Printf("Staged verified binary for %s.\n", p.Docker)
+		imagePlatforms = append(imagePlatforms, p.Docker)
+	}
+	notices, err := os.ReadFile(noticesFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", noticesFile, err)
+		os.Exit(1)
+	}
+	if err := os.WriteFile(filepath.Join(buildContext, noticesFile), notices, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", noticesFile, err)
+		os.Exit(1)
+	}
+	if err := os.WriteFile(filepath.Join(buildContext, "Dockerfile"), dockerfile, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing Dockerfile: %v\n", err)
+		os.Exit(1)
+	}
+
+	args := []string{"buildx", "build",
+		"--platform", strings.Join(imagePlatforms, ","),
+		"--build-arg", "VERSION=" + version,
+		"--tag", fmt.Sprintf("%s:%s", *image, version),
+	}
+	if *latest {
+		args = append(args, "--tag", *image+":latest")
+	}
+	if *push {
+		args = append(args, "--push")
+	}
+	args = append(args, buildContext)
+
+	fmt.Printf("+ docker %s\n", strings.Join(args, " "))
+	cmd := exec.Command("docker", args...)
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error building image: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Built %s:%s for %s.\n", *image, version, strings.Join(imagePlatforms, ", "))
+}
@@ -0,0 +1,310 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/test-server/internal/checksumsjson"
+	"github.com/google/test-server/internal/githubapi"
+	"github.com/google/test-server/internal/nightly"
+	"github.com/google/test-server/internal/releaserepo"
+	"github.com/google/test-server/internal/semver"
+)
+
+// --- General Project Configuration ---
+const (
+	mirrorManifestName = "mirror.json"
+	pagesBranch        = "gh-pages"
+)
+
+// repo is the repository cleaned up; see releaserepo.EnvVar.
+var repo = releaserepo.Production
+
+var (
+	maxAgeDays = flag.Int("max-age-days", 30, "Delete pre-releases published more than this many days ago")
+	bucket     = flag.String("bucket", "", "Mirror location to clean up as well, gs://bucket[/prefix] or s3://bucket[/prefix]")
+	dryRun     = flag.Bool("dry-run", false, "Print what would be deleted, but delete nothing")
+)
+
+// protectedVersions returns the versions that must be kept whatever their
+// age, with the reason: everything the SDK checksums.json files pin or point
+// a channel to, and the snapshots still listed in nightly.json.
+func protectedVersions() (map[string]string, error) {
+	protected := make(map[string]string)
+	// A version any SDK copy refers to may still be installed.
+	for _, file := range checksumsjson.Files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		var all map[string]json.RawMessage
+		if err := json.Unmarshal(data, &all); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", file, err)
+		}
+		for key, value := range all {
+			switch {
+			case key == checksumsjson.ChannelsKey:
+				channels := make(map[string]string)
+				if err := json.Unmarshal(value, &channels); err != nil {
+					return nil, fmt.Errorf("failed to parse the channels of %s: %w", file, err)
+				}
+				for name, version := range channels {
+					protected[version] = "channel " + name + " in " + file
+				}
+			case !checksumsjson.IsReserved(key):
+				if _, ok := protected[key]; !ok {
+					protected[key] = "listed in " + file
+				}
+			}
+		}
+	}
+
+	if out, err := exec.Command("git", "fetch", "origin", pagesBranch).CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("git fetch origin %s failed: %v\n%s", pagesBranch, err, out)
+	}
+	data, err := exec.Command("git", "show", "origin/"+pagesBranch+":"+nightly.ManifestName).Output()
+	if err != nil {
+		fmt.Printf("No %s on %s; no snapshot is protected by it.\n", nightly.ManifestName, pagesBranch)
+		data = nil
+	}
+	m, err := nightly.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range m.Snapshots {
+		protected[s.Version] = "listed in " + nightly.ManifestName
+	}
+	return protected, nil
+}
+
+// candidate reports whether tag names a pre-release this tool may delete.
+// Stable releases and tags that are not versions are never touched.
+func candidate(tag string) bool {
+	v, err := semver.Parse(tag)
+	return err == nil && v.Prerelease()
+}
+
+// mirrorVersions lists the version directories under the mirror location.
+func mirrorVersions(location string) ([]string, error) {
+	var cmd *exec.Cmd
+	if strings.HasPrefix(location, "gs://") {
+		cmd = exec.Command("gcloud", "storage", "ls", location+"/")
+	} else {
+		cmd = exec.Command("aws", "s3", "ls", location+"/")
+	}
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("listing %s failed: %w", location, err)
+	}
+	// gcloud prints full URLs ("gs://bucket/prefix/v0.2.9/"), aws relative
+	// prefixes ("PRE v0.2.9/"); objects next to the versions are skipped.
+	var versions []string
+	for _, line := range strings.Split(string(out), "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasSuffix(line, "/") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "PRE ")
+		versions = append(versions, path.Base(strings.TrimSuffix(line, "/")))
+	}
+	return versions, nil
+}
+
+// mirrorDate reads when the mirror of version was generated.
+func mirrorDate(location, version string) (time.Time, error) {
+	remote := location + "/" + version + "/" + mirrorManifestName
+	var cmd *exec.Cmd
+	if strings.HasPrefix(location, "gs://") {
+		cmd = exec.Command("gcloud", "storage", "cat", remote)
+	} else {
+		cmd = exec.Command("aws", "s3", "cp", "--only-show-errors", remote, "-")
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("reading %s failed: %w", remote, err)
+	}
+	var m struct {
+		GeneratedAt time.Time `json:"generated_at"`
+	}
+	if err := json.Unmarshal(out, &m); err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse %s: %w", remote, err)
+	}
+	return m.GeneratedAt, nil
+}
+
+func removeMirror(location, version string) error {
+	remote := location + "/" + version + "/"
+	var cmd *exec.Cmd
+	if strings.HasPrefix(location, "gs://") {
+		cmd = exec.Command("gcloud", "storage", "rm", "--recursive", remote)
+	} else {
+		cmd = exec.Command("aws", "s3", "rm", "--recursive", "--only-show-errors", remote)
+	}
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("removing %s failed: %w", remote, err)
+	}
+	return nil
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: go run ./scripts/gc-releases [--max-age-days 30] [--bucket gs://bucket/prefix] [--dry-run]")
+		fmt.Fprintln(os.Stderr, "Deletes nightly and release candidate releases, and their mirror copies, older than --max-age-days.")
+		fmt.Fprintln(os.Stderr, "Stable releases and versions referenced by an SDK checksums.json or nightly.json are kept.")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 0 || *maxAgeDays < 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	var err error
+	if repo, err = releaserepo.FromEnv(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if repo.Staging() {
+		fmt.Printf("Using the staging release repository %s.\n", repo)
+	}
+	location := strings.TrimSuffix(*bucket, "/")
+	if location != "" && !strings.HasPrefix(location, "gs://") && !strings.HasPrefix(location, "s3://") {
+		fmt.Fprintf(os.Stderr, "Error: unsupported mirror location %q; use gs://... or s3://...\n", location)
+		os.Exit(1)
+	}
+
+	protected, err := protectedVersions()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	c := githubapi.New(repo)
+	if c.Token == "" && !*dryRun {
+		fmt.Fprintln(os.Stderr, "Error: deleting releases needs a token in GITHUB_TOKEN or GH_TOKEN, or a gh login")
+		os.Exit(1)
+	}
+	releases, err := c.Releases()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing the releases: %v\n", err)
+		os.Exit(1)
+	}
+	cutoff := time.Now().Add(-time.Duration(*maxAgeDays) * 24 * time.Hour)
+
+	// published maps every release to its date, so that mirror copies of
+	// releases that are kept are kept too.
+	published := make(map[string]time.Time)
+	var expired []githubapi.Release
+	for _, r := range releases {
+		date := r.PublishedAt
+		if date.IsZero() {
+			date = r.CreatedAt
+		}
+		published[r.TagName] = date
+		if !candidate(r.TagName) || !date.Before(cutoff) {
+			continue
+		}
+		if reason, ok := protected[r.TagName]; ok {
+			fmt.Printf("Keeping %s (%s).\n", r.TagName, reason)
+			continue
+		}
+		expired = append(expired, r)
+	}
+	sort.Slice(expired, func(i, j int) bool { return expired[i].TagName < expired[j].TagName })
+
+	deleted := 0
+	for _, r := range expired {
+		// Nightly tags only mark a build; release candidate tags stay as the
+		// record of what was tested before a release.
+		deleteTag := nightly.IsSnapshot(r.TagName)
+		action := "release"
+		if deleteTag {
+			action = "release and tag"
+		}
+		fmt.Printf("Deleting the %s %s (%d assets, published %s)...\n", action, r.TagName, len(r.Assets), published[r.TagName].Format("2006-01-02"))
+		if *dryRun {
+			continue
+		}
+		if err := c.DeleteRelease(r.ID); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if deleteTag {
+			if err := c.DeleteTag(r.TagName); err != nil && !githubapi.IsNotFound(err) {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		deleted++
+	}
+
+	removed := 0
+	if location != "" {
+		versions, err := mirrorVersions(location)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		for _, version := range versions {
+			if !candidate(version) {
+				continue
+			}
+			if reason, ok := protected[version]; ok {
+				fmt.Printf("Keeping the mirror of %s (%s).\n", version, reason)
+				continue
+			}
+			// The release date decides, unless the release is gone.
+			date, ok := published[version]
+			if !ok {
+				if date, err = mirrorDate(location, version); err != nil {
+					fmt.Printf("Warning: keeping the mirror of %s: %v\n", version, err)
+					continue
+				}
+			}
+			if !date.Before(cutoff) {
+				continue
+			}
+			fmt.Printf("Removing the mirror of %s...\n", version)
+			if *dryRun {
+				continue
+			}
+			if err := removeMirror(location, version); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			removed++
+		}
+	}
+
+	if *dryRun {
+		fmt.Println("Dry run; nothing was deleted.")
+		return
+	}
+	fmt.Printf("Deleted %d releases", deleted)
+	if location != "" {
+		fmt.Printf(" and %d mirror copies", removed)
+	}
+	fmt.Println(".")
+}
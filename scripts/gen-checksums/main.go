@@ -0,0 +1,134 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/google/test-server/internal/platforms"
+)
+
+// --- General Project Configuration ---
+const (
+	projectName = "test-server"
+)
+
+var (
+	dir     = flag.String("dir", "dist", "Directory holding the locally built release archives")
+	outPath = flag.String("out", "", "Checksums file to write (default: <dir>/test-server_<version>_checksums.txt)")
+	partial = flag.Bool("partial", false, "Skip platforms whose archive was not built instead of failing")
+)
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// format renders checksums the way goreleaser writes the released checksums
+// file: "<sha256>  <archive>" lines sorted by archive name.
+func format(checksums map[string]string) string {
+	names := make([]string, 0, len(checksums))
+	for name := range checksums {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&b, "%s  %s\n", checksums[name], name)
+	}
+	return b.String()
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: go run ./scripts/gen-checksums [--dir dist] [--out file] [--partial] <version_tag>")
+		fmt.Fprintln(os.Stderr, "Hashes locally built release archives into a checksums file in the release format, e.g. for")
+		fmt.Fprintln(os.Stderr, "update-sdk-checksums --checksums-file or verify-release --dir.")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	version := flag.Arg(0)
+	if !strings.HasPrefix(version, "v") {
+		fmt.Fprintln(os.Stderr, "Error: version_tag must start with 'v' (e.g., v0.1.0)")
+		os.Exit(1)
+	}
+	out := *outPath
+	if out == "" {
+		out = filepath.Join(*dir, fmt.Sprintf("%s_%s_checksums.txt", projectName, strings.TrimPrefix(version, "v")))
+	}
+
+	matrix, err := platforms.Load(platforms.File)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	checksums := make(map[string]string)
+	var missing []string
+	for _, archive := range matrix.ArchiveNames(projectName) {
+		sum, err := fileSHA256(filepath.Join(*dir, archive))
+		if os.IsNotExist(err) {
+			missing = append(missing, archive)
+			continue
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error hashing %s: %v\n", archive, err)
+			os.Exit(1)
+		}
+		checksums[archive] = sum
+	}
+	if len(missing) > 0 && !*partial {
+		fmt.Fprintf(os.Stderr, "Error: %d archives are missing from %s:\n", len(missing), *dir)
+		for _, m := range missing {
+			fmt.Fprintf(os.Stderr, "  %s\n", m)
+		}
+		fmt.Fprintln(os.Stderr, "Build every platform (e.g. goreleaser release --snapshot --clean) or pass --partial.")
+		os.Exit(1)
+	}
+	if len(checksums) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: no release archives in %s\n", *dir)
+		os.Exit(1)
+	}
+
+	if err := os.WriteFile(out, []byte(format(checksums)), 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", out, err)
+		os.Exit(1)
+	}
+	fmt.Printf("Wrote %s (%d archives", out, len(checksums))
+	if len(missing) > 0 {
+		fmt.Printf(", %d skipped", len(missing))
+	}
+	fmt.Println(").")
+}
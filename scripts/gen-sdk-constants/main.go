@@ -0,0 +1,73 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/google/test-server/internal/sdkconstants"
+)
+
+var check = flag.Bool("check", false, "Fail if a generated file is out of date, without writing anything")
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: go run ./scripts/gen-sdk-constants [--check]")
+		fmt.Fprintf(os.Stderr, "Generates the constants module of every SDK from %s.\n", sdkconstants.File)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	c, err := sdkconstants.Load(sdkconstants.File)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *check {
+		outdated, err := sdkconstants.Outdated(c)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		for _, t := range outdated {
+			fmt.Fprintf(os.Stderr, "%s (%s SDK) does not match %s.\n", t.Path, t.SDK, sdkconstants.File)
+		}
+		if len(outdated) > 0 {
+			fmt.Fprintln(os.Stderr, "Error: the SDK constants are out of date; run go run ./scripts/gen-sdk-constants")
+			os.Exit(1)
+		}
+		fmt.Printf("The SDK constants match %s (test-server %s).\n", sdkconstants.File, c.TestServerVersion)
+		return
+	}
+
+	written, err := sdkconstants.Write(c)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	for _, path := range written {
+		fmt.Printf("Wrote %s.\n", path)
+	}
+	if len(written) == 0 {
+		fmt.Println("The SDK constants are up to date.")
+	}
+}
@@ -0,0 +1,575 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/google/test-server/internal/checksumsjson"
+	"github.com/google/test-server/internal/fixture"
+	"github.com/google/test-server/internal/githubapi"
+	"github.com/google/test-server/internal/platforms"
+	"github.com/google/test-server/internal/releaserepo"
+	"github.com/google/test-server/internal/sdkconstants"
+)
+
+// --- General Project Configuration ---
+const (
+	projectName = "test-server"
+
+	// workDir is where the SDK copy is mounted in the containers.
+	workDir = "/work"
+)
+
+// repo is the repository the installers download from; see
+// releaserepo.EnvVar.
+var repo = releaserepo.Production
+
+// client downloads the release assets of repo.
+var client *githubapi.Client
+
+var (
+	sdkList      = flag.String("sdk", "typescript,python,dotnet", "Comma-separated SDK installers to run")
+	platformList = flag.String("platform", "all", `Platforms to test: "host", "all" or a comma-separated list of goos/goarch`)
+	mirror       = flag.String("mirror", "", "Mirror the installers fall back to, passed as TEST_SERVER_MIRROR")
+	outDir       = flag.String("out", "dist/installer-e2e", "Directory for the per-run logs and report.json")
+	dryRun       = flag.Bool("dry-run", false, "Print the runs instead of starting them")
+)
+
+// installer describes how to run one SDK's real installer in a copy of the
+// SDK directory, and where it leaves the binary.
+type installer struct {
+	name string
+	dir  string // SDK directory copied into the run
+	// steps are the commands run in the copy, in order; "{out}" is replaced
+	// by the directory the binary is installed to, where the SDK takes one.
+	steps [][]string
+	// binary is the installed binary relative to the copy.
+	binary string
+	// images are the container images per libc ("glibc", "musl") and for
+	// Windows containers ("windows"). A missing image means the combination
+	// only runs natively.
+	images map[string]string
+	// published reports whether the SDK is released for p at all.
+	published func(p platforms.Platform) bool
+}
+
+var installers = []installer{
+	{
+		name: "typescript",
+		dir:  "sdks/typescript",
+		steps: [][]string{
+			// The installer is run explicitly so that a failure is not hidden
+			// by npm's handling of lifecycle scripts.
+			{"npm", "install", "--no-audit", "--no-fund", "--ignore-scripts"},
+			{"node", "postinstall.js"},
+		},
+		binary: "bin/" + projectName,
+		images: map[string]string{
+			"glibc": "node:20-bookworm-slim",
+			"musl":  "node:20-alpine",
+		},
+		published: func(p platforms.Platform) bool { return p.Node != "" },
+	},
+	{
+		name: "python",
+		dir:  "sdks/python",
+		steps: [][]string{
+			{"{python}", "-m", "pip", "install", "--quiet", "--target", "{out}/deps", "requests"},
+			{"{python}", "src/test_server_sdk/install.py"},
+		},
+		binary: "src/test_server_sdk/bin/" + projectName,
+		images: map[string]string{
+			"glibc":   "python:3.12-slim-bookworm",
+			"musl":    "python:3.12-alpine",
+			"windows": "python:3.12-windowsservercore-ltsc2022",
+		},
+		published: func(p platforms.Platform) bool { return len(p.Python) > 0 },
+	},
+	{
+		name: "dotnet",
+		dir:  "sdks/dotnet",
+		steps: [][]string{
+			{"dotnet", "run", "--project", "tools/installer", "--", "{out}/bin"},
+		},
+		binary: "out/bin/" + projectName,
+		images: map[string]string{
+			"glibc":   "mcr.microsoft.com/dotnet/sdk:8.0",
+			"musl":    "mcr.microsoft.com/dotnet/sdk:8.0-alpine",
+			"windows": "mcr.microsoft.com/dotnet/sdk:8.0-windowsservercore-ltsc2022",
+		},
+		published: func(p platforms.Platform) bool { return p.Dotnet != "" },
+	},
+}
+
+// skipDirs are never copied into a run: they hold the results of earlier
+// installs and builds.
+var skipDirs = map[string]bool{"node_modules": true, "bin": true, "obj": true, "out": true, "__pycache__": true, "dist": true}
+
+// run is one installer on one platform and libc.
+type run struct {
+	Installer string  `json:"installer"`
+	Platform  string  `json:"platform"`
+	Libc      string  `json:"libc,omitempty"`
+	Runner    string  `json:"runner"` // "docker", "docker-windows" or "native"
+	Image     string  `json:"image,omitempty"`
+	Status    string  `json:"status"`
+	Reason    string  `json:"reason,omitempty"`
+	Seconds   float64 `json:"seconds"`
+	LogFile   string  `json:"log_file,omitempty"`
+
+	inst     installer
+	platform platforms.Platform
+}
+
+type report struct {
+	Version string `json:"version"`
+	Repo    string `json:"repo"`
+	Runs    []run  `json:"runs"`
+}
+
+// expectedBinary returns the SHA-256 of the binary in the release archive of
+// p, after checking the archive against checksums.json like the installers do.
+func expectedBinary(checksums checksumsjson.Document, matrix *platforms.Matrix, version string, p platforms.Platform) (string, error) {
+	archive := matrix.ArchiveName(projectName, p)
+	digest, ok := checksums[version][archive]
+	if !ok {
+		return "", fmt.Errorf("%s has no digest for %s in %s", version, archive, checksumsjson.Files[0])
+	}
+	data, err := client.Download(version, archive)
+	if err != nil {
+		return "", err
+	}
+	dir, err := os.MkdirTemp("", "installer-e2e-expected-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+	pin := fixture.Pin{Archive: archive, SHA256: digest, Binary: platforms.BinaryName(projectName, p)}
+	if err := fixture.Store(dir, pin, data); err != nil {
+		return "", err
+	}
+	return fileSHA256(filepath.Join(dir, digest, pin.Binary))
+}
+
+func fileSHA256(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// dockerOSType returns the OS the Docker daemon runs containers for, or ""
+// when Docker is not available.
+func dockerOSType() string {
+	out, err := exec.Command("docker", "info", "--format", "{{.OSType}}").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// plan returns every run for the selected installers and platforms, with the
+// ones this host cannot perform already skipped.
+func plan(selected []installer, targets []platforms.Platform, dockerOS string) []run {
+	var runs []run
+	for _, inst := range selected {
+		for _, p := range targets {
+			libcs := p.Libc
+			if len(libcs) == 0 {
+				libcs = []string{""}
+			}
+			for _, libc := range libcs {
+				r := run{Installer: inst.name, Platform: fixture.Platform(p.GOOS, p.GOARCH), Libc: libc, inst: inst, platform: p}
+				native := p.GOOS == runtime.GOOS && p.GOARCH == runtime.GOARCH && (libc == "" || libc == hostLibc())
+				switch {
+				case !inst.published(p):
+					r.Status, r.Reason = "SKIP", "not published for this platform"
+				case p.GOOS == "linux" && dockerOS == "linux" && inst.images[libc] != "":
+					r.Runner, r.Image = "docker", inst.images[libc]
+				case native:
+					r.Runner = "native"
+				case p.GOOS == "windows" && p.GOARCH == "amd64" && dockerOS == "windows" && inst.images["windows"] != "":
+					r.Runner, r.Image = "docker-windows", inst.images["windows"]
+				default:
+					r.Status, r.Reason = "SKIP", "needs a "+r.Platform+" runner"
+					if p.GOOS == "linux" {
+						r.Reason = "needs Docker with Linux containers"
+					}
+				}
+				runs = append(runs, r)
+			}
+		}
+	}
+	return runs
+}
+
+// hostLibc returns the libc of this Linux host.
+func hostLibc() string {
+	if runtime.GOOS != "linux" {
+		return ""
+	}
+	if matches, _ := filepath.Glob("/lib/ld-musl-*"); len(matches) > 0 {
+		return "musl"
+	}
+	return "glibc"
+}
+
+// copySDK copies dir to dst without the results of earlier installs.
+func copySDK(dir, dst string) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if d.IsDir() {
+			if skipDirs[d.Name()] && rel != "." {
+				return filepath.SkipDir
+			}
+			return os.MkdirAll(target, 0755)
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(target, data, info.Mode().Perm())
+	})
+}
+
+// commands returns the steps of r with their placeholders filled in for root,
+// the SDK copy as the run sees it.
+func commands(r run, root string) [][]string {
+	python := "python3"
+	if r.platform.GOOS == "windows" {
+		python = "python"
+	}
+	var out [][]string
+	for _, step := range r.inst.steps {
+		args := make([]string, len(step))
+		for i, a := range step {
+			a = strings.ReplaceAll(a, "{python}", python)
+			a = strings.ReplaceAll(a, "{out}", root+"/out")
+			args[i] = a
+		}
+		out = append(out, args)
+	}
+	return out
+}
+
+// installEnv is the environment of the installers.
+func installEnv(r run, root string) []string {
+	env := []string{
+		// Always set, so that the TypeScript installer downloads the release
+		// instead of using its platform package.
+		releaserepo.EnvVar + "=" + repo.String(),
+	}
+	if *mirror != "" {
+		env = append(env, "TEST_SERVER_MIRROR="+*mirror)
+	}
+	if r.Installer == "python" {
+		env = append(env, "PYTHONPATH="+root+"/out/deps")
+	}
+	return env
+}
+
+// execute performs r in a fresh copy of the SDK and checks the installed
+// binary against want.
+func execute(r run, version, want string) run {
+	r.LogFile = filepath.Join(*outDir, fmt.Sprintf("%s-%s-%s", r.Installer, r.platform.GOOS, r.platform.GOARCH))
+	if r.Libc != "" {
+		r.LogFile += "-" + r.Libc
+	}
+	r.LogFile += ".log"
+	logFile, err := os.Create(r.LogFile)
+	if err != nil {
+		r.Status, r.Reason = "FAIL", err.Error()
+		return r
+	}
+	defer logFile.Close()
+	out := io.MultiWriter(os.Stdout, logFile)
+
+	start := time.Now()
+	defer func() { r.Seconds = time.Since(start).Round(time.Millisecond).Seconds() }()
+
+	copyDir, err := os.MkdirTemp("", "installer-e2e-"+r.Installer+"-")
+	if err != nil {
+		r.Status, r.Reason = "FAIL", err.Error()
+		return r
+	}
+	defer os.RemoveAll(copyDir)
+	if err := copySDK(r.inst.dir, copyDir); err != nil {
+		r.Status, r.Reason = "FAIL", fmt.Sprintf("copying %s: %v", r.inst.dir, err)
+		return r
+	}
+
+	binaryName := platforms.BinaryName(projectName, r.platform)
+	binary := strings.TrimSuffix(r.inst.binary, projectName) + binaryName
+	var cmd *exec.Cmd
+	switch r.Runner {
+	case "native":
+		root := filepath.ToSlash(copyDir)
+		env := append(os.Environ(), installEnv(r, root)...)
+		for _, args := range commands(r, root) {
+			fmt.Fprintf(out, "==> [%s %s] %s\n", r.Installer, r.Platform, strings.Join(args, " "))
+			c := exec.Command(args[0], args[1:]...)
+			c.Dir, c.Env, c.Stdout, c.Stderr = copyDir, env, out, out
+			if err := c.Run(); err != nil {
+				r.Status, r.Reason = "FAIL", fmt.Sprintf("%s: %v", strings.Join(args, " "), err)
+				return r
+			}
+		}
+		cmd = exec.Command(filepath.Join(copyDir, filepath.FromSlash(binary)), "--version")
+	default:
+		root, shell := workDir, []string{"sh", "-c"}
+		if r.Runner == "docker-windows" {
+			root, shell = "C:"+workDir, []string{"cmd", "/S", "/C"}
+		}
+		var script []string
+		for _, args := range commands(r, root) {
+			script = append(script, strings.Join(args, " "))
+		}
+		// The binary has to run on the target, so it is started in the
+		// container too.
+		script = append(script, root+"/"+binary+" --version")
+		args := []string{"run", "--rm", "-v", copyDir + ":" + root, "-w", root}
+		if r.Runner == "docker" {
+			args = append(args, "--platform", r.Platform, "-e", "HOME=/tmp", "-e", "DOTNET_CLI_HOME=/tmp", "-e", "npm_config_cache=/tmp/.npm")
+			if runtime.GOOS == "linux" {
+				// Files written to the mount stay removable by this user.
+				args = append(args, "--user", fmt.Sprintf("%d:%d", os.Getuid(), os.Getgid()))
+			}
+		}
+		for _, e := range installEnv(r, root) {
+			args = append(args, "-e", e)
+		}
+		args = append(args, r.Image)
+		args = append(args, shell...)
+		args = append(args, strings.Join(script, " && "))
+		cmd = exec.Command("docker", args...)
+	}
+	fmt.Fprintf(out, "==> [%s %s] %s\n", r.Installer, r.Platform, strings.Join(cmd.Args, " "))
+	cmd.Stdout, cmd.Stderr = out, out
+	if err := cmd.Run(); err != nil {
+		r.Status, r.Reason = "FAIL", fmt.Sprintf("installer or binary failed: %v", err)
+		return r
+	}
+
+	installed := filepath.Join(copyDir, filepath.FromSlash(binary))
+	info, err := os.Stat(installed)
+	if err != nil {
+		r.Status, r.Reason = "FAIL", fmt.Sprintf("no binary at %s", binary)
+		return r
+	}
+	if r.platform.GOOS != "windows" && info.Mode().Perm()&0111 == 0 {
+		r.Status, r.Reason = "FAIL", fmt.Sprintf("%s is not executable (%s)", binary, info.Mode().Perm())
+		return r
+	}
+	got, err := fileSHA256(installed)
+	if err != nil {
+		r.Status, r.Reason = "FAIL", err.Error()
+		return r
+	}
+	if got != want {
+		r.Status, r.Reason = "FAIL", fmt.Sprintf("installed binary has SHA-256 %s, the %s release %s", got, version, want)
+		return r
+	}
+	r.Status = "PASS"
+	return r
+}
+
+// selectPlatforms resolves --platform against the matrix.
+func selectPlatforms(matrix *platforms.Matrix) ([]platforms.Platform, error) {
+	switch *platformList {
+	case "all":
+		return matrix.Platforms, nil
+	case "host":
+		p, ok := matrix.Find(runtime.GOOS, runtime.GOARCH)
+		if !ok {
+			return nil, fmt.Errorf("%s/%s is not a released platform", runtime.GOOS, runtime.GOARCH)
+		}
+		return []platforms.Platform{p}, nil
+	}
+	var out []platforms.Platform
+	for _, name := range strings.Split(*platformList, ",") {
+		goos, goarch, _ := strings.Cut(strings.TrimSpace(name), "/")
+		p, ok := matrix.Find(goos, goarch)
+		if !ok {
+			return nil, fmt.Errorf("%q is not a released platform", name)
+		}
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: go run ./scripts/installer-e2e [--sdk typescript,python,dotnet] [--platform all|host|goos/goarch,...] [--mirror url] [--out dir] [--dry-run]")
+		fmt.Fprintln(os.Stderr, "Runs every SDK's real installer per platform, in containers where possible, and checks the installed binary.")
+		fmt.Fprintf(os.Stderr, "The installers download the version pinned in %s from %s, or the repository in %s.\n", sdkconstants.File, releaserepo.Production, releaserepo.EnvVar)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	var err error
+	if repo, err = releaserepo.FromEnv(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	client = githubapi.New(repo)
+	if repo.Staging() {
+		fmt.Printf("Using the staging release repository %s.\n", repo)
+	}
+
+	var selected []installer
+	wanted := map[string]bool{}
+	for _, name := range strings.Split(*sdkList, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			wanted[name] = true
+		}
+	}
+	for _, inst := range installers {
+		if wanted[inst.name] {
+			selected = append(selected, inst)
+			delete(wanted, inst.name)
+		}
+	}
+	for name := range wanted {
+		fmt.Fprintf(os.Stderr, "Error: unknown SDK %q (known: typescript, python, dotnet)\n", name)
+		os.Exit(1)
+	}
+
+	constants, err := sdkconstants.Load(sdkconstants.File)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	version := constants.TestServerVersion
+	matrix, err := platforms.Load(platforms.File)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	targets, err := selectPlatforms(matrix)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	checksums, err := checksumsjson.Load(checksumsjson.Files[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	runs := plan(selected, targets, dockerOSType())
+	if *dryRun {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "INSTALLER\tPLATFORM\tLIBC\tRUNNER\tDETAILS")
+		for _, r := range runs {
+			details := r.Image
+			if r.Status == "SKIP" {
+				r.Runner, details = "-", r.Reason
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", r.Installer, r.Platform, r.Libc, r.Runner, details)
+		}
+		w.Flush()
+		fmt.Printf("Dry run; would install %s from %s.\n", version, repo)
+		return
+	}
+	if err := os.MkdirAll(*outDir, 0755); err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating %s: %v\n", *outDir, err)
+		os.Exit(1)
+	}
+
+	// The binary every installer must end up with, per platform.
+	expected := make(map[string]string)
+	for i, r := range runs {
+		if r.Status == "SKIP" {
+			continue
+		}
+		want, ok := expected[r.Platform]
+		if !ok {
+			if want, err = expectedBinary(checksums, matrix, version, r.platform); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			expected[r.Platform] = want
+		}
+		runs[i] = execute(r, version, want)
+	}
+
+	rep := report{Version: version, Repo: repo.String(), Runs: runs}
+	data, err := json.MarshalIndent(rep, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error encoding report: %v\n", err)
+		os.Exit(1)
+	}
+	reportPath := filepath.Join(*outDir, "report.json")
+	if err := os.WriteFile(reportPath, append(data, '\n'), 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", reportPath, err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("\nInstaller results for %s from %s:\n", version, repo)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "INSTALLER\tPLATFORM\tLIBC\tRUNNER\tSTATUS\tTIME\tDETAILS")
+	failed := false
+	for _, r := range runs {
+		details := r.Reason
+		if details == "" {
+			details = r.LogFile
+		}
+		runner := r.Runner
+		if runner == "" {
+			runner = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%.1fs\t%s\n", r.Installer, r.Platform, r.Libc, runner, r.Status, r.Seconds, details)
+		failed = failed || r.Status == "FAIL"
+	}
+	w.Flush()
+	fmt.Printf("Report written to %s.\n", reportPath)
+	if failed {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,618 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/test-server/internal/githubapi"
+	"github.com/google/test-server/internal/platforms"
+	"github.com/google/test-server/internal/releaserepo"
+	"github.com/google/test-server/internal/yank"
+)
+
+// --- General Project Configuration ---
+const (
+	githubOwner = "google"
+	githubRepo  = "test-server"
+	projectName = "test-server"
+
+	maintainer   = "Google LLC <googleapis-packages@google.com>"
+	description  = "HTTP record/replay server for hermetic SDK tests"
+	aptSuite     = "stable"
+	aptComponent = "main"
+	noticesFile  = "THIRD_PARTY_NOTICES"
+	docDir       = "/usr/share/doc/" + projectName
+	manDir       = "/usr/share/man/man1"
+)
+
+// client downloads the release assets.
+var client *githubapi.Client
+
+var (
+	outDir = flag.String("out", "dist/linux-repo", "Local copy of the package repository")
+	bucket = flag.String("bucket", "", "GCS bucket (gs://...) to sync the repository from and publish it to")
+	gpgKey = flag.String("gpg-key", "", "GPG key ID used to sign the APT Release file and the RPM repodata")
+	noRPM  = flag.Bool("skip-rpm", false, "Only build .deb packages (rpmbuild and createrepo_c are not required)")
+)
+
+func fetchChecksums(version string) (map[string]string, error) {
+	name := fmt.Sprintf("%s_%s_checksums.txt", projectName, strings.TrimPrefix(version, "v"))
+	fmt.Printf("Downloading %s from %s...\n", name, releaserepo.Production)
+	body, err := client.Download(version, name)
+	if err != nil {
+		return nil, err
+	}
+	checksums := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		parts := strings.Fields(scanner.Text())
+		if len(parts) == 2 {
+			checksums[parts[1]] = parts[0]
+		}
+	}
+	return checksums, scanner.Err()
+}
+
+// installPaths maps the completion scripts and man pages in the release
+// archives to where the packages install them. Man pages are gzipped on
+// install.
+var installPaths = map[string]string{
+	"completions/" + projectName + ".bash": "/usr/share/bash-completion/completions/" + projectName,
+	"completions/_" + projectName:          "/usr/share/zsh/vendor-completions/_" + projectName,
+	"completions/" + projectName + ".fish": "/usr/share/fish/vendor_completions.d/" + projectName + ".fish",
+}
+
+// installPath returns where the archive member name is installed, if at all.
+func installPath(name string) (string, bool) {
+	if path, ok := installPaths[name]; ok {
+		return path, true
+	}
+	if page, ok := strings.CutPrefix(name, "manpages/"); ok && strings.HasSuffix(page, ".1") {
+		return manDir + "/" + page + ".gz", true
+	}
+	return "", false
+}
+
+// fetchArchive downloads archive, verifies it against expected and returns the
+// test-server binary it contains, along with its completion scripts and man
+// pages named by their install path. Archives of older releases have neither.
+func fetchArchive(version, archive, expected string) ([]byte, []tarEntry, error) {
+	fmt.Printf("Downloading %s from %s...\n", archive, releaserepo.Production)
+	data, err := client.Download(version, archive)
+	if err != nil {
+		return nil, nil, err
+	}
+	sum := sha256.Sum256(data)
+	if actual := hex.EncodeToString(sum[:]); actual != expected {
+		return nil, nil, fmt.Errorf("checksum mismatch for %s: expected %s, got %s", archive, expected, actual)
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+	var binary []byte
+	var extras []tarEntry
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if hdr.Name == projectName {
+			if binary, err = io.ReadAll(tr); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+		path, ok := installPath(hdr.Name)
+		if !ok {
+			continue
+		}
+		content, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, nil, err
+		}
+		if strings.HasSuffix(path, ".gz") {
+			if content, err = gzipN(content); err != nil {
+				return nil, nil, err
+			}
+		}
+		extras = append(extras, tarEntry{Name: path, Mode: 0644, Data: content})
+	}
+	if binary == nil {
+		return nil, nil, fmt.Errorf("%s not found in %s", projectName, archive)
+	}
+	sort.Slice(extras, func(i, j int) bool { return extras[i].Name < extras[j].Name })
+	return binary, extras, nil
+}
+
+// gzipN compresses data like gzip -9n, without a name or timestamp, as
+// Debian policy asks for man pages.
+func gzipN(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	gz, err := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := gz.Write(data); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+type tarEntry struct {
+	Name string
+	Mode int64
+	Data []byte
+}
+
+func tarGz(entries []tarEntry, mtime time.Time) ([]byte, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.Name, Mode: e.Mode, Size: int64(len(e.Data)), ModTime: mtime, Typeflag: tar.TypeReg}
+		if strings.HasSuffix(e.Name, "/") {
+			hdr.Typeflag, hdr.Size = tar.TypeDir, 0
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return nil, err
+		}
+		if _, err := tw.Write(e.Data); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeAr writes a Debian-flavoured ar archive with the given members, in order.
+func writeAr(w io.Writer, members []tarEntry, mtime time.Time) error {
+	if _, err := io.WriteString(w, "!<arch>\n"); err != nil {
+		return err
+	}
+	for _, m := range members {
+		hdr := fmt.Sprintf("%-16s%-12d%-6d%-6d%-8o%-10d`\n", m.Name, mtime.Unix(), 0, 0, m.Mode, len(m.Data))
+		if _, err := io.WriteString(w, hdr); err != nil {
+			return err
+		}
+		if _, err := w.Write(m.Data); err != nil {
+			return err
+		}
+		if len(m.Data)%2 == 1 {
+			if _, err := w.Write([]byte{'\n'}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func debControl(pkgVersion, arch string, installedSize int) string {
+	return fmt.Sprintf(`Package: %s
+Version: %s
+Architecture: %s
+Maintainer: %s
+Installed-Size: %d
+Section: devel
+Priority: optional
+Homepage: https://github.com/%s/%s
+Description: %s
+`, projectName, pkgVersion, arch, maintainer, installedSize, githubOwner, githubRepo, description)
+}
+
+// parentDirs returns the directory entries the install paths of extras need
+// beyond the ones every package has, parents first.
+func parentDirs(extras []tarEntry) []tarEntry {
+	seen := map[string]bool{"/usr": true, "/usr/bin": true, "/usr/share": true, "/usr/share/doc": true, docDir: true}
+	var dirs []tarEntry
+	for _, e := range extras {
+		var missing []string
+		for dir := filepath.Dir(e.Name); !seen[dir]; dir = filepath.Dir(dir) {
+			seen[dir] = true
+			missing = append([]string{dir}, missing...)
+		}
+		for _, dir := range missing {
+			dirs = append(dirs, tarEntry{Name: "." + dir + "/", Mode: 0755})
+		}
+	}
+	return dirs
+}
+
+func buildDeb(path, pkgVersion, arch string, binary, notices []byte, extras []tarEntry, mtime time.Time) error {
+	installedSize := len(binary) + len(notices)
+	for _, e := range extras {
+		installedSize += len(e.Data)
+	}
+	control, err := tarGz([]tarEntry{
+		{Name: "./control", Mode: 0644, Data: []byte(debControl(pkgVersion, arch, (installedSize+1023)/1024))},
+	}, mtime)
+	if err != nil {
+		return err
+	}
+	entries := []tarEntry{
+		{Name: "./usr/", Mode: 0755},
+		{Name: "./usr/bin/", Mode: 0755},
+		{Name: "./usr/bin/" + projectName, Mode: 0755, Data: binary},
+		{Name: "./usr/share/", Mode: 0755},
+		{Name: "./usr/share/doc/", Mode: 0755},
+		{Name: "." + docDir + "/", Mode: 0755},
+		{Name: "." + docDir + "/" + noticesFile, Mode: 0644, Data: notices},
+	}
+	entries = append(entries, parentDirs(extras)...)
+	for _, e := range extras {
+		entries = append(entries, tarEntry{Name: "." + e.Name, Mode: e.Mode, Data: e.Data})
+	}
+	payload, err := tarGz(entries, mtime)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return writeAr(f, []tarEntry{
+		{Name: "debian-binary", Mode: 0644, Data: []byte("2.0\n")},
+		{Name: "control.tar.gz", Mode: 0644, Data: control},
+		{Name: "data.tar.gz", Mode: 0644, Data: payload},
+	}, mtime)
+}
+
+// readDebControl extracts the control file from an existing .deb so that
+// packages from earlier releases stay listed in the regenerated index.
+func readDebControl(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	if !bytes.HasPrefix(data, []byte("!<arch>\n")) {
+		return "", fmt.Errorf("%s is not an ar archive", path)
+	}
+	rest := data[8:]
+	for len(rest) >= 60 {
+		name := strings.TrimSpace(string(rest[0:16]))
+		size, err := strconv.Atoi(strings.TrimSpace(string(rest[48:58])))
+		if err != nil || 60+size > len(rest) {
+			return "", fmt.Errorf("%s has a malformed ar header", path)
+		}
+		member := rest[60 : 60+size]
+		rest = rest[min(60+size+size%2, len(rest)):]
+		if name != "control.tar.gz" {
+			continue
+		}
+		gz, err := gzip.NewReader(bytes.NewReader(member))
+		if err != nil {
+			return "", err
+		}
+		tr := tar.NewReader(gz)
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return "", err
+			}
+			if strings.TrimPrefix(hdr.Name, "./") == "control" {
+				control, err := io.ReadAll(tr)
+				return string(control), err
+			}
+		}
+	}
+	return "", fmt.Errorf("no control file in %s", path)
+}
+
+// writeAptIndexes regenerates Packages, Packages.gz and Release for every
+// .deb under the pool, then signs Release when a GPG key is configured.
+func writeAptIndexes(root string) error {
+	byArch := map[string][]string{}
+	err := filepath.Walk(filepath.Join(root, "pool"), func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || !strings.HasSuffix(path, ".deb") {
+			return err
+		}
+		control, err := readDebControl(path)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(root, path)
+		md5sum := md5.Sum(data)
+		sha := sha256.Sum256(data)
+		stanza := strings.TrimRight(control, "\n") + fmt.Sprintf("\nFilename: %s\nSize: %d\nMD5sum: %s\nSHA256: %s\n",
+			filepath.ToSlash(rel), len(data), hex.EncodeToString(md5sum[:]), hex.EncodeToString(sha[:]))
+		for _, line := range strings.Split(control, "\n") {
+			if arch, ok := strings.CutPrefix(line, "Architecture: "); ok {
+				byArch[arch] = append(byArch[arch], stanza)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	dist := filepath.Join(root, "dists", aptSuite)
+	var archs, indexFiles []string
+	for arch, stanzas := range byArch {
+		archs = append(archs, arch)
+		sort.Strings(stanzas)
+		packages := []byte(strings.Join(stanzas, "\n"))
+		dir := filepath.Join(dist, aptComponent, "binary-"+arch)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(dir, "Packages"), packages, 0644); err != nil {
+			return err
+		}
+		var gzBuf bytes.Buffer
+		gz := gzip.NewWriter(&gzBuf)
+		gz.Write(packages)
+		gz.Close()
+		if err := os.WriteFile(filepath.Join(dir, "Packages.gz"), gzBuf.Bytes(), 0644); err != nil {
+			return err
+		}
+		prefix := fmt.Sprintf("%s/binary-%s/", aptComponent, arch)
+		indexFiles = append(indexFiles, prefix+"Packages", prefix+"Packages.gz")
+	}
+	sort.Strings(archs)
+	sort.Strings(indexFiles)
+
+	var md5Lines, shaLines strings.Builder
+	for _, name := range indexFiles {
+		data, err := os.ReadFile(filepath.Join(dist, name))
+		if err != nil {
+			return err
+		}
+		m := md5.Sum(data)
+		s := sha256.Sum256(data)
+		fmt.Fprintf(&md5Lines, " %s %d %s\n", hex.EncodeToString(m[:]), len(data), name)
+		fmt.Fprintf(&shaLines, " %s %d %s\n", hex.EncodeToString(s[:]), len(data), name)
+	}
+	release := fmt.Sprintf("Origin: %s\nLabel: %s\nSuite: %s\nCodename: %s\nDate: %s\nArchitectures: %s\nComponents: %s\nDescription: %s\nMD5Sum:\n%sSHA256:\n%s",
+		projectName, projectName, aptSuite, aptSuite, time.Now().UTC().Format(time.RFC1123), strings.Join(archs, " "), aptComponent, description, md5Lines.String(), shaLines.String())
+	releasePath := filepath.Join(dist, "Release")
+	if err := os.WriteFile(releasePath, []byte(release), 0644); err != nil {
+		return err
+	}
+
+	if *gpgKey == "" {
+		return nil
+	}
+	if err := run("gpg", "--batch", "--yes", "--local-user", *gpgKey, "--armor", "--detach-sign", "--output", releasePath+".gpg", releasePath); err != nil {
+		return err
+	}
+	return run("gpg", "--batch", "--yes", "--local-user", *gpgKey, "--clearsign", "--output", filepath.Join(dist, "InRelease"), releasePath)
+}
+
+const rpmSpec = `Name: %s
+Version: %s
+Release: 1
+Summary: %s
+License: Apache-2.0
+URL: https://github.com/%s/%s
+BuildArch: %s
+AutoReqProv: no
+
+%%description
+%s.
+
+%%install
+mkdir -p %%{buildroot}/usr/bin %%{buildroot}%s
+install -m 0755 %s %%{buildroot}/usr/bin/%s
+install -m 0644 %s %%{buildroot}%s/%s
+%s
+%%files
+/usr/bin/%s
+%s/%s
+%s`
+
+func buildRPM(outDir, pkgVersion, arch string, binary, notices []byte, extras []tarEntry) error {
+	work, err := os.MkdirTemp("", "rpmbuild-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(work)
+	binPath := filepath.Join(work, projectName)
+	if err := os.WriteFile(binPath, binary, 0755); err != nil {
+		return err
+	}
+	noticesPath := filepath.Join(work, noticesFile)
+	if err := os.WriteFile(noticesPath, notices, 0644); err != nil {
+		return err
+	}
+	var extraInstall, extraFiles strings.Builder
+	for i, e := range extras {
+		src := filepath.Join(work, fmt.Sprintf("extra-%d", i))
+		if err := os.WriteFile(src, e.Data, 0644); err != nil {
+			return err
+		}
+		fmt.Fprintf(&extraInstall, "install -D -m 0644 %s %%{buildroot}%s\n", src, e.Name)
+		fmt.Fprintf(&extraFiles, "%s\n", e.Name)
+	}
+	specPath := filepath.Join(work, projectName+".spec")
+	spec := fmt.Sprintf(rpmSpec, projectName, pkgVersion, description, githubOwner, githubRepo, arch, description,
+		docDir, binPath, projectName, noticesPath, docDir, noticesFile, extraInstall.String(), projectName, docDir, noticesFile, extraFiles.String())
+	if err := os.WriteFile(specPath, []byte(spec), 0644); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return err
+	}
+	return run("rpmbuild", "-bb", "--target", arch,
+		"--define", "_topdir "+filepath.Join(work, "build"),
+		"--define", "_rpmdir "+outDir,
+		"--define", "_build_name_fmt %%{NAME}-%%{VERSION}-%%{RELEASE}.%%{ARCH}.rpm",
+		specPath)
+}
+
+func writeRPMIndexes(root string) error {
+	if err := run("createrepo_c", "--update", root); err != nil {
+		return err
+	}
+	if *gpgKey == "" {
+		return nil
+	}
+	repomd := filepath.Join(root, "repodata", "repomd.xml")
+	return run("gpg", "--batch", "--yes", "--local-user", *gpgKey, "--armor", "--detach-sign", "--output", repomd+".asc", repomd)
+}
+
+func run(name string, args ...string) error {
+	fmt.Printf("+ %s %s\n", name, strings.Join(args, " "))
+	cmd := exec.Command(name, args...)
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s failed: %w", name, err)
+	}
+	return nil
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: go run ./scripts/linux-packages [--out dir] [--bucket gs://...] [--gpg-key id] [--skip-rpm] <version_tag>")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	client = githubapi.New(releaserepo.Production)
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	version := flag.Arg(0)
+	if !strings.HasPrefix(version, "v") {
+		fmt.Fprintln(os.Stderr, "Error: version_tag must start with 'v' (e.g., v0.1.0)")
+		os.Exit(1)
+	}
+	if err := yank.CheckVersion(version); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	pkgVersion := strings.TrimPrefix(version, "v")
+	aptRoot := filepath.Join(*outDir, "apt")
+	rpmRoot := filepath.Join(*outDir, "rpm")
+
+	// Start from the published repository so earlier versions stay in the indexes.
+	if *bucket != "" {
+		if err := os.MkdirAll(*outDir, 0755); err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating %s: %v\n", *outDir, err)
+			os.Exit(1)
+		}
+		if err := run("gcloud", "storage", "rsync", "--recursive", *bucket, *outDir); err != nil {
+			fmt.Fprintf(os.Stderr, "Error syncing %s: %v\n", *bucket, err)
+			os.Exit(1)
+		}
+	}
+
+	matrix, err := platforms.Load(platforms.File)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	checksums, err := fetchChecksums(version)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error fetching checksums: %v\n", err)
+		os.Exit(1)
+	}
+	notices, err := os.ReadFile(noticesFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", noticesFile, err)
+		os.Exit(1)
+	}
+	mtime := time.Now().UTC()
+	for _, a := range matrix.Filter(func(p platforms.Platform) bool { return p.Deb != "" }) {
+		archive := matrix.ArchiveName(projectName, a)
+		expected, ok := checksums[archive]
+		if !ok {
+			fmt.Fprintf(os.Stderr, "Error: checksums file has no entry for %s\n", archive)
+			os.Exit(1)
+		}
+		binary, extras, err := fetchArchive(version, archive, expected)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		debPath := filepath.Join(aptRoot, "pool", aptComponent, projectName[:1], projectName, fmt.Sprintf("%s_%s_%s.deb", projectName, pkgVersion, a.Deb))
+		if err := buildDeb(debPath, pkgVersion, a.Deb, binary, notices, extras, mtime); err != nil {
+			fmt.Fprintf(os.Stderr, "Error building %s: %v\n", debPath, err)
+			os.Exit(1)
+		}
+		fmt.Printf("Built %s.\n", debPath)
+
+		if !*noRPM && a.RPM != "" {
+			if err := buildRPM(filepath.Join(rpmRoot, a.RPM), pkgVersion, a.RPM, binary, notices, extras); err != nil {
+				fmt.Fprintf(os.Stderr, "Error building rpm for %s: %v\n", a.RPM, err)
+				os.Exit(1)
+			}
+		}
+	}
+
+	if err := writeAptIndexes(aptRoot); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing APT metadata: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Regenerated APT metadata in %s.\n", aptRoot)
+	if !*noRPM {
+		if err := writeRPMIndexes(rpmRoot); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing RPM metadata: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Regenerated RPM metadata in %s.\n", rpmRoot)
+	}
+
+	if *bucket != "" {
+		if err := run("gcloud", "storage", "rsync", "--recursive", *outDir, *bucket); err != nil {
+			fmt.Fprintf(os.Stderr, "Error publishing to %s: %v\n", *bucket, err)
+			os.Exit(1)
+		}
+		fmt.Printf("Published repository to %s.\n", *bucket)
+	}
+}
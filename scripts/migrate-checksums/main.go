@@ -0,0 +1,174 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/test-server/internal/checksumsjson"
+)
+
+var (
+	to     = flag.Int("to", checksumsjson.Current, "Schema version to convert to")
+	check  = flag.Bool("check", false, "Fail if a file is not at the --to schema, without writing anything")
+	dryRun = flag.Bool("dry-run", false, "Print the diffs without writing any file")
+)
+
+// migrated is one file before and after the migration.
+type migrated struct {
+	Path     string
+	From     int
+	Old, New []byte
+	Applied  []string
+}
+
+// diff returns a unified diff of m, or "" when nothing changed.
+func diff(m migrated) (string, error) {
+	if bytes.Equal(m.Old, m.New) {
+		return "", nil
+	}
+	dir, err := os.MkdirTemp("", "migrate-checksums-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+	if err := os.WriteFile(filepath.Join(dir, "old"), m.Old, 0644); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "new"), m.New, 0644); err != nil {
+		return "", err
+	}
+	// git diff exits 1 when the files differ.
+	cmd := exec.Command("git", "diff", "--no-index", "--no-color", "old", "new")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil && len(out) == 0 {
+		return "", fmt.Errorf("git diff failed: %w", err)
+	}
+	// Label the hunks with the real file instead of the temporary copies.
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- a/%s\n+++ b/%s\n", m.Path, m.Path)
+	text := string(out)
+	if i := strings.Index(text, "\n@@"); i >= 0 {
+		text = text[i+1:]
+	}
+	b.WriteString(text)
+	return b.String(), nil
+}
+
+func migrate(path string) (migrated, error) {
+	m := migrated{Path: path}
+	var err error
+	if m.Old, err = os.ReadFile(path); err != nil {
+		return m, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	d, err := checksumsjson.Parse(m.Old)
+	if err != nil {
+		return m, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if m.From, err = d.Schema(); err != nil {
+		return m, fmt.Errorf("%s: %w", path, err)
+	}
+	if m.Applied, err = checksumsjson.Migrate(d, *to); err != nil {
+		return m, fmt.Errorf("%s: %w", path, err)
+	}
+	if problems := d.Validate(); len(problems) > 0 {
+		return m, fmt.Errorf("%s is invalid after the migration:\n  %s", path, strings.Join(problems, "\n  "))
+	}
+	if m.New, err = d.Marshal(); err != nil {
+		return m, fmt.Errorf("failed to encode %s: %w", path, err)
+	}
+	return m, nil
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: go run ./scripts/migrate-checksums [--to version] [--check | --dry-run]")
+		fmt.Fprintln(os.Stderr, "Converts every SDK checksums.json to a schema version in one pass, validating the result and printing the diff.")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	// Every file is migrated and validated before any is written, so that
+	// the copies never end up on different schemas.
+	var files []migrated
+	for _, path := range checksumsjson.Files {
+		m, err := migrate(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		files = append(files, m)
+	}
+	for _, m := range files[1:] {
+		if !bytes.Equal(m.New, files[0].New) {
+			fmt.Fprintf(os.Stderr, "Error: %s and %s differ; run go run ./scripts/check-consistency and fix them first\n", files[0].Path, m.Path)
+			os.Exit(1)
+		}
+	}
+
+	changed := 0
+	for _, m := range files {
+		d, err := diff(m)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if d == "" {
+			fmt.Printf("%s is already at schema %d.\n", m.Path, *to)
+			continue
+		}
+		changed++
+		fmt.Printf("%s: schema %d -> %d\n", m.Path, m.From, *to)
+		for _, a := range m.Applied {
+			fmt.Printf("  %s\n", a)
+		}
+		if !*check {
+			fmt.Print(d)
+		}
+	}
+	if changed == 0 {
+		return
+	}
+	if *check {
+		fmt.Fprintf(os.Stderr, "Error: %d checksums.json files are not at schema %d; run go run ./scripts/migrate-checksums\n", changed, *to)
+		os.Exit(1)
+	}
+	if *dryRun {
+		fmt.Println("Dry run; nothing was written.")
+		return
+	}
+	for _, m := range files {
+		if bytes.Equal(m.Old, m.New) {
+			continue
+		}
+		if err := os.WriteFile(m.Path, m.New, 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", m.Path, err)
+			os.Exit(1)
+		}
+	}
+	fmt.Printf("Migrated %d files to schema %d.\n", changed, *to)
+}
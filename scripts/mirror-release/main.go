@@ -0,0 +1,376 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/test-server/internal/checksumsjson"
+	"github.com/google/test-server/internal/githubapi"
+	"github.com/google/test-server/internal/releaserepo"
+	"github.com/google/test-server/internal/verify"
+	"github.com/google/test-server/internal/yank"
+)
+
+// --- General Project Configuration ---
+const (
+	projectName = "test-server"
+
+	checksumsJSONPath = "sdks/typescript/checksums.json"
+	manifestName      = "mirror.json"
+	schemaVersion     = 1
+)
+
+// repo is the repository mirrored from, a scratch one in staging rehearsals.
+var repo = releaserepo.Production
+
+var (
+	bucket    = flag.String("bucket", "", "Mirror location, gs://bucket[/prefix] or s3://bucket[/prefix] (required)")
+	publicURL = flag.String("public-url", "", "HTTPS URL the bucket location is served at (default: derived from --bucket)")
+	cosignKey = flag.String("cosign-key", "", "Cosign public key used to verify the signature of the checksums file")
+	outDir    = flag.String("out", "dist/mirror", "Directory the assets and the manifest are downloaded to")
+	dryRun    = flag.Bool("dry-run", false, "Download and verify the assets and write the manifest, but upload nothing")
+)
+
+type ghAsset struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+type mirrorAsset struct {
+	Name   string `json:"name"`
+	URL    string `json:"url"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// mirrorManifest is the mirror.json written next to the mirrored assets. The
+// SDK installers read it when TEST_SERVER_MIRROR is set and GitHub fails.
+type mirrorManifest struct {
+	SchemaVersion int           `json:"schema_version"`
+	Version       string        `json:"version"`
+	GeneratedAt   string        `json:"generated_at"`
+	Source        string        `json:"source"`
+	Assets        []mirrorAsset `json:"assets"`
+}
+
+func releaseAssets(version string) ([]ghAsset, error) {
+	c := githubapi.New(repo)
+	rel, err := c.FindRelease(version)
+	if err != nil {
+		return nil, err
+	}
+	assets, err := c.Assets(rel.ID)
+	if err != nil {
+		return nil, err
+	}
+	var out []ghAsset
+	for _, a := range assets {
+		out = append(out, ghAsset{Name: a.Name, URL: a.BrowserDownloadURL})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, nil
+}
+
+// download saves url to dest and returns its size and SHA-256.
+func download(url, dest string) (int64, string, error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, "", fmt.Errorf("failed to download %s: status %s", url, resp.Status)
+	}
+	f, err := os.Create(dest)
+	if err != nil {
+		return 0, "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(f, h), resp.Body)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	return n, hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func parseChecksums(data []byte) map[string]string {
+	checksums := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		parts := strings.Fields(scanner.Text())
+		if len(parts) == 2 {
+			checksums[parts[1]] = parts[0]
+		}
+	}
+	return checksums
+}
+
+// checkSDKChecksums compares the release checksums with the ones pinned in
+// the repository, when the version is there already.
+func checkSDKChecksums(version string, checksums map[string]string) error {
+	all, err := checksumsjson.LoadCurrent(checksumsJSONPath)
+	if err != nil {
+		return err
+	}
+	pinned, ok := all.Digests(version)
+	if !ok {
+		fmt.Printf("Note: %s is not in %s yet; trusting the release checksums file.\n", version, checksumsJSONPath)
+		return nil
+	}
+	for archive, digest := range pinned {
+		if checksums[archive] != digest {
+			return fmt.Errorf("%s: the release checksums file does not match %s", archive, checksumsJSONPath)
+		}
+	}
+	return nil
+}
+
+// defaultPublicURL returns the public HTTPS endpoint of a gs:// or s3:// location.
+func defaultPublicURL(location string) (string, error) {
+	switch {
+	case strings.HasPrefix(location, "gs://"):
+		return "https://storage.googleapis.com/" + strings.TrimPrefix(location, "gs://"), nil
+	case strings.HasPrefix(location, "s3://"):
+		b, prefix, _ := strings.Cut(strings.TrimPrefix(location, "s3://"), "/")
+		u := "https://" + b + ".s3.amazonaws.com"
+		if prefix != "" {
+			u += "/" + prefix
+		}
+		return u, nil
+	}
+	return "", fmt.Errorf("unsupported mirror location %q; use gs://... or s3://...", location)
+}
+
+// upload copies a local file to the remote object. cacheControl is optional.
+func upload(local, remote, cacheControl string) error {
+	var cmd *exec.Cmd
+	if strings.HasPrefix(remote, "gs://") {
+		args := []string{"storage", "cp"}
+		if cacheControl != "" {
+			args = append(args, "--cache-control="+cacheControl)
+		}
+		cmd = exec.Command("gcloud", append(args, local, remote)...)
+	} else {
+		args := []string{"s3", "cp", "--only-show-errors"}
+		if cacheControl != "" {
+			args = append(args, "--cache-control", cacheControl)
+		}
+		cmd = exec.Command("aws", append(args, local, remote)...)
+	}
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("upload to %s failed: %w", remote, err)
+	}
+	return nil
+}
+
+// remoteSHA256 reads the remote object back and hashes it.
+func remoteSHA256(remote string) (string, error) {
+	var cmd *exec.Cmd
+	if strings.HasPrefix(remote, "gs://") {
+		cmd = exec.Command("gcloud", "storage", "cat", remote)
+	} else {
+		cmd = exec.Command("aws", "s3", "cp", "--only-show-errors", remote, "-")
+	}
+	h := sha256.New()
+	cmd.Stdout, cmd.Stderr = h, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("reading back %s failed: %w", remote, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: go run ./scripts/mirror-release --bucket gs://bucket/prefix [--public-url url] [--cosign-key cosign.pub] [--dry-run] <version_tag>")
+		fmt.Fprintln(os.Stderr, "Copies every asset of a release to a GCS or S3 mirror and writes the mirror manifest.")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 || *bucket == "" {
+		flag.Usage()
+		os.Exit(1)
+	}
+	version := flag.Arg(0)
+	if !strings.HasPrefix(version, "v") {
+		fmt.Fprintln(os.Stderr, "Error: version_tag must start with 'v' (e.g., v0.1.0)")
+		os.Exit(1)
+	}
+	var err error
+	if repo, err = releaserepo.FromEnv(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if repo.Staging() {
+		fmt.Printf("Using the staging release repository %s.\n", repo)
+	}
+	if err := yank.CheckVersion(version); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	location := strings.TrimSuffix(*bucket, "/")
+	base := strings.TrimSuffix(*publicURL, "/")
+	if base == "" {
+		var err error
+		if base, err = defaultPublicURL(location); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	} else if _, err := defaultPublicURL(location); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	assets, err := releaseAssets(version)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	dir := filepath.Join(*outDir, version)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating %s: %v\n", dir, err)
+		os.Exit(1)
+	}
+
+	m := &mirrorManifest{
+		SchemaVersion: schemaVersion,
+		Version:       version,
+		GeneratedAt:   time.Now().UTC().Format(time.RFC3339),
+		Source:        repo.ReleaseURL(version),
+	}
+	for _, a := range assets {
+		if a.Name == manifestName {
+			fmt.Fprintf(os.Stderr, "Error: the release has an asset named %s, which the mirror manifest would overwrite\n", manifestName)
+			os.Exit(1)
+		}
+		fmt.Printf("Downloading %s...\n", a.Name)
+		size, digest, err := download(a.URL, filepath.Join(dir, a.Name))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		m.Assets = append(m.Assets, mirrorAsset{Name: a.Name, URL: base + "/" + version + "/" + a.Name, Size: size, SHA256: digest})
+	}
+
+	// The archives must match the release checksums file, and that file the
+	// checksums pinned in the repository and, with --cosign-key, its signature.
+	checksumsFileName := fmt.Sprintf("%s_%s_checksums.txt", projectName, strings.TrimPrefix(version, "v"))
+	checksumsText, err := os.ReadFile(filepath.Join(dir, checksumsFileName))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: the release has no %s: %v\n", checksumsFileName, err)
+		os.Exit(1)
+	}
+	if *cosignKey != "" {
+		keyPEM, err := os.ReadFile(*cosignKey)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", *cosignKey, err)
+			os.Exit(1)
+		}
+		pub, err := verify.ParsePublicKey(keyPEM)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		sig, err := os.ReadFile(filepath.Join(dir, verify.SignatureName(checksumsFileName)))
+		if err == nil {
+			err = verify.Blob(pub, bytes.NewReader(checksumsText), sig)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error verifying the signature of %s: %v\n", checksumsFileName, err)
+			os.Exit(1)
+		}
+		fmt.Println("Checksums signature verified.")
+	}
+	checksums := parseChecksums(checksumsText)
+	if err := checkSDKChecksums(version, checksums); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	digests := make(map[string]string, len(m.Assets))
+	for _, a := range m.Assets {
+		digests[a.Name] = a.SHA256
+	}
+	for archive, want := range checksums {
+		got, ok := digests[archive]
+		if !ok {
+			fmt.Fprintf(os.Stderr, "Error: %s is listed in %s but not attached to the release\n", archive, checksumsFileName)
+			os.Exit(1)
+		}
+		if got != want {
+			fmt.Fprintf(os.Stderr, "Error: checksum mismatch for %s: expected %s, got %s\n", archive, want, got)
+			os.Exit(1)
+		}
+	}
+	fmt.Printf("Verified %d archives against %s.\n", len(checksums), checksumsFileName)
+
+	out, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error encoding manifest: %v\n", err)
+		os.Exit(1)
+	}
+	manifestPath := filepath.Join(dir, manifestName)
+	if err := os.WriteFile(manifestPath, append(out, '\n'), 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", manifestPath, err)
+		os.Exit(1)
+	}
+	fmt.Printf("Wrote %s.\n", manifestPath)
+	if *dryRun {
+		fmt.Printf("Dry run: nothing uploaded to %s.\n", location)
+		return
+	}
+
+	// Assets first, each read back and compared; the manifest last, so that
+	// installers never see a manifest pointing at missing or partial objects.
+	for _, a := range m.Assets {
+		remote := location + "/" + version + "/" + a.Name
+		if err := upload(filepath.Join(dir, a.Name), remote, ""); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		got, err := remoteSHA256(remote)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if got != a.SHA256 {
+			fmt.Fprintf(os.Stderr, "Error: %s does not match the release after upload: expected %s, got %s\n", remote, a.SHA256, got)
+			os.Exit(1)
+		}
+		fmt.Printf("Mirrored %s.\n", a.Name)
+	}
+	if err := upload(manifestPath, location+"/"+version+"/"+manifestName, "no-cache"); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("\nMirrored %d assets of %s to %s.\n", len(m.Assets), version, location)
+	fmt.Printf("Installers fall back to it with TEST_SERVER_MIRROR=%s\n", base)
+}
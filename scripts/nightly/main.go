@@ -0,0 +1,378 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/test-server/internal/buildflags"
+	"github.com/google/test-server/internal/checksumsjson"
+	"github.com/google/test-server/internal/nightly"
+	"github.com/google/test-server/internal/platforms"
+	"github.com/google/test-server/internal/releaserepo"
+	"github.com/google/test-server/internal/semver"
+)
+
+// --- General Project Configuration ---
+const (
+	projectName       = "test-server"
+	checksumsJSONPath = "sdks/typescript/checksums.json"
+)
+
+// archiveFiles are packed next to the binary, as in the goreleaser archives.
+var archiveFiles = []string{"LICENSE", "README.md", "THIRD_PARTY_NOTICES"}
+
+var (
+	outDir        = flag.String("out", "dist/nightly", "Directory to write the archives, the checksums file and nightly.json to")
+	publish       = flag.Bool("publish", false, "Create the GitHub pre-release, delete expired snapshots and push nightly.json to gh-pages")
+	retentionDays = flag.Int("retention-days", 14, "Delete snapshots older than this many days")
+	keep          = flag.Int("keep", 3, "Always keep this many of the newest snapshots, however old")
+	pagesBranch   = flag.String("branch", "gh-pages", "Branch published by GitHub Pages")
+)
+
+// repo is where the snapshots are published; see releaserepo.EnvVar.
+var repo = releaserepo.Production
+
+func run(dir, name string, args ...string) error {
+	fmt.Printf("+ %s %s\n", name, strings.Join(args, " "))
+	cmd := exec.Command(name, args...)
+	cmd.Dir = dir
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s failed: %w", name, err)
+	}
+	return nil
+}
+
+func output(name string, args ...string) (string, error) {
+	out, err := exec.Command(name, args...).Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+// latestStable returns the newest stable release the SDKs know about.
+func latestStable() (semver.Version, error) {
+	all, err := checksumsjson.LoadCurrent(checksumsJSONPath)
+	if err != nil {
+		return semver.Version{}, err
+	}
+	var latest *semver.Version
+	for _, tag := range all.Versions() {
+		v, err := semver.Parse(tag)
+		if err != nil {
+			return semver.Version{}, fmt.Errorf("%s: %w", checksumsJSONPath, err)
+		}
+		if !v.Prerelease() && (latest == nil || semver.Less(*latest, v)) {
+			latest = &v
+		}
+	}
+	if latest == nil {
+		return semver.Version{}, fmt.Errorf("no stable release in %s", checksumsJSONPath)
+	}
+	return *latest, nil
+}
+
+// loadManifest reads the published nightly.json from the Pages branch, or the
+// local copy when not publishing. Both may not exist yet.
+func loadManifest() (*nightly.Manifest, error) {
+	if !*publish {
+		data, err := os.ReadFile(filepath.Join(*outDir, nightly.ManifestName))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		return nightly.Parse(data)
+	}
+	if err := run(".", "git", "fetch", "origin", *pagesBranch); err != nil {
+		return nil, err
+	}
+	data, err := exec.Command("git", "show", "origin/"+*pagesBranch+":"+nightly.ManifestName).Output()
+	if err != nil {
+		fmt.Printf("No %s on %s yet; starting a new one.\n", nightly.ManifestName, *pagesBranch)
+		data = nil
+	}
+	return nightly.Parse(data)
+}
+
+// archive packs the binary and archiveFiles into the archive format of name.
+func archive(name, binaryName string, binary []byte, mtime time.Time) ([]byte, error) {
+	files := map[string][]byte{binaryName: binary}
+	for _, f := range archiveFiles {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		files[f] = data
+	}
+	names := make([]string, 0, len(files))
+	for n := range files {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	if strings.HasSuffix(name, ".zip") {
+		zw := zip.NewWriter(&buf)
+		for _, n := range names {
+			hdr := &zip.FileHeader{Name: n, Method: zip.Deflate, Modified: mtime}
+			hdr.SetMode(0644)
+			if n == binaryName {
+				hdr.SetMode(0755)
+			}
+			w, err := zw.CreateHeader(hdr)
+			if err != nil {
+				return nil, err
+			}
+			if _, err := w.Write(files[n]); err != nil {
+				return nil, err
+			}
+		}
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, n := range names {
+		mode := int64(0644)
+		if n == binaryName {
+			mode = 0755
+		}
+		if err := tw.WriteHeader(&tar.Header{Name: n, Mode: mode, Size: int64(len(files[n])), ModTime: mtime, Typeflag: tar.TypeReg}); err != nil {
+			return nil, err
+		}
+		if _, err := tw.Write(files[n]); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// build cross-compiles every platform with the release flags and writes the
+// archives and the checksums file to dir. It returns the uploaded files.
+func build(version, dir string, matrix *platforms.Matrix, mtime time.Time) ([]string, []nightly.Asset, error) {
+	var files []string
+	var assets []nightly.Asset
+	var lines []string
+	for _, p := range matrix.Platforms {
+		fmt.Printf("Building %s/%s...\n", p.GOOS, p.GOARCH)
+		binaryName := platforms.BinaryName(projectName, p)
+		binPath := filepath.Join(dir, p.GOOS+"_"+p.GOARCH, binaryName)
+		cmd := exec.Command("go", buildflags.Args(version, binPath)...)
+		cmd.Env = append(os.Environ(), buildflags.Env(p.GOOS, p.GOARCH)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return nil, nil, fmt.Errorf("go build for %s/%s failed: %v\n%s", p.GOOS, p.GOARCH, err, out)
+		}
+		binary, err := os.ReadFile(binPath)
+		if err != nil {
+			return nil, nil, err
+		}
+		name := matrix.ArchiveName(projectName, p)
+		data, err := archive(name, binaryName, binary, mtime)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to pack %s: %w", name, err)
+		}
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			return nil, nil, err
+		}
+		sum := sha256.Sum256(data)
+		digest := hex.EncodeToString(sum[:])
+		lines = append(lines, fmt.Sprintf("%s  %s\n", digest, name))
+		files = append(files, path)
+		assets = append(assets, nightly.Asset{OS: p.GOOS, Arch: p.GOARCH, Archive: name, URL: repo.DownloadURL(version, name), SHA256: digest})
+	}
+	checksumsPath := filepath.Join(dir, fmt.Sprintf("%s_%s_checksums.txt", projectName, strings.TrimPrefix(version, "v")))
+	// Sorted by archive name, like the checksums file of a release.
+	sort.Slice(lines, func(i, j int) bool { return strings.Fields(lines[i])[1] < strings.Fields(lines[j])[1] })
+	if err := os.WriteFile(checksumsPath, []byte(strings.Join(lines, "")), 0644); err != nil {
+		return nil, nil, err
+	}
+	return append(files, checksumsPath), assets, nil
+}
+
+// publishRelease replaces the GitHub pre-release of version, so that a second
+// build on the same day wins.
+func publishRelease(version, commit string, date time.Time, files []string) error {
+	exec.Command("gh", "release", "delete", version, "--repo", repo.String(), "--cleanup-tag", "--yes").Run()
+	notes := fmt.Sprintf("Nightly snapshot of %s built from %s on %s. Not a release: it is unsigned and deleted after %d days.",
+		projectName, commit, date.Format("2006-01-02"), *retentionDays)
+	args := []string{"release", "create", version, "--repo", repo.String(), "--prerelease", "--latest=false",
+		"--target", commit, "--title", "Nightly " + date.Format("2006-01-02"), "--notes", notes}
+	return run(".", "gh", append(args, files...)...)
+}
+
+// publishGHPages commits nightly.json to the Pages branch in a temporary
+// worktree, leaving the current checkout untouched.
+func publishGHPages(data []byte) error {
+	workDir, err := os.MkdirTemp("", "nightly-manifest-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(workDir)
+	if err := run(".", "git", "worktree", "add", "--detach", workDir, "origin/"+*pagesBranch); err != nil {
+		return err
+	}
+	defer run(".", "git", "worktree", "remove", "--force", workDir)
+
+	if err := os.WriteFile(filepath.Join(workDir, nightly.ManifestName), data, 0644); err != nil {
+		return err
+	}
+	if err := run(workDir, "git", "add", nightly.ManifestName); err != nil {
+		return err
+	}
+	if err := exec.Command("git", "-C", workDir, "diff", "--cached", "--quiet").Run(); err == nil {
+		fmt.Printf("%s on %s is already up to date.\n", nightly.ManifestName, *pagesBranch)
+		return nil
+	}
+	if err := run(workDir, "git", "commit", "-m", "Update "+nightly.ManifestName); err != nil {
+		return err
+	}
+	return run(workDir, "git", "push", "origin", "HEAD:"+*pagesBranch)
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: go run ./scripts/nightly [--publish] [--retention-days n] [--keep n] [--out dir]")
+		fmt.Fprintln(os.Stderr, "Builds a nightly snapshot of HEAD for every platform and adds it to nightly.json, expiring old snapshots.")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 0 || *retentionDays < 1 || *keep < 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	var err error
+	if repo, err = releaserepo.FromEnv(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	commit, err := output("git", "rev-parse", "HEAD")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: git rev-parse HEAD failed: %v\n", err)
+		os.Exit(1)
+	}
+	// Archive timestamps come from the commit so that rebuilding it gives the
+	// same archives.
+	commitTime, err := output("git", "log", "-1", "--format=%ct", commit)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: git log failed: %v\n", err)
+		os.Exit(1)
+	}
+	secs, _ := strconv.ParseInt(commitTime, 10, 64)
+	mtime := time.Unix(secs, 0).UTC()
+	now := time.Now().UTC()
+
+	latest, err := latestStable()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	version := nightly.Version(latest, now)
+	m, err := loadManifest()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	m.RetentionDays = *retentionDays
+
+	if s, ok := m.Find(m.Latest); ok && s.Commit == commit {
+		fmt.Printf("%s is already the latest snapshot (%s); only expiring old snapshots.\n", commit, s.Version)
+	} else {
+		matrix, err := platforms.Load(platforms.File)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		dir := filepath.Join(*outDir, version)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating %s: %v\n", dir, err)
+			os.Exit(1)
+		}
+		files, assets, err := build(version, dir, matrix, mtime)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if err := run(".", "go", "run", "./scripts/check-binaries", "--dir", dir, version); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if *publish {
+			if err := publishRelease(version, commit, now, files); err != nil {
+				fmt.Fprintf(os.Stderr, "Error publishing %s: %v\n", version, err)
+				os.Exit(1)
+			}
+		}
+		m.Add(nightly.Snapshot{Version: version, Commit: commit, Date: now, Assets: assets})
+		fmt.Printf("Built snapshot %s of %s.\n", version, commit)
+	}
+
+	expired := m.Expire(now, time.Duration(*retentionDays)*24*time.Hour, *keep)
+	for _, s := range expired {
+		fmt.Printf("Expiring snapshot %s from %s.\n", s.Version, s.Date.Format("2006-01-02"))
+		if *publish {
+			if err := run(".", "gh", "release", "delete", s.Version, "--repo", repo.String(), "--cleanup-tag", "--yes"); err != nil {
+				// Keep going: the manifest no longer lists it, and the next
+				// run cannot find it there to retry either.
+				fmt.Fprintf(os.Stderr, "Warning: could not delete the release of %s: %v\n", s.Version, err)
+			}
+		}
+	}
+
+	data, err := m.Marshal(now)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := os.MkdirAll(*outDir, 0755); err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating %s: %v\n", *outDir, err)
+		os.Exit(1)
+	}
+	manifestPath := filepath.Join(*outDir, nightly.ManifestName)
+	if err := os.WriteFile(manifestPath, data, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", manifestPath, err)
+		os.Exit(1)
+	}
+	fmt.Printf("Wrote %s (%d snapshots, latest %s).\n", manifestPath, len(m.Snapshots), m.Latest)
+	if *publish {
+		if err := publishGHPages(data); err != nil {
+			fmt.Fprintf(os.Stderr, "Error publishing %s: %v\n", nightly.ManifestName, err)
+			os.Exit(1)
+		}
+		fmt.Printf("Published %s to %s.\n", nightly.ManifestName, *pagesBranch)
+	}
+}
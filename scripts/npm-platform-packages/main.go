@@ -0,0 +1,313 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/google/test-server/internal/checksumsjson"
+	"github.com/google/test-server/internal/platforms"
+	"github.com/google/test-server/internal/yank"
+)
+
+// --- General Project Configuration ---
+const (
+	githubOwner = "google"
+	githubRepo  = "test-server"
+	projectName = "test-server"
+
+	sdkDir            = "sdks/typescript"
+	checksumsJSONPath = sdkDir + "/checksums.json"
+	packageScope      = "@test-server"
+	noticesFile       = "THIRD_PARTY_NOTICES"
+)
+
+var (
+	outDir    = flag.String("out", "dist/npm", "Directory to write the platform packages to")
+	updateSDK = flag.Bool("update-sdk", false, "Write the optionalDependencies into sdks/typescript/package.json")
+	publish   = flag.Bool("publish", false, "Run `npm publish` for every generated package")
+)
+
+type platformPackage struct {
+	Name            string            `json:"name"`
+	Version         string            `json:"version"`
+	Description     string            `json:"description"`
+	License         string            `json:"license"`
+	Repository      map[string]string `json:"repository"`
+	OS              []string          `json:"os"`
+	CPU             []string          `json:"cpu"`
+	Files           []string          `json:"files"`
+	PreferUnplugged bool              `json:"preferUnplugged"`
+}
+
+func packageName(osName, cpu string) string {
+	return fmt.Sprintf("%s/cli-%s-%s", packageScope, osName, cpu)
+}
+
+func loadChecksums(version string) (map[string]string, error) {
+	all, err := checksumsjson.LoadCurrent(checksumsJSONPath)
+	if err != nil {
+		return nil, err
+	}
+	checksums, ok := all.Digests(version)
+	if !ok {
+		return nil, fmt.Errorf("%s has no entry for %s; run update-sdk-checksums first", checksumsJSONPath, version)
+	}
+	return checksums, nil
+}
+
+func fetchVerified(version, archive, expected string) ([]byte, error) {
+	url := fmt.Sprintf("https://github.com/%s/%s/releases/download/%s/%s", githubOwner, githubRepo, version, archive)
+	fmt.Printf("Downloading %s...\n", url)
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download %s: status %s", url, resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	if actual := hex.EncodeToString(sum[:]); actual != expected {
+		return nil, fmt.Errorf("checksum mismatch for %s: expected %s, got %s", archive, expected, actual)
+	}
+	return data, nil
+}
+
+// extractBinary returns the contents of the test-server binary in archive.
+func extractBinary(archive string, data []byte) ([]byte, error) {
+	if strings.HasSuffix(archive, ".zip") {
+		zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return nil, err
+		}
+		for _, zf := range zr.File {
+			if zf.Name == projectName+".exe" {
+				rc, err := zf.Open()
+				if err != nil {
+					return nil, err
+				}
+				defer rc.Close()
+				return io.ReadAll(rc)
+			}
+		}
+		return nil, fmt.Errorf("%s.exe not found in %s", projectName, archive)
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("%s not found in %s", projectName, archive)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Name == projectName {
+			return io.ReadAll(tr)
+		}
+	}
+}
+
+func writePackage(dir string, pkg platformPackage, binaryName string, binary, notices []byte) error {
+	if err := os.MkdirAll(filepath.Join(dir, "bin"), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "bin", binaryName), binary, 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(pkg, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "package.json"), append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, noticesFile), notices, 0644); err != nil {
+		return err
+	}
+	readme := fmt.Sprintf("# %s\n\nThe %s %s/%s binary for [test-server-sdk](https://www.npmjs.com/package/test-server-sdk).\nThis package is installed automatically as an optional dependency; do not depend on it directly.\n", pkg.Name, projectName, pkg.OS[0], pkg.CPU[0])
+	return os.WriteFile(filepath.Join(dir, "README.md"), []byte(readme), 0644)
+}
+
+var optionalDepsRe = regexp.MustCompile(`(?s)"optionalDependencies":\s*\{.*?\}`)
+
+// updateSDKPackageJSON pins the platform packages as optionalDependencies,
+// editing the file textually to keep its key order intact.
+func updateSDKPackageJSON(deps map[string]string) error {
+	path := filepath.Join(sdkDir, "package.json")
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(deps))
+	for name := range deps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var lines []string
+	for _, name := range names {
+		lines = append(lines, fmt.Sprintf("    %q: %q", name, deps[name]))
+	}
+	block := fmt.Sprintf("\"optionalDependencies\": {\n%s\n  }", strings.Join(lines, ",\n"))
+
+	var updated string
+	switch {
+	case optionalDepsRe.Match(content):
+		updated = optionalDepsRe.ReplaceAllLiteralString(string(content), block)
+	case bytes.Contains(content, []byte(`"devDependencies"`)):
+		updated = strings.Replace(string(content), `"devDependencies"`, block+",\n  \"devDependencies\"", 1)
+	default:
+		return fmt.Errorf("could not find where to insert optionalDependencies in %s", path)
+	}
+	if !json.Valid([]byte(updated)) {
+		return fmt.Errorf("refusing to write invalid JSON to %s", path)
+	}
+	if err := os.WriteFile(path, []byte(updated), 0644); err != nil {
+		return err
+	}
+	fmt.Printf("Updated optionalDependencies in %s.\n", path)
+	return nil
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: go run ./scripts/npm-platform-packages [--out dir] [--update-sdk] [--publish] <version_tag>")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	version := flag.Arg(0)
+	if !strings.HasPrefix(version, "v") {
+		fmt.Fprintln(os.Stderr, "Error: version_tag must start with 'v' (e.g., v0.1.0)")
+		os.Exit(1)
+	}
+	if err := yank.CheckVersion(version); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	pkgVersion := strings.TrimPrefix(version, "v")
+
+	matrix, err := platforms.Load(platforms.File)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	checksums, err := loadChecksums(version)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	notices, err := os.ReadFile(noticesFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", noticesFile, err)
+		os.Exit(1)
+	}
+
+	deps := map[string]string{}
+	var dirs []string
+	npmPlatforms := matrix.Filter(func(p platforms.Platform) bool { return p.Node != "" })
+	for _, p := range npmPlatforms {
+		archive := matrix.ArchiveName(projectName, p)
+		// p.Node is node's process.platform-process.arch, e.g. "win32-x64".
+		osName, cpu, _ := strings.Cut(p.Node, "-")
+		expected, ok := checksums[archive]
+		if !ok {
+			fmt.Fprintf(os.Stderr, "Error: no checksum for %s in %s\n", archive, version)
+			os.Exit(1)
+		}
+		data, err := fetchVerified(version, archive, expected)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		binary, err := extractBinary(archive, data)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error extracting %s: %v\n", archive, err)
+			os.Exit(1)
+		}
+
+		name := packageName(osName, cpu)
+		binaryName := platforms.BinaryName(projectName, p)
+		pkg := platformPackage{
+			Name:            name,
+			Version:         pkgVersion,
+			Description:     fmt.Sprintf("The %s binary for %s %s.", projectName, osName, cpu),
+			License:         "Apache-2.0",
+			Repository:      map[string]string{"type": "git", "url": fmt.Sprintf("git+https://github.com/%s/%s.git", githubOwner, githubRepo)},
+			OS:              []string{osName},
+			CPU:             []string{cpu},
+			Files:           []string{"bin", noticesFile},
+			PreferUnplugged: true,
+		}
+		dir := filepath.Join(*outDir, "cli-"+p.Node)
+		if err := writePackage(dir, pkg, binaryName, binary, notices); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", dir, err)
+			os.Exit(1)
+		}
+		fmt.Printf("Generated %s@%s in %s.\n", name, pkgVersion, dir)
+		deps[name] = pkgVersion
+		dirs = append(dirs, dir)
+	}
+
+	if *updateSDK {
+		if err := updateSDKPackageJSON(deps); err != nil {
+			fmt.Fprintf(os.Stderr, "Error updating SDK package.json: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if *publish {
+		for _, dir := range dirs {
+			cmd := exec.Command("npm", "publish", "--access", "public")
+			cmd.Dir = dir
+			cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+			if err := cmd.Run(); err != nil {
+				fmt.Fprintf(os.Stderr, "Error publishing %s: %v\n", dir, err)
+				os.Exit(1)
+			}
+		}
+	}
+}
@@ -0,0 +1,264 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/google/test-server/internal/checksumsjson"
+	"github.com/google/test-server/internal/platforms"
+	"github.com/google/test-server/internal/yank"
+)
+
+// --- General Project Configuration ---
+const (
+	githubOwner = "google"
+	githubRepo  = "test-server"
+	projectName = "test-server"
+
+	checksumsJSONPath = "sdks/dotnet/checksums.json"
+	packageIDPrefix   = "TestServerSdk.Runtime."
+	noticesFile       = "THIRD_PARTY_NOTICES"
+)
+
+var outDir = flag.String("out", "dist/nuget", "Directory to write the .nupkg files to")
+
+type nuspecData struct {
+	ID         string
+	Version    string
+	RID        string
+	ServerTag  string
+	ProjectURL string
+}
+
+var nuspecTemplate = template.Must(template.New("nuspec").Parse(`<?xml version="1.0" encoding="utf-8"?>
+<package xmlns="http://schemas.microsoft.com/packaging/2013/05/nuspec.xsd">
+  <metadata>
+    <id>{{.ID}}</id>
+    <version>{{.Version}}</version>
+    <authors>Google LLC</authors>
+    <license type="expression">Apache-2.0</license>
+    <projectUrl>{{.ProjectURL}}</projectUrl>
+    <description>The test-server {{.ServerTag}} binary for {{.RID}}, consumed by TestServerSdk.</description>
+    <repository type="git" url="{{.ProjectURL}}.git" />
+  </metadata>
+</package>
+`))
+
+const contentTypes = `<?xml version="1.0" encoding="utf-8"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+  <Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml" />
+  <Default Extension="nuspec" ContentType="application/octet" />
+  <Default Extension="exe" ContentType="application/octet" />
+  <Override PartName="/runtimes/%s/native/%s" ContentType="application/octet" />
+  <Override PartName="/THIRD_PARTY_NOTICES" ContentType="application/octet" />
+</Types>
+`
+
+const rels = `<?xml version="1.0" encoding="utf-8"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+  <Relationship Type="http://schemas.microsoft.com/packaging/2010/07/manifest" Target="/%s.nuspec" Id="R0" />
+</Relationships>
+`
+
+func loadChecksums(version string) (map[string]string, error) {
+	all, err := checksumsjson.LoadCurrent(checksumsJSONPath)
+	if err != nil {
+		return nil, err
+	}
+	checksums, ok := all.Digests(version)
+	if !ok {
+		return nil, fmt.Errorf("%s has no entry for %s; run update-sdk-checksums first", checksumsJSONPath, version)
+	}
+	return checksums, nil
+}
+
+func fetchVerifiedBinary(version, archive, expected string) ([]byte, error) {
+	url := fmt.Sprintf("https://github.com/%s/%s/releases/download/%s/%s", githubOwner, githubRepo, version, archive)
+	fmt.Printf("Downloading %s...\n", url)
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download %s: status %s", url, resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	if actual := hex.EncodeToString(sum[:]); actual != expected {
+		return nil, fmt.Errorf("checksum mismatch for %s: expected %s, got %s", archive, expected, actual)
+	}
+
+	if strings.HasSuffix(archive, ".zip") {
+		zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return nil, err
+		}
+		for _, zf := range zr.File {
+			if zf.Name == projectName+".exe" {
+				rc, err := zf.Open()
+				if err != nil {
+					return nil, err
+				}
+				defer rc.Close()
+				return io.ReadAll(rc)
+			}
+		}
+		return nil, fmt.Errorf("%s.exe not found in %s", projectName, archive)
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("%s not found in %s", projectName, archive)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Name == projectName {
+			return io.ReadAll(tr)
+		}
+	}
+}
+
+func writeNupkg(path string, data nuspecData, binaryName string, binary, notices []byte) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+
+	var nuspec bytes.Buffer
+	if err := nuspecTemplate.Execute(&nuspec, data); err != nil {
+		return err
+	}
+	entries := []struct {
+		Name string
+		Data []byte
+		Mode os.FileMode
+	}{
+		{"[Content_Types].xml", []byte(fmt.Sprintf(contentTypes, data.RID, binaryName)), 0644},
+		{"_rels/.rels", []byte(fmt.Sprintf(rels, data.ID)), 0644},
+		{data.ID + ".nuspec", nuspec.Bytes(), 0644},
+		{fmt.Sprintf("runtimes/%s/native/%s", data.RID, binaryName), binary, 0755},
+		{noticesFile, notices, 0644},
+	}
+	for _, e := range entries {
+		hdr := &zip.FileHeader{Name: e.Name, Method: zip.Deflate}
+		hdr.SetMode(e.Mode)
+		w, err := zw.CreateHeader(hdr)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(e.Data); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: go run ./scripts/nuget-runtime-packages [--out dir] <version_tag>")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	version := flag.Arg(0)
+	if !strings.HasPrefix(version, "v") {
+		fmt.Fprintln(os.Stderr, "Error: version_tag must start with 'v' (e.g., v0.1.0)")
+		os.Exit(1)
+	}
+	if err := yank.CheckVersion(version); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	matrix, err := platforms.Load(platforms.File)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	checksums, err := loadChecksums(version)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	notices, err := os.ReadFile(noticesFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", noticesFile, err)
+		os.Exit(1)
+	}
+	if err := os.MkdirAll(*outDir, 0755); err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating %s: %v\n", *outDir, err)
+		os.Exit(1)
+	}
+
+	for _, p := range matrix.Filter(func(p platforms.Platform) bool { return p.Dotnet != "" }) {
+		archive := matrix.ArchiveName(projectName, p)
+		expected, ok := checksums[archive]
+		if !ok {
+			fmt.Fprintf(os.Stderr, "Error: no checksum for %s in %s\n", archive, version)
+			os.Exit(1)
+		}
+		binary, err := fetchVerifiedBinary(version, archive, expected)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		binaryName := platforms.BinaryName(projectName, p)
+		data := nuspecData{
+			ID:         packageIDPrefix + p.Dotnet,
+			Version:    strings.TrimPrefix(version, "v"),
+			RID:        p.Dotnet,
+			ServerTag:  version,
+			ProjectURL: fmt.Sprintf("https://github.com/%s/%s", githubOwner, githubRepo),
+		}
+		path := filepath.Join(*outDir, fmt.Sprintf("%s.%s.nupkg", data.ID, data.Version))
+		if err := writeNupkg(path, data, binaryName, binary, notices); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", path, err)
+			os.Exit(1)
+		}
+		fmt.Printf("Built %s.\n", path)
+	}
+	fmt.Println("\nPush with: dotnet nuget push \"" + filepath.Join(*outDir, "*.nupkg") + "\" --source https://api.nuget.org/v3/index.json")
+}
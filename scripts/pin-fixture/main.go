@@ -0,0 +1,211 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/google/test-server/internal/checksumsjson"
+	"github.com/google/test-server/internal/fixture"
+	"github.com/google/test-server/internal/githubapi"
+	"github.com/google/test-server/internal/platforms"
+	"github.com/google/test-server/internal/releaserepo"
+)
+
+// --- General Project Configuration ---
+const projectName = "test-server"
+
+// repo is the repository the archives are downloaded from; see
+// releaserepo.EnvVar.
+var repo = releaserepo.Production
+
+// client downloads the release assets of repo.
+var client *githubapi.Client
+
+var (
+	platformList = flag.String("platform", "host", `Platforms to pin and fetch: "host", "all" or a comma-separated list of goos/goarch`)
+	offline      = flag.Bool("offline", false, "Never download; fail if a pinned binary is not cached")
+	printPath    = flag.Bool("print", false, "Print only the path of the cached binary of the single version and platform, e.g. for TEST_SERVER_BINARY")
+)
+
+// selectPlatforms resolves --platform against the matrix.
+func selectPlatforms(matrix *platforms.Matrix) ([]platforms.Platform, error) {
+	switch *platformList {
+	case "all":
+		return matrix.Platforms, nil
+	case "host":
+		p, ok := matrix.Find(runtime.GOOS, runtime.GOARCH)
+		if !ok {
+			return nil, fmt.Errorf("%s/%s is not a released platform", runtime.GOOS, runtime.GOARCH)
+		}
+		return []platforms.Platform{p}, nil
+	}
+	var out []platforms.Platform
+	for _, name := range strings.Split(*platformList, ",") {
+		goos, goarch, _ := strings.Cut(strings.TrimSpace(name), "/")
+		p, ok := matrix.Find(goos, goarch)
+		if !ok {
+			return nil, fmt.Errorf("%q is not a released platform", name)
+		}
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+// pin records the archive digests of version from checksums.json, the same
+// digests the SDK installers check.
+func pin(m *fixture.Manifest, matrix *platforms.Matrix, selected []platforms.Platform, version string) error {
+	checksums, err := checksumsjson.Load(checksumsjson.Files[0])
+	if err != nil {
+		return err
+	}
+	digests, ok := checksums[version]
+	if !ok || checksumsjson.IsReserved(version) {
+		return fmt.Errorf("%s is not in %s; run go run ./scripts/update-sdk-checksums %s first", version, checksumsjson.Files[0], version)
+	}
+	for _, p := range selected {
+		archive := matrix.ArchiveName(projectName, p)
+		sum, ok := digests[archive]
+		if !ok {
+			return fmt.Errorf("%s has no digest for %s in %s", version, archive, checksumsjson.Files[0])
+		}
+		m.Add(version, fixture.Platform(p.GOOS, p.GOARCH), fixture.Pin{
+			Archive: archive,
+			SHA256:  sum,
+			Binary:  platforms.BinaryName(projectName, p),
+		})
+	}
+	return nil
+}
+
+// sync makes sure the binary of version for p is cached, downloading it
+// unless --offline is set. It reports whether anything was downloaded.
+func sync(m *fixture.Manifest, version string, p platforms.Platform) (bool, error) {
+	key := fixture.Platform(p.GOOS, p.GOARCH)
+	pinned, ok := m.Fixtures[version][key]
+	if !ok {
+		return false, fmt.Errorf("%s is not pinned for %s; run go run ./scripts/pin-fixture --platform %s %s", version, key, key, version)
+	}
+	cached, err := fixture.Cached(fixture.Dir, pinned)
+	if err != nil || cached {
+		return false, err
+	}
+	if *offline {
+		return false, fmt.Errorf("%s for %s is not cached in %s and --offline is set", version, key, fixture.Dir)
+	}
+	data, err := client.Download(version, pinned.Archive)
+	if err != nil {
+		return false, err
+	}
+	return true, fixture.Store(fixture.Dir, pinned, data)
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: go run ./scripts/pin-fixture [--platform host|all|goos/goarch,...] [--offline] [--print] [<version_tag>...]")
+		fmt.Fprintf(os.Stderr, "Pins the given versions in %s/%s with the digests from checksums.json and caches their verified binaries.\n", fixture.Dir, fixture.ManifestName)
+		fmt.Fprintln(os.Stderr, "Without versions, fetches and verifies every pinned version for the selected platforms.")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	var err error
+	if repo, err = releaserepo.FromEnv(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	client = githubapi.New(repo)
+	// With --print, stdout is only the path.
+	status := os.Stdout
+	if *printPath {
+		status = os.Stderr
+	}
+	if repo.Staging() {
+		fmt.Fprintf(status, "Using the staging release repository %s.\n", repo)
+	}
+
+	matrix, err := platforms.Load(platforms.File)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	selected, err := selectPlatforms(matrix)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	m, err := fixture.Load(fixture.Dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	versions := flag.Args()
+	if len(versions) > 0 {
+		for _, version := range versions {
+			if err := pin(m, matrix, selected, version); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		if err := m.Save(fixture.Dir); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing the pins: %v\n", err)
+			os.Exit(1)
+		}
+	} else {
+		versions = m.Versions()
+	}
+	if *printPath && (len(versions) != 1 || len(selected) != 1) {
+		fmt.Fprintln(os.Stderr, "Error: --print needs exactly one version and one platform")
+		os.Exit(1)
+	}
+	if len(versions) == 0 {
+		fmt.Fprintf(status, "Nothing is pinned in %s/%s.\n", fixture.Dir, fixture.ManifestName)
+		return
+	}
+
+	for _, version := range versions {
+		for _, p := range selected {
+			key := fixture.Platform(p.GOOS, p.GOARCH)
+			if _, ok := m.Fixtures[version][key]; !ok && len(flag.Args()) == 0 && !*printPath {
+				// Syncing only covers what was pinned.
+				continue
+			}
+			downloaded, err := sync(m, version, p)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			path, err := fixture.Binary(fixture.Dir, version, p.GOOS, p.GOARCH)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			if *printPath {
+				fmt.Println(path)
+				continue
+			}
+			state := "cached"
+			if downloaded {
+				state = "downloaded"
+			}
+			fmt.Printf("%s %s: %s (%s)\n", version, key, path, state)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/test-server/internal/provenance"
+	"github.com/google/test-server/internal/verify"
+)
+
+// --- General Project Configuration ---
+const (
+	githubOwner = "google"
+	githubRepo  = "test-server"
+	projectName = "test-server"
+)
+
+var (
+	distDir   = flag.String("dist", "dist", "goreleaser output directory containing the checksums file")
+	builderID = flag.String("builder-id", "", "Builder identity to record (derived from GITHUB_* env vars when empty)")
+	upload    = flag.Bool("upload", false, "Attach the provenance to the GitHub release with `gh release upload`")
+	key       = flag.String("key", "env://COSIGN_PRIVATE_KEY", "Cosign private key signing the provenance (COSIGN_PASSWORD unlocks it)")
+	cosignKey = flag.String("cosign-key", verify.KeyFile, "Public key the signed provenance is checked against before it is written")
+)
+
+func readChecksums(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	checksums := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		parts := strings.Fields(scanner.Text())
+		if len(parts) == 2 {
+			checksums[parts[1]] = parts[0]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(checksums) == 0 {
+		return nil, fmt.Errorf("no checksums found in %s", path)
+	}
+	return checksums, nil
+}
+
+// defaultBuilderID derives the builder identity from the GitHub Actions
+// environment. Outside of it there is no builder anyone could trust, so the
+// release workflow is the only place the provenance is generated.
+func defaultBuilderID() (string, error) {
+	workflowRef := os.Getenv("GITHUB_WORKFLOW_REF")
+	if workflowRef == "" {
+		return "", fmt.Errorf("not running in GitHub Actions; the release workflow (%s) generates the provenance, or pass --builder-id", provenance.DefaultBuilderID)
+	}
+	return "https://github.com/" + workflowRef, nil
+}
+
+// sign wraps payload in a DSSE envelope signed by `cosign sign-blob`.
+func sign(payload []byte) (*provenance.Envelope, error) {
+	dir, err := os.MkdirTemp("", "provenance")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	paePath := filepath.Join(dir, "pae")
+	sigPath := filepath.Join(dir, "pae.sig")
+	if err := os.WriteFile(paePath, provenance.PAE(provenance.PayloadType, payload), 0600); err != nil {
+		return nil, err
+	}
+	cmd := exec.Command("cosign", "sign-blob", "--key", *key, "--output-signature", sigPath, "--yes", paePath)
+	cmd.Stdout, cmd.Stderr = os.Stderr, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("cosign sign-blob: %w", err)
+	}
+	sig, err := os.ReadFile(sigPath)
+	if err != nil {
+		return nil, err
+	}
+	return provenance.NewEnvelope(payload, sig), nil
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: go run ./scripts/provenance [--dist dir] [--builder-id id] [--key key] [--cosign-key cosign.pub] [--upload] <version_tag>")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	tag := flag.Arg(0)
+	if !strings.HasPrefix(tag, "v") {
+		fmt.Fprintln(os.Stderr, "Error: version_tag must start with 'v' (e.g., v0.1.0)")
+		os.Exit(1)
+	}
+
+	checksumsPath := filepath.Join(*distDir, fmt.Sprintf("%s_%s_checksums.txt", projectName, strings.TrimPrefix(tag, "v")))
+	checksums, err := readChecksums(checksumsPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", checksumsPath, err)
+		os.Exit(1)
+	}
+
+	commit, err := exec.Command("git", "rev-list", "-n", "1", tag).Output()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error resolving commit for %s: %v\n", tag, err)
+		os.Exit(1)
+	}
+
+	builder := *builderID
+	if builder == "" {
+		if builder, err = defaultBuilderID(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	repo := fmt.Sprintf("https://github.com/%s/%s", githubOwner, githubRepo)
+	stmt := provenance.NewStatement(checksums, builder, repo, tag, strings.TrimSpace(string(commit)), os.Getenv("GITHUB_RUN_ID"), time.Now())
+
+	payload, err := json.Marshal(stmt)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error encoding provenance: %v\n", err)
+		os.Exit(1)
+	}
+	env, err := sign(payload)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error signing provenance: %v\n", err)
+		os.Exit(1)
+	}
+	data, err := json.MarshalIndent(env, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error encoding provenance: %v\n", err)
+		os.Exit(1)
+	}
+	// Catch a release signed with another key than the one users verify with.
+	pemBytes, err := os.ReadFile(*cosignKey)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	pub, err := verify.ParsePublicKey(pemBytes)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if _, err := provenance.Open(data, pub); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: the signed provenance does not verify against %s: %v\n", *cosignKey, err)
+		os.Exit(1)
+	}
+	outPath := filepath.Join(*distDir, provenance.FileName(projectName, tag))
+	if err := os.WriteFile(outPath, append(data, '\n'), 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", outPath, err)
+		os.Exit(1)
+	}
+	fmt.Printf("Wrote signed provenance for %d archives to %s (builder %s).\n", len(checksums), outPath, builder)
+
+	if *upload {
+		cmd := exec.Command("gh", "release", "upload", tag, outPath, "--clobber")
+		cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+		if err := cmd.Run(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error uploading provenance: %v\n", err)
+			os.Exit(1)
+		}
+	}
+}
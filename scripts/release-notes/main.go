@@ -0,0 +1,81 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/google/test-server/internal/releasenotes"
+)
+
+func loadCommits(from, to string) ([]releasenotes.Commit, error) {
+	const fieldSep, recordSep = "\x1f", "\x1e"
+	out, err := exec.Command("git", "log", "--no-merges",
+		"--format=%H"+fieldSep+"%s"+fieldSep+"%b"+recordSep,
+		fmt.Sprintf("%s..%s", from, to)).Output()
+	if err != nil {
+		return nil, fmt.Errorf("git log %s..%s failed: %w", from, to, err)
+	}
+
+	var commits []releasenotes.Commit
+	for _, record := range strings.Split(string(out), recordSep) {
+		record = strings.TrimSpace(record)
+		if record == "" {
+			continue
+		}
+		parts := strings.SplitN(record, fieldSep, 3)
+		if len(parts) < 2 {
+			continue
+		}
+		body := ""
+		if len(parts) == 3 {
+			body = parts[2]
+		}
+		files, err := exec.Command("git", "diff-tree", "--no-commit-id", "--name-only", "-r", parts[0]).Output()
+		if err != nil {
+			return nil, fmt.Errorf("git diff-tree %s failed: %w", parts[0], err)
+		}
+		c := releasenotes.Parse(parts[0], parts[1], body, strings.Fields(string(files)))
+		if c.Type == releasenotes.OtherType {
+			fmt.Fprintf(os.Stderr, "Warning: %.7s %q has no known conventional-commit type; listed under Other Changes.\n", c.Hash, c.Subject)
+		}
+		commits = append(commits, c)
+	}
+	return commits, nil
+}
+
+func main() {
+	if len(os.Args) < 3 {
+		fmt.Fprintln(os.Stderr, "Usage: go run ./scripts/release-notes <from_tag> <to_tag>")
+		fmt.Fprintln(os.Stderr, "Example: go run ./scripts/release-notes v0.2.7 v0.2.8 > notes.md")
+		os.Exit(1)
+	}
+	from, to := os.Args[1], os.Args[2]
+
+	commits, err := loadCommits(from, to)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if len(commits) == 0 {
+		fmt.Fprintf(os.Stderr, "No commits found between %s and %s.\n", from, to)
+		os.Exit(1)
+	}
+	fmt.Print(releasenotes.Render(from, to, commits))
+}
@@ -0,0 +1,420 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/google/test-server/internal/githubapi"
+	"github.com/google/test-server/internal/releaserepo"
+)
+
+// --- General Project Configuration ---
+const (
+	projectName = "test-server"
+
+	// stateFile records which steps already completed so that --resume can
+	// pick up where a failed run stopped.
+	stateFile = ".release-state.json"
+)
+
+// releaseState is persisted to stateFile after every successful step.
+type releaseState struct {
+	Tag       string   `json:"tag"`
+	Staging   string   `json:"staging,omitempty"`
+	Completed []string `json:"completed"`
+}
+
+func (s *releaseState) done(step string) bool {
+	for _, c := range s.Completed {
+		if c == step {
+			return true
+		}
+	}
+	return false
+}
+
+// step is a single stage of the release flow.
+type step struct {
+	Name string
+	Run  func(tag string) error
+}
+
+// steps is the ordered release flow. Every step must be safe to re-run.
+var steps = []step{
+	{Name: "verify-tag", Run: verifyTag},
+	{Name: "api-compat", Run: checkAPICompat},
+	{Name: "wait-for-assets", Run: waitForAssets},
+	{Name: "check-binaries", Run: checkBinaries},
+	{Name: "compat-matrix", Run: publishCompatMatrix},
+	{Name: "update-sdk-checksums", Run: updateSDKChecksums},
+	{Name: "sdk-smoke-tests", Run: runSmokeTests},
+	{Name: "open-pr", Run: openPR},
+	{Name: "announce", Run: announce},
+}
+
+// stagingSteps rehearse the release against a scratch repository: the tag is
+// released there by goreleaser, the SDKs are pinned to it and installed from
+// it, and the pin is reverted instead of opening a PR.
+var stagingSteps = []step{
+	{Name: "push-staging-tag", Run: pushStagingTag},
+	{Name: "api-compat", Run: checkAPICompat},
+	{Name: "goreleaser", Run: runGoreleaser},
+	{Name: "wait-for-assets", Run: waitForAssets},
+	{Name: "check-binaries", Run: checkBinaries},
+	{Name: "compat-matrix", Run: publishCompatMatrix},
+	{Name: "check-release-assets", Run: checkReleaseAssets},
+	{Name: "update-sdk-checksums", Run: updateSDKChecksums},
+	{Name: "sdk-smoke-tests", Run: runSmokeTests},
+	{Name: "installer-e2e", Run: runInstallerE2E},
+	{Name: "mirror", Run: mirrorRelease},
+	{Name: "restore-sdks", Run: restoreSDKs},
+}
+
+// repo is the repository the release is published to; --staging replaces it.
+var repo = releaserepo.Production
+
+var (
+	resume       = flag.Bool("resume", false, "Skip steps that completed in a previous run for the same tag")
+	assetTimeout = flag.Duration("asset-timeout", 30*time.Minute, "How long to wait for goreleaser assets to appear")
+	pollInterval = flag.Duration("poll-interval", 30*time.Second, "How often to poll for release assets")
+	baseBranch   = flag.String("base", "main", "Base branch for the checksum update PR")
+	staging      = flag.String("staging", "", "Rehearse the release against this scratch GitHub repository (owner/name) instead of "+releaserepo.Production.String())
+	stagingBkt   = flag.String("staging-bucket", "", "With --staging, also mirror the release to this scratch bucket (gs://... or s3://...)")
+	discussion   = flag.String("discussion", "", "Also announce the release in this GitHub Discussions category")
+)
+
+// smokeTests are the per-SDK commands run against the freshly pinned binary.
+var smokeTests = []struct {
+	Name string
+	Dir  string
+	Cmd  []string
+}{
+	{Name: "TypeScript", Dir: "sdks/typescript", Cmd: []string{"npm", "ci"}},
+	{Name: "Python", Dir: "sdks/python/src", Cmd: []string{"python", "-m", "test_server_sdk.install"}},
+	{Name: "Dotnet", Dir: "sdks/dotnet/tools/installer", Cmd: []string{"dotnet", "run"}},
+}
+
+func run(dir string, name string, args ...string) error {
+	cmd := exec.Command(name, args...)
+	cmd.Dir = dir
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	fmt.Printf("+ %s %s\n", name, strings.Join(args, " "))
+	return cmd.Run()
+}
+
+func output(name string, args ...string) (string, error) {
+	out, err := exec.Command(name, args...).Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+func verifyTag(tag string) error {
+	local, err := output("git", "rev-parse", "--verify", "--quiet", tag+"^{commit}")
+	if err != nil {
+		return fmt.Errorf("tag %s does not exist locally: %w", tag, err)
+	}
+	// An annotated tag is listed twice, the second time peeled to its commit,
+	// and a lightweight tag once, as the commit itself.
+	remote, err := output("git", "ls-remote", "--tags", "origin", "refs/tags/"+tag, "refs/tags/"+tag+"^{}")
+	if err != nil {
+		return fmt.Errorf("failed to query remote tags: %w", err)
+	}
+	commit := ""
+	for _, line := range strings.Split(remote, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		if fields[1] == "refs/tags/"+tag+"^{}" || commit == "" {
+			commit = fields[0]
+		}
+	}
+	if commit == "" {
+		return fmt.Errorf("tag %s has not been pushed to origin", tag)
+	}
+	if commit != local {
+		return fmt.Errorf("tag %s points to %s locally but %s on origin", tag, local, commit)
+	}
+	fmt.Printf("Tag %s verified at %s.\n", tag, local)
+	return nil
+}
+
+// waitForAssets polls the release until goreleaser has uploaded the checksums
+// file, its last asset. Unchanged polls are answered from the ETag cache.
+func waitForAssets(tag string) error {
+	checksumsFileName := fmt.Sprintf("%s_%s_checksums.txt", projectName, strings.TrimPrefix(tag, "v"))
+	c := githubapi.New(repo)
+	deadline := time.Now().Add(*assetTimeout)
+	for {
+		rel, err := c.Release(tag)
+		if err != nil && !githubapi.IsNotFound(err) {
+			return err
+		}
+		if rel != nil {
+			for _, a := range rel.Assets {
+				if a.Name == checksumsFileName && a.State == "uploaded" {
+					fmt.Printf("Release assets are available at %s.\n", a.BrowserDownloadURL)
+					return nil
+				}
+			}
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out after %s waiting for %s on %s", *assetTimeout, checksumsFileName, repo.ReleaseURL(tag))
+		}
+		fmt.Printf("Waiting for %s on %s...\n", checksumsFileName, repo.ReleaseURL(tag))
+		time.Sleep(*pollInterval)
+	}
+}
+
+// checkAPICompat refuses breaking changes to the exported Go API outside a
+// major release.
+func checkAPICompat(tag string) error {
+	return run(".", "go", "run", "./scripts/api-compat", tag)
+}
+
+// checkBinaries checks that every archive holds the binary of its platform
+// before the SDKs are pinned to them.
+func checkBinaries(tag string) error {
+	return run(".", "go", "run", "./scripts/check-binaries", tag)
+}
+
+// publishCompatMatrix attaches the SDK compatibility matrix to the release.
+func publishCompatMatrix(tag string) error {
+	if err := run(".", "go", "run", "./scripts/compat-matrix", "--out", "dist/compat", tag); err != nil {
+		return err
+	}
+	return run(".", "gh", "release", "upload", tag, "--repo", repo.String(), "--clobber", "dist/compat/compatibility.json", "dist/compat/compatibility.md")
+}
+
+// pushStagingTag pushes the local tag to the staging repository, replacing the
+// tag of an earlier rehearsal.
+func pushStagingTag(tag string) error {
+	if _, err := output("git", "rev-parse", "--verify", "--quiet", tag+"^{commit}"); err != nil {
+		return fmt.Errorf("tag %s does not exist locally: %w", tag, err)
+	}
+	head, _ := output("git", "rev-parse", "HEAD")
+	tagged, _ := output("git", "rev-parse", tag+"^{commit}")
+	if head != tagged {
+		return fmt.Errorf("HEAD is not at %s; check out the tag so that goreleaser builds it", tag)
+	}
+	remote := fmt.Sprintf("https://github.com/%s.git", repo)
+	return run(".", "git", "push", "--force", remote, "refs/tags/"+tag)
+}
+
+// runGoreleaser publishes the release to the staging repository. The real
+// release is published by the Release workflow on the pushed tag (see
+// CONTRIBUTING.md), so this only runs in rehearsals.
+func runGoreleaser(tag string) error {
+	cmd := exec.Command("goreleaser", "release", "--clean")
+	cmd.Env = append(os.Environ(), "TEST_SERVER_RELEASE_OWNER="+repo.Owner, "TEST_SERVER_RELEASE_NAME="+repo.Name)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	fmt.Printf("+ goreleaser release --clean (publishing to %s)\n", repo)
+	return cmd.Run()
+}
+
+// checkReleaseAssets checks the rehearsed release has every expected asset.
+// The provenance comes from the trusted builder, which rehearsals do not run,
+// and the Windows installers need the signing certificate.
+func checkReleaseAssets(tag string) error {
+	return run(".", "go", "run", "./scripts/check-release-assets", "--skip-provenance", "--skip-windows-installers", tag)
+}
+
+func updateSDKChecksums(tag string) error {
+	return run(".", "go", "run", "./scripts/update-sdk-checksums", tag)
+}
+
+// runInstallerE2E runs every SDK installer against the staged release on
+// each platform this machine can reach, in containers where possible.
+func runInstallerE2E(tag string) error {
+	return run(".", "go", "run", "./scripts/installer-e2e")
+}
+
+func runSmokeTests(tag string) error {
+	var failed []string
+	for _, t := range smokeTests {
+		fmt.Printf("\n--- Smoke testing %s SDK ---\n", t.Name)
+		if err := run(t.Dir, t.Cmd[0], t.Cmd[1:]...); err != nil {
+			fmt.Fprintf(os.Stderr, "Smoke test for %s failed: %v\n", t.Name, err)
+			failed = append(failed, t.Name)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("smoke tests failed for: %v", failed)
+	}
+	return nil
+}
+
+// mirrorRelease copies the rehearsed release to the staging bucket, when one
+// was given.
+func mirrorRelease(tag string) error {
+	if *stagingBkt == "" {
+		fmt.Println("No --staging-bucket given; skipping the mirror.")
+		return nil
+	}
+	return run(".", "go", "run", "./scripts/mirror-release", "--bucket", *stagingBkt, tag)
+}
+
+// restoreSDKs reverts the checksums the rehearsal pinned in the SDKs.
+func restoreSDKs(tag string) error {
+	return run(".", "git", "checkout", "--", "sdks")
+}
+
+func openPR(tag string) error {
+	branch := "release/checksums-" + tag
+	title := fmt.Sprintf("chore: update SDK checksums for %s", tag)
+	cmds := [][]string{
+		{"git", "checkout", "-B", branch},
+		{"git", "add", "sdks"},
+		{"git", "commit", "-m", title},
+		{"git", "push", "--force-with-lease", "origin", branch},
+	}
+	for _, c := range cmds {
+		if err := run(".", c[0], c[1:]...); err != nil {
+			return err
+		}
+	}
+	pr, err := githubapi.New(repo).CreatePullRequest(branch, *baseBranch, title, fmt.Sprintf("Pins the SDK installers to test-server %s.", tag))
+	if err != nil {
+		return fmt.Errorf("failed to open the checksum PR: %w", err)
+	}
+	fmt.Printf("Checksum PR: %s\n", pr.HTMLURL)
+	return nil
+}
+
+// announce posts the release summary to the configured webhooks, and with
+// --discussion to GitHub Discussions.
+func announce(tag string) error {
+	args := []string{"run", "./scripts/announce"}
+	if *discussion != "" {
+		args = append(args, "--discussion", *discussion)
+	}
+	return run(".", "go", append(args, tag)...)
+}
+
+func loadState(tag string) (*releaseState, error) {
+	state := &releaseState{Tag: tag, Staging: *staging}
+	if !*resume {
+		return state, nil
+	}
+	data, err := os.ReadFile(stateFile)
+	if os.IsNotExist(err) {
+		return state, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", stateFile, err)
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", stateFile, err)
+	}
+	if state.Tag != tag {
+		return nil, fmt.Errorf("%s belongs to %s, not %s; remove it or drop --resume", stateFile, state.Tag, tag)
+	}
+	if state.Staging != *staging {
+		return nil, fmt.Errorf("%s belongs to a run with --staging=%q; remove it or drop --resume", stateFile, state.Staging)
+	}
+	return state, nil
+}
+
+func saveState(state *releaseState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(stateFile, append(data, '\n'), 0644)
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: go run ./scripts/release [--resume] [--discussion category] [--staging owner/name [--staging-bucket url]] <version_tag>")
+		fmt.Fprintln(os.Stderr, "Example: go run ./scripts/release v0.2.9")
+		fmt.Fprintln(os.Stderr, "         go run ./scripts/release --staging someone/test-server-staging v0.3.0-rc.0")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	tag := flag.Arg(0)
+	if !strings.HasPrefix(tag, "v") {
+		fmt.Fprintln(os.Stderr, "Error: version_tag must start with 'v' (e.g., v0.1.0)")
+		os.Exit(1)
+	}
+
+	flow := steps
+	if *staging != "" {
+		var err error
+		if repo, err = releaserepo.Parse(*staging); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: --staging: %v\n", err)
+			os.Exit(1)
+		}
+		if !repo.Staging() {
+			fmt.Fprintf(os.Stderr, "Error: --staging must name a scratch repository, not %s\n", repo)
+			os.Exit(1)
+		}
+		// The rehearsal ends by reverting sdks/, which must not throw away
+		// unrelated changes.
+		if dirty, _ := output("git", "status", "--porcelain", "--", "sdks"); dirty != "" {
+			fmt.Fprintln(os.Stderr, "Error: sdks/ has uncommitted changes; commit or stash them before a staging rehearsal")
+			os.Exit(1)
+		}
+		// The tools the flow runs and the SDK installers read the repository
+		// from the environment.
+		os.Setenv(releaserepo.EnvVar, repo.String())
+		flow = stagingSteps
+		fmt.Printf("Rehearsing release %s against %s.\n", tag, repo)
+	} else if *stagingBkt != "" {
+		fmt.Fprintln(os.Stderr, "Error: --staging-bucket requires --staging")
+		os.Exit(1)
+	}
+
+	state, err := loadState(tag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	for _, s := range flow {
+		if state.done(s.Name) {
+			fmt.Printf("\n=== Skipping %s (already completed) ===\n", s.Name)
+			continue
+		}
+		fmt.Printf("\n=== Running %s ===\n", s.Name)
+		if err := s.Run(tag); err != nil {
+			fmt.Fprintf(os.Stderr, "\nStep %s failed: %v\n", s.Name, err)
+			fmt.Fprintf(os.Stderr, "Fix the problem and re-run with --resume to continue from this step.\n")
+			os.Exit(1)
+		}
+		state.Completed = append(state.Completed, s.Name)
+		if err := saveState(state); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not save release state: %v\n", err)
+		}
+	}
+
+	os.Remove(stateFile)
+	if repo.Staging() {
+		fmt.Printf("\nRehearsal of %s against %s completed. Delete the release and tag there when done.\n", tag, repo)
+		return
+	}
+	fmt.Printf("\nRelease %s completed.\n", tag)
+}
@@ -0,0 +1,239 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/google/test-server/internal/checksumsjson"
+	"github.com/google/test-server/internal/sdkconstants"
+	"github.com/google/test-server/internal/semver"
+	"github.com/google/test-server/internal/yank"
+)
+
+// --- General Project Configuration ---
+const (
+	projectName       = "test-server"
+	checksumsJSONPath = "sdks/typescript/checksums.json"
+)
+
+var (
+	reason       = flag.String("reason", "", "Why the bad version is rolled back; recorded in yanked.json (required)")
+	baseBranch   = flag.String("base", "main", "Base branch for the rollback PR")
+	skipHomebrew = flag.Bool("skip-homebrew", false, "Do not open a PR pointing the Homebrew formula back at the good version")
+	dryRun       = flag.Bool("dry-run", false, "Print the commands instead of running them")
+)
+
+func run(dir string, name string, args ...string) error {
+	fmt.Printf("+ %s %s\n", name, strings.Join(args, " "))
+	if *dryRun {
+		return nil
+	}
+	cmd := exec.Command(name, args...)
+	cmd.Dir = dir
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+func output(name string, args ...string) (string, error) {
+	out, err := exec.Command(name, args...).Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+func loadChecksums() (checksumsjson.Document, error) {
+	all, err := checksumsjson.LoadCurrent(checksumsJSONPath)
+	if err != nil {
+		return nil, err
+	}
+	return all, nil
+}
+
+// pinnedVersion returns the version the SDK installers install by default.
+func pinnedVersion() (string, error) {
+	c, err := sdkconstants.Load(sdkconstants.File)
+	if err != nil {
+		return "", err
+	}
+	return c.TestServerVersion, nil
+}
+
+// closeReleasePR closes the checksum PR scripts/release opened for the bad
+// version, if it has not been merged yet.
+func closeReleasePR(bad, good string) error {
+	branch := "release/checksums-" + bad
+	if *dryRun {
+		fmt.Printf("+ gh pr close <open PR from %s>\n", branch)
+		return nil
+	}
+	numbers, err := output("gh", "pr", "list", "--head", branch, "--state", "open", "--json", "number", "--jq", ".[].number")
+	if err != nil {
+		return fmt.Errorf("failed to list PRs from %s: %w", branch, err)
+	}
+	for _, n := range strings.Fields(numbers) {
+		comment := fmt.Sprintf("%s %s is being rolled back to %s.", projectName, bad, good)
+		if err := run(".", "gh", "pr", "close", n, "--comment", comment, "--delete-branch"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func openRollbackPR(bad, good string, actions []string) error {
+	branch := "rollback/" + bad
+	title := fmt.Sprintf("chore: roll back %s %s to %s", projectName, bad, good)
+	body := fmt.Sprintf("Rolls back %s %s: %s\n\n- %s\n\nAfter merging, republish versions.json with `go run ./scripts/versions-manifest --gh-pages` and release the SDKs so the installers pick up the pin and the yanked list.",
+		projectName, bad, *reason, strings.Join(actions, "\n- "))
+	cmds := [][]string{
+		{"git", "add", "sdks", yank.File},
+		{"git", "commit", "-m", title},
+		{"git", "push", "--force-with-lease", "origin", branch},
+		{"gh", "pr", "create", "--base", *baseBranch, "--head", branch, "--title", title, "--body", body},
+	}
+	for _, c := range cmds {
+		if err := run(".", c[0], c[1:]...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: go run ./scripts/rollback --reason text [--base branch] [--skip-homebrew] [--dry-run] <bad_tag> <good_tag>")
+		fmt.Fprintln(os.Stderr, "Example: go run ./scripts/rollback --reason \"replay crashes on empty bodies\" v0.2.9 v0.2.8")
+		fmt.Fprintln(os.Stderr, "Yanks a bad release, moves the SDK pin and the channels pointing at it back to the good one, and opens the PRs.")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 2 || *reason == "" {
+		flag.Usage()
+		os.Exit(1)
+	}
+	bad, err := semver.Parse(flag.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	good, err := semver.Parse(flag.Arg(1))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	badTag, goodTag := bad.Tag(), good.Tag()
+	if !semver.Less(good, bad) {
+		fmt.Fprintf(os.Stderr, "Error: the good version %s must be older than the bad version %s\n", goodTag, badTag)
+		os.Exit(1)
+	}
+
+	all, err := loadChecksums()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	for _, tag := range []string{badTag, goodTag} {
+		if _, ok := all.Digests(tag); !ok {
+			fmt.Fprintf(os.Stderr, "Error: %s has no checksums in %s; both versions must have been released\n", tag, checksumsJSONPath)
+			os.Exit(1)
+		}
+	}
+	if err := yank.CheckVersion(goodTag); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: cannot roll back to %s: %v\n", goodTag, err)
+		os.Exit(1)
+	}
+	pinned, err := pinnedVersion()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if pinned == badTag && good.Prerelease() {
+		fmt.Fprintf(os.Stderr, "Error: the SDKs pin %s; roll back to a stable release, not %s\n", badTag, goodTag)
+		os.Exit(1)
+	}
+	var channels []string
+	for channel, v := range all[checksumsjson.ChannelsKey] {
+		if v == badTag {
+			channels = append(channels, channel)
+		}
+	}
+	sort.Strings(channels)
+	if dirty, _ := output("git", "status", "--porcelain"); dirty != "" && !*dryRun {
+		fmt.Fprintln(os.Stderr, "Error: the working tree has uncommitted changes; the rollback commits to a new branch")
+		os.Exit(1)
+	}
+
+	fail := func(step string, err error) {
+		fmt.Fprintf(os.Stderr, "\nStep %s failed: %v\n", step, err)
+		fmt.Fprintf(os.Stderr, "Fix the problem, discard the partial changes, check out %s again and re-run.\n", *baseBranch)
+		os.Exit(1)
+	}
+	var actions []string
+
+	fmt.Printf("\n=== Rolling back %s to %s ===\n", badTag, goodTag)
+	if err := run(".", "git", "checkout", "-B", "rollback/"+badTag); err != nil {
+		fail("branch", err)
+	}
+	if pinned == badTag {
+		if err := run(".", "go", "run", "./scripts/update-sdk-checksums", goodTag); err != nil {
+			fail("pin", err)
+		}
+		actions = append(actions, fmt.Sprintf("Pins the SDK installers back to %s.", goodTag))
+	} else {
+		fmt.Printf("The SDKs pin %s, not %s; leaving the pin alone.\n", pinned, badTag)
+	}
+	for _, channel := range channels {
+		if err := run(".", "go", "run", "./scripts/update-sdk-checksums", "--channel", channel, goodTag); err != nil {
+			fail("channel "+channel, err)
+		}
+		actions = append(actions, fmt.Sprintf("Points the %s channel back to %s.", channel, goodTag))
+	}
+	yanked, err := yank.Load(yank.File)
+	if err != nil {
+		fail("yank", err)
+	}
+	if _, ok := yanked[badTag]; ok {
+		fmt.Printf("%s is already yanked.\n", badTag)
+	} else {
+		if err := run(".", "go", "run", "./scripts/yank", "--reason", *reason, "--replacement", goodTag, badTag); err != nil {
+			fail("yank", err)
+		}
+		actions = append(actions, fmt.Sprintf("Yanks %s in favour of %s, so the installers and the release tooling refuse it.", badTag, goodTag))
+	}
+	if len(actions) == 0 {
+		fmt.Println("Nothing in this repository points at the bad version any more; not opening a PR.")
+	} else if err := openRollbackPR(badTag, goodTag, actions); err != nil {
+		fail("open-pr", err)
+	}
+	if err := closeReleasePR(badTag, goodTag); err != nil {
+		fail("close-release-pr", err)
+	}
+	if !*skipHomebrew {
+		if err := run(".", "go", "run", "./scripts/update-homebrew", goodTag); err != nil {
+			fail("homebrew", err)
+		}
+	}
+
+	fmt.Printf("\nRollback of %s to %s is ready for review.\n", badTag, goodTag)
+	fmt.Println("Once the PRs merge:")
+	fmt.Println("  - republish versions.json: go run ./scripts/versions-manifest --gh-pages")
+	fmt.Println("  - release the SDKs: installers only refuse the versions listed in the checksums.json they shipped with")
+	fmt.Printf("  - regenerate the Scoop and winget manifests for %s: go run ./scripts/windows-manifests %s\n", goodTag, goodTag)
+}
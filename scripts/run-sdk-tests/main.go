@@ -0,0 +1,266 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/google/test-server/internal/fixture"
+)
+
+// --- General Project Configuration ---
+const (
+	projectName = "test-server"
+
+	// binaryEnv makes every SDK use the given binary instead of the one its
+	// installer downloaded.
+	binaryEnv = "TEST_SERVER_BINARY"
+)
+
+var (
+	sdkList    = flag.String("sdk", "typescript,python,dotnet", "Comma-separated SDK suites to run")
+	binaryPath = flag.String("binary", "", "Use this server binary instead of building one from the working tree")
+	fixtureVer = flag.String("fixture", "", "Use the release binary pinned and cached in testdata/bin for this version (see scripts/pin-fixture)")
+	outDir     = flag.String("out", "dist/sdk-tests", "Directory for the per-SDK logs and report.json")
+)
+
+// step is one command of a suite, run in dir.
+type step struct {
+	dir  string
+	args []string
+}
+
+// suite describes how to run one SDK's tests. Steps run in order and the
+// suite fails at the first one that does.
+type suite struct {
+	name  string
+	tool  string
+	steps []step
+	env   []string
+	// skip reports a reason the suite cannot run in this tree, if any.
+	skip func() string
+}
+
+var suites = []suite{
+	{
+		name: "typescript",
+		tool: "npm",
+		steps: []step{
+			{"sdks/typescript", []string{"npm", "install", "--no-audit", "--no-fund"}},
+			{"sdks/typescript", []string{"npm", "run", "build"}},
+			{"sdks/typescript/sample", []string{"npm", "install", "--no-audit", "--no-fund"}},
+			{"sdks/typescript/sample", []string{"npx", "tsc"}},
+			{"sdks/typescript/sample", []string{"npx", "jasmine", "--config=./jasmine.json"}},
+		},
+	},
+	{
+		name:  "python",
+		tool:  pythonCommand(),
+		steps: []step{{"sdks/python", []string{pythonCommand(), "-m", "pytest", "-v", "sample"}}},
+		env:   []string{"PYTHONPATH=src"},
+	},
+	{
+		name:  "dotnet",
+		tool:  "dotnet",
+		steps: []step{{"sdks/dotnet", []string{"dotnet", "test"}}},
+		skip: func() string {
+			projects, _ := filepath.Glob("sdks/dotnet/*Tests*/*.csproj")
+			if len(projects) == 0 {
+				return "no test project under sdks/dotnet"
+			}
+			return ""
+		},
+	},
+}
+
+func pythonCommand() string {
+	if runtime.GOOS == "windows" {
+		return "python"
+	}
+	return "python3"
+}
+
+type result struct {
+	SDK      string  `json:"sdk"`
+	Status   string  `json:"status"`
+	Reason   string  `json:"reason,omitempty"`
+	Seconds  float64 `json:"seconds"`
+	LogFile  string  `json:"log_file,omitempty"`
+	ExitCode int     `json:"exit_code"`
+}
+
+type report struct {
+	Binary  string   `json:"binary"`
+	Results []result `json:"results"`
+}
+
+func buildServer(dir string) (string, error) {
+	binary := filepath.Join(dir, projectName)
+	if runtime.GOOS == "windows" {
+		binary += ".exe"
+	}
+	fmt.Printf("Building %s from the working tree...\n", projectName)
+	cmd := exec.Command("go", "build", "-o", binary, ".")
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("go build failed: %w", err)
+	}
+	return binary, nil
+}
+
+func runSuite(s suite, binary string) (r result) {
+	r.SDK = s.name
+	if s.skip != nil {
+		if reason := s.skip(); reason != "" {
+			r.Status, r.Reason = "SKIP", reason
+			return r
+		}
+	}
+	if _, err := exec.LookPath(s.tool); err != nil {
+		r.Status, r.Reason, r.ExitCode = "FAIL", s.tool+" not found in PATH", -1
+		return r
+	}
+
+	r.LogFile = filepath.Join(*outDir, s.name+".log")
+	logFile, err := os.Create(r.LogFile)
+	if err != nil {
+		r.Status, r.Reason, r.ExitCode = "FAIL", err.Error(), -1
+		return r
+	}
+	defer logFile.Close()
+	out := io.MultiWriter(os.Stdout, logFile)
+
+	start := time.Now()
+	defer func() { r.Seconds = time.Since(start).Round(time.Millisecond).Seconds() }()
+	for _, st := range s.steps {
+		args := st.args
+		fmt.Fprintf(out, "==> [%s] (%s) %s\n", s.name, st.dir, strings.Join(args, " "))
+		cmd := exec.Command(args[0], args[1:]...)
+		cmd.Dir = st.dir
+		cmd.Env = append(append(os.Environ(), binaryEnv+"="+binary), s.env...)
+		cmd.Stdout, cmd.Stderr = out, out
+		if err := cmd.Run(); err != nil {
+			r.Status, r.Reason, r.ExitCode = "FAIL", fmt.Sprintf("%s: %v", strings.Join(args, " "), err), cmd.ProcessState.ExitCode()
+			return r
+		}
+	}
+	r.Status = "PASS"
+	return r
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: go run ./scripts/run-sdk-tests [--sdk typescript,python,dotnet] [--binary path | --fixture version] [--out dir]")
+		fmt.Fprintln(os.Stderr, "Runs every SDK's test suite against a server built from the working tree.")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	selected := map[string]bool{}
+	for _, name := range strings.Split(*sdkList, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			selected[name] = true
+		}
+	}
+	var toRun []suite
+	for _, s := range suites {
+		if selected[s.name] {
+			toRun = append(toRun, s)
+			delete(selected, s.name)
+		}
+	}
+	for name := range selected {
+		fmt.Fprintf(os.Stderr, "Error: unknown SDK %q (known: typescript, python, dotnet)\n", name)
+		os.Exit(1)
+	}
+	if err := os.MkdirAll(*outDir, 0755); err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating %s: %v\n", *outDir, err)
+		os.Exit(1)
+	}
+
+	if *binaryPath != "" && *fixtureVer != "" {
+		fmt.Fprintln(os.Stderr, "Error: --binary and --fixture are mutually exclusive")
+		os.Exit(1)
+	}
+	binary := *binaryPath
+	if *fixtureVer != "" {
+		var err error
+		if binary, err = fixture.Binary(fixture.Dir, *fixtureVer, runtime.GOOS, runtime.GOARCH); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if binary == "" {
+		var err error
+		if binary, err = buildServer(*outDir); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	binary, err := filepath.Abs(binary)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if _, err := os.Stat(binary); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	rep := report{Binary: binary}
+	for _, s := range toRun {
+		rep.Results = append(rep.Results, runSuite(s, binary))
+	}
+
+	data, err := json.MarshalIndent(rep, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error encoding report: %v\n", err)
+		os.Exit(1)
+	}
+	reportPath := filepath.Join(*outDir, "report.json")
+	if err := os.WriteFile(reportPath, append(data, '\n'), 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", reportPath, err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("\nSDK test results against %s:\n", binary)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SDK\tSTATUS\tTIME\tDETAILS")
+	failed := false
+	for _, r := range rep.Results {
+		details := r.Reason
+		if details == "" {
+			details = r.LogFile
+		}
+		fmt.Fprintf(w, "%s\t%s\t%.1fs\t%s\n", r.SDK, r.Status, r.Seconds, details)
+		failed = failed || r.Status == "FAIL"
+	}
+	w.Flush()
+	fmt.Printf("Report written to %s.\n", reportPath)
+	if failed {
+		os.Exit(1)
+	}
+}
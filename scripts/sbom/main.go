@@ -0,0 +1,345 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"crypto/sha256"
+	"debug/buildinfo"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// --- General Project Configuration ---
+const (
+	githubOwner = "google"
+	githubRepo  = "test-server"
+	projectName = "test-server"
+)
+
+var (
+	binaryPath = flag.String("binary", "", "Path to a built test-server binary (built from source when empty)")
+	outDir     = flag.String("out", "dist/sbom", "Directory to write the SBOM files to")
+	upload     = flag.Bool("upload", false, "Attach the SBOMs to the GitHub release with `gh release upload`")
+)
+
+// component is the format-neutral description of one SBOM entry.
+type component struct {
+	Name    string
+	Version string
+	PURL    string
+}
+
+// subject is one artifact an SBOM is produced for.
+type subject struct {
+	Name       string // used in the output file names
+	Root       component
+	Components []component
+}
+
+func goSubject(version, binary string) (subject, error) {
+	if binary == "" {
+		tmp, err := os.MkdirTemp("", "sbom-")
+		if err != nil {
+			return subject{}, err
+		}
+		defer os.RemoveAll(tmp)
+		binary = filepath.Join(tmp, projectName)
+		cmd := exec.Command("go", "build", "-trimpath", "-o", binary, ".")
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			return subject{}, fmt.Errorf("failed to build %s: %w", projectName, err)
+		}
+	}
+	info, err := buildinfo.ReadFile(binary)
+	if err != nil {
+		return subject{}, fmt.Errorf("failed to read build info from %s: %w", binary, err)
+	}
+
+	s := subject{
+		Name: projectName,
+		Root: component{Name: info.Main.Path, Version: version, PURL: fmt.Sprintf("pkg:golang/%s@%s", info.Main.Path, version)},
+	}
+	for _, dep := range info.Deps {
+		if dep.Replace != nil {
+			dep = dep.Replace
+		}
+		s.Components = append(s.Components, component{
+			Name:    dep.Path,
+			Version: dep.Version,
+			PURL:    fmt.Sprintf("pkg:golang/%s@%s", dep.Path, dep.Version),
+		})
+	}
+	s.Components = append(s.Components, component{
+		Name:    "stdlib",
+		Version: info.GoVersion,
+		PURL:    fmt.Sprintf("pkg:golang/stdlib@%s", info.GoVersion),
+	})
+	return s, nil
+}
+
+func typescriptSubject() (subject, error) {
+	var pkg struct {
+		Name         string            `json:"name"`
+		Version      string            `json:"version"`
+		Dependencies map[string]string `json:"dependencies"`
+	}
+	data, err := os.ReadFile("sdks/typescript/package.json")
+	if err != nil {
+		return subject{}, err
+	}
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		return subject{}, fmt.Errorf("failed to parse package.json: %w", err)
+	}
+	s := subject{
+		Name: "sdk-typescript",
+		Root: component{Name: pkg.Name, Version: pkg.Version, PURL: fmt.Sprintf("pkg:npm/%s@%s", pkg.Name, pkg.Version)},
+	}
+	for name, version := range pkg.Dependencies {
+		s.Components = append(s.Components, component{Name: name, Version: version, PURL: fmt.Sprintf("pkg:npm/%s@%s", name, strings.TrimLeft(version, "^~"))})
+	}
+	return s, nil
+}
+
+var (
+	pyNameRe    = regexp.MustCompile(`(?m)^name\s*=\s*"([^"]+)"`)
+	pyVersionRe = regexp.MustCompile(`(?m)^version\s*=\s*"([^"]+)"`)
+	pyDepsRe    = regexp.MustCompile(`(?s)\ndependencies\s*=\s*\[(.*?)\]`)
+	pyDepRe     = regexp.MustCompile(`"([A-Za-z0-9_.\-]+)\s*([^"]*)"`)
+)
+
+func pythonSubject() (subject, error) {
+	data, err := os.ReadFile("sdks/python/pyproject.toml")
+	if err != nil {
+		return subject{}, err
+	}
+	content := string(data)
+	name, version := firstGroup(pyNameRe, content), firstGroup(pyVersionRe, content)
+	s := subject{
+		Name: "sdk-python",
+		Root: component{Name: name, Version: version, PURL: fmt.Sprintf("pkg:pypi/%s@%s", name, version)},
+	}
+	if m := pyDepsRe.FindStringSubmatch(content); m != nil {
+		for _, dep := range pyDepRe.FindAllStringSubmatch(m[1], -1) {
+			s.Components = append(s.Components, component{Name: dep[1], Version: dep[2], PURL: "pkg:pypi/" + strings.ToLower(dep[1])})
+		}
+	}
+	return s, nil
+}
+
+func dotnetSubject() (subject, error) {
+	data, err := os.ReadFile("sdks/dotnet/TestServerSdk.csproj")
+	if err != nil {
+		return subject{}, err
+	}
+	var proj struct {
+		PropertyGroups []struct {
+			PackageVersion string `xml:"PackageVersion"`
+		} `xml:"PropertyGroup"`
+		ItemGroups []struct {
+			References []struct {
+				Include string `xml:"Include,attr"`
+				Version string `xml:"Version,attr"`
+			} `xml:"PackageReference"`
+		} `xml:"ItemGroup"`
+	}
+	if err := xml.Unmarshal(data, &proj); err != nil {
+		return subject{}, fmt.Errorf("failed to parse TestServerSdk.csproj: %w", err)
+	}
+	version := ""
+	for _, pg := range proj.PropertyGroups {
+		if pg.PackageVersion != "" {
+			version = pg.PackageVersion
+		}
+	}
+	s := subject{
+		Name: "sdk-dotnet",
+		Root: component{Name: "TestServerSdk", Version: version, PURL: "pkg:nuget/TestServerSdk@" + version},
+	}
+	for _, ig := range proj.ItemGroups {
+		for _, ref := range ig.References {
+			s.Components = append(s.Components, component{Name: ref.Include, Version: ref.Version, PURL: fmt.Sprintf("pkg:nuget/%s@%s", ref.Include, ref.Version)})
+		}
+	}
+	return s, nil
+}
+
+func firstGroup(re *regexp.Regexp, s string) string {
+	if m := re.FindStringSubmatch(s); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+func cycloneDX(s subject, timestamp string) any {
+	toCDX := func(c component, typ string) map[string]any {
+		return map[string]any{
+			"type":    typ,
+			"bom-ref": c.PURL,
+			"name":    c.Name,
+			"version": c.Version,
+			"purl":    c.PURL,
+		}
+	}
+	var components []map[string]any
+	var dependsOn []string
+	for _, c := range s.Components {
+		components = append(components, toCDX(c, "library"))
+		dependsOn = append(dependsOn, c.PURL)
+	}
+	return map[string]any{
+		"bomFormat":   "CycloneDX",
+		"specVersion": "1.5",
+		"version":     1,
+		"metadata": map[string]any{
+			"timestamp": timestamp,
+			"tools":     map[string]any{"components": []map[string]any{{"type": "application", "name": "scripts/sbom"}}},
+			"component": toCDX(s.Root, "application"),
+		},
+		"components":   components,
+		"dependencies": []map[string]any{{"ref": s.Root.PURL, "dependsOn": dependsOn}},
+	}
+}
+
+func spdx(s subject, timestamp string) any {
+	spdxID := func(c component) string {
+		sum := sha256.Sum256([]byte(c.PURL))
+		return "SPDXRef-Package-" + hex.EncodeToString(sum[:8])
+	}
+	pkg := func(c component) map[string]any {
+		return map[string]any{
+			"SPDXID":           spdxID(c),
+			"name":             c.Name,
+			"versionInfo":      c.Version,
+			"downloadLocation": "NOASSERTION",
+			"filesAnalyzed":    false,
+			"externalRefs": []map[string]any{{
+				"referenceCategory": "PACKAGE-MANAGER",
+				"referenceType":     "purl",
+				"referenceLocator":  c.PURL,
+			}},
+		}
+	}
+	packages := []map[string]any{pkg(s.Root)}
+	relationships := []map[string]any{{
+		"spdxElementId":      "SPDXRef-DOCUMENT",
+		"relationshipType":   "DESCRIBES",
+		"relatedSpdxElement": spdxID(s.Root),
+	}}
+	for _, c := range s.Components {
+		packages = append(packages, pkg(c))
+		relationships = append(relationships, map[string]any{
+			"spdxElementId":      spdxID(s.Root),
+			"relationshipType":   "DEPENDS_ON",
+			"relatedSpdxElement": spdxID(c),
+		})
+	}
+	return map[string]any{
+		"spdxVersion":       "SPDX-2.3",
+		"dataLicense":       "CC0-1.0",
+		"SPDXID":            "SPDXRef-DOCUMENT",
+		"name":              fmt.Sprintf("%s-%s", s.Root.Name, s.Root.Version),
+		"documentNamespace": fmt.Sprintf("https://github.com/%s/%s/sbom/%s-%s", githubOwner, githubRepo, s.Name, s.Root.Version),
+		"creationInfo": map[string]any{
+			"created":  timestamp,
+			"creators": []string{"Tool: scripts/sbom"},
+		},
+		"packages":      packages,
+		"relationships": relationships,
+	}
+}
+
+func writeJSON(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: go run ./scripts/sbom [--binary path] [--out dir] [--upload] <version_tag>")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	version := flag.Arg(0)
+	if !strings.HasPrefix(version, "v") {
+		fmt.Fprintln(os.Stderr, "Error: version_tag must start with 'v' (e.g., v0.1.0)")
+		os.Exit(1)
+	}
+
+	server, err := goSubject(version, *binaryPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	subjects := []subject{server}
+	for _, load := range []func() (subject, error){typescriptSubject, pythonSubject, dotnetSubject} {
+		s, err := load()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		subjects = append(subjects, s)
+	}
+
+	if err := os.MkdirAll(*outDir, 0755); err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating %s: %v\n", *outDir, err)
+		os.Exit(1)
+	}
+	timestamp := time.Now().UTC().Format(time.RFC3339)
+	var written []string
+	for _, s := range subjects {
+		sort.Slice(s.Components, func(i, j int) bool { return s.Components[i].Name < s.Components[j].Name })
+		base := filepath.Join(*outDir, fmt.Sprintf("%s_%s", s.Name, strings.TrimPrefix(version, "v")))
+		for path, doc := range map[string]any{
+			base + ".cdx.json":  cycloneDX(s, timestamp),
+			base + ".spdx.json": spdx(s, timestamp),
+		} {
+			if err := writeJSON(path, doc); err != nil {
+				fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", path, err)
+				os.Exit(1)
+			}
+			fmt.Printf("Wrote %s (%d components).\n", path, len(s.Components))
+			written = append(written, path)
+		}
+	}
+
+	if *upload {
+		args := append([]string{"release", "upload", version, "--clobber"}, written...)
+		cmd := exec.Command("gh", args...)
+		cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+		if err := cmd.Run(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error uploading SBOMs to release %s: %v\n", version, err)
+			os.Exit(1)
+		}
+		fmt.Printf("Attached %d SBOM files to release %s.\n", len(written), version)
+	}
+}
@@ -0,0 +1,245 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// --- General Project Configuration ---
+const (
+	projectName = "test-server"
+	noticesFile = "THIRD_PARTY_NOTICES"
+)
+
+var (
+	outPath = flag.String("out", noticesFile, "File to write the notices to")
+	check   = flag.Bool("check", false, "Fail if the notices file is out of date instead of writing it")
+)
+
+// allowedLicenses are the licenses that may be linked into the release
+// binary. Anything else needs legal review before it is added here.
+var allowedLicenses = map[string]bool{
+	"Apache-2.0":   true,
+	"BSD-2-Clause": true,
+	"BSD-3-Clause": true,
+	"ISC":          true,
+	"MIT":          true,
+	"MPL-2.0":      true,
+}
+
+type module struct {
+	Path    string
+	Version string
+	Dir     string
+}
+
+type component struct {
+	name     string
+	licenses []string
+	files    []noticeFile
+}
+
+type noticeFile struct {
+	name string
+	text string
+}
+
+// binaryModules lists the modules whose packages are linked into the
+// test-server binary, excluding the main module and the standard library.
+func binaryModules() ([]module, error) {
+	out, err := exec.Command("go", "list", "-deps", "-f", "{{with .Module}}{{if not .Main}}{{.Path}}\t{{.Version}}\t{{.Dir}}{{end}}{{end}}", ".").Output()
+	if err != nil {
+		if ee, ok := err.(*exec.ExitError); ok {
+			return nil, fmt.Errorf("go list failed: %s", strings.TrimSpace(string(ee.Stderr)))
+		}
+		return nil, fmt.Errorf("go list failed: %w", err)
+	}
+	seen := map[string]bool{}
+	var mods []module
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) != 3 || seen[fields[0]] {
+			continue
+		}
+		if fields[2] == "" {
+			return nil, fmt.Errorf("module %s@%s is not downloaded; run 'go mod download' first", fields[0], fields[1])
+		}
+		seen[fields[0]] = true
+		mods = append(mods, module{Path: fields[0], Version: fields[1], Dir: fields[2]})
+	}
+	sort.Slice(mods, func(i, j int) bool { return mods[i].Path < mods[j].Path })
+	return mods, scanner.Err()
+}
+
+// isLicenseFile reports whether name holds license terms, as opposed to
+// other legal notices (NOTICE, PATENTS) that are reproduced as-is.
+func isLicenseFile(name string) bool {
+	upper := strings.ToUpper(name)
+	return strings.HasPrefix(upper, "LICENSE") || strings.HasPrefix(upper, "LICENCE") || strings.HasPrefix(upper, "COPYING")
+}
+
+func isNoticeFile(name string) bool {
+	upper := strings.ToUpper(name)
+	return isLicenseFile(name) || strings.HasPrefix(upper, "NOTICE") || upper == "PATENTS"
+}
+
+// identifyLicense returns the SPDX identifier of a license text, or "" if
+// it is not one of the common permissive licenses.
+func identifyLicense(text string) string {
+	normalized := strings.Join(strings.Fields(text), " ")
+	switch {
+	case strings.Contains(normalized, "GNU"):
+		return ""
+	case strings.Contains(normalized, "Apache License") && strings.Contains(normalized, "Version 2.0"):
+		return "Apache-2.0"
+	case strings.Contains(normalized, "Mozilla Public License Version 2.0"):
+		return "MPL-2.0"
+	case strings.Contains(normalized, "Permission is hereby granted, free of charge"):
+		return "MIT"
+	case strings.Contains(normalized, "Permission to use, copy, modify, and/or distribute this software"):
+		return "ISC"
+	case strings.Contains(normalized, "Redistribution and use in source and binary forms"):
+		if strings.Contains(normalized, "Neither the name") || strings.Contains(normalized, "names of its contributors") {
+			return "BSD-3-Clause"
+		}
+		return "BSD-2-Clause"
+	}
+	return ""
+}
+
+// collect reads the license and notice files at the root of dir.
+func collect(name, dir string) (component, error) {
+	c := component{name: name}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return c, err
+	}
+	seen := map[string]bool{}
+	for _, e := range entries {
+		if e.IsDir() || !isNoticeFile(e.Name()) {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return c, err
+		}
+		c.files = append(c.files, noticeFile{name: e.Name(), text: strings.TrimSpace(string(data))})
+		if !isLicenseFile(e.Name()) {
+			continue
+		}
+		id := identifyLicense(string(data))
+		if id == "" {
+			return c, fmt.Errorf("%s: could not identify the license in %s", name, e.Name())
+		}
+		if !allowedLicenses[id] {
+			return c, fmt.Errorf("%s: license %s is not on the allowed list", name, id)
+		}
+		if !seen[id] {
+			seen[id] = true
+			c.licenses = append(c.licenses, id)
+		}
+	}
+	if len(c.licenses) == 0 {
+		return c, fmt.Errorf("%s: no license file found in %s", name, dir)
+	}
+	return c, nil
+}
+
+func render(components []component) []byte {
+	var b strings.Builder
+	rule := strings.Repeat("=", 80)
+	fmt.Fprintln(&b, "THIRD-PARTY SOFTWARE NOTICES")
+	fmt.Fprintln(&b)
+	fmt.Fprintf(&b, "%s is distributed under the Apache License 2.0 (see LICENSE). It includes\n", projectName)
+	fmt.Fprintln(&b, "the following third-party software, whose license terms are reproduced below.")
+	fmt.Fprintln(&b, "This file is generated by scripts/third-party-notices; do not edit it by hand.")
+	fmt.Fprintln(&b)
+	for _, c := range components {
+		fmt.Fprintf(&b, "  %s (%s)\n", c.name, strings.Join(c.licenses, " AND "))
+	}
+	for _, c := range components {
+		fmt.Fprintf(&b, "\n%s\n%s\nLicense: %s\n%s\n", rule, c.name, strings.Join(c.licenses, " AND "), rule)
+		for _, f := range c.files {
+			fmt.Fprintf(&b, "\n--- %s ---\n\n%s\n", f.name, f.text)
+		}
+	}
+	return []byte(b.String())
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: go run ./scripts/third-party-notices [--out file] [--check]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	mods, err := binaryModules()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	goroot, err := exec.Command("go", "env", "GOROOT").Output()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error running go env GOROOT: %v\n", err)
+		os.Exit(1)
+	}
+
+	// The Go runtime and standard library are linked into every binary.
+	std, err := collect("Go standard library", strings.TrimSpace(string(goroot)))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	components := []component{std}
+	for _, m := range mods {
+		c, err := collect(m.Path+" "+m.Version, m.Dir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		components = append(components, c)
+	}
+	notices := render(components)
+
+	if *check {
+		current, err := os.ReadFile(*outPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", *outPath, err)
+			os.Exit(1)
+		}
+		if !bytes.Equal(current, notices) {
+			fmt.Fprintf(os.Stderr, "Error: %s is out of date; run 'go run ./scripts/third-party-notices'.\n", *outPath)
+			os.Exit(1)
+		}
+		fmt.Printf("%s is up to date (%d components).\n", *outPath, len(components))
+		return
+	}
+	if err := os.WriteFile(*outPath, notices, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", *outPath, err)
+		os.Exit(1)
+	}
+	fmt.Printf("Wrote %s with %d components.\n", *outPath, len(components))
+}
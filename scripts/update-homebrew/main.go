@@ -0,0 +1,244 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/google/test-server/internal/checksumsjson"
+	"github.com/google/test-server/internal/yank"
+)
+
+// --- General Project Configuration ---
+const (
+	githubOwner = "google"
+	githubRepo  = "test-server"
+	projectName = "test-server"
+
+	// checksumsJSONPath is the canonical record of released archive digests.
+	checksumsJSONPath = "sdks/typescript/checksums.json"
+	formulaPath       = "Formula/test-server.rb"
+)
+
+var (
+	tapRepo = flag.String("tap", "google/homebrew-tap", "GitHub repository of the Homebrew tap")
+	dryRun  = flag.Bool("dry-run", false, "Print the formula instead of opening a PR against the tap")
+)
+
+// formulaPlatforms lists the archives the formula installs from, keyed by the
+// Homebrew OS block and CPU check they belong to.
+var formulaPlatforms = []struct {
+	OS      string // on_macos / on_linux
+	CPU     string // Hardware::CPU predicate
+	Archive string // archive name suffix after the project name
+}{
+	{"on_macos", "arm?", "Darwin_arm64.tar.gz"},
+	{"on_macos", "intel?", "Darwin_x86_64.tar.gz"},
+	{"on_linux", "arm?", "Linux_arm64.tar.gz"},
+	{"on_linux", "intel?", "Linux_x86_64.tar.gz"},
+}
+
+type formulaAsset struct {
+	CPU    string
+	URL    string
+	SHA256 string
+}
+
+type formulaData struct {
+	Version  string
+	Homepage string
+	MacOS    []formulaAsset
+	Linux    []formulaAsset
+}
+
+var formulaTemplate = template.Must(template.New("formula").Parse(`# typed: false
+# frozen_string_literal: true
+
+# This file is generated by scripts/update-homebrew in {{.Homepage}}.
+# DO NOT EDIT.
+class TestServer < Formula
+  desc "Lightweight record-replay reverse proxy for software testing"
+  homepage "{{.Homepage}}"
+  version "{{.Version}}"
+  license "Apache-2.0"
+
+  on_macos do
+{{- range .MacOS}}
+    if Hardware::CPU.{{.CPU}}
+      url "{{.URL}}"
+      sha256 "{{.SHA256}}"
+    end
+{{- end}}
+  end
+
+  on_linux do
+{{- range .Linux}}
+    if Hardware::CPU.{{.CPU}}
+      url "{{.URL}}"
+      sha256 "{{.SHA256}}"
+    end
+{{- end}}
+  end
+
+  def install
+    bin.install "test-server"
+    # Archives of releases before the completions and man pages were added
+    # ship only the binary.
+    if Dir.exist?("completions")
+      bash_completion.install "completions/test-server.bash" => "test-server"
+      zsh_completion.install "completions/_test-server"
+      fish_completion.install "completions/test-server.fish"
+    end
+    man1.install Dir["manpages/*.1"]
+  end
+
+  test do
+    assert_match version.to_s, shell_output("#{bin}/test-server --version")
+  end
+end
+`))
+
+func loadChecksums(version string) (map[string]string, error) {
+	all, err := checksumsjson.LoadCurrent(checksumsJSONPath)
+	if err != nil {
+		return nil, err
+	}
+	checksums, ok := all.Digests(version)
+	if !ok {
+		return nil, fmt.Errorf("%s has no entry for %s; run update-sdk-checksums first", checksumsJSONPath, version)
+	}
+	return checksums, nil
+}
+
+func renderFormula(version string, checksums map[string]string) (string, error) {
+	data := formulaData{
+		Version:  strings.TrimPrefix(version, "v"),
+		Homepage: fmt.Sprintf("https://github.com/%s/%s", githubOwner, githubRepo),
+	}
+	for _, p := range formulaPlatforms {
+		archive := fmt.Sprintf("%s_%s", projectName, p.Archive)
+		sum, ok := checksums[archive]
+		if !ok {
+			return "", fmt.Errorf("no checksum for %s in %s", archive, version)
+		}
+		asset := formulaAsset{
+			CPU:    p.CPU,
+			URL:    fmt.Sprintf("https://github.com/%s/%s/releases/download/%s/%s", githubOwner, githubRepo, version, archive),
+			SHA256: sum,
+		}
+		if p.OS == "on_macos" {
+			data.MacOS = append(data.MacOS, asset)
+		} else {
+			data.Linux = append(data.Linux, asset)
+		}
+	}
+	var b strings.Builder
+	if err := formulaTemplate.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+func run(dir, name string, args ...string) error {
+	cmd := exec.Command(name, args...)
+	cmd.Dir = dir
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	return cmd.Run()
+}
+
+func openTapPR(version, formula string) error {
+	workDir, err := os.MkdirTemp("", "homebrew-tap-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(workDir)
+
+	if err := run(".", "gh", "repo", "clone", *tapRepo, workDir, "--", "--depth=1"); err != nil {
+		return fmt.Errorf("failed to clone %s: %w", *tapRepo, err)
+	}
+	target := filepath.Join(workDir, formulaPath)
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(target, []byte(formula), 0644); err != nil {
+		return err
+	}
+
+	branch := fmt.Sprintf("%s-%s", projectName, version)
+	title := fmt.Sprintf("%s %s", projectName, strings.TrimPrefix(version, "v"))
+	cmds := [][]string{
+		{"git", "checkout", "-B", branch},
+		{"git", "add", formulaPath},
+		{"git", "commit", "-m", title},
+		{"git", "push", "--force", "origin", branch},
+		{"gh", "pr", "create", "--head", branch, "--title", title,
+			"--body", fmt.Sprintf("Updates the formula to %s/%s %s.", githubOwner, githubRepo, version)},
+	}
+	for _, c := range cmds {
+		if err := run(workDir, c[0], c[1:]...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: go run ./scripts/update-homebrew [--tap owner/repo] [--dry-run] <version_tag>")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	version := flag.Arg(0)
+	if !strings.HasPrefix(version, "v") {
+		fmt.Fprintln(os.Stderr, "Error: version_tag must start with 'v' (e.g., v0.1.0)")
+		os.Exit(1)
+	}
+	if err := yank.CheckVersion(version); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	checksums, err := loadChecksums(version)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	formula, err := renderFormula(version, checksums)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error rendering formula: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *dryRun {
+		fmt.Print(formula)
+		return
+	}
+	if err := openTapPR(version, formula); err != nil {
+		fmt.Fprintf(os.Stderr, "Error updating %s: %v\n", *tapRepo, err)
+		os.Exit(1)
+	}
+	fmt.Printf("Opened formula update PR for %s against %s.\n", version, *tapRepo)
+}
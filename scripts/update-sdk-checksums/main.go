@@ -18,6 +18,7 @@ package main
 import (
 	"bufio"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"io"
 	"net/http"
@@ -25,24 +26,48 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
+
+	"github.com/google/test-server/internal/checksumsjson"
+	"github.com/google/test-server/internal/nightly"
+	"github.com/google/test-server/internal/platforms"
+	"github.com/google/test-server/internal/provenance"
+	"github.com/google/test-server/internal/releaserepo"
+	"github.com/google/test-server/internal/sdkconstants"
+	"github.com/google/test-server/internal/verify"
+	"github.com/google/test-server/internal/yank"
 )
 
 // --- General Project Configuration ---
 const (
-	githubOwner = "google"
-	githubRepo  = "test-server"
 	projectName = "test-server"
 )
 
+// repo is the repository the release is downloaded from; see releaserepo.EnvVar.
+var repo = releaserepo.Production
+
+var (
+	verifyProvenance = flag.Bool("verify-provenance", false, "Verify the release provenance before trusting its checksums")
+	builderID        = flag.String("builder-id", provenance.DefaultBuilderID, "Trusted builder identity used with --verify-provenance")
+	cosignKey        = flag.String("cosign-key", "", "Cosign public key used to verify the signature of the checksums file")
+	channel          = flag.String("channel", "", "Publish the version on a pre-release channel (e.g. beta, rc) instead of pinning it in the SDKs")
+	checksumsFile    = flag.String("checksums-file", "", "Read the checksums from this local file (e.g. written by scripts/gen-checksums) instead of the release; its signature, if checked, is read from <file>.sig")
+)
+
+// channelsKey is the checksums.json entry mapping a pre-release channel to the
+// version the SDK installers resolve it to, e.g. {"beta": "v0.3.0-beta.1"}.
+const channelsKey = "channels"
+
+var channelNameRe = regexp.MustCompile(`^[a-z][a-z0-9-]*$`)
+
 // --- SDK Specific Configurations ---
 
 // SDKConfig holds the unique properties for each SDK that needs updating.
+// The pinned version itself lives in sdks/constants.json; see
+// internal/sdkconstants.
 type SDKConfig struct {
-	Name              string   // e.g., "TypeScript", "Python"
-	SDKDir            string   // Relative path to the SDK's directory
-	InstallScriptFile []string // A list of files to update with the new version
-	ChecksumsJSONFile string   // e.g., "checksums.json"
-	VersionVarName    string   // The name of the version constant/variable in the install script
+	Name              string // e.g., "TypeScript", "Python"
+	SDKDir            string // Relative path to the SDK's directory
+	ChecksumsJSONFile string // e.g., "checksums.json"
 }
 
 // sdksToUpdate is the list of all SDKs this script should manage.
@@ -51,32 +76,72 @@ var sdksToUpdate = []SDKConfig{
 	{
 		Name:              "TypeScript",
 		SDKDir:            "sdks/typescript",
-		InstallScriptFile: []string{"postinstall.js"},
 		ChecksumsJSONFile: "checksums.json",
-		VersionVarName:    "TEST_SERVER_VERSION",
 	},
 	{
 		Name:              "Python",
 		SDKDir:            "sdks/python/src/test_server_sdk",
-		InstallScriptFile: []string{"install.py"},
 		ChecksumsJSONFile: "checksums.json",
-		VersionVarName:    "TEST_SERVER_VERSION",
 	},
 	{
 		Name:              "Dotnet",
 		SDKDir:            "sdks/dotnet",
-		InstallScriptFile: []string{"BinaryInstaller.cs", "TestServerSdk.cs", "tools/installer/Program.cs"},
 		ChecksumsJSONFile: "checksums.json",
-		VersionVarName:    "TEST_SERVER_VERSION",
 	},
 }
 
+// verifyChecksumsSignature downloads the cosign signature of the checksums
+// file, or reads it next to --checksums-file, and verifies it with the public
+// key at keyPath.
+func verifyChecksumsSignature(version, checksumsText, keyPath string) error {
+	keyPEM, err := os.ReadFile(keyPath)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", keyPath, err)
+	}
+	pub, err := verify.ParsePublicKey(keyPEM)
+	if err != nil {
+		return err
+	}
+	if *checksumsFile != "" {
+		sigPath := verify.SignatureName(*checksumsFile)
+		sig, err := os.ReadFile(sigPath)
+		if err != nil {
+			return fmt.Errorf("failed to read signature: %w", err)
+		}
+		return verify.Blob(pub, strings.NewReader(checksumsText), sig)
+	}
+	checksumsFileName := fmt.Sprintf("%s_%s_checksums.txt", projectName, strings.TrimPrefix(version, "v"))
+	sigURL := repo.DownloadURL(version, verify.SignatureName(checksumsFileName))
+	fmt.Printf("Downloading signature from %s...\n", sigURL)
+	resp, err := http.Get(sigURL)
+	if err != nil {
+		return fmt.Errorf("failed to download signature from %s: %w", sigURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to download signature: status %s", resp.Status)
+	}
+	sig, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read signature: %w", err)
+	}
+	return verify.Blob(pub, strings.NewReader(checksumsText), sig)
+}
+
 func fetchChecksumsTxt(version string) (string, error) {
+	if *checksumsFile != "" {
+		fmt.Printf("Reading checksums file %s...\n", *checksumsFile)
+		data, err := os.ReadFile(*checksumsFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read checksums file: %w", err)
+		}
+		return string(data), nil
+	}
 	// The version in the checksums.txt filename typically does not have the 'v' prefix.
 	versionForFileName := strings.TrimPrefix(version, "v")
 	checksumsFileName := fmt.Sprintf("%s_%s_checksums.txt", projectName, versionForFileName)
 	// The version in the download URL (tag) does have the 'v' prefix.
-	checksumsURL := fmt.Sprintf("https://github.com/%s/%s/releases/download/%s/%s", githubOwner, githubRepo, version, checksumsFileName)
+	checksumsURL := repo.DownloadURL(version, checksumsFileName)
 	fmt.Printf("Downloading checksums file from %s...\n", checksumsURL)
 
 	resp, err := http.Get(checksumsURL)
@@ -97,6 +162,44 @@ func fetchChecksumsTxt(version string) (string, error) {
 	return string(body), nil
 }
 
+// verifyReleaseProvenance downloads the provenance attached to the release and
+// checks it is signed with the release key (--cosign-key, else the committed
+// cosign.pub) and was produced by the trusted builder for exactly these
+// checksums.
+func verifyReleaseProvenance(version string, checksums map[string]string) error {
+	keyPath := *cosignKey
+	if keyPath == "" {
+		keyPath = verify.KeyFile
+	}
+	keyPEM, err := os.ReadFile(keyPath)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", keyPath, err)
+	}
+	pub, err := verify.ParsePublicKey(keyPEM)
+	if err != nil {
+		return err
+	}
+	url := repo.DownloadURL(version, provenance.FileName(projectName, version))
+	fmt.Printf("Downloading provenance from %s...\n", url)
+	resp, err := http.Get(url)
+	if err != nil {
+		return fmt.Errorf("failed to download provenance from %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to download provenance: status %s", resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read provenance: %w", err)
+	}
+	stmt, err := provenance.Open(data, pub)
+	if err != nil {
+		return err
+	}
+	return provenance.Verify(stmt, *builderID, version, checksums)
+}
+
 func parseChecksumsTxt(checksumsText string) (map[string]string, error) {
 	checksums := make(map[string]string)
 	scanner := bufio.NewScanner(strings.NewReader(checksumsText))
@@ -122,7 +225,7 @@ func parseChecksumsTxt(checksumsText string) (map[string]string, error) {
 	return checksums, nil
 }
 
-func updateChecksumsJSON(checksumsJSONPath, newVersion string, newChecksumsMap map[string]string) error {
+func updateChecksumsJSON(checksumsJSONPath, newVersion, channel string, newChecksumsMap map[string]string, yanked yank.List) error {
 	allChecksums := make(map[string]map[string]string) // Reset if unmarshal fails
 
 	if _, err := os.Stat(checksumsJSONPath); err == nil {
@@ -141,58 +244,134 @@ func updateChecksumsJSON(checksumsJSONPath, newVersion string, newChecksumsMap m
 	}
 
 	allChecksums[newVersion] = newChecksumsMap
-	updatedJSON, err := json.MarshalIndent(allChecksums, "", "  ")
+	if channel != "" {
+		if allChecksums[channelsKey] == nil {
+			allChecksums[channelsKey] = make(map[string]string)
+		}
+		allChecksums[channelsKey][channel] = newVersion
+	}
+	// The installers refuse yanked versions; keep their list in step with yanked.json.
+	delete(allChecksums, yank.ChecksumsKey)
+	if len(yanked) > 0 {
+		allChecksums[yank.ChecksumsKey] = yanked.Replacements()
+	}
+	// Files written by older tools are brought to the current schema.
+	doc := checksumsjson.Document(allChecksums)
+	if _, err := checksumsjson.Migrate(doc, checksumsjson.Current); err != nil {
+		return fmt.Errorf("%s: %w", checksumsJSONPath, err)
+	}
+	updatedJSON, err := doc.Marshal()
 	if err != nil {
 		return fmt.Errorf("failed to marshal updated checksums JSON: %w", err)
 	}
 
-	updatedJSON = append(updatedJSON, '\n')
-
 	err = os.WriteFile(checksumsJSONPath, updatedJSON, 0644)
 	if err != nil {
 		return fmt.Errorf("failed to write updated %s: %w", checksumsJSONPath, err)
 	}
 	fmt.Printf("Updated %s with checksums for version %s.\n", checksumsJSONPath, newVersion)
+	if channel != "" {
+		fmt.Printf("Pointed the %s channel in %s to %s.\n", channel, checksumsJSONPath, newVersion)
+	}
 	return nil
 }
 
-func updateVersionInFile(filePath, newVersion, varName string) error {
-	content, err := os.ReadFile(filePath)
-	if err != nil {
-		return fmt.Errorf("failed to read %s: %w", filePath, err)
+// checkPlatformArchives fails when the release lacks an archive for any
+// platform in platforms.json, since the SDK installers would fail there.
+func checkPlatformArchives(matrix *platforms.Matrix, checksums map[string]string) error {
+	var missing []string
+	for _, archive := range matrix.ArchiveNames(projectName) {
+		if _, ok := checksums[archive]; !ok {
+			missing = append(missing, archive)
+		}
 	}
-
-	re := regexp.MustCompile(fmt.Sprintf(`(?m)(^\s*.*\b%s\b\s*=\s*['"]).*?(['"].*$)`, varName))
-
-	if !re.Match(content) {
-		// If the variable isn't in the file, it's not an error. Just skip it.
-		fmt.Printf("Note: Did not find '%s' in %s, skipping update for this file.\n", varName, filePath)
-		return nil
+	if len(missing) > 0 {
+		return fmt.Errorf("checksums.txt has no entry for %s", strings.Join(missing, ", "))
 	}
+	return nil
+}
 
-	replacement := []byte(fmt.Sprintf(`${1}%s${2}`, newVersion))
-
-	updatedContent := re.ReplaceAll(content, replacement)
+// copyPlatformsJSON ships the platform matrix next to the SDK's
+// checksums.json, where its installer reads it from.
+func copyPlatformsJSON(sdkDir string) error {
+	data, err := os.ReadFile(platforms.File)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", platforms.File, err)
+	}
+	dst := filepath.Join(sdkDir, platforms.File)
+	if err := os.WriteFile(dst, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", dst, err)
+	}
+	return nil
+}
 
-	err = os.WriteFile(filePath, updatedContent, 0644)
+// pinVersion sets the version the installers download by default and
+// regenerates the SDK constants from it.
+func pinVersion(newVersion string) error {
+	c, err := sdkconstants.Load(sdkconstants.File)
 	if err != nil {
-		return fmt.Errorf("failed to write updated %s: %w", filePath, err)
+		return err
+	}
+	c.TestServerVersion = newVersion
+	if err := c.Save(sdkconstants.File); err != nil {
+		return fmt.Errorf("failed to write %s: %w", sdkconstants.File, err)
+	}
+	written, err := sdkconstants.Write(c)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Pinned %s in %s.\n", newVersion, sdkconstants.File)
+	for _, path := range written {
+		fmt.Printf("Regenerated %s.\n", path)
 	}
-	fmt.Printf("Updated %s in %s to %s.\n", varName, filePath, newVersion)
 	return nil
 }
 
 func main() {
-	if len(os.Args) < 2 {
-		fmt.Fprintln(os.Stderr, "Usage: go run scripts/update-sdk-checksums/main.go <version_tag>")
+	flag.Parse()
+	if flag.NArg() < 1 {
+		fmt.Fprintln(os.Stderr, "Usage: go run scripts/update-sdk-checksums/main.go [--verify-provenance] [--cosign-key cosign.pub] [--channel name] [--checksums-file file] <version_tag>")
 		fmt.Fprintln(os.Stderr, "Example: go run scripts/update-sdk-checksums/main.go v0.1.0")
 		os.Exit(1)
 	}
-	newVersion := os.Args[1]
+	newVersion := flag.Arg(0)
 	if !strings.HasPrefix(newVersion, "v") {
 		fmt.Fprintln(os.Stderr, "Error: version_tag must start with 'v' (e.g., v0.1.0)")
 		os.Exit(1)
 	}
+	var err error
+	if repo, err = releaserepo.FromEnv(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if repo.Staging() {
+		fmt.Printf("Using the staging release repository %s.\n", repo)
+	}
+	if *checksumsFile != "" && *verifyProvenance {
+		fmt.Fprintln(os.Stderr, "Error: --verify-provenance checks a published release; it cannot be combined with --checksums-file")
+		os.Exit(1)
+	}
+	if *channel != "" && (!channelNameRe.MatchString(*channel) || *channel == "stable") {
+		fmt.Fprintf(os.Stderr, "Error: invalid channel %q; stable releases are published without --channel\n", *channel)
+		os.Exit(1)
+	}
+	// Nightly snapshots expire within days, so they never go into the SDKs;
+	// the installers resolve the nightly channel from nightly.json instead.
+	if *channel == nightly.Channel || nightly.IsSnapshot(newVersion) {
+		fmt.Fprintf(os.Stderr, "Error: nightly snapshots are published by scripts/nightly to %s, not added to the SDKs\n", nightly.ManifestName)
+		os.Exit(1)
+	}
+
+	yanked, err := yank.Load(yank.File)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := yanked.Check(newVersion); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Yanked versions are never added to the SDKs; publish %s instead.\n", yanked[newVersion].Replacement)
+		os.Exit(1)
+	}
 
 	fmt.Printf("Fetching checksums for test-server version: %s\n", newVersion)
 	checksumsText, err := fetchChecksumsTxt(newVersion)
@@ -201,37 +380,63 @@ func main() {
 		os.Exit(1)
 	}
 
+	if *cosignKey != "" {
+		if err := verifyChecksumsSignature(newVersion, checksumsText, *cosignKey); err != nil {
+			fmt.Fprintf(os.Stderr, "\nError verifying checksums.txt signature: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println("Checksums signature verified.")
+	}
+
 	newChecksumsMap, err := parseChecksumsTxt(checksumsText)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "\nError parsing checksums.txt: %v\n", err)
 		os.Exit(1)
 	}
 
+	matrix, err := platforms.Load(platforms.File)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "\nError: %v\n", err)
+		os.Exit(1)
+	}
+	if err := checkPlatformArchives(matrix, newChecksumsMap); err != nil {
+		fmt.Fprintf(os.Stderr, "\nError: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *verifyProvenance {
+		if err := verifyReleaseProvenance(newVersion, newChecksumsMap); err != nil {
+			fmt.Fprintf(os.Stderr, "\nError verifying provenance: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println("Provenance verified.")
+	}
+
 	var failedSDKs []string
 
 	for _, sdk := range sdksToUpdate {
 		fmt.Printf("\n--- Updating %s SDK ---\n", sdk.Name)
 
 		sdkChecksumsJSONPath := filepath.Join(sdk.SDKDir, sdk.ChecksumsJSONFile)
-		if err := updateChecksumsJSON(sdkChecksumsJSONPath, newVersion, newChecksumsMap); err != nil {
+		if err := updateChecksumsJSON(sdkChecksumsJSONPath, newVersion, *channel, newChecksumsMap, yanked); err != nil {
 			fmt.Fprintf(os.Stderr, "Error updating %s: %v\n", sdkChecksumsJSONPath, err)
 			failedSDKs = append(failedSDKs, sdk.Name)
 			continue
 		}
 
-		var sdkScriptUpdateFailed bool
-		for _, scriptFile := range sdk.InstallScriptFile {
-			sdkInstallScriptPath := filepath.Join(sdk.SDKDir, scriptFile)
-			if err := updateVersionInFile(sdkInstallScriptPath, newVersion, sdk.VersionVarName); err != nil {
-				fmt.Fprintf(os.Stderr, "Error updating %s: %v\n", sdkInstallScriptPath, err)
-				sdkScriptUpdateFailed = true
-				break
-			}
+		if err := copyPlatformsJSON(sdk.SDKDir); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			failedSDKs = append(failedSDKs, sdk.Name)
+			continue
 		}
+	}
 
-		if sdkScriptUpdateFailed {
-			failedSDKs = append(failedSDKs, sdk.Name)
-			continue // Move to the next SDK
+	// Channel releases are opt-in; the pinned stable version stays as is.
+	if *channel == "" && len(failedSDKs) == 0 {
+		fmt.Println("\n--- Pinning the SDK version ---")
+		if err := pinVersion(newVersion); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
 		}
 	}
 
@@ -0,0 +1,254 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/test-server/internal/githubapi"
+	"github.com/google/test-server/internal/releaserepo"
+)
+
+// --- General Project Configuration ---
+const (
+	projectName = "test-server"
+)
+
+var (
+	distDir  = flag.String("dir", "dist", "Directory whose test-server_* files are uploaded when no files are given")
+	parallel = flag.Int("parallel", 3, "Number of concurrent uploads")
+	retries  = flag.Int("retries", 5, "Attempts per asset before giving up")
+	timeout  = flag.Duration("timeout", 30*time.Minute, "Timeout for a single upload attempt")
+)
+
+type localFile struct {
+	Path   string
+	Name   string
+	Size   int64
+	SHA256 string
+}
+
+// downloadDigest fetches the uploaded asset back and hashes it; used when the
+// API does not report a digest for the asset.
+func downloadDigest(c *githubapi.Client, asset githubapi.Asset) (string, error) {
+	h := sha256.New()
+	if err := c.DownloadAsset(asset, h); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// matches reports whether the remote asset is a complete upload of f.
+func matches(c *githubapi.Client, asset githubapi.Asset, f localFile) (bool, error) {
+	if asset.State != "uploaded" || asset.Size != f.Size {
+		return false, nil
+	}
+	if digest, ok := strings.CutPrefix(asset.Digest, "sha256:"); ok {
+		return digest == f.SHA256, nil
+	}
+	digest, err := downloadDigest(c, asset)
+	if err != nil {
+		return false, err
+	}
+	return digest == f.SHA256, nil
+}
+
+// syncAsset makes sure f is on the release: assets that are already complete
+// are kept, partial or stale ones are replaced and every upload is verified.
+func syncAsset(c *githubapi.Client, releaseID int64, existing *githubapi.Asset, f localFile) (string, error) {
+	if existing != nil {
+		ok, err := matches(c, *existing, f)
+		if err != nil {
+			return "", fmt.Errorf("checking existing asset: %w", err)
+		}
+		if ok {
+			return "already uploaded", nil
+		}
+		if err := c.DeleteAsset(existing.ID); err != nil {
+			return "", fmt.Errorf("removing stale asset: %w", err)
+		}
+	}
+
+	var lastErr error
+	for attempt := 1; attempt <= *retries; attempt++ {
+		if attempt > 1 {
+			backoff := time.Duration(1<<(attempt-2)) * 2 * time.Second
+			fmt.Printf("%s: attempt %d/%d failed (%v); retrying in %s\n", f.Name, attempt-1, *retries, lastErr, backoff)
+			time.Sleep(backoff)
+			// A failed upload can leave a partial asset behind that blocks the retry.
+			if assets, err := c.Assets(releaseID); err == nil {
+				for _, partial := range assets {
+					if partial.Name == f.Name {
+						c.DeleteAsset(partial.ID)
+					}
+				}
+			}
+		}
+		asset, err := uploadFile(c, releaseID, f)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		ok, err := matches(c, *asset, f)
+		if err != nil {
+			lastErr = fmt.Errorf("verifying upload: %w", err)
+			continue
+		}
+		if !ok {
+			lastErr = fmt.Errorf("uploaded asset does not match local file (size %d, digest %q)", asset.Size, asset.Digest)
+			continue
+		}
+		return "uploaded", nil
+	}
+	return "", lastErr
+}
+
+func uploadFile(c *githubapi.Client, releaseID int64, f localFile) (*githubapi.Asset, error) {
+	file, err := os.Open(f.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return c.UploadAsset(releaseID, f.Name, file, f.Size)
+}
+
+func hashFile(path string) (localFile, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return localFile{}, err
+	}
+	defer file.Close()
+	h := sha256.New()
+	n, err := io.Copy(h, file)
+	if err != nil {
+		return localFile{}, err
+	}
+	return localFile{Path: path, Name: filepath.Base(path), Size: n, SHA256: hex.EncodeToString(h.Sum(nil))}, nil
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: go run ./scripts/upload-assets [--dir dist] [--parallel n] [--retries n] <version_tag> [file...]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	version := flag.Arg(0)
+	if !strings.HasPrefix(version, "v") {
+		fmt.Fprintln(os.Stderr, "Error: version_tag must start with 'v' (e.g., v0.1.0)")
+		os.Exit(1)
+	}
+
+	paths := flag.Args()[1:]
+	if len(paths) == 0 {
+		matches, err := filepath.Glob(filepath.Join(*distDir, projectName+"_*"))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error listing %s: %v\n", *distDir, err)
+			os.Exit(1)
+		}
+		paths = matches
+	}
+	if len(paths) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: no files to upload in %s\n", *distDir)
+		os.Exit(1)
+	}
+	var files []localFile
+	for _, p := range paths {
+		f, err := hashFile(p)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", p, err)
+			os.Exit(1)
+		}
+		files = append(files, f)
+	}
+
+	repo, err := releaserepo.FromEnv()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	c := githubapi.New(repo)
+	if c.Token == "" {
+		fmt.Fprintln(os.Stderr, "Error: set GITHUB_TOKEN or log in with `gh auth login`")
+		os.Exit(1)
+	}
+	c.HTTP.Timeout = *timeout
+	rel, err := c.FindRelease(version)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if !rel.Draft {
+		fmt.Printf("Warning: release %s is already published.\n", version)
+	}
+	assets, err := c.Assets(rel.ID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing assets: %v\n", err)
+		os.Exit(1)
+	}
+	existing := make(map[string]githubapi.Asset)
+	for _, a := range assets {
+		existing[a.Name] = a
+	}
+
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		failed []string
+	)
+	sem := make(chan struct{}, max(*parallel, 1))
+	for _, f := range files {
+		var prev *githubapi.Asset
+		if a, ok := existing[f.Name]; ok {
+			prev = &a
+		}
+		wg.Add(1)
+		go func(f localFile, prev *githubapi.Asset) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			status, err := syncAsset(c, rel.ID, prev, f)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				fmt.Printf("FAIL %s: %v\n", f.Name, err)
+				failed = append(failed, f.Name)
+				return
+			}
+			fmt.Printf("OK   %s (%d bytes): %s\n", f.Name, f.Size, status)
+		}(f, prev)
+	}
+	wg.Wait()
+
+	if len(failed) > 0 {
+		fmt.Fprintf(os.Stderr, "Error: %d of %d assets failed to upload: %s\n", len(failed), len(files), strings.Join(failed, ", "))
+		fmt.Fprintln(os.Stderr, "Re-run the same command to resume; completed assets are skipped.")
+		os.Exit(1)
+	}
+	fmt.Printf("All %d assets are on release %s.\n", len(files), version)
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/google/test-server/internal/provenance"
+	"github.com/google/test-server/internal/verify"
+)
+
+// --- General Project Configuration ---
+const (
+	githubOwner = "google"
+	githubRepo  = "test-server"
+	projectName = "test-server"
+)
+
+var (
+	builderID      = flag.String("builder-id", provenance.DefaultBuilderID, "Trusted builder identity")
+	cosignKey      = flag.String("cosign-key", verify.KeyFile, "Cosign public key the provenance must be signed with")
+	provenanceFile = flag.String("provenance", "", "Local provenance file (downloaded from the release when empty)")
+	checksumsFile  = flag.String("checksums", "", "Local checksums.txt (downloaded from the release when empty)")
+)
+
+func fetchAsset(version, asset string) ([]byte, error) {
+	url := fmt.Sprintf("https://github.com/%s/%s/releases/download/%s/%s", githubOwner, githubRepo, version, asset)
+	fmt.Printf("Downloading %s...\n", url)
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download %s: status %s", url, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func load(localPath, version, asset string) ([]byte, error) {
+	if localPath != "" {
+		return os.ReadFile(localPath)
+	}
+	return fetchAsset(version, asset)
+}
+
+func parseChecksums(text string) map[string]string {
+	checksums := make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for scanner.Scan() {
+		parts := strings.Fields(scanner.Text())
+		if len(parts) == 2 {
+			checksums[parts[1]] = parts[0]
+		}
+	}
+	return checksums
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: go run ./scripts/verify-provenance [--builder-id id] [--cosign-key cosign.pub] [--provenance file] [--checksums file] <version_tag>")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	version := flag.Arg(0)
+
+	checksumsText, err := load(*checksumsFile, version, fmt.Sprintf("%s_%s_checksums.txt", projectName, strings.TrimPrefix(version, "v")))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading checksums: %v\n", err)
+		os.Exit(1)
+	}
+	provenanceData, err := load(*provenanceFile, version, provenance.FileName(projectName, version))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading provenance: %v\n", err)
+		os.Exit(1)
+	}
+
+	pemBytes, err := os.ReadFile(*cosignKey)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	pub, err := verify.ParsePublicKey(pemBytes)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	stmt, err := provenance.Open(provenanceData, pub)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Provenance verification failed for %s: %v\n", version, err)
+		os.Exit(1)
+	}
+	checksums := parseChecksums(string(checksumsText))
+	if err := provenance.Verify(stmt, *builderID, version, checksums); err != nil {
+		fmt.Fprintf(os.Stderr, "Provenance verification failed for %s: %v\n", version, err)
+		os.Exit(1)
+	}
+	fmt.Printf("Provenance for %s verified: %d archives built by %s.\n", version, len(checksums), stmt.Predicate.RunDetails.Builder.ID)
+}
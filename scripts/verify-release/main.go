@@ -0,0 +1,432 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/google/test-server/internal/githubapi"
+	"github.com/google/test-server/internal/platforms"
+	"github.com/google/test-server/internal/releaserepo"
+	"github.com/google/test-server/internal/verify"
+)
+
+// --- General Project Configuration ---
+const (
+	projectName = "test-server"
+)
+
+// repo is the repository the assets are downloaded from (see releaserepo.EnvVar).
+var repo = releaserepo.Production
+
+// client downloads the release assets of repo.
+var client *githubapi.Client
+
+var (
+	cosignKey = flag.String("cosign-key", "", "Cosign public key; when set, every archive and the checksums file must carry a valid signature")
+	localDir  = flag.String("dir", "", "Verify the archives, checksums file and signatures in this local directory (e.g. a goreleaser dist/) instead of the release")
+)
+
+// result is one row of the verification matrix.
+type result struct {
+	Archive   string
+	Download  string
+	Checksum  string
+	Signature string
+	Extract   string
+	Run       string
+}
+
+func (r result) failed() bool {
+	for _, s := range []string{r.Download, r.Checksum, r.Signature, r.Extract, r.Run} {
+		if strings.HasPrefix(s, "FAIL") {
+			return true
+		}
+	}
+	return false
+}
+
+func downloadURL(version, asset string) string {
+	return repo.DownloadURL(version, asset)
+}
+
+// fetchAsset returns the release asset name, read from --dir when set.
+func fetchAsset(version, name string) ([]byte, error) {
+	if *localDir != "" {
+		return os.ReadFile(filepath.Join(*localDir, name))
+	}
+	return client.Download(version, name)
+}
+
+// verifySignature fetches the cosign signature of asset and checks it
+// against blob.
+func verifySignature(pub *ecdsa.PublicKey, version, asset string, blob io.Reader) error {
+	sig, err := fetchAsset(version, verify.SignatureName(asset))
+	if err != nil {
+		return err
+	}
+	return verify.Blob(pub, blob, sig)
+}
+
+func fetchChecksums(version string, pub *ecdsa.PublicKey) (map[string]string, error) {
+	checksumsFileName := fmt.Sprintf("%s_%s_checksums.txt", projectName, strings.TrimPrefix(version, "v"))
+	if *localDir != "" {
+		fmt.Printf("Reading checksums file %s from %s...\n", checksumsFileName, *localDir)
+	} else {
+		fmt.Printf("Downloading checksums file from %s...\n", downloadURL(version, checksumsFileName))
+	}
+	body, err := fetchAsset(version, checksumsFileName)
+	if err != nil {
+		return nil, err
+	}
+	if pub != nil {
+		if err := verifySignature(pub, version, checksumsFileName, bytes.NewReader(body)); err != nil {
+			return nil, fmt.Errorf("signature check of %s failed: %w", checksumsFileName, err)
+		}
+		fmt.Printf("Signature of %s verified.\n", checksumsFileName)
+	}
+
+	checksums := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		parts := strings.Fields(scanner.Text())
+		if len(parts) == 2 {
+			checksums[parts[1]] = parts[0]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(checksums) == 0 {
+		return nil, fmt.Errorf("no checksums found in %s", checksumsFileName)
+	}
+	return checksums, nil
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func extractTarGz(archive, dest string) error {
+	f, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		if err := writeFile(dest, hdr.Name, os.FileMode(hdr.Mode), tr); err != nil {
+			return err
+		}
+	}
+}
+
+func extractZip(archive, dest string) error {
+	zr, err := zip.OpenReader(archive)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+	for _, zf := range zr.File {
+		if zf.FileInfo().IsDir() {
+			continue
+		}
+		rc, err := zf.Open()
+		if err != nil {
+			return err
+		}
+		err = writeFile(dest, zf.Name, zf.Mode(), rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeFile(dest, name string, mode os.FileMode, r io.Reader) error {
+	target := filepath.Join(dest, name)
+	if !strings.HasPrefix(target, filepath.Clean(dest)+string(os.PathSeparator)) {
+		return fmt.Errorf("archive entry %s escapes the extraction directory", name)
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode|0600)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	_, err = io.Copy(out, r)
+	return err
+}
+
+// currentArchiveName returns the archive for the platform this script runs
+// on, or "" when the platform is not released.
+func currentArchiveName(matrix *platforms.Matrix) string {
+	p, ok := matrix.Find(runtime.GOOS, runtime.GOARCH)
+	if !ok {
+		return ""
+	}
+	return matrix.ArchiveName(projectName, p)
+}
+
+func verifyArchive(version, archive, expected, workDir, current string, pub *ecdsa.PublicKey) result {
+	res := result{Archive: archive, Checksum: "-", Signature: "-", Extract: "-", Run: "-"}
+	archivePath := filepath.Join(workDir, archive)
+	if *localDir != "" {
+		archivePath = filepath.Join(*localDir, archive)
+		if _, err := os.Stat(archivePath); err != nil {
+			res.Download = "FAIL: " + err.Error()
+			return res
+		}
+		res.Download = "local"
+	} else {
+		data, err := client.Download(version, archive)
+		if err == nil {
+			err = os.WriteFile(archivePath, data, 0644)
+		}
+		if err != nil {
+			res.Download = "FAIL: " + err.Error()
+			return res
+		}
+		res.Download = "ok"
+	}
+
+	actual, err := fileSHA256(archivePath)
+	if err != nil {
+		res.Checksum = "FAIL: " + err.Error()
+		return res
+	}
+	if actual != expected {
+		res.Checksum = fmt.Sprintf("FAIL: expected %s, got %s", expected, actual)
+		return res
+	}
+	res.Checksum = "ok"
+
+	if pub != nil {
+		f, err := os.Open(archivePath)
+		if err != nil {
+			res.Signature = "FAIL: " + err.Error()
+			return res
+		}
+		err = verifySignature(pub, version, archive, f)
+		f.Close()
+		if err != nil {
+			res.Signature = "FAIL: " + err.Error()
+			return res
+		}
+		res.Signature = "ok"
+	}
+
+	extractDir := filepath.Join(workDir, strings.TrimSuffix(strings.TrimSuffix(archive, ".zip"), ".tar.gz"))
+	switch {
+	case strings.HasSuffix(archive, ".tar.gz"):
+		err = extractTarGz(archivePath, extractDir)
+	case strings.HasSuffix(archive, ".zip"):
+		err = extractZip(archivePath, extractDir)
+	default:
+		err = fmt.Errorf("unknown archive format")
+	}
+	if err != nil {
+		res.Extract = "FAIL: " + err.Error()
+		return res
+	}
+	binaryName := projectName
+	if strings.HasSuffix(archive, ".zip") {
+		binaryName += ".exe"
+	}
+	binaryPath := filepath.Join(extractDir, binaryName)
+	if _, err := os.Stat(binaryPath); err != nil {
+		res.Extract = fmt.Sprintf("FAIL: %s not found in archive", binaryName)
+		return res
+	}
+	res.Extract = "ok"
+
+	if archive != current {
+		res.Run = "skipped (other platform)"
+		return res
+	}
+	out, err := exec.Command(binaryPath, "--version").CombinedOutput()
+	if err != nil {
+		res.Run = fmt.Sprintf("FAIL: %v: %s", err, strings.TrimSpace(string(out)))
+		return res
+	}
+	if !strings.Contains(string(out), strings.TrimPrefix(version, "v")) {
+		res.Run = fmt.Sprintf("FAIL: unexpected version output %q", strings.TrimSpace(string(out)))
+		return res
+	}
+	res.Run = "ok"
+	return res
+}
+
+func printMatrix(results []result) {
+	fmt.Printf("\n%-40s %-10s %-10s %-10s %-10s %s\n", "ARCHIVE", "DOWNLOAD", "CHECKSUM", "SIGNATURE", "EXTRACT", "RUN")
+	for _, r := range results {
+		fmt.Printf("%-40s %-10s %-10s %-10s %-10s %s\n", r.Archive, short(r.Download), short(r.Checksum), short(r.Signature), short(r.Extract), short(r.Run))
+	}
+	for _, r := range results {
+		for _, s := range []string{r.Download, r.Checksum, r.Signature, r.Extract, r.Run} {
+			if strings.HasPrefix(s, "FAIL: ") {
+				fmt.Printf("\n%s: %s", r.Archive, strings.TrimPrefix(s, "FAIL: "))
+			}
+		}
+	}
+	fmt.Println()
+}
+
+func short(s string) string {
+	if strings.HasPrefix(s, "FAIL") {
+		return "FAIL"
+	}
+	return s
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: go run ./scripts/verify-release [--cosign-key cosign.pub] [--dir dist] <version_tag>")
+		fmt.Fprintln(os.Stderr, "Example: go run ./scripts/verify-release v0.2.8")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	version := flag.Arg(0)
+	if !strings.HasPrefix(version, "v") {
+		fmt.Fprintln(os.Stderr, "Error: version_tag must start with 'v' (e.g., v0.1.0)")
+		os.Exit(1)
+	}
+	var err error
+	if repo, err = releaserepo.FromEnv(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if repo.Staging() {
+		fmt.Printf("Using the staging release repository %s.\n", repo)
+	}
+	client = githubapi.New(repo)
+
+	var pub *ecdsa.PublicKey
+	if *cosignKey != "" {
+		keyPEM, err := os.ReadFile(*cosignKey)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", *cosignKey, err)
+			os.Exit(1)
+		}
+		if pub, err = verify.ParsePublicKey(keyPEM); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	matrix, err := platforms.Load(platforms.File)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	checksums, err := fetchChecksums(version, pub)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	workDir, err := os.MkdirTemp("", "verify-release-")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating work directory: %v\n", err)
+		os.Exit(1)
+	}
+	defer os.RemoveAll(workDir)
+
+	// Every platform in the matrix must be released; archives in the checksums
+	// file beyond the matrix are verified as well.
+	archives := matrix.ArchiveNames(projectName)
+	var extra []string
+	for archive := range checksums {
+		if !slices.Contains(archives, archive) {
+			extra = append(extra, archive)
+		}
+	}
+	sort.Strings(extra)
+	archives = append(archives, extra...)
+	current := currentArchiveName(matrix)
+
+	var results []result
+	failed := false
+	for _, archive := range archives {
+		expected, ok := checksums[archive]
+		if !ok {
+			res := result{Archive: archive, Download: "FAIL: missing from checksums file", Checksum: "-", Signature: "-", Extract: "-", Run: "-"}
+			failed = true
+			results = append(results, res)
+			continue
+		}
+		fmt.Printf("Verifying %s...\n", archive)
+		res := verifyArchive(version, archive, expected, workDir, current, pub)
+		failed = failed || res.failed()
+		results = append(results, res)
+	}
+	printMatrix(results)
+
+	if failed {
+		os.RemoveAll(workDir)
+		fmt.Fprintln(os.Stderr, "Release verification failed.")
+		os.Exit(1)
+	}
+	fmt.Printf("All %d archives for %s verified.\n", len(results), version)
+}
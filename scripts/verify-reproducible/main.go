@@ -0,0 +1,329 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/google/test-server/internal/buildflags"
+	"github.com/google/test-server/internal/githubapi"
+	"github.com/google/test-server/internal/platforms"
+	"github.com/google/test-server/internal/releaserepo"
+)
+
+// --- General Project Configuration ---
+const (
+	projectName = "test-server"
+)
+
+// client downloads the release assets.
+var client *githubapi.Client
+
+var (
+	keep    = flag.Bool("keep", false, "Keep the work directory with the published and rebuilt binaries")
+	onlyOne = flag.String("platform", "", "Only check this goos/goarch")
+)
+
+// result is one row of the report.
+type result struct {
+	Platform  string
+	Published string
+	Rebuilt   string
+	Err       error
+	Diff      []string // differing `go version -m` lines
+}
+
+func fetchChecksums(version string) (map[string]string, error) {
+	name := fmt.Sprintf("%s_%s_checksums.txt", projectName, strings.TrimPrefix(version, "v"))
+	body, err := client.Download(version, name)
+	if err != nil {
+		return nil, err
+	}
+	checksums := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		parts := strings.Fields(scanner.Text())
+		if len(parts) == 2 {
+			checksums[parts[1]] = parts[0]
+		}
+	}
+	if len(checksums) == 0 {
+		return nil, fmt.Errorf("no checksums found in %s", name)
+	}
+	return checksums, nil
+}
+
+func extractBinary(archive string, data []byte, binaryName string) ([]byte, error) {
+	if strings.HasSuffix(archive, ".zip") {
+		zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return nil, err
+		}
+		for _, zf := range zr.File {
+			if zf.Name == binaryName {
+				rc, err := zf.Open()
+				if err != nil {
+					return nil, err
+				}
+				defer rc.Close()
+				return io.ReadAll(rc)
+			}
+		}
+		return nil, fmt.Errorf("%s not found in %s", binaryName, archive)
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("%s not found in %s", binaryName, archive)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Name == binaryName {
+			return io.ReadAll(tr)
+		}
+	}
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// buildInfo returns the `go version -m` lines of a binary: the toolchain,
+// module versions and build settings it was built with.
+func buildInfo(path string) []string {
+	out, err := exec.Command("go", "version", "-m", path).Output()
+	if err != nil {
+		return nil
+	}
+	var lines []string
+	for _, l := range strings.Split(string(out), "\n")[1:] {
+		if l = strings.TrimSpace(l); l != "" {
+			lines = append(lines, l)
+		}
+	}
+	return lines
+}
+
+// diffLines returns the lines of a and b that the other lacks, prefixed with
+// "published:" and "rebuilt:".
+func diffLines(a, b []string) []string {
+	inA, inB := map[string]bool{}, map[string]bool{}
+	for _, l := range a {
+		inA[l] = true
+	}
+	for _, l := range b {
+		inB[l] = true
+	}
+	var diff []string
+	for _, l := range a {
+		if !inB[l] {
+			diff = append(diff, "published: "+l)
+		}
+	}
+	for _, l := range b {
+		if !inA[l] {
+			diff = append(diff, "rebuilt:   "+l)
+		}
+	}
+	return diff
+}
+
+func check(version, srcDir, workDir string, matrix *platforms.Matrix, p platforms.Platform, checksums map[string]string) result {
+	res := result{Platform: p.GOOS + "/" + p.GOARCH, Published: "-", Rebuilt: "-"}
+	archive := matrix.ArchiveName(projectName, p)
+	binaryName := platforms.BinaryName(projectName, p)
+	dir := filepath.Join(workDir, p.GOOS+"_"+p.GOARCH)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		res.Err = err
+		return res
+	}
+
+	expected, ok := checksums[archive]
+	if !ok {
+		res.Err = fmt.Errorf("%s is not in the release checksums file", archive)
+		return res
+	}
+	data, err := client.Download(version, archive)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	if actual := sha256Hex(data); actual != expected {
+		res.Err = fmt.Errorf("checksum mismatch for %s: expected %s, got %s", archive, expected, actual)
+		return res
+	}
+	published, err := extractBinary(archive, data, binaryName)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	publishedPath := filepath.Join(dir, "published-"+binaryName)
+	if err := os.WriteFile(publishedPath, published, 0755); err != nil {
+		res.Err = err
+		return res
+	}
+	res.Published = sha256Hex(published)
+
+	rebuiltPath := filepath.Join(dir, "rebuilt-"+binaryName)
+	cmd := exec.Command("go", buildflags.Args(version, rebuiltPath)...)
+	cmd.Dir = srcDir
+	cmd.Env = append(os.Environ(), buildflags.Env(p.GOOS, p.GOARCH)...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		res.Err = fmt.Errorf("go build failed: %v\n%s", err, out)
+		return res
+	}
+	rebuilt, err := os.ReadFile(rebuiltPath)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	res.Rebuilt = sha256Hex(rebuilt)
+	if res.Rebuilt != res.Published {
+		res.Diff = diffLines(buildInfo(publishedPath), buildInfo(rebuiltPath))
+	}
+	return res
+}
+
+func short(digest string) string {
+	if len(digest) > 16 {
+		return digest[:16]
+	}
+	return digest
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: go run ./scripts/verify-reproducible [--platform goos/goarch] [--keep] <version_tag>")
+		fmt.Fprintln(os.Stderr, "Rebuilds the release binaries from the tagged source and compares them with the published archives.")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	client = githubapi.New(releaserepo.Production)
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	version := flag.Arg(0)
+	if !strings.HasPrefix(version, "v") {
+		fmt.Fprintln(os.Stderr, "Error: version_tag must start with 'v' (e.g., v0.1.0)")
+		os.Exit(1)
+	}
+
+	matrix, err := platforms.Load(platforms.File)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	selected := matrix.Platforms
+	if *onlyOne != "" {
+		goos, goarch, _ := strings.Cut(*onlyOne, "/")
+		p, ok := matrix.Find(goos, goarch)
+		if !ok {
+			fmt.Fprintf(os.Stderr, "Error: %s is not in %s\n", *onlyOne, platforms.File)
+			os.Exit(1)
+		}
+		selected = []platforms.Platform{p}
+	}
+	checksums, err := fetchChecksums(version)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	workDir, err := os.MkdirTemp("", "verify-reproducible-")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating work directory: %v\n", err)
+		os.Exit(1)
+	}
+	// A clean checkout of the tag, so that the VCS settings stamped into the
+	// binary (revision, time, modified=false) match the release build.
+	srcDir := filepath.Join(workDir, "src")
+	if out, err := exec.Command("git", "worktree", "add", "--detach", srcDir, version).CombinedOutput(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: git worktree add %s failed: %v\n%s", version, err, out)
+		os.Exit(1)
+	}
+
+	var results []result
+	for _, p := range selected {
+		fmt.Printf("Checking %s/%s...\n", p.GOOS, p.GOARCH)
+		results = append(results, check(version, srcDir, workDir, matrix, p, checksums))
+	}
+	exec.Command("git", "worktree", "remove", "--force", srcDir).Run()
+	if *keep {
+		fmt.Printf("Kept the binaries in %s.\n", workDir)
+	} else {
+		os.RemoveAll(workDir)
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "\nPLATFORM\tPUBLISHED\tREBUILT\tRESULT")
+	failed := 0
+	for _, r := range results {
+		status := "reproducible"
+		switch {
+		case r.Err != nil:
+			status = "ERROR"
+			failed++
+		case r.Rebuilt != r.Published:
+			status = "DIFFERS"
+			failed++
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", r.Platform, short(r.Published), short(r.Rebuilt), status)
+	}
+	tw.Flush()
+	for _, r := range results {
+		if r.Err != nil {
+			fmt.Printf("\n%s: %v\n", r.Platform, r.Err)
+		} else if r.Rebuilt != r.Published {
+			fmt.Printf("\n%s differs from the published binary.", r.Platform)
+			if len(r.Diff) == 0 {
+				fmt.Println(" The build info matches, so the difference is in the compiled code itself.")
+				continue
+			}
+			fmt.Println(" Build info differences:")
+			for _, l := range r.Diff {
+				fmt.Printf("  %s\n", l)
+			}
+		}
+	}
+	if failed > 0 {
+		fmt.Fprintf(os.Stderr, "\n%d of %d binaries of %s could not be reproduced.\n", failed, len(results), version)
+		os.Exit(1)
+	}
+	fmt.Printf("\nAll %d binaries of %s are reproducible.\n", len(results), version)
+}
@@ -0,0 +1,244 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/google/test-server/internal/checksumsjson"
+	"github.com/google/test-server/internal/platforms"
+	"github.com/google/test-server/internal/semver"
+	"github.com/google/test-server/internal/yank"
+)
+
+// --- General Project Configuration ---
+const (
+	githubOwner = "google"
+	githubRepo  = "test-server"
+	projectName = "test-server"
+
+	checksumsJSONPath = "sdks/typescript/checksums.json"
+	channelsKey       = "channels"
+	manifestName      = "versions.json"
+	schemaVersion     = 1
+)
+
+var (
+	outDir      = flag.String("out", "dist/pages", "Directory to write versions.json to")
+	yankedPath  = flag.String("yanked", yank.File, "JSON file listing the yanked versions with their reason and replacement (optional)")
+	ghPages     = flag.Bool("gh-pages", false, "Commit and push versions.json to the gh-pages branch")
+	bucket      = flag.String("bucket", "", "Also upload versions.json to this GCS bucket (gs://...)")
+	pagesBranch = flag.String("branch", "gh-pages", "Branch published by GitHub Pages")
+)
+
+type asset struct {
+	OS      string `json:"os"`
+	Arch    string `json:"arch"`
+	Archive string `json:"archive"`
+	URL     string `json:"url"`
+	SHA256  string `json:"sha256"`
+}
+
+type releaseEntry struct {
+	Version    string  `json:"version"`
+	Prerelease bool    `json:"prerelease"`
+	Yanked     bool    `json:"yanked"`
+	YankReason string  `json:"yank_reason,omitempty"`
+	ReplacedBy string  `json:"replaced_by,omitempty"`
+	Assets     []asset `json:"assets"`
+}
+
+// manifest is the published versions.json. Installers resolve "latest" or a
+// channel through it without calling the GitHub API.
+type manifest struct {
+	SchemaVersion int               `json:"schema_version"`
+	GeneratedAt   string            `json:"generated_at"`
+	Latest        string            `json:"latest"`
+	Channels      map[string]string `json:"channels"`
+	Releases      []releaseEntry    `json:"releases"`
+}
+
+func downloadURL(version, asset string) string {
+	return fmt.Sprintf("https://github.com/%s/%s/releases/download/%s/%s", githubOwner, githubRepo, version, asset)
+}
+
+func buildManifest(all checksumsjson.Document, matrix *platforms.Matrix, yanked yank.List) (*manifest, error) {
+	m := &manifest{
+		SchemaVersion: schemaVersion,
+		GeneratedAt:   time.Now().UTC().Format(time.RFC3339),
+		Channels:      map[string]string{},
+	}
+	type parsedRelease struct {
+		v       semver.Version
+		entries map[string]string
+		tag     string
+	}
+	var releases []parsedRelease
+	for _, tag := range all.Versions() {
+		entries := all[tag]
+		v, err := semver.Parse(tag)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", checksumsJSONPath, err)
+		}
+		releases = append(releases, parsedRelease{v, entries, tag})
+	}
+	sort.Slice(releases, func(i, j int) bool { return semver.Less(releases[j].v, releases[i].v) })
+
+	for _, r := range releases {
+		y, isYanked := yanked[r.tag]
+		e := releaseEntry{Version: r.tag, Prerelease: r.v.Prerelease(), Yanked: isYanked, YankReason: y.Reason, ReplacedBy: y.Replacement}
+		for _, p := range matrix.Platforms {
+			archive := matrix.ArchiveName(projectName, p)
+			digest, ok := r.entries[archive]
+			if !ok {
+				continue // platform added after this release
+			}
+			e.Assets = append(e.Assets, asset{OS: p.GOOS, Arch: p.GOARCH, Archive: archive, URL: downloadURL(r.tag, archive), SHA256: digest})
+		}
+		if m.Latest == "" && !e.Prerelease && !e.Yanked {
+			m.Latest = r.tag
+		}
+		m.Releases = append(m.Releases, e)
+	}
+	if m.Latest == "" {
+		return nil, fmt.Errorf("no stable, non-yanked release found")
+	}
+	m.Channels["stable"] = m.Latest
+	for channel, tag := range all[channelsKey] {
+		if _, ok := all[tag]; !ok {
+			return nil, fmt.Errorf("channel %s points to %s, which has no checksums", channel, tag)
+		}
+		if _, ok := yanked[tag]; ok {
+			fmt.Printf("Warning: channel %s points to yanked release %s; leaving it out.\n", channel, tag)
+			continue
+		}
+		m.Channels[channel] = tag
+	}
+	return m, nil
+}
+
+func run(dir, name string, args ...string) error {
+	cmd := exec.Command(name, args...)
+	cmd.Dir = dir
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s %v failed: %w", name, args, err)
+	}
+	return nil
+}
+
+// publishGHPages commits the manifest to the Pages branch in a temporary
+// worktree, leaving the current checkout untouched.
+func publishGHPages(data []byte) error {
+	workDir, err := os.MkdirTemp("", "versions-manifest-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(workDir)
+	if err := run(".", "git", "fetch", "origin", *pagesBranch); err != nil {
+		return err
+	}
+	if err := run(".", "git", "worktree", "add", "--detach", workDir, "origin/"+*pagesBranch); err != nil {
+		return err
+	}
+	defer run(".", "git", "worktree", "remove", "--force", workDir)
+
+	if err := os.WriteFile(filepath.Join(workDir, manifestName), data, 0644); err != nil {
+		return err
+	}
+	if err := run(workDir, "git", "add", manifestName); err != nil {
+		return err
+	}
+	if err := exec.Command("git", "-C", workDir, "diff", "--cached", "--quiet").Run(); err == nil {
+		fmt.Println("versions.json on gh-pages is already up to date.")
+		return nil
+	}
+	if err := run(workDir, "git", "commit", "-m", "Update versions.json"); err != nil {
+		return err
+	}
+	return run(workDir, "git", "push", "origin", "HEAD:"+*pagesBranch)
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: go run ./scripts/versions-manifest [--out dir] [--gh-pages] [--bucket gs://...]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	all, err := checksumsjson.LoadCurrent(checksumsJSONPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	matrix, err := platforms.Load(platforms.File)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	yanked, err := yank.Load(*yankedPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	m, err := buildManifest(all, matrix, yanked)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	out, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error encoding manifest: %v\n", err)
+		os.Exit(1)
+	}
+	out = append(out, '\n')
+
+	if err := os.MkdirAll(*outDir, 0755); err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating %s: %v\n", *outDir, err)
+		os.Exit(1)
+	}
+	path := filepath.Join(*outDir, manifestName)
+	if err := os.WriteFile(path, out, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", path, err)
+		os.Exit(1)
+	}
+	fmt.Printf("Wrote %s: %d releases, latest %s.\n", path, len(m.Releases), m.Latest)
+
+	if *ghPages {
+		if err := publishGHPages(out); err != nil {
+			fmt.Fprintf(os.Stderr, "Error publishing to %s: %v\n", *pagesBranch, err)
+			os.Exit(1)
+		}
+		fmt.Printf("Published %s to the %s branch.\n", manifestName, *pagesBranch)
+	}
+	if *bucket != "" {
+		cmd := exec.Command("gcloud", "storage", "cp", "--cache-control=no-cache", path, *bucket+"/"+manifestName)
+		cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+		if err := cmd.Run(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error uploading to %s: %v\n", *bucket, err)
+			os.Exit(1)
+		}
+		fmt.Printf("Uploaded %s to %s.\n", manifestName, *bucket)
+	}
+}
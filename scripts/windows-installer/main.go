@@ -0,0 +1,381 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/google/test-server/internal/checksumsjson"
+	"github.com/google/test-server/internal/githubapi"
+	"github.com/google/test-server/internal/platforms"
+	"github.com/google/test-server/internal/releaserepo"
+	"github.com/google/test-server/internal/semver"
+	"github.com/google/test-server/internal/yank"
+)
+
+// --- General Project Configuration ---
+const (
+	projectName = "test-server"
+	publisher   = "Google LLC"
+
+	// checksumsJSONPath is the canonical record of released archive digests.
+	checksumsJSONPath = "sdks/typescript/checksums.json"
+
+	// passwordEnv holds the password of the --pkcs12 code signing certificate.
+	passwordEnv = "WINDOWS_SIGNING_PASSWORD"
+)
+
+// repo is the repository the archives are downloaded from and the installers
+// uploaded to; see releaserepo.EnvVar.
+var repo = releaserepo.Production
+
+// client downloads the release assets of repo.
+var client *githubapi.Client
+
+var (
+	outDir       = flag.String("out", "dist/windows-installer", "Directory to write the installers to")
+	pkcs12       = flag.String("pkcs12", "", "Authenticode certificate and key (.pfx) to sign the binary and the installer with; the password is read from "+passwordEnv)
+	timestampURL = flag.String("timestamp-url", "http://timestamp.digicert.com", "RFC 3161 timestamp server used when signing")
+	unsigned     = flag.Bool("unsigned", false, "Build unsigned installers, for testing only")
+	upload       = flag.Bool("upload", false, "Attach the installers to the GitHub release")
+)
+
+// passwordFile holds the signing password for osslsigncode, which would show
+// it in the process list if passed with -pass.
+var passwordFile string
+
+// archiveFiles are installed next to the binary. Archives of older releases
+// may lack some of them.
+var archiveFiles = []string{"LICENSE", "README.md", "THIRD_PARTY_NOTICES"}
+
+// pathScript adds the install directory to the user's PATH, or removes it
+// with -Remove. It edits the raw registry value so that entries such as
+// %USERPROFILE%\bin stay unexpanded, then tells running programs (Explorer,
+// new terminals) that the environment changed.
+const pathScript = `param([Parameter(Mandatory = $true)][string]$Dir, [switch]$Remove)
+$key = Get-Item -Path 'HKCU:\Environment'
+$path = $key.GetValue('Path', '', 'DoNotExpandEnvironmentNames')
+$entries = @($path -split ';' | Where-Object { $_ -and $_.TrimEnd('\') -ne $Dir.TrimEnd('\') })
+if (-not $Remove) { $entries += $Dir }
+Set-ItemProperty -Path 'HKCU:\Environment' -Name 'Path' -Value ($entries -join ';') -Type ExpandString
+Add-Type -Namespace Win32 -Name Env -MemberDefinition @'
+[DllImport("user32.dll", CharSet = CharSet.Unicode)]
+public static extern IntPtr SendMessageTimeout(IntPtr hWnd, uint Msg, UIntPtr wParam, string lParam, uint fuFlags, uint uTimeout, out UIntPtr lpdwResult);
+'@
+$result = [UIntPtr]::Zero
+[Win32.Env]::SendMessageTimeout([IntPtr]0xffff, 0x1A, [UIntPtr]::Zero, 'Environment', 2, 5000, [ref]$result) | Out-Null
+`
+
+type nsisData struct {
+	Name        string
+	Version     string
+	FileVersion string
+	Publisher   string
+	URL         string
+	License     string
+	OutFile     string
+	Files       []string
+}
+
+// nsisTemplate installs per user, so that no administrator rights are
+// needed, and registers an uninstaller under "Apps & features".
+var nsisTemplate = template.Must(template.New("installer").Parse(`; Generated by scripts/windows-installer. DO NOT EDIT.
+Unicode true
+SetCompressor /SOLID lzma
+!define UNINSTALL_KEY "Software\Microsoft\Windows\CurrentVersion\Uninstall\{{.Name}}"
+
+Name "{{.Name}} {{.Version}}"
+OutFile "{{.OutFile}}"
+InstallDir "$LOCALAPPDATA\Programs\{{.Name}}"
+RequestExecutionLevel user
+ShowInstDetails show
+ShowUninstDetails show
+
+VIProductVersion "{{.FileVersion}}"
+VIAddVersionKey "ProductName" "{{.Name}}"
+VIAddVersionKey "CompanyName" "{{.Publisher}}"
+VIAddVersionKey "FileDescription" "{{.Name}} installer"
+VIAddVersionKey "FileVersion" "{{.Version}}"
+VIAddVersionKey "ProductVersion" "{{.Version}}"
+VIAddVersionKey "LegalCopyright" "Copyright {{.Publisher}}"
+
+LicenseData "{{.License}}"
+Page license
+Page directory
+Page instfiles
+UninstPage uninstConfirm
+UninstPage instfiles
+
+Section "Install"
+  SetOutPath "$INSTDIR"
+{{- range .Files}}
+  File "{{.}}"
+{{- end}}
+  File "path.ps1"
+  WriteUninstaller "$INSTDIR\uninstall.exe"
+  DetailPrint "Adding $INSTDIR to the user PATH"
+  nsExec::ExecToLog 'powershell.exe -NoProfile -ExecutionPolicy Bypass -File "$INSTDIR\path.ps1" -Dir "$INSTDIR"'
+  WriteRegStr HKCU "${UNINSTALL_KEY}" "DisplayName" "{{.Name}}"
+  WriteRegStr HKCU "${UNINSTALL_KEY}" "DisplayVersion" "{{.Version}}"
+  WriteRegStr HKCU "${UNINSTALL_KEY}" "Publisher" "{{.Publisher}}"
+  WriteRegStr HKCU "${UNINSTALL_KEY}" "URLInfoAbout" "{{.URL}}"
+  WriteRegStr HKCU "${UNINSTALL_KEY}" "InstallLocation" "$INSTDIR"
+  WriteRegStr HKCU "${UNINSTALL_KEY}" "UninstallString" '"$INSTDIR\uninstall.exe"'
+  WriteRegDWORD HKCU "${UNINSTALL_KEY}" "NoModify" 1
+  WriteRegDWORD HKCU "${UNINSTALL_KEY}" "NoRepair" 1
+SectionEnd
+
+Section "Uninstall"
+  DetailPrint "Removing $INSTDIR from the user PATH"
+  nsExec::ExecToLog 'powershell.exe -NoProfile -ExecutionPolicy Bypass -File "$INSTDIR\path.ps1" -Dir "$INSTDIR" -Remove'
+{{- range .Files}}
+  Delete "$INSTDIR\{{.}}"
+{{- end}}
+  Delete "$INSTDIR\path.ps1"
+  Delete "$INSTDIR\uninstall.exe"
+  RMDir "$INSTDIR"
+  DeleteRegKey HKCU "${UNINSTALL_KEY}"
+SectionEnd
+`))
+
+func loadChecksums(version string) (map[string]string, error) {
+	all, err := checksumsjson.LoadCurrent(checksumsJSONPath)
+	if err != nil {
+		return nil, err
+	}
+	checksums, ok := all.Digests(version)
+	if !ok {
+		return nil, fmt.Errorf("%s has no entry for %s; run update-sdk-checksums first", checksumsJSONPath, version)
+	}
+	return checksums, nil
+}
+
+// extractArchive downloads archive, verifies it against expected and writes
+// the binary and archiveFiles to dir. It returns the names it wrote.
+func extractArchive(version, archive, expected, binaryName, dir string) ([]string, error) {
+	fmt.Printf("Downloading %s from %s...\n", archive, repo)
+	data, err := client.Download(version, archive)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	if actual := hex.EncodeToString(sum[:]); actual != expected {
+		return nil, fmt.Errorf("checksum mismatch for %s: expected %s, got %s", archive, expected, actual)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", archive, err)
+	}
+	want := append([]string{binaryName}, archiveFiles...)
+	var written []string
+	for _, name := range want {
+		f, err := zr.Open(name)
+		if err != nil && name != binaryName {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s not found in %s", name, archive)
+		}
+		content, err := io.ReadAll(f)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), content, 0644); err != nil {
+			return nil, err
+		}
+		written = append(written, name)
+	}
+	return written, nil
+}
+
+func run(dir, name string, args ...string) error {
+	fmt.Printf("+ %s %s\n", name, strings.Join(args, " "))
+	cmd := exec.Command(name, args...)
+	cmd.Dir = dir
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s failed: %w", name, err)
+	}
+	return nil
+}
+
+// sign Authenticode-signs path in place.
+func sign(path, description string) error {
+	signed := path + ".signed"
+	err := run(".", "osslsigncode", "sign", "-pkcs12", *pkcs12, "-readpass", passwordFile,
+		"-n", description, "-i", "https://github.com/"+repo.String(), "-h", "sha256", "-ts", *timestampURL,
+		"-in", path, "-out", signed)
+	if err != nil {
+		return err
+	}
+	return os.Rename(signed, path)
+}
+
+// buildInstaller signs the binary of p, packs it with makensis and signs the
+// installer. It returns the installer's path.
+func buildInstaller(version string, ver semver.Version, matrix *platforms.Matrix, p platforms.Platform, checksums map[string]string) (string, error) {
+	archive := matrix.ArchiveName(projectName, p)
+	expected, ok := checksums[archive]
+	if !ok {
+		return "", fmt.Errorf("%s has no checksum for %s in %s", checksumsJSONPath, archive, version)
+	}
+	dir := filepath.Join(*outDir, p.Archive)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	binaryName := platforms.BinaryName(projectName, p)
+	files, err := extractArchive(version, archive, expected, binaryName, dir)
+	if err != nil {
+		return "", err
+	}
+	if !*unsigned {
+		if err := sign(filepath.Join(dir, binaryName), projectName); err != nil {
+			return "", err
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "path.ps1"), []byte(pathScript), 0644); err != nil {
+		return "", err
+	}
+
+	installer, err := filepath.Abs(filepath.Join(*outDir, platforms.InstallerName(projectName, version, p)))
+	if err != nil {
+		return "", err
+	}
+	license, err := filepath.Abs("LICENSE")
+	if err != nil {
+		return "", err
+	}
+	var script bytes.Buffer
+	err = nsisTemplate.Execute(&script, nsisData{
+		Name:    projectName,
+		Version: strings.TrimPrefix(version, "v"),
+		// Windows version resources are four dot-separated numbers.
+		FileVersion: fmt.Sprintf("%d.%d.%d.0", ver.Major, ver.Minor, ver.Patch),
+		Publisher:   publisher,
+		URL:         "https://github.com/" + repo.String(),
+		License:     license,
+		OutFile:     installer,
+		Files:       files,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to render the installer script: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "installer.nsi"), script.Bytes(), 0644); err != nil {
+		return "", err
+	}
+	if err := run(dir, "makensis", "-V2", "-INPUTCHARSET", "UTF8", "installer.nsi"); err != nil {
+		return "", err
+	}
+	if !*unsigned {
+		if err := sign(installer, projectName+" installer"); err != nil {
+			return "", err
+		}
+	}
+	return installer, nil
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: go run ./scripts/windows-installer (--pkcs12 cert.pfx | --unsigned) [--out dir] [--upload] <version_tag>")
+		fmt.Fprintln(os.Stderr, "Builds a self-extracting installer for every Windows platform from the release archives.")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 || (*pkcs12 == "") == !*unsigned {
+		flag.Usage()
+		os.Exit(1)
+	}
+	version := flag.Arg(0)
+	ver, err := semver.Parse(version)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if *upload && *unsigned {
+		fmt.Fprintln(os.Stderr, "Error: unsigned installers are never uploaded to a release")
+		os.Exit(1)
+	}
+	if err := yank.CheckVersion(version); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if repo, err = releaserepo.FromEnv(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	client = githubapi.New(repo)
+	if repo.Staging() {
+		fmt.Printf("Using the staging release repository %s.\n", repo)
+	}
+	if !*unsigned {
+		password := os.Getenv(passwordEnv)
+		if password == "" {
+			fmt.Fprintf(os.Stderr, "Error: %s must hold the password of %s\n", passwordEnv, *pkcs12)
+			os.Exit(1)
+		}
+		f, err := os.CreateTemp("", "signing-password-")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		defer os.Remove(f.Name())
+		f.WriteString(password)
+		f.Close()
+		passwordFile = f.Name()
+	}
+
+	matrix, err := platforms.Load(platforms.File)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	checksums, err := loadChecksums(version)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	var installers []string
+	for _, p := range matrix.Filter(func(p platforms.Platform) bool { return p.GOOS == "windows" }) {
+		installer, err := buildInstaller(version, ver, matrix, p, checksums)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error building the %s installer: %v\n", p.Archive, err)
+			os.Exit(1)
+		}
+		fmt.Printf("Built %s.\n", installer)
+		installers = append(installers, installer)
+	}
+
+	if *upload {
+		args := append([]string{"release", "upload", version, "--repo", repo.String(), "--clobber"}, installers...)
+		if err := run(".", "gh", args...); err != nil {
+			fmt.Fprintf(os.Stderr, "Error uploading the installers: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Attached %d installers to %s.\n", len(installers), version)
+	}
+}
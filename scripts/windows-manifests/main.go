@@ -0,0 +1,264 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/google/test-server/internal/checksumsjson"
+	"github.com/google/test-server/internal/yank"
+)
+
+// --- General Project Configuration ---
+const (
+	githubOwner = "google"
+	githubRepo  = "test-server"
+	projectName = "test-server"
+
+	wingetIdentifier = "Google.TestServer"
+	wingetPublisher  = "Google LLC"
+	manifestVersion  = "1.6.0"
+)
+
+var outDir = flag.String("out", "dist/windows", "Directory to write the Scoop and winget manifests to")
+
+// windowsArchives maps the Scoop and winget architecture names to the archive
+// suffix goreleaser produces for them.
+var windowsArchives = []struct {
+	Scoop   string
+	Winget  string
+	Archive string
+}{
+	{"64bit", "x64", "Windows_x86_64.zip"},
+	{"32bit", "x86", "Windows_i386.zip"},
+	{"arm64", "arm64", "Windows_arm64.zip"},
+}
+
+type scoopArch struct {
+	URL  string `json:"url"`
+	Hash any    `json:"hash"`
+}
+
+type scoopManifest struct {
+	Version      string               `json:"version"`
+	Description  string               `json:"description"`
+	Homepage     string               `json:"homepage"`
+	License      string               `json:"license"`
+	Architecture map[string]scoopArch `json:"architecture"`
+	Bin          string               `json:"bin"`
+	CheckVer     map[string]string    `json:"checkver"`
+	AutoUpdate   map[string]any       `json:"autoupdate"`
+}
+
+type wingetInstaller struct {
+	Arch   string
+	URL    string
+	SHA256 string
+}
+
+type wingetData struct {
+	Identifier      string
+	Version         string
+	Publisher       string
+	Homepage        string
+	ManifestVersion string
+	Installers      []wingetInstaller
+}
+
+var wingetTemplates = map[string]*template.Template{
+	"%s.yaml": template.Must(template.New("version").Parse(`# Generated by scripts/windows-manifests. DO NOT EDIT.
+PackageIdentifier: {{.Identifier}}
+PackageVersion: {{.Version}}
+DefaultLocale: en-US
+ManifestType: version
+ManifestVersion: {{.ManifestVersion}}
+`)),
+	"%s.installer.yaml": template.Must(template.New("installer").Parse(`# Generated by scripts/windows-manifests. DO NOT EDIT.
+PackageIdentifier: {{.Identifier}}
+PackageVersion: {{.Version}}
+InstallerType: zip
+NestedInstallerType: portable
+NestedInstallerFiles:
+  - RelativeFilePath: test-server.exe
+    PortableCommandAlias: test-server
+Installers:
+{{- range .Installers}}
+  - Architecture: {{.Arch}}
+    InstallerUrl: {{.URL}}
+    InstallerSha256: {{.SHA256}}
+{{- end}}
+ManifestType: installer
+ManifestVersion: {{.ManifestVersion}}
+`)),
+	"%s.locale.en-US.yaml": template.Must(template.New("locale").Parse(`# Generated by scripts/windows-manifests. DO NOT EDIT.
+PackageIdentifier: {{.Identifier}}
+PackageVersion: {{.Version}}
+PackageLocale: en-US
+Publisher: {{.Publisher}}
+PackageName: test-server
+PackageUrl: {{.Homepage}}
+License: Apache-2.0
+ShortDescription: Lightweight record-replay reverse proxy for software testing.
+ManifestType: defaultLocale
+ManifestVersion: {{.ManifestVersion}}
+`)),
+}
+
+func loadChecksums(version string) (map[string]string, error) {
+	// checksums.json is the canonical record of released archive digests.
+	d, err := checksumsjson.LoadCurrent(checksumsjson.Files[0])
+	if err != nil {
+		return nil, err
+	}
+	checksums, ok := d.Digests(version)
+	if !ok {
+		return nil, fmt.Errorf("%s has no entry for %s; run update-sdk-checksums first", checksumsjson.Files[0], version)
+	}
+	return checksums, nil
+}
+
+func releaseURL(version, archive string) string {
+	return fmt.Sprintf("https://github.com/%s/%s/releases/download/%s/%s", githubOwner, githubRepo, version, archive)
+}
+
+func writeScoop(version string, checksums map[string]string) (string, error) {
+	homepage := fmt.Sprintf("https://github.com/%s/%s", githubOwner, githubRepo)
+	manifest := scoopManifest{
+		Version:      strings.TrimPrefix(version, "v"),
+		Description:  "Lightweight record-replay reverse proxy for software testing.",
+		Homepage:     homepage,
+		License:      "Apache-2.0",
+		Architecture: map[string]scoopArch{},
+		Bin:          projectName + ".exe",
+		CheckVer:     map[string]string{"github": homepage},
+	}
+	autoUpdate := map[string]scoopArch{}
+	for _, w := range windowsArchives {
+		archive := fmt.Sprintf("%s_%s", projectName, w.Archive)
+		sum, ok := checksums[archive]
+		if !ok {
+			return "", fmt.Errorf("no checksum for %s in %s", archive, version)
+		}
+		manifest.Architecture[w.Scoop] = scoopArch{URL: releaseURL(version, archive), Hash: sum}
+		// Scoop looks the archive name up in the release checksums file.
+		autoUpdate[w.Scoop] = scoopArch{
+			URL:  releaseURL("v$version", archive),
+			Hash: map[string]string{"url": releaseURL("v$version", projectName+"_$version_checksums.txt")},
+		}
+	}
+	manifest.AutoUpdate = map[string]any{"architecture": autoUpdate}
+
+	data, err := json.MarshalIndent(manifest, "", "    ")
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(*outDir, "scoop", projectName+".json")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", err
+	}
+	return path, os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+func writeWinget(version string, checksums map[string]string) ([]string, error) {
+	data := wingetData{
+		Identifier:      wingetIdentifier,
+		Version:         strings.TrimPrefix(version, "v"),
+		Publisher:       wingetPublisher,
+		Homepage:        fmt.Sprintf("https://github.com/%s/%s", githubOwner, githubRepo),
+		ManifestVersion: manifestVersion,
+	}
+	for _, w := range windowsArchives {
+		archive := fmt.Sprintf("%s_%s", projectName, w.Archive)
+		sum, ok := checksums[archive]
+		if !ok {
+			return nil, fmt.Errorf("no checksum for %s in %s", archive, version)
+		}
+		data.Installers = append(data.Installers, wingetInstaller{Arch: w.Winget, URL: releaseURL(version, archive), SHA256: strings.ToUpper(sum)})
+	}
+
+	// winget-pkgs layout: manifests/<first letter>/<Publisher>/<Package>/<version>/
+	parts := strings.SplitN(wingetIdentifier, ".", 2)
+	dir := filepath.Join(*outDir, "winget", "manifests", strings.ToLower(parts[0][:1]), parts[0], parts[1], data.Version)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	var written []string
+	for nameFormat, tmpl := range wingetTemplates {
+		path := filepath.Join(dir, fmt.Sprintf(nameFormat, wingetIdentifier))
+		f, err := os.Create(path)
+		if err != nil {
+			return nil, err
+		}
+		err = tmpl.Execute(f, data)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to render %s: %w", path, err)
+		}
+		written = append(written, path)
+	}
+	return written, nil
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: go run ./scripts/windows-manifests [--out dir] <version_tag>")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	version := flag.Arg(0)
+	if !strings.HasPrefix(version, "v") {
+		fmt.Fprintln(os.Stderr, "Error: version_tag must start with 'v' (e.g., v0.1.0)")
+		os.Exit(1)
+	}
+	if err := yank.CheckVersion(version); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	checksums, err := loadChecksums(version)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	scoopPath, err := writeScoop(version, checksums)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing Scoop manifest: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Wrote Scoop manifest %s.\n", scoopPath)
+
+	wingetPaths, err := writeWinget(version, checksums)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing winget manifests: %v\n", err)
+		os.Exit(1)
+	}
+	for _, p := range wingetPaths {
+		fmt.Printf("Wrote winget manifest %s.\n", p)
+	}
+	fmt.Println("\nCopy the Scoop manifest into the bucket repository and submit the winget")
+	fmt.Println("manifests to microsoft/winget-pkgs (e.g. with `wingetcreate submit`).")
+}
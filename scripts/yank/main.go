@@ -0,0 +1,225 @@
+/*
+Copyright 2025 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/google/test-server/internal/checksumsjson"
+	"github.com/google/test-server/internal/sdkconstants"
+	"github.com/google/test-server/internal/semver"
+	"github.com/google/test-server/internal/yank"
+)
+
+// --- General Project Configuration ---
+const (
+	channelsKey = "channels"
+)
+
+// checksumsFiles are the per-SDK copies of checksums.json; the first one is
+// used to look up the released versions.
+var checksumsFiles = []string{
+	"sdks/typescript/checksums.json",
+	"sdks/python/src/test_server_sdk/checksums.json",
+	"sdks/dotnet/checksums.json",
+}
+
+var (
+	reason      = flag.String("reason", "", "Why the version is yanked (required unless --undo)")
+	replacement = flag.String("replacement", "", "Version users should install instead (default: the next newer stable release)")
+	undo        = flag.Bool("undo", false, "Remove the version from the yanked list")
+)
+
+func loadChecksums(path string) (map[string]map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	all := make(map[string]map[string]string)
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return all, nil
+}
+
+// releasedVersions returns the versions with checksums, newest first.
+func releasedVersions(all map[string]map[string]string) ([]semver.Version, error) {
+	var versions []semver.Version
+	for tag := range all {
+		if checksumsjson.IsReserved(tag) {
+			continue
+		}
+		v, err := semver.Parse(tag)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", checksumsFiles[0], err)
+		}
+		versions = append(versions, v)
+	}
+	sort.Slice(versions, func(i, j int) bool { return semver.Less(versions[j], versions[i]) })
+	return versions, nil
+}
+
+// defaultReplacement returns the oldest stable, not yanked release newer than
+// version.
+func defaultReplacement(released []semver.Version, yanked yank.List, version semver.Version) (string, error) {
+	var found string
+	for _, v := range released {
+		if !semver.Less(version, v) {
+			break
+		}
+		if _, ok := yanked[v.Tag()]; !ok && !v.Prerelease() {
+			found = v.Tag()
+		}
+	}
+	if found == "" {
+		return "", fmt.Errorf("no stable release newer than %s to replace it with; release a fix first or pass --replacement", version.Tag())
+	}
+	return found, nil
+}
+
+// pinnedVersion returns the version the SDK installers install by default.
+func pinnedVersion() (string, error) {
+	c, err := sdkconstants.Load(sdkconstants.File)
+	if err != nil {
+		return "", err
+	}
+	return c.TestServerVersion, nil
+}
+
+// writeChecksums sets the yanked entry of every checksums.json copy.
+func writeChecksums(yanked yank.List) error {
+	for _, path := range checksumsFiles {
+		all, err := loadChecksums(path)
+		if err != nil {
+			return err
+		}
+		delete(all, yank.ChecksumsKey)
+		if len(yanked) > 0 {
+			all[yank.ChecksumsKey] = yanked.Replacements()
+		}
+		data, err := json.MarshalIndent(all, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal %s: %w", path, err)
+		}
+		if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		fmt.Printf("Updated %s.\n", path)
+	}
+	return nil
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: go run ./scripts/yank --reason text [--replacement version_tag] <version_tag>")
+		fmt.Fprintln(os.Stderr, "       go run ./scripts/yank --undo <version_tag>")
+		fmt.Fprintln(os.Stderr, "Marks a release as yanked so the release tooling and the SDK installers refuse it.")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 || (!*undo && *reason == "") {
+		flag.Usage()
+		os.Exit(1)
+	}
+	version, err := semver.Parse(flag.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	tag := version.Tag()
+
+	yanked, err := yank.Load(yank.File)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	all, err := loadChecksums(checksumsFiles[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	released, err := releasedVersions(all)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *undo {
+		if _, ok := yanked[tag]; !ok {
+			fmt.Fprintf(os.Stderr, "Error: %s is not yanked\n", tag)
+			os.Exit(1)
+		}
+		delete(yanked, tag)
+	} else {
+		if _, ok := all[tag]; !ok {
+			fmt.Fprintf(os.Stderr, "Error: %s has no checksums in %s; only released versions can be yanked\n", tag, checksumsFiles[0])
+			os.Exit(1)
+		}
+		pinned, err := pinnedVersion()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if pinned == tag {
+			fmt.Fprintf(os.Stderr, "Error: the SDK installers still pin %s; pin the replacement first with `go run ./scripts/update-sdk-checksums <version_tag>`\n", tag)
+			os.Exit(1)
+		}
+		for channel, v := range all[channelsKey] {
+			if v == tag {
+				fmt.Fprintf(os.Stderr, "Error: the %s channel points to %s; publish another version on it first with `go run ./scripts/update-sdk-checksums --channel %s <version_tag>`\n", channel, tag, channel)
+				os.Exit(1)
+			}
+		}
+		repl := *replacement
+		if repl == "" {
+			if repl, err = defaultReplacement(released, yanked, version); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		yanked[tag] = yank.Entry{Reason: *reason, Replacement: repl}
+	}
+
+	isReleased := func(v string) bool {
+		_, ok := all[v]
+		return ok && !checksumsjson.IsReserved(v)
+	}
+	if err := yanked.Validate(isReleased); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := yanked.Save(yank.File); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Updated %s.\n", yank.File)
+	if err := writeChecksums(yanked); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *undo {
+		fmt.Printf("\n%s is no longer yanked.\n", tag)
+	} else {
+		fmt.Printf("\n%s is yanked in favour of %s.\n", tag, yanked[tag].Replacement)
+	}
+	fmt.Println("Commit the changes, republish versions.json with `go run ./scripts/versions-manifest --gh-pages`,")
+	fmt.Println("and release the SDKs: installers only refuse the versions listed in the checksums.json they shipped with.")
+}
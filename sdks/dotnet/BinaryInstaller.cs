@@ -30,10 +30,14 @@ namespace TestServerSdk
 {
   public static class BinaryInstaller
   {
-    private const string GithubOwner = "google";
-    private const string GithubRepo = "test-server";
-    private const string ProjectName = "test-server";
-    public const string TEST_SERVER_VERSION = "v0.2.8";
+    private const string GithubOwner = ServerConstants.GithubOwner;
+    private const string GithubRepo = ServerConstants.GithubRepo;
+    private const string ProjectName = ServerConstants.ProjectName;
+    public const string TEST_SERVER_VERSION = ServerConstants.TestServerVersion;
+    // Nightly snapshots are not in checksums.json: they expire within days, so
+    // scripts/nightly publishes them, with their checksums, in this manifest.
+    private const string NightlyChannel = ServerConstants.NightlyChannel;
+    private const string NightlyManifestUrl = ServerConstants.NightlyManifestUrl;
 
     /// <summary>
     /// Ensures the test-server binary for the given version is present in the specified output directory.
@@ -64,19 +68,31 @@ namespace TestServerSdk
       }
       Console.WriteLine($"[SDK] Found and read embedded checksums file successfully.");
 
-      using var doc = JsonDocument.Parse(checksumsJson);
-      var versionNode = doc.RootElement.TryGetProperty(version, out var vNode)
-        ? vNode
-        : throw new InvalidOperationException($"Checksums.json does not contain an entry for version {version}.");
-
-      var (goOs, archPart, archiveExt, platform) = GetPlatformDetails();
-      var archiveName = $"{ProjectName}_{goOs}_{archPart}{archiveExt}";
+      var (archiveBaseName, archiveExt, platform) = GetPlatformDetails(assembly);
+      var archiveName = $"{ProjectName}_{archiveBaseName}{archiveExt}";
 
-      var expectedChecksumNode = versionNode.TryGetProperty(archiveName, out var cNode)
-        ? cNode
-        : throw new InvalidOperationException($"Checksums.json for {version} does not contain an entry for {archiveName}.");
+      using var doc = JsonDocument.Parse(checksumsJson);
+      string? expectedChecksum;
+      // Nightly snapshots are not signed; the manifest is trusted over HTTPS.
+      var nightly = Environment.GetEnvironmentVariable("TEST_SERVER_CHANNEL") == NightlyChannel && version == TEST_SERVER_VERSION;
+      if (nightly)
+      {
+        (version, expectedChecksum) = await ResolveNightlyAsync(archiveName);
+        EnsureNotYanked(doc.RootElement, version);
+      }
+      else
+      {
+        version = ResolveChannelVersion(doc.RootElement, version);
+        EnsureNotYanked(doc.RootElement, version);
+        var versionNode = doc.RootElement.TryGetProperty(version, out var vNode)
+          ? vNode
+          : throw new InvalidOperationException($"Checksums.json does not contain an entry for version {version}.");
 
-      var expectedChecksum = expectedChecksumNode.GetString();
+        var expectedChecksumNode = versionNode.TryGetProperty(archiveName, out var cNode)
+          ? cNode
+          : throw new InvalidOperationException($"Checksums.json for {version} does not contain an entry for {archiveName}.");
+        expectedChecksum = expectedChecksumNode.GetString();
+      }
       if (string.IsNullOrEmpty(expectedChecksum) || expectedChecksum.StartsWith("PLEASE_RUN_UPDATE_SCRIPT"))
         throw new InvalidOperationException($"Checksum for {archiveName} in {version} looks invalid or is a placeholder.");
 
@@ -92,17 +108,37 @@ namespace TestServerSdk
         return;
       }
 
-      var downloadUrl = $"https://github.com/{GithubOwner}/{GithubRepo}/releases/download/{version}/{archiveName}";
+      // The release tooling's staging rehearsal (scripts/release --staging) points
+      // TEST_SERVER_RELEASE_REPO at a scratch repository.
+      var releaseRepo = Environment.GetEnvironmentVariable("TEST_SERVER_RELEASE_REPO");
+      if (string.IsNullOrEmpty(releaseRepo)) releaseRepo = $"{GithubOwner}/{GithubRepo}";
+      var downloadUrl = $"https://github.com/{releaseRepo}/releases/download/{version}/{archiveName}";
       var archivePath = Path.Combine(binDir, archiveName);
 
+      var signatureUrl = downloadUrl + ".sig";
+
       try
       {
-        await DownloadFileAsync(downloadUrl, archivePath);
+        try
+        {
+          await DownloadFileAsync(downloadUrl, archivePath);
+        }
+        catch (Exception e) when (e is HttpRequestException || e is TaskCanceledException)
+        {
+          // The archive is verified against checksums.json and its signature either way, so the mirror does not
+          // need to be trusted.
+          var mirror = Environment.GetEnvironmentVariable("TEST_SERVER_MIRROR");
+          if (string.IsNullOrEmpty(mirror)) throw;
+          Console.WriteLine($"[SDK] Download from GitHub failed ({e.Message}); falling back to the mirror at {mirror}.");
+          await DownloadFileAsync(await MirrorDownloadUrlAsync(mirror, version, archiveName), archivePath);
+          if (!nightly) signatureUrl = await MirrorDownloadUrlAsync(mirror, version, archiveName + ".sig");
+        }
         var actualChecksum = await ComputeSha256Async(archivePath);
         if (!string.Equals(actualChecksum, expectedChecksum, StringComparison.OrdinalIgnoreCase))
         {
           throw new InvalidOperationException($"Checksum mismatch for {archiveName}. Expected: {expectedChecksum}, Actual: {actualChecksum}");
         }
+        if (!nightly) await VerifySignatureAsync(signatureUrl, archivePath, archiveName);
 
         ExtractArchive(archivePath, archiveExt, binDir);
         EnsureExecutable(finalBinaryPath);
@@ -119,23 +155,96 @@ namespace TestServerSdk
       }
     }
 
-    private static (string goOs, string archPart, string archiveExt, string platform) GetPlatformDetails()
+    /// <summary>
+    /// Resolves the pre-release channel named by TEST_SERVER_CHANNEL (e.g. "beta") to the version it points to
+    /// in checksums.json. Versions other than the pinned one were requested explicitly and are kept as is.
+    /// </summary>
+    private static string ResolveChannelVersion(JsonElement root, string version)
+    {
+      var channel = Environment.GetEnvironmentVariable("TEST_SERVER_CHANNEL");
+      if (string.IsNullOrEmpty(channel) || channel == "stable" || version != TEST_SERVER_VERSION) return version;
+
+      if (root.TryGetProperty("channels", out var channels) &&
+          channels.TryGetProperty(channel, out var resolved) &&
+          resolved.GetString() is string channelVersion)
+      {
+        Console.WriteLine($"[SDK] Using the {channel} channel of {ProjectName}: {channelVersion}");
+        return channelVersion;
+      }
+      throw new InvalidOperationException($"Unknown release channel '{channel}' (TEST_SERVER_CHANNEL); checksums.json has no such channel.");
+    }
+
+    /// <summary>
+    /// Resolves the nightly channel to the latest snapshot in the nightly manifest and returns it with the
+    /// checksum the manifest lists for archiveName.
+    /// </summary>
+    private static async Task<(string version, string? checksum)> ResolveNightlyAsync(string archiveName)
+    {
+      Console.WriteLine($"[SDK] Reading nightly manifest {NightlyManifestUrl}...");
+      using var client = new HttpClient { Timeout = TimeSpan.FromSeconds(30) };
+      using var manifest = JsonDocument.Parse(await client.GetStringAsync(NightlyManifestUrl));
+      var latest = manifest.RootElement.GetProperty("latest").GetString();
+      foreach (var snapshot in manifest.RootElement.GetProperty("snapshots").EnumerateArray())
+      {
+        if (snapshot.GetProperty("version").GetString() != latest) continue;
+        foreach (var asset in snapshot.GetProperty("assets").EnumerateArray())
+        {
+          if (asset.GetProperty("archive").GetString() != archiveName) continue;
+          Console.WriteLine($"[SDK] Using the nightly channel of {ProjectName}: {latest} ({snapshot.GetProperty("commit").GetString()})");
+          return (latest!, asset.GetProperty("sha256").GetString());
+        }
+        throw new InvalidOperationException($"Nightly snapshot {latest} has no {archiveName}.");
+      }
+      throw new InvalidOperationException($"{NightlyManifestUrl} has no snapshots.");
+    }
+
+    /// <summary>
+    /// Refuses versions withdrawn after their release. checksums.json lists them under "yanked", each mapped to
+    /// the version that replaces it.
+    /// </summary>
+    private static void EnsureNotYanked(JsonElement root, string version)
+    {
+      if (root.TryGetProperty("yanked", out var yanked) &&
+          yanked.TryGetProperty(version, out var replacement))
+      {
+        throw new InvalidOperationException(
+          $"{ProjectName} {version} has been yanked and must not be installed. " +
+          $"Use {replacement.GetString()} instead: upgrade TestServerSdk, or point TEST_SERVER_CHANNEL at a channel that has moved on.");
+      }
+    }
+
+    /// <summary>
+    /// Looks the current platform up in the release platform matrix ('platforms.json', embedded next to
+    /// 'checksums.json') and returns its archive name without the project prefix and extension.
+    /// </summary>
+    private static (string archiveBaseName, string archiveExt, string platform) GetPlatformDetails(Assembly assembly)
     {
-      string platform;
-      if (RuntimeInformation.IsOSPlatform(OSPlatform.OSX)) platform = "darwin";
-      else if (RuntimeInformation.IsOSPlatform(OSPlatform.Linux)) platform = "linux";
-      else if (RuntimeInformation.IsOSPlatform(OSPlatform.Windows)) platform = "win32";
+      string platform, goos;
+      if (RuntimeInformation.IsOSPlatform(OSPlatform.OSX)) (platform, goos) = ("darwin", "darwin");
+      else if (RuntimeInformation.IsOSPlatform(OSPlatform.Linux)) (platform, goos) = ("linux", "linux");
+      else if (RuntimeInformation.IsOSPlatform(OSPlatform.Windows)) (platform, goos) = ("win32", "windows");
       else throw new PlatformNotSupportedException("Unsupported OS platform");
 
-      var arch = RuntimeInformation.ProcessArchitecture;
-      string archPart;
-      if (arch == Architecture.X64) archPart = "x86_64";
-      else if (arch == Architecture.Arm64) archPart = "arm64";
-      else throw new PlatformNotSupportedException("Unsupported architecture");
+      var goarch = RuntimeInformation.ProcessArchitecture switch
+      {
+        Architecture.X64 => "amd64",
+        Architecture.Arm64 => "arm64",
+        Architecture.X86 => "386",
+        _ => throw new PlatformNotSupportedException("Unsupported architecture"),
+      };
 
-      string goOs = platform == "darwin" ? "Darwin" : platform == "linux" ? "Linux" : "Windows";
-      string archiveExt = platform == "win32" ? ".zip" : ".tar.gz";
-      return (goOs, archPart, archiveExt, platform);
+      using var stream = assembly.GetManifestResourceStream("TestServerSdk.platforms.json")
+        ?? throw new FileNotFoundException("Could not find the embedded resource 'TestServerSdk.platforms.json'. This is a packaging error.");
+      using var matrix = JsonDocument.Parse(stream);
+      foreach (var entry in matrix.RootElement.GetProperty("platforms").EnumerateArray())
+      {
+        if (entry.GetProperty("goos").GetString() == goos && entry.GetProperty("goarch").GetString() == goarch)
+        {
+          var archiveExt = matrix.RootElement.GetProperty("archive_extensions").GetProperty(goos).GetString()!;
+          return (entry.GetProperty("archive").GetString()!, archiveExt, platform);
+        }
+      }
+      throw new PlatformNotSupportedException($"No {ProjectName} release for {goos}/{goarch}");
     }
 
     private static async Task DownloadFileAsync(string url, string destinationPath)
@@ -150,6 +259,57 @@ namespace TestServerSdk
       Console.WriteLine("[TestServerSDK] Download complete.");
     }
 
+    /// <summary>
+    /// Returns the URL of the archive on the mirror named by TEST_SERVER_MIRROR, read from the 'mirror.json'
+    /// that scripts/mirror-release writes next to the mirrored assets.
+    /// </summary>
+    private static async Task<string> MirrorDownloadUrlAsync(string mirror, string version, string archiveName)
+    {
+      var manifestUrl = $"{mirror.TrimEnd('/')}/{version}/mirror.json";
+      Console.WriteLine($"[SDK] Reading mirror manifest {manifestUrl}...");
+      using var client = new HttpClient { Timeout = TimeSpan.FromSeconds(30) };
+      using var manifest = JsonDocument.Parse(await client.GetStringAsync(manifestUrl));
+      foreach (var asset in manifest.RootElement.GetProperty("assets").EnumerateArray())
+      {
+        if (asset.GetProperty("name").GetString() == archiveName)
+          return asset.GetProperty("url").GetString()!;
+      }
+      throw new InvalidOperationException($"{archiveName} is not listed in {manifestUrl}");
+    }
+
+    /// <summary>
+    /// Verifies the archive against its cosign signature, the base64 of an ASN.1 ECDSA signature over the SHA-256
+    /// of the archive. The public key is the 'cosign.pub' embedded into the TestServerSdk.dll; a staging rehearsal
+    /// signed with a throwaway key points TEST_SERVER_COSIGN_KEY at its public half instead.
+    /// </summary>
+    private static async Task VerifySignatureAsync(string signatureUrl, string archivePath, string archiveName)
+    {
+      string publicKey;
+      var keyPath = Environment.GetEnvironmentVariable("TEST_SERVER_COSIGN_KEY");
+      if (!string.IsNullOrEmpty(keyPath))
+      {
+        publicKey = await File.ReadAllTextAsync(keyPath);
+      }
+      else
+      {
+        keyPath = "TestServerSdk.cosign.pub";
+        using var stream = Assembly.GetExecutingAssembly().GetManifestResourceStream(keyPath)
+          ?? throw new FileNotFoundException($"Embedded resource '{keyPath}' not found.");
+        using var reader = new StreamReader(stream);
+        publicKey = await reader.ReadToEndAsync();
+      }
+      Console.WriteLine($"[SDK] Verifying the signature of {archiveName} with {keyPath}...");
+
+      using var client = new HttpClient { Timeout = TimeSpan.FromSeconds(30) };
+      var signature = Convert.FromBase64String((await client.GetStringAsync(signatureUrl)).Trim());
+      using var ecdsa = ECDsa.Create();
+      ecdsa.ImportFromPem(publicKey);
+      var data = await File.ReadAllBytesAsync(archivePath);
+      if (!ecdsa.VerifyData(data, signature, HashAlgorithmName.SHA256, DSASignatureFormat.Rfc3279DerSequence))
+        throw new InvalidOperationException($"The signature of {archiveName} does not match the key {keyPath}.");
+      Console.WriteLine("[SDK] Signature verified successfully.");
+    }
+
     private static async Task<string> ComputeSha256Async(string filePath)
     {
       using var stream = File.OpenRead(filePath);
@@ -0,0 +1,30 @@
+/*
+ * Copyright 2025 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Code generated by scripts/gen-sdk-constants from sdks/constants.json. DO NOT EDIT.
+
+namespace TestServerSdk
+{
+  public static class ServerConstants
+  {
+    public const string TestServerVersion = "v0.2.8";
+    public const string GithubOwner = "google";
+    public const string GithubRepo = "test-server";
+    public const string ProjectName = "test-server";
+    public const string NightlyChannel = "nightly";
+    public const string NightlyManifestUrl = "https://google.github.io/test-server/nightly.json";
+  }
+}
@@ -0,0 +1,94 @@
+/*
+ * Copyright 2025 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+using System;
+using System.Collections.Generic;
+using System.Net.Http;
+using System.Text;
+using System.Text.Json;
+using System.Threading.Tasks;
+
+namespace TestServerSdk
+{
+  /// <summary>
+  /// Changes the stubs of a running test-server endpoint and resets its state through its /__admin API,
+  /// without restarting the server. A stub is written like a route of the configuration file, e.g.
+  /// <c>{"name": "files", "path": "/v1/files", "response": {"body": "[]"}}</c>.
+  /// </summary>
+  public class TestServerAdmin
+  {
+    private static readonly HttpClient Client = new HttpClient();
+    private readonly string _baseUrl;
+
+    /// <param name="baseUrl">The URL of the endpoint, e.g. http://localhost:1443.</param>
+    public TestServerAdmin(string baseUrl)
+    {
+      _baseUrl = baseUrl.TrimEnd('/');
+    }
+
+    /// <summary>Returns the stubs of the endpoint, in the order they match.</summary>
+    public async Task<List<JsonElement>> ListStubsAsync()
+    {
+      var body = await CallAsync(HttpMethod.Get, "/__admin/stubs");
+      var stubs = new List<JsonElement>();
+      foreach (var stub in body.GetProperty("stubs").EnumerateArray()) stubs.Add(stub);
+      return stubs;
+    }
+
+    /// <summary>Returns the stub named name.</summary>
+    public Task<JsonElement> GetStubAsync(string name) =>
+      CallAsync(HttpMethod.Get, $"/__admin/stubs/{Uri.EscapeDataString(name)}");
+
+    /// <summary>Adds a stub before the others and returns it with its name.</summary>
+    public Task<JsonElement> AddStubAsync(object stub) =>
+      CallAsync(HttpMethod.Post, "/__admin/stubs", stub);
+
+    /// <summary>Replaces the stub named name.</summary>
+    public Task<JsonElement> UpdateStubAsync(string name, object stub) =>
+      CallAsync(HttpMethod.Put, $"/__admin/stubs/{Uri.EscapeDataString(name)}", stub);
+
+    /// <summary>Deletes the stub named name.</summary>
+    public Task DeleteStubAsync(string name) =>
+      CallAsync(HttpMethod.Delete, $"/__admin/stubs/{Uri.EscapeDataString(name)}");
+
+    /// <summary>Restores the stubs of the configuration file.</summary>
+    public Task RestoreStubsAsync() => CallAsync(HttpMethod.Delete, "/__admin/stubs");
+
+    /// <summary>
+    /// Restores the stubs of the configuration file, moves every scenario back to its start and clears the
+    /// request journal.
+    /// </summary>
+    public Task ResetAsync() => CallAsync(HttpMethod.Post, "/__admin/reset");
+
+    private async Task<JsonElement> CallAsync(HttpMethod method, string path, object? body = null)
+    {
+      using var request = new HttpRequestMessage(method, _baseUrl + path);
+      if (body != null)
+      {
+        var json = body as string ?? JsonSerializer.Serialize(body);
+        request.Content = new StringContent(json, Encoding.UTF8, "application/json");
+      }
+      using var response = await Client.SendAsync(request);
+      var text = await response.Content.ReadAsStringAsync();
+      if (!response.IsSuccessStatusCode)
+      {
+        throw new HttpRequestException($"[TestServerSdk] {method} {path} failed with status {(int)response.StatusCode}: {text}");
+      }
+      using var document = JsonDocument.Parse(string.IsNullOrEmpty(text) ? "null" : text);
+      return document.RootElement.Clone();
+    }
+  }
+}
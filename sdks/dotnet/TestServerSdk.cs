@@ -17,6 +17,7 @@
 using System;
 using System.Diagnostics;
 using System.IO;
+using System.Runtime.InteropServices;
 using System.Threading;
 using System.Threading.Tasks;
 using System.Text.Json;
@@ -29,7 +30,7 @@ namespace TestServerSdk
   {
     public string ConfigPath { get; set; } = "";
     public string RecordingDir { get; set; } = "";
-    public string Mode { get; set; } = ""; // "record" or "replay"
+    public string Mode { get; set; } = ""; // "record", "replay" or "auto"
     public string BinaryPath { get; set; } = "";
 
     public string? TestServerSecrets { get; set; }
@@ -45,7 +46,7 @@ namespace TestServerSdk
     private Process? _process;
     private readonly TestServerOptions _options;
     private readonly string _binaryPath;
-    public const string TEST_SERVER_VERSION = "v0.2.8";
+    public const string TEST_SERVER_VERSION = ServerConstants.TestServerVersion;
 
     public TestServerProcess(TestServerOptions options)
     {
@@ -57,9 +58,26 @@ namespace TestServerSdk
     {
       var binaryName = Environment.OSVersion.Platform == PlatformID.Win32NT ? "test-server.exe" : "test-server";
 
+      // An explicit binary (e.g. a local build used by scripts/run-sdk-tests) wins over everything else.
+      var overridePath = Environment.GetEnvironmentVariable("TEST_SERVER_BINARY");
+      if (!string.IsNullOrEmpty(overridePath))
+      {
+        if (File.Exists(overridePath)) return Path.GetFullPath(overridePath);
+        throw new FileNotFoundException($"[TestServerSdk] test-server binary not found at {overridePath} (set by TEST_SERVER_BINARY).");
+      }
+
       var p = Path.GetFullPath(_options.BinaryPath);
       if (File.Exists(p)) return p;
 
+      // Prefer the binary shipped by a TestServerSdk.Runtime.<rid> NuGet package, if referenced. Those carry
+      // the pinned stable version, so they are skipped when a pre-release channel is requested.
+      var channel = Environment.GetEnvironmentVariable("TEST_SERVER_CHANNEL");
+      if (string.IsNullOrEmpty(channel) || channel == "stable")
+      {
+        var runtimePackageBinary = FindRuntimePackageBinary(binaryName);
+        if (runtimePackageBinary != null) return runtimePackageBinary;
+      }
+
       // If the binary does not exist at the provided path, attempt to install it into that folder
       try
       {
@@ -75,6 +93,30 @@ namespace TestServerSdk
       }
     }
 
+    private static string? FindRuntimePackageBinary(string binaryName)
+    {
+      var os = RuntimeInformation.IsOSPlatform(OSPlatform.Windows) ? "win"
+        : RuntimeInformation.IsOSPlatform(OSPlatform.OSX) ? "osx"
+        : "linux";
+      var arch = RuntimeInformation.OSArchitecture switch
+      {
+        Architecture.Arm64 => "arm64",
+        Architecture.X86 => "x86",
+        _ => "x64",
+      };
+      var candidates = new[]
+      {
+        // RID-specific builds copy native assets next to the application.
+        Path.Combine(AppContext.BaseDirectory, binaryName),
+        Path.Combine(AppContext.BaseDirectory, "runtimes", $"{os}-{arch}", "native", binaryName),
+      };
+      foreach (var candidate in candidates)
+      {
+        if (File.Exists(candidate)) return candidate;
+      }
+      return null;
+    }
+
     public async Task<Process> StartAsync()
     {
       var args = $"{_options.Mode} --config {_options.ConfigPath} --recording-dir {_options.RecordingDir}";
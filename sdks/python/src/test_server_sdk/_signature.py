@@ -0,0 +1,114 @@
+# Copyright 2025 Google LLC
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+
+"""Verifies the cosign signatures of the release archives.
+
+`cosign sign-blob` signs the SHA-256 of a file with an ECDSA P-256 key and
+writes the base64 of the ASN.1 signature to <file>.sig. The standard library
+has no ECDSA, so the verification is done here rather than adding a
+dependency to the installer.
+"""
+
+import base64
+import hashlib
+
+# The NIST P-256 curve: y^2 = x^3 - 3x + b over GF(_P), of order _N.
+_P = 0xFFFFFFFF00000001000000000000000000000000FFFFFFFFFFFFFFFFFFFFFFFF
+_B = 0x5AC635D8AA3A93E7B3EBBD55769886BC651D06B0CC53B0F63BCE3C3E27D2604B
+_N = 0xFFFFFFFF00000000FFFFFFFFFFFFFFFFBCE6FAADA7179E84F3B9CAC2FC632551
+_G = (
+    0x6B17D1F2E12C4247F8BCE6E563A440F277037D812DEB33A0F4A13945D898C296,
+    0x4FE342E2FE1A7F9B8EE7EB4A7C0F9E162BCE33576B315ECECBB6406837BF51F5,
+)
+# The DER of the prime256v1 OID, which a P-256 public key names.
+_P256_OID = bytes.fromhex("06082a8648ce3d030107")
+
+
+class SignatureError(ValueError):
+    """The signature does not match, or the key or signature is malformed."""
+
+
+def _add(p, q):
+    if p is None:
+        return q
+    if q is None:
+        return p
+    if p[0] == q[0] and (p[1] + q[1]) % _P == 0:
+        return None
+    if p == q:
+        slope = (3 * p[0] * p[0] - 3) * pow(2 * p[1], -1, _P)
+    else:
+        slope = (q[1] - p[1]) * pow(q[0] - p[0], -1, _P)
+    x = (slope * slope - p[0] - q[0]) % _P
+    return x, (slope * (p[0] - x) - p[1]) % _P
+
+
+def _multiply(k, p):
+    result = None
+    while k:
+        if k & 1:
+            result = _add(result, p)
+        p = _add(p, p)
+        k >>= 1
+    return result
+
+
+def parse_public_key(pem):
+    """Returns the point of a PEM encoded P-256 public key, as in cosign.pub."""
+    lines = [l.strip() for l in pem.strip().splitlines()]
+    if not lines or lines[0] != "-----BEGIN PUBLIC KEY-----" or lines[-1] != "-----END PUBLIC KEY-----":
+        raise SignatureError("the public key is not a PEM PUBLIC KEY")
+    der = base64.b64decode("".join(lines[1:-1]))
+    # The SubjectPublicKeyInfo of a P-256 key ends with the uncompressed point.
+    if _P256_OID not in der or len(der) < 65 or der[-65] != 4:
+        raise SignatureError("the public key is not an uncompressed ECDSA P-256 key")
+    x, y = int.from_bytes(der[-64:-32], "big"), int.from_bytes(der[-32:], "big")
+    if (y * y - x * x * x + 3 * x - _B) % _P != 0:
+        raise SignatureError("the public key is not on the P-256 curve")
+    return x, y
+
+
+def _parse_signature(der):
+    """Returns r and s of an ASN.1 SEQUENCE of two INTEGERs."""
+    if len(der) < 8 or der[0] != 0x30 or der[1] != len(der) - 2:
+        raise SignatureError("the signature is not an ASN.1 ECDSA signature")
+    values, i = [], 2
+    for _ in range(2):
+        if der[i] != 0x02 or i + 2 + der[i + 1] > len(der):
+            raise SignatureError("the signature is not an ASN.1 ECDSA signature")
+        values.append(int.from_bytes(der[i + 2:i + 2 + der[i + 1]], "big"))
+        i += 2 + der[i + 1]
+    if i != len(der):
+        raise SignatureError("the signature has trailing data")
+    return values
+
+
+def verify_file(public_key_pem, path, signature):
+    """Raises SignatureError unless signature, the content of a .sig file, is cosign's signature of the file at path."""
+    try:
+        r, s = _parse_signature(base64.b64decode(signature.strip(), validate=True))
+    except (ValueError, IndexError) as e:
+        raise SignatureError(f"malformed signature: {e}") from e
+    q = parse_public_key(public_key_pem)
+    if not (0 < r < _N and 0 < s < _N):
+        raise SignatureError("the signature is out of range")
+    digest = hashlib.sha256()
+    with open(path, "rb") as f:
+        for chunk in iter(lambda: f.read(65536), b""):
+            digest.update(chunk)
+    e = int.from_bytes(digest.digest(), "big")
+    w = pow(s, -1, _N)
+    point = _add(_multiply(e * w % _N, _G), _multiply(r * w % _N, q))
+    if point is None or point[0] % _N != r:
+        raise SignatureError("the signature does not match")
@@ -0,0 +1,69 @@
+# Copyright 2025 Google LLC
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+
+from typing import Any, Dict, List, Optional
+from urllib.parse import quote
+
+import requests
+
+
+class TestServerAdmin:
+    """Changes the stubs of a running test-server endpoint and resets its state
+    through its /__admin API, without restarting the server.
+
+    A stub is a dict written like a route of the configuration file, e.g.
+    {"name": "files", "path": "/v1/files", "response": {"body": "[]"}}.
+    """
+
+    def __init__(self, base_url: str, timeout: float = 5):
+        """base_url is the URL of the endpoint, e.g. http://localhost:1443."""
+        self.base_url = base_url.rstrip("/")
+        self.timeout = timeout
+
+    def list_stubs(self) -> List[Dict[str, Any]]:
+        """Returns the stubs of the endpoint, in the order they match."""
+        return self._call("GET", "/__admin/stubs")["stubs"]
+
+    def get_stub(self, name: str) -> Dict[str, Any]:
+        """Returns the stub named name."""
+        return self._call("GET", f"/__admin/stubs/{quote(name, safe='')}")
+
+    def add_stub(self, stub: Dict[str, Any]) -> Dict[str, Any]:
+        """Adds a stub before the others and returns it with its name."""
+        return self._call("POST", "/__admin/stubs", stub)
+
+    def update_stub(self, name: str, stub: Dict[str, Any]) -> Dict[str, Any]:
+        """Replaces the stub named name."""
+        return self._call("PUT", f"/__admin/stubs/{quote(name, safe='')}", stub)
+
+    def delete_stub(self, name: str):
+        """Deletes the stub named name."""
+        self._call("DELETE", f"/__admin/stubs/{quote(name, safe='')}")
+
+    def restore_stubs(self):
+        """Restores the stubs of the configuration file."""
+        self._call("DELETE", "/__admin/stubs")
+
+    def reset(self):
+        """Restores the stubs of the configuration file, moves every scenario
+        back to its start and clears the request journal."""
+        self._call("POST", "/__admin/reset")
+
+    def _call(self, method: str, path: str, body: Optional[Dict[str, Any]] = None) -> Any:
+        response = requests.request(method, self.base_url + path, json=body, timeout=self.timeout)
+        if not response.ok:
+            raise RuntimeError(
+                f"{method} {path} failed with status {response.status_code}: {response.text}"
+            )
+        return response.json() if response.content else None
@@ -25,13 +25,35 @@ import requests
 import subprocess
 
 # --- Configuration ---
-TEST_SERVER_VERSION = "v0.2.8"
-GITHUB_OWNER = "google"
-GITHUB_REPO = "test-server"
-PROJECT_NAME = "test-server"
+# Nightly snapshots are not in checksums.json: they expire within days, so
+# scripts/nightly publishes them, with their checksums, in NIGHTLY_MANIFEST_URL.
+try:
+    from ._constants import (
+        TEST_SERVER_VERSION,
+        GITHUB_OWNER,
+        GITHUB_REPO,
+        PROJECT_NAME,
+        NIGHTLY_CHANNEL,
+        NIGHTLY_MANIFEST_URL,
+    )
+    from ._signature import verify_file
+except ImportError:  # run as a script: python install.py
+    from _constants import (
+        TEST_SERVER_VERSION,
+        GITHUB_OWNER,
+        GITHUB_REPO,
+        PROJECT_NAME,
+        NIGHTLY_CHANNEL,
+        NIGHTLY_MANIFEST_URL,
+    )
+    from _signature import verify_file
 PROJECT_ROOT = Path(__file__).parent
 
 CHECKSUMS_PATH = PROJECT_ROOT / "checksums.json"
+PLATFORMS_PATH = PROJECT_ROOT / "platforms.json"
+# The public half of the release signing key. A staging rehearsal signed with
+# a throwaway key points TEST_SERVER_COSIGN_KEY at its public half instead.
+COSIGN_KEY_PATH = PROJECT_ROOT / "cosign.pub"
 
 try:
     with open(CHECKSUMS_PATH, "r") as f:
@@ -40,6 +62,54 @@ except (FileNotFoundError, json.JSONDecodeError) as e:
     print(f"Error loading checksums.json: {e}", file=sys.stderr)
     sys.exit(1)
 
+try:
+    with open(PLATFORMS_PATH, "r") as f:
+        PLATFORM_MATRIX = json.load(f)
+except (FileNotFoundError, json.JSONDecodeError) as e:
+    print(f"Error loading platforms.json: {e}", file=sys.stderr)
+    sys.exit(1)
+
+
+def resolve_version():
+    """Returns the pinned version, or the one the TEST_SERVER_CHANNEL channel points to."""
+    channel = os.environ.get("TEST_SERVER_CHANNEL")
+    if not channel or channel == "stable":
+        return TEST_SERVER_VERSION
+    channels = ALL_EXPECTED_CHECKSUMS.get("channels", {})
+    version = channels.get(channel)
+    if not version:
+        known = ", ".join(["stable"] + sorted(channels))
+        raise ValueError(f"Unknown release channel '{channel}' (TEST_SERVER_CHANNEL). Known channels: {known}")
+    print(f"Using the {channel} channel of {PROJECT_NAME}: {version}")
+    return version
+
+
+def resolve_nightly(archive_name):
+    """Returns the latest snapshot in the nightly manifest and trusts the checksum it lists for archive_name."""
+    print(f"Reading nightly manifest {NIGHTLY_MANIFEST_URL}...")
+    r = requests.get(NIGHTLY_MANIFEST_URL, timeout=30)
+    r.raise_for_status()
+    manifest = r.json()
+    snapshot = next((s for s in manifest.get("snapshots", []) if s.get("version") == manifest.get("latest")), None)
+    if snapshot is None:
+        raise ValueError(f"{NIGHTLY_MANIFEST_URL} has no snapshots")
+    asset = next((a for a in snapshot.get("assets", []) if a.get("archive") == archive_name), None)
+    if asset is None:
+        raise ValueError(f"Nightly snapshot {snapshot['version']} has no {archive_name}")
+    ALL_EXPECTED_CHECKSUMS[snapshot["version"]] = {archive_name: asset["sha256"]}
+    print(f"Using the nightly channel of {PROJECT_NAME}: {snapshot['version']} ({snapshot.get('commit')})")
+    return snapshot["version"]
+
+
+def check_not_yanked(version):
+    """Refuses versions withdrawn after their release; checksums.json maps them to their replacement."""
+    replacement = ALL_EXPECTED_CHECKSUMS.get("yanked", {}).get(version)
+    if replacement:
+        raise ValueError(
+            f"{PROJECT_NAME} {version} has been yanked and must not be installed. "
+            f"Use {replacement} instead: upgrade test-server-sdk, or point TEST_SERVER_CHANNEL at a channel that has moved on."
+        )
+
 
 def get_platform_details():
     """Determines the OS and architecture to download the correct binary."""
@@ -47,26 +117,33 @@ def get_platform_details():
     arch = platform.machine()
     
     if os_platform.startswith("darwin"):
-        go_os = "Darwin"
-        archive_extension = ".tar.gz"
+        goos = "darwin"
     elif os_platform.startswith("linux"):
-        go_os = "Linux"
-        archive_extension = ".tar.gz"
+        goos = "linux"
     elif os_platform.startswith("win32"):
-        go_os = "Windows"
-        archive_extension = ".zip"
+        goos = "windows"
     else:
         raise OSError(f"Unsupported platform: {os_platform}")
 
     if arch in ["x86_64", "AMD64"]:
-        go_arch = "x86_64"
-    elif arch in ["arm64", "aarch64"]:
-        go_arch = "arm64"
+        goarch = "amd64"
+    elif arch in ["arm64", "aarch64", "ARM64"]:
+        goarch = "arm64"
+    elif arch in ["i386", "i686", "x86"]:
+        goarch = "386"
     else:
         raise OSError(f"Unsupported architecture: {arch}")
-        
-    binary_name = f"{PROJECT_NAME}.exe" if go_os == "Windows" else PROJECT_NAME
-    return go_os, go_arch, archive_extension, binary_name
+
+    # The released platforms and their archive names come from platforms.json.
+    for entry in PLATFORM_MATRIX["platforms"]:
+        if entry["goos"] == goos and entry["goarch"] == goarch:
+            break
+    else:
+        raise OSError(f"No {PROJECT_NAME} release for {goos}/{goarch}")
+
+    archive_extension = PLATFORM_MATRIX["archive_extensions"][goos]
+    binary_name = f"{PROJECT_NAME}.exe" if goos == "windows" else PROJECT_NAME
+    return goos, entry["archive"], archive_extension, binary_name
 
 
 def calculate_file_sha256(file_path):
@@ -78,8 +155,18 @@ def calculate_file_sha256(file_path):
     return sha256.hexdigest()
 
 
-def download_and_verify(download_url, archive_path, version, archive_name):
-    """Downloads the binary archive and verifies its checksum."""
+def verify_signature(signature_url, archive_path, archive_name):
+    """Verifies the archive against its cosign signature and the release public key."""
+    key_path = Path(os.environ.get("TEST_SERVER_COSIGN_KEY") or COSIGN_KEY_PATH)
+    print(f"Verifying the signature of {archive_name} with {key_path}...")
+    r = requests.get(signature_url, timeout=30)
+    r.raise_for_status()
+    verify_file(key_path.read_text(), archive_path, r.text)
+    print("Signature verified successfully.")
+
+
+def download_and_verify(download_url, archive_path, version, archive_name, signature_url=None):
+    """Downloads the binary archive and verifies its checksum and, given signature_url, its signature."""
     print(f"Downloading {archive_name} from {download_url}...")
     try:
         with requests.get(download_url, stream=True, timeout=60) as r:
@@ -98,6 +185,8 @@ def download_and_verify(download_url, archive_path, version, archive_name):
         if actual_checksum != expected_checksum:
             raise ValueError(f"Checksum mismatch! Expected {expected_checksum}, got {actual_checksum}")
         print("Checksum verified successfully.")
+        if signature_url:
+            verify_signature(signature_url, archive_path, archive_name)
 
     except Exception as e:
         if archive_path.exists():
@@ -106,6 +195,18 @@ def download_and_verify(download_url, archive_path, version, archive_name):
         raise
 
 
+def mirror_download_url(mirror, version, archive_name):
+    """Returns the archive URL from the mirror.json that scripts/mirror-release writes next to the assets."""
+    manifest_url = f"{mirror.rstrip('/')}/{version}/mirror.json"
+    print(f"Reading mirror manifest {manifest_url}...")
+    r = requests.get(manifest_url, timeout=30)
+    r.raise_for_status()
+    for asset in r.json().get("assets", []):
+        if asset.get("name") == archive_name:
+            return asset["url"]
+    raise ValueError(f"{archive_name} is not listed in {manifest_url}")
+
+
 def extract_archive(archive_path, archive_extension, destination_dir):
     """Extracts the binary from the downloaded archive into the destination."""
     print(f"Extracting binary from {archive_path} to {destination_dir}...")
@@ -123,9 +224,9 @@ def extract_archive(archive_path, archive_extension, destination_dir):
             print(f"Cleaned up {archive_path}.")
 
 
-def ensure_binary_is_executable(binary_path, go_os):
+def ensure_binary_is_executable(binary_path, goos):
     """Sets executable permissions on the binary for non-Windows systems."""
-    if go_os != "Windows":
+    if goos != "windows":
         st = os.stat(binary_path)
         os.chmod(binary_path, st.st_mode | stat.S_IEXEC)
         print(f"Set executable permission for {binary_path}")
@@ -159,9 +260,18 @@ def verify_binary_usability(binary_path: Path) -> None:
         ) from e
 
 
+def release_repo() -> str:
+    """Returns the repository releases are downloaded from.
+
+    The release tooling's staging rehearsal (scripts/release --staging) points
+    TEST_SERVER_RELEASE_REPO at a scratch repository.
+    """
+    return os.environ.get("TEST_SERVER_RELEASE_REPO") or f"{GITHUB_OWNER}/{GITHUB_REPO}"
+
+
 def install_binary(bin_dir: Path):
     """Main function to orchestrate the installation to a specific directory."""
-    go_os, go_arch, archive_extension, binary_name = get_platform_details()
+    goos, archive_base_name, archive_extension, binary_name = get_platform_details()
     binary_path = bin_dir / binary_name
 
     if binary_path.exists():
@@ -170,15 +280,32 @@ def install_binary(bin_dir: Path):
 
     bin_dir.mkdir(parents=True, exist_ok=True)
 
-    version = TEST_SERVER_VERSION
-    archive_name = f"{PROJECT_NAME}_{go_os}_{go_arch}{archive_extension}"
-    download_url = f"https://github.com/{GITHUB_OWNER}/{GITHUB_REPO}/releases/download/{version}/{archive_name}"
+    archive_name = f"{PROJECT_NAME}_{archive_base_name}{archive_extension}"
+    # Nightly snapshots are not signed; the manifest is trusted over HTTPS.
+    nightly = os.environ.get("TEST_SERVER_CHANNEL") == NIGHTLY_CHANNEL
+    if nightly:
+        version = resolve_nightly(archive_name)
+    else:
+        version = resolve_version()
+    check_not_yanked(version)
+    download_url = f"https://github.com/{release_repo()}/releases/download/{version}/{archive_name}"
     archive_path = bin_dir / archive_name
 
     try:
-        download_and_verify(download_url, archive_path, version, archive_name)
+        try:
+            signature_url = None if nightly else download_url + ".sig"
+            download_and_verify(download_url, archive_path, version, archive_name, signature_url)
+        except Exception:
+            # The archive is verified against checksums.json and its signature either way, so the mirror does not
+            # need to be trusted.
+            mirror = os.environ.get("TEST_SERVER_MIRROR")
+            if not mirror:
+                raise
+            print(f"Download from GitHub failed; falling back to the mirror at {mirror}.")
+            signature_url = None if nightly else mirror_download_url(mirror, version, archive_name + ".sig")
+            download_and_verify(mirror_download_url(mirror, version, archive_name), archive_path, version, archive_name, signature_url)
         extract_archive(archive_path, archive_extension, bin_dir)
-        ensure_binary_is_executable(binary_path, go_os)
+        ensure_binary_is_executable(binary_path, goos)
         verify_binary_usability(binary_path)
         print(f"\n{PROJECT_NAME} binary is ready at {binary_path}")
     except Exception as e:
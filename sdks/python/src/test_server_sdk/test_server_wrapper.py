@@ -41,6 +41,15 @@ class TestServer:
         Finds the platform-specific binary. If not found, attempts to run the
         installer script.
         """
+        # An explicit binary (e.g. a local build used by scripts/run-sdk-tests) wins.
+        override_path = os.environ.get("TEST_SERVER_BINARY")
+        if override_path:
+            if not Path(override_path).exists():
+                raise FileNotFoundError(
+                    f"test-server binary not found at {override_path} (set by TEST_SERVER_BINARY)."
+                )
+            return Path(override_path)
+
         binary_name = f"{PROJECT_NAME}.exe" if sys.platform == "win32" else PROJECT_NAME
         binary_path = Path(__file__).parent / "bin" / binary_name
 
@@ -0,0 +1,26 @@
+/**
+ * Copyright 2025 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Code generated by scripts/gen-sdk-constants from sdks/constants.json. DO NOT EDIT.
+
+module.exports = Object.freeze({
+    TEST_SERVER_VERSION: 'v0.2.8',
+    GITHUB_OWNER: 'google',
+    GITHUB_REPO: 'test-server',
+    PROJECT_NAME: 'test-server',
+    NIGHTLY_CHANNEL: 'nightly',
+    NIGHTLY_MANIFEST_URL: 'https://google.github.io/test-server/nightly.json',
+});
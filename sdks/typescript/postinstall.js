@@ -23,31 +23,38 @@ const axios = require('axios');
 const extract = require('extract-zip');
 const tar = require('tar');
 const allExpectedChecksums = require('./checksums.json');
-const TEST_SERVER_VERSION = 'v0.2.8';
+const platformMatrix = require('./platforms.json');
+// Nightly snapshots are not in checksums.json: they expire within days, so
+// scripts/nightly publishes them, with their checksums, in NIGHTLY_MANIFEST_URL.
+const {
+    TEST_SERVER_VERSION,
+    GITHUB_OWNER,
+    GITHUB_REPO,
+    PROJECT_NAME,
+    NIGHTLY_CHANNEL,
+    NIGHTLY_MANIFEST_URL,
+} = require('./constants');
 
-const GITHUB_OWNER = 'google';
-const GITHUB_REPO = 'test-server';
-const PROJECT_NAME = 'test-server';
 const BIN_DIR = path.join(__dirname, 'bin');
+// The public half of the release signing key. A staging rehearsal signed with
+// a throwaway key points TEST_SERVER_COSIGN_KEY at its public half instead.
+const COSIGN_KEY_PATH = path.join(__dirname, 'cosign.pub');
 const getBinaryPath = () => path.join(BIN_DIR, os.platform() === 'win32' ? `${PROJECT_NAME}.exe` : PROJECT_NAME);
 
+// The repository releases are downloaded from. The release tooling's staging
+// rehearsal (scripts/release --staging) points TEST_SERVER_RELEASE_REPO at a
+// scratch repository; checksums.json still has to match what is downloaded.
+const releaseRepo = () => process.env.TEST_SERVER_RELEASE_REPO || `${GITHUB_OWNER}/${GITHUB_REPO}`;
+
+// Looks the current platform up in platforms.json, the release platform matrix.
 function getPlatformDetails() {
     const platform = os.platform();
     const arch = os.arch();
-    let goOs, goArchFilenamePart, archiveExtension = '.tar.gz';
-
-    if (platform === 'darwin') goOs = 'Darwin';
-    else if (platform === 'linux') goOs = 'Linux';
-    else if (platform === 'win32') {
-        goOs = 'Windows';
-        archiveExtension = '.zip';
-    } else throw new Error(`Unsupported platform: ${platform}`);
+    const entry = platformMatrix.platforms.find(p => p.node === `${platform}-${arch}`);
+    if (!entry) throw new Error(`Unsupported platform: ${platform}-${arch}`);
 
-    if (arch === 'x64') goArchFilenamePart = 'x86_64';
-    else if (arch === 'arm64') goArchFilenamePart = 'arm64';
-    else throw new Error(`Unsupported architecture: ${arch}`);
-
-    return { goOs, goArchFilenamePart, archiveExtension, platform };
+    const archiveExtension = platformMatrix.archive_extensions[entry.goos];
+    return { archiveBaseName: entry.archive, archiveExtension, platform };
 }
 
 function calculateFileSha256(filePath) {
@@ -60,7 +67,23 @@ function calculateFileSha256(filePath) {
     });
 }
 
-async function downloadBinaryArchive(downloadUrl, archivePath, version, archiveName) {
+// Verifies the archive against its cosign signature, the base64 of an ASN.1
+// ECDSA signature over the SHA-256 of the archive.
+async function verifySignature(signatureUrl, archivePath, archiveName) {
+    const keyPath = process.env.TEST_SERVER_COSIGN_KEY || COSIGN_KEY_PATH;
+    console.log(`Verifying the signature of ${archiveName} with ${keyPath}...`);
+    const response = await axios.get(signatureUrl, { timeout: 30000, responseType: 'text' });
+    const signature = Buffer.from(String(response.data).trim(), 'base64');
+    const publicKey = fs.readFileSync(keyPath, 'utf8');
+    if (!crypto.verify('sha256', fs.readFileSync(archivePath), publicKey, signature)) {
+        throw new Error(`The signature of ${archiveName} does not match the key ${keyPath}.`);
+    }
+    console.log('Signature verified successfully.');
+}
+
+// Downloads the archive and verifies its checksum and, given signatureUrl,
+// its signature.
+async function downloadBinaryArchive(downloadUrl, archivePath, version, archiveName, signatureUrl) {
     console.log(`Downloading ${archiveName} (version: ${version}) to ${archivePath}...`);
     try {
         const writer = fs.createWriteStream(archivePath);
@@ -110,6 +133,9 @@ async function downloadBinaryArchive(downloadUrl, archivePath, version, archiveN
             );
         }
         console.log('Checksum verified successfully.');
+        if (signatureUrl) {
+            await verifySignature(signatureUrl, archivePath, archiveName);
+        }
 
     } catch (error) {
         console.error(`Failed during binary download or checksum verification for ${archiveName} from ${downloadUrl}: ${error.message}`);
@@ -166,7 +192,101 @@ function ensureBinaryIsExecutable(binaryPath, platform) {
     }
 }
 
+// Resolves the version to install: the pinned TEST_SERVER_VERSION, or the
+// version a pre-release channel (beta, rc, ...) currently points to when
+// TEST_SERVER_CHANNEL is set. The nightly channel goes through resolveNightly.
+function resolveVersion() {
+    const channel = process.env.TEST_SERVER_CHANNEL;
+    if (!channel || channel === 'stable') {
+        return TEST_SERVER_VERSION;
+    }
+    const channels = allExpectedChecksums.channels || {};
+    const version = channels[channel];
+    if (!version) {
+        throw new Error(
+            `Unknown release channel "${channel}" (TEST_SERVER_CHANNEL). ` +
+            `Known channels: ${['stable', ...Object.keys(channels)].join(', ')}`
+        );
+    }
+    console.log(`Using the ${channel} channel of ${PROJECT_NAME}: ${version}`);
+    return version;
+}
+
+// Resolves the nightly channel to the latest snapshot in the nightly manifest
+// and trusts the checksum the manifest lists for archiveName.
+async function resolveNightly(archiveName) {
+    console.log(`Reading nightly manifest ${NIGHTLY_MANIFEST_URL}...`);
+    const response = await axios.get(NIGHTLY_MANIFEST_URL, { timeout: 30000 });
+    const manifest = response.data;
+    const snapshot = (manifest.snapshots || []).find(s => s.version === manifest.latest);
+    if (!snapshot) {
+        throw new Error(`${NIGHTLY_MANIFEST_URL} has no snapshots`);
+    }
+    const asset = (snapshot.assets || []).find(a => a.archive === archiveName);
+    if (!asset) {
+        throw new Error(`Nightly snapshot ${snapshot.version} has no ${archiveName}`);
+    }
+    allExpectedChecksums[snapshot.version] = { [archiveName]: asset.sha256 };
+    console.log(`Using the nightly channel of ${PROJECT_NAME}: ${snapshot.version} (${snapshot.commit})`);
+    return snapshot.version;
+}
+
+// Refuses versions withdrawn after their release. checksums.json lists them
+// under "yanked", each with the version that replaces it.
+function checkNotYanked(version) {
+    const replacement = (allExpectedChecksums.yanked || {})[version];
+    if (replacement) {
+        throw new Error(
+            `${PROJECT_NAME} ${version} has been yanked and must not be installed. ` +
+            `Use ${replacement} instead: upgrade test-server-sdk, or point TEST_SERVER_CHANNEL at a channel that has moved on.`
+        );
+    }
+}
+
+// Returns the URL of the archive on the mirror named by TEST_SERVER_MIRROR,
+// read from the mirror.json scripts/mirror-release writes next to the assets.
+async function mirrorDownloadUrl(mirror, version, archiveName) {
+    const manifestUrl = `${mirror.replace(/\/+$/, '')}/${version}/mirror.json`;
+    console.log(`Reading mirror manifest ${manifestUrl}...`);
+    const response = await axios.get(manifestUrl, { timeout: 30000 });
+    const asset = (response.data.assets || []).find(a => a.name === archiveName);
+    if (!asset) {
+        throw new Error(`${archiveName} is not listed in ${manifestUrl}`);
+    }
+    return asset.url;
+}
+
+// Returns true when the platform-specific optional dependency providing the
+// binary (e.g. @test-server/cli-linux-x64) was installed by the package manager.
+function hasPlatformPackage() {
+    const packageName = `@test-server/cli-${os.platform()}-${os.arch()}`;
+    try {
+        require.resolve(`${packageName}/package.json`);
+        console.log(`Using ${PROJECT_NAME} binary from ${packageName}; skipping download.`);
+        return true;
+    } catch (e) {
+        return false;
+    }
+}
+
 async function main() {
+    if (process.env.TEST_SERVER_BINARY) {
+        console.log(`Using ${PROJECT_NAME} binary from TEST_SERVER_BINARY (${process.env.TEST_SERVER_BINARY}); skipping download.`);
+        return;
+    }
+    const { archiveBaseName, archiveExtension, platform } = getPlatformDetails();
+    const archiveName = `${PROJECT_NAME}_${archiveBaseName}${archiveExtension}`;
+
+    // Nightly snapshots are not signed; the manifest is trusted over HTTPS.
+    const nightly = process.env.TEST_SERVER_CHANNEL === NIGHTLY_CHANNEL;
+    const version = nightly ? await resolveNightly(archiveName) : resolveVersion();
+    checkNotYanked(version);
+    // Platform packages carry the pinned stable binary only.
+    // A staging rehearsal exercises the download, not the npm platform packages.
+    if (version === TEST_SERVER_VERSION && !process.env.TEST_SERVER_RELEASE_REPO && hasPlatformPackage()) {
+        return;
+    }
+
     const binaryPath = getBinaryPath();
     if (fs.existsSync(binaryPath)) {
         console.log(`${PROJECT_NAME} binary already exists at ${binaryPath}. Removing it for a fresh install.`);
@@ -177,14 +297,21 @@ async function main() {
         fs.mkdirSync(BIN_DIR, { recursive: true });
     }
 
-    const { goOs, goArchFilenamePart, archiveExtension, platform } = getPlatformDetails();
-    
-    const version = TEST_SERVER_VERSION;
-    const archiveName = `${PROJECT_NAME}_${goOs}_${goArchFilenamePart}${archiveExtension}`;
-    const downloadUrl = `https://github.com/${GITHUB_OWNER}/${GITHUB_REPO}/releases/download/${version}/${archiveName}`;
+    const downloadUrl = `https://github.com/${releaseRepo()}/releases/download/${version}/${archiveName}`;
     const archivePath = path.join(BIN_DIR, archiveName);
 
-    await downloadBinaryArchive(downloadUrl, archivePath, version, archiveName);
+    try {
+        await downloadBinaryArchive(downloadUrl, archivePath, version, archiveName, nightly ? null : `${downloadUrl}.sig`);
+    } catch (error) {
+        // The archive is verified against checksums.json and its signature
+        // either way, so the mirror does not need to be trusted.
+        const mirror = process.env.TEST_SERVER_MIRROR;
+        if (!mirror) throw error;
+        console.log(`Download from GitHub failed; falling back to the mirror at ${mirror}.`);
+        const mirrorUrl = await mirrorDownloadUrl(mirror, version, archiveName);
+        const signatureUrl = nightly ? null : await mirrorDownloadUrl(mirror, version, `${archiveName}.sig`);
+        await downloadBinaryArchive(mirrorUrl, archivePath, version, archiveName, signatureUrl);
+    }
     await extractBinaryFromArchive(archivePath, archiveExtension, binaryPath);
     ensureBinaryIsExecutable(binaryPath, platform);
 
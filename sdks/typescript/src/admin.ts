@@ -0,0 +1,88 @@
+/**
+ * Copyright 2025 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+/**
+ * A route of test-server, written like the routes of the configuration file,
+ * e.g. { name: 'files', path: '/v1/files', response: { body: '[]' } }.
+ */
+export type Stub = { name?: string; [field: string]: unknown };
+
+/**
+ * Changes the stubs of a running test-server endpoint and resets its state
+ * through its /__admin API, without restarting the server.
+ */
+export class TestServerAdmin {
+    private readonly baseUrl: string;
+
+    /**
+     * @param baseUrl The URL of the endpoint, e.g. http://localhost:1443.
+     */
+    constructor(baseUrl: string) {
+        this.baseUrl = baseUrl.replace(/\/+$/, '');
+    }
+
+    /** Returns the stubs of the endpoint, in the order they match. */
+    async listStubs(): Promise<Stub[]> {
+        const body = await this.call('GET', '/__admin/stubs');
+        return body.stubs;
+    }
+
+    /** Returns the stub named name. */
+    async getStub(name: string): Promise<Stub> {
+        return this.call('GET', `/__admin/stubs/${encodeURIComponent(name)}`);
+    }
+
+    /** Adds a stub before the others and returns it with its name. */
+    async addStub(stub: Stub): Promise<Stub> {
+        return this.call('POST', '/__admin/stubs', stub);
+    }
+
+    /** Replaces the stub named name. */
+    async updateStub(name: string, stub: Stub): Promise<Stub> {
+        return this.call('PUT', `/__admin/stubs/${encodeURIComponent(name)}`, stub);
+    }
+
+    /** Deletes the stub named name. */
+    async deleteStub(name: string): Promise<void> {
+        await this.call('DELETE', `/__admin/stubs/${encodeURIComponent(name)}`);
+    }
+
+    /** Restores the stubs of the configuration file. */
+    async restoreStubs(): Promise<void> {
+        await this.call('DELETE', '/__admin/stubs');
+    }
+
+    /**
+     * Restores the stubs of the configuration file, moves every scenario back
+     * to its start and clears the request journal.
+     */
+    async reset(): Promise<void> {
+        await this.call('POST', '/__admin/reset');
+    }
+
+    private async call(method: string, path: string, body?: unknown): Promise<any> {
+        const response = await fetch(this.baseUrl + path, {
+            method,
+            headers: body === undefined ? undefined : { 'Content-Type': 'application/json' },
+            body: body === undefined ? undefined : JSON.stringify(body),
+        });
+        const text = await response.text();
+        if (!response.ok) {
+            throw new Error(`[test-server-sdk] ${method} ${path} failed with status ${response.status}: ${text}`);
+        }
+        return text ? JSON.parse(text) : undefined;
+    }
+}
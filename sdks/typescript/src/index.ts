@@ -19,11 +19,42 @@ import * as path from 'path';
 import * as fs from 'fs';
 import { parse } from 'yaml';
 
+export { Stub, TestServerAdmin } from './admin';
+
 const PROJECT_NAME = 'test-server';
 
+/**
+ * Returns the binary shipped in the platform-specific optional dependency
+ * (e.g. @test-server/cli-linux-x64), or undefined when it is not installed.
+ */
+const getPlatformPackageBinaryPath = (binaryName: string): string | undefined => {
+    const packageName = `@test-server/cli-${process.platform}-${process.arch}`;
+    try {
+        const packageJson = require.resolve(`${packageName}/package.json`);
+        const binaryPath = path.join(path.dirname(packageJson), 'bin', binaryName);
+        return fs.existsSync(binaryPath) ? binaryPath : undefined;
+    } catch {
+        return undefined;
+    }
+};
+
 const getBinaryPath = (): string => {
     const platform = process.platform;
     const binaryName = platform === 'win32' ? `${PROJECT_NAME}.exe` : PROJECT_NAME;
+    // An explicit binary (e.g. a local build used by scripts/run-sdk-tests) wins over everything else.
+    const overridePath = process.env.TEST_SERVER_BINARY;
+    if (overridePath) {
+        if (!fs.existsSync(overridePath)) {
+            throw new Error(`test-server binary not found at ${overridePath} (set by TEST_SERVER_BINARY).`);
+        }
+        return overridePath;
+    }
+    // A pre-release channel build is downloaded by postinstall into bin/.
+    const channel = process.env.TEST_SERVER_CHANNEL;
+    const platformPackageBinary = !channel || channel === 'stable' ? getPlatformPackageBinaryPath(binaryName) : undefined;
+    if (platformPackageBinary) {
+        return platformPackageBinary;
+    }
     // Assuming this script (when compiled) is in sdks/typescript/dist/index.js
     // So __dirname is sdks/typescript/dist
     const binaryPath = path.resolve(__dirname, '..', 'bin', binaryName); 
@@ -48,9 +79,10 @@ export interface TestServerOptions {
      * Mode to run test-server in.
      * - 'record': Forces record mode.
      * - 'replay': Forces replay mode.
-     * - 'cli-driven': Mode is determined by CLI arguments. Defaults to 'replay' unless --record is passed.
+     * - 'auto': Replays recorded requests and records the missing ones.
+     * - 'cli-driven': Mode is determined by CLI arguments. Defaults to 'replay' unless --record or --auto is passed.
      */
-    mode: 'record' | 'replay' | 'cli-driven';
+    mode: 'record' | 'replay' | 'auto' | 'cli-driven';
     /** Optional environment variables for the test-server process. */
     env?: NodeJS.ProcessEnv;
     /** Optional callback for stdout data. */
@@ -72,16 +104,24 @@ export async function startTestServer(options: TestServerOptions): Promise<Child
     const { configPath, recordingDir, mode: optionsMode, env, onStdOut, onStdErr, onExit, onError } = options;
     const binaryPath = getBinaryPath();
 
-    let effectiveMode: 'record' | 'replay';
+    let effectiveMode: 'record' | 'replay' | 'auto';
 
     if (optionsMode === 'record') {
         effectiveMode = 'record';
     } else if (optionsMode === 'replay') {
         effectiveMode = 'replay';
+    } else if (optionsMode === 'auto') {
+        effectiveMode = 'auto';
     } else { // optionsMode === 'cli-driven'
         console.log('Process args: ');
         console.log(process.argv);
-        effectiveMode = process.argv.includes('--record') ? 'record' : 'replay';
+        if (process.argv.includes('--record')) {
+            effectiveMode = 'record';
+        } else if (process.argv.includes('--auto')) {
+            effectiveMode = 'auto';
+        } else {
+            effectiveMode = 'replay';
+        }
     }
 
     const args = [